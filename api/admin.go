@@ -44,6 +44,8 @@ func InitAdmin() {
 	BaseRoutes.Admin.Handle("/get_brand_image", ApiAppHandlerTrustRequester(getBrandImage)).Methods("GET")
 	BaseRoutes.Admin.Handle("/reset_mfa", ApiAdminSystemRequired(adminResetMfa)).Methods("POST")
 	BaseRoutes.Admin.Handle("/reset_password", ApiAdminSystemRequired(adminResetPassword)).Methods("POST")
+	BaseRoutes.Admin.Handle("/revoke_all_sessions", ApiAdminSystemRequired(adminRevokeAllSessions)).Methods("POST")
+	BaseRoutes.Admin.Handle("/unlock_user", ApiAdminSystemRequired(adminUnlockUser)).Methods("POST")
 	BaseRoutes.Admin.Handle("/ldap_sync_now", ApiAdminSystemRequired(ldapSyncNow)).Methods("POST")
 	BaseRoutes.Admin.Handle("/ldap_test", ApiAdminSystemRequired(ldapTest)).Methods("POST")
 	BaseRoutes.Admin.Handle("/saml_metadata", ApiAppHandler(samlMetadata)).Methods("GET")
@@ -138,8 +140,13 @@ func getConfig(c *Context, w http.ResponseWriter, r *http.Request) {
 
 func reloadConfig(c *Context, w http.ResponseWriter, r *http.Request) {
 	debug.FreeOSMemory()
+	oldPermissions := getBuiltInRolePermissions()
 	utils.LoadConfig(utils.CfgFileName)
 
+	if err := UpdateSavedRolesForConfig(oldPermissions); err != nil {
+		l4g.Error(err.Error())
+	}
+
 	// start/restart email batching job if necessary
 	InitEmailBatching()
 
@@ -193,9 +200,14 @@ func saveConfig(c *Context, w http.ResponseWriter, r *http.Request) {
 	c.LogAudit("")
 
 	//oldCfg := utils.Cfg
+	oldPermissions := getBuiltInRolePermissions()
 	utils.SaveConfig(utils.CfgFileName, cfg)
 	utils.LoadConfig(utils.CfgFileName)
 
+	if err := UpdateSavedRolesForConfig(oldPermissions); err != nil {
+		l4g.Error(err.Error())
+	}
+
 	if einterfaces.GetMetricsInterface() != nil {
 		if *utils.Cfg.MetricsSettings.Enable {
 			einterfaces.GetMetricsInterface().StartServer()
@@ -664,6 +676,11 @@ func adminResetMfa(c *Context, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := ResetSecondFactors(userId); err != nil {
+		c.Err = err
+		return
+	}
+
 	if err := DeactivateMfa(userId); err != nil {
 		c.Err = err
 		return
@@ -676,6 +693,56 @@ func adminResetMfa(c *Context, w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(model.MapToJson(rdata)))
 }
 
+func adminRevokeAllSessions(c *Context, w http.ResponseWriter, r *http.Request) {
+	userIds := model.ArrayFromJson(r.Body)
+	if len(userIds) == 0 {
+		c.SetInvalidParam("adminRevokeAllSessions", "user_ids")
+		return
+	}
+
+	for _, userId := range userIds {
+		if len(userId) != 26 {
+			c.SetInvalidParam("adminRevokeAllSessions", "user_ids")
+			return
+		}
+	}
+
+	for _, userId := range userIds {
+		RevokeAllSession(c, userId)
+		if c.Err != nil {
+			return
+		}
+	}
+
+	c.LogAudit("revoked all sessions for " + strconv.Itoa(len(userIds)) + " users")
+
+	ReturnStatusOK(w)
+}
+
+func adminUnlockUser(c *Context, w http.ResponseWriter, r *http.Request) {
+	props := model.MapFromJson(r.Body)
+
+	userId := props["user_id"]
+	if len(userId) != 26 {
+		c.SetInvalidParam("adminUnlockUser", "user_id")
+		return
+	}
+
+	if result := <-Srv.Store.User().Get(userId); result.Err != nil {
+		c.Err = result.Err
+		return
+	}
+
+	if err := UnlockUser(userId); err != nil {
+		c.Err = err
+		return
+	}
+
+	c.LogAuditWithUserId(userId, "")
+
+	ReturnStatusOK(w)
+}
+
 func adminResetPassword(c *Context, w http.ResponseWriter, r *http.Request) {
 	props := model.MapFromJson(r.Body)
 
@@ -708,6 +775,10 @@ func ldapSyncNow(c *Context, w http.ResponseWriter, r *http.Request) {
 		if utils.IsLicensed && *utils.License.Features.LDAP && *utils.Cfg.LdapSettings.Enable {
 			if ldapI := einterfaces.GetLdapInterface(); ldapI != nil {
 				ldapI.SyncNow()
+
+				if _, err := SyncLdapGroupLinks(false); err != nil {
+					l4g.Error(utils.T("api.ldap_group.sync.error"), err.Error())
+				}
 			} else {
 				l4g.Error("%v", model.NewLocAppError("ldapSyncNow", "ent.ldap.disabled.app_error", nil, "").Error())
 			}
@@ -571,6 +571,79 @@ func TestAdminResetMfa(t *testing.T) {
 	// need to add more test cases when enterprise bits can be loaded into tests
 }
 
+func TestAdminRevokeAllSessions(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+
+	if _, err := th.BasicClient.AdminRevokeAllSessions([]string{th.BasicUser2.Id}); err == nil {
+		t.Fatal("should have failed - not an admin")
+	}
+
+	if _, err := th.SystemAdminClient.AdminRevokeAllSessions([]string{}); err == nil {
+		t.Fatal("should have failed - no user ids")
+	}
+
+	if _, err := th.SystemAdminClient.AdminRevokeAllSessions([]string{"junk"}); err == nil {
+		t.Fatal("should have failed - bad user id")
+	}
+
+	Client2 := th.CreateClient()
+	Client2.Must(Client2.Login(th.BasicUser2.Email, th.BasicUser2.Password))
+
+	if _, err := th.SystemAdminClient.AdminRevokeAllSessions([]string{th.BasicUser.Id, th.BasicUser2.Id}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := th.BasicClient.GetMe(""); err == nil {
+		t.Fatal("should've been logged out")
+	}
+
+	if _, err := Client2.GetMe(""); err == nil {
+		t.Fatal("should've been logged out")
+	}
+
+	if _, err := th.SystemAdminClient.GetMe(""); err != nil {
+		t.Fatal("admin should still be logged in")
+	}
+}
+
+func TestAdminUnlockUser(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	user := th.BasicUser2
+
+	passwordAttempts := utils.Cfg.ServiceSettings.MaximumLoginAttempts
+	defer func() {
+		utils.Cfg.ServiceSettings.MaximumLoginAttempts = passwordAttempts
+	}()
+	utils.Cfg.ServiceSettings.MaximumLoginAttempts = 1
+
+	Client := th.CreateClient()
+	Client.Login(user.Email, "notthepassword")
+
+	if _, err := Client.Login(user.Email, user.Password); err == nil {
+		t.Fatal("should be locked out")
+	}
+
+	if _, err := th.BasicClient.AdminUnlockUser(user.Id); err == nil {
+		t.Fatal("should have failed - not an admin")
+	}
+
+	if _, err := th.SystemAdminClient.AdminUnlockUser("junk"); err == nil {
+		t.Fatal("should have failed - bad user id")
+	}
+
+	if _, err := th.SystemAdminClient.AdminUnlockUser(model.NewId()); err == nil {
+		t.Fatal("should have failed - unknown user")
+	}
+
+	if _, err := th.SystemAdminClient.AdminUnlockUser(user.Id); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Client.Login(user.Email, user.Password); err != nil {
+		t.Fatal("should be able to login once unlocked", err)
+	}
+}
+
 func TestAdminResetPassword(t *testing.T) {
 	th := Setup().InitSystemAdmin()
 	Client := th.SystemAdminClient
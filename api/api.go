@@ -54,6 +54,17 @@ type Routes struct {
 
 	Webrtc *mux.Router // 'api/v3/webrtc'
 
+	Roles    *mux.Router // 'api/v3/roles'
+	NeedRole *mux.Router // 'api/v3/roles/{role_id:[a-z_]+}'
+
+	UserGroups    *mux.Router // 'api/v3/user_groups'
+	NeedUserGroup *mux.Router // 'api/v3/user_groups/{group_id:[A-Za-z0-9]+}'
+
+	Reminders    *mux.Router // 'api/v3/reminders'
+	NeedReminder *mux.Router // 'api/v3/reminders/{reminder_id:[A-Za-z0-9]+}'
+
+	Scim *mux.Router // 'scim/v2'
+
 	WebSocket *WebSocketRouter // websocket api
 }
 
@@ -85,6 +96,13 @@ func InitApi() {
 	BaseRoutes.Public = BaseRoutes.ApiRoot.PathPrefix("/public").Subrouter()
 	BaseRoutes.Emoji = BaseRoutes.ApiRoot.PathPrefix("/emoji").Subrouter()
 	BaseRoutes.Webrtc = BaseRoutes.ApiRoot.PathPrefix("/webrtc").Subrouter()
+	BaseRoutes.Roles = BaseRoutes.ApiRoot.PathPrefix("/roles").Subrouter()
+	BaseRoutes.NeedRole = BaseRoutes.Roles.PathPrefix("/{role_id:[a-z_]+}").Subrouter()
+	BaseRoutes.UserGroups = BaseRoutes.ApiRoot.PathPrefix("/user_groups").Subrouter()
+	BaseRoutes.NeedUserGroup = BaseRoutes.UserGroups.PathPrefix("/{group_id:[A-Za-z0-9]+}").Subrouter()
+	BaseRoutes.Reminders = BaseRoutes.ApiRoot.PathPrefix("/reminders").Subrouter()
+	BaseRoutes.NeedReminder = BaseRoutes.Reminders.PathPrefix("/{reminder_id:[A-Za-z0-9]+}").Subrouter()
+	BaseRoutes.Scim = Srv.Router.PathPrefix(model.SCIM_URL_SUFFIX).Subrouter()
 
 	BaseRoutes.WebSocket = NewWebSocketRouter()
 
@@ -105,6 +123,16 @@ func InitApi() {
 	InitStatus()
 	InitWebrtc()
 	InitReaction()
+	InitGuest()
+	InitRole()
+	InitWebAuthn()
+	InitScim()
+	InitLdapGroup()
+	InitProfileAttribute()
+	InitMailQueue()
+	InitUserGroup()
+	InitThread()
+	InitReminder()
 	InitDeprecated()
 
 	// 404 on any api route before web.go has a chance to serve it
@@ -4,16 +4,94 @@
 package api
 
 import (
+	l4g "github.com/alecthomas/log4go"
 	"github.com/mattermost/platform/einterfaces"
 	"github.com/mattermost/platform/model"
 	"github.com/mattermost/platform/utils"
+	"gopkg.in/throttled/throttled.v2"
+	"gopkg.in/throttled/throttled.v2/store/memstore"
 
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
+const (
+	LOGIN_RATE_LIMIT_MEMORY_STORE_SIZE = 10000
+)
+
+// loginRateLimiter throttles login attempts by key. The underlying limiter is rebuilt whenever the
+// configured rate changes.
+type loginRateLimiter struct {
+	sync.Mutex
+	perMinute int
+	limiter   *throttled.GCRARateLimiter
+}
+
+var loginIpRateLimiter = &loginRateLimiter{}
+var loginIdRateLimiter = &loginRateLimiter{}
+
+// allow returns false along with the time until the next attempt is permitted if the key is being throttled
+func (l *loginRateLimiter) allow(key string, perMinute int) (bool, time.Duration) {
+	if perMinute <= 0 {
+		return true, 0
+	}
+
+	l.Lock()
+	defer l.Unlock()
+
+	if l.limiter == nil || l.perMinute != perMinute {
+		store, err := memstore.New(LOGIN_RATE_LIMIT_MEMORY_STORE_SIZE)
+		if err != nil {
+			l4g.Error(utils.T("api.user.login_rate_limit.init.error"), err.Error())
+			return true, 0
+		}
+
+		limiter, err := throttled.NewGCRARateLimiter(store, throttled.RateQuota{MaxRate: throttled.PerMin(perMinute), MaxBurst: perMinute - 1})
+		if err != nil {
+			l4g.Error(utils.T("api.user.login_rate_limit.init.error"), err.Error())
+			return true, 0
+		}
+
+		l.limiter = limiter
+		l.perMinute = perMinute
+	}
+
+	limited, result, err := l.limiter.RateLimit(key, 1)
+	if err != nil {
+		l4g.Error(utils.T("api.user.login_rate_limit.init.error"), err.Error())
+		return true, 0
+	}
+
+	return !limited, result.RetryAfter
+}
+
+// checkLoginRateLimit sets a 429 error on the context if too many login attempts have been made from the
+// request's IP address or for the given login id
+func checkLoginRateLimit(c *Context, w http.ResponseWriter, loginId string) bool {
+	allowed, retryAfter := loginIpRateLimiter.allow(c.IpAddress, *utils.Cfg.ServiceSettings.LoginAttemptsPerMinutePerIp)
+
+	if allowed && len(loginId) > 0 {
+		allowed, retryAfter = loginIdRateLimiter.allow(strings.ToLower(loginId), *utils.Cfg.ServiceSettings.LoginAttemptsPerMinutePerLoginId)
+	}
+
+	if !allowed {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		c.Err = model.NewLocAppError("checkLoginRateLimit", "api.user.login.rate_limited.app_error", nil, "ip="+c.IpAddress+" login_id="+loginId)
+		c.Err.StatusCode = http.StatusTooManyRequests
+		return false
+	}
+
+	return true
+}
+
+// checkPasswordAndAllCriteria checks the password before the MFA token so that a login with the wrong password
+// can't use up one of the user's recovery codes or find out whether a token is valid
 func checkPasswordAndAllCriteria(user *model.User, password string, mfaToken string) *model.AppError {
-	if err := checkUserAdditionalAuthenticationCriteria(user, mfaToken); err != nil {
+	if err := checkUserLoginAttempts(user); err != nil {
 		return err
 	}
 
@@ -21,6 +99,10 @@ func checkPasswordAndAllCriteria(user *model.User, password string, mfaToken str
 		return err
 	}
 
+	if err := checkUserAdditionalAuthenticationCriteria(user, mfaToken); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -39,10 +121,27 @@ func doubleCheckPassword(user *model.User, password string) *model.AppError {
 
 func checkUserPassword(user *model.User, password string) *model.AppError {
 	if !model.ComparePassword(user.Password, password) {
-		if result := <-Srv.Store.User().UpdateFailedPasswordAttempts(user.Id, user.FailedAttempts+1); result.Err != nil {
+		maxAttempts := utils.Cfg.ServiceSettings.MaximumLoginAttempts
+
+		// a lockout that has expired starts counting from scratch
+		attempts := user.FailedAttempts + 1
+		if user.FailedAttempts >= maxAttempts {
+			attempts = 1
+		}
+
+		if result := <-Srv.Store.User().UpdateFailedPasswordAttempts(user.Id, attempts); result.Err != nil {
 			return result.Err
 		}
 
+		if attempts == maxAttempts {
+			l4g.Warn(utils.T("api.user.check_user_password.locked_out.warn"), user.Id)
+
+			audit := &model.Audit{UserId: user.Id, Action: "lockout", ExtraInfo: "failed_attempts=" + strconv.Itoa(attempts)}
+			if result := <-Srv.Store.Audit().Save(audit); result.Err != nil {
+				l4g.Error(result.Err.Error())
+			}
+		}
+
 		return model.NewLocAppError("checkUserPassword", "api.user.check_user_password.invalid.app_error", nil, "user_id="+user.Id)
 	} else {
 		if result := <-Srv.Store.User().UpdateFailedPasswordAttempts(user.Id, 0); result.Err != nil {
@@ -70,11 +169,15 @@ func checkLdapUserPasswordAndAllCriteria(ldapId *string, password string, mfaTok
 		user = ldapUser
 	}
 
-	if err := checkUserMfa(user, mfaToken); err != nil {
+	if err := checkUserNotDisabled(user); err != nil {
 		return nil, err
 	}
 
-	if err := checkUserNotDisabled(user); err != nil {
+	if err := checkGuestNotExpired(user); err != nil {
+		return nil, err
+	}
+
+	if err := checkUserMfa(user, mfaToken); err != nil {
 		return nil, err
 	}
 
@@ -82,16 +185,18 @@ func checkLdapUserPasswordAndAllCriteria(ldapId *string, password string, mfaTok
 	return user, nil
 }
 
+// checkUserAdditionalAuthenticationCriteria checks the MFA token last so that a recovery code is only used up
+// when every other check has passed
 func checkUserAdditionalAuthenticationCriteria(user *model.User, mfaToken string) *model.AppError {
-	if err := checkUserMfa(user, mfaToken); err != nil {
+	if err := checkEmailVerified(user); err != nil {
 		return err
 	}
 
-	if err := checkEmailVerified(user); err != nil {
+	if err := checkUserNotDisabled(user); err != nil {
 		return err
 	}
 
-	if err := checkUserNotDisabled(user); err != nil {
+	if err := checkGuestNotExpired(user); err != nil {
 		return err
 	}
 
@@ -99,14 +204,45 @@ func checkUserAdditionalAuthenticationCriteria(user *model.User, mfaToken string
 		return err
 	}
 
+	if err := checkUserMfa(user, mfaToken); err != nil {
+		return err
+	}
+
 	return nil
 }
 
+// checkUserMfa accepts a TOTP code, a JSON encoded WebAuthn assertion or one of the user's recovery codes as token
 func checkUserMfa(user *model.User, token string) *model.AppError {
-	if !user.MfaActive || !utils.IsLicensed || !*utils.License.Features.MFA || !*utils.Cfg.ServiceSettings.EnableMultifactorAuthentication {
+	if !isMfaAvailable() {
+		return nil
+	}
+
+	credentials, err := GetWebAuthnCredentials(user.Id)
+	if err != nil {
+		return err
+	}
+
+	if !user.MfaActive && len(credentials) == 0 {
+		return nil
+	}
+
+	if remaining, ok := user.UseMfaRecoveryCode(token); ok {
+		if result := <-Srv.Store.User().UpdateMfaRecoveryCodes(user.Id, remaining); result.Err != nil {
+			return result.Err
+		}
+
+		user.MfaRecoveryCodes = remaining
 		return nil
 	}
 
+	if assertion := model.WebAuthnAssertionFromJson(strings.NewReader(token)); assertion != nil && len(assertion.CredentialId) > 0 {
+		return checkWebAuthnAssertion(user, credentials, assertion)
+	}
+
+	if len(credentials) > 0 && (!user.MfaActive || isSecurityKeyRequired(user)) {
+		return model.NewLocAppError("checkUserMfa", "api.user.check_user_mfa.security_key_required.app_error", nil, "user_id="+user.Id)
+	}
+
 	mfaInterface := einterfaces.GetMfaInterface()
 	if mfaInterface == nil {
 		return model.NewLocAppError("checkUserMfa", "api.user.check_user_mfa.not_available.app_error", nil, "")
@@ -122,7 +258,7 @@ func checkUserMfa(user *model.User, token string) *model.AppError {
 }
 
 func checkUserLoginAttempts(user *model.User) *model.AppError {
-	if user.FailedAttempts >= utils.Cfg.ServiceSettings.MaximumLoginAttempts {
+	if user.IsLockedOut(utils.Cfg.ServiceSettings.MaximumLoginAttempts, *utils.Cfg.ServiceSettings.LoginLockoutDurationInMinutes) {
 		return model.NewLocAppError("checkUserLoginAttempts", "api.user.check_user_login_attempts.too_many.app_error", nil, "user_id="+user.Id)
 	}
 
@@ -143,6 +279,13 @@ func checkUserNotDisabled(user *model.User) *model.AppError {
 	return nil
 }
 
+func checkGuestNotExpired(user *model.User) *model.AppError {
+	if user.IsGuestExpired() {
+		return model.NewLocAppError("Login", "api.user.login.guest_expired.app_error", nil, "user_id="+user.Id)
+	}
+	return nil
+}
+
 func authenticateUser(user *model.User, password, mfaToken string) (*model.User, *model.AppError) {
 	ldapAvailable := *utils.Cfg.LdapSettings.Enable && einterfaces.GetLdapInterface() != nil && utils.IsLicensed && *utils.License.Features.LDAP
 
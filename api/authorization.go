@@ -37,7 +37,7 @@ func HasPermissionToTeamContext(c *Context, teamId string, permission *model.Per
 	if teamMember != nil {
 		roles := teamMember.GetRoles()
 
-		if CheckIfRolesGrantPermission(roles, permission.Id) {
+		if CheckIfRolesGrantPermissionInTeam(teamId, roles, permission.Id) {
 			return true
 		}
 	}
@@ -58,7 +58,7 @@ func HasPermissionToTeam(user *model.User, teamMember *model.TeamMember, permiss
 
 	roles := teamMember.GetRoles()
 
-	if CheckIfRolesGrantPermission(roles, permission.Id) {
+	if CheckIfRolesGrantPermissionInTeam(teamMember.TeamId, roles, permission.Id) {
 		return true
 	}
 
@@ -67,30 +67,30 @@ func HasPermissionToTeam(user *model.User, teamMember *model.TeamMember, permiss
 
 func HasPermissionToChannelContext(c *Context, channelId string, permission *model.Permission) bool {
 	cmc := Srv.Store.Channel().GetAllChannelMembersForUser(c.Session.UserId, true)
+	tc := Srv.Store.Channel().GetTeamIdForChannel(channelId, true)
+
+	teamId := ""
+	if tcresult := <-tc; tcresult.Err == nil {
+		teamId = tcresult.Data.(string)
+	}
 
 	var channelRoles []string
 	if cmcresult := <-cmc; cmcresult.Err == nil {
 		ids := cmcresult.Data.(map[string]string)
 		if roles, ok := ids[channelId]; ok {
 			channelRoles = strings.Fields(roles)
-			if CheckIfRolesGrantPermission(channelRoles, permission.Id) {
+			if CheckIfRolesGrantPermissionInTeam(teamId, channelRoles, permission.Id) {
 				return true
 			}
 		}
 	}
 
-	cc := Srv.Store.Channel().Get(channelId)
-	if ccresult := <-cc; ccresult.Err == nil {
-		channel := ccresult.Data.(*model.Channel)
-
-		if teamMember := c.Session.GetTeamByTeamId(channel.TeamId); teamMember != nil {
-			roles := teamMember.GetRoles()
+	if teamMember := c.Session.GetTeamByTeamId(teamId); teamMember != nil {
+		roles := teamMember.GetRoles()
 
-			if CheckIfRolesGrantPermission(roles, permission.Id) {
-				return true
-			}
+		if CheckIfRolesGrantPermissionInTeam(teamId, roles, permission.Id) {
+			return true
 		}
-
 	}
 
 	if HasPermissionToContext(c, permission) {
@@ -107,9 +107,14 @@ func HasPermissionToChannel(user *model.User, teamMember *model.TeamMember, chan
 		return false
 	}
 
+	teamId := ""
+	if teamMember != nil {
+		teamId = teamMember.TeamId
+	}
+
 	roles := channelMember.GetRoles()
 
-	if CheckIfRolesGrantPermission(roles, permission.Id) {
+	if CheckIfRolesGrantPermissionInTeam(teamId, roles, permission.Id) {
 		return true
 	}
 
@@ -118,29 +123,33 @@ func HasPermissionToChannel(user *model.User, teamMember *model.TeamMember, chan
 
 func HasPermissionToChannelByPostContext(c *Context, postId string, permission *model.Permission) bool {
 	cmc := Srv.Store.Channel().GetMemberForPost(postId, c.Session.UserId)
+	cc := Srv.Store.Channel().GetForPost(postId)
+
+	var channel *model.Channel
+	teamId := ""
+	if ccresult := <-cc; ccresult.Err == nil {
+		channel = ccresult.Data.(*model.Channel)
+		teamId = channel.TeamId
+	}
 
 	var channelRoles []string
 	if cmcresult := <-cmc; cmcresult.Err == nil {
 		channelMember := cmcresult.Data.(*model.ChannelMember)
 		channelRoles = channelMember.GetRoles()
 
-		if CheckIfRolesGrantPermission(channelRoles, permission.Id) {
+		if CheckIfRolesGrantPermissionInTeam(teamId, channelRoles, permission.Id) {
 			return true
 		}
 	}
 
-	cc := Srv.Store.Channel().GetForPost(postId)
-	if ccresult := <-cc; ccresult.Err == nil {
-		channel := ccresult.Data.(*model.Channel)
-
+	if channel != nil {
 		if teamMember := c.Session.GetTeamByTeamId(channel.TeamId); teamMember != nil {
 			roles := teamMember.GetRoles()
 
-			if CheckIfRolesGrantPermission(roles, permission.Id) {
+			if CheckIfRolesGrantPermissionInTeam(channel.TeamId, roles, permission.Id) {
 				return true
 			}
 		}
-
 	}
 
 	if HasPermissionToContext(c, permission) {
@@ -169,18 +178,35 @@ func HasPermissionToUser(c *Context, userId string) bool {
 }
 
 func CheckIfRolesGrantPermission(roles []string, permissionId string) bool {
+	return CheckIfRolesGrantPermissionInTeam("", roles, permissionId)
+}
+
+// CheckIfRolesGrantPermissionInTeam checks the roles against the saved roles, applying the
+// overrides of the team's scheme first when a team is given.
+func CheckIfRolesGrantPermissionInTeam(teamId string, roles []string, permissionId string) bool {
+	var scheme *model.Scheme
+	if len(teamId) > 0 {
+		scheme = GetSchemeForTeam(teamId)
+	}
+
 	for _, roleId := range roles {
-		if role, ok := model.BuiltInRoles[roleId]; !ok {
-			l4g.Debug("Bad role in system " + roleId)
-			return false
-		} else {
-			permissions := role.Permissions
-			for _, permission := range permissions {
-				if permission == permissionId {
-					return true
+		if scheme != nil {
+			if permissions, ok := scheme.GetRolePermissions(roleId); ok {
+				for _, permission := range permissions {
+					if permission == permissionId {
+						return true
+					}
 				}
+				continue
 			}
 		}
+
+		if role := GetRole(roleId); role == nil {
+			l4g.Debug("Bad role in system " + roleId)
+			return false
+		} else if role.HasPermission(permissionId) {
+			return true
+		}
 	}
 
 	return false
@@ -0,0 +1,65 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api
+
+import (
+	"strings"
+
+	l4g "github.com/alecthomas/log4go"
+
+	"github.com/mattermost/platform/model"
+	"github.com/mattermost/platform/utils"
+)
+
+// sendAutoResponse replies to a direct message with the other user's out of office message if they have one
+// active, at most once a day for each sender
+func sendAutoResponse(c *Context, post *model.Post, channel *model.Channel) {
+	if post.IsSystemMessage() {
+		return
+	}
+
+	receiverId := ""
+	for _, userId := range strings.Split(channel.Name, "__") {
+		if userId != post.UserId {
+			receiverId = userId
+		}
+	}
+
+	if len(receiverId) == 0 {
+		return
+	}
+
+	var receiver *model.User
+	if result := <-Srv.Store.User().Get(receiverId); result.Err != nil {
+		l4g.Error(utils.T("api.auto_responder.get_user.error"), receiverId, result.Err)
+		return
+	} else {
+		receiver = result.Data.(*model.User)
+	}
+
+	now := model.GetMillis()
+
+	autoResponder := receiver.GetActiveAutoResponder(now)
+	if autoResponder == nil {
+		return
+	}
+
+	if result := <-Srv.Store.Post().CountByTypeSince(channel.Id, receiver.Id, model.POST_AUTO_RESPONDER, now-model.AUTO_RESPONDER_INTERVAL); result.Err != nil {
+		l4g.Error(utils.T("api.auto_responder.count.error"), channel.Id, result.Err)
+		return
+	} else if result.Data.(int64) > 0 {
+		return
+	}
+
+	autoResponse := &model.Post{
+		ChannelId: channel.Id,
+		UserId:    receiver.Id,
+		Message:   autoResponder.Message,
+		Type:      model.POST_AUTO_RESPONDER,
+	}
+
+	if _, err := CreatePost(c, autoResponse, false); err != nil {
+		l4g.Error(utils.T("api.auto_responder.create_post.error"), channel.Id, err)
+	}
+}
@@ -80,7 +80,7 @@ func (cfg *AutoUserCreator) createRandomUser() (*model.User, bool) {
 
 	ruser := result.Data.(*model.User)
 
-	status := &model.Status{ruser.Id, model.STATUS_ONLINE, false, model.GetMillis(), ""}
+	status := &model.Status{UserId: ruser.Id, Status: model.STATUS_ONLINE, LastActivityAt: model.GetMillis()}
 	if result := <-Srv.Store.Status().SaveOrUpdate(status); result.Err != nil {
 		result.Err.Translate(utils.T)
 		l4g.Error(result.Err.Error())
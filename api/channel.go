@@ -45,6 +45,7 @@ func InitChannel() {
 	BaseRoutes.NeedChannel.Handle("/delete", ApiUserRequired(deleteChannel)).Methods("POST")
 	BaseRoutes.NeedChannel.Handle("/add", ApiUserRequired(addMember)).Methods("POST")
 	BaseRoutes.NeedChannel.Handle("/remove", ApiUserRequired(removeMember)).Methods("POST")
+	BaseRoutes.NeedChannel.Handle("/update_member_roles", ApiUserRequired(updateChannelMemberRoles)).Methods("POST")
 }
 
 func createChannel(c *Context, w http.ResponseWriter, r *http.Request) {
@@ -152,10 +153,28 @@ func createDirectChannel(c *Context, w http.ResponseWriter, r *http.Request) {
 }
 
 func CreateDirectChannel(userId string, otherUserId string) (*model.Channel, *model.AppError) {
-	uc := Srv.Store.User().Get(otherUserId)
+	uc := Srv.Store.User().Get(userId)
+	ouc := Srv.Store.User().Get(otherUserId)
 
+	var user *model.User
 	if uresult := <-uc; uresult.Err != nil {
+		return nil, model.NewLocAppError("CreateDirectChannel", "api.channel.create_direct_channel.invalid_user.app_error", nil, userId)
+	} else {
+		user = uresult.Data.(*model.User)
+	}
+
+	var otherUser *model.User
+	if uresult := <-ouc; uresult.Err != nil {
 		return nil, model.NewLocAppError("CreateDirectChannel", "api.channel.create_direct_channel.invalid_user.app_error", nil, otherUserId)
+	} else {
+		otherUser = uresult.Data.(*model.User)
+	}
+
+	// guests can only message the people they share a channel with
+	if (user.IsGuest() || otherUser.IsGuest()) && !UsersShareChannel(userId, otherUserId) {
+		err := model.NewLocAppError("CreateDirectChannel", "api.channel.create_direct_channel.guest.app_error", nil, "user_id="+userId+", other_user_id="+otherUserId)
+		err.StatusCode = http.StatusForbidden
+		return nil, err
 	}
 
 	if result := <-Srv.Store.Channel().CreateDirectChannel(userId, otherUserId); result.Err != nil {
@@ -626,6 +645,10 @@ func AddUserToChannel(user *model.User, channel *model.Channel) (*model.ChannelM
 		NotifyProps: model.GetDefaultChannelNotifyProps(),
 		Roles:       model.ROLE_CHANNEL_USER.Id,
 	}
+
+	if user.IsGuest() {
+		newMember.Roles = model.ROLE_CHANNEL_GUEST.Id
+	}
 	if result := <-Srv.Store.Channel().SaveMember(newMember); result.Err != nil {
 		l4g.Error("Failed to add member user_id=%v channel_id=%v err=%v", user.Id, channel.Id, result.Err)
 		return nil, model.NewLocAppError("AddUserToChannel", "api.channel.add_user.to.channel.failed.app_error", nil, "")
@@ -749,6 +772,11 @@ func leave(c *Context, w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		if err := CheckLdapGroupMemberRemoval(user.Id, channel.TeamId, channel.Id); err != nil {
+			c.Err = err
+			return
+		}
+
 		if cmresult := <-Srv.Store.Channel().RemoveMember(channel.Id, c.Session.UserId); cmresult.Err != nil {
 			c.Err = cmresult.Err
 			return
@@ -908,7 +936,7 @@ func getChannel(c *Context, w http.ResponseWriter, r *http.Request) {
 func SetActiveChannel(userId string, channelId string) *model.AppError {
 	status, err := GetStatus(userId)
 	if err != nil {
-		status = &model.Status{userId, model.STATUS_ONLINE, false, model.GetMillis(), channelId}
+		status = &model.Status{UserId: userId, Status: model.STATUS_ONLINE, LastActivityAt: model.GetMillis(), ActiveChannel: channelId}
 	} else {
 		status.ActiveChannel = channelId
 		if !status.Manual {
@@ -1108,6 +1136,11 @@ func removeMember(c *Context, w http.ResponseWriter, r *http.Request) {
 				return
 			}
 
+			if err := CheckLdapGroupMemberRemoval(userIdToRemove, channel.TeamId, channel.Id); err != nil {
+				c.Err = err
+				return
+			}
+
 			if err := RemoveUserFromChannel(userIdToRemove, c.Session.UserId, channel); err != nil {
 				c.Err = model.NewLocAppError("updateChannel", "api.channel.remove_member.unable.app_error", nil, err.Message)
 				return
@@ -1191,6 +1224,14 @@ func updateNotifyProps(c *Context, w http.ResponseWriter, r *http.Request) {
 		member.NotifyProps["desktop"] = desktop
 	}
 
+	if mentionKeys, exists := data["mention_keys"]; exists {
+		member.NotifyProps["mention_keys"] = mentionKeys
+	}
+
+	if mutedUntil, exists := data["muted_until"]; exists {
+		member.NotifyProps["muted_until"] = mutedUntil
+	}
+
 	if result := <-Srv.Store.Channel().UpdateMember(&member); result.Err != nil {
 		c.Err = result.Err
 		return
@@ -1203,6 +1244,67 @@ func updateNotifyProps(c *Context, w http.ResponseWriter, r *http.Request) {
 
 }
 
+func updateChannelMemberRoles(c *Context, w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	channelId := params["channel_id"]
+
+	props := model.MapFromJson(r.Body)
+
+	userId := props["user_id"]
+	if len(userId) != 26 {
+		c.SetInvalidParam("updateChannelMemberRoles", "user_id")
+		return
+	}
+
+	newRoles := props["new_roles"]
+	if !model.IsValidChannelRoles(newRoles) {
+		c.SetInvalidParam("updateChannelMemberRoles", "new_roles")
+		return
+	}
+
+	if !HasPermissionToChannelContext(c, channelId, model.PERMISSION_MANAGE_CHANNEL_ROLES) {
+		return
+	}
+
+	if _, err := UpdateChannelMemberRoles(channelId, userId, newRoles); err != nil {
+		c.Err = err
+		return
+	}
+
+	c.LogAudit("channel_id=" + channelId + " user_id=" + userId + " roles=" + newRoles)
+
+	rdata := map[string]string{}
+	rdata["status"] = "ok"
+	w.Write([]byte(model.MapToJson(rdata)))
+}
+
+func UpdateChannelMemberRoles(channelId string, userId string, newRoles string) (*model.ChannelMember, *model.AppError) {
+	if !model.IsValidChannelRoles(newRoles) {
+		return nil, NewInvalidParamError("UpdateChannelMemberRoles", "new_roles")
+	}
+
+	var member model.ChannelMember
+	if result := <-Srv.Store.Channel().GetMember(channelId, userId); result.Err != nil {
+		return nil, result.Err
+	} else {
+		member = result.Data.(model.ChannelMember)
+	}
+
+	member.Roles = newRoles
+
+	if result := <-Srv.Store.Channel().UpdateMember(&member); result.Err != nil {
+		return nil, result.Err
+	}
+
+	InvalidateCacheForUser(userId)
+
+	message := model.NewWebSocketEvent(model.WEBSOCKET_EVENT_CHANNEL_MEMBER_UPDATED, "", "", userId, nil)
+	message.Add("channel_member", member.ToJson())
+	go Publish(message)
+
+	return &member, nil
+}
+
 func searchMoreChannels(c *Context, w http.ResponseWriter, r *http.Request) {
 	props := model.ChannelSearchFromJson(r.Body)
 	if props == nil {
@@ -1221,6 +1323,10 @@ func searchMoreChannels(c *Context, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !HasPermissionToTeamContext(c, c.TeamId, model.PERMISSION_LIST_TEAM_CHANNELS) {
+		return
+	}
+
 	if result := <-Srv.Store.Channel().SearchMore(c.Session.UserId, c.TeamId, props.Term); result.Err != nil {
 		c.Err = result.Err
 		return
@@ -1239,6 +1345,10 @@ func autocompleteChannels(c *Context, w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if !HasPermissionToTeamContext(c, c.TeamId, model.PERMISSION_LIST_TEAM_CHANNELS) {
+		return
+	}
+
 	var channels *model.ChannelList
 
 	if result := <-Srv.Store.Channel().SearchInTeam(c.TeamId, term); result.Err != nil {
@@ -5,6 +5,7 @@ package api
 
 import (
 	"net/http"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -1538,6 +1539,26 @@ func TestUpdateNotifyProps(t *testing.T) {
 		t.Fatal("NotifyProps[\"mark_unread\"] did not update properly")
 	}
 
+	// test updating channel keywords and muting
+	data["mention_keys"] = "deploy,outage"
+	data["muted_until"] = strconv.FormatInt(model.GetMillis()+60*60*1000, 10)
+
+	if result, err := Client.UpdateNotifyProps(data); err != nil {
+		t.Fatal(err)
+	} else if notifyProps := result.Data.(map[string]string); notifyProps["mention_keys"] != "deploy,outage" {
+		t.Fatal("NotifyProps[\"mention_keys\"] did not update properly")
+	} else if notifyProps["muted_until"] != data["muted_until"] {
+		t.Fatal("NotifyProps[\"muted_until\"] did not update properly")
+	}
+
+	data["muted_until"] = "junk"
+	if _, err := Client.UpdateNotifyProps(data); err == nil {
+		t.Fatal("Should have errored - bad muted until")
+	}
+
+	delete(data, "mention_keys")
+	data["muted_until"] = ""
+
 	// test error cases
 	data["user_id"] = "junk"
 	if _, err := Client.UpdateNotifyProps(data); err == nil {
@@ -1666,6 +1687,76 @@ func TestGetChannelMember(t *testing.T) {
 	}
 }
 
+func TestUpdateChannelMemberRoles(t *testing.T) {
+	th := Setup().InitSystemAdmin().InitBasic()
+	Client := th.BasicClient
+	team := th.BasicTeam
+
+	const CHANNEL_MEMBER = "channel_user"
+	const CHANNEL_ADMIN = "channel_user channel_admin"
+
+	channel1 := &model.Channel{DisplayName: "A Test API Name", Name: "a" + model.NewId() + "a", Type: model.CHANNEL_OPEN, TeamId: team.Id}
+	channel1 = Client.Must(Client.CreateChannel(channel1)).Data.(*model.Channel)
+	Client.Must(Client.AddChannelMember(channel1.Id, th.BasicUser2.Id))
+
+	// user 2 trying to promote themselves
+	th.LoginBasic2()
+	if _, err := Client.UpdateChannelRoles(channel1.Id, th.BasicUser2.Id, CHANNEL_ADMIN); err == nil {
+		t.Fatal("should have errored, not channel admin")
+	}
+
+	// the channel creator is a channel admin
+	th.LoginBasic()
+	if _, err := Client.UpdateChannelRoles(channel1.Id, th.BasicUser2.Id, CHANNEL_ADMIN); err != nil {
+		t.Fatal(err)
+	}
+
+	if result, err := Client.GetChannelMember(channel1.Id, th.BasicUser2.Id); err != nil {
+		t.Fatal(err)
+	} else if cm := result.Data.(*model.ChannelMember); cm.Roles != CHANNEL_ADMIN {
+		t.Fatal("roles should have been updated")
+	}
+
+	// user 2 can now demote the creator
+	th.LoginBasic2()
+	if _, err := Client.UpdateChannelRoles(channel1.Id, th.BasicUser.Id, CHANNEL_MEMBER); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Client.UpdateChannelRoles(channel1.Id, th.BasicUser.Id, "junk"); err == nil {
+		t.Fatal("should have errored, invalid roles")
+	}
+
+	// channel admins can't grant roles outside of the channel
+	for _, roles := range []string{"system_admin", "channel_user system_admin", "team_admin", "system_user"} {
+		if _, err := Client.UpdateChannelRoles(channel1.Id, th.BasicUser2.Id, roles); err == nil {
+			t.Fatal("should have errored, not a channel role", roles)
+		} else if err.StatusCode != http.StatusBadRequest {
+			t.Fatal("should have been a bad request", roles, err.StatusCode)
+		}
+	}
+
+	if result, err := Client.GetChannelMember(channel1.Id, th.BasicUser2.Id); err != nil {
+		t.Fatal(err)
+	} else if cm := result.Data.(*model.ChannelMember); cm.Roles != CHANNEL_ADMIN {
+		t.Fatal("roles shouldn't have changed", cm.Roles)
+	}
+
+	if _, err := Client.UpdateChannelRoles(channel1.Id, "junk", CHANNEL_MEMBER); err == nil {
+		t.Fatal("should have errored, invalid user id")
+	}
+
+	if _, err := Client.UpdateChannelRoles(channel1.Id, th.SystemAdminUser.Id, CHANNEL_MEMBER); err == nil {
+		t.Fatal("should have errored, user is not a member of the channel")
+	}
+
+	// system admins can manage roles in any channel
+	th.SystemAdminClient.SetTeamId(team.Id)
+	if _, err := th.SystemAdminClient.UpdateChannelRoles(channel1.Id, th.BasicUser2.Id, CHANNEL_MEMBER); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestSearchMoreChannels(t *testing.T) {
 	th := Setup().InitBasic()
 	Client := th.BasicClient
@@ -0,0 +1,148 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api
+
+import (
+	"strings"
+	"time"
+
+	"github.com/mattermost/platform/model"
+)
+
+type RemindProvider struct {
+}
+
+const (
+	CMD_REMIND = "remind"
+)
+
+func init() {
+	RegisterCommandProvider(&RemindProvider{})
+}
+
+func (me *RemindProvider) GetTrigger() string {
+	return CMD_REMIND
+}
+
+func (me *RemindProvider) GetCommand(c *Context) *model.Command {
+	return &model.Command{
+		Trigger:          CMD_REMIND,
+		AutoComplete:     true,
+		AutoCompleteDesc: c.T("api.command_remind.desc"),
+		AutoCompleteHint: c.T("api.command_remind.hint"),
+		DisplayName:      c.T("api.command_remind.name"),
+	}
+}
+
+func (me *RemindProvider) DoCommand(c *Context, args *model.CommandArgs, message string) *model.CommandResponse {
+	words := strings.Fields(message)
+	if len(words) == 0 {
+		return &model.CommandResponse{ResponseType: model.COMMAND_RESPONSE_TYPE_EPHEMERAL, Text: c.T("api.command_remind.usage")}
+	}
+
+	switch strings.ToLower(words[0]) {
+	case "list":
+		return me.list(c)
+	case "delete":
+		if len(words) != 2 {
+			return &model.CommandResponse{ResponseType: model.COMMAND_RESPONSE_TYPE_EPHEMERAL, Text: c.T("api.command_remind.usage")}
+		}
+
+		return me.delete(c, words[1])
+	case "snooze":
+		if len(words) < 3 {
+			return &model.CommandResponse{ResponseType: model.COMMAND_RESPONSE_TYPE_EPHEMERAL, Text: c.T("api.command_remind.usage")}
+		}
+
+		return me.snooze(c, words[1], strings.Join(words[2:], " "))
+	case "me":
+		message = strings.Join(words[1:], " ")
+	}
+
+	remindAt, text, err := ParseUserReminderTime(c.Session.UserId, message)
+	if err != nil {
+		return &model.CommandResponse{ResponseType: model.COMMAND_RESPONSE_TYPE_EPHEMERAL, Text: c.T("api.command_remind.invalid_time")}
+	} else if len(text) == 0 {
+		return &model.CommandResponse{ResponseType: model.COMMAND_RESPONSE_TYPE_EPHEMERAL, Text: c.T("api.command_remind.no_message")}
+	}
+
+	reminder := &model.Reminder{
+		UserId:   c.Session.UserId,
+		TeamId:   c.TeamId,
+		Message:  text,
+		RemindAt: remindAt,
+	}
+
+	if reminder, err = CreateReminder(reminder); err != nil {
+		return &model.CommandResponse{ResponseType: model.COMMAND_RESPONSE_TYPE_EPHEMERAL, Text: c.T("api.command_remind.error")}
+	}
+
+	return &model.CommandResponse{ResponseType: model.COMMAND_RESPONSE_TYPE_EPHEMERAL, Text: c.T("api.command_remind.success", map[string]interface{}{"Time": me.formatTime(c, reminder.RemindAt)})}
+}
+
+func (me *RemindProvider) list(c *Context) *model.CommandResponse {
+	reminders, err := GetRemindersForUser(c.Session.UserId)
+	if err != nil {
+		return &model.CommandResponse{ResponseType: model.COMMAND_RESPONSE_TYPE_EPHEMERAL, Text: c.T("api.command_remind.error")}
+	} else if len(reminders) == 0 {
+		return &model.CommandResponse{ResponseType: model.COMMAND_RESPONSE_TYPE_EPHEMERAL, Text: c.T("api.command_remind.list_empty")}
+	}
+
+	lines := []string{c.T("api.command_remind.list_title")}
+	for _, reminder := range reminders {
+		text := reminder.Message
+		if len(reminder.PostId) > 0 {
+			text = strings.TrimSpace(c.T("api.command_remind.list_post", map[string]interface{}{"Link": getReminderPostLink(reminder)}) + " " + text)
+		}
+
+		key := "api.command_remind.list_item"
+		if reminder.FiredAt != 0 {
+			key = "api.command_remind.list_item_sent"
+		}
+
+		lines = append(lines, c.T(key, map[string]interface{}{"Id": reminder.Id, "Time": me.formatTime(c, reminder.RemindAt), "Message": text}))
+	}
+
+	return &model.CommandResponse{ResponseType: model.COMMAND_RESPONSE_TYPE_EPHEMERAL, Text: strings.Join(lines, "\n")}
+}
+
+func (me *RemindProvider) delete(c *Context, id string) *model.CommandResponse {
+	if _, err := GetReminder(id, c.Session.UserId); err != nil {
+		return &model.CommandResponse{ResponseType: model.COMMAND_RESPONSE_TYPE_EPHEMERAL, Text: c.T("api.command_remind.not_found", map[string]interface{}{"Id": id})}
+	}
+
+	if err := DeleteReminder(id); err != nil {
+		return &model.CommandResponse{ResponseType: model.COMMAND_RESPONSE_TYPE_EPHEMERAL, Text: c.T("api.command_remind.error")}
+	}
+
+	return &model.CommandResponse{ResponseType: model.COMMAND_RESPONSE_TYPE_EPHEMERAL, Text: c.T("api.command_remind.deleted")}
+}
+
+func (me *RemindProvider) snooze(c *Context, id string, when string) *model.CommandResponse {
+	reminder, err := GetReminder(id, c.Session.UserId)
+	if err != nil {
+		return &model.CommandResponse{ResponseType: model.COMMAND_RESPONSE_TYPE_EPHEMERAL, Text: c.T("api.command_remind.not_found", map[string]interface{}{"Id": id})}
+	}
+
+	remindAt, text, err := ParseUserReminderTime(c.Session.UserId, when)
+	if err != nil || len(text) > 0 {
+		return &model.CommandResponse{ResponseType: model.COMMAND_RESPONSE_TYPE_EPHEMERAL, Text: c.T("api.command_remind.invalid_time")}
+	}
+
+	if reminder, err = SnoozeReminder(reminder, remindAt); err != nil {
+		return &model.CommandResponse{ResponseType: model.COMMAND_RESPONSE_TYPE_EPHEMERAL, Text: c.T("api.command_remind.error")}
+	}
+
+	return &model.CommandResponse{ResponseType: model.COMMAND_RESPONSE_TYPE_EPHEMERAL, Text: c.T("api.command_remind.success", map[string]interface{}{"Time": me.formatTime(c, reminder.RemindAt)})}
+}
+
+// formatTime formats the time of a reminder in the user's time zone
+func (me *RemindProvider) formatTime(c *Context, millis int64) string {
+	location, err := getUserLocation(c.Session.UserId)
+	if err != nil {
+		location = time.Local
+	}
+
+	return time.Unix(0, millis*int64(time.Millisecond)).In(location).Format(REMINDER_TIME_FORMAT)
+}
@@ -0,0 +1,49 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mattermost/platform/model"
+)
+
+func TestRemindCommand(t *testing.T) {
+	th := Setup().InitBasic()
+	Client := th.BasicClient
+	channel := th.BasicChannel
+
+	Client.Must(Client.Command(channel.Id, "/remind me in 2 hours check the build"))
+
+	reminders := Client.Must(Client.GetReminders()).Data.([]*model.Reminder)
+	if len(reminders) != 1 || reminders[0].Message != "check the build" || reminders[0].TeamId != th.BasicTeam.Id {
+		t.Fatal("should have created the reminder", reminders)
+	}
+
+	reminder := reminders[0]
+
+	if r := Client.Must(Client.Command(channel.Id, "/remind list")).Data.(*model.CommandResponse); !strings.Contains(r.Text, reminder.Id) {
+		t.Fatal("should have listed the reminder", r.Text)
+	}
+
+	Client.Must(Client.Command(channel.Id, "/remind tomorrow"))
+	Client.Must(Client.Command(channel.Id, "/remind sometime soon"))
+
+	if reminders := Client.Must(Client.GetReminders()).Data.([]*model.Reminder); len(reminders) != 1 {
+		t.Fatal("shouldn't have created reminders without a message or a time", reminders)
+	}
+
+	Client.Must(Client.Command(channel.Id, "/remind snooze "+reminder.Id+" in 3 days"))
+
+	if reminders := Client.Must(Client.GetReminders()).Data.([]*model.Reminder); reminders[0].RemindAt <= reminder.RemindAt {
+		t.Fatal("should have snoozed the reminder", reminders)
+	}
+
+	Client.Must(Client.Command(channel.Id, "/remind delete "+reminder.Id))
+
+	if reminders := Client.Must(Client.GetReminders()).Data.([]*model.Reminder); len(reminders) != 0 {
+		t.Fatal("should have deleted the reminder", reminders)
+	}
+}
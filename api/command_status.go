@@ -0,0 +1,78 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api
+
+import (
+	"strings"
+	"time"
+
+	"github.com/mattermost/platform/model"
+)
+
+type StatusProvider struct {
+}
+
+const (
+	CMD_STATUS       = "status"
+	CMD_STATUS_CLEAR = "clear"
+)
+
+func init() {
+	RegisterCommandProvider(&StatusProvider{})
+}
+
+func (me *StatusProvider) GetTrigger() string {
+	return CMD_STATUS
+}
+
+func (me *StatusProvider) GetCommand(c *Context) *model.Command {
+	return &model.Command{
+		Trigger:          CMD_STATUS,
+		AutoComplete:     true,
+		AutoCompleteDesc: c.T("api.command_status.desc"),
+		AutoCompleteHint: c.T("api.command_status.hint"),
+		DisplayName:      c.T("api.command_status.name"),
+	}
+}
+
+// DoCommand sets the custom status from a message like "2h :calendar: In a meeting" where the duration
+// and emoji are optional. Sending nothing or "clear" clears it.
+func (me *StatusProvider) DoCommand(c *Context, args *model.CommandArgs, message string) *model.CommandResponse {
+	message = strings.TrimSpace(message)
+
+	if len(message) == 0 || message == CMD_STATUS_CLEAR {
+		SetCustomStatus(c.Session.UserId, nil)
+		return &model.CommandResponse{ResponseType: model.COMMAND_RESPONSE_TYPE_EPHEMERAL, Text: c.T("api.command_status.cleared")}
+	}
+
+	customStatus := &model.CustomStatus{}
+
+	fields := strings.Fields(message)
+	if duration, err := time.ParseDuration(fields[0]); err == nil {
+		if duration <= 0 {
+			return &model.CommandResponse{ResponseType: model.COMMAND_RESPONSE_TYPE_EPHEMERAL, Text: c.T("api.command_dnd.invalid_duration", map[string]interface{}{"Duration": fields[0]})}
+		}
+
+		customStatus.ExpiresAt = model.GetMillis() + int64(duration/time.Millisecond)
+		message = strings.TrimSpace(strings.TrimPrefix(message, fields[0]))
+		fields = fields[1:]
+	}
+
+	if len(fields) > 0 && len(fields[0]) > 2 && strings.HasPrefix(fields[0], ":") && strings.HasSuffix(fields[0], ":") {
+		customStatus.Emoji = fields[0]
+		message = strings.TrimSpace(strings.TrimPrefix(message, fields[0]))
+	}
+
+	customStatus.Text = message
+
+	customStatus.PreSave()
+	if err := customStatus.IsValid(model.GetMillis()); err != nil {
+		err.Translate(c.T)
+		return &model.CommandResponse{ResponseType: model.COMMAND_RESPONSE_TYPE_EPHEMERAL, Text: err.Message}
+	}
+
+	SetCustomStatus(c.Session.UserId, customStatus)
+
+	return &model.CommandResponse{ResponseType: model.COMMAND_RESPONSE_TYPE_EPHEMERAL, Text: c.T("api.command_status.success")}
+}
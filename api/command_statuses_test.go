@@ -4,6 +4,7 @@
 package api
 
 import (
+	"strings"
 	"testing"
 	"time"
 
@@ -15,6 +16,55 @@ func TestStatusCommands(t *testing.T) {
 	commandAndTest(t, th, "away")
 	commandAndTest(t, th, "offline")
 	commandAndTest(t, th, "online")
+	commandAndTest(t, th, "dnd")
+	commandAndTest(t, th, "online")
+}
+
+func TestDndCommand(t *testing.T) {
+	th := Setup().InitBasic()
+	Client := th.BasicClient
+	channel := th.BasicChannel
+	user := th.BasicUser
+
+	Client.Must(Client.Command(channel.Id, "/dnd 1h"))
+
+	status, err := GetStatus(user.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if status.Status != model.STATUS_DND || status.PrevStatus != model.STATUS_ONLINE {
+		t.Fatal("should be in dnd with the previous status saved")
+	}
+
+	if status.DNDEndTime < model.GetMillis()+59*60*1000 {
+		t.Fatal("should have set the end time an hour from now")
+	}
+
+	SetStatusOnline(user.Id, "", false)
+	if status, _ := GetStatus(user.Id); status.Status != model.STATUS_DND {
+		t.Fatal("automatic status changes should not override dnd")
+	}
+
+	if !DoesStatusAllowPushNotification(user, nil, &model.Status{UserId: user.Id, Status: model.STATUS_OFFLINE}, channel.Id) {
+		t.Fatal("offline status should allow push notifications")
+	}
+
+	if DoesStatusAllowPushNotification(user, nil, status, channel.Id) {
+		t.Fatal("dnd status should not allow push notifications")
+	}
+
+	r1 := Client.Must(Client.Command(channel.Id, "/dnd junk")).Data.(*model.CommandResponse)
+	if !strings.Contains(r1.Text, "junk") {
+		t.Fatal("should have rejected the duration")
+	}
+
+	SetStatusDoNotDisturb(user.Id, model.GetMillis()-1000)
+	RevertExpiredDNDStatuses()
+
+	if status, _ := GetStatus(user.Id); status.Status != model.STATUS_ONLINE || status.Manual || status.DNDEndTime != 0 {
+		t.Fatal("should have restored the previous status", status.Status)
+	}
 }
 
 func commandAndTest(t *testing.T, th *TestHelper, status string) {
@@ -38,3 +88,37 @@ func commandAndTest(t *testing.T, th *TestHelper, status string) {
 		t.Fatal("Error setting status " + status)
 	}
 }
+
+func TestCustomStatusCommand(t *testing.T) {
+	th := Setup().InitBasic()
+	Client := th.BasicClient
+	channel := th.BasicChannel
+	user := th.BasicUser
+
+	Client.Must(Client.Command(channel.Id, "/status 2h :palm_tree: On vacation until 10/24"))
+
+	status, err := GetStatus(user.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if status.CustomEmoji != "palm_tree" || status.CustomText != "On vacation until 10/24" {
+		t.Fatal("should have set the custom status", status.CustomEmoji, status.CustomText)
+	}
+
+	if status.CustomExpiresAt < model.GetMillis()+119*60*1000 {
+		t.Fatal("should have set the expiry two hours from now")
+	}
+
+	Client.Must(Client.Command(channel.Id, "/status In a meeting"))
+
+	if status, _ := GetStatus(user.Id); status.CustomEmoji != "" || status.CustomText != "In a meeting" || status.CustomExpiresAt != 0 {
+		t.Fatal("should have replaced the custom status")
+	}
+
+	Client.Must(Client.Command(channel.Id, "/status clear"))
+
+	if status, _ := GetStatus(user.Id); status.GetCustomStatus(model.GetMillis()) != nil {
+		t.Fatal("should have cleared the custom status")
+	}
+}
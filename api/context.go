@@ -47,66 +47,73 @@ type Context struct {
 }
 
 func ApiAppHandler(h func(*Context, http.ResponseWriter, *http.Request)) http.Handler {
-	return &handler{h, false, false, true, false, false, false, false}
+	return &handler{h, false, false, true, false, false, false, false, false}
 }
 
 func AppHandler(h func(*Context, http.ResponseWriter, *http.Request)) http.Handler {
-	return &handler{h, false, false, false, false, false, false, false}
+	return &handler{h, false, false, false, false, false, false, false, false}
 }
 
 func AppHandlerIndependent(h func(*Context, http.ResponseWriter, *http.Request)) http.Handler {
-	return &handler{h, false, false, false, false, true, false, false}
+	return &handler{h, false, false, false, false, true, false, false, false}
 }
 
 func ApiUserRequired(h func(*Context, http.ResponseWriter, *http.Request)) http.Handler {
-	return &handler{h, true, false, true, false, false, false, true}
+	return &handler{h, true, false, true, false, false, false, true, false}
 }
 
 func ApiUserRequiredActivity(h func(*Context, http.ResponseWriter, *http.Request), isUserActivity bool) http.Handler {
-	return &handler{h, true, false, true, isUserActivity, false, false, true}
+	return &handler{h, true, false, true, isUserActivity, false, false, true, false}
 }
 
 func ApiUserRequiredMfa(h func(*Context, http.ResponseWriter, *http.Request)) http.Handler {
-	return &handler{h, true, false, true, false, false, false, false}
+	return &handler{h, true, false, true, false, false, false, false, false}
+}
+
+// ApiUserRequiredExpiredPassword is like ApiUserRequired but still allows a user whose password expired, so that
+// they can change it
+func ApiUserRequiredExpiredPassword(h func(*Context, http.ResponseWriter, *http.Request)) http.Handler {
+	return &handler{h, true, false, true, false, false, false, true, true}
 }
 
 func UserRequired(h func(*Context, http.ResponseWriter, *http.Request)) http.Handler {
-	return &handler{h, true, false, false, false, false, false, true}
+	return &handler{h, true, false, false, false, false, false, true, false}
 }
 
 func AppHandlerTrustRequester(h func(*Context, http.ResponseWriter, *http.Request)) http.Handler {
-	return &handler{h, false, false, false, false, false, true, false}
+	return &handler{h, false, false, false, false, false, true, false, false}
 }
 
 func ApiAdminSystemRequired(h func(*Context, http.ResponseWriter, *http.Request)) http.Handler {
-	return &handler{h, true, true, true, false, false, false, true}
+	return &handler{h, true, true, true, false, false, false, true, false}
 }
 
 func ApiAdminSystemRequiredTrustRequester(h func(*Context, http.ResponseWriter, *http.Request)) http.Handler {
-	return &handler{h, true, true, true, false, false, true, true}
+	return &handler{h, true, true, true, false, false, true, true, false}
 }
 
 func ApiAppHandlerTrustRequester(h func(*Context, http.ResponseWriter, *http.Request)) http.Handler {
-	return &handler{h, false, false, true, false, false, true, false}
+	return &handler{h, false, false, true, false, false, true, false, false}
 }
 
 func ApiUserRequiredTrustRequester(h func(*Context, http.ResponseWriter, *http.Request)) http.Handler {
-	return &handler{h, true, false, true, false, false, true, true}
+	return &handler{h, true, false, true, false, false, true, true, false}
 }
 
 func ApiAppHandlerTrustRequesterIndependent(h func(*Context, http.ResponseWriter, *http.Request)) http.Handler {
-	return &handler{h, false, false, true, false, true, true, false}
+	return &handler{h, false, false, true, false, true, true, false, false}
 }
 
 type handler struct {
-	handleFunc         func(*Context, http.ResponseWriter, *http.Request)
-	requireUser        bool
-	requireSystemAdmin bool
-	isApi              bool
-	isUserActivity     bool
-	isTeamIndependent  bool
-	trustRequester     bool
-	requireMfa         bool
+	handleFunc           func(*Context, http.ResponseWriter, *http.Request)
+	requireUser          bool
+	requireSystemAdmin   bool
+	isApi                bool
+	isUserActivity       bool
+	isTeamIndependent    bool
+	trustRequester       bool
+	requireMfa           bool
+	allowExpiredPassword bool
 }
 
 func (h handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -191,6 +198,12 @@ func (h handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		} else if !session.IsOAuth && isTokenFromQueryString {
 			c.Err = model.NewLocAppError("ServeHTTP", "api.context.token_provided.app_error", nil, "token="+token)
 			c.Err.StatusCode = http.StatusUnauthorized
+		} else if IsSessionIdle(session) {
+			c.LogAuditWithUserId(session.UserId, "session idle timeout session_id="+session.Id)
+			c.RemoveSessionCookie(w, r)
+			RevokeSessionById(c, session.Id)
+			c.Err = model.NewLocAppError("ServeHTTP", "api.context.session_idle.app_error", nil, "session_id="+session.Id)
+			c.Err.StatusCode = http.StatusUnauthorized
 		} else {
 			c.Session = *session
 		}
@@ -213,6 +226,12 @@ func (h handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		c.MfaRequired()
 	}
 
+	// the routes that are exempt from MFA enforcement are the ones needed to fix the account, so they're
+	// exempt from changing an expired password as well
+	if c.Err == nil && h.requireMfa && !h.allowExpiredPassword {
+		c.PasswordChangeRequired()
+	}
+
 	if c.Err == nil && h.requireSystemAdmin {
 		c.SystemAdminRequired()
 	}
@@ -221,6 +240,11 @@ func (h handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		SetStatusOnline(c.Session.UserId, c.Session.Id, false)
 	}
 
+	// requests that the client makes on its own in the background don't keep the session from idling out
+	if c.Err == nil && h.isUserActivity && token != "" && len(c.Session.Id) > 0 {
+		UpdateSessionActivity(&c.Session)
+	}
+
 	if c.Err == nil && (h.requireUser || h.requireSystemAdmin) {
 		//check if teamId exist
 		c.CheckTeamId()
@@ -347,7 +371,7 @@ func (c *Context) UserRequired() {
 
 func (c *Context) MfaRequired() {
 	// Must be licensed for MFA and have it configured for enforcement
-	if !utils.IsLicensed || !*utils.License.Features.MFA || !*utils.Cfg.ServiceSettings.EnableMultifactorAuthentication || !*utils.Cfg.ServiceSettings.EnforceMultifactorAuthentication {
+	if !isMfaAvailable() || (!*utils.Cfg.ServiceSettings.EnforceMultifactorAuthentication && !*utils.Cfg.ServiceSettings.EnforceSecurityKeysForAdmins) {
 		return
 	}
 
@@ -370,14 +394,47 @@ func (c *Context) MfaRequired() {
 			return
 		}
 
-		if !user.MfaActive {
-			c.Err = model.NewLocAppError("", "api.context.mfa_required.app_error", nil, "MfaRequired")
+		securityKeyRequired := isSecurityKeyRequired(user)
+		if user.MfaActive && !securityKeyRequired {
+			return
+		}
+
+		if !securityKeyRequired && !*utils.Cfg.ServiceSettings.EnforceMultifactorAuthentication {
+			return
+		}
+
+		if credentials, err := GetWebAuthnCredentials(user.Id); err != nil {
+			c.Err = err
+			return
+		} else if len(credentials) == 0 {
+			if securityKeyRequired {
+				c.Err = model.NewLocAppError("", "api.context.security_key_required.app_error", nil, "MfaRequired")
+			} else {
+				c.Err = model.NewLocAppError("", "api.context.mfa_required.app_error", nil, "MfaRequired")
+			}
 			c.Err.StatusCode = http.StatusUnauthorized
 			return
 		}
 	}
 }
 
+// PasswordChangeRequired blocks sessions that were created with an expired password until the password is changed
+func (c *Context) PasswordChangeRequired() {
+	if *utils.Cfg.PasswordSettings.MaximumAgeInDays <= 0 || c.Session.Props[model.SESSION_PROP_PASSWORD_EXPIRED] != "true" {
+		return
+	}
+
+	if result := <-Srv.Store.User().GetProfileByIds([]string{c.Session.UserId}, true); result.Err != nil {
+		c.Err = model.NewLocAppError("", "api.context.session_expired.app_error", nil, "PasswordChangeRequired")
+		c.Err.StatusCode = http.StatusUnauthorized
+		return
+	} else if user, ok := result.Data.(map[string]*model.User)[c.Session.UserId]; !ok || user.LastPasswordUpdate < c.Session.CreateAt {
+		c.Err = model.NewLocAppError("", "api.context.password_expired.app_error", nil, "PasswordChangeRequired")
+		c.Err.StatusCode = http.StatusForbidden
+		return
+	}
+}
+
 func (c *Context) SystemAdminRequired() {
 	if len(c.Session.UserId) == 0 {
 		c.Err = model.NewLocAppError("", "api.context.session_expired.app_error", nil, "SystemAdminRequired")
@@ -574,6 +631,43 @@ func AddSessionToCache(session *model.Session) {
 	sessionCache.AddWithExpiresInSecs(session.Token, session, int64(*utils.Cfg.ServiceSettings.SessionCacheInMinutes*60))
 }
 
+// IsSessionIdle returns true if the session has been unused for longer than the configured idle timeout. The cached
+// copy of a session can be behind when the user is active through another server so the database is checked before
+// giving up on it.
+func IsSessionIdle(session *model.Session) bool {
+	timeout := *utils.Cfg.ServiceSettings.SessionIdleTimeoutInMinutes
+	if !session.IsIdle(timeout) {
+		return false
+	}
+
+	if result := <-Srv.Store.Session().Get(session.Id); result.Err == nil {
+		if stored := result.Data.(*model.Session); !stored.IsIdle(timeout) {
+			AddSessionToCache(stored)
+			return false
+		}
+	}
+
+	return true
+}
+
+// UpdateSessionActivity records that the session was just used so that it doesn't hit the idle timeout
+func UpdateSessionActivity(session *model.Session) {
+	now := model.GetMillis()
+	if now-session.LastActivityAt < model.SESSION_ACTIVITY_UPDATE_TIME {
+		return
+	}
+
+	// replace the cached session instead of modifying it since other requests may be reading it
+	updated := *session
+	updated.LastActivityAt = now
+	AddSessionToCache(&updated)
+	session.LastActivityAt = now
+
+	if result := <-Srv.Store.Session().UpdateLastActivityAt(session.Id, now); result.Err != nil {
+		l4g.Error(utils.T("api.context.session_activity.error"), session.Id, result.Err)
+	}
+}
+
 func InvalidateAllCaches() {
 	l4g.Info(utils.T("api.context.invalidate_all_caches"))
 	sessionCache.Purge()
@@ -581,6 +675,9 @@ func InvalidateAllCaches() {
 	store.ClearChannelCaches()
 	store.ClearUserCaches()
 	store.ClearPostCaches()
+	store.ClearRoleCaches()
+	store.ClearSchemeCaches()
+	store.ClearProfileAttributeCaches()
 }
 
 func (c *Context) CheckTeamId() {
@@ -0,0 +1,362 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api
+
+import (
+	"database/sql"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	l4g "github.com/alecthomas/log4go"
+	"github.com/nicksnyder/go-i18n/i18n"
+
+	"github.com/mattermost/platform/model"
+	"github.com/mattermost/platform/utils"
+)
+
+const (
+	DIGEST_TASK_NAME  = "Digest Emails"
+	DIGEST_LEASE_NAME = "DigestEmailsLease"
+
+	DIGEST_CHECK_INTERVAL     = 5 * time.Minute
+	DIGEST_MAX_ITEMS          = 10
+	DIGEST_TOP_CHANNELS       = 5
+	DIGEST_FLAGGED_POST_LIMIT = 200
+)
+
+// digestChannel is a channel with unread activity. Count is the number of mentions for channels listed under
+// mentions and the number of unread messages otherwise.
+type digestChannel struct {
+	channel  *model.Channel
+	name     string
+	teamName string
+	count    int64
+}
+
+type digestPost struct {
+	post     *model.Post
+	teamName string
+}
+
+// digest is the unread activity of a user since their last digest email
+type digest struct {
+	unreadCount    int64
+	mentions       []*digestChannel
+	directMessages []*digestChannel
+	activeChannels []*digestChannel
+	flaggedPosts   []*digestPost
+}
+
+func (d *digest) isEmpty() bool {
+	return len(d.mentions) == 0 && len(d.directMessages) == 0 && len(d.activeChannels) == 0 && len(d.flaggedPosts) == 0
+}
+
+type digestSectionItem struct {
+	Name   string
+	Detail string
+	Link   string
+}
+
+type digestSection struct {
+	Title string
+	Items []*digestSectionItem
+}
+
+// StartDigestJob starts the task that emails users who have opted in to digests when theirs are due. Only
+// one server in a cluster sends digests at a time.
+func StartDigestJob() {
+	model.CreateRecurringTask(DIGEST_TASK_NAME, SendDueDigests, DIGEST_CHECK_INTERVAL)
+}
+
+func SendDueDigests() {
+	if !utils.Cfg.EmailSettings.SendEmailNotifications {
+		return
+	}
+
+	if !acquireLease(DIGEST_LEASE_NAME, 2*DIGEST_CHECK_INTERVAL) {
+		return
+	}
+
+	now := time.Now()
+
+	for _, frequency := range []string{model.DIGEST_FREQUENCY_DAILY, model.DIGEST_FREQUENCY_WEEKLY} {
+		if result := <-Srv.Store.User().GetByNotifyProp(model.DIGEST_FREQUENCY_NOTIFY_PROP, frequency); result.Err != nil {
+			l4g.Error(utils.T("api.digest.send_due_digests.get_users.error"), result.Err)
+		} else {
+			for _, user := range result.Data.([]*model.User) {
+				sendDigestIfDue(user, now, sendDigestEmail)
+			}
+		}
+	}
+}
+
+// sendDigestIfDue passes the user's digest to send if one is due and there's something to tell them about.
+// The send function is passed in so that it can be tested without sending email.
+func sendDigestIfDue(user *model.User, now time.Time, send func(*model.User, *model.DigestSchedule, *digest)) {
+	schedule, err := model.DigestScheduleFromNotifyProps(user.NotifyProps)
+	if err != nil {
+		l4g.Warn(utils.T("api.digest.send_digest.schedule.warn"), user.Id, err)
+		return
+	} else if schedule == nil {
+		return
+	}
+
+	var lastSent int64
+	if result := <-Srv.Store.Preference().Get(user.Id, model.PREFERENCE_CATEGORY_NOTIFICATIONS, model.PREFERENCE_NAME_DIGEST_LAST_SENT); result.Err != nil && result.Err.DetailedError != sql.ErrNoRows.Error() {
+		l4g.Error(utils.T("api.digest.send_digest.last_sent.error"), user.Id, result.Err)
+		return
+	} else if result.Err == nil {
+		lastSent, _ = strconv.ParseInt(result.Data.(model.Preference).Value, 10, 64)
+	}
+
+	// the first digest covers activity from when the user opted in rather than everything they haven't read
+	if lastSent != 0 {
+		if !schedule.IsDue(lastSent, now) {
+			return
+		}
+
+		if digest, err := getDigest(user, lastSent); err != nil {
+			l4g.Error(utils.T("api.digest.send_digest.get_digest.error"), user.Id, err)
+			return
+		} else if !digest.isEmpty() {
+			send(user, schedule, digest)
+		}
+	}
+
+	if result := <-Srv.Store.Preference().Save(&model.Preferences{{
+		UserId:   user.Id,
+		Category: model.PREFERENCE_CATEGORY_NOTIFICATIONS,
+		Name:     model.PREFERENCE_NAME_DIGEST_LAST_SENT,
+		Value:    strconv.FormatInt(now.UnixNano()/int64(time.Millisecond), 10),
+	}}); result.Err != nil {
+		l4g.Error(utils.T("api.digest.send_digest.save_last_sent.error"), user.Id, result.Err)
+	}
+}
+
+// getDigest collects the channels where the user has unread mentions, direct messages or messages and the
+// flagged posts that have been edited or replied to since the given time
+func getDigest(user *model.User, since int64) (*digest, *model.AppError) {
+	d := &digest{}
+
+	var teams []*model.Team
+	if result := <-Srv.Store.Team().GetTeamsByUserId(user.Id); result.Err != nil {
+		return nil, result.Err
+	} else {
+		teams = result.Data.([]*model.Team)
+	}
+
+	teamNames := make(map[string]string)
+	seen := make(map[string]bool)
+
+	for _, team := range teams {
+		teamNames[team.Id] = team.Name
+
+		cchan := Srv.Store.Channel().GetChannels(team.Id, user.Id)
+		mchan := Srv.Store.Channel().GetMembersForUser(team.Id, user.Id)
+		ccchan := Srv.Store.Channel().GetChannelCounts(team.Id, user.Id)
+
+		var channels *model.ChannelList
+		if result := <-cchan; result.Err != nil {
+			// the user isn't in any channels on this team
+			<-mchan
+			<-ccchan
+			continue
+		} else {
+			channels = result.Data.(*model.ChannelList)
+		}
+
+		var members *model.ChannelMembers
+		if result := <-mchan; result.Err != nil {
+			<-ccchan
+			return nil, result.Err
+		} else {
+			members = result.Data.(*model.ChannelMembers)
+		}
+
+		var counts *model.ChannelCounts
+		if result := <-ccchan; result.Err != nil {
+			return nil, result.Err
+		} else {
+			counts = result.Data.(*model.ChannelCounts)
+		}
+
+		membersByChannel := make(map[string]*model.ChannelMember)
+		for i := range *members {
+			membersByChannel[(*members)[i].ChannelId] = &(*members)[i]
+		}
+
+		for _, channel := range *channels {
+			member := membersByChannel[channel.Id]
+
+			// direct channels are listed for every team
+			if member == nil || seen[channel.Id] || channel.LastPostAt <= since {
+				continue
+			}
+			seen[channel.Id] = true
+
+			unread := counts.Counts[channel.Id] - member.MsgCount
+
+			if channel.Type == model.CHANNEL_DIRECT {
+				if unread > 0 {
+					d.directMessages = append(d.directMessages, &digestChannel{
+						channel:  channel,
+						name:     getDirectChannelName(channel, user.Id),
+						teamName: team.Name,
+						count:    unread,
+					})
+				}
+			} else {
+				if member.MentionCount > 0 {
+					d.mentions = append(d.mentions, &digestChannel{channel: channel, name: channel.DisplayName, teamName: team.Name, count: member.MentionCount})
+				}
+
+				if unread > 0 {
+					d.activeChannels = append(d.activeChannels, &digestChannel{channel: channel, name: channel.DisplayName, teamName: team.Name, count: unread})
+				}
+			}
+		}
+	}
+
+	sort.Sort(digestChannelsByActivity(d.mentions))
+	sort.Sort(digestChannelsByActivity(d.directMessages))
+	sort.Sort(digestChannelsByActivity(d.activeChannels))
+
+	if len(d.mentions) > DIGEST_MAX_ITEMS {
+		d.mentions = d.mentions[:DIGEST_MAX_ITEMS]
+	}
+
+	if len(d.directMessages) > DIGEST_MAX_ITEMS {
+		d.directMessages = d.directMessages[:DIGEST_MAX_ITEMS]
+	}
+
+	if len(d.activeChannels) > DIGEST_TOP_CHANNELS {
+		d.activeChannels = d.activeChannels[:DIGEST_TOP_CHANNELS]
+	}
+
+	if len(teams) > 0 {
+		if result := <-Srv.Store.Post().GetFlaggedPosts(user.Id, 0, DIGEST_FLAGGED_POST_LIMIT); result.Err != nil {
+			return nil, result.Err
+		} else {
+			list := result.Data.(*model.PostList)
+
+			for _, id := range list.Order {
+				post := list.Posts[id]
+
+				// replies to a post also update it
+				if post.UpdateAt <= since || post.DeleteAt != 0 {
+					continue
+				}
+
+				teamName := teams[0].Name
+				if result := <-Srv.Store.Channel().Get(post.ChannelId); result.Err == nil {
+					if name, ok := teamNames[result.Data.(*model.Channel).TeamId]; ok {
+						teamName = name
+					}
+				}
+
+				d.flaggedPosts = append(d.flaggedPosts, &digestPost{post: post, teamName: teamName})
+
+				if len(d.flaggedPosts) >= DIGEST_MAX_ITEMS {
+					break
+				}
+			}
+		}
+	}
+
+	if result := <-Srv.Store.User().GetUnreadCount(user.Id); result.Err != nil {
+		return nil, result.Err
+	} else {
+		d.unreadCount = result.Data.(int64)
+	}
+
+	return d, nil
+}
+
+func getDirectChannelName(channel *model.Channel, userId string) string {
+	for _, id := range strings.Split(channel.Name, "__") {
+		if id == userId {
+			continue
+		}
+
+		if result := <-Srv.Store.User().Get(id); result.Err == nil {
+			return "@" + result.Data.(*model.User).Username
+		}
+	}
+
+	return channel.DisplayName
+}
+
+// digestChannelsByActivity sorts channels with the most activity first
+type digestChannelsByActivity []*digestChannel
+
+func (c digestChannelsByActivity) Len() int      { return len(c) }
+func (c digestChannelsByActivity) Swap(i, j int) { c[i], c[j] = c[j], c[i] }
+func (c digestChannelsByActivity) Less(i, j int) bool {
+	if c[i].count != c[j].count {
+		return c[i].count > c[j].count
+	}
+
+	return c[i].channel.LastPostAt > c[j].channel.LastPostAt
+}
+
+func sendDigestEmail(user *model.User, schedule *model.DigestSchedule, d *digest) {
+	translateFunc := utils.GetUserTranslations(user.Locale)
+	siteURL := *utils.Cfg.ServiceSettings.SiteURL
+
+	subject := translateFunc("api.digest.send_digest_email.subject."+schedule.Frequency, map[string]interface{}{"SiteName": utils.Cfg.TeamSettings.SiteName})
+
+	body := utils.NewHTMLTemplate("digest_body", user.Locale)
+	body.Props["SiteURL"] = siteURL
+	body.Props["Title"] = translateFunc("api.digest.send_digest_email.title." + schedule.Frequency)
+	body.Props["BodyText"] = translateFunc("api.digest.send_digest_email.body_text", d.unreadCount, map[string]interface{}{"Count": d.unreadCount})
+	body.Props["Sections"] = getDigestSections(d, siteURL, translateFunc)
+	body.Props["Button"] = translateFunc("api.digest.send_digest_email.button")
+	body.Props["ButtonLink"] = siteURL
+
+	if err := QueueNotificationMail(user.Email, subject, body.Render()); err != nil {
+		l4g.Warn(utils.T("api.digest.send_digest_email.send.warn"), user.Email, err)
+	}
+}
+
+func getDigestSections(d *digest, siteURL string, translateFunc i18n.TranslateFunc) []*digestSection {
+	var sections []*digestSection
+
+	channelSection := func(title string, channels []*digestChannel, detailId string) {
+		if len(channels) == 0 {
+			return
+		}
+
+		section := &digestSection{Title: translateFunc(title)}
+		for _, c := range channels {
+			section.Items = append(section.Items, &digestSectionItem{
+				Name:   c.name,
+				Detail: translateFunc(detailId, c.count, map[string]interface{}{"Count": c.count}),
+				Link:   siteURL + "/" + c.teamName + "/channels/" + c.channel.Name,
+			})
+		}
+
+		sections = append(sections, section)
+	}
+
+	channelSection("api.digest.section.mentions", d.mentions, "api.digest.section.mention_count")
+	channelSection("api.digest.section.direct_messages", d.directMessages, "api.digest.section.message_count")
+	channelSection("api.digest.section.active_channels", d.activeChannels, "api.digest.section.message_count")
+
+	if len(d.flaggedPosts) > 0 {
+		section := &digestSection{Title: translateFunc("api.digest.section.flagged_posts")}
+		for _, p := range d.flaggedPosts {
+			section.Items = append(section.Items, &digestSectionItem{
+				Name:   getMessageForNotification(p.post, translateFunc),
+				Detail: translateFunc("api.digest.section.flagged_post_updated"),
+				Link:   siteURL + "/" + p.teamName + "/pl/" + p.post.Id,
+			})
+		}
+
+		sections = append(sections, section)
+	}
+
+	return sections
+}
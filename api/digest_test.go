@@ -0,0 +1,106 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/mattermost/platform/model"
+	"github.com/mattermost/platform/store"
+)
+
+func TestSendDigestIfDue(t *testing.T) {
+	th := Setup().InitBasic()
+
+	user := th.BasicUser
+	user.NotifyProps = model.StringMap{
+		model.DIGEST_FREQUENCY_NOTIFY_PROP: model.DIGEST_FREQUENCY_DAILY,
+		model.DIGEST_TIME_NOTIFY_PROP:      "08:00",
+		model.DIGEST_TIMEZONE_NOTIFY_PROP:  "UTC",
+	}
+
+	var sent *digest
+	send := func(u *model.User, schedule *model.DigestSchedule, d *digest) {
+		sent = d
+	}
+
+	// the first check only records when the user started receiving digests
+	sendDigestIfDue(user, time.Now(), send)
+
+	if sent != nil {
+		t.Fatal("shouldn't have sent a digest before the first one is due")
+	}
+
+	if result := <-Srv.Store.Preference().Get(user.Id, model.PREFERENCE_CATEGORY_NOTIFICATIONS, model.PREFERENCE_NAME_DIGEST_LAST_SENT); result.Err != nil {
+		t.Fatal("should have saved when the digest was last sent", result.Err)
+	}
+
+	lastSent := model.GetMillis() - 2*24*60*60*1000
+	store.Must(Srv.Store.Preference().Save(&model.Preferences{{
+		UserId:   user.Id,
+		Category: model.PREFERENCE_CATEGORY_NOTIFICATIONS,
+		Name:     model.PREFERENCE_NAME_DIGEST_LAST_SENT,
+		Value:    strconv.FormatInt(lastSent, 10),
+	}}))
+
+	store.Must(Srv.Store.Post().Save(&model.Post{ChannelId: th.BasicChannel.Id, UserId: th.BasicUser2.Id, Message: "@" + user.Username}))
+	store.Must(Srv.Store.Channel().IncrementMentionCount(th.BasicChannel.Id, user.Id))
+
+	dm, err := CreateDirectChannel(user.Id, th.BasicUser2.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Must(Srv.Store.Post().Save(&model.Post{ChannelId: dm.Id, UserId: th.BasicUser2.Id, Message: "hello"}))
+
+	store.Must(Srv.Store.Preference().Save(&model.Preferences{{
+		UserId:   user.Id,
+		Category: model.PREFERENCE_CATEGORY_FLAGGED_POST,
+		Name:     th.BasicPost.Id,
+		Value:    "true",
+	}}))
+	store.Must(Srv.Store.Post().Save(&model.Post{ChannelId: th.BasicChannel.Id, UserId: th.BasicUser2.Id, RootId: th.BasicPost.Id, Message: "reply"}))
+
+	sendDigestIfDue(user, time.Now(), send)
+
+	if sent == nil {
+		t.Fatal("should have sent a digest")
+	}
+
+	if len(sent.mentions) != 1 || sent.mentions[0].channel.Id != th.BasicChannel.Id || sent.mentions[0].count != 1 {
+		t.Fatal("should have listed the mention", sent.mentions)
+	}
+
+	if len(sent.directMessages) != 1 || sent.directMessages[0].channel.Id != dm.Id || sent.directMessages[0].name != "@"+th.BasicUser2.Username {
+		t.Fatal("should have listed the direct message", sent.directMessages)
+	}
+
+	if len(sent.activeChannels) != 1 || sent.activeChannels[0].channel.Id != th.BasicChannel.Id {
+		t.Fatal("should have listed the active channel", sent.activeChannels)
+	}
+
+	if len(sent.flaggedPosts) != 1 || sent.flaggedPosts[0].post.Id != th.BasicPost.Id || sent.flaggedPosts[0].teamName != th.BasicTeam.Name {
+		t.Fatal("should have listed the flagged post", sent.flaggedPosts)
+	}
+
+	if sections := getDigestSections(sent, "http://localhost", func(id string, args ...interface{}) string { return id }); len(sections) != 4 {
+		t.Fatal("should have rendered a section for each kind of activity", len(sections))
+	}
+
+	// the next digest isn't due until tomorrow
+	sent = nil
+	sendDigestIfDue(user, time.Now(), send)
+
+	if sent != nil {
+		t.Fatal("shouldn't have sent another digest")
+	}
+
+	user.NotifyProps[model.DIGEST_FREQUENCY_NOTIFY_PROP] = model.DIGEST_FREQUENCY_NEVER
+	sendDigestIfDue(user, time.Now().Add(48*time.Hour), send)
+
+	if sent != nil {
+		t.Fatal("shouldn't have sent a digest after opting out")
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/mattermost/platform/model"
+	"github.com/mattermost/platform/store"
 	"github.com/mattermost/platform/utils"
 
 	l4g "github.com/alecthomas/log4go"
@@ -18,33 +19,48 @@ import (
 )
 
 const (
-	EMAIL_BATCHING_TASK_NAME = "Email Batching"
+	EMAIL_BATCHING_TASK_NAME  = "Email Batching"
+	EMAIL_BATCHING_LEASE_NAME = "EmailBatchingLease"
 )
 
-var emailBatchingJob *EmailBatchingJob
-
+// InitEmailBatching starts, restarts or stops the job that sends batched email notifications. It's called
+// again whenever the config changes so that a new interval takes effect right away.
 func InitEmailBatching() {
-	if *utils.Cfg.EmailSettings.EnableEmailBatching {
-		if emailBatchingJob == nil {
-			emailBatchingJob = MakeEmailBatchingJob(*utils.Cfg.EmailSettings.EmailBatchingBufferSize)
-		}
-
-		// note that we don't support changing EmailBatchingBufferSize without restarting the server
+	if task := model.GetTaskByName(EMAIL_BATCHING_TASK_NAME); task != nil {
+		task.Cancel()
+	}
 
-		emailBatchingJob.Start()
+	if *utils.Cfg.EmailSettings.EnableEmailBatching {
+		l4g.Debug(utils.T("api.email_batching.start.starting"), *utils.Cfg.EmailSettings.EmailBatchingInterval)
+		model.CreateRecurringTask(EMAIL_BATCHING_TASK_NAME, CheckPendingEmails, time.Duration(*utils.Cfg.EmailSettings.EmailBatchingInterval)*time.Second)
 	}
 }
 
+// AddNotificationEmailToBatch queues a notification email to be sent with the user's next batch. Pending
+// notifications are saved in the database so they aren't lost on a restart and can be sent by any server.
 func AddNotificationEmailToBatch(user *model.User, post *model.Post, team *model.Team) *model.AppError {
 	if !*utils.Cfg.EmailSettings.EnableEmailBatching {
 		return model.NewLocAppError("AddNotificationEmailToBatch", "api.email_batching.add_notification_email_to_batch.disabled.app_error", nil, "")
 	}
 
-	if !emailBatchingJob.Add(user, post, team) {
+	if result := <-Srv.Store.PendingEmailNotification().Count(); result.Err != nil {
+		return result.Err
+	} else if result.Data.(int64) >= int64(*utils.Cfg.EmailSettings.EmailBatchingBufferSize) {
+		// return an error if we couldn't queue the email notification so that we can send an immediate email
 		l4g.Error(utils.T("api.email_batching.add_notification_email_to_batch.channel_full.app_error"))
 		return model.NewLocAppError("AddNotificationEmailToBatch", "api.email_batching.add_notification_email_to_batch.channel_full.app_error", nil, "")
 	}
 
+	notification := &model.PendingEmailNotification{
+		UserId:   user.Id,
+		PostId:   post.Id,
+		TeamName: team.Name,
+	}
+
+	if result := <-Srv.Store.PendingEmailNotification().Save(notification); result.Err != nil {
+		return result.Err
+	}
+
 	return nil
 }
 
@@ -52,78 +68,87 @@ type batchedNotification struct {
 	userId   string
 	post     *model.Post
 	teamName string
+	queuedAt int64
 }
 
-type EmailBatchingJob struct {
-	newNotifications     chan *batchedNotification
-	pendingNotifications map[string][]*batchedNotification
-}
-
-func MakeEmailBatchingJob(bufferSize int) *EmailBatchingJob {
-	return &EmailBatchingJob{
-		newNotifications:     make(chan *batchedNotification, bufferSize),
-		pendingNotifications: make(map[string][]*batchedNotification),
+// CheckPendingEmails sends the batches that are due. Only the server holding the email batching lease sends
+// them so that each notification is sent once in a cluster. The lease outlasts the interval so that it's
+// renewed by the next run.
+func CheckPendingEmails() {
+	interval := time.Duration(*utils.Cfg.EmailSettings.EmailBatchingInterval) * time.Second
+	if !acquireLease(EMAIL_BATCHING_LEASE_NAME, 2*interval) {
+		return
 	}
-}
 
-func (job *EmailBatchingJob) Start() {
-	if task := model.GetTaskByName(EMAIL_BATCHING_TASK_NAME); task != nil {
-		task.Cancel()
-	}
+	// it's a bit weird to pass the send email function through here, but it makes it so that we can test
+	// without actually sending emails
+	pending := checkPendingNotifications(time.Now(), sendBatchedEmailNotification)
 
-	l4g.Debug(utils.T("api.email_batching.start.starting"), *utils.Cfg.EmailSettings.EmailBatchingInterval)
-	model.CreateRecurringTask(EMAIL_BATCHING_TASK_NAME, job.CheckPendingEmails, time.Duration(*utils.Cfg.EmailSettings.EmailBatchingInterval)*time.Second)
+	l4g.Debug(utils.T("api.email_batching.check_pending_emails.finished_running"), pending)
 }
 
-func (job *EmailBatchingJob) Add(user *model.User, post *model.Post, team *model.Team) bool {
-	notification := &batchedNotification{
-		userId:   user.Id,
-		post:     post,
-		teamName: team.Name,
+// getPendingNotifications loads the queued notifications grouped by the user receiving them in the order
+// they were queued. Notifications for posts that have since been deleted are kept without a post so that
+// they're removed along with the rest of the user's batch.
+func getPendingNotifications() (map[string][]*batchedNotification, *model.AppError) {
+	var stored []*model.PendingEmailNotification
+	if result := <-Srv.Store.PendingEmailNotification().GetAll(); result.Err != nil {
+		return nil, result.Err
+	} else {
+		stored = result.Data.([]*model.PendingEmailNotification)
 	}
 
-	select {
-	case job.newNotifications <- notification:
-		return true
-	default:
-		// return false if we couldn't queue the email notification so that we can send an immediate email
-		return false
+	pchans := make([]store.StoreChannel, len(stored))
+	for i, notification := range stored {
+		pchans[i] = Srv.Store.Post().Get(notification.PostId)
 	}
-}
 
-func (job *EmailBatchingJob) CheckPendingEmails() {
-	job.handleNewNotifications()
+	notifications := make(map[string][]*batchedNotification)
+	for i, notification := range stored {
+		var post *model.Post
+		if result := <-pchans[i]; result.Err == nil {
+			post = result.Data.(*model.PostList).Posts[notification.PostId]
+		}
 
-	// it's a bit weird to pass the send email function through here, but it makes it so that we can test
-	// without actually sending emails
-	job.checkPendingNotifications(time.Now(), sendBatchedEmailNotification)
+		notifications[notification.UserId] = append(notifications[notification.UserId], &batchedNotification{
+			userId:   notification.UserId,
+			post:     post,
+			teamName: notification.TeamName,
+			queuedAt: notification.CreateAt,
+		})
+	}
 
-	l4g.Debug(utils.T("api.email_batching.check_pending_emails.finished_running"), len(job.pendingNotifications))
+	return notifications, nil
 }
 
-func (job *EmailBatchingJob) handleNewNotifications() {
-	receiving := true
+// checkPendingNotifications sends the batches that are due and returns the number of users still waiting
+// on one
+func checkPendingNotifications(now time.Time, handler func(string, []*batchedNotification)) int {
+	pendingNotifications, err := getPendingNotifications()
+	if err != nil {
+		l4g.Error(utils.T("api.email_batching.check_pending_emails.get_pending.app_error"), err)
+		return 0
+	}
 
-	// read in new notifications to send
-	for receiving {
-		select {
-		case notification := <-job.newNotifications:
-			userId := notification.userId
+	waiting := 0
 
-			if _, ok := job.pendingNotifications[userId]; !ok {
-				job.pendingNotifications[userId] = []*batchedNotification{notification}
-			} else {
-				job.pendingNotifications[userId] = append(job.pendingNotifications[userId], notification)
+	// look for users who've acted since pending posts were received
+	for userId, queued := range pendingNotifications {
+		// anything queued after this point is left for the next batch
+		before := queued[len(queued)-1].queuedAt
+
+		notifications := make([]*batchedNotification, 0, len(queued))
+		for _, notification := range queued {
+			if notification.post != nil {
+				notifications = append(notifications, notification)
 			}
-		default:
-			receiving = false
 		}
-	}
-}
 
-func (job *EmailBatchingJob) checkPendingNotifications(now time.Time, handler func(string, []*batchedNotification)) {
-	// look for users who've acted since pending posts were received
-	for userId, notifications := range job.pendingNotifications {
+		if len(notifications) == 0 {
+			deletePendingNotifications(userId, before)
+			continue
+		}
+
 		schan := Srv.Store.Status().Get(userId)
 		pchan := Srv.Store.Preference().Get(userId, model.PREFERENCE_CATEGORY_NOTIFICATIONS, model.PREFERENCE_NAME_EMAIL_INTERVAL)
 		batchStartTime := notifications[0].post.CreateAt
@@ -131,10 +156,10 @@ func (job *EmailBatchingJob) checkPendingNotifications(now time.Time, handler fu
 		// check if the user has been active and would've seen any new posts
 		if result := <-schan; result.Err != nil {
 			l4g.Error(utils.T("api.email_batching.check_pending_emails.status.app_error"), result.Err)
-			delete(job.pendingNotifications, userId)
+			deletePendingNotifications(userId, before)
 			continue
 		} else if status := result.Data.(*model.Status); status.LastActivityAt >= batchStartTime {
-			delete(job.pendingNotifications, userId)
+			deletePendingNotifications(userId, before)
 			continue
 		}
 
@@ -155,10 +180,25 @@ func (job *EmailBatchingJob) checkPendingNotifications(now time.Time, handler fu
 
 		// send the email notification if it's been long enough
 		if now.Sub(time.Unix(batchStartTime/1000, 0)) > time.Duration(interval)*time.Second {
-			go handler(userId, notifications)
-			delete(job.pendingNotifications, userId)
+			// remove the batch before sending it so that it isn't sent twice if this server stops in between
+			if deletePendingNotifications(userId, before) {
+				go handler(userId, notifications)
+			}
+		} else {
+			waiting++
 		}
 	}
+
+	return waiting
+}
+
+func deletePendingNotifications(userId string, before int64) bool {
+	if result := <-Srv.Store.PendingEmailNotification().DeleteForUser(userId, before); result.Err != nil {
+		l4g.Error(utils.T("api.email_batching.check_pending_emails.delete.app_error"), userId, result.Err)
+		return false
+	}
+
+	return true
 }
 
 func sendBatchedEmailNotification(userId string, notifications []*batchedNotification) {
@@ -207,7 +247,7 @@ func sendBatchedEmailNotification(userId string, notifications []*batchedNotific
 	body.Props["Posts"] = template.HTML(contents)
 	body.Props["BodyText"] = translateFunc("api.email_batching.send_batched_email_notification.body_text", len(notifications))
 
-	if err := utils.SendMail(user.Email, subject, body.Render()); err != nil {
+	if err := QueueNotificationMail(user.Email, subject, body.Render()); err != nil {
 		l4g.Warn(utils.T("api.email_batchings.send_batched_email_notification.send.app_error"), user.Email, err)
 	}
 }
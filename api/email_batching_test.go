@@ -9,102 +9,65 @@ import (
 
 	"github.com/mattermost/platform/model"
 	"github.com/mattermost/platform/store"
+	"github.com/mattermost/platform/utils"
 )
 
-func TestHandleNewNotifications(t *testing.T) {
-	Setup()
-
-	id1 := model.NewId()
-	id2 := model.NewId()
-	id3 := model.NewId()
+func getPendingNotificationsForUser(userId string) []*model.PendingEmailNotification {
+	var notifications []*model.PendingEmailNotification
+	for _, notification := range store.Must(Srv.Store.PendingEmailNotification().GetAll()).([]*model.PendingEmailNotification) {
+		if notification.UserId == userId {
+			notifications = append(notifications, notification)
+		}
+	}
 
-	// test queueing of received posts by user
-	job := MakeEmailBatchingJob(128)
+	return notifications
+}
 
-	job.handleNewNotifications()
+func TestAddNotificationEmailToBatch(t *testing.T) {
+	th := Setup().InitBasic()
 
-	if len(job.pendingNotifications) != 0 {
-		t.Fatal("shouldn't have added any pending notifications")
-	}
+	enableEmailBatching := *utils.Cfg.EmailSettings.EnableEmailBatching
+	bufferSize := *utils.Cfg.EmailSettings.EmailBatchingBufferSize
+	defer func() {
+		*utils.Cfg.EmailSettings.EnableEmailBatching = enableEmailBatching
+		*utils.Cfg.EmailSettings.EmailBatchingBufferSize = bufferSize
+	}()
 
-	job.Add(&model.User{Id: id1}, &model.Post{UserId: id1, Message: "test"}, &model.Team{Name: "team"})
-	if len(job.pendingNotifications) != 0 {
-		t.Fatal("shouldn't have added any pending notifications")
+	*utils.Cfg.EmailSettings.EnableEmailBatching = false
+	if err := AddNotificationEmailToBatch(th.BasicUser, th.BasicPost, th.BasicTeam); err == nil {
+		t.Fatal("should have failed with email batching disabled")
 	}
 
-	job.handleNewNotifications()
-	if len(job.pendingNotifications) != 1 {
-		t.Fatal("should have received posts for 1 user")
-	} else if len(job.pendingNotifications[id1]) != 1 {
-		t.Fatal("should have received 1 post for user")
-	}
+	*utils.Cfg.EmailSettings.EnableEmailBatching = true
+	*utils.Cfg.EmailSettings.EmailBatchingBufferSize = int(store.Must(Srv.Store.PendingEmailNotification().Count()).(int64)) + 1
 
-	job.Add(&model.User{Id: id1}, &model.Post{UserId: id1, Message: "test"}, &model.Team{Name: "team"})
-	job.handleNewNotifications()
-	if len(job.pendingNotifications) != 1 {
-		t.Fatal("should have received posts for 1 user")
-	} else if len(job.pendingNotifications[id1]) != 2 {
-		t.Fatal("should have received 2 posts for user1", job.pendingNotifications[id1])
+	if err := AddNotificationEmailToBatch(th.BasicUser, th.BasicPost, th.BasicTeam); err != nil {
+		t.Fatal(err)
 	}
 
-	job.Add(&model.User{Id: id2}, &model.Post{UserId: id1, Message: "test"}, &model.Team{Name: "team"})
-	job.handleNewNotifications()
-	if len(job.pendingNotifications) != 2 {
-		t.Fatal("should have received posts for 2 users")
-	} else if len(job.pendingNotifications[id1]) != 2 {
-		t.Fatal("should have received 2 posts for user1")
-	} else if len(job.pendingNotifications[id2]) != 1 {
-		t.Fatal("should have received 1 post for user2")
+	if notifications := getPendingNotificationsForUser(th.BasicUser.Id); len(notifications) != 1 || notifications[0].PostId != th.BasicPost.Id || notifications[0].TeamName != th.BasicTeam.Name {
+		t.Fatal("should have saved the notification", notifications)
 	}
 
-	job.Add(&model.User{Id: id2}, &model.Post{UserId: id2, Message: "test"}, &model.Team{Name: "team"})
-	job.Add(&model.User{Id: id1}, &model.Post{UserId: id3, Message: "test"}, &model.Team{Name: "team"})
-	job.Add(&model.User{Id: id3}, &model.Post{UserId: id3, Message: "test"}, &model.Team{Name: "team"})
-	job.Add(&model.User{Id: id2}, &model.Post{UserId: id2, Message: "test"}, &model.Team{Name: "team"})
-	job.handleNewNotifications()
-	if len(job.pendingNotifications) != 3 {
-		t.Fatal("should have received posts for 3 users")
-	} else if len(job.pendingNotifications[id1]) != 3 {
-		t.Fatal("should have received 3 posts for user1")
-	} else if len(job.pendingNotifications[id2]) != 3 {
-		t.Fatal("should have received 3 posts for user2")
-	} else if len(job.pendingNotifications[id3]) != 1 {
-		t.Fatal("should have received 1 post for user3")
+	// the buffer size is read each time so it can be changed without a restart
+	if err := AddNotificationEmailToBatch(th.BasicUser, th.BasicPost, th.BasicTeam); err == nil {
+		t.Fatal("should have failed with the buffer full")
 	}
 
-	// test ordering of received posts
-	job = MakeEmailBatchingJob(128)
-
-	job.Add(&model.User{Id: id1}, &model.Post{UserId: id1, Message: "test1"}, &model.Team{Name: "team"})
-	job.Add(&model.User{Id: id1}, &model.Post{UserId: id1, Message: "test2"}, &model.Team{Name: "team"})
-	job.Add(&model.User{Id: id2}, &model.Post{UserId: id1, Message: "test3"}, &model.Team{Name: "team"})
-	job.Add(&model.User{Id: id1}, &model.Post{UserId: id1, Message: "test4"}, &model.Team{Name: "team"})
-	job.Add(&model.User{Id: id2}, &model.Post{UserId: id1, Message: "test5"}, &model.Team{Name: "team"})
-	job.handleNewNotifications()
-	if job.pendingNotifications[id1][0].post.Message != "test1" ||
-		job.pendingNotifications[id1][1].post.Message != "test2" ||
-		job.pendingNotifications[id1][2].post.Message != "test4" {
-		t.Fatal("incorrect order of received posts for user1")
-	} else if job.pendingNotifications[id2][0].post.Message != "test3" ||
-		job.pendingNotifications[id2][1].post.Message != "test5" {
-		t.Fatal("incorrect order of received posts for user2")
-	}
+	store.Must(Srv.Store.PendingEmailNotification().DeleteForUser(th.BasicUser.Id, model.GetMillis()))
 }
 
 func TestCheckPendingNotifications(t *testing.T) {
-	Setup()
-
-	id1 := model.NewId()
-
-	job := MakeEmailBatchingJob(128)
-	job.pendingNotifications[id1] = []*batchedNotification{
-		{
-			post: &model.Post{
-				UserId:   id1,
-				CreateAt: 10000000,
-			},
-		},
-	}
+	th := Setup().InitBasic()
+
+	id1 := th.BasicUser.Id
+
+	post := store.Must(Srv.Store.Post().Save(&model.Post{
+		ChannelId: th.BasicChannel.Id,
+		UserId:    th.BasicUser2.Id,
+		CreateAt:  10000000,
+	})).(*model.Post)
+	store.Must(Srv.Store.PendingEmailNotification().Save(&model.PendingEmailNotification{UserId: id1, PostId: post.Id, TeamName: th.BasicTeam.Name}))
 
 	store.Must(Srv.Store.Status().SaveOrUpdate(&model.Status{
 		UserId:         id1,
@@ -118,9 +81,9 @@ func TestCheckPendingNotifications(t *testing.T) {
 	}}))
 
 	// test that notifications aren't sent before interval
-	job.checkPendingNotifications(time.Unix(10001, 0), func(string, []*batchedNotification) {})
+	checkPendingNotifications(time.Unix(10001, 0), func(string, []*batchedNotification) {})
 
-	if job.pendingNotifications[id1] == nil || len(job.pendingNotifications[id1]) != 1 {
+	if len(getPendingNotificationsForUser(id1)) != 1 {
 		t.Fatal("should'nt have sent queued post")
 	}
 
@@ -130,34 +93,38 @@ func TestCheckPendingNotifications(t *testing.T) {
 		LastActivityAt: 10001000,
 	}))
 
-	job.checkPendingNotifications(time.Unix(10002, 0), func(string, []*batchedNotification) {})
+	checkPendingNotifications(time.Unix(10002, 0), func(string, []*batchedNotification) {})
 
-	if job.pendingNotifications[id1] != nil && len(job.pendingNotifications[id1]) != 0 {
+	if len(getPendingNotificationsForUser(id1)) != 0 {
 		t.Fatal("should've remove queued post since user acted")
 	}
 
 	// test that notifications are sent if enough time passes since the first message
-	job.pendingNotifications[id1] = []*batchedNotification{
-		{
-			post: &model.Post{
-				UserId:   id1,
-				CreateAt: 10060000,
-				Message:  "post1",
-			},
-		},
-		{
-			post: &model.Post{
-				UserId:   id1,
-				CreateAt: 10090000,
-				Message:  "post2",
-			},
-		},
-	}
+	post1 := store.Must(Srv.Store.Post().Save(&model.Post{
+		ChannelId: th.BasicChannel.Id,
+		UserId:    th.BasicUser2.Id,
+		CreateAt:  10060000,
+		Message:   "post1",
+	})).(*model.Post)
+	post2 := store.Must(Srv.Store.Post().Save(&model.Post{
+		ChannelId: th.BasicChannel.Id,
+		UserId:    th.BasicUser2.Id,
+		CreateAt:  10090000,
+		Message:   "post2",
+	})).(*model.Post)
+
+	store.Must(Srv.Store.PendingEmailNotification().Save(&model.PendingEmailNotification{UserId: id1, PostId: post1.Id, TeamName: th.BasicTeam.Name}))
+	time.Sleep(5 * time.Millisecond)
+	store.Must(Srv.Store.PendingEmailNotification().Save(&model.PendingEmailNotification{UserId: id1, PostId: post2.Id, TeamName: th.BasicTeam.Name}))
 
 	received := make(chan *model.Post, 2)
 	timeout := make(chan bool)
 
-	job.checkPendingNotifications(time.Unix(10130, 0), func(s string, notifications []*batchedNotification) {
+	checkPendingNotifications(time.Unix(10130, 0), func(userId string, notifications []*batchedNotification) {
+		if userId != id1 {
+			return
+		}
+
 		for _, notification := range notifications {
 			received <- notification.post
 		}
@@ -169,7 +136,7 @@ func TestCheckPendingNotifications(t *testing.T) {
 		timeout <- true
 	}()
 
-	if job.pendingNotifications[id1] != nil && len(job.pendingNotifications[id1]) != 0 {
+	if len(getPendingNotificationsForUser(id1)) != 0 {
 		t.Fatal("should've remove queued posts when sending messages")
 	}
 
@@ -0,0 +1,210 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	l4g "github.com/alecthomas/log4go"
+
+	"github.com/mattermost/platform/model"
+	"github.com/mattermost/platform/utils"
+)
+
+const (
+	EMAIL_REPLY_MAX_SIZE         = 10 * 1024 * 1024 // 10 MB
+	EMAIL_REPLY_SIGNATURE_LENGTH = 20
+)
+
+var emailReplyServer *utils.InboundMailServer
+
+// StartEmailReplyServer starts listening for replies to notification emails if they're enabled
+func StartEmailReplyServer() {
+	if !*utils.Cfg.EmailSettings.EnableEmailReplies {
+		return
+	}
+
+	l4g.Info(utils.T("api.email_reply.start.info"), *utils.Cfg.EmailSettings.InboundSMTPListenAddress)
+
+	if server, err := utils.StartInboundMailServer(*utils.Cfg.EmailSettings.InboundSMTPListenAddress, EMAIL_REPLY_MAX_SIZE, handleInboundMail); err != nil {
+		l4g.Error(utils.T("api.email_reply.start.error"), err)
+	} else {
+		emailReplyServer = server
+	}
+}
+
+func StopEmailReplyServer() {
+	if emailReplyServer != nil {
+		emailReplyServer.Close()
+		emailReplyServer = nil
+	}
+}
+
+// GetReplyToAddress returns the address that a user replies to when answering an email about a post. Each
+// address contains the post's id and a signature so that replies can't be posted into other threads. It's
+// empty if email replies are disabled.
+func GetReplyToAddress(userId string, postId string) string {
+	if !*utils.Cfg.EmailSettings.EnableEmailReplies {
+		return ""
+	}
+
+	address := *utils.Cfg.EmailSettings.ReplyToAddress
+	at := strings.LastIndex(address, "@")
+	if at <= 0 {
+		return ""
+	}
+
+	return address[:at] + "+" + postId + "." + getEmailReplySignature(userId, postId) + address[at:]
+}
+
+func getEmailReplySignature(userId string, postId string) string {
+	mac := hmac.New(sha256.New, []byte(*utils.Cfg.EmailSettings.ReplyToSalt))
+	mac.Write([]byte(userId + ":" + postId))
+
+	return hex.EncodeToString(mac.Sum(nil))[:EMAIL_REPLY_SIGNATURE_LENGTH]
+}
+
+// parseReplyToAddress returns the post id and signature from an address made by GetReplyToAddress
+func parseReplyToAddress(address string) (string, string, bool) {
+	address = strings.ToLower(address)
+	base := strings.ToLower(*utils.Cfg.EmailSettings.ReplyToAddress)
+
+	at := strings.LastIndex(address, "@")
+	baseAt := strings.LastIndex(base, "@")
+	if at <= 0 || baseAt <= 0 || address[at:] != base[baseAt:] {
+		return "", "", false
+	}
+
+	prefix := base[:baseAt] + "+"
+	if !strings.HasPrefix(address[:at], prefix) {
+		return "", "", false
+	}
+
+	parts := strings.Split(address[len(prefix):at], ".")
+	if len(parts) != 2 || len(parts[0]) != 26 || len(parts[1]) != EMAIL_REPLY_SIGNATURE_LENGTH {
+		return "", "", false
+	}
+
+	return parts[0], parts[1], true
+}
+
+func handleInboundMail(from string, to []string, data []byte) {
+	// bounces are sent from the null sender
+	if len(from) == 0 {
+		return
+	}
+
+	inbound, err := utils.ParseInboundMail(data)
+	if err != nil {
+		l4g.Warn(utils.T("api.email_reply.parse.warn"), from, err)
+		return
+	} else if inbound.AutoSubmitted {
+		return
+	}
+
+	for _, address := range to {
+		postId, signature, ok := parseReplyToAddress(address)
+		if !ok {
+			continue
+		}
+
+		if _, err := CreatePostFromEmailReply(inbound.From, postId, signature, inbound.Text); err != nil {
+			l4g.Warn(utils.T("api.email_reply.create_post.warn"), inbound.From, postId, err)
+		}
+	}
+}
+
+// CreatePostFromEmailReply posts the text of an email as a reply in the thread of the post the email was
+// about, as the user the email was sent to. The signature ties the reply address to that user so it can't
+// be used by anyone else.
+func CreatePostFromEmailReply(senderEmail string, postId string, signature string, text string) (*model.Post, *model.AppError) {
+	var user *model.User
+	if result := <-Srv.Store.User().GetByEmail(senderEmail); result.Err != nil {
+		return nil, model.NewLocAppError("CreatePostFromEmailReply", "api.email_reply.unknown_sender.app_error", nil, "email="+senderEmail)
+	} else {
+		user = result.Data.(*model.User)
+	}
+
+	if !hmac.Equal([]byte(signature), []byte(getEmailReplySignature(user.Id, postId))) || user.DeleteAt != 0 {
+		return nil, model.NewLocAppError("CreatePostFromEmailReply", "api.email_reply.signature.app_error", nil, "user_id="+user.Id+", post_id="+postId)
+	}
+
+	message := utils.ExtractReplyText(text)
+	if len(message) == 0 {
+		return nil, model.NewLocAppError("CreatePostFromEmailReply", "api.email_reply.empty.app_error", nil, "post_id="+postId)
+	}
+
+	var post *model.Post
+	if result := <-Srv.Store.Post().Get(postId); result.Err != nil {
+		return nil, result.Err
+	} else {
+		post = result.Data.(*model.PostList).Posts[postId]
+	}
+
+	var channel *model.Channel
+	if result := <-Srv.Store.Channel().Get(post.ChannelId); result.Err != nil {
+		return nil, result.Err
+	} else {
+		channel = result.Data.(*model.Channel)
+	}
+
+	if channel.DeleteAt != 0 {
+		return nil, model.NewLocAppError("CreatePostFromEmailReply", "api.email_reply.deleted_channel.app_error", nil, "channel_id="+channel.Id)
+	}
+
+	var channelMember *model.ChannelMember
+	if result := <-Srv.Store.Channel().GetMember(channel.Id, user.Id); result.Err == nil {
+		member := result.Data.(model.ChannelMember)
+		channelMember = &member
+	}
+
+	// direct messages are attached to a team for the links in notifications
+	teamId := channel.TeamId
+	if len(teamId) == 0 {
+		if result := <-Srv.Store.Team().GetTeamsByUserId(user.Id); result.Err != nil {
+			return nil, result.Err
+		} else if teams := result.Data.([]*model.Team); len(teams) > 0 {
+			teamId = teams[0].Id
+		}
+	}
+
+	var teamMember *model.TeamMember
+	if result := <-Srv.Store.Team().GetMember(teamId, user.Id); result.Err == nil {
+		member := result.Data.(model.TeamMember)
+		teamMember = &member
+	}
+
+	if !HasPermissionToChannel(user, teamMember, channelMember, model.PERMISSION_CREATE_POST) {
+		return nil, model.NewLocAppError("CreatePostFromEmailReply", "api.email_reply.permissions.app_error", nil, "user_id="+user.Id+", channel_id="+channel.Id)
+	}
+
+	rootId := post.RootId
+	if len(rootId) == 0 {
+		rootId = post.Id
+	}
+
+	c := &Context{
+		Session: model.Session{
+			UserId: user.Id,
+		},
+		RequestId: model.NewId(),
+		T:         utils.TfuncWithFallback(user.Locale),
+		Locale:    user.Locale,
+		TeamId:    teamId,
+	}
+	c.SetSiteURL(*utils.Cfg.ServiceSettings.SiteURL)
+
+	reply := &model.Post{
+		ChannelId: channel.Id,
+		UserId:    user.Id,
+		RootId:    rootId,
+		Message:   message,
+	}
+	reply.AddProp("from_email", "true")
+
+	return CreatePost(c, reply, true)
+}
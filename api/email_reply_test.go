@@ -0,0 +1,98 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api
+
+import (
+	"testing"
+
+	"github.com/mattermost/platform/model"
+	"github.com/mattermost/platform/store"
+	"github.com/mattermost/platform/utils"
+)
+
+func TestGetReplyToAddress(t *testing.T) {
+	Setup()
+
+	enableEmailReplies := *utils.Cfg.EmailSettings.EnableEmailReplies
+	replyToAddress := *utils.Cfg.EmailSettings.ReplyToAddress
+	defer func() {
+		*utils.Cfg.EmailSettings.EnableEmailReplies = enableEmailReplies
+		*utils.Cfg.EmailSettings.ReplyToAddress = replyToAddress
+	}()
+
+	userId := model.NewId()
+	postId := model.NewId()
+
+	*utils.Cfg.EmailSettings.EnableEmailReplies = false
+	if address := GetReplyToAddress(userId, postId); address != "" {
+		t.Fatal("shouldn't have made an address with email replies disabled")
+	}
+
+	*utils.Cfg.EmailSettings.EnableEmailReplies = true
+	*utils.Cfg.EmailSettings.ReplyToAddress = "reply@example.com"
+
+	address := GetReplyToAddress(userId, postId)
+	if !model.IsValidEmail(address) {
+		t.Fatal("should have made a valid address", address)
+	}
+
+	if id, signature, ok := parseReplyToAddress(address); !ok || id != postId || signature != getEmailReplySignature(userId, postId) {
+		t.Fatal("should have parsed the address", address)
+	}
+
+	if _, _, ok := parseReplyToAddress("reply+" + postId + ".abc@example.com"); ok {
+		t.Fatal("shouldn't have parsed an address without a full signature")
+	}
+
+	if _, _, ok := parseReplyToAddress("other@example.com"); ok {
+		t.Fatal("shouldn't have parsed a different address")
+	}
+
+	if getEmailReplySignature(model.NewId(), postId) == getEmailReplySignature(userId, postId) {
+		t.Fatal("should have signed the address for the user")
+	}
+}
+
+func TestCreatePostFromEmailReply(t *testing.T) {
+	th := Setup().InitBasic()
+
+	user := th.BasicUser
+	postId := th.BasicPost.Id
+	signature := getEmailReplySignature(user.Id, postId)
+
+	if _, err := CreatePostFromEmailReply("unknown@example.com", postId, signature, "reply"); err == nil {
+		t.Fatal("should have failed for an unknown sender")
+	}
+
+	if _, err := CreatePostFromEmailReply(th.BasicUser2.Email, postId, signature, "reply"); err == nil {
+		t.Fatal("should have failed for an address signed for another user")
+	}
+
+	if _, err := CreatePostFromEmailReply(user.Email, postId, signature, "> quoted only"); err == nil {
+		t.Fatal("should have failed without any new text")
+	}
+
+	post, err := CreatePostFromEmailReply(user.Email, postId, signature, "Sounds good\n\nOn Mon, Jan 2, 2017, Mattermost wrote:\n> "+th.BasicPost.Message)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if post.Message != "Sounds good" || post.RootId != postId || post.UserId != user.Id || post.ChannelId != th.BasicChannel.Id {
+		t.Fatal("should have replied in the thread", post)
+	}
+
+	// replying to an email about a reply continues the same thread
+	signature = getEmailReplySignature(user.Id, post.Id)
+	if reply, err := CreatePostFromEmailReply(user.Email, post.Id, signature, "Another reply"); err != nil {
+		t.Fatal(err)
+	} else if reply.RootId != postId {
+		t.Fatal("should have replied to the root post", reply)
+	}
+
+	store.Must(Srv.Store.Channel().RemoveMember(th.BasicChannel.Id, user.Id))
+
+	if _, err := CreatePostFromEmailReply(user.Email, postId, getEmailReplySignature(user.Id, postId), "reply"); err == nil {
+		t.Fatal("should have failed after leaving the channel")
+	}
+}
@@ -0,0 +1,348 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	l4g "github.com/alecthomas/log4go"
+	"github.com/gorilla/mux"
+	"github.com/mattermost/platform/model"
+	"github.com/mattermost/platform/utils"
+)
+
+const (
+	GUEST_EXPIRY_TASK_NAME = "Deactivate Expired Guests"
+)
+
+func InitGuest() {
+	l4g.Debug(utils.T("api.guest.init.debug"))
+
+	BaseRoutes.NeedTeam.Handle("/invite_guests", ApiAdminSystemRequired(inviteGuests)).Methods("POST")
+	BaseRoutes.NeedUser.Handle("/convert_to_guest", ApiAdminSystemRequired(convertUserToGuest)).Methods("POST")
+	BaseRoutes.NeedUser.Handle("/convert_to_user", ApiAdminSystemRequired(convertGuestToUser)).Methods("POST")
+}
+
+func inviteGuests(c *Context, w http.ResponseWriter, r *http.Request) {
+	invite := model.GuestInviteFromJson(r.Body)
+	if invite == nil {
+		c.SetInvalidParam("inviteGuests", "invite")
+		return
+	}
+
+	if err := invite.IsValid(); err != nil {
+		c.Err = err
+		c.Err.StatusCode = http.StatusBadRequest
+		return
+	}
+
+	tchan := Srv.Store.Team().Get(c.TeamId)
+	uchan := Srv.Store.User().Get(c.Session.UserId)
+
+	var team *model.Team
+	if result := <-tchan; result.Err != nil {
+		c.Err = result.Err
+		return
+	} else {
+		team = result.Data.(*model.Team)
+	}
+
+	var user *model.User
+	if result := <-uchan; result.Err != nil {
+		c.Err = result.Err
+		return
+	} else {
+		user = result.Data.(*model.User)
+	}
+
+	channels, err := getGuestChannels(team.Id, invite.ChannelIds)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	InviteGuests(team, channels, user.GetDisplayName(), invite)
+
+	c.LogAudit("team_id=" + team.Id + " channel_ids=" + strings.Join(invite.ChannelIds, ","))
+
+	w.Write([]byte(invite.ToJson()))
+}
+
+// getGuestChannels loads the channels a guest is being given access to and makes sure they all belong to the team.
+func getGuestChannels(teamId string, channelIds []string) ([]*model.Channel, *model.AppError) {
+	channels := make([]*model.Channel, 0, len(channelIds))
+
+	for _, channelId := range channelIds {
+		if result := <-Srv.Store.Channel().Get(channelId); result.Err != nil {
+			return nil, result.Err
+		} else {
+			channel := result.Data.(*model.Channel)
+
+			if channel.TeamId != teamId || channel.DeleteAt > 0 || channel.Type == model.CHANNEL_DIRECT {
+				err := model.NewLocAppError("getGuestChannels", "api.guest.invite_guests.channel.app_error", nil, "channel_id="+channelId)
+				err.StatusCode = http.StatusBadRequest
+				return nil, err
+			}
+
+			channels = append(channels, channel)
+		}
+	}
+
+	return channels, nil
+}
+
+func InviteGuests(team *model.Team, channels []*model.Channel, senderName string, invite *model.GuestInvite) {
+	channelNames := make([]string, 0, len(channels))
+	channelIds := make([]string, 0, len(channels))
+	for _, channel := range channels {
+		channelNames = append(channelNames, channel.DisplayName)
+		channelIds = append(channelIds, channel.Id)
+	}
+
+	for _, email := range invite.Emails {
+		if len(email) == 0 {
+			continue
+		}
+
+		subject := utils.T("api.templates.guest_invite_subject",
+			map[string]interface{}{"SenderName": senderName, "TeamDisplayName": team.DisplayName, "SiteName": utils.ClientCfg["SiteName"]})
+
+		bodyPage := utils.NewHTMLTemplate("guest_invite_body", model.DEFAULT_LOCALE)
+		bodyPage.Props["SiteURL"] = *utils.Cfg.ServiceSettings.SiteURL
+		bodyPage.Props["Title"] = utils.T("api.templates.guest_invite_body.title")
+		bodyPage.Html["Info"] = template.HTML(utils.T("api.templates.guest_invite_body.info",
+			map[string]interface{}{"SenderName": senderName, "TeamDisplayName": team.DisplayName}))
+		bodyPage.Props["ChannelsTitle"] = utils.T("api.templates.guest_invite_body.channels")
+		bodyPage.Props["Channels"] = strings.Join(channelNames, ", ")
+		bodyPage.Props["Button"] = utils.T("api.templates.guest_invite_body.button")
+
+		if invite.ExpiresAt > 0 {
+			bodyPage.Props["ExpiryInfo"] = utils.T("api.templates.guest_invite_body.expiry_info",
+				map[string]interface{}{"ExpiresAt": time.Unix(0, invite.ExpiresAt*int64(time.Millisecond)).UTC().Format("January 2, 2006")})
+		}
+
+		props := make(map[string]string)
+		props["email"] = email
+		props["id"] = team.Id
+		props["display_name"] = team.DisplayName
+		props["name"] = team.Name
+		props["time"] = fmt.Sprintf("%v", model.GetMillis())
+		props["guest"] = "true"
+		props["channel_ids"] = strings.Join(channelIds, " ")
+		props["guest_expires_at"] = fmt.Sprintf("%v", invite.ExpiresAt)
+		data := model.MapToJson(props)
+		hash := model.HashPassword(fmt.Sprintf("%v:%v", data, utils.Cfg.EmailSettings.InviteSalt))
+		bodyPage.Props["Link"] = fmt.Sprintf("%s/signup_user_complete/?d=%s&h=%s", *utils.Cfg.ServiceSettings.SiteURL, url.QueryEscape(data), url.QueryEscape(hash))
+
+		if !utils.Cfg.EmailSettings.SendEmailNotifications {
+			l4g.Info(utils.T("api.team.invite_members.sending.info"), email, bodyPage.Props["Link"])
+		}
+
+		if err := utils.SendMail(email, subject, bodyPage.Render()); err != nil {
+			l4g.Error(utils.T("api.team.invite_members.send.error"), err)
+		}
+	}
+}
+
+// joinGuestFromInvite turns a user that signed up through a guest invite into a guest of the team and adds
+// them to the channels listed in the invite.
+func joinGuestFromInvite(user *model.User, team *model.Team, props map[string]string) (*model.User, *model.AppError) {
+	expiresAt, _ := strconv.ParseInt(props["guest_expires_at"], 10, 64)
+
+	ruser, err := ConvertUserToGuest(user, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := JoinUserToTeam(team, ruser); err != nil {
+		return nil, err
+	}
+
+	channels, err := getGuestChannels(team.Id, strings.Fields(props["channel_ids"]))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, channel := range channels {
+		if _, err := AddUserToChannel(ruser, channel); err != nil {
+			return nil, err
+		}
+	}
+
+	ruser.Sanitize(map[string]bool{})
+
+	return ruser, nil
+}
+
+func convertUserToGuest(c *Context, w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	userId := params["user_id"]
+
+	props := model.MapFromJson(r.Body)
+
+	var expiresAt int64
+	if len(props["expires_at"]) > 0 {
+		var err error
+		if expiresAt, err = strconv.ParseInt(props["expires_at"], 10, 64); err != nil || expiresAt < 0 {
+			c.SetInvalidParam("convertUserToGuest", "expires_at")
+			return
+		}
+	}
+
+	var user *model.User
+	if result := <-Srv.Store.User().Get(userId); result.Err != nil {
+		c.Err = result.Err
+		return
+	} else {
+		user = result.Data.(*model.User)
+	}
+
+	if ruser, err := ConvertUserToGuest(user, expiresAt); err != nil {
+		c.Err = err
+		return
+	} else {
+		c.LogAuditWithUserId(ruser.Id, fmt.Sprintf("expires_at=%v", expiresAt))
+		ruser.Sanitize(map[string]bool{})
+		w.Write([]byte(ruser.ToJson()))
+	}
+}
+
+func convertGuestToUser(c *Context, w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	userId := params["user_id"]
+
+	var user *model.User
+	if result := <-Srv.Store.User().Get(userId); result.Err != nil {
+		c.Err = result.Err
+		return
+	} else {
+		user = result.Data.(*model.User)
+	}
+
+	if ruser, err := ConvertGuestToUser(user); err != nil {
+		c.Err = err
+		return
+	} else {
+		c.LogAuditWithUserId(ruser.Id, "")
+		ruser.Sanitize(map[string]bool{})
+		w.Write([]byte(ruser.ToJson()))
+	}
+}
+
+func ConvertUserToGuest(user *model.User, expiresAt int64) (*model.User, *model.AppError) {
+	if user.IsInRole(model.ROLE_SYSTEM_ADMIN.Id) {
+		err := model.NewLocAppError("ConvertUserToGuest", "api.guest.convert_to_guest.system_admin.app_error", nil, "user_id="+user.Id)
+		err.StatusCode = http.StatusBadRequest
+		return nil, err
+	}
+
+	if err := updateMemberRolesForUser(user.Id, model.ROLE_TEAM_GUEST.Id, model.ROLE_CHANNEL_GUEST.Id); err != nil {
+		return nil, err
+	}
+
+	user.GuestExpiresAt = expiresAt
+
+	return UpdateUserRoles(user, model.ROLE_SYSTEM_GUEST.Id)
+}
+
+func ConvertGuestToUser(user *model.User) (*model.User, *model.AppError) {
+	if !user.IsGuest() {
+		err := model.NewLocAppError("ConvertGuestToUser", "api.guest.convert_to_user.not_guest.app_error", nil, "user_id="+user.Id)
+		err.StatusCode = http.StatusBadRequest
+		return nil, err
+	}
+
+	if err := updateMemberRolesForUser(user.Id, model.ROLE_TEAM_USER.Id, model.ROLE_CHANNEL_USER.Id); err != nil {
+		return nil, err
+	}
+
+	user.GuestExpiresAt = 0
+
+	return UpdateUserRoles(user, model.ROLE_SYSTEM_USER.Id)
+}
+
+// updateMemberRolesForUser replaces the roles of every team and channel membership of a user.
+func updateMemberRolesForUser(userId string, teamRoles string, channelRoles string) *model.AppError {
+	tchan := Srv.Store.Team().GetTeamsForUser(userId)
+	cchan := Srv.Store.Channel().GetAllChannelMembersForUser(userId, false)
+
+	if result := <-tchan; result.Err != nil {
+		return result.Err
+	} else {
+		for _, member := range result.Data.([]*model.TeamMember) {
+			member.Roles = teamRoles
+
+			if result := <-Srv.Store.Team().UpdateMember(member); result.Err != nil {
+				return result.Err
+			}
+		}
+	}
+
+	if result := <-cchan; result.Err != nil {
+		return result.Err
+	} else {
+		for channelId := range result.Data.(map[string]string) {
+			if mresult := <-Srv.Store.Channel().GetMember(channelId, userId); mresult.Err != nil {
+				return mresult.Err
+			} else {
+				member := mresult.Data.(model.ChannelMember)
+				member.Roles = channelRoles
+
+				if result := <-Srv.Store.Channel().UpdateMember(&member); result.Err != nil {
+					return result.Err
+				}
+			}
+		}
+	}
+
+	InvalidateCacheForUser(userId)
+
+	return nil
+}
+
+// UsersShareChannel returns true if both users are members of at least one common channel.
+func UsersShareChannel(userId string, otherUserId string) bool {
+	uchan := Srv.Store.Channel().GetAllChannelMembersForUser(userId, true)
+	ochan := Srv.Store.Channel().GetAllChannelMembersForUser(otherUserId, true)
+
+	uresult := <-uchan
+	oresult := <-ochan
+	if uresult.Err != nil || oresult.Err != nil {
+		return false
+	}
+
+	otherChannels := oresult.Data.(map[string]string)
+	for channelId := range uresult.Data.(map[string]string) {
+		if _, ok := otherChannels[channelId]; ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+func StartGuestExpiryJob() {
+	DeactivateExpiredGuests()
+	model.CreateRecurringTask(GUEST_EXPIRY_TASK_NAME, DeactivateExpiredGuests, time.Hour)
+}
+
+func DeactivateExpiredGuests() {
+	if result := <-Srv.Store.User().GetExpiredGuests(model.GetMillis()); result.Err != nil {
+		l4g.Error(utils.T("api.guest.deactivate_expired_guests.error"), result.Err.Error())
+	} else {
+		for _, user := range result.Data.([]*model.User) {
+			if _, err := UpdateActive(user, false); err != nil {
+				l4g.Error(utils.T("api.guest.deactivate_expired_guests.error"), err.Error())
+			} else {
+				SetStatusOffline(user.Id, false)
+			}
+		}
+	}
+}
@@ -0,0 +1,179 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/mattermost/platform/model"
+)
+
+func TestConvertUserToGuest(t *testing.T) {
+	th := Setup().InitSystemAdmin().InitBasic()
+	Client := th.BasicClient
+
+	if _, err := Client.ConvertUserToGuest(th.BasicUser2.Id, 0); err == nil {
+		t.Fatal("should have errored, not a system admin")
+	}
+
+	if _, err := th.SystemAdminClient.ConvertUserToGuest(th.SystemAdminUser.Id, 0); err == nil {
+		t.Fatal("should have errored, system admins can't become guests")
+	}
+
+	if result, err := th.SystemAdminClient.ConvertUserToGuest(th.BasicUser2.Id, 0); err != nil {
+		t.Fatal(err)
+	} else if user := result.Data.(*model.User); !user.IsGuest() {
+		t.Fatal("user should be a guest")
+	} else if len(user.Password) != 0 {
+		t.Fatal("password should have been sanitized")
+	}
+
+	if result := <-Srv.Store.Team().GetMember(th.BasicTeam.Id, th.BasicUser2.Id); result.Err != nil {
+		t.Fatal(result.Err)
+	} else if member := result.Data.(model.TeamMember); member.Roles != model.ROLE_TEAM_GUEST.Id {
+		t.Fatal("team member roles should have been updated")
+	}
+
+	if _, err := th.SystemAdminClient.ConvertGuestToUser(th.BasicUser.Id); err == nil {
+		t.Fatal("should have errored, not a guest")
+	}
+
+	if result, err := th.SystemAdminClient.ConvertGuestToUser(th.BasicUser2.Id); err != nil {
+		t.Fatal(err)
+	} else if user := result.Data.(*model.User); user.IsGuest() {
+		t.Fatal("user should no longer be a guest")
+	}
+
+	if result := <-Srv.Store.Team().GetMember(th.BasicTeam.Id, th.BasicUser2.Id); result.Err != nil {
+		t.Fatal(result.Err)
+	} else if member := result.Data.(model.TeamMember); member.Roles != model.ROLE_TEAM_USER.Id {
+		t.Fatal("team member roles should have been restored")
+	}
+}
+
+func TestGuestRestrictions(t *testing.T) {
+	th := Setup().InitSystemAdmin().InitBasic()
+	Client := th.BasicClient
+
+	th.SystemAdminClient.Must(th.SystemAdminClient.ConvertUserToGuest(th.BasicUser2.Id, 0))
+	Client.Must(Client.AddChannelMember(th.BasicChannel.Id, th.BasicUser2.Id))
+
+	if result := <-Srv.Store.Channel().GetMember(th.BasicChannel.Id, th.BasicUser2.Id); result.Err != nil {
+		t.Fatal(result.Err)
+	} else if member := result.Data.(model.ChannelMember); member.Roles != model.ROLE_CHANNEL_GUEST.Id {
+		t.Fatal("guest should have the guest channel role")
+	}
+
+	th.LoginBasic2()
+
+	if _, err := Client.CreateDirectChannel(th.SystemAdminUser.Id); err == nil {
+		t.Fatal("should have errored, guest shares no channel with the user")
+	}
+
+	if _, err := Client.CreateDirectChannel(th.BasicUser.Id); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Client.SearchUsers(model.UserSearch{Term: th.BasicUser.Username, TeamId: th.BasicTeam.Id}); err == nil {
+		t.Fatal("should have errored, guests can only search within a channel")
+	}
+
+	if _, err := Client.SearchUsers(model.UserSearch{Term: th.BasicUser.Username, InChannelId: th.BasicChannel.Id}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Client.SearchMoreChannels(model.ChannelSearch{Term: th.BasicChannel.Name}); err == nil {
+		t.Fatal("should have errored, guests can't browse channels")
+	}
+
+	th.LoginBasic()
+
+	if result, err := Client.SearchUsers(model.UserSearch{Term: th.BasicUser2.Username, TeamId: th.BasicTeam.Id}); err != nil {
+		t.Fatal(err)
+	} else if users := result.Data.([]*model.User); len(users) != 0 {
+		t.Fatal("guests should be hidden from regular users")
+	}
+}
+
+func TestGuestChannelPermissions(t *testing.T) {
+	th := Setup().InitSystemAdmin().InitBasic()
+	Client := th.BasicClient
+
+	publicChannel := th.CreateChannel(Client, th.BasicTeam)
+	privateChannel := th.CreatePrivateChannel(Client, th.BasicTeam)
+
+	th.SystemAdminClient.Must(th.SystemAdminClient.ConvertUserToGuest(th.BasicUser2.Id, 0))
+	Client.Must(Client.AddChannelMember(th.BasicChannel.Id, th.BasicUser2.Id))
+
+	th.LoginBasic2()
+
+	if _, err := Client.GetPosts(th.BasicChannel.Id, 0, 10, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Client.CreatePost(&model.Post{ChannelId: th.BasicChannel.Id, Message: "hello"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, channel := range []*model.Channel{publicChannel, privateChannel} {
+		if _, err := Client.GetPosts(channel.Id, 0, 10, ""); err == nil || err.StatusCode != http.StatusForbidden {
+			t.Fatal("should have been forbidden from reading a channel the guest isn't a member of", channel.Type, err)
+		}
+
+		if _, err := Client.CreatePost(&model.Post{ChannelId: channel.Id, Message: "hello"}); err == nil || err.StatusCode != http.StatusForbidden {
+			t.Fatal("should have been forbidden from posting in a channel the guest isn't a member of", channel.Type, err)
+		}
+	}
+}
+
+func TestDeactivateExpiredGuests(t *testing.T) {
+	th := Setup().InitSystemAdmin().InitBasic()
+
+	if _, err := ConvertUserToGuest(th.BasicUser2, model.GetMillis()-1000); err != nil {
+		t.Fatal(err)
+	}
+
+	DeactivateExpiredGuests()
+
+	if result := <-Srv.Store.User().Get(th.BasicUser2.Id); result.Err != nil {
+		t.Fatal(result.Err)
+	} else if user := result.Data.(*model.User); user.DeleteAt == 0 {
+		t.Fatal("expired guest should have been deactivated")
+	}
+
+	if result := <-Srv.Store.User().Get(th.BasicUser.Id); result.Err != nil {
+		t.Fatal(result.Err)
+	} else if user := result.Data.(*model.User); user.DeleteAt != 0 {
+		t.Fatal("regular user should not have been deactivated")
+	}
+}
+
+func TestInviteGuests(t *testing.T) {
+	th := Setup().InitSystemAdmin().InitBasic()
+	Client := th.BasicClient
+
+	invite := &model.GuestInvite{
+		Emails:     []string{"success+" + model.NewId() + "@simulator.amazonses.com"},
+		ChannelIds: []string{th.BasicChannel.Id},
+	}
+
+	if _, err := Client.InviteGuests(invite); err == nil {
+		t.Fatal("should have errored, not a system admin")
+	}
+
+	th.SystemAdminClient.SetTeamId(th.BasicTeam.Id)
+
+	if _, err := th.SystemAdminClient.InviteGuests(&model.GuestInvite{Emails: invite.Emails}); err == nil {
+		t.Fatal("should have errored, no channels")
+	}
+
+	if _, err := th.SystemAdminClient.InviteGuests(&model.GuestInvite{Emails: invite.Emails, ChannelIds: []string{model.NewId()}}); err == nil {
+		t.Fatal("should have errored, channel does not exist")
+	}
+
+	if _, err := th.SystemAdminClient.InviteGuests(invite); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,437 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	l4g "github.com/alecthomas/log4go"
+
+	"github.com/mattermost/platform/einterfaces"
+	"github.com/mattermost/platform/model"
+	"github.com/mattermost/platform/utils"
+)
+
+const (
+	LDAP_GROUP_SYNC_TASK_NAME  = "Synchronize LDAP Groups"
+	LDAP_GROUP_SYNC_LEASE_NAME = "LdapGroupSyncLease"
+)
+
+func InitLdapGroup() {
+	l4g.Debug(utils.T("api.ldap_group.init.debug"))
+
+	BaseRoutes.Admin.Handle("/ldap_groups", ApiAdminSystemRequired(getLdapGroups)).Methods("GET")
+	BaseRoutes.Admin.Handle("/ldap_group_links", ApiAdminSystemRequired(getLdapGroupLinks)).Methods("GET")
+	BaseRoutes.Admin.Handle("/link_ldap_group", ApiAdminSystemRequired(linkLdapGroup)).Methods("POST")
+	BaseRoutes.Admin.Handle("/unlink_ldap_group", ApiAdminSystemRequired(unlinkLdapGroup)).Methods("POST")
+	BaseRoutes.Admin.Handle("/ldap_group_sync", ApiAdminSystemRequired(ldapGroupSync)).Methods("POST")
+}
+
+func isLdapGroupSyncAvailable() bool {
+	return utils.IsLicensed && *utils.License.Features.LDAP && *utils.Cfg.LdapSettings.Enable && einterfaces.GetLdapInterface() != nil
+}
+
+func ldapGroupSyncDisabledError(where string) *model.AppError {
+	err := model.NewLocAppError(where, "ent.ldap.disabled.app_error", nil, "")
+	err.StatusCode = http.StatusNotImplemented
+	return err
+}
+
+// StartLdapGroupSyncJob starts the task that syncs the LDAP groups. Only one server in a cluster syncs them at a time.
+func StartLdapGroupSyncJob() {
+	interval := time.Duration(*utils.Cfg.LdapSettings.SyncIntervalMinutes) * time.Minute
+
+	model.CreateRecurringTask(LDAP_GROUP_SYNC_TASK_NAME, func() {
+		if !isLdapGroupSyncAvailable() || !acquireLease(LDAP_GROUP_SYNC_LEASE_NAME, 2*interval) {
+			return
+		}
+
+		if _, err := SyncLdapGroupLinks(false); err != nil {
+			l4g.Error(utils.T("api.ldap_group.sync.error"), err.Error())
+		}
+
+		if err := SyncLdapUserGroups(); err != nil {
+			l4g.Error(utils.T("api.user_group.sync_ldap.error"), err.Error())
+		}
+	}, interval)
+}
+
+func getLdapGroups(c *Context, w http.ResponseWriter, r *http.Request) {
+	if !isLdapGroupSyncAvailable() {
+		c.Err = ldapGroupSyncDisabledError("getLdapGroups")
+		return
+	}
+
+	if groups, err := einterfaces.GetLdapInterface().GetAllGroups(); err != nil {
+		c.Err = err
+	} else {
+		w.Write([]byte(model.LdapGroupsToJson(groups)))
+	}
+}
+
+func getLdapGroupLinks(c *Context, w http.ResponseWriter, r *http.Request) {
+	if result := <-Srv.Store.LdapGroupLink().GetAll(); result.Err != nil {
+		c.Err = result.Err
+	} else {
+		w.Write([]byte(model.LdapGroupLinksToJson(result.Data.([]*model.LdapGroupLink))))
+	}
+}
+
+func linkLdapGroup(c *Context, w http.ResponseWriter, r *http.Request) {
+	link := model.LdapGroupLinkFromJson(r.Body)
+	if link == nil {
+		c.SetInvalidParam("linkLdapGroup", "link")
+		return
+	}
+
+	link.Id = ""
+	link.CreatorId = c.Session.UserId
+
+	if rlink, err := LinkLdapGroup(link); err != nil {
+		c.Err = err
+	} else {
+		c.LogAudit("group_id=" + rlink.GroupId + " team_id=" + rlink.TeamId + " channel_id=" + rlink.ChannelId)
+		w.Write([]byte(rlink.ToJson()))
+	}
+}
+
+// LinkLdapGroup links a directory group to a team or to one of the team's private channels
+func LinkLdapGroup(link *model.LdapGroupLink) (*model.LdapGroupLink, *model.AppError) {
+	if !isLdapGroupSyncAvailable() {
+		return nil, ldapGroupSyncDisabledError("LinkLdapGroup")
+	}
+
+	if result := <-Srv.Store.Team().Get(link.TeamId); result.Err != nil || result.Data.(*model.Team).DeleteAt > 0 {
+		err := model.NewLocAppError("LinkLdapGroup", "api.ldap_group.link.team.app_error", nil, "team_id="+link.TeamId)
+		err.StatusCode = http.StatusBadRequest
+		return nil, err
+	}
+
+	if len(link.ChannelId) > 0 {
+		if result := <-Srv.Store.Channel().Get(link.ChannelId); result.Err != nil {
+			err := model.NewLocAppError("LinkLdapGroup", "api.ldap_group.link.channel.app_error", nil, "channel_id="+link.ChannelId)
+			err.StatusCode = http.StatusBadRequest
+			return nil, err
+		} else if channel := result.Data.(*model.Channel); channel.TeamId != link.TeamId || channel.Type != model.CHANNEL_PRIVATE || channel.DeleteAt > 0 {
+			err := model.NewLocAppError("LinkLdapGroup", "api.ldap_group.link.channel.app_error", nil, "channel_id="+link.ChannelId)
+			err.StatusCode = http.StatusBadRequest
+			return nil, err
+		}
+	}
+
+	groups, err := einterfaces.GetLdapInterface().GetAllGroups()
+	if err != nil {
+		return nil, err
+	}
+
+	found := false
+	for _, group := range groups {
+		if group.Id == link.GroupId {
+			link.GroupName = group.DisplayName
+			found = true
+			break
+		}
+	}
+
+	if !found {
+		err := model.NewLocAppError("LinkLdapGroup", "api.ldap_group.link.group.app_error", nil, "group_id="+link.GroupId)
+		err.StatusCode = http.StatusBadRequest
+		return nil, err
+	}
+
+	if result := <-Srv.Store.LdapGroupLink().Save(link); result.Err != nil {
+		result.Err.StatusCode = http.StatusBadRequest
+		return nil, result.Err
+	} else {
+		return result.Data.(*model.LdapGroupLink), nil
+	}
+}
+
+func unlinkLdapGroup(c *Context, w http.ResponseWriter, r *http.Request) {
+	props := model.MapFromJson(r.Body)
+
+	id := props["id"]
+	if len(id) != 26 {
+		c.SetInvalidParam("unlinkLdapGroup", "id")
+		return
+	}
+
+	if result := <-Srv.Store.LdapGroupLink().Delete(id); result.Err != nil {
+		result.Err.StatusCode = http.StatusBadRequest
+		c.Err = result.Err
+		return
+	}
+
+	c.LogAudit("id=" + id)
+
+	ReturnStatusOK(w)
+}
+
+func ldapGroupSync(c *Context, w http.ResponseWriter, r *http.Request) {
+	props := model.MapFromJson(r.Body)
+
+	report, err := SyncLdapGroupLinks(props["dry_run"] == "true")
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	if !report.DryRun {
+		c.LogAudit("")
+	}
+
+	w.Write([]byte(report.ToJson()))
+}
+
+// ldapGroupSyncTarget is a team, or a channel when channelId is set, with the groups linked to it
+type ldapGroupSyncTarget struct {
+	teamId    string
+	channelId string
+	groupIds  []string
+}
+
+// SyncLdapGroupLinks makes the LDAP users among the members of every linked team and channel match the members
+// of the groups linked to it. Users that don't sign in with LDAP are never removed so that members added by hand
+// keep their access. When dryRun is set the changes are only reported.
+func SyncLdapGroupLinks(dryRun bool) (*model.LdapGroupSyncReport, *model.AppError) {
+	if !isLdapGroupSyncAvailable() {
+		return nil, ldapGroupSyncDisabledError("SyncLdapGroupLinks")
+	}
+
+	report := &model.LdapGroupSyncReport{DryRun: dryRun, Results: []*model.LdapGroupSyncResult{}}
+
+	var links []*model.LdapGroupLink
+	if result := <-Srv.Store.LdapGroupLink().GetAll(); result.Err != nil {
+		return nil, result.Err
+	} else {
+		links = result.Data.([]*model.LdapGroupLink)
+	}
+
+	if len(links) == 0 {
+		return report, nil
+	}
+
+	// several groups can be linked to the same team or channel and their members are combined. Channels are
+	// synchronized before teams since leaving a team also removes the user from its channels.
+	var channelTargets, teamTargets []*ldapGroupSyncTarget
+	targetsByKey := make(map[string]*ldapGroupSyncTarget)
+	for _, link := range links {
+		key := link.TeamId + link.ChannelId
+		if target, ok := targetsByKey[key]; ok {
+			target.groupIds = append(target.groupIds, link.GroupId)
+		} else {
+			target = &ldapGroupSyncTarget{teamId: link.TeamId, channelId: link.ChannelId, groupIds: []string{link.GroupId}}
+			targetsByKey[key] = target
+
+			if len(link.ChannelId) > 0 {
+				channelTargets = append(channelTargets, target)
+			} else {
+				teamTargets = append(teamTargets, target)
+			}
+		}
+	}
+
+	usersByLdapId, err := getUsersByLdapId()
+	if err != nil {
+		return nil, err
+	}
+
+	groupMembers := make(map[string][]string)
+	for _, target := range append(channelTargets, teamTargets...) {
+		result := &model.LdapGroupSyncResult{TeamId: target.teamId, ChannelId: target.channelId, GroupIds: target.groupIds}
+
+		if err := syncLdapGroupTarget(target, result, usersByLdapId, groupMembers, dryRun); err != nil {
+			l4g.Error(utils.T("api.ldap_group.sync.error"), err.Error())
+			result.Error = err.Error()
+		}
+
+		report.Results = append(report.Results, result)
+	}
+
+	return report, nil
+}
+
+// getUsersByLdapId returns the users that sign in with LDAP keyed by their id in the directory
+func getUsersByLdapId() (map[string]*model.User, *model.AppError) {
+	usersByLdapId := make(map[string]*model.User)
+	if result := <-Srv.Store.User().GetAll(); result.Err != nil {
+		return nil, result.Err
+	} else {
+		for _, user := range result.Data.([]*model.User) {
+			if user.AuthService == model.USER_AUTH_SERVICE_LDAP && user.AuthData != nil {
+				usersByLdapId[*user.AuthData] = user
+			}
+		}
+	}
+
+	return usersByLdapId, nil
+}
+
+func syncLdapGroupTarget(target *ldapGroupSyncTarget, syncResult *model.LdapGroupSyncResult, usersByLdapId map[string]*model.User, groupMembers map[string][]string, dryRun bool) *model.AppError {
+	var team *model.Team
+	if result := <-Srv.Store.Team().Get(target.teamId); result.Err != nil {
+		return result.Err
+	} else {
+		team = result.Data.(*model.Team)
+	}
+
+	var channel *model.Channel
+	if len(target.channelId) > 0 {
+		if result := <-Srv.Store.Channel().Get(target.channelId); result.Err != nil {
+			return result.Err
+		} else {
+			channel = result.Data.(*model.Channel)
+		}
+	}
+
+	if team.DeleteAt > 0 || (channel != nil && channel.DeleteAt > 0) {
+		return nil
+	}
+
+	wanted := make(map[string]*model.User)
+	for _, groupId := range target.groupIds {
+		if _, ok := groupMembers[groupId]; !ok {
+			if ids, err := einterfaces.GetLdapInterface().GetGroupMemberIds(groupId); err != nil {
+				return err
+			} else {
+				groupMembers[groupId] = ids
+			}
+		}
+
+		for _, ldapId := range groupMembers[groupId] {
+			if user := usersByLdapId[ldapId]; user != nil && user.DeleteAt == 0 {
+				wanted[user.Id] = user
+			}
+		}
+	}
+
+	current, err := ldapGroupTargetMemberIds(team, channel)
+	if err != nil {
+		return err
+	}
+
+	ldapUserIds := make(map[string]bool)
+	for _, user := range usersByLdapId {
+		ldapUserIds[user.Id] = true
+	}
+
+	for userId := range wanted {
+		if !current[userId] {
+			syncResult.Added = append(syncResult.Added, userId)
+		}
+	}
+
+	for userId := range current {
+		if ldapUserIds[userId] && wanted[userId] == nil {
+			syncResult.Removed = append(syncResult.Removed, userId)
+		}
+	}
+
+	sort.Strings(syncResult.Added)
+	sort.Strings(syncResult.Removed)
+
+	if dryRun {
+		return nil
+	}
+
+	for _, userId := range syncResult.Added {
+		if err := JoinUserToTeam(team, wanted[userId]); err != nil {
+			return err
+		}
+
+		if channel != nil {
+			if _, err := AddUserToChannel(wanted[userId], channel); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, userId := range syncResult.Removed {
+		if channel != nil {
+			if err := RemoveUserFromChannel(userId, "", channel); err != nil {
+				return err
+			}
+		} else if result := <-Srv.Store.User().Get(userId); result.Err != nil {
+			return result.Err
+		} else if err := LeaveTeam(team, result.Data.(*model.User)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func ldapGroupTargetMemberIds(team *model.Team, channel *model.Channel) (map[string]bool, *model.AppError) {
+	ids := make(map[string]bool)
+
+	if channel != nil {
+		if result := <-Srv.Store.Channel().GetMembers(channel.Id); result.Err != nil {
+			return nil, result.Err
+		} else {
+			for _, member := range result.Data.([]model.ChannelMember) {
+				ids[member.UserId] = true
+			}
+		}
+
+		return ids, nil
+	}
+
+	if teamIds, err := GetAllTeamMemberIds(team.Id); err != nil {
+		return nil, err
+	} else {
+		for _, id := range teamIds {
+			ids[id] = true
+		}
+	}
+
+	return ids, nil
+}
+
+// CheckLdapGroupMemberRemoval returns an error when the user belongs to a directory group linked to the team, or
+// to the channel when channelId is set, since synchronization would add them back
+func CheckLdapGroupMemberRemoval(userId string, teamId string, channelId string) *model.AppError {
+	if !isLdapGroupSyncAvailable() {
+		return nil
+	}
+
+	var user *model.User
+	if result := <-Srv.Store.User().Get(userId); result.Err != nil {
+		return nil
+	} else if user = result.Data.(*model.User); user.AuthService != model.USER_AUTH_SERVICE_LDAP || user.AuthData == nil {
+		return nil
+	}
+
+	var lchan = Srv.Store.LdapGroupLink().GetForTeam(teamId)
+	if len(channelId) > 0 {
+		lchan = Srv.Store.LdapGroupLink().GetForChannel(channelId)
+	}
+
+	var links []*model.LdapGroupLink
+	if result := <-lchan; result.Err != nil {
+		return result.Err
+	} else {
+		links = result.Data.([]*model.LdapGroupLink)
+	}
+
+	for _, link := range links {
+		ids, err := einterfaces.GetLdapInterface().GetGroupMemberIds(link.GroupId)
+		if err != nil {
+			// don't lock admins out of managing members while the directory can't be reached
+			l4g.Warn(utils.T("api.ldap_group.check_removal.warn"), link.GroupId, err.Error())
+			continue
+		}
+
+		for _, id := range ids {
+			if id == *user.AuthData {
+				err := model.NewLocAppError("CheckLdapGroupMemberRemoval", "api.ldap_group.remove_member.app_error", map[string]interface{}{"Group": link.GroupName}, "user_id="+userId)
+				err.StatusCode = http.StatusForbidden
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,153 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/mattermost/platform/einterfaces"
+	"github.com/mattermost/platform/model"
+	"github.com/mattermost/platform/store"
+	"github.com/mattermost/platform/utils"
+)
+
+const testLdapGroupId = "cn=engineering,ou=groups,dc=example,dc=com"
+
+// testLdapGroups serves groups from memory, the other methods of the interface aren't used by group sync
+type testLdapGroups struct {
+	einterfaces.LdapInterface
+	members map[string][]string
+}
+
+func (l *testLdapGroups) GetAllGroups() ([]*model.LdapGroup, *model.AppError) {
+	return []*model.LdapGroup{{Id: testLdapGroupId, DisplayName: "Engineering"}}, nil
+}
+
+func (l *testLdapGroups) GetGroupMemberIds(groupId string) ([]string, *model.AppError) {
+	return l.members[groupId], nil
+}
+
+func enableTestLdapGroups(ldapGroups *testLdapGroups) func() {
+	isLicensed := utils.IsLicensed
+	license := utils.License
+	enable := *utils.Cfg.LdapSettings.Enable
+	ldapI := einterfaces.GetLdapInterface()
+
+	utils.IsLicensed = true
+	utils.License = &model.License{Features: &model.Features{}}
+	utils.License.Features.SetDefaults()
+	*utils.Cfg.LdapSettings.Enable = true
+	einterfaces.RegisterLdapInterface(ldapGroups)
+
+	return func() {
+		utils.IsLicensed = isLicensed
+		utils.License = license
+		*utils.Cfg.LdapSettings.Enable = enable
+		einterfaces.RegisterLdapInterface(ldapI)
+	}
+}
+
+func makeTestLdapUser(t *testing.T, user *model.User) string {
+	ldapId := model.NewId()
+	if result := <-Srv.Store.User().UpdateAuthData(user.Id, model.USER_AUTH_SERVICE_LDAP, &ldapId, user.Email, false); result.Err != nil {
+		t.Fatal(result.Err)
+	}
+
+	return ldapId
+}
+
+func TestLdapGroupSync(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	Client := th.BasicClient
+
+	ldapGroups := &testLdapGroups{members: map[string][]string{}}
+	defer enableTestLdapGroups(ldapGroups)()
+
+	team := th.CreateTeam(th.SystemAdminClient)
+	th.SystemAdminClient.SetTeamId(team.Id)
+	channel := th.CreatePrivateChannel(th.SystemAdminClient, team)
+
+	ldapUser := th.CreateUser(Client)
+	ldapId := makeTestLdapUser(t, ldapUser)
+	ldapGroups.members[testLdapGroupId] = []string{ldapId}
+
+	if _, err := Client.LinkLdapGroup(&model.LdapGroupLink{GroupId: testLdapGroupId, TeamId: team.Id}); err == nil {
+		t.Fatal("should have failed without permissions")
+	}
+
+	if _, err := th.SystemAdminClient.LinkLdapGroup(&model.LdapGroupLink{GroupId: "cn=unknown", TeamId: team.Id}); err == nil {
+		t.Fatal("should have failed to link an unknown group")
+	}
+
+	if _, err := th.SystemAdminClient.LinkLdapGroup(&model.LdapGroupLink{GroupId: testLdapGroupId, TeamId: team.Id, ChannelId: th.SystemAdminChannel.Id}); err == nil {
+		t.Fatal("should have failed to link a channel from another team")
+	}
+
+	teamLink := th.SystemAdminClient.Must(th.SystemAdminClient.LinkLdapGroup(&model.LdapGroupLink{GroupId: testLdapGroupId, TeamId: team.Id})).Data.(*model.LdapGroupLink)
+	if teamLink.GroupName != "Engineering" {
+		t.Fatal("should have stored the group's name")
+	}
+
+	th.SystemAdminClient.Must(th.SystemAdminClient.LinkLdapGroup(&model.LdapGroupLink{GroupId: testLdapGroupId, TeamId: team.Id, ChannelId: channel.Id}))
+
+	if links := th.SystemAdminClient.Must(th.SystemAdminClient.GetLdapGroupLinks()).Data.([]*model.LdapGroupLink); len(links) < 2 {
+		t.Fatal("should have returned the links")
+	}
+
+	report := th.SystemAdminClient.Must(th.SystemAdminClient.SyncLdapGroups(true)).Data.(*model.LdapGroupSyncReport)
+	if !report.DryRun || len(report.Results) != 2 {
+		t.Fatal("should have reported on both links")
+	}
+
+	for _, result := range report.Results {
+		if len(result.Added) != 1 || result.Added[0] != ldapUser.Id {
+			t.Fatal("should have reported adding the user", result)
+		}
+	}
+
+	if result := <-Srv.Store.Team().GetMember(team.Id, ldapUser.Id); result.Err == nil {
+		t.Fatal("a dry run shouldn't have added the user")
+	}
+
+	th.SystemAdminClient.Must(th.SystemAdminClient.SyncLdapGroups(false))
+
+	if result := <-Srv.Store.Channel().GetMember(channel.Id, ldapUser.Id); result.Err != nil {
+		t.Fatal("should have added the user to the channel")
+	}
+
+	if _, err := th.SystemAdminClient.RemoveChannelMember(channel.Id, ldapUser.Id); err == nil {
+		t.Fatal("shouldn't have removed a member of a linked group")
+	} else if err.StatusCode != http.StatusForbidden {
+		t.Fatal("wrong status code", err.StatusCode)
+	}
+
+	// users that don't sign in with LDAP are left alone
+	th.SystemAdminClient.Must(th.SystemAdminClient.AddChannelMember(channel.Id, th.BasicUser.Id))
+
+	ldapGroups.members[testLdapGroupId] = []string{}
+
+	report = th.SystemAdminClient.Must(th.SystemAdminClient.SyncLdapGroups(false)).Data.(*model.LdapGroupSyncReport)
+	for _, result := range report.Results {
+		if len(result.Removed) != 1 || result.Removed[0] != ldapUser.Id {
+			t.Fatal("should have removed only the user", result)
+		}
+	}
+
+	if result := <-Srv.Store.Channel().GetMember(channel.Id, th.BasicUser.Id); result.Err != nil {
+		t.Fatal("shouldn't have removed a user added by hand")
+	}
+
+	if result := <-Srv.Store.Team().GetMember(team.Id, ldapUser.Id); result.Err != nil || result.Data.(model.TeamMember).DeleteAt == 0 {
+		t.Fatal("should have removed the user from the team")
+	}
+
+	th.SystemAdminClient.Must(th.SystemAdminClient.UnlinkLdapGroup(teamLink.Id))
+
+	if _, err := th.SystemAdminClient.UnlinkLdapGroup(teamLink.Id); err == nil {
+		t.Fatal("should have failed to remove a missing link")
+	}
+
+	Srv.Store.(*store.SqlStore).GetMaster().Exec("DELETE FROM LdapGroupLinks WHERE TeamId = :TeamId", map[string]interface{}{"TeamId": team.Id})
+}
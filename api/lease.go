@@ -0,0 +1,28 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api
+
+import (
+	"time"
+
+	l4g "github.com/alecthomas/log4go"
+
+	"github.com/mattermost/platform/model"
+	"github.com/mattermost/platform/utils"
+)
+
+// leaseHolderId identifies this server when it holds a lease
+var leaseHolderId = model.NewId()
+
+// acquireLease returns true if this server holds the named lease for the given duration, making it the
+// only server in a cluster that runs the job the lease protects. Jobs renew their lease each time they
+// run so it only moves to another server when the one holding it stops.
+func acquireLease(name string, duration time.Duration) bool {
+	if result := <-Srv.Store.System().AcquireLease(name, leaseHolderId, int64(duration/time.Millisecond)); result.Err != nil {
+		l4g.Error(utils.T("api.lease.acquire.error"), name, result.Err)
+		return false
+	} else {
+		return result.Data.(bool)
+	}
+}
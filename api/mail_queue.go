@@ -0,0 +1,266 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api
+
+import (
+	"net/http"
+	"time"
+
+	l4g "github.com/alecthomas/log4go"
+
+	"github.com/mattermost/platform/model"
+	"github.com/mattermost/platform/utils"
+)
+
+const (
+	MAIL_QUEUE_MAINTENANCE_TASK_NAME = "Mail Queue Maintenance"
+
+	MAIL_QUEUE_POLL_INTERVAL        = 5 * time.Second
+	MAIL_QUEUE_RETRY_DELAY          = time.Minute // doubled after each failed attempt
+	MAIL_QUEUE_MAX_RETRY_DELAY      = 6 * time.Hour
+	MAIL_QUEUE_RATE_LIMIT_DELAY     = 5 * time.Minute
+	MAIL_QUEUE_RATE_LIMIT_PERIOD    = time.Hour
+	MAIL_QUEUE_STUCK_TIMEOUT        = 10 * time.Minute
+	MAIL_QUEUE_RETENTION            = 7 * 24 * time.Hour
+	MAIL_QUEUE_MAINTENANCE_INTERVAL = 5 * time.Minute
+	MAIL_QUEUE_FAILURES_SHOWN       = 50
+)
+
+// mailQueueWake wakes an idle worker when an email is queued instead of waiting for the next poll
+var mailQueueWake = make(chan bool, 1)
+
+func InitMailQueue() {
+	l4g.Debug(utils.T("api.mail_queue.init.debug"))
+
+	BaseRoutes.Admin.Handle("/mail_queue", ApiAdminSystemRequired(getMailQueueStats)).Methods("GET")
+	BaseRoutes.Admin.Handle("/mark_email_bouncing", ApiAdminSystemRequired(markEmailBouncing)).Methods("POST")
+	BaseRoutes.Admin.Handle("/unmark_email_bouncing", ApiAdminSystemRequired(unmarkEmailBouncing)).Methods("POST")
+}
+
+// StartMailQueue starts the workers that send queued emails and the task that returns emails left behind by
+// stopped servers to the queue. Every server runs workers since an email is only ever claimed by one of them.
+func StartMailQueue() {
+	for i := 0; i < *utils.Cfg.EmailSettings.MailQueueWorkers; i++ {
+		go runMailQueueWorker()
+	}
+
+	model.CreateRecurringTask(MAIL_QUEUE_MAINTENANCE_TASK_NAME, cleanMailQueue, MAIL_QUEUE_MAINTENANCE_INTERVAL)
+}
+
+// QueueMail adds an email to the outbound mail queue to be sent in the background
+func QueueMail(to, subject, body string) *model.AppError {
+	return queueOutgoingEmail(&model.OutgoingEmail{Recipient: to, Subject: subject, Body: body})
+}
+
+func queueOutgoingEmail(email *model.OutgoingEmail) *model.AppError {
+	if !utils.Cfg.EmailSettings.SendEmailNotifications || len(utils.Cfg.EmailSettings.SMTPServer) == 0 {
+		return nil
+	}
+
+	if result := <-Srv.Store.MailQueue().Save(email); result.Err != nil {
+		return result.Err
+	}
+
+	select {
+	case mailQueueWake <- true:
+	default:
+	}
+
+	return nil
+}
+
+// QueueNotificationMail queues a notification email unless the address has been marked as bouncing
+func QueueNotificationMail(to, subject, body string) *model.AppError {
+	return QueueNotificationMailWithReplyTo(to, "", subject, body)
+}
+
+// QueueNotificationMailWithReplyTo queues a notification email that's replied to at the given address
+func QueueNotificationMailWithReplyTo(to, replyTo, subject, body string) *model.AppError {
+	if result := <-Srv.Store.MailQueue().IsBouncing(to); result.Err != nil {
+		return result.Err
+	} else if result.Data.(bool) {
+		l4g.Debug(utils.T("api.mail_queue.queue_notification_mail.bouncing.debug"), to)
+		return nil
+	}
+
+	return queueOutgoingEmail(&model.OutgoingEmail{Recipient: to, ReplyTo: replyTo, Subject: subject, Body: body})
+}
+
+// MarkEmailBouncing stops notifications from being sent to an address that mail can't be delivered to. It's
+// the hook for bounce reports from the mail server or provider.
+func MarkEmailBouncing(email string, reason string) *model.AppError {
+	if result := <-Srv.Store.MailQueue().SaveBounce(&model.EmailBounce{Email: email, Reason: reason}); result.Err != nil {
+		return result.Err
+	}
+
+	return nil
+}
+
+func runMailQueueWorker() {
+	var connection *utils.MailConnection
+
+	for {
+		var email *model.OutgoingEmail
+		if result := <-Srv.Store.MailQueue().ClaimNext(model.GetMillis()); result.Err != nil {
+			l4g.Error(utils.T("api.mail_queue.worker.claim.error"), result.Err)
+		} else if result.Data != nil {
+			email = result.Data.(*model.OutgoingEmail)
+		}
+
+		if email == nil {
+			// don't hold on to the connection while there's nothing to send
+			if connection != nil {
+				connection.Close()
+				connection = nil
+			}
+
+			select {
+			case <-mailQueueWake:
+			case <-time.After(MAIL_QUEUE_POLL_INTERVAL):
+			}
+
+			continue
+		}
+
+		if connection == nil {
+			if newConnection, err := utils.OpenMailConnection(utils.Cfg); err != nil {
+				finishOutgoingEmail(email, &utils.MailError{AppError: err})
+				continue
+			} else {
+				connection = newConnection
+			}
+		}
+
+		if err := sendOutgoingEmail(email, connection.SendWithReplyTo); err != nil && !utils.IsPermanentMailError(err) {
+			// the connection may have been dropped so open a new one for the next email
+			connection.Close()
+			connection = nil
+		}
+	}
+}
+
+// sendOutgoingEmail sends a claimed email unless the recipient has reached the hourly limit, in which case
+// it's put back in the queue for later. The send function is passed in so that it can be tested without an
+// SMTP server.
+func sendOutgoingEmail(email *model.OutgoingEmail, send func(to, replyTo, subject, body string) *utils.MailError) *utils.MailError {
+	if limit := *utils.Cfg.EmailSettings.MaxEmailsPerRecipient; limit > 0 {
+		since := model.GetMillis() - int64(MAIL_QUEUE_RATE_LIMIT_PERIOD/time.Millisecond)
+
+		if result := <-Srv.Store.MailQueue().CountSentToSince(email.Recipient, since); result.Err != nil {
+			l4g.Error(utils.T("api.mail_queue.send.count.error"), email.Id, result.Err)
+		} else if result.Data.(int64) >= int64(limit) {
+			email.Status = model.OUTGOING_EMAIL_STATUS_PENDING
+			email.NextAttemptAt = model.GetMillis() + int64(MAIL_QUEUE_RATE_LIMIT_DELAY/time.Millisecond)
+			updateOutgoingEmail(email)
+			return nil
+		}
+	}
+
+	err := send(email.Recipient, email.ReplyTo, email.Subject, email.Body)
+	finishOutgoingEmail(email, err)
+
+	return err
+}
+
+// finishOutgoingEmail records the result of trying to send an email. Temporary failures are retried with
+// a growing delay until the maximum number of attempts is reached.
+func finishOutgoingEmail(email *model.OutgoingEmail, err *utils.MailError) {
+	email.Attempts++
+
+	if err == nil {
+		email.Status = model.OUTGOING_EMAIL_STATUS_SENT
+		email.LastError = ""
+	} else {
+		email.LastError = err.Error()
+
+		if utils.IsPermanentMailError(err) || email.Attempts >= *utils.Cfg.EmailSettings.MailQueueMaxAttempts {
+			l4g.Error(utils.T("api.mail_queue.send.failed.error"), email.Id, email.Recipient, err)
+			email.Status = model.OUTGOING_EMAIL_STATUS_FAILED
+		} else {
+			l4g.Warn(utils.T("api.mail_queue.send.retry.warn"), email.Id, email.Recipient, err)
+			email.Status = model.OUTGOING_EMAIL_STATUS_PENDING
+			email.NextAttemptAt = model.GetMillis() + int64(getMailQueueRetryDelay(email.Attempts)/time.Millisecond)
+		}
+	}
+
+	updateOutgoingEmail(email)
+}
+
+// getMailQueueRetryDelay returns how long to wait before retrying an email that has failed the given number of
+// times, doubling the delay after each attempt up to MAIL_QUEUE_MAX_RETRY_DELAY
+func getMailQueueRetryDelay(attempts int) time.Duration {
+	delay := MAIL_QUEUE_RETRY_DELAY
+	for i := 1; i < attempts && delay < MAIL_QUEUE_MAX_RETRY_DELAY; i++ {
+		delay *= 2
+	}
+
+	if delay > MAIL_QUEUE_MAX_RETRY_DELAY {
+		delay = MAIL_QUEUE_MAX_RETRY_DELAY
+	}
+
+	return delay
+}
+
+func updateOutgoingEmail(email *model.OutgoingEmail) {
+	if result := <-Srv.Store.MailQueue().Update(email); result.Err != nil {
+		l4g.Error(utils.T("api.mail_queue.update.error"), email.Id, result.Err)
+	}
+}
+
+func cleanMailQueue() {
+	now := model.GetMillis()
+
+	if result := <-Srv.Store.MailQueue().ResetStuck(now - int64(MAIL_QUEUE_STUCK_TIMEOUT/time.Millisecond)); result.Err != nil {
+		l4g.Error(utils.T("api.mail_queue.clean.error"), result.Err)
+	}
+
+	if result := <-Srv.Store.MailQueue().PermanentDeleteFinishedBefore(now - int64(MAIL_QUEUE_RETENTION/time.Millisecond)); result.Err != nil {
+		l4g.Error(utils.T("api.mail_queue.clean.error"), result.Err)
+	}
+}
+
+func getMailQueueStats(c *Context, w http.ResponseWriter, r *http.Request) {
+	if result := <-Srv.Store.MailQueue().GetStats(MAIL_QUEUE_FAILURES_SHOWN); result.Err != nil {
+		c.Err = result.Err
+	} else {
+		w.Write([]byte(result.Data.(*model.MailQueueStats).ToJson()))
+	}
+}
+
+func markEmailBouncing(c *Context, w http.ResponseWriter, r *http.Request) {
+	bounce := model.EmailBounceFromJson(r.Body)
+	if bounce == nil {
+		c.SetInvalidParam("markEmailBouncing", "bounce")
+		return
+	}
+
+	if err := MarkEmailBouncing(bounce.Email, bounce.Reason); err != nil {
+		err.StatusCode = http.StatusBadRequest
+		c.Err = err
+		return
+	}
+
+	c.LogAudit("email=" + bounce.Email)
+
+	ReturnStatusOK(w)
+}
+
+func unmarkEmailBouncing(c *Context, w http.ResponseWriter, r *http.Request) {
+	props := model.MapFromJson(r.Body)
+
+	email := props["email"]
+	if len(email) == 0 {
+		c.SetInvalidParam("unmarkEmailBouncing", "email")
+		return
+	}
+
+	if result := <-Srv.Store.MailQueue().DeleteBounce(email); result.Err != nil {
+		c.Err = result.Err
+		return
+	}
+
+	c.LogAudit("email=" + email)
+
+	ReturnStatusOK(w)
+}
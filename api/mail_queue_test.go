@@ -0,0 +1,147 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api
+
+import (
+	"testing"
+
+	"github.com/mattermost/platform/model"
+	"github.com/mattermost/platform/store"
+	"github.com/mattermost/platform/utils"
+)
+
+func TestSendOutgoingEmail(t *testing.T) {
+	Setup()
+
+	maxAttempts := *utils.Cfg.EmailSettings.MailQueueMaxAttempts
+	maxPerRecipient := *utils.Cfg.EmailSettings.MaxEmailsPerRecipient
+	defer func() {
+		*utils.Cfg.EmailSettings.MailQueueMaxAttempts = maxAttempts
+		*utils.Cfg.EmailSettings.MaxEmailsPerRecipient = maxPerRecipient
+	}()
+
+	*utils.Cfg.EmailSettings.MailQueueMaxAttempts = 2
+	*utils.Cfg.EmailSettings.MaxEmailsPerRecipient = 1
+
+	recipient := "success+" + model.NewId() + "@simulator.amazonses.com"
+
+	sent := 0
+	send := func(to, replyTo, subject, body string) *utils.MailError {
+		sent++
+		return nil
+	}
+	tempFail := func(to, replyTo, subject, body string) *utils.MailError {
+		return &utils.MailError{AppError: model.NewLocAppError("SendMail", "utils.mail.send_mail.to_address.app_error", nil, "451 Try again later"), ReplyCode: 451}
+	}
+
+	email := store.Must(Srv.Store.MailQueue().Save(&model.OutgoingEmail{Recipient: recipient, Subject: "subject", Body: "body"})).(*model.OutgoingEmail)
+
+	if err := sendOutgoingEmail(email, tempFail); err == nil {
+		t.Fatal("should have returned the error")
+	}
+
+	if email.Status != model.OUTGOING_EMAIL_STATUS_PENDING || email.Attempts != 1 || email.NextAttemptAt <= model.GetMillis() {
+		t.Fatal("should have scheduled a retry", email)
+	}
+
+	sendOutgoingEmail(email, tempFail)
+	if email.Status != model.OUTGOING_EMAIL_STATUS_FAILED || len(email.LastError) == 0 {
+		t.Fatal("should have failed after the maximum number of attempts", email)
+	}
+
+	email = store.Must(Srv.Store.MailQueue().Save(&model.OutgoingEmail{Recipient: recipient, Subject: "subject", Body: "body"})).(*model.OutgoingEmail)
+	if err := sendOutgoingEmail(email, send); err != nil {
+		t.Fatal(err)
+	}
+
+	if email.Status != model.OUTGOING_EMAIL_STATUS_SENT || sent != 1 {
+		t.Fatal("should have sent the email", email)
+	}
+
+	email = store.Must(Srv.Store.MailQueue().Save(&model.OutgoingEmail{Recipient: recipient, Subject: "subject", Body: "body"})).(*model.OutgoingEmail)
+	if err := sendOutgoingEmail(email, send); err != nil {
+		t.Fatal(err)
+	}
+
+	if email.Status != model.OUTGOING_EMAIL_STATUS_PENDING || email.Attempts != 0 || sent != 1 {
+		t.Fatal("should have held back the email over the hourly limit", email)
+	}
+
+	permanentFail := func(to, replyTo, subject, body string) *utils.MailError {
+		return &utils.MailError{AppError: model.NewLocAppError("SendMail", "utils.mail.send_mail.to_address.app_error", nil, "550 No such user"), ReplyCode: 550}
+	}
+
+	*utils.Cfg.EmailSettings.MaxEmailsPerRecipient = 0
+	sendOutgoingEmail(email, permanentFail)
+	if email.Status != model.OUTGOING_EMAIL_STATUS_FAILED || email.Attempts != 1 {
+		t.Fatal("shouldn't have retried a permanent failure", email)
+	}
+}
+
+func TestMailQueueAdmin(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	Client := th.BasicClient
+
+	if _, err := Client.GetMailQueueStats(); err == nil {
+		t.Fatal("should have failed without permissions")
+	}
+
+	if _, err := Client.MarkEmailBouncing(th.BasicUser2.Email, "550 No such user"); err == nil {
+		t.Fatal("should have failed without permissions")
+	}
+
+	if stats := th.SystemAdminClient.Must(th.SystemAdminClient.GetMailQueueStats()).Data.(*model.MailQueueStats); stats.Failures == nil && stats.Failed > 0 {
+		t.Fatal("should have returned the failures")
+	}
+
+	if _, err := th.SystemAdminClient.MarkEmailBouncing("invalid", ""); err == nil {
+		t.Fatal("should have failed with an invalid address")
+	}
+
+	th.SystemAdminClient.Must(th.SystemAdminClient.MarkEmailBouncing(th.BasicUser2.Email, "550 No such user"))
+
+	if bouncing := store.Must(Srv.Store.MailQueue().IsBouncing(th.BasicUser2.Email)).(bool); !bouncing {
+		t.Fatal("should have marked the address as bouncing")
+	}
+
+	sendEmailNotifications := utils.Cfg.EmailSettings.SendEmailNotifications
+	smtpServer := utils.Cfg.EmailSettings.SMTPServer
+	defer func() {
+		utils.Cfg.EmailSettings.SendEmailNotifications = sendEmailNotifications
+		utils.Cfg.EmailSettings.SMTPServer = smtpServer
+	}()
+
+	utils.Cfg.EmailSettings.SendEmailNotifications = true
+	utils.Cfg.EmailSettings.SMTPServer = "localhost"
+
+	before := store.Must(Srv.Store.MailQueue().GetStats(0)).(*model.MailQueueStats).Pending
+
+	if err := QueueNotificationMail(th.BasicUser2.Email, "subject", "body"); err != nil {
+		t.Fatal(err)
+	}
+
+	if after := store.Must(Srv.Store.MailQueue().GetStats(0)).(*model.MailQueueStats).Pending; after != before {
+		t.Fatal("shouldn't have queued a notification to a bouncing address")
+	}
+
+	th.SystemAdminClient.Must(th.SystemAdminClient.UnmarkEmailBouncing(th.BasicUser2.Email))
+
+	if bouncing := store.Must(Srv.Store.MailQueue().IsBouncing(th.BasicUser2.Email)).(bool); bouncing {
+		t.Fatal("should have unmarked the address")
+	}
+}
+
+func TestGetMailQueueRetryDelay(t *testing.T) {
+	if delay := getMailQueueRetryDelay(1); delay != MAIL_QUEUE_RETRY_DELAY {
+		t.Fatal("first retry should use the base delay", delay)
+	}
+
+	if delay := getMailQueueRetryDelay(3); delay != 4*MAIL_QUEUE_RETRY_DELAY {
+		t.Fatal("delay should double after each attempt", delay)
+	}
+
+	if delay := getMailQueueRetryDelay(100); delay != MAIL_QUEUE_MAX_RETRY_DELAY {
+		t.Fatal("delay should be capped", delay)
+	}
+}
@@ -0,0 +1,793 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	l4g "github.com/alecthomas/log4go"
+
+	"github.com/mattermost/platform/einterfaces"
+	"github.com/mattermost/platform/model"
+	"github.com/mattermost/platform/store"
+	"github.com/mattermost/platform/utils"
+)
+
+// NotificationChannel delivers notifications about new posts through a single medium such as email or push
+// notifications. Channels other than the built in ones can be added with RegisterNotificationChannel.
+type NotificationChannel interface {
+	// Name identifies the channel in metrics and in the notification_channels preferences of users
+	Name() string
+
+	// GetRecipients returns the ids of the users that should be notified through the channel
+	GetRecipients(n *PostNotification) []string
+
+	// Send delivers or queues the notification for the recipients without waiting on slow services. It's
+	// called even when there are no recipients left after users turned the channel off.
+	Send(n *PostNotification, userIds []string) *model.AppError
+}
+
+// BackgroundNotificationChannel is implemented by channels whose Send returns before the notification has been
+// delivered. They report the sent and failed metrics themselves once the result is known.
+type BackgroundNotificationChannel interface {
+	NotificationChannel
+
+	SendsInBackground()
+}
+
+var registeredNotificationChannelsMutex sync.Mutex
+var registeredNotificationChannels []NotificationChannel
+
+// RegisterNotificationChannel adds a channel that's notified of new posts after the built in ones
+func RegisterNotificationChannel(channel NotificationChannel) {
+	registeredNotificationChannelsMutex.Lock()
+	defer registeredNotificationChannelsMutex.Unlock()
+
+	registeredNotificationChannels = append(registeredNotificationChannels, channel)
+}
+
+func getNotificationChannels() []NotificationChannel {
+	channels := []NotificationChannel{
+		&EmailNotificationChannel{},
+		&PushNotificationChannel{},
+		&WebSocketNotificationChannel{},
+		&HttpNotificationChannel{},
+	}
+
+	registeredNotificationChannelsMutex.Lock()
+	defer registeredNotificationChannelsMutex.Unlock()
+
+	return append(channels, registeredNotificationChannels...)
+}
+
+// PostNotification is everything known about who to notify of a new post and how. It's filled in by each step
+// of the notification pipeline before being handed to the notification channels.
+type PostNotification struct {
+	Context *Context
+	Post    *model.Post
+	Channel *model.Channel
+	Team    *model.Team
+	Sender  *model.User
+
+	// SenderUsername is the username of the sender unless it was overridden by a webhook
+	SenderUsername string
+
+	Profiles           map[string]*model.User
+	ChannelNotifyProps map[string]model.StringMap
+	FileInfos          []*model.FileInfo
+
+	// MentionedUserIds are the users mentioned by name, through a group, by a reply to their thread or by
+	// a direct message
+	MentionedUserIds map[string]bool
+
+	// HereUserIds are the online users who were only mentioned by @here
+	HereUserIds map[string]bool
+
+	// ActivityUserIds are the users who get push notifications for all activity in the channel
+	ActivityUserIds []string
+
+	// OtherMentions are the usernames of the mentioned users who aren't in the channel
+	OtherMentions []string
+
+	// Thread is the root post and replies of the thread that the post replies to, if any
+	Thread *model.PostList
+
+	// ThreadMemberships are the participants in the thread that the post replies to
+	ThreadMemberships []*model.ThreadMembership
+
+	// ThreadParticipantIds are the users who join the thread of the post because they were mentioned by username in it
+	ThreadParticipantIds map[string]bool
+
+	HereMention    bool
+	ChannelMention bool
+	AllMention     bool
+
+	Statuses    map[string]*model.Status
+	SenderNames map[string]string
+
+	// DisabledChannels are the names of the notification channels turned off by each user
+	DisabledChannels map[string]map[string]bool
+
+	createAt time.Time
+}
+
+// GetMentionedUserIds returns the ids of all mentioned users, including the ones mentioned by @here
+func (n *PostNotification) GetMentionedUserIds() []string {
+	ids := make([]string, 0, len(n.MentionedUserIds)+len(n.HereUserIds))
+	for id := range n.MentionedUserIds {
+		ids = append(ids, id)
+	}
+	for id := range n.HereUserIds {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	return ids
+}
+
+func (n *PostNotification) WasMentioned(userId string) bool {
+	return n.MentionedUserIds[userId] || n.HereUserIds[userId]
+}
+
+// IsSuppressed returns true if the user muted the channel or was in their do not disturb hours when the post
+// was made. Those users are still mentioned but aren't notified.
+func (n *PostNotification) IsSuppressed(userId string) bool {
+	if profile, ok := n.Profiles[userId]; ok && profile.IsInDndSchedule(n.createAt) {
+		return true
+	}
+
+	return model.IsChannelMutedByNotifyProps(n.ChannelNotifyProps[userId])
+}
+
+// GetStatus returns the status of a user, who's assumed to be offline if it couldn't be loaded
+func (n *PostNotification) GetStatus(userId string) *model.Status {
+	if status, ok := n.Statuses[userId]; ok {
+		return status
+	}
+
+	return &model.Status{UserId: userId, Status: model.STATUS_OFFLINE}
+}
+
+// getRecipientIds returns the ids of every user who may be notified through any channel
+func (n *PostNotification) getRecipientIds() []string {
+	ids := n.GetMentionedUserIds()
+	for _, id := range n.ActivityUserIds {
+		if !n.WasMentioned(id) {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids
+}
+
+// notificationStore is the data used to send notifications. It's kept separate from the store so that each
+// step of the notification pipeline can be tested without a database.
+type notificationStore interface {
+	GetProfilesInChannel(channelId string) (map[string]*model.User, *model.AppError)
+	GetProfilesByIds(userIds []string) (map[string]*model.User, *model.AppError)
+	GetProfilesByUsernames(usernames []string, teamId string) (map[string]*model.User, *model.AppError)
+	GetChannelMembers(channelId string) ([]model.ChannelMember, *model.AppError)
+	GetFileInfosForPost(postId string) ([]*model.FileInfo, *model.AppError)
+	GetThread(rootId string) (*model.PostList, *model.AppError)
+	GetThreadMemberships(postId string) ([]*model.ThreadMembership, *model.AppError)
+	FollowThread(postId string, channelId string, userId string, refollow bool) *model.AppError
+	IncrementThreadUnreadReplies(postId string, userId string, replyAt int64) *model.AppError
+	ViewThread(postId string, userId string) *model.AppError
+	GetUserGroupsByNames(names []string) ([]*model.UserGroup, *model.AppError)
+	GetUserGroupMemberIds(groupId string) ([]string, *model.AppError)
+	GetOnlineStatuses() ([]*model.Status, *model.AppError)
+	GetStatus(userId string) (*model.Status, *model.AppError)
+	GetPreferenceCategory(userId string, category string) (model.Preferences, *model.AppError)
+	IncrementMentionCount(channelId string, userId string) *model.AppError
+}
+
+type sqlNotificationStore struct {
+	store store.Store
+}
+
+func (s sqlNotificationStore) GetProfilesInChannel(channelId string) (map[string]*model.User, *model.AppError) {
+	if result := <-s.store.User().GetProfilesInChannel(channelId, -1, -1, true); result.Err != nil {
+		return nil, result.Err
+	} else {
+		return result.Data.(map[string]*model.User), nil
+	}
+}
+
+func (s sqlNotificationStore) GetProfilesByIds(userIds []string) (map[string]*model.User, *model.AppError) {
+	if result := <-s.store.User().GetProfileByIds(userIds, true); result.Err != nil {
+		return nil, result.Err
+	} else {
+		return result.Data.(map[string]*model.User), nil
+	}
+}
+
+func (s sqlNotificationStore) GetProfilesByUsernames(usernames []string, teamId string) (map[string]*model.User, *model.AppError) {
+	if result := <-s.store.User().GetProfilesByUsernames(usernames, teamId); result.Err != nil {
+		return nil, result.Err
+	} else {
+		return result.Data.(map[string]*model.User), nil
+	}
+}
+
+func (s sqlNotificationStore) GetChannelMembers(channelId string) ([]model.ChannelMember, *model.AppError) {
+	if result := <-s.store.Channel().GetMembers(channelId); result.Err != nil {
+		return nil, result.Err
+	} else {
+		return result.Data.([]model.ChannelMember), nil
+	}
+}
+
+func (s sqlNotificationStore) GetFileInfosForPost(postId string) ([]*model.FileInfo, *model.AppError) {
+	if result := <-s.store.FileInfo().GetForPost(postId); result.Err != nil {
+		return nil, result.Err
+	} else {
+		return result.Data.([]*model.FileInfo), nil
+	}
+}
+
+func (s sqlNotificationStore) GetThread(rootId string) (*model.PostList, *model.AppError) {
+	if result := <-s.store.Post().Get(rootId); result.Err != nil {
+		return nil, result.Err
+	} else {
+		return result.Data.(*model.PostList), nil
+	}
+}
+
+func (s sqlNotificationStore) GetThreadMemberships(postId string) ([]*model.ThreadMembership, *model.AppError) {
+	if result := <-s.store.ThreadMembership().GetForPost(postId); result.Err != nil {
+		return nil, result.Err
+	} else {
+		return result.Data.([]*model.ThreadMembership), nil
+	}
+}
+
+func (s sqlNotificationStore) FollowThread(postId string, channelId string, userId string, refollow bool) *model.AppError {
+	return FollowThread(postId, channelId, userId, refollow)
+}
+
+func (s sqlNotificationStore) IncrementThreadUnreadReplies(postId string, userId string, replyAt int64) *model.AppError {
+	return (<-s.store.ThreadMembership().IncrementUnreadReplies(postId, userId, replyAt)).Err
+}
+
+func (s sqlNotificationStore) ViewThread(postId string, userId string) *model.AppError {
+	return ViewThread(postId, userId)
+}
+
+func (s sqlNotificationStore) GetUserGroupsByNames(names []string) ([]*model.UserGroup, *model.AppError) {
+	if result := <-s.store.UserGroup().GetByNames(names); result.Err != nil {
+		return nil, result.Err
+	} else {
+		return result.Data.([]*model.UserGroup), nil
+	}
+}
+
+func (s sqlNotificationStore) GetUserGroupMemberIds(groupId string) ([]string, *model.AppError) {
+	if result := <-s.store.UserGroup().GetMemberIds(groupId); result.Err != nil {
+		return nil, result.Err
+	} else {
+		return result.Data.([]string), nil
+	}
+}
+
+func (s sqlNotificationStore) GetOnlineStatuses() ([]*model.Status, *model.AppError) {
+	if result := <-s.store.Status().GetOnline(); result.Err != nil {
+		return nil, result.Err
+	} else {
+		return result.Data.([]*model.Status), nil
+	}
+}
+
+func (s sqlNotificationStore) GetStatus(userId string) (*model.Status, *model.AppError) {
+	return GetStatus(userId)
+}
+
+func (s sqlNotificationStore) GetPreferenceCategory(userId string, category string) (model.Preferences, *model.AppError) {
+	if result := <-s.store.Preference().GetCategory(userId, category); result.Err != nil {
+		return nil, result.Err
+	} else {
+		return result.Data.(model.Preferences), nil
+	}
+}
+
+func (s sqlNotificationStore) IncrementMentionCount(channelId string, userId string) *model.AppError {
+	return (<-s.store.Channel().IncrementMentionCount(channelId, userId)).Err
+}
+
+// notificationService works out who to notify of a new post and delivers the notifications through each of
+// its channels
+type notificationService struct {
+	store    notificationStore
+	channels []NotificationChannel
+}
+
+func newNotificationService(store notificationStore, channels []NotificationChannel) *notificationService {
+	return &notificationService{
+		store:    store,
+		channels: channels,
+	}
+}
+
+// sendNotifications notifies the users mentioned by a new post and returns the ids of those users
+func sendNotifications(c *Context, post *model.Post, team *model.Team, channel *model.Channel) []string {
+	return newNotificationService(sqlNotificationStore{Srv.Store}, getNotificationChannels()).SendNotifications(c, post, team, channel)
+}
+
+func (s *notificationService) SendNotifications(c *Context, post *model.Post, team *model.Team, channel *model.Channel) []string {
+	n, err := s.loadPostNotification(c, post, team, channel)
+	if err != nil {
+		return nil
+	}
+
+	if err := s.addMentions(n); err != nil {
+		return nil
+	}
+
+	s.warnOutOfChannelMentions(n)
+	s.limitChannelWideMentions(n)
+	s.addHereMentions(n)
+
+	// mention counts MUST be updated before push notifications are sent since they include the unread count
+	s.incrementMentionCounts(n)
+	s.updateThreadMemberships(n)
+
+	s.loadStatuses(n)
+	s.loadSenderNames(n)
+	s.loadDisabledChannels(n)
+
+	s.deliver(n)
+
+	return n.GetMentionedUserIds()
+}
+
+// loadPostNotification gets the members of the channel along with anything else needed to notify them
+func (s *notificationService) loadPostNotification(c *Context, post *model.Post, team *model.Team, channel *model.Channel) (*PostNotification, *model.AppError) {
+	n := &PostNotification{
+		Context:              c,
+		Post:                 post,
+		Channel:              channel,
+		Team:                 team,
+		ChannelNotifyProps:   make(map[string]model.StringMap),
+		MentionedUserIds:     make(map[string]bool),
+		HereUserIds:          make(map[string]bool),
+		ThreadParticipantIds: make(map[string]bool),
+		Statuses:             make(map[string]*model.Status),
+		SenderNames:          make(map[string]string),
+		DisabledChannels:     make(map[string]map[string]bool),
+		createAt:             time.Now(),
+	}
+
+	if profiles, err := s.store.GetProfilesInChannel(channel.Id); err != nil {
+		l4g.Error(utils.T("api.post.handle_post_events_and_forget.profiles.error"), c.TeamId, err)
+		return nil, err
+	} else {
+		n.Profiles = profiles
+	}
+
+	if members, err := s.store.GetChannelMembers(channel.Id); err != nil {
+		l4g.Error(utils.T("api.post.send_notifications_and_forget.members.error"), channel.Id, err)
+	} else {
+		for _, member := range members {
+			if _, ok := n.Profiles[member.UserId]; ok {
+				n.ChannelNotifyProps[member.UserId] = member.NotifyProps
+			}
+		}
+	}
+
+	if sender, ok := n.Profiles[post.UserId]; !ok {
+		l4g.Error(utils.T("api.post.send_notifications_and_forget.user_id.error"), post.UserId)
+		return nil, model.NewLocAppError("sendNotifications", "api.post.send_notifications_and_forget.sender.app_error", nil, "user_id="+post.UserId)
+	} else {
+		n.Sender = sender
+	}
+
+	if value, ok := post.Props["override_username"]; ok && post.Props["from_webhook"] == "true" {
+		n.SenderUsername = value.(string)
+	} else {
+		n.SenderUsername = n.Sender.Username
+	}
+
+	if len(post.FileIds) != 0 {
+		if infos, err := s.store.GetFileInfosForPost(post.Id); err != nil {
+			l4g.Warn(utils.T("api.post.send_notifications.files.error"), post.Id, err)
+		} else {
+			n.FileInfos = infos
+		}
+	}
+
+	if len(post.RootId) > 0 {
+		if list, err := s.store.GetThread(post.RootId); err != nil {
+			l4g.Error(utils.T("api.post.send_notifications_and_forget.comment_thread.error"), post.RootId, err)
+			return nil, err
+		} else {
+			n.Thread = list
+		}
+
+		if memberships, err := s.store.GetThreadMemberships(post.RootId); err != nil {
+			l4g.Error(utils.T("api.post.send_notifications_and_forget.thread_memberships.error"), post.RootId, err)
+		} else {
+			n.ThreadMemberships = memberships
+		}
+	}
+
+	return n, nil
+}
+
+// addMentions finds the users mentioned by the post along with the ones following all activity in the channel
+func (s *notificationService) addMentions(n *PostNotification) *model.AppError {
+	post := n.Post
+
+	if n.Channel.Type == model.CHANNEL_DIRECT {
+		var otherUserId string
+		if userIds := strings.Split(n.Channel.Name, "__"); userIds[0] == post.UserId {
+			otherUserId = userIds[1]
+		} else {
+			otherUserId = userIds[0]
+		}
+
+		n.MentionedUserIds[otherUserId] = true
+		if post.Props["from_webhook"] == "true" {
+			n.MentionedUserIds[post.UserId] = true
+		}
+
+		return nil
+	}
+
+	groups := s.getMentionedUserGroups(post.Message)
+	keywords := getMentionKeywordsInChannel(n.Profiles, groups)
+	addChannelMentionKeywords(keywords, n.ChannelNotifyProps)
+
+	n.MentionedUserIds, n.OtherMentions, n.HereMention, n.ChannelMention, n.AllMention = getExplicitMentions(post.Message, keywords)
+
+	// members of mentioned groups who aren't in the channel are warned about like any other user
+	n.OtherMentions = append(n.OtherMentions, s.getUserGroupMentionsNotInChannel(groups, n.Profiles)...)
+
+	// only mentioning someone by username makes them follow the thread, not @channel, @all, a group or a keyword
+	n.ThreadParticipantIds, _, _, _, _ = getExplicitMentions(post.Message, getUsernameMentionKeywords(n.Profiles))
+
+	if n.Thread != nil {
+		// get users that have comment thread mentions enabled
+		for _, threadPost := range n.Thread.Posts {
+			if profile, ok := n.Profiles[threadPost.UserId]; ok {
+				if profile.NotifyProps["comments"] == "any" || (profile.NotifyProps["comments"] == "root" && threadPost.Id == n.Thread.Order[0]) {
+					n.MentionedUserIds[threadPost.UserId] = true
+				}
+			}
+		}
+
+		// participants in the thread are notified of replies unless they muted it, even if they would otherwise
+		// be notified because of their comments setting
+		for _, membership := range n.ThreadMemberships {
+			if _, ok := n.Profiles[membership.UserId]; !ok {
+				continue
+			}
+
+			if membership.IsNotified() {
+				n.MentionedUserIds[membership.UserId] = true
+			} else if membership.Muted && !n.ThreadParticipantIds[membership.UserId] {
+				delete(n.MentionedUserIds, membership.UserId)
+			}
+		}
+	}
+
+	// prevent the user from mentioning themselves
+	if post.Props["from_webhook"] != "true" {
+		delete(n.MentionedUserIds, post.UserId)
+		delete(n.ThreadParticipantIds, post.UserId)
+	}
+
+	// find which users in the channel are set up to always receive mobile notifications
+	if !post.IsSystemMessage() {
+		for _, profile := range n.Profiles {
+			if profile.NotifyProps["push"] == model.USER_NOTIFY_ALL && (post.UserId != profile.Id || post.Props["from_webhook"] == "true") {
+				n.ActivityUserIds = append(n.ActivityUserIds, profile.Id)
+			}
+		}
+		sort.Strings(n.ActivityUserIds)
+	}
+
+	return nil
+}
+
+// getUsernameMentionKeywords returns the @username keywords of the users in the channel
+func getUsernameMentionKeywords(profiles map[string]*model.User) map[string][]string {
+	keywords := make(map[string][]string, len(profiles))
+	for id, profile := range profiles {
+		userMention := "@" + strings.ToLower(profile.Username)
+		keywords[userMention] = append(keywords[userMention], id)
+	}
+
+	return keywords
+}
+
+// Returns the user groups that may be mentioned in the message, keyed by name, along with the IDs of their members.
+func (s *notificationService) getMentionedUserGroups(message string) map[string][]string {
+	groups := make(map[string][]string)
+
+	var names []string
+	for _, word := range strings.Fields(message) {
+		for _, splitWord := range strings.FieldsFunc(word, func(c rune) bool { return model.SplitRunes[c] }) {
+			if strings.HasPrefix(splitWord, "@") && len(splitWord) > 1 {
+				names = append(names, strings.ToLower(splitWord[1:]))
+			}
+		}
+	}
+
+	if len(names) == 0 {
+		return groups
+	}
+
+	if result, err := s.store.GetUserGroupsByNames(names); err != nil {
+		l4g.Error(utils.T("api.post.send_notifications_and_forget.user_groups.error"), err)
+	} else {
+		for _, group := range result {
+			if ids, err := s.store.GetUserGroupMemberIds(group.Id); err != nil {
+				l4g.Error(utils.T("api.post.send_notifications_and_forget.user_groups.error"), err)
+			} else {
+				groups[group.Name] = ids
+			}
+		}
+	}
+
+	return groups
+}
+
+// Returns the usernames of the members of the mentioned user groups that aren't in the channel.
+func (s *notificationService) getUserGroupMentionsNotInChannel(groups map[string][]string, profiles map[string]*model.User) []string {
+	var ids []string
+	for _, memberIds := range groups {
+		for _, id := range memberIds {
+			if _, ok := profiles[id]; !ok {
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	var usernames []string
+	if len(ids) == 0 {
+		return usernames
+	}
+
+	if result, err := s.store.GetProfilesByIds(ids); err != nil {
+		l4g.Error(utils.T("api.post.send_notifications_and_forget.user_groups.error"), err)
+	} else {
+		for _, profile := range result {
+			usernames = append(usernames, profile.Username)
+		}
+	}
+
+	return usernames
+}
+
+// warnOutOfChannelMentions lets the poster know about the mentioned users on the team who won't see the post
+func (s *notificationService) warnOutOfChannelMentions(n *PostNotification) {
+	if len(n.OtherMentions) == 0 {
+		return
+	}
+
+	if profiles, err := s.store.GetProfilesByUsernames(n.OtherMentions, n.Team.Id); err == nil {
+		go sendOutOfChannelMentions(n.Context, n.Post, profiles)
+	}
+}
+
+// limitChannelWideMentions turns off @here in channels with too many members to notify and lets the poster know
+// that @here, @channel and @all had no effect
+func (s *notificationService) limitChannelWideMentions(n *PostNotification) {
+	maxNotifications := *utils.Cfg.TeamSettings.MaxNotificationsPerChannel
+	if int64(len(n.Profiles)) <= maxNotifications {
+		return
+	}
+
+	var messages []string
+	if n.HereMention {
+		n.HereMention = false
+		messages = append(messages, "api.post.disabled_here")
+	}
+
+	if n.ChannelMention {
+		messages = append(messages, "api.post.disabled_channel")
+	}
+
+	if n.AllMention {
+		messages = append(messages, "api.post.disabled_all")
+	}
+
+	for _, message := range messages {
+		SendEphemeralPost(
+			n.Context.TeamId,
+			n.Post.UserId,
+			&model.Post{
+				ChannelId: n.Post.ChannelId,
+				Message:   n.Context.T(message, map[string]interface{}{"Users": maxNotifications}),
+				CreateAt:  n.Post.CreateAt + 1,
+			},
+		)
+	}
+}
+
+// addHereMentions mentions the online members of the channel if the post contains @here
+func (s *notificationService) addHereMentions(n *PostNotification) {
+	if !n.HereMention {
+		return
+	}
+
+	if statuses, err := s.store.GetOnlineStatuses(); err != nil {
+		l4g.Warn(utils.T("api.post.notification.here.warn"), err)
+	} else {
+		for _, status := range statuses {
+			if status.UserId == n.Post.UserId {
+				continue
+			}
+
+			_, profileFound := n.Profiles[status.UserId]
+
+			if status.Status == model.STATUS_ONLINE && profileFound && !n.MentionedUserIds[status.UserId] {
+				n.HereUserIds[status.UserId] = true
+			}
+		}
+	}
+}
+
+func (s *notificationService) incrementMentionCounts(n *PostNotification) {
+	var wg sync.WaitGroup
+
+	for _, id := range n.GetMentionedUserIds() {
+		wg.Add(1)
+
+		go func(userId string) {
+			defer wg.Done()
+
+			if err := s.store.IncrementMentionCount(n.Post.ChannelId, userId); err != nil {
+				l4g.Warn(utils.T("api.post.update_mention_count_and_forget.update_error"), n.Post.Id, n.Post.ChannelId, err)
+			}
+		}(id)
+	}
+
+	wg.Wait()
+}
+
+// updateThreadMemberships has the poster and the users mentioned by the post follow its thread and counts the
+// reply as unread for everyone else following it
+func (s *notificationService) updateThreadMemberships(n *PostNotification) {
+	post := n.Post
+
+	threadId := post.RootId
+	if len(threadId) == 0 {
+		threadId = post.Id
+	}
+
+	followers := make(map[string]bool)
+	for id := range n.ThreadParticipantIds {
+		followers[id] = true
+	}
+
+	if !post.IsSystemMessage() && post.Props["from_webhook"] != "true" && (len(post.RootId) > 0 || len(followers) > 0) {
+		followers[post.UserId] = true
+	}
+
+	for id := range followers {
+		if err := s.store.FollowThread(threadId, post.ChannelId, id, true); err != nil {
+			l4g.Warn(utils.T("api.post.send_notifications_and_forget.follow_thread.warn"), threadId, id, err)
+		}
+	}
+
+	if len(post.RootId) == 0 || n.Thread == nil {
+		return
+	}
+
+	// the author of the root post starts following it on the first reply
+	if root, ok := n.Thread.Posts[post.RootId]; ok && !followers[root.UserId] && !root.IsSystemMessage() && root.Props["from_webhook"] != "true" {
+		if err := s.store.FollowThread(post.RootId, post.ChannelId, root.UserId, false); err != nil {
+			l4g.Warn(utils.T("api.post.send_notifications_and_forget.follow_thread.warn"), post.RootId, root.UserId, err)
+		}
+	}
+
+	if err := s.store.IncrementThreadUnreadReplies(post.RootId, post.UserId, post.CreateAt); err != nil {
+		l4g.Warn(utils.T("api.post.send_notifications_and_forget.thread_unread.warn"), post.RootId, err)
+		return
+	}
+
+	// the poster has seen their own reply
+	if followers[post.UserId] {
+		if err := s.store.ViewThread(post.RootId, post.UserId); err != nil {
+			l4g.Warn(utils.T("api.post.send_notifications_and_forget.thread_unread.warn"), post.RootId, err)
+		}
+	}
+
+	if memberships, err := s.store.GetThreadMemberships(post.RootId); err == nil {
+		for _, membership := range memberships {
+			if membership.IsNotified() && membership.UserId != post.UserId {
+				publishThreadUpdated(membership)
+			}
+		}
+	}
+}
+
+func (s *notificationService) loadStatuses(n *PostNotification) {
+	for _, id := range n.getRecipientIds() {
+		if status, err := s.store.GetStatus(id); err == nil {
+			n.Statuses[id] = status
+		}
+	}
+}
+
+// loadSenderNames gets the name of the sender in the display format preferred by each recipient
+func (s *notificationService) loadSenderNames(n *PostNotification) {
+	for _, id := range n.getRecipientIds() {
+		if n.Post.IsSystemMessage() {
+			n.SenderNames[id] = n.Context.T("system.message.name")
+		} else if value, ok := n.Post.Props["override_username"]; ok && n.Post.Props["from_webhook"] == "true" {
+			n.SenderNames[id] = value.(string)
+		} else {
+			// show the sender's username if the recipient hasn't picked a display format
+			n.SenderNames[id] = n.Sender.Username
+
+			if preferences, err := s.store.GetPreferenceCategory(id, model.PREFERENCE_CATEGORY_DISPLAY_SETTINGS); err == nil {
+				for _, preference := range preferences {
+					if preference.Name == model.PREFERENCE_NAME_DISPLAY_NAME_FORMAT {
+						n.SenderNames[id] = n.Sender.GetDisplayNameForPreference(preference.Value)
+					}
+				}
+			}
+		}
+	}
+}
+
+// loadDisabledChannels gets the notification channels that each recipient turned off
+func (s *notificationService) loadDisabledChannels(n *PostNotification) {
+	for _, id := range n.getRecipientIds() {
+		if preferences, err := s.store.GetPreferenceCategory(id, model.PREFERENCE_CATEGORY_NOTIFICATION_CHANNELS); err == nil {
+			for _, preference := range preferences {
+				if preference.Value == "false" {
+					if n.DisabledChannels[id] == nil {
+						n.DisabledChannels[id] = make(map[string]bool)
+					}
+
+					n.DisabledChannels[id][preference.Name] = true
+				}
+			}
+		}
+	}
+}
+
+// deliver sends the notification through each channel to the recipients who haven't turned it off
+func (s *notificationService) deliver(n *PostNotification) {
+	for _, channel := range s.channels {
+		name := channel.Name()
+
+		userIds := []string{}
+		for _, id := range channel.GetRecipients(n) {
+			if !n.DisabledChannels[id][name] {
+				userIds = append(userIds, id)
+			}
+		}
+
+		err := channel.Send(n, userIds)
+		if err != nil {
+			l4g.Error(utils.T("api.notification.deliver.error"), name, n.Post.Id, err)
+		}
+
+		if _, ok := channel.(BackgroundNotificationChannel); !ok || err != nil {
+			countNotifications(name, len(userIds), err == nil)
+		}
+	}
+}
+
+// countNotifications records whether the notifications sent through a channel were delivered
+func countNotifications(name string, count int, sent bool) {
+	metrics := einterfaces.GetMetricsInterface()
+	if metrics == nil {
+		return
+	}
+
+	for i := 0; i < count; i++ {
+		if sent {
+			metrics.IncrementNotificationSent(name)
+		} else {
+			metrics.IncrementNotificationFailure(name)
+		}
+	}
+}
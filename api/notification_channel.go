@@ -0,0 +1,262 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	l4g "github.com/alecthomas/log4go"
+
+	"github.com/mattermost/platform/model"
+	"github.com/mattermost/platform/utils"
+)
+
+const (
+	NOTIFICATION_CHANNEL_EMAIL     = "email"
+	NOTIFICATION_CHANNEL_PUSH      = "push"
+	NOTIFICATION_CHANNEL_WEBSOCKET = "websocket"
+	NOTIFICATION_CHANNEL_HTTP      = "http"
+
+	HTTP_NOTIFICATION_TIMEOUT = 30 * time.Second
+)
+
+// EmailNotificationChannel emails mentioned users who aren't online
+type EmailNotificationChannel struct{}
+
+func (ch *EmailNotificationChannel) Name() string {
+	return NOTIFICATION_CHANNEL_EMAIL
+}
+
+func (ch *EmailNotificationChannel) GetRecipients(n *PostNotification) []string {
+	if !utils.Cfg.EmailSettings.SendEmailNotifications {
+		return nil
+	}
+
+	var userIds []string
+	for id := range n.MentionedUserIds {
+		if profile, ok := n.Profiles[id]; !ok || profile.NotifyProps["email"] == "false" {
+			continue
+		}
+
+		if n.GetStatus(id).Status != model.STATUS_ONLINE && !n.IsSuppressed(id) {
+			userIds = append(userIds, id)
+		}
+	}
+	sort.Strings(userIds)
+
+	return userIds
+}
+
+func (ch *EmailNotificationChannel) Send(n *PostNotification, userIds []string) *model.AppError {
+	for _, id := range userIds {
+		sendNotificationEmail(n.Context, n.Post, n.Profiles[id], n.Channel, n.Team, n.SenderNames[id], n.Sender)
+	}
+
+	return nil
+}
+
+// PushNotificationChannel sends push notifications to the mobile devices of mentioned users and of those who
+// follow all activity in the channel
+type PushNotificationChannel struct{}
+
+func (ch *PushNotificationChannel) Name() string {
+	return NOTIFICATION_CHANNEL_PUSH
+}
+
+func (ch *PushNotificationChannel) GetRecipients(n *PostNotification) []string {
+	if !*utils.Cfg.EmailSettings.SendPushNotifications {
+		return nil
+	}
+
+	pushServer := *utils.Cfg.EmailSettings.PushNotificationServer
+	if *utils.Cfg.EmailSettings.PushNotificationSender == model.PUSH_SENDER_PROXY && pushServer == model.MHPNS && (!utils.IsLicensed || !*utils.License.Features.MHPNS) {
+		l4g.Warn(utils.T("api.post.send_notifications_and_forget.push_notification.mhpnsWarn"))
+		return nil
+	}
+
+	var userIds []string
+	for _, id := range n.getRecipientIds() {
+		if profile, ok := n.Profiles[id]; ok && DoesStatusAllowPushNotification(profile, n.ChannelNotifyProps[id], n.GetStatus(id), n.Post.ChannelId) {
+			userIds = append(userIds, id)
+		}
+	}
+
+	return userIds
+}
+
+func (ch *PushNotificationChannel) Send(n *PostNotification, userIds []string) *model.AppError {
+	for _, id := range userIds {
+		sendPushNotification(n.Post, n.Profiles[id], n.Channel, n.SenderNames[id], n.WasMentioned(id))
+	}
+
+	return nil
+}
+
+// WebSocketNotificationChannel publishes the post to the clients of the channel members. Mentioned users are
+// listed in the event so that their clients show a desktop notification.
+type WebSocketNotificationChannel struct{}
+
+func (ch *WebSocketNotificationChannel) Name() string {
+	return NOTIFICATION_CHANNEL_WEBSOCKET
+}
+
+func (ch *WebSocketNotificationChannel) GetRecipients(n *PostNotification) []string {
+	now := model.GetMillis()
+
+	// users set to do not disturb are still mentioned but don't get a desktop notification
+	userIds := []string{}
+	for _, id := range n.GetMentionedUserIds() {
+		if !n.IsSuppressed(id) && !n.GetStatus(id).IsDND(now) {
+			userIds = append(userIds, id)
+		}
+	}
+
+	return userIds
+}
+
+func (ch *WebSocketNotificationChannel) Send(n *PostNotification, userIds []string) *model.AppError {
+	message := model.NewWebSocketEvent(model.WEBSOCKET_EVENT_POSTED, "", n.Post.ChannelId, "", nil)
+	message.Add("post", n.Post.ToJson())
+	message.Add("channel_type", n.Channel.Type)
+	message.Add("channel_display_name", n.Channel.DisplayName)
+	message.Add("channel_name", n.Channel.Name)
+	message.Add("sender_name", n.SenderUsername)
+	message.Add("team_id", n.Team.Id)
+
+	if len(n.Post.FileIds) != 0 {
+		message.Add("otherFile", "true")
+
+		for _, info := range n.FileInfos {
+			if info.IsImage() {
+				message.Add("image", "true")
+				break
+			}
+		}
+	}
+
+	if len(userIds) != 0 {
+		message.Add("mentions", model.ArrayToJson(userIds))
+	}
+
+	Publish(message)
+	return nil
+}
+
+// HttpNotificationChannel posts the mentions to the URL set in HttpNotificationURL so that they can be
+// forwarded to services like paging or chat systems
+type HttpNotificationChannel struct{}
+
+type httpNotificationPayload struct {
+	PostId             string   `json:"post_id"`
+	ChannelId          string   `json:"channel_id"`
+	ChannelName        string   `json:"channel_name"`
+	ChannelDisplayName string   `json:"channel_display_name"`
+	ChannelType        string   `json:"channel_type"`
+	TeamId             string   `json:"team_id"`
+	TeamName           string   `json:"team_name"`
+	SenderId           string   `json:"sender_id"`
+	SenderName         string   `json:"sender_name"`
+	Message            string   `json:"message"`
+	CreateAt           int64    `json:"create_at"`
+	UserIds            []string `json:"user_ids"`
+	Usernames          []string `json:"usernames"`
+}
+
+func (ch *HttpNotificationChannel) Name() string {
+	return NOTIFICATION_CHANNEL_HTTP
+}
+
+func (ch *HttpNotificationChannel) GetRecipients(n *PostNotification) []string {
+	if len(*utils.Cfg.EmailSettings.HttpNotificationURL) == 0 {
+		return nil
+	}
+
+	var userIds []string
+	for _, id := range n.GetMentionedUserIds() {
+		if _, ok := n.Profiles[id]; ok && !n.IsSuppressed(id) {
+			userIds = append(userIds, id)
+		}
+	}
+
+	return userIds
+}
+
+func (ch *HttpNotificationChannel) Send(n *PostNotification, userIds []string) *model.AppError {
+	if len(userIds) == 0 {
+		return nil
+	}
+
+	payload := &httpNotificationPayload{
+		PostId:             n.Post.Id,
+		ChannelId:          n.Channel.Id,
+		ChannelName:        n.Channel.Name,
+		ChannelDisplayName: n.Channel.DisplayName,
+		ChannelType:        n.Channel.Type,
+		TeamId:             n.Team.Id,
+		TeamName:           n.Team.Name,
+		SenderId:           n.Post.UserId,
+		SenderName:         n.SenderUsername,
+		Message:            model.ClearMentionTags(n.Post.Message),
+		CreateAt:           n.Post.CreateAt,
+		UserIds:            userIds,
+	}
+
+	for _, id := range userIds {
+		payload.Usernames = append(payload.Usernames, n.Profiles[id].Username)
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return model.NewLocAppError("HttpNotificationChannel.Send", "api.notification.http.marshal.app_error", nil, err.Error())
+	}
+
+	go func() {
+		if err := postHttpNotification(*utils.Cfg.EmailSettings.HttpNotificationURL, body); err != nil {
+			l4g.Error(utils.T("api.notification.deliver.error"), NOTIFICATION_CHANNEL_HTTP, payload.PostId, err)
+			countNotifications(NOTIFICATION_CHANNEL_HTTP, len(userIds), false)
+		} else {
+			countNotifications(NOTIFICATION_CHANNEL_HTTP, len(userIds), true)
+		}
+	}()
+
+	return nil
+}
+
+// SendsInBackground marks the channel as a BackgroundNotificationChannel since the POST can take up to
+// HTTP_NOTIFICATION_TIMEOUT
+func (ch *HttpNotificationChannel) SendsInBackground() {}
+
+func postHttpNotification(url string, body []byte) *model.AppError {
+	client := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: *utils.Cfg.ServiceSettings.EnableInsecureOutgoingConnections},
+		},
+		Timeout: HTTP_NOTIFICATION_TIMEOUT,
+	}
+
+	req, _ := http.NewRequest("POST", url, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	if resp, err := client.Do(req); err != nil {
+		return model.NewLocAppError("postHttpNotification", "api.notification.http.send.app_error", nil, err.Error())
+	} else {
+		defer func() {
+			ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+		}()
+
+		if resp.StatusCode >= 300 {
+			return model.NewLocAppError("postHttpNotification", "api.notification.http.send.app_error", nil, "status="+strconv.Itoa(resp.StatusCode))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,688 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mattermost/platform/model"
+	"github.com/mattermost/platform/utils"
+)
+
+type fakeNotificationStore struct {
+	profiles       map[string]*model.User
+	members        []model.ChannelMember
+	threads        map[string]*model.PostList
+	memberships    map[string]map[string]*model.ThreadMembership
+	groups         map[string]*model.UserGroup
+	groupMembers   map[string][]string
+	statuses       map[string]*model.Status
+	preferences    map[string]model.Preferences
+	mentionsMutex  sync.Mutex
+	mentionCounts  map[string]int
+	teamUsernames  map[string]bool
+	getThreadError bool
+}
+
+func newFakeNotificationStore(profiles ...*model.User) *fakeNotificationStore {
+	s := &fakeNotificationStore{
+		profiles:      make(map[string]*model.User),
+		threads:       make(map[string]*model.PostList),
+		memberships:   make(map[string]map[string]*model.ThreadMembership),
+		groups:        make(map[string]*model.UserGroup),
+		groupMembers:  make(map[string][]string),
+		statuses:      make(map[string]*model.Status),
+		preferences:   make(map[string]model.Preferences),
+		mentionCounts: make(map[string]int),
+		teamUsernames: make(map[string]bool),
+	}
+
+	for _, profile := range profiles {
+		s.profiles[profile.Id] = profile
+		s.members = append(s.members, model.ChannelMember{UserId: profile.Id, NotifyProps: model.GetDefaultChannelNotifyProps()})
+	}
+
+	return s
+}
+
+func (s *fakeNotificationStore) GetProfilesInChannel(channelId string) (map[string]*model.User, *model.AppError) {
+	return s.profiles, nil
+}
+
+func (s *fakeNotificationStore) GetProfilesByIds(userIds []string) (map[string]*model.User, *model.AppError) {
+	profiles := make(map[string]*model.User)
+	for _, id := range userIds {
+		profiles[id] = &model.User{Id: id, Username: "user-" + id}
+	}
+
+	return profiles, nil
+}
+
+func (s *fakeNotificationStore) GetProfilesByUsernames(usernames []string, teamId string) (map[string]*model.User, *model.AppError) {
+	profiles := make(map[string]*model.User)
+	for _, username := range usernames {
+		if s.teamUsernames[username] {
+			profiles[username] = &model.User{Id: model.NewId(), Username: username}
+		}
+	}
+
+	return profiles, nil
+}
+
+func (s *fakeNotificationStore) GetChannelMembers(channelId string) ([]model.ChannelMember, *model.AppError) {
+	return s.members, nil
+}
+
+func (s *fakeNotificationStore) GetFileInfosForPost(postId string) ([]*model.FileInfo, *model.AppError) {
+	return []*model.FileInfo{}, nil
+}
+
+func (s *fakeNotificationStore) GetThread(rootId string) (*model.PostList, *model.AppError) {
+	if list, ok := s.threads[rootId]; ok && !s.getThreadError {
+		return list, nil
+	}
+
+	return nil, model.NewLocAppError("GetThread", "store.sql_post.get.app_error", nil, "")
+}
+
+func (s *fakeNotificationStore) GetThreadMemberships(postId string) ([]*model.ThreadMembership, *model.AppError) {
+	memberships := []*model.ThreadMembership{}
+	for _, membership := range s.memberships[postId] {
+		memberships = append(memberships, membership)
+	}
+
+	return memberships, nil
+}
+
+func (s *fakeNotificationStore) FollowThread(postId string, channelId string, userId string, refollow bool) *model.AppError {
+	if s.memberships[postId] == nil {
+		s.memberships[postId] = make(map[string]*model.ThreadMembership)
+	}
+
+	if membership, ok := s.memberships[postId][userId]; ok {
+		membership.Following = membership.Following || refollow
+	} else {
+		s.memberships[postId][userId] = &model.ThreadMembership{PostId: postId, UserId: userId, ChannelId: channelId, Following: true}
+	}
+
+	return nil
+}
+
+func (s *fakeNotificationStore) IncrementThreadUnreadReplies(postId string, userId string, replyAt int64) *model.AppError {
+	for _, membership := range s.memberships[postId] {
+		membership.LastReplyAt = replyAt
+		if membership.IsNotified() && membership.UserId != userId {
+			membership.UnreadReplies++
+		}
+	}
+
+	return nil
+}
+
+func (s *fakeNotificationStore) ViewThread(postId string, userId string) *model.AppError {
+	if membership, ok := s.memberships[postId][userId]; ok {
+		membership.UnreadReplies = 0
+	}
+
+	return nil
+}
+
+func (s *fakeNotificationStore) GetUserGroupsByNames(names []string) ([]*model.UserGroup, *model.AppError) {
+	groups := []*model.UserGroup{}
+	for _, name := range names {
+		if group, ok := s.groups[name]; ok {
+			groups = append(groups, group)
+		}
+	}
+
+	return groups, nil
+}
+
+func (s *fakeNotificationStore) GetUserGroupMemberIds(groupId string) ([]string, *model.AppError) {
+	return s.groupMembers[groupId], nil
+}
+
+func (s *fakeNotificationStore) GetOnlineStatuses() ([]*model.Status, *model.AppError) {
+	statuses := []*model.Status{}
+	for _, status := range s.statuses {
+		if status.Status == model.STATUS_ONLINE {
+			statuses = append(statuses, status)
+		}
+	}
+
+	return statuses, nil
+}
+
+func (s *fakeNotificationStore) GetStatus(userId string) (*model.Status, *model.AppError) {
+	if status, ok := s.statuses[userId]; ok {
+		return status, nil
+	}
+
+	return nil, model.NewLocAppError("GetStatus", "store.sql_status.get.missing.app_error", nil, "")
+}
+
+func (s *fakeNotificationStore) GetPreferenceCategory(userId string, category string) (model.Preferences, *model.AppError) {
+	preferences := model.Preferences{}
+	for _, preference := range s.preferences[userId] {
+		if preference.Category == category {
+			preferences = append(preferences, preference)
+		}
+	}
+
+	return preferences, nil
+}
+
+func (s *fakeNotificationStore) IncrementMentionCount(channelId string, userId string) *model.AppError {
+	s.mentionsMutex.Lock()
+	defer s.mentionsMutex.Unlock()
+
+	s.mentionCounts[userId]++
+	return nil
+}
+
+type fakeNotificationChannel struct {
+	name       string
+	recipients func(n *PostNotification) []string
+	sent       []string
+	calls      int
+}
+
+func (ch *fakeNotificationChannel) Name() string {
+	return ch.name
+}
+
+func (ch *fakeNotificationChannel) GetRecipients(n *PostNotification) []string {
+	return ch.recipients(n)
+}
+
+func (ch *fakeNotificationChannel) Send(n *PostNotification, userIds []string) *model.AppError {
+	ch.calls++
+	ch.sent = append(ch.sent, userIds...)
+	return nil
+}
+
+func setupNotificationTest() {
+	utils.TranslationsPreInit()
+	utils.LoadConfig("config.json")
+}
+
+func newTestNotificationUser(username string) *model.User {
+	user := &model.User{Id: model.NewId(), Username: username}
+	user.SetDefaultNotifications()
+	return user
+}
+
+func newTestNotificationContext() *Context {
+	return &Context{
+		RequestId: model.NewId(),
+		T:         utils.TfuncWithFallback(model.DEFAULT_LOCALE),
+	}
+}
+
+func TestNotificationServiceAddMentions(t *testing.T) {
+	setupNotificationTest()
+
+	sender := newTestNotificationUser("sender")
+	user1 := newTestNotificationUser("user1")
+	user2 := newTestNotificationUser("user2")
+	user2.NotifyProps["push"] = model.USER_NOTIFY_ALL
+	fakeStore := newFakeNotificationStore(sender, user1, user2)
+
+	channel := &model.Channel{Id: model.NewId(), Type: model.CHANNEL_OPEN}
+	team := &model.Team{Id: model.NewId()}
+	service := newNotificationService(fakeStore, nil)
+
+	post := &model.Post{Id: model.NewId(), UserId: sender.Id, ChannelId: channel.Id, Message: "hey @user1 and @sender and @outsider and @nobody"}
+	fakeStore.teamUsernames["outsider"] = true
+
+	n, err := service.loadPostNotification(newTestNotificationContext(), post, team, channel)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := service.addMentions(n); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(n.MentionedUserIds) != 1 || !n.MentionedUserIds[user1.Id] {
+		t.Fatal("should have only mentioned user1", n.MentionedUserIds)
+	}
+
+	if len(n.OtherMentions) != 2 {
+		t.Fatal("should have found the users who aren't in the channel", n.OtherMentions)
+	}
+
+	if len(n.ActivityUserIds) != 1 || n.ActivityUserIds[0] != user2.Id {
+		t.Fatal("should have found the user following all activity", n.ActivityUserIds)
+	}
+
+	// replies notify the users who enabled comment notifications
+	user2.NotifyProps["comments"] = "any"
+	rootId := model.NewId()
+	fakeStore.threads[rootId] = &model.PostList{
+		Order: []string{rootId},
+		Posts: map[string]*model.Post{rootId: {Id: rootId, UserId: user2.Id}},
+	}
+
+	reply := &model.Post{Id: model.NewId(), UserId: sender.Id, ChannelId: channel.Id, RootId: rootId, Message: "reply"}
+	n, _ = service.loadPostNotification(newTestNotificationContext(), reply, team, channel)
+	if err := service.addMentions(n); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(n.MentionedUserIds) != 1 || !n.MentionedUserIds[user2.Id] {
+		t.Fatal("should have mentioned the user in the thread", n.MentionedUserIds)
+	}
+
+	fakeStore.getThreadError = true
+	if _, err := service.loadPostNotification(newTestNotificationContext(), reply, team, channel); err == nil {
+		t.Fatal("should have failed without the thread")
+	}
+	fakeStore.getThreadError = false
+
+	// groups mention their members in the channel and warn about the others
+	group := &model.UserGroup{Id: model.NewId(), Name: "devs"}
+	fakeStore.groups[group.Name] = group
+	fakeStore.groupMembers[group.Id] = []string{user1.Id, user2.Id, "outsideruserid"}
+
+	groupPost := &model.Post{Id: model.NewId(), UserId: sender.Id, ChannelId: channel.Id, Message: "@devs please review"}
+	n, _ = service.loadPostNotification(newTestNotificationContext(), groupPost, team, channel)
+	if err := service.addMentions(n); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(n.MentionedUserIds) != 2 || !n.MentionedUserIds[user1.Id] || !n.MentionedUserIds[user2.Id] {
+		t.Fatal("should have mentioned the group members", n.MentionedUserIds)
+	}
+
+	if len(n.OtherMentions) != 1 || n.OtherMentions[0] != "user-outsideruserid" {
+		t.Fatal("should have warned about the group member outside the channel", n.OtherMentions)
+	}
+
+	// direct messages always mention the other user
+	dm := &model.Channel{Id: model.NewId(), Type: model.CHANNEL_DIRECT, Name: model.GetDMNameFromIds(sender.Id, user1.Id)}
+	dmPost := &model.Post{Id: model.NewId(), UserId: sender.Id, ChannelId: dm.Id, Message: "hi"}
+	n, _ = service.loadPostNotification(newTestNotificationContext(), dmPost, team, dm)
+	if err := service.addMentions(n); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(n.MentionedUserIds) != 1 || !n.MentionedUserIds[user1.Id] {
+		t.Fatal("should have mentioned the other user in the direct channel", n.MentionedUserIds)
+	}
+
+	if _, err := service.loadPostNotification(newTestNotificationContext(), &model.Post{UserId: model.NewId()}, team, channel); err == nil {
+		t.Fatal("should have failed for a sender outside of the channel")
+	}
+}
+
+func TestNotificationServiceHereMentions(t *testing.T) {
+	setupNotificationTest()
+
+	sender := newTestNotificationUser("sender")
+	online := newTestNotificationUser("online")
+	away := newTestNotificationUser("away")
+	fakeStore := newFakeNotificationStore(sender, online, away)
+	fakeStore.statuses[sender.Id] = &model.Status{UserId: sender.Id, Status: model.STATUS_ONLINE}
+	fakeStore.statuses[online.Id] = &model.Status{UserId: online.Id, Status: model.STATUS_ONLINE}
+	fakeStore.statuses[away.Id] = &model.Status{UserId: away.Id, Status: model.STATUS_AWAY}
+	fakeStore.statuses["otherchannel"] = &model.Status{UserId: "otherchannel", Status: model.STATUS_ONLINE}
+
+	channel := &model.Channel{Id: model.NewId(), Type: model.CHANNEL_OPEN}
+	service := newNotificationService(fakeStore, nil)
+
+	post := &model.Post{Id: model.NewId(), UserId: sender.Id, ChannelId: channel.Id, Message: "@here lunch?"}
+	n, _ := service.loadPostNotification(newTestNotificationContext(), post, &model.Team{Id: model.NewId()}, channel)
+	if err := service.addMentions(n); err != nil {
+		t.Fatal(err)
+	}
+
+	service.addHereMentions(n)
+
+	if len(n.HereUserIds) != 1 || !n.HereUserIds[online.Id] {
+		t.Fatal("should have only mentioned the online member of the channel", n.HereUserIds)
+	}
+
+	if !n.WasMentioned(online.Id) || n.WasMentioned(away.Id) {
+		t.Fatal("should have been mentioned by @here")
+	}
+
+	service.incrementMentionCounts(n)
+
+	if len(fakeStore.mentionCounts) != 1 || fakeStore.mentionCounts[online.Id] != 1 {
+		t.Fatal("should have incremented the mention count of the online user", fakeStore.mentionCounts)
+	}
+
+	// @here is turned off in channels with too many members
+	maxNotifications := *utils.Cfg.TeamSettings.MaxNotificationsPerChannel
+	defer func() {
+		*utils.Cfg.TeamSettings.MaxNotificationsPerChannel = maxNotifications
+	}()
+	*utils.Cfg.TeamSettings.MaxNotificationsPerChannel = 2
+
+	n, _ = service.loadPostNotification(newTestNotificationContext(), post, &model.Team{Id: model.NewId()}, channel)
+	service.addMentions(n)
+	service.limitChannelWideMentions(n)
+	service.addHereMentions(n)
+
+	if len(n.HereUserIds) != 0 {
+		t.Fatal("shouldn't have mentioned anyone with @here", n.HereUserIds)
+	}
+}
+
+func TestNotificationServiceThreads(t *testing.T) {
+	setupNotificationTest()
+
+	author := newTestNotificationUser("author")
+	replier := newTestNotificationUser("replier")
+	mentioned := newTestNotificationUser("mentioned")
+	muter := newTestNotificationUser("muter")
+	muter.NotifyProps["comments"] = "any"
+	fakeStore := newFakeNotificationStore(author, replier, mentioned, muter)
+
+	channel := &model.Channel{Id: model.NewId(), Type: model.CHANNEL_OPEN}
+	team := &model.Team{Id: model.NewId()}
+	service := newNotificationService(fakeStore, nil)
+
+	root := &model.Post{Id: model.NewId(), UserId: author.Id, ChannelId: channel.Id, Message: "question"}
+	muterReply := &model.Post{Id: model.NewId(), UserId: muter.Id, ChannelId: channel.Id, RootId: root.Id, Message: "me too"}
+	fakeStore.threads[root.Id] = &model.PostList{
+		Order: []string{root.Id, muterReply.Id},
+		Posts: map[string]*model.Post{root.Id: root, muterReply.Id: muterReply},
+	}
+	fakeStore.memberships[root.Id] = map[string]*model.ThreadMembership{
+		muter.Id: {PostId: root.Id, UserId: muter.Id, ChannelId: channel.Id, Following: true, Muted: true},
+	}
+
+	// the first reply makes the author, the replier and the mentioned user follow the thread
+	reply := &model.Post{Id: model.NewId(), UserId: replier.Id, ChannelId: channel.Id, RootId: root.Id, Message: "try this @mentioned", CreateAt: 1234}
+	mentions := service.SendNotifications(newTestNotificationContext(), reply, team, channel)
+
+	if len(mentions) != 1 || mentions[0] != mentioned.Id {
+		t.Fatal("should have only mentioned the mentioned user since the muter muted the thread", mentions)
+	}
+
+	for _, id := range []string{author.Id, replier.Id, mentioned.Id} {
+		if membership, ok := fakeStore.memberships[root.Id][id]; !ok || !membership.Following {
+			t.Fatal("should have followed the thread", id)
+		}
+	}
+
+	if fakeStore.memberships[root.Id][author.Id].UnreadReplies != 1 || fakeStore.memberships[root.Id][mentioned.Id].UnreadReplies != 1 {
+		t.Fatal("should have counted the reply as unread")
+	}
+
+	if fakeStore.memberships[root.Id][replier.Id].UnreadReplies != 0 || fakeStore.memberships[root.Id][muter.Id].UnreadReplies != 0 {
+		t.Fatal("shouldn't have counted the reply as unread for the replier or the muted thread")
+	}
+
+	// followers are notified of later replies without being mentioned or having comment notifications on
+	fakeStore.threads[root.Id].AddPost(reply)
+	fakeStore.threads[root.Id].AddOrder(reply.Id)
+	fakeStore.memberships[root.Id][mentioned.Id].Following = false
+
+	reply2 := &model.Post{Id: model.NewId(), UserId: replier.Id, ChannelId: channel.Id, RootId: root.Id, Message: "did it work?", CreateAt: 5678}
+	mentions = service.SendNotifications(newTestNotificationContext(), reply2, team, channel)
+
+	if len(mentions) != 1 || mentions[0] != author.Id {
+		t.Fatal("should have notified the author who follows the thread", mentions)
+	}
+
+	if fakeStore.memberships[root.Id][author.Id].UnreadReplies != 2 || fakeStore.memberships[root.Id][mentioned.Id].UnreadReplies != 1 {
+		t.Fatal("should have only counted the reply for users following the thread")
+	}
+
+	// users who unfollowed aren't followed again by replies but are when they're mentioned
+	fakeStore.memberships[root.Id][author.Id].Following = false
+
+	reply3 := &model.Post{Id: model.NewId(), UserId: replier.Id, ChannelId: channel.Id, RootId: root.Id, Message: "@mentioned ping"}
+	service.SendNotifications(newTestNotificationContext(), reply3, team, channel)
+
+	if fakeStore.memberships[root.Id][author.Id].Following {
+		t.Fatal("shouldn't have followed the thread again for the author")
+	}
+
+	if !fakeStore.memberships[root.Id][mentioned.Id].Following {
+		t.Fatal("should have followed the thread again for the mentioned user")
+	}
+}
+
+func TestNotificationServiceThreadsChannelMention(t *testing.T) {
+	setupNotificationTest()
+
+	author := newTestNotificationUser("author")
+	user1 := newTestNotificationUser("user1")
+	user2 := newTestNotificationUser("user2")
+	fakeStore := newFakeNotificationStore(author, user1, user2)
+
+	channel := &model.Channel{Id: model.NewId(), Type: model.CHANNEL_OPEN}
+	service := newNotificationService(fakeStore, nil)
+
+	// a root post that mentions the whole channel notifies everyone without making them follow it
+	root := &model.Post{Id: model.NewId(), UserId: author.Id, ChannelId: channel.Id, Message: "@channel the build is broken"}
+	mentions := service.SendNotifications(newTestNotificationContext(), root, &model.Team{Id: model.NewId()}, channel)
+
+	if len(mentions) != 2 {
+		t.Fatal("should have mentioned everyone else in the channel", mentions)
+	}
+
+	if len(fakeStore.memberships[root.Id]) != 0 {
+		t.Fatal("shouldn't have followed the thread for a channel wide mention", fakeStore.memberships[root.Id])
+	}
+
+	root2 := &model.Post{Id: model.NewId(), UserId: author.Id, ChannelId: channel.Id, Message: "@channel the build is broken, @user1 can you look?"}
+	service.SendNotifications(newTestNotificationContext(), root2, &model.Team{Id: model.NewId()}, channel)
+
+	if _, ok := fakeStore.memberships[root2.Id][user1.Id]; !ok {
+		t.Fatal("should have followed the thread for the user mentioned by username")
+	}
+
+	if _, ok := fakeStore.memberships[root2.Id][user2.Id]; ok {
+		t.Fatal("shouldn't have followed the thread for the user only mentioned by @channel")
+	}
+}
+
+func TestNotificationServiceSenderNames(t *testing.T) {
+	setupNotificationTest()
+
+	sender := newTestNotificationUser("sender")
+	sender.FirstName = "Jo"
+	sender.LastName = "Doe"
+	user1 := newTestNotificationUser("user1")
+	user2 := newTestNotificationUser("user2")
+	fakeStore := newFakeNotificationStore(sender, user1, user2)
+	fakeStore.preferences[user2.Id] = model.Preferences{
+		{UserId: user2.Id, Category: model.PREFERENCE_CATEGORY_DISPLAY_SETTINGS, Name: model.PREFERENCE_NAME_DISPLAY_NAME_FORMAT, Value: model.PREFERENCE_VALUE_DISPLAY_NAME_FULL},
+	}
+
+	channel := &model.Channel{Id: model.NewId(), Type: model.CHANNEL_OPEN}
+	service := newNotificationService(fakeStore, nil)
+
+	post := &model.Post{Id: model.NewId(), UserId: sender.Id, ChannelId: channel.Id, Message: "@user1 @user2"}
+	n, _ := service.loadPostNotification(newTestNotificationContext(), post, &model.Team{Id: model.NewId()}, channel)
+	service.addMentions(n)
+	service.loadSenderNames(n)
+
+	if n.SenderNames[user1.Id] != "sender" {
+		t.Fatal("should have defaulted to the username", n.SenderNames[user1.Id])
+	}
+
+	if n.SenderNames[user2.Id] != "Jo Doe" {
+		t.Fatal("should have used the preferred display name", n.SenderNames[user2.Id])
+	}
+
+	post.Props = model.StringInterface{"from_webhook": "true", "override_username": "bot"}
+	n, _ = service.loadPostNotification(newTestNotificationContext(), post, &model.Team{Id: model.NewId()}, channel)
+	service.addMentions(n)
+	service.loadSenderNames(n)
+
+	if n.SenderUsername != "bot" || n.SenderNames[user2.Id] != "bot" {
+		t.Fatal("should have used the name set by the webhook")
+	}
+}
+
+func TestNotificationServiceDeliver(t *testing.T) {
+	setupNotificationTest()
+
+	sender := newTestNotificationUser("sender")
+	user1 := newTestNotificationUser("user1")
+	user2 := newTestNotificationUser("user2")
+	fakeStore := newFakeNotificationStore(sender, user1, user2)
+	fakeStore.preferences[user2.Id] = model.Preferences{
+		{UserId: user2.Id, Category: model.PREFERENCE_CATEGORY_NOTIFICATION_CHANNELS, Name: "fake", Value: "false"},
+		{UserId: user2.Id, Category: model.PREFERENCE_CATEGORY_NOTIFICATION_CHANNELS, Name: "other", Value: "true"},
+	}
+
+	mentioned := func(n *PostNotification) []string {
+		return n.GetMentionedUserIds()
+	}
+	fake := &fakeNotificationChannel{name: "fake", recipients: mentioned}
+	other := &fakeNotificationChannel{name: "other", recipients: mentioned}
+	nobody := &fakeNotificationChannel{name: "nobody", recipients: func(n *PostNotification) []string { return nil }}
+
+	channel := &model.Channel{Id: model.NewId(), Type: model.CHANNEL_OPEN}
+	service := newNotificationService(fakeStore, []NotificationChannel{fake, other, nobody})
+
+	post := &model.Post{Id: model.NewId(), UserId: sender.Id, ChannelId: channel.Id, Message: "@user1 @user2"}
+	mentions := service.SendNotifications(newTestNotificationContext(), post, &model.Team{Id: model.NewId()}, channel)
+
+	if len(mentions) != 2 {
+		t.Fatal("should have mentioned both users", mentions)
+	}
+
+	if len(fake.sent) != 1 || fake.sent[0] != user1.Id {
+		t.Fatal("shouldn't have notified the user who turned the channel off", fake.sent)
+	}
+
+	if len(other.sent) != 2 {
+		t.Fatal("should have notified both users", other.sent)
+	}
+
+	if nobody.calls != 1 || len(nobody.sent) != 0 {
+		t.Fatal("should have been called without recipients")
+	}
+
+	if fakeStore.mentionCounts[user1.Id] != 1 || fakeStore.mentionCounts[user2.Id] != 1 {
+		t.Fatal("should have incremented the mention counts", fakeStore.mentionCounts)
+	}
+}
+
+func TestNotificationChannelRecipients(t *testing.T) {
+	setupNotificationTest()
+
+	sendEmailNotifications := utils.Cfg.EmailSettings.SendEmailNotifications
+	sendPushNotifications := *utils.Cfg.EmailSettings.SendPushNotifications
+	pushNotificationServer := *utils.Cfg.EmailSettings.PushNotificationServer
+	defer func() {
+		utils.Cfg.EmailSettings.SendEmailNotifications = sendEmailNotifications
+		*utils.Cfg.EmailSettings.SendPushNotifications = sendPushNotifications
+		*utils.Cfg.EmailSettings.PushNotificationServer = pushNotificationServer
+	}()
+	utils.Cfg.EmailSettings.SendEmailNotifications = true
+	*utils.Cfg.EmailSettings.SendPushNotifications = true
+	*utils.Cfg.EmailSettings.PushNotificationServer = "http://localhost:8066"
+
+	online := newTestNotificationUser("online")
+	offline := newTestNotificationUser("offline")
+	noEmail := newTestNotificationUser("noemail")
+	noEmail.NotifyProps["email"] = "false"
+	dnd := newTestNotificationUser("dnd")
+	muted := newTestNotificationUser("muted")
+	follower := newTestNotificationUser("follower")
+
+	n := &PostNotification{
+		Post:    &model.Post{ChannelId: model.NewId()},
+		Channel: &model.Channel{},
+		Profiles: map[string]*model.User{
+			online.Id: online, offline.Id: offline, noEmail.Id: noEmail, dnd.Id: dnd, muted.Id: muted, follower.Id: follower,
+		},
+		ChannelNotifyProps: map[string]model.StringMap{
+			muted.Id: {"muted_until": strconv.FormatInt(model.GetMillis()+60*60*1000, 10)},
+		},
+		MentionedUserIds: map[string]bool{offline.Id: true, noEmail.Id: true, dnd.Id: true, muted.Id: true},
+		HereUserIds:      map[string]bool{online.Id: true},
+		ActivityUserIds:  []string{follower.Id, offline.Id},
+		Statuses: map[string]*model.Status{
+			online.Id: {UserId: online.Id, Status: model.STATUS_ONLINE, LastActivityAt: model.GetMillis(), ActiveChannel: "other"},
+			dnd.Id:    {UserId: dnd.Id, Status: model.STATUS_DND},
+		},
+		createAt: time.Now(),
+	}
+
+	if !n.IsSuppressed(muted.Id) {
+		t.Fatal("should have suppressed notifications for the muted channel")
+	}
+
+	if recipients := (&EmailNotificationChannel{}).GetRecipients(n); len(recipients) != 2 || !contains(recipients, offline.Id) || !contains(recipients, dnd.Id) {
+		t.Fatal("should have emailed the offline users that allow emails", recipients)
+	}
+
+	if recipients := (&PushNotificationChannel{}).GetRecipients(n); len(recipients) != 4 || contains(recipients, dnd.Id) || contains(recipients, muted.Id) {
+		t.Fatal("should have pushed to everyone but the muted and do not disturb users", recipients)
+	}
+
+	if recipients := (&WebSocketNotificationChannel{}).GetRecipients(n); len(recipients) != 3 || contains(recipients, dnd.Id) || contains(recipients, muted.Id) || contains(recipients, follower.Id) {
+		t.Fatal("should have listed the mentioned users to show desktop notifications to", recipients)
+	}
+}
+
+func TestHttpNotificationChannel(t *testing.T) {
+	setupNotificationTest()
+
+	received := make(chan *httpNotificationPayload, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload httpNotificationPayload
+		json.NewDecoder(r.Body).Decode(&payload)
+		received <- &payload
+	}))
+	defer server.Close()
+
+	httpNotificationURL := *utils.Cfg.EmailSettings.HttpNotificationURL
+	defer func() {
+		*utils.Cfg.EmailSettings.HttpNotificationURL = httpNotificationURL
+	}()
+
+	user := newTestNotificationUser("user1")
+	n := &PostNotification{
+		Post:             &model.Post{Id: model.NewId(), UserId: model.NewId(), Message: "hi @user1"},
+		Channel:          &model.Channel{Id: model.NewId(), Name: "town-square"},
+		Team:             &model.Team{Id: model.NewId(), Name: "team"},
+		SenderUsername:   "sender",
+		Profiles:         map[string]*model.User{user.Id: user},
+		MentionedUserIds: map[string]bool{user.Id: true},
+		createAt:         time.Now(),
+	}
+
+	channel := &HttpNotificationChannel{}
+
+	*utils.Cfg.EmailSettings.HttpNotificationURL = ""
+	if recipients := channel.GetRecipients(n); len(recipients) != 0 {
+		t.Fatal("shouldn't have any recipients without a URL")
+	}
+
+	*utils.Cfg.EmailSettings.HttpNotificationURL = server.URL
+	recipients := channel.GetRecipients(n)
+	if len(recipients) != 1 {
+		t.Fatal("should have notified the mentioned user")
+	}
+
+	if err := channel.Send(n, recipients); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case payload := <-received:
+		if payload.PostId != n.Post.Id || payload.SenderName != "sender" || len(payload.Usernames) != 1 || payload.Usernames[0] != "user1" {
+			t.Fatal("should have sent the notification", payload)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("should have posted the notification")
+	}
+}
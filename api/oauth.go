@@ -298,7 +298,13 @@ func completeOAuth(c *Context, w http.ResponseWriter, r *http.Request) {
 
 	uri := c.GetSiteURL() + "/signup/" + service + "/complete"
 
-	if body, teamId, props, err := AuthorizeOAuthUser(service, code, state, uri); err != nil {
+	codeVerifier := ""
+	if cookie, err := r.Cookie(model.OPENID_VERIFIER_COOKIE); err == nil {
+		codeVerifier = cookie.Value
+		http.SetCookie(w, &http.Cookie{Name: model.OPENID_VERIFIER_COOKIE, Value: "", Path: "/", MaxAge: -1, HttpOnly: true})
+	}
+
+	if body, teamId, props, err := AuthorizeOAuthUser(service, code, state, uri, codeVerifier); err != nil {
 		c.Err = err
 		return
 	} else {
@@ -641,7 +647,7 @@ func loginWithOAuth(c *Context, w http.ResponseWriter, r *http.Request) {
 		stateProps["redirect_to"] = redirectTo
 	}
 
-	if authUrl, err := GetAuthorizationCode(c, service, stateProps, loginHint); err != nil {
+	if authUrl, err := GetAuthorizationCode(c, w, service, stateProps, loginHint); err != nil {
 		c.Err = err
 		return
 	} else {
@@ -701,7 +707,7 @@ func signupWithOAuth(c *Context, w http.ResponseWriter, r *http.Request) {
 		stateProps["team_id"] = teamId
 	}
 
-	if authUrl, err := GetAuthorizationCode(c, service, stateProps, ""); err != nil {
+	if authUrl, err := GetAuthorizationCode(c, w, service, stateProps, ""); err != nil {
 		c.Err = err
 		return
 	} else {
@@ -709,10 +715,13 @@ func signupWithOAuth(c *Context, w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func GetAuthorizationCode(c *Context, service string, props map[string]string, loginHint string) (string, *model.AppError) {
+func GetAuthorizationCode(c *Context, w http.ResponseWriter, service string, props map[string]string, loginHint string) (string, *model.AppError) {
+	if service == model.SERVICE_OPENID {
+		return getOpenIdAuthorizationUrl(c, w, props, loginHint)
+	}
 
 	sso := utils.Cfg.GetSSOService(service)
-	if sso != nil && !sso.Enable {
+	if sso == nil || !sso.Enable {
 		return "", model.NewLocAppError("GetAuthorizationCode", "api.user.get_authorization_code.unsupported.app_error", nil, "service="+service)
 	}
 
@@ -738,7 +747,11 @@ func GetAuthorizationCode(c *Context, service string, props map[string]string, l
 	return authUrl, nil
 }
 
-func AuthorizeOAuthUser(service, code, state, redirectUri string) (io.ReadCloser, string, map[string]string, *model.AppError) {
+func AuthorizeOAuthUser(service, code, state, redirectUri, codeVerifier string) (io.ReadCloser, string, map[string]string, *model.AppError) {
+	if service == model.SERVICE_OPENID {
+		return AuthorizeOpenIdUser(code, state, redirectUri, codeVerifier)
+	}
+
 	sso := utils.Cfg.GetSSOService(service)
 	if sso == nil || !sso.Enable {
 		return nil, "", nil, model.NewLocAppError("AuthorizeOAuthUser", "api.user.authorize_oauth_user.unsupported.app_error", nil, "service="+service)
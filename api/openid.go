@@ -0,0 +1,252 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api
+
+import (
+	"bytes"
+	"crypto/tls"
+	b64 "encoding/base64"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mattermost/platform/model"
+	"github.com/mattermost/platform/utils"
+)
+
+const (
+	OPENID_CACHE_SIZE          = 10
+	OPENID_CACHE_SEC           = 60 * 60
+	OPENID_VERIFIER_COOKIE_SEC = 10 * 60
+)
+
+var openIdCache = utils.NewLru(OPENID_CACHE_SIZE)
+
+func openIdHttpClient() *http.Client {
+	tr := &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: *utils.Cfg.ServiceSettings.EnableInsecureOutgoingConnections},
+	}
+	return &http.Client{Transport: tr, Timeout: 30 * time.Second}
+}
+
+func getOpenIdJson(location string) ([]byte, error) {
+	req, _ := http.NewRequest("GET", location, nil)
+	req.Header.Set("Accept", "application/json")
+
+	if resp, err := openIdHttpClient().Do(req); err != nil {
+		return nil, err
+	} else {
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, errors.New("unexpected response " + resp.Status + " from " + location)
+		}
+		return ioutil.ReadAll(io.LimitReader(resp.Body, 1024*1024))
+	}
+}
+
+// GetOpenIdDiscovery returns the configured identity provider's discovery document
+func GetOpenIdDiscovery() (*model.OpenIdDiscovery, *model.AppError) {
+	endpoint := *utils.Cfg.OpenIdSettings.DiscoveryEndpoint
+
+	if cached, ok := openIdCache.Get("discovery:" + endpoint); ok {
+		return cached.(*model.OpenIdDiscovery), nil
+	}
+
+	data, err := getOpenIdJson(endpoint)
+	if err != nil {
+		return nil, model.NewLocAppError("GetOpenIdDiscovery", "api.openid.discovery.app_error", nil, err.Error())
+	}
+
+	discovery := model.OpenIdDiscoveryFromJson(bytes.NewReader(data))
+	if discovery == nil || !discovery.IsValid() {
+		return nil, model.NewLocAppError("GetOpenIdDiscovery", "api.openid.discovery.app_error", nil, "endpoint="+endpoint)
+	}
+
+	openIdCache.AddWithExpiresInSecs("discovery:"+endpoint, discovery, OPENID_CACHE_SEC)
+
+	return discovery, nil
+}
+
+// getOpenIdKeys returns the identity provider's signing keys. Passing refresh skips the cache which is needed
+// when the provider rotates its keys.
+func getOpenIdKeys(discovery *model.OpenIdDiscovery, refresh bool) (*model.JSONWebKeySet, *model.AppError) {
+	if cached, ok := openIdCache.Get("jwks:" + discovery.JwksUri); ok && !refresh {
+		return cached.(*model.JSONWebKeySet), nil
+	}
+
+	data, err := getOpenIdJson(discovery.JwksUri)
+	if err != nil {
+		return nil, model.NewLocAppError("getOpenIdKeys", "api.openid.keys.app_error", nil, err.Error())
+	}
+
+	keys := model.JSONWebKeySetFromJson(bytes.NewReader(data))
+	if keys == nil {
+		return nil, model.NewLocAppError("getOpenIdKeys", "api.openid.keys.app_error", nil, "jwks_uri="+discovery.JwksUri)
+	}
+
+	openIdCache.AddWithExpiresInSecs("jwks:"+discovery.JwksUri, keys, OPENID_CACHE_SEC)
+
+	return keys, nil
+}
+
+func getOpenIdAuthorizationUrl(c *Context, w http.ResponseWriter, props map[string]string, loginHint string) (string, *model.AppError) {
+	settings := utils.Cfg.OpenIdSettings
+	if !*settings.Enable {
+		return "", model.NewLocAppError("GetAuthorizationCode", "api.user.get_authorization_code.unsupported.app_error", nil, "service="+model.SERVICE_OPENID)
+	}
+
+	discovery, err := GetOpenIdDiscovery()
+	if err != nil {
+		return "", err
+	}
+
+	props["hash"] = model.HashPassword(*settings.Id)
+	props["nonce"] = model.NewId()
+	state := b64.StdEncoding.EncodeToString([]byte(model.MapToJson(props)))
+
+	// the verifier never leaves the browser until the code is exchanged so that an intercepted code is useless
+	verifier := model.NewPKCEVerifier()
+	http.SetCookie(w, &http.Cookie{
+		Name:     model.OPENID_VERIFIER_COOKIE,
+		Value:    verifier,
+		Path:     "/",
+		MaxAge:   OPENID_VERIFIER_COOKIE_SEC,
+		Expires:  time.Unix(model.GetMillis()/1000+OPENID_VERIFIER_COOKIE_SEC, 0),
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(c.GetSiteURL(), "https"),
+	})
+
+	p := url.Values{}
+	p.Set("response_type", "code")
+	p.Set("client_id", *settings.Id)
+	p.Set("redirect_uri", c.GetSiteURL()+"/signup/"+model.SERVICE_OPENID+"/complete")
+	p.Set("scope", *settings.Scope)
+	p.Set("state", state)
+	p.Set("nonce", props["nonce"])
+	p.Set("code_challenge", model.PKCEChallenge(verifier))
+	p.Set("code_challenge_method", "S256")
+
+	if len(loginHint) > 0 {
+		p.Set("login_hint", loginHint)
+	}
+
+	separator := "?"
+	if strings.Contains(discovery.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+
+	return discovery.AuthorizationEndpoint + separator + p.Encode(), nil
+}
+
+// AuthorizeOpenIdUser exchanges the code for tokens and returns the verified claims of the user as JSON
+func AuthorizeOpenIdUser(code, state, redirectUri, codeVerifier string) (io.ReadCloser, string, map[string]string, *model.AppError) {
+	settings := utils.Cfg.OpenIdSettings
+	if !*settings.Enable {
+		return nil, "", nil, model.NewLocAppError("AuthorizeOAuthUser", "api.user.authorize_oauth_user.unsupported.app_error", nil, "service="+model.SERVICE_OPENID)
+	}
+
+	stateStr := ""
+	if b, err := b64.StdEncoding.DecodeString(state); err != nil {
+		return nil, "", nil, model.NewLocAppError("AuthorizeOAuthUser", "api.user.authorize_oauth_user.invalid_state.app_error", nil, err.Error())
+	} else {
+		stateStr = string(b)
+	}
+
+	stateProps := model.MapFromJson(strings.NewReader(stateStr))
+
+	if !model.ComparePassword(stateProps["hash"], *settings.Id) {
+		return nil, "", nil, model.NewLocAppError("AuthorizeOAuthUser", "api.user.authorize_oauth_user.invalid_state.app_error", nil, "")
+	}
+
+	if len(codeVerifier) == 0 {
+		return nil, "", nil, model.NewLocAppError("AuthorizeOAuthUser", "api.openid.authorize.missing_verifier.app_error", nil, "")
+	}
+
+	discovery, appErr := GetOpenIdDiscovery()
+	if appErr != nil {
+		return nil, "", nil, appErr
+	}
+
+	p := url.Values{}
+	p.Set("client_id", *settings.Id)
+	p.Set("client_secret", *settings.Secret)
+	p.Set("code", code)
+	p.Set("grant_type", model.ACCESS_TOKEN_GRANT_TYPE)
+	p.Set("redirect_uri", redirectUri)
+	p.Set("code_verifier", codeVerifier)
+
+	client := openIdHttpClient()
+	req, _ := http.NewRequest("POST", discovery.TokenEndpoint, strings.NewReader(p.Encode()))
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	var tr *model.OpenIdTokenResponse
+	if resp, err := client.Do(req); err != nil {
+		return nil, "", nil, model.NewLocAppError("AuthorizeOAuthUser", "api.user.authorize_oauth_user.token_failed.app_error", nil, err.Error())
+	} else {
+		tr = model.OpenIdTokenResponseFromJson(resp.Body)
+		ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if tr == nil || len(tr.IdToken) == 0 {
+			return nil, "", nil, model.NewLocAppError("AuthorizeOAuthUser", "api.user.authorize_oauth_user.bad_response.app_error", nil, "")
+		}
+	}
+
+	claims, appErr := verifyOpenIdToken(discovery, tr.IdToken, stateProps["nonce"])
+	if appErr != nil {
+		return nil, "", nil, appErr
+	}
+
+	// some providers only put the profile claims in the user info response
+	if len(discovery.UserInfoEndpoint) > 0 && len(tr.AccessToken) > 0 && strings.ToLower(tr.TokenType) == model.ACCESS_TOKEN_TYPE {
+		req, _ = http.NewRequest("GET", discovery.UserInfoEndpoint, nil)
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("Authorization", "Bearer "+tr.AccessToken)
+
+		if resp, err := client.Do(req); err == nil {
+			userInfo := model.OpenIdClaimsFromJson(io.LimitReader(resp.Body, 1024*1024))
+			ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+
+			if resp.StatusCode == http.StatusOK && userInfo != nil && userInfo.GetString("sub") == claims.GetString("sub") {
+				for name, value := range userInfo {
+					if _, ok := claims[name]; !ok {
+						claims[name] = value
+					}
+				}
+			}
+		}
+	}
+
+	return ioutil.NopCloser(strings.NewReader(claims.ToJson())), stateProps["team_id"], stateProps, nil
+}
+
+func verifyOpenIdToken(discovery *model.OpenIdDiscovery, token, nonce string) (model.OpenIdClaims, *model.AppError) {
+	keys, appErr := getOpenIdKeys(discovery, false)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	if keys.Get(model.IdTokenKeyId(token)) == nil {
+		if keys, appErr = getOpenIdKeys(discovery, true); appErr != nil {
+			return nil, appErr
+		}
+	}
+
+	claims, err := model.VerifyIdToken(token, keys)
+	if err != nil {
+		return nil, model.NewLocAppError("AuthorizeOAuthUser", "api.openid.authorize.invalid_token.app_error", nil, err.Error())
+	}
+
+	if err := claims.Validate(discovery.Issuer, *utils.Cfg.OpenIdSettings.Id, nonce, model.GetMillis()/1000); err != nil {
+		return nil, model.NewLocAppError("AuthorizeOAuthUser", "api.openid.authorize.invalid_token.app_error", nil, err.Error())
+	}
+
+	return claims, nil
+}
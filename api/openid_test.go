@@ -0,0 +1,187 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/platform/model"
+	"github.com/mattermost/platform/utils"
+)
+
+type testOpenIdProvider struct {
+	*httptest.Server
+	key      *rsa.PrivateKey
+	nonce    string
+	verifier string
+}
+
+func newTestOpenIdProvider(t *testing.T) *testOpenIdProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idp := &testOpenIdProvider{key: key}
+	idp.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			json.NewEncoder(w).Encode(&model.OpenIdDiscovery{
+				Issuer:                idp.URL,
+				AuthorizationEndpoint: idp.URL + "/auth",
+				TokenEndpoint:         idp.URL + "/token",
+				UserInfoEndpoint:      idp.URL + "/userinfo",
+				JwksUri:               idp.URL + "/jwks",
+			})
+		case "/jwks":
+			json.NewEncoder(w).Encode(&model.JSONWebKeySet{Keys: []*model.JSONWebKey{{
+				Kid: "key1",
+				Kty: "RSA",
+				N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}}})
+		case "/token":
+			r.ParseForm()
+			if r.Form.Get("code") != "code1" || r.Form.Get("code_verifier") != idp.verifier {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			json.NewEncoder(w).Encode(&model.OpenIdTokenResponse{
+				AccessToken: "access1",
+				TokenType:   "bearer",
+				IdToken:     idp.signIdToken(t),
+			})
+		case "/userinfo":
+			if r.Header.Get("Authorization") != "Bearer access1" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			w.Write([]byte(`{"sub": "user1", "given_name": "Jane", "family_name": "Doe"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	return idp
+}
+
+func (idp *testOpenIdProvider) signIdToken(t *testing.T) string {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "key1"})
+	payload, _ := json.Marshal(map[string]interface{}{
+		"iss":                idp.URL,
+		"aud":                "client1",
+		"sub":                "user1",
+		"nonce":              idp.nonce,
+		"exp":                model.GetMillis()/1000 + 300,
+		"email":              "jane@example.com",
+		"preferred_username": "jane",
+	})
+
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	hash := sha256.Sum256([]byte(signed))
+
+	signature, err := rsa.SignPKCS1v15(rand.Reader, idp.key, crypto.SHA256, hash[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestOpenIdLogin(t *testing.T) {
+	Setup()
+
+	idp := newTestOpenIdProvider(t)
+	defer idp.Close()
+
+	enable := *utils.Cfg.OpenIdSettings.Enable
+	id := *utils.Cfg.OpenIdSettings.Id
+	discoveryEndpoint := *utils.Cfg.OpenIdSettings.DiscoveryEndpoint
+	defer func() {
+		*utils.Cfg.OpenIdSettings.Enable = enable
+		*utils.Cfg.OpenIdSettings.Id = id
+		*utils.Cfg.OpenIdSettings.DiscoveryEndpoint = discoveryEndpoint
+	}()
+	*utils.Cfg.OpenIdSettings.Enable = true
+	*utils.Cfg.OpenIdSettings.Id = "client1"
+	*utils.Cfg.OpenIdSettings.DiscoveryEndpoint = idp.URL + "/.well-known/openid-configuration"
+
+	c := &Context{}
+	c.SetSiteURL("http://localhost:8065")
+	w := httptest.NewRecorder()
+
+	authUrl, err := GetAuthorizationCode(c, w, model.SERVICE_OPENID, map[string]string{"action": model.OAUTH_ACTION_LOGIN}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(authUrl, idp.URL+"/auth?") {
+		t.Fatal("should've used the discovered authorization endpoint", authUrl)
+	}
+
+	query, _ := url.ParseQuery(strings.SplitN(authUrl, "?", 2)[1])
+	if query.Get("code_challenge_method") != "S256" || len(query.Get("nonce")) == 0 {
+		t.Fatal("should've requested PKCE and a nonce")
+	}
+
+	response := http.Response{Header: w.Header()}
+	for _, cookie := range response.Cookies() {
+		if cookie.Name == model.OPENID_VERIFIER_COOKIE {
+			idp.verifier = cookie.Value
+		}
+	}
+
+	if model.PKCEChallenge(idp.verifier) != query.Get("code_challenge") {
+		t.Fatal("the verifier cookie should match the challenge")
+	}
+
+	idp.nonce = query.Get("nonce")
+	redirectUri := c.GetSiteURL() + "/signup/openid/complete"
+
+	if _, _, _, err := AuthorizeOAuthUser(model.SERVICE_OPENID, "code1", query.Get("state"), redirectUri, "wrong"); err == nil {
+		t.Fatal("should've failed with the wrong verifier")
+	}
+
+	if _, _, _, err := AuthorizeOAuthUser(model.SERVICE_OPENID, "code1", query.Get("state"), redirectUri, ""); err == nil {
+		t.Fatal("should've failed without a verifier")
+	}
+
+	if body, _, props, err := AuthorizeOAuthUser(model.SERVICE_OPENID, "code1", query.Get("state"), redirectUri, idp.verifier); err != nil {
+		t.Fatal(err)
+	} else {
+		data, _ := ioutil.ReadAll(body)
+		claims := model.OpenIdClaimsFromJson(strings.NewReader(string(data)))
+
+		if claims.GetString("sub") != "user1" || claims.GetString("email") != "jane@example.com" {
+			t.Fatal("should've returned the id token claims")
+		}
+
+		if claims.GetString("given_name") != "Jane" {
+			t.Fatal("should've merged the user info claims")
+		}
+
+		if props["action"] != model.OAUTH_ACTION_LOGIN {
+			t.Fatal("should've returned the state")
+		}
+	}
+
+	idp.nonce = "other"
+	if _, _, _, err := AuthorizeOAuthUser(model.SERVICE_OPENID, "code1", query.Get("state"), redirectUri, idp.verifier); err == nil {
+		t.Fatal("should've failed with a replayed token")
+	}
+}
@@ -280,6 +280,7 @@ func handlePostEvents(c *Context, post *model.Post, triggerWebhooks bool) {
 
 	if channel.Type == model.CHANNEL_DIRECT {
 		go makeDirectChannelVisible(post.ChannelId)
+		sendAutoResponse(c, post, channel)
 	}
 }
 
@@ -455,11 +456,21 @@ func handleWebhookEvents(c *Context, post *model.Post, team *model.Team, channel
 	}
 }
 
-// Given a map of user IDs to profiles, returns a list of mention
-// keywords for all users in the channel.
-func getMentionKeywordsInChannel(profiles map[string]*model.User) map[string][]string {
+// Given a map of user IDs to profiles and a map of user group names to the IDs of their members,
+// returns a list of mention keywords for all users in the channel.
+func getMentionKeywordsInChannel(profiles map[string]*model.User, groups map[string][]string) map[string][]string {
 	keywords := make(map[string][]string)
 
+	// Mentioning a group mentions each of its members that are in the channel
+	for name, memberIds := range groups {
+		groupMention := "@" + strings.ToLower(name)
+		for _, id := range memberIds {
+			if _, ok := profiles[id]; ok {
+				keywords[groupMention] = append(keywords[groupMention], id)
+			}
+		}
+	}
+
 	for id, profile := range profiles {
 		userMention := "@" + strings.ToLower(profile.Username)
 		keywords[userMention] = append(keywords[userMention], id)
@@ -489,6 +500,18 @@ func getMentionKeywordsInChannel(profiles map[string]*model.User) map[string][]s
 	return keywords
 }
 
+// Adds the extra keywords that users have set up for a single channel to the keywords returned
+// by getMentionKeywordsInChannel.
+func addChannelMentionKeywords(keywords map[string][]string, members map[string]model.StringMap) {
+	for id, notifyProps := range members {
+		member := &model.ChannelMember{NotifyProps: notifyProps}
+		for _, k := range member.GetMentionKeys() {
+			key := strings.ToLower(k)
+			keywords[key] = append(keywords[key], id)
+		}
+	}
+}
+
 // Given a message and a map mapping mention keywords to the users who use them, returns a map of mentioned
 // users and a slice of potencial mention users not in the channel and whether or not @here was mentioned.
 func getExplicitMentions(message string, keywords map[string][]string) (map[string]bool, []string, bool, bool, bool) {
@@ -571,289 +594,6 @@ func getExplicitMentions(message string, keywords map[string][]string) (map[stri
 	return mentioned, potentialOthersMentioned, hereMentioned, channelMentioned, allMentioned
 }
 
-func sendNotifications(c *Context, post *model.Post, team *model.Team, channel *model.Channel) []string {
-	pchan := Srv.Store.User().GetProfilesInChannel(channel.Id, -1, -1, true)
-	fchan := Srv.Store.FileInfo().GetForPost(post.Id)
-
-	var profileMap map[string]*model.User
-	if result := <-pchan; result.Err != nil {
-		l4g.Error(utils.T("api.post.handle_post_events_and_forget.profiles.error"), c.TeamId, result.Err)
-		return nil
-	} else {
-		profileMap = result.Data.(map[string]*model.User)
-	}
-
-	// If the user who made the post is mention don't send a notification
-	if _, ok := profileMap[post.UserId]; !ok {
-		l4g.Error(utils.T("api.post.send_notifications_and_forget.user_id.error"), post.UserId)
-		return nil
-	}
-
-	mentionedUserIds := make(map[string]bool)
-	allActivityPushUserIds := []string{}
-	hereNotification := false
-	channelNotification := false
-	allNotification := false
-	updateMentionChans := []store.StoreChannel{}
-
-	if channel.Type == model.CHANNEL_DIRECT {
-		var otherUserId string
-		if userIds := strings.Split(channel.Name, "__"); userIds[0] == post.UserId {
-			otherUserId = userIds[1]
-		} else {
-			otherUserId = userIds[0]
-		}
-
-		mentionedUserIds[otherUserId] = true
-		if post.Props["from_webhook"] == "true" {
-			mentionedUserIds[post.UserId] = true
-		}
-	} else {
-		keywords := getMentionKeywordsInChannel(profileMap)
-
-		var potentialOtherMentions []string
-		mentionedUserIds, potentialOtherMentions, hereNotification, channelNotification, allNotification = getExplicitMentions(post.Message, keywords)
-
-		// get users that have comment thread mentions enabled
-		if len(post.RootId) > 0 {
-			if result := <-Srv.Store.Post().Get(post.RootId); result.Err != nil {
-				l4g.Error(utils.T("api.post.send_notifications_and_forget.comment_thread.error"), post.RootId, result.Err)
-				return nil
-			} else {
-				list := result.Data.(*model.PostList)
-
-				for _, threadPost := range list.Posts {
-					profile := profileMap[threadPost.UserId]
-					if profile.NotifyProps["comments"] == "any" || (profile.NotifyProps["comments"] == "root" && threadPost.Id == list.Order[0]) {
-						mentionedUserIds[threadPost.UserId] = true
-					}
-				}
-			}
-		}
-
-		// prevent the user from mentioning themselves
-		if post.Props["from_webhook"] != "true" {
-			delete(mentionedUserIds, post.UserId)
-		}
-
-		if len(potentialOtherMentions) > 0 {
-			if result := <-Srv.Store.User().GetProfilesByUsernames(potentialOtherMentions, team.Id); result.Err == nil {
-				outOfChannelMentions := result.Data.(map[string]*model.User)
-				go sendOutOfChannelMentions(c, post, outOfChannelMentions)
-			}
-		}
-
-		// find which users in the channel are set up to always receive mobile notifications
-		for _, profile := range profileMap {
-			if profile.NotifyProps["push"] == model.USER_NOTIFY_ALL &&
-				(post.UserId != profile.Id || post.Props["from_webhook"] == "true") &&
-				!post.IsSystemMessage() {
-				allActivityPushUserIds = append(allActivityPushUserIds, profile.Id)
-			}
-		}
-	}
-
-	mentionedUsersList := make([]string, 0, len(mentionedUserIds))
-	for id := range mentionedUserIds {
-		mentionedUsersList = append(mentionedUsersList, id)
-		updateMentionChans = append(updateMentionChans, Srv.Store.Channel().IncrementMentionCount(post.ChannelId, id))
-	}
-
-	var sender *model.User
-	senderName := make(map[string]string)
-	for _, id := range mentionedUsersList {
-		senderName[id] = ""
-		if post.IsSystemMessage() {
-			senderName[id] = c.T("system.message.name")
-		} else if profile, ok := profileMap[post.UserId]; ok {
-			if value, ok := post.Props["override_username"]; ok && post.Props["from_webhook"] == "true" {
-				senderName[id] = value.(string)
-			} else {
-				//Get the Display name preference from the receiver
-				if result := <-Srv.Store.Preference().Get(id, model.PREFERENCE_CATEGORY_DISPLAY_SETTINGS, "name_format"); result.Err != nil {
-					// Show default sender's name if user doesn't set display settings.
-					senderName[id] = profile.Username
-				} else {
-					senderName[id] = profile.GetDisplayNameForPreference(result.Data.(model.Preference).Value)
-				}
-			}
-			sender = profile
-		}
-	}
-
-	var senderUsername string
-	if value, ok := post.Props["override_username"]; ok && post.Props["from_webhook"] == "true" {
-		senderUsername = value.(string)
-	} else {
-		senderUsername = profileMap[post.UserId].Username
-	}
-
-	if utils.Cfg.EmailSettings.SendEmailNotifications {
-		for _, id := range mentionedUsersList {
-			userAllowsEmails := profileMap[id].NotifyProps["email"] != "false"
-
-			var status *model.Status
-			var err *model.AppError
-			if status, err = GetStatus(id); err != nil {
-				status = &model.Status{
-					UserId:         id,
-					Status:         model.STATUS_OFFLINE,
-					Manual:         false,
-					LastActivityAt: 0,
-					ActiveChannel:  "",
-				}
-			}
-
-			if userAllowsEmails && status.Status != model.STATUS_ONLINE {
-				sendNotificationEmail(c, post, profileMap[id], channel, team, senderName[id], sender)
-			}
-		}
-	}
-
-	// If the channel has more than 1K users then @here is disabled
-	if hereNotification && int64(len(profileMap)) > *utils.Cfg.TeamSettings.MaxNotificationsPerChannel {
-		hereNotification = false
-		SendEphemeralPost(
-			c.TeamId,
-			post.UserId,
-			&model.Post{
-				ChannelId: post.ChannelId,
-				Message:   c.T("api.post.disabled_here", map[string]interface{}{"Users": *utils.Cfg.TeamSettings.MaxNotificationsPerChannel}),
-				CreateAt:  post.CreateAt + 1,
-			},
-		)
-	}
-
-	// If the channel has more than 1K users then @channel is disabled
-	if channelNotification && int64(len(profileMap)) > *utils.Cfg.TeamSettings.MaxNotificationsPerChannel {
-		SendEphemeralPost(
-			c.TeamId,
-			post.UserId,
-			&model.Post{
-				ChannelId: post.ChannelId,
-				Message:   c.T("api.post.disabled_channel", map[string]interface{}{"Users": *utils.Cfg.TeamSettings.MaxNotificationsPerChannel}),
-				CreateAt:  post.CreateAt + 1,
-			},
-		)
-	}
-
-	// If the channel has more than 1K users then @all is disabled
-	if allNotification && int64(len(profileMap)) > *utils.Cfg.TeamSettings.MaxNotificationsPerChannel {
-		SendEphemeralPost(
-			c.TeamId,
-			post.UserId,
-			&model.Post{
-				ChannelId: post.ChannelId,
-				Message:   c.T("api.post.disabled_all", map[string]interface{}{"Users": *utils.Cfg.TeamSettings.MaxNotificationsPerChannel}),
-				CreateAt:  post.CreateAt + 1,
-			},
-		)
-	}
-
-	if hereNotification {
-		if result := <-Srv.Store.Status().GetOnline(); result.Err != nil {
-			l4g.Warn(utils.T("api.post.notification.here.warn"), result.Err)
-			return nil
-		} else {
-			statuses := result.Data.([]*model.Status)
-			for _, status := range statuses {
-				if status.UserId == post.UserId {
-					continue
-				}
-
-				_, profileFound := profileMap[status.UserId]
-				_, alreadyMentioned := mentionedUserIds[status.UserId]
-
-				if status.Status == model.STATUS_ONLINE && profileFound && !alreadyMentioned {
-					mentionedUsersList = append(mentionedUsersList, status.UserId)
-					updateMentionChans = append(updateMentionChans, Srv.Store.Channel().IncrementMentionCount(post.ChannelId, status.UserId))
-				}
-			}
-		}
-	}
-
-	// Make sure all mention updates are complete to prevent race
-	// Probably better to batch these DB updates in the future
-	// MUST be completed before push notifications send
-	for _, uchan := range updateMentionChans {
-		if result := <-uchan; result.Err != nil {
-			l4g.Warn(utils.T("api.post.update_mention_count_and_forget.update_error"), post.Id, post.ChannelId, result.Err)
-		}
-	}
-
-	sendPushNotifications := false
-	if *utils.Cfg.EmailSettings.SendPushNotifications {
-		pushServer := *utils.Cfg.EmailSettings.PushNotificationServer
-		if pushServer == model.MHPNS && (!utils.IsLicensed || !*utils.License.Features.MHPNS) {
-			l4g.Warn(utils.T("api.post.send_notifications_and_forget.push_notification.mhpnsWarn"))
-			sendPushNotifications = false
-		} else {
-			sendPushNotifications = true
-		}
-	}
-
-	if sendPushNotifications {
-		for _, id := range mentionedUsersList {
-			var status *model.Status
-			var err *model.AppError
-			if status, err = GetStatus(id); err != nil {
-				status = &model.Status{id, model.STATUS_OFFLINE, false, 0, ""}
-			}
-
-			if DoesStatusAllowPushNotification(profileMap[id], status, post.ChannelId) {
-				sendPushNotification(post, profileMap[id], channel, senderName[id], true)
-			}
-		}
-
-		for _, id := range allActivityPushUserIds {
-			if _, ok := mentionedUserIds[id]; !ok {
-				var status *model.Status
-				var err *model.AppError
-				if status, err = GetStatus(id); err != nil {
-					status = &model.Status{id, model.STATUS_OFFLINE, false, 0, ""}
-				}
-
-				if DoesStatusAllowPushNotification(profileMap[id], status, post.ChannelId) {
-					sendPushNotification(post, profileMap[id], channel, senderName[id], false)
-				}
-			}
-		}
-	}
-
-	message := model.NewWebSocketEvent(model.WEBSOCKET_EVENT_POSTED, "", post.ChannelId, "", nil)
-	message.Add("post", post.ToJson())
-	message.Add("channel_type", channel.Type)
-	message.Add("channel_display_name", channel.DisplayName)
-	message.Add("channel_name", channel.Name)
-	message.Add("sender_name", senderUsername)
-	message.Add("team_id", team.Id)
-
-	if len(post.FileIds) != 0 {
-		message.Add("otherFile", "true")
-
-		var infos []*model.FileInfo
-		if result := <-fchan; result.Err != nil {
-			l4g.Warn(utils.T("api.post.send_notifications.files.error"), post.Id, result.Err)
-		} else {
-			infos = result.Data.([]*model.FileInfo)
-		}
-
-		for _, info := range infos {
-			if info.IsImage() {
-				message.Add("image", "true")
-				break
-			}
-		}
-	}
-
-	if len(mentionedUsersList) != 0 {
-		message.Add("mentions", model.ArrayToJson(mentionedUsersList))
-	}
-
-	Publish(message)
-	return mentionedUsersList
-}
-
 func sendNotificationEmail(c *Context, post *model.Post, user *model.User, channel *model.Channel, team *model.Team, senderName string, sender *model.User) {
 	// skip if inactive
 	if user.DeleteAt > 0 {
@@ -957,7 +697,12 @@ func sendNotificationEmail(c *Context, post *model.Post, user *model.User, chann
 			"Hour": fmt.Sprintf("%02d", tm.Hour()), "Minute": fmt.Sprintf("%02d", tm.Minute()),
 			"TimeZone": zone, "Month": month, "Day": day}))
 
-	if err := utils.SendMail(user.Email, html.UnescapeString(subject), bodyPage.Render()); err != nil {
+	replyTo := GetReplyToAddress(user.Id, post.Id)
+	if len(replyTo) > 0 {
+		bodyPage.Props["ReplyText"] = userLocale("api.templates.post_body.reply")
+	}
+
+	if err := QueueNotificationMailWithReplyTo(user.Email, replyTo, html.UnescapeString(subject), bodyPage.Render()); err != nil {
 		l4g.Error(utils.T("api.post.send_notifications_and_forget.send.error"), user.Email, err)
 	}
 
@@ -1028,18 +773,31 @@ func sendPushNotification(post *model.Post, user *model.User, channel *model.Cha
 	msg.Type = model.PUSH_TYPE_MESSAGE
 	msg.TeamId = channel.TeamId
 	msg.ChannelId = channel.Id
-	msg.ChannelName = channel.Name
+	msg.PostId = post.Id
 
-	if *utils.Cfg.EmailSettings.PushNotificationContents == model.FULL_NOTIFICATION {
-		if channel.Type == model.CHANNEL_DIRECT {
-			msg.Category = model.CATEGORY_DM
-			msg.Message = "@" + senderName + ": " + model.ClearMentionTags(post.Message)
-		} else {
-			msg.Message = senderName + userLocale("api.post.send_notifications_and_forget.push_in") + channelName + ": " + model.ClearMentionTags(post.Message)
-		}
+	if channel.Type == model.CHANNEL_DIRECT {
+		msg.Category = model.CATEGORY_DM
+	}
+
+	var fullMessage string
+	if channel.Type == model.CHANNEL_DIRECT {
+		fullMessage = "@" + senderName + ": " + model.ClearMentionTags(post.Message)
+	} else {
+		fullMessage = senderName + userLocale("api.post.send_notifications_and_forget.push_in") + channelName + ": " + model.ClearMentionTags(post.Message)
+	}
+
+	contents := *utils.Cfg.EmailSettings.PushNotificationContents
+
+	if contents == model.FULL_NOTIFICATION {
+		msg.ChannelName = channel.Name
+		msg.Message = fullMessage
+	} else if contents == model.ID_LOADED_NOTIFICATION || contents == model.ENCRYPTED_NOTIFICATION {
+		// the app fetches or decrypts the post itself so nothing about it is sent through the push services
+		msg.IsIdLoaded = true
+		msg.Message = userLocale("api.post.send_notifications_and_forget.push_id_loaded")
 	} else {
+		msg.ChannelName = channel.Name
 		if channel.Type == model.CHANNEL_DIRECT {
-			msg.Category = model.CATEGORY_DM
 			msg.Message = senderName + userLocale("api.post.send_notifications_and_forget.push_message")
 		} else if wasMentioned {
 			msg.Message = senderName + userLocale("api.post.send_notifications_and_forget.push_mention") + channelName
@@ -1053,10 +811,15 @@ func sendPushNotification(post *model.Post, user *model.User, channel *model.Cha
 	for _, session := range sessions {
 		tmpMessage := *model.PushNotificationFromJson(strings.NewReader(msg.ToJson()))
 		tmpMessage.SetDeviceIdAndPlatform(session.DeviceId)
-		sendToPushProxy(tmpMessage)
-		if einterfaces.GetMetricsInterface() != nil {
-			einterfaces.GetMetricsInterface().IncrementPostSentPush()
+
+		// devices without a key still get notified but have to load the post from the server
+		if contents == model.ENCRYPTED_NOTIFICATION && len(session.DeviceKey) > 0 {
+			if err := tmpMessage.EncryptContents(session.DeviceKey, &model.PushNotificationContents{Message: fullMessage, ChannelName: channel.Name}); err != nil {
+				l4g.Error(utils.T("api.post.send_notifications_and_forget.push_encrypt.error"), session.Id, err)
+			}
 		}
+
+		sendToPushSender(tmpMessage, session)
 	}
 }
 
@@ -1081,24 +844,7 @@ func clearPushNotification(userId string, channelId string) {
 	for _, session := range sessions {
 		tmpMessage := *model.PushNotificationFromJson(strings.NewReader(msg.ToJson()))
 		tmpMessage.SetDeviceIdAndPlatform(session.DeviceId)
-		sendToPushProxy(tmpMessage)
-	}
-}
-
-func sendToPushProxy(msg model.PushNotification) {
-	msg.ServerId = utils.CfgDiagnosticId
-
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: *utils.Cfg.ServiceSettings.EnableInsecureOutgoingConnections},
-	}
-	httpClient := &http.Client{Transport: tr}
-	request, _ := http.NewRequest("POST", *utils.Cfg.EmailSettings.PushNotificationServer+model.API_URL_SUFFIX_V1+"/send_push", strings.NewReader(msg.ToJson()))
-
-	if resp, err := httpClient.Do(request); err != nil {
-		l4g.Error(utils.T("api.post.send_notifications_and_forget.push_notification.error"), msg.DeviceId, err)
-	} else {
-		ioutil.ReadAll(resp.Body)
-		resp.Body.Close()
+		sendToPushSender(tmpMessage, session)
 	}
 }
 
@@ -1513,6 +1259,10 @@ func deletePost(c *Context, w http.ResponseWriter, r *http.Request) {
 		go DeletePostFiles(post)
 		go DeleteFlaggedPost(c.Session.UserId, post)
 
+		if len(post.RootId) == 0 {
+			go DeleteThreadMemberships(post)
+		}
+
 		InvalidateCacheForChannelPosts(post.ChannelId)
 
 		result := make(map[string]string)
@@ -1528,6 +1278,12 @@ func DeleteFlaggedPost(userId string, post *model.Post) {
 	}
 }
 
+func DeleteThreadMemberships(post *model.Post) {
+	if result := <-Srv.Store.ThreadMembership().PermanentDeleteByPost(post.Id); result.Err != nil {
+		l4g.Warn(utils.T("api.post.delete_thread_memberships.warn"), post.Id, result.Err)
+	}
+}
+
 func DeletePostFiles(post *model.Post) {
 	if len(post.FileIds) != 0 {
 		return
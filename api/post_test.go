@@ -902,6 +902,39 @@ func TestMakeDirectChannelVisible(t *testing.T) {
 	}
 }
 
+func TestAddChannelMentionKeywords(t *testing.T) {
+	user1 := &model.User{
+		Id:       model.NewId(),
+		Username: "user1",
+		NotifyProps: map[string]string{
+			"mention_keys": "user1,@user1",
+		},
+	}
+	user2 := &model.User{
+		Id:          model.NewId(),
+		Username:    "user2",
+		NotifyProps: map[string]string{},
+	}
+
+	keywords := getMentionKeywordsInChannel(map[string]*model.User{user1.Id: user1, user2.Id: user2}, nil)
+	addChannelMentionKeywords(keywords, map[string]model.StringMap{
+		user1.Id: {"mention_keys": "Outage, sev1"},
+		user2.Id: {"mention_keys": "outage"},
+	})
+
+	if ids := keywords["outage"]; len(ids) != 2 {
+		t.Fatal("both users should be mentioned by the channel keyword")
+	}
+
+	if ids := keywords["sev1"]; len(ids) != 1 || ids[0] != user1.Id {
+		t.Fatal("channel keyword should have been trimmed and added for user1")
+	}
+
+	if mentions, _, _, _, _ := getExplicitMentions("we have an OUTAGE", keywords); !mentions[user1.Id] || !mentions[user2.Id] {
+		t.Fatal("channel keywords should mention both users")
+	}
+}
+
 func TestGetMentionKeywords(t *testing.T) {
 	// user with username or custom mentions enabled
 	user1 := &model.User{
@@ -914,7 +947,7 @@ func TestGetMentionKeywords(t *testing.T) {
 	}
 
 	profiles := map[string]*model.User{user1.Id: user1}
-	mentions := getMentionKeywordsInChannel(profiles)
+	mentions := getMentionKeywordsInChannel(profiles, nil)
 	if len(mentions) != 3 {
 		t.Fatal("should've returned three mention keywords")
 	} else if ids, ok := mentions["user"]; !ok || ids[0] != user1.Id {
@@ -936,7 +969,7 @@ func TestGetMentionKeywords(t *testing.T) {
 	}
 
 	profiles = map[string]*model.User{user2.Id: user2}
-	mentions = getMentionKeywordsInChannel(profiles)
+	mentions = getMentionKeywordsInChannel(profiles, nil)
 	if len(mentions) != 2 {
 		t.Fatal("should've returned two mention keyword")
 	} else if ids, ok := mentions["First"]; !ok || ids[0] != user2.Id {
@@ -954,7 +987,7 @@ func TestGetMentionKeywords(t *testing.T) {
 	}
 
 	profiles = map[string]*model.User{user3.Id: user3}
-	mentions = getMentionKeywordsInChannel(profiles)
+	mentions = getMentionKeywordsInChannel(profiles, nil)
 	if len(mentions) != 3 {
 		t.Fatal("should've returned three mention keywords")
 	} else if ids, ok := mentions["@channel"]; !ok || ids[0] != user3.Id {
@@ -976,7 +1009,7 @@ func TestGetMentionKeywords(t *testing.T) {
 	}
 
 	profiles = map[string]*model.User{user4.Id: user4}
-	mentions = getMentionKeywordsInChannel(profiles)
+	mentions = getMentionKeywordsInChannel(profiles, nil)
 	if len(mentions) != 6 {
 		t.Fatal("should've returned six mention keywords")
 	} else if ids, ok := mentions["user"]; !ok || ids[0] != user4.Id {
@@ -1018,7 +1051,7 @@ func TestGetMentionKeywords(t *testing.T) {
 		user3.Id: user3,
 		user4.Id: user4,
 	}
-	mentions = getMentionKeywordsInChannel(profiles)
+	mentions = getMentionKeywordsInChannel(profiles, nil)
 	if len(mentions) != 6 {
 		t.Fatal("should've returned six mention keywords")
 	} else if ids, ok := mentions["user"]; !ok || len(ids) != 2 || (ids[0] != user1.Id && ids[1] != user1.Id) || (ids[0] != user4.Id && ids[1] != user4.Id) {
@@ -1034,6 +1067,18 @@ func TestGetMentionKeywords(t *testing.T) {
 	} else if ids, ok := mentions["@all"]; !ok || len(ids) != 2 || (ids[0] != user3.Id && ids[1] != user3.Id) || (ids[0] != user4.Id && ids[1] != user4.Id) {
 		t.Fatal("should've mentioned user3 and user4 with @all")
 	}
+
+	// user groups only mention their members in the channel
+	groups := map[string][]string{"sre": {user1.Id, model.NewId()}}
+	profiles = map[string]*model.User{user1.Id: user1}
+	mentions = getMentionKeywordsInChannel(profiles, groups)
+	if ids, ok := mentions["@sre"]; !ok || len(ids) != 1 || ids[0] != user1.Id {
+		t.Fatal("should've mentioned only user1 with @sre")
+	}
+
+	if mentioned, _, _, _, _ := getExplicitMentions("ping @sre.", mentions); !mentioned[user1.Id] {
+		t.Fatal("should've mentioned user1 through the group")
+	}
 }
 
 func TestGetExplicitMentionsAtHere(t *testing.T) {
@@ -1360,3 +1405,66 @@ func TestSendNotifications(t *testing.T) {
 		t.Fatal("user should have been mentioned")
 	}
 }
+
+func TestAutoResponder(t *testing.T) {
+	th := Setup().InitBasic()
+	Client := th.BasicClient
+
+	th.LoginBasic2()
+
+	data := map[string]string{
+		"user_id":                                th.BasicUser2.Id,
+		"email":                                  "true",
+		"desktop":                                "all",
+		"desktop_sound":                          "false",
+		"comments":                               "any",
+		model.AUTO_RESPONDER_ACTIVE_NOTIFY_PROP:  "true",
+		model.AUTO_RESPONDER_MESSAGE_NOTIFY_PROP: "",
+	}
+
+	if _, err := Client.UpdateUserNotify(data); err == nil {
+		t.Fatal("should have failed without a message")
+	}
+
+	data[model.AUTO_RESPONDER_MESSAGE_NOTIFY_PROP] = "I'm out of the office until Monday"
+	Client.Must(Client.UpdateUserNotify(data))
+
+	th.LoginBasic()
+
+	if user := Client.Must(Client.GetUser(th.BasicUser2.Id, "")).Data.(*model.User); user.OutOfOffice == nil || user.OutOfOffice.Message != data[model.AUTO_RESPONDER_MESSAGE_NOTIFY_PROP] {
+		t.Fatal("should have shown the out of office message in the profile")
+	}
+
+	channel := Client.Must(Client.CreateDirectChannel(th.BasicUser2.Id)).Data.(*model.Channel)
+
+	Client.Must(Client.CreatePost(&model.Post{ChannelId: channel.Id, Message: "are you there?"}))
+	time.Sleep(100 * time.Millisecond)
+	Client.Must(Client.CreatePost(&model.Post{ChannelId: channel.Id, Message: "hello?"}))
+	time.Sleep(100 * time.Millisecond)
+
+	autoResponses := 0
+	for _, post := range Client.Must(Client.GetPosts(channel.Id, 0, 10, "")).Data.(*model.PostList).Posts {
+		if post.Type == model.POST_AUTO_RESPONDER {
+			autoResponses++
+
+			if post.UserId != th.BasicUser2.Id || post.Message != data[model.AUTO_RESPONDER_MESSAGE_NOTIFY_PROP] {
+				t.Fatal("should have replied with the out of office message")
+			}
+		}
+	}
+
+	if autoResponses != 1 {
+		t.Fatal("should have replied once", autoResponses)
+	}
+
+	// replies aren't sent to the user who is out of the office
+	th.LoginBasic2()
+	Client.Must(Client.CreatePost(&model.Post{ChannelId: channel.Id, Message: "I'm back"}))
+	time.Sleep(100 * time.Millisecond)
+
+	if result := <-Srv.Store.Post().CountByTypeSince(channel.Id, th.BasicUser.Id, model.POST_AUTO_RESPONDER, 0); result.Err != nil {
+		t.Fatal(result.Err)
+	} else if result.Data.(int64) != 0 {
+		t.Fatal("shouldn't have replied on behalf of a user without an out of office message")
+	}
+}
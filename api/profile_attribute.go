@@ -0,0 +1,178 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api
+
+import (
+	"net/http"
+
+	l4g "github.com/alecthomas/log4go"
+
+	"github.com/mattermost/platform/model"
+	"github.com/mattermost/platform/store"
+	"github.com/mattermost/platform/utils"
+)
+
+func InitProfileAttribute() {
+	l4g.Debug(utils.T("api.profile_attribute.init.debug"))
+
+	BaseRoutes.Users.Handle("/profile_attributes", ApiUserRequired(getProfileAttributes)).Methods("GET")
+
+	BaseRoutes.Admin.Handle("/add_profile_attribute", ApiAdminSystemRequired(addProfileAttribute)).Methods("POST")
+	BaseRoutes.Admin.Handle("/update_profile_attribute", ApiAdminSystemRequired(updateProfileAttribute)).Methods("POST")
+	BaseRoutes.Admin.Handle("/delete_profile_attribute", ApiAdminSystemRequired(deleteProfileAttribute)).Methods("POST")
+}
+
+// GetProfileAttributes returns the custom profile attributes in the order they are shown on profiles
+func GetProfileAttributes() ([]*model.ProfileAttribute, *model.AppError) {
+	if result := <-Srv.Store.ProfileAttribute().GetAll(true); result.Err != nil {
+		return nil, result.Err
+	} else {
+		return result.Data.([]*model.ProfileAttribute), nil
+	}
+}
+
+func getProfileAttributes(c *Context, w http.ResponseWriter, r *http.Request) {
+	if attributes, err := GetProfileAttributes(); err != nil {
+		c.Err = err
+	} else {
+		w.Write([]byte(model.ProfileAttributesToJson(attributes)))
+	}
+}
+
+func addProfileAttribute(c *Context, w http.ResponseWriter, r *http.Request) {
+	attribute := model.ProfileAttributeFromJson(r.Body)
+	if attribute == nil {
+		c.SetInvalidParam("addProfileAttribute", "attribute")
+		return
+	}
+
+	attribute.Id = ""
+
+	if result := <-Srv.Store.ProfileAttribute().Save(attribute); result.Err != nil {
+		result.Err.StatusCode = http.StatusBadRequest
+		c.Err = result.Err
+	} else {
+		rattribute := result.Data.(*model.ProfileAttribute)
+		c.LogAudit("name=" + rattribute.Name)
+		w.Write([]byte(rattribute.ToJson()))
+	}
+}
+
+func updateProfileAttribute(c *Context, w http.ResponseWriter, r *http.Request) {
+	attribute := model.ProfileAttributeFromJson(r.Body)
+	if attribute == nil {
+		c.SetInvalidParam("updateProfileAttribute", "attribute")
+		return
+	}
+
+	var oldAttribute *model.ProfileAttribute
+	if result := <-Srv.Store.ProfileAttribute().Get(attribute.Id); result.Err != nil {
+		result.Err.StatusCode = http.StatusBadRequest
+		c.Err = result.Err
+		return
+	} else {
+		oldAttribute = result.Data.(*model.ProfileAttribute)
+	}
+
+	// Values are saved on users by name so it can't be changed once the attribute exists
+	attribute.Name = oldAttribute.Name
+	attribute.CreateAt = oldAttribute.CreateAt
+
+	if result := <-Srv.Store.ProfileAttribute().Update(attribute); result.Err != nil {
+		result.Err.StatusCode = http.StatusBadRequest
+		c.Err = result.Err
+	} else {
+		rattribute := result.Data.(*model.ProfileAttribute)
+		c.LogAudit("name=" + rattribute.Name)
+		w.Write([]byte(rattribute.ToJson()))
+	}
+}
+
+func deleteProfileAttribute(c *Context, w http.ResponseWriter, r *http.Request) {
+	props := model.MapFromJson(r.Body)
+
+	id := props["id"]
+	if len(id) != 26 {
+		c.SetInvalidParam("deleteProfileAttribute", "id")
+		return
+	}
+
+	if result := <-Srv.Store.ProfileAttribute().Delete(id); result.Err != nil {
+		result.Err.StatusCode = http.StatusBadRequest
+		c.Err = result.Err
+		return
+	}
+
+	c.LogAudit("id=" + id)
+
+	ReturnStatusOK(w)
+}
+
+// sanitizeProfileAttributes removes the values of hidden attributes unless showHidden is set
+func sanitizeProfileAttributes(user *model.User, showHidden bool) {
+	if len(user.ProfileAttributes) == 0 {
+		return
+	}
+
+	if attributes, err := GetProfileAttributes(); err != nil {
+		l4g.Error(utils.T("api.profile_attribute.sanitize.error"), user.Id, err.Error())
+		user.ProfileAttributes = model.StringMap{}
+	} else {
+		user.SanitizeProfileAttributes(attributes, showHidden)
+	}
+}
+
+// cleanProfileAttributes validates the attribute values sent with a profile update. Attributes synchronized
+// from the user's sign in service keep their current values and leaving the values out keeps them all.
+func cleanProfileAttributes(user *model.User) *model.AppError {
+	var oldUser *model.User
+	if result := <-Srv.Store.User().Get(user.Id); result.Err != nil {
+		return result.Err
+	} else {
+		oldUser = result.Data.(*model.User)
+	}
+
+	if user.ProfileAttributes == nil {
+		user.ProfileAttributes = oldUser.ProfileAttributes
+		return nil
+	}
+
+	attributes, err := GetProfileAttributes()
+	if err != nil {
+		return err
+	}
+
+	values, err := model.CleanProfileAttributes(attributes, user.ProfileAttributes)
+	if err != nil {
+		err.StatusCode = http.StatusBadRequest
+		return err
+	}
+
+	for _, attribute := range attributes {
+		if len(attribute.MappedAttribute(oldUser.AuthService)) > 0 {
+			if value, ok := oldUser.ProfileAttributes[attribute.Name]; ok {
+				values[attribute.Name] = value
+			} else {
+				delete(values, attribute.Name)
+			}
+		}
+	}
+
+	user.ProfileAttributes = values
+
+	return nil
+}
+
+// addProfileAttributeSearchOptions lets searches match the values of the attributes the user can see
+func addProfileAttributeSearchOptions(searchOptions map[string]bool, showHidden bool) {
+	if attributes, err := GetProfileAttributes(); err != nil {
+		l4g.Error(utils.T("api.profile_attribute.search.error"), err.Error())
+	} else {
+		for _, attribute := range attributes {
+			if showHidden || attribute.Visibility != model.PROFILE_ATTRIBUTE_VISIBILITY_HIDDEN {
+				searchOptions[store.USER_SEARCH_OPTION_PROFILE_ATTRIBUTE_PREFIX+attribute.Name] = true
+			}
+		}
+	}
+}
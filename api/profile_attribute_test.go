@@ -0,0 +1,114 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api
+
+import (
+	"testing"
+
+	"github.com/mattermost/platform/model"
+)
+
+func TestProfileAttributes(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	Client := th.BasicClient
+
+	if _, err := Client.AddProfileAttribute(&model.ProfileAttribute{Name: "department", DisplayName: "Department", Type: model.PROFILE_ATTRIBUTE_TYPE_TEXT}); err == nil {
+		t.Fatal("should have failed without permissions")
+	}
+
+	department := th.SystemAdminClient.Must(th.SystemAdminClient.AddProfileAttribute(&model.ProfileAttribute{
+		Name:        "department",
+		DisplayName: "Department",
+		Type:        model.PROFILE_ATTRIBUTE_TYPE_TEXT,
+	})).Data.(*model.ProfileAttribute)
+	defer th.SystemAdminClient.DeleteProfileAttribute(department.Id)
+
+	pronouns := th.SystemAdminClient.Must(th.SystemAdminClient.AddProfileAttribute(&model.ProfileAttribute{
+		Name:        "pronouns",
+		DisplayName: "Pronouns",
+		Type:        model.PROFILE_ATTRIBUTE_TYPE_SELECT,
+		Options:     model.StringArray{"she/her", "he/him", "they/them"},
+	})).Data.(*model.ProfileAttribute)
+	defer th.SystemAdminClient.DeleteProfileAttribute(pronouns.Id)
+
+	location := th.SystemAdminClient.Must(th.SystemAdminClient.AddProfileAttribute(&model.ProfileAttribute{
+		Name:        "location",
+		DisplayName: "Location",
+		Type:        model.PROFILE_ATTRIBUTE_TYPE_TEXT,
+		Visibility:  model.PROFILE_ATTRIBUTE_VISIBILITY_HIDDEN,
+	})).Data.(*model.ProfileAttribute)
+	defer th.SystemAdminClient.DeleteProfileAttribute(location.Id)
+
+	if attributes := Client.Must(Client.GetProfileAttributes()).Data.([]*model.ProfileAttribute); len(attributes) < 3 {
+		t.Fatal("should have returned the attributes")
+	}
+
+	location.Name = "office"
+	location.DisplayName = "Office"
+	if rlocation := th.SystemAdminClient.Must(th.SystemAdminClient.UpdateProfileAttribute(location)).Data.(*model.ProfileAttribute); rlocation.Name != "location" || rlocation.DisplayName != "Office" {
+		t.Fatal("should have updated the display name but not the name")
+	}
+
+	user := th.BasicUser
+	user.ProfileAttributes = model.StringMap{"pronouns": "other"}
+	if _, err := Client.UpdateUser(user); err == nil {
+		t.Fatal("should have failed with a value that isn't an option")
+	}
+
+	user.ProfileAttributes = model.StringMap{"department": "Marketing", "pronouns": "they/them", "location": "Toronto", "unknown": "value"}
+	ruser := Client.Must(Client.UpdateUser(user)).Data.(*model.User)
+	if len(ruser.ProfileAttributes) != 3 || ruser.ProfileAttributes["location"] != "Toronto" {
+		t.Fatal("should have saved the known attributes", ruser.ProfileAttributes)
+	}
+
+	// leaving the attributes out keeps them
+	ruser.ProfileAttributes = nil
+	ruser = Client.Must(Client.UpdateUser(ruser)).Data.(*model.User)
+	if ruser.ProfileAttributes["department"] != "Marketing" {
+		t.Fatal("should have kept the attributes")
+	}
+
+	Client.Login(th.BasicUser2.Email, th.BasicUser2.Password)
+
+	other := Client.Must(Client.GetUser(user.Id, "")).Data.(*model.User)
+	if other.ProfileAttributes["department"] != "Marketing" {
+		t.Fatal("should have returned visible attributes")
+	}
+
+	if _, ok := other.ProfileAttributes["location"]; ok {
+		t.Fatal("shouldn't have returned hidden attributes")
+	}
+
+	if profiles := Client.Must(Client.GetProfilesByIds([]string{user.Id})).Data.(map[string]*model.User); profiles[user.Id].ProfileAttributes["pronouns"] != "they/them" {
+		t.Fatal("should have returned visible attributes")
+	} else if _, ok := profiles[user.Id].ProfileAttributes["location"]; ok {
+		t.Fatal("shouldn't have returned hidden attributes")
+	}
+
+	if admin := th.SystemAdminClient.Must(th.SystemAdminClient.GetUser(user.Id, "")).Data.(*model.User); admin.ProfileAttributes["location"] != "Toronto" {
+		t.Fatal("admins should see hidden attributes")
+	}
+
+	if profiles := Client.Must(Client.SearchUsers(model.UserSearch{Term: "marketing", TeamId: th.BasicTeam.Id})).Data.([]*model.User); len(profiles) != 1 || profiles[0].Id != user.Id {
+		t.Fatal("should have found the user by department")
+	}
+
+	if profiles := Client.Must(Client.SearchUsers(model.UserSearch{Term: "toronto", TeamId: th.BasicTeam.Id})).Data.([]*model.User); len(profiles) != 0 {
+		t.Fatal("shouldn't have found the user by a hidden attribute")
+	}
+
+	if profiles := th.SystemAdminClient.Must(th.SystemAdminClient.SearchUsers(model.UserSearch{Term: "toronto", TeamId: th.BasicTeam.Id})).Data.([]*model.User); len(profiles) != 1 {
+		t.Fatal("admins should find users by hidden attributes")
+	}
+
+	th.SystemAdminClient.Must(th.SystemAdminClient.DeleteProfileAttribute(department.Id))
+
+	if _, err := th.SystemAdminClient.DeleteProfileAttribute(department.Id); err == nil {
+		t.Fatal("should have failed to delete a missing attribute")
+	}
+
+	if other := Client.Must(Client.GetUser(user.Id, "")).Data.(*model.User); len(other.ProfileAttributes["department"]) > 0 {
+		t.Fatal("shouldn't have returned the values of deleted attributes")
+	}
+}
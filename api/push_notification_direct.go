@@ -0,0 +1,427 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	l4g "github.com/alecthomas/log4go"
+
+	"github.com/mattermost/platform/model"
+	"github.com/mattermost/platform/utils"
+)
+
+const (
+	// Apple rejects provider tokens more than an hour old but also ones that are refreshed too often
+	APNS_TOKEN_LIFETIME = 50 * time.Minute
+
+	FCM_SCOPE          = "https://www.googleapis.com/auth/firebase.messaging"
+	FCM_TOKEN_LIFETIME = time.Hour
+)
+
+// APNSSender sends notifications to iOS devices using the APNs HTTP/2 API, authenticating with a JSON web
+// token signed by a key from the Apple developer account
+type APNSSender struct {
+	server string
+	topic  string
+	keyId  string
+	teamId string
+	key    *ecdsa.PrivateKey
+	client *http.Client
+
+	mutex         sync.Mutex
+	token         string
+	tokenIssuedAt time.Time
+}
+
+func NewAPNSSender(server, keyFile, keyId, teamId, topic string, client *http.Client) (*APNSSender, *model.AppError) {
+	key, err := loadPrivateKey(utils.FindConfigFile(keyFile), false)
+	if err != nil {
+		return nil, model.NewLocAppError("NewAPNSSender", "api.push_notification.apns.key.app_error", nil, err.Error())
+	}
+
+	return &APNSSender{
+		server: strings.TrimRight(server, "/"),
+		topic:  topic,
+		keyId:  keyId,
+		teamId: teamId,
+		key:    key.(*ecdsa.PrivateKey),
+		client: client,
+	}, nil
+}
+
+func (s *APNSSender) getToken() (string, *model.AppError) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if len(s.token) > 0 && time.Since(s.tokenIssuedAt) < APNS_TOKEN_LIFETIME {
+		return s.token, nil
+	}
+
+	now := time.Now()
+	token, err := signJWT(map[string]interface{}{"alg": "ES256", "kid": s.keyId}, map[string]interface{}{"iss": s.teamId, "iat": now.Unix()}, func(hash []byte) ([]byte, error) {
+		r, sig, err := ecdsa.Sign(rand.Reader, s.key, hash)
+		if err != nil {
+			return nil, err
+		}
+
+		// JWTs use the fixed size encoding of the signature rather than ASN.1
+		signature := make([]byte, 64)
+		r.FillBytes(signature[:32])
+		sig.FillBytes(signature[32:])
+		return signature, nil
+	})
+	if err != nil {
+		return "", model.NewLocAppError("APNSSender.getToken", "api.push_notification.apns.token.app_error", nil, err.Error())
+	}
+
+	s.token = token
+	s.tokenIssuedAt = now
+
+	return token, nil
+}
+
+func (s *APNSSender) clearToken() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.token = ""
+}
+
+func (s *APNSSender) Send(msg *model.PushNotification) *model.AppError {
+	token, appErr := s.getToken()
+	if appErr != nil {
+		appErr.StatusCode = http.StatusBadRequest
+		return appErr
+	}
+
+	aps := map[string]interface{}{"badge": msg.Badge}
+	pushType := "alert"
+	priority := "10"
+
+	if msg.Type == model.PUSH_TYPE_CLEAR {
+		aps["content-available"] = 1
+		pushType = "background"
+		priority = "5"
+	} else {
+		aps["alert"] = msg.Message
+		aps["sound"] = "default"
+
+		if len(msg.Category) > 0 {
+			aps["category"] = msg.Category
+		}
+
+		if msg.ContentAvailable != 0 {
+			aps["content-available"] = 1
+		}
+
+		// lets the app's notification service extension replace the placeholder with the post
+		if msg.IsIdLoaded {
+			aps["mutable-content"] = 1
+		}
+	}
+
+	payload, _ := json.Marshal(map[string]interface{}{
+		"aps":            aps,
+		"type":           msg.Type,
+		"team_id":        msg.TeamId,
+		"channel_id":     msg.ChannelId,
+		"channel_name":   msg.ChannelName,
+		"post_id":        msg.PostId,
+		"is_id_loaded":   msg.IsIdLoaded,
+		"encrypted_data": msg.EncryptedData,
+	})
+
+	request, _ := http.NewRequest("POST", s.server+"/3/device/"+msg.DeviceId, bytes.NewReader(payload))
+	request.Header.Set("authorization", "bearer "+token)
+	request.Header.Set("apns-topic", s.topic)
+	request.Header.Set("apns-push-type", pushType)
+	request.Header.Set("apns-priority", priority)
+
+	resp, err := s.client.Do(request)
+	if err != nil {
+		return newPushError("api.push_notification.apns.send.app_error", http.StatusServiceUnavailable, err.Error())
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		l4g.Debug(utils.T("api.push_notification.apns.sent.debug"), msg.DeviceId, resp.Header.Get("apns-id"))
+		return nil
+	}
+
+	var reply struct {
+		Reason string `json:"reason"`
+	}
+	json.NewDecoder(resp.Body).Decode(&reply)
+
+	details := resp.Status + " " + reply.Reason
+
+	switch {
+	case resp.StatusCode == http.StatusGone || reply.Reason == "BadDeviceToken" || reply.Reason == "Unregistered" || reply.Reason == "DeviceTokenNotForTopic":
+		return newPushError("api.push_notification.invalid_device.app_error", http.StatusGone, details)
+	case reply.Reason == "ExpiredProviderToken" || reply.Reason == "InvalidProviderToken":
+		// sign a new token for the next attempt
+		s.clearToken()
+		return newPushError("api.push_notification.apns.send.app_error", http.StatusUnauthorized, details)
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return newPushError("api.push_notification.apns.send.app_error", resp.StatusCode, details)
+	default:
+		return newPushError("api.push_notification.apns.send.app_error", http.StatusBadRequest, details)
+	}
+}
+
+// FCMSender sends notifications to Android devices using the FCM HTTP v1 API, authenticating with an
+// OAuth access token obtained for a Firebase service account
+type FCMSender struct {
+	server      string
+	projectId   string
+	clientEmail string
+	tokenURI    string
+	key         *rsa.PrivateKey
+	client      *http.Client
+
+	mutex          sync.Mutex
+	accessToken    string
+	tokenExpiresAt time.Time
+}
+
+type fcmServiceAccount struct {
+	ProjectId   string `json:"project_id"`
+	PrivateKey  string `json:"private_key"`
+	ClientEmail string `json:"client_email"`
+	TokenURI    string `json:"token_uri"`
+}
+
+func NewFCMSender(server, serviceAccountFile string, client *http.Client) (*FCMSender, *model.AppError) {
+	var account fcmServiceAccount
+
+	if data, err := ioutil.ReadFile(utils.FindConfigFile(serviceAccountFile)); err != nil {
+		return nil, model.NewLocAppError("NewFCMSender", "api.push_notification.fcm.service_account.app_error", nil, err.Error())
+	} else if err := json.Unmarshal(data, &account); err != nil {
+		return nil, model.NewLocAppError("NewFCMSender", "api.push_notification.fcm.service_account.app_error", nil, err.Error())
+	}
+
+	key, err := parsePrivateKey([]byte(account.PrivateKey), true)
+	if err != nil {
+		return nil, model.NewLocAppError("NewFCMSender", "api.push_notification.fcm.service_account.app_error", nil, err.Error())
+	}
+
+	return &FCMSender{
+		server:      strings.TrimRight(server, "/"),
+		projectId:   account.ProjectId,
+		clientEmail: account.ClientEmail,
+		tokenURI:    account.TokenURI,
+		key:         key.(*rsa.PrivateKey),
+		client:      client,
+	}, nil
+}
+
+func (s *FCMSender) getAccessToken() (string, *model.AppError) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if len(s.accessToken) > 0 && time.Now().Before(s.tokenExpiresAt) {
+		return s.accessToken, nil
+	}
+
+	now := time.Now()
+	assertion, err := signJWT(map[string]interface{}{"alg": "RS256", "typ": "JWT"}, map[string]interface{}{
+		"iss":   s.clientEmail,
+		"scope": FCM_SCOPE,
+		"aud":   s.tokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(FCM_TOKEN_LIFETIME).Unix(),
+	}, func(hash []byte) ([]byte, error) {
+		return rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, hash)
+	})
+	if err != nil {
+		return "", model.NewLocAppError("FCMSender.getAccessToken", "api.push_notification.fcm.token.app_error", nil, err.Error())
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "urn:ietf:params:oauth:grant-type:jwt-bearer")
+	form.Set("assertion", assertion)
+
+	resp, err := s.client.PostForm(s.tokenURI, form)
+	if err != nil {
+		return "", newPushError("api.push_notification.fcm.token.app_error", http.StatusServiceUnavailable, err.Error())
+	}
+	defer resp.Body.Close()
+
+	var reply struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", newPushError("api.push_notification.fcm.token.app_error", resp.StatusCode, resp.Status)
+	} else if err := json.NewDecoder(resp.Body).Decode(&reply); err != nil || len(reply.AccessToken) == 0 {
+		return "", newPushError("api.push_notification.fcm.token.app_error", http.StatusServiceUnavailable, resp.Status)
+	}
+
+	s.accessToken = reply.AccessToken
+	// refresh the token a minute early so that it doesn't expire while a notification is being sent
+	s.tokenExpiresAt = now.Add(time.Duration(reply.ExpiresIn)*time.Second - time.Minute)
+
+	return s.accessToken, nil
+}
+
+func (s *FCMSender) clearAccessToken() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.accessToken = ""
+}
+
+func (s *FCMSender) Send(msg *model.PushNotification) *model.AppError {
+	token, appErr := s.getAccessToken()
+	if appErr != nil {
+		return appErr
+	}
+
+	// data messages only support string values
+	data := map[string]string{
+		"type":           msg.Type,
+		"badge":          strconv.Itoa(msg.Badge),
+		"message":        msg.Message,
+		"team_id":        msg.TeamId,
+		"channel_id":     msg.ChannelId,
+		"channel_name":   msg.ChannelName,
+		"category":       msg.Category,
+		"server_id":      msg.ServerId,
+		"post_id":        msg.PostId,
+		"is_id_loaded":   strconv.FormatBool(msg.IsIdLoaded),
+		"encrypted_data": msg.EncryptedData,
+	}
+
+	payload, _ := json.Marshal(map[string]interface{}{
+		"message": map[string]interface{}{
+			"token":   msg.DeviceId,
+			"data":    data,
+			"android": map[string]string{"priority": "high"},
+		},
+	})
+
+	request, _ := http.NewRequest("POST", s.server+"/v1/projects/"+s.projectId+"/messages:send", bytes.NewReader(payload))
+	request.Header.Set("Authorization", "Bearer "+token)
+	request.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(request)
+	if err != nil {
+		return newPushError("api.push_notification.fcm.send.app_error", http.StatusServiceUnavailable, err.Error())
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		var reply struct {
+			Name string `json:"name"`
+		}
+		json.NewDecoder(resp.Body).Decode(&reply)
+
+		l4g.Debug(utils.T("api.push_notification.fcm.sent.debug"), msg.DeviceId, reply.Name)
+		return nil
+	}
+
+	var reply struct {
+		Error struct {
+			Status  string `json:"status"`
+			Details []struct {
+				ErrorCode string `json:"errorCode"`
+			} `json:"details"`
+		} `json:"error"`
+	}
+	json.NewDecoder(resp.Body).Decode(&reply)
+
+	errorCode := reply.Error.Status
+	for _, detail := range reply.Error.Details {
+		if len(detail.ErrorCode) > 0 {
+			errorCode = detail.ErrorCode
+		}
+	}
+
+	details := resp.Status + " " + errorCode
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || errorCode == "UNREGISTERED":
+		return newPushError("api.push_notification.invalid_device.app_error", http.StatusGone, details)
+	case resp.StatusCode == http.StatusUnauthorized:
+		// get a new access token for the next attempt
+		s.clearAccessToken()
+		return newPushError("api.push_notification.fcm.send.app_error", http.StatusUnauthorized, details)
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return newPushError("api.push_notification.fcm.send.app_error", resp.StatusCode, details)
+	default:
+		return newPushError("api.push_notification.fcm.send.app_error", http.StatusBadRequest, details)
+	}
+}
+
+func loadPrivateKey(fileName string, isRSA bool) (crypto.PrivateKey, error) {
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	return parsePrivateKey(data, isRSA)
+}
+
+// parsePrivateKey reads a PEM encoded PKCS #8 private key, such as an APNs .p8 key or the key of a Google
+// service account
+func parsePrivateKey(data []byte, isRSA bool) (crypto.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, x509.ErrUnsupportedAlgorithm
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := key.(*rsa.PrivateKey); ok != isRSA {
+		return nil, x509.ErrUnsupportedAlgorithm
+	} else if _, ok := key.(*ecdsa.PrivateKey); !isRSA && !ok {
+		return nil, x509.ErrUnsupportedAlgorithm
+	}
+
+	return key, nil
+}
+
+// signJWT builds a JSON web token from its header and claims using the given function to sign their hash
+func signJWT(header map[string]interface{}, claims map[string]interface{}, sign func(hash []byte) ([]byte, error)) (string, error) {
+	h, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+
+	c, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c)
+	hash := sha256.Sum256([]byte(signingInput))
+
+	signature, err := sign(hash[:])
+	if err != nil {
+		return "", err
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
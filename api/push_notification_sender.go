@@ -0,0 +1,240 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api
+
+import (
+	"crypto/tls"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	l4g "github.com/alecthomas/log4go"
+
+	"github.com/mattermost/platform/einterfaces"
+	"github.com/mattermost/platform/model"
+	"github.com/mattermost/platform/utils"
+)
+
+const (
+	PUSH_NOTIFICATION_TIMEOUT     = 30 * time.Second
+	PUSH_NOTIFICATION_RETRY_DELAY = time.Second // doubled after each failed attempt
+)
+
+// PushNotificationSender delivers a push notification to a single device. Errors have a 410 Gone status
+// code when the device is no longer registered and a 400 Bad Request one when sending again won't help.
+// Anything else is retried.
+type PushNotificationSender interface {
+	Send(msg *model.PushNotification) *model.AppError
+}
+
+var pushNotificationRetryDelay = PUSH_NOTIFICATION_RETRY_DELAY
+
+var pushSenderMutex sync.Mutex
+var pushSender PushNotificationSender
+var pushSenderSettings string
+
+// getPushNotificationSender returns the sender for the configured delivery method. Senders are kept between
+// notifications so that the tokens used to authenticate with APNs and FCM are reused until they expire.
+func getPushNotificationSender() (PushNotificationSender, *model.AppError) {
+	settings := utils.Cfg.EmailSettings
+
+	key := strings.Join([]string{
+		*settings.PushNotificationSender,
+		*settings.PushNotificationServer,
+		*settings.APNSServer,
+		*settings.APNSKeyFile,
+		*settings.APNSKeyId,
+		*settings.APNSTeamId,
+		*settings.APNSTopic,
+		*settings.FCMServer,
+		*settings.FCMServiceAccountFile,
+	}, "|")
+
+	pushSenderMutex.Lock()
+	defer pushSenderMutex.Unlock()
+
+	if pushSender != nil && pushSenderSettings == key {
+		return pushSender, nil
+	}
+
+	client := newPushHttpClient()
+
+	var sender PushNotificationSender
+	if *settings.PushNotificationSender == model.PUSH_SENDER_DIRECT {
+		direct := &DirectPushSender{}
+
+		if len(*settings.APNSKeyFile) > 0 {
+			if apns, err := NewAPNSSender(*settings.APNSServer, *settings.APNSKeyFile, *settings.APNSKeyId, *settings.APNSTeamId, *settings.APNSTopic, client); err != nil {
+				return nil, err
+			} else {
+				direct.APNS = apns
+			}
+		}
+
+		if len(*settings.FCMServiceAccountFile) > 0 {
+			if fcm, err := NewFCMSender(*settings.FCMServer, *settings.FCMServiceAccountFile, client); err != nil {
+				return nil, err
+			} else {
+				direct.FCM = fcm
+			}
+		}
+
+		sender = direct
+	} else {
+		sender = NewProxyPushSender(*settings.PushNotificationServer, client)
+	}
+
+	pushSender = sender
+	pushSenderSettings = key
+
+	return sender, nil
+}
+
+func newPushHttpClient() *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: *utils.Cfg.ServiceSettings.EnableInsecureOutgoingConnections},
+			ForceAttemptHTTP2: true,
+		},
+		Timeout: PUSH_NOTIFICATION_TIMEOUT,
+	}
+}
+
+func newPushError(id string, statusCode int, details string) *model.AppError {
+	err := model.NewLocAppError("PushNotificationSender.Send", id, nil, details)
+	err.StatusCode = statusCode
+	return err
+}
+
+// sendToPushSender delivers a notification to the device of a mobile app session in the background
+func sendToPushSender(msg model.PushNotification, session *model.Session) {
+	sender, err := getPushNotificationSender()
+	if err != nil {
+		l4g.Error(utils.T("api.post.send_notifications_and_forget.push_notification.error"), msg.DeviceId, err)
+		return
+	}
+
+	go deliverPushNotification(sender, msg, session)
+}
+
+// deliverPushNotification sends a notification, retrying temporary failures with a growing delay and
+// removing the device from the session if it's no longer registered. It returns true if it was sent.
+func deliverPushNotification(sender PushNotificationSender, msg model.PushNotification, session *model.Session) bool {
+	msg.ServerId = utils.CfgDiagnosticId
+	metrics := einterfaces.GetMetricsInterface()
+
+	for attempt := 1; ; attempt++ {
+		err := sender.Send(&msg)
+		if err == nil {
+			if metrics != nil && msg.Type == model.PUSH_TYPE_MESSAGE {
+				metrics.IncrementPostSentPush()
+			}
+
+			return true
+		}
+
+		if err.StatusCode == http.StatusGone {
+			l4g.Info(utils.T("api.push_notification.deliver.remove_device.info"), msg.DeviceId, session.UserId)
+			removePushDevice(session)
+
+			if metrics != nil {
+				metrics.IncrementPushNotificationDeviceRemoved()
+			}
+
+			return false
+		}
+
+		if err.StatusCode == http.StatusBadRequest || attempt >= *utils.Cfg.EmailSettings.PushMaxAttempts {
+			l4g.Error(utils.T("api.post.send_notifications_and_forget.push_notification.error"), msg.DeviceId, err)
+
+			if metrics != nil {
+				metrics.IncrementPushNotificationFailure()
+			}
+
+			return false
+		}
+
+		l4g.Warn(utils.T("api.push_notification.deliver.retry.warn"), msg.DeviceId, attempt, err)
+
+		if metrics != nil {
+			metrics.IncrementPushNotificationRetry()
+		}
+
+		time.Sleep(pushNotificationRetryDelay << uint(attempt-1))
+	}
+}
+
+func removePushDevice(session *model.Session) {
+	if result := <-Srv.Store.Session().UpdateDeviceId(session.Id, "", "", session.ExpiresAt); result.Err != nil {
+		l4g.Error(utils.T("api.push_notification.remove_device.error"), session.Id, result.Err)
+		return
+	}
+
+	sessionCache.Remove(session.Token)
+}
+
+// ProxyPushSender sends notifications through a push proxy server, such as the hosted one run by Mattermost
+type ProxyPushSender struct {
+	url    string
+	client *http.Client
+}
+
+func NewProxyPushSender(url string, client *http.Client) *ProxyPushSender {
+	return &ProxyPushSender{url: url, client: client}
+}
+
+func (s *ProxyPushSender) Send(msg *model.PushNotification) *model.AppError {
+	request, _ := http.NewRequest("POST", s.url+model.API_URL_SUFFIX_V1+"/send_push", strings.NewReader(msg.ToJson()))
+	request.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(request)
+	if err != nil {
+		return newPushError("api.push_notification.proxy.send.app_error", http.StatusServiceUnavailable, err.Error())
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+		ioutil.ReadAll(resp.Body)
+		return newPushError("api.push_notification.proxy.send.app_error", resp.StatusCode, resp.Status)
+	} else if resp.StatusCode >= 400 {
+		ioutil.ReadAll(resp.Body)
+		return newPushError("api.push_notification.proxy.send.app_error", http.StatusBadRequest, resp.Status)
+	}
+
+	// older proxies don't reply with a status
+	response := model.PushResponseFromJson(resp.Body)
+
+	switch response[model.PUSH_STATUS] {
+	case model.PUSH_STATUS_REMOVE:
+		return newPushError("api.push_notification.invalid_device.app_error", http.StatusGone, "")
+	case model.PUSH_STATUS_FAIL:
+		return newPushError("api.push_notification.proxy.send.app_error", http.StatusServiceUnavailable, response[model.PUSH_STATUS_ERROR_MSG])
+	}
+
+	return nil
+}
+
+// DirectPushSender sends notifications straight to Apple's and Google's push services for servers that
+// can't or don't want to use a proxy
+type DirectPushSender struct {
+	APNS *APNSSender
+	FCM  *FCMSender
+}
+
+func (s *DirectPushSender) Send(msg *model.PushNotification) *model.AppError {
+	switch msg.Platform {
+	case model.PUSH_NOTIFY_APPLE:
+		if s.APNS != nil {
+			return s.APNS.Send(msg)
+		}
+	case model.PUSH_NOTIFY_ANDROID:
+		if s.FCM != nil {
+			return s.FCM.Send(msg)
+		}
+	}
+
+	return newPushError("api.push_notification.direct.platform.app_error", http.StatusBadRequest, "platform="+msg.Platform)
+}
@@ -0,0 +1,395 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattermost/platform/model"
+	"github.com/mattermost/platform/store"
+	"github.com/mattermost/platform/utils"
+)
+
+type testPushSender func(msg *model.PushNotification) *model.AppError
+
+func (f testPushSender) Send(msg *model.PushNotification) *model.AppError {
+	return f(msg)
+}
+
+func writeTestPrivateKey(t *testing.T, key crypto.PrivateKey) string {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := ioutil.TempFile("", "push_key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	pem.Encode(file, &pem.Block{Type: "PRIVATE KEY", Bytes: der})
+
+	return file.Name()
+}
+
+// checkTestJWT verifies the signature of a JSON web token and returns its claims
+func checkTestJWT(t *testing.T, token string, verify func(hash []byte, signature []byte) bool) map[string]interface{} {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		t.Fatal("should have been a JWT", token)
+	}
+
+	hash := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+	if !verify(hash[:], signature) {
+		t.Fatal("should have signed the JWT")
+	}
+
+	data, _ := base64.RawURLEncoding.DecodeString(parts[1])
+
+	var claims map[string]interface{}
+	json.Unmarshal(data, &claims)
+
+	return claims
+}
+
+func TestDeliverPushNotification(t *testing.T) {
+	th := Setup().InitBasic()
+
+	maxAttempts := *utils.Cfg.EmailSettings.PushMaxAttempts
+	retryDelay := pushNotificationRetryDelay
+	defer func() {
+		*utils.Cfg.EmailSettings.PushMaxAttempts = maxAttempts
+		pushNotificationRetryDelay = retryDelay
+	}()
+
+	*utils.Cfg.EmailSettings.PushMaxAttempts = 3
+	pushNotificationRetryDelay = time.Millisecond
+
+	session := store.Must(Srv.Store.Session().Save(&model.Session{UserId: th.BasicUser.Id, DeviceId: "android:" + model.NewId(), ExpiresAt: model.GetMillis() + 100000})).(*model.Session)
+
+	msg := model.PushNotification{Type: model.PUSH_TYPE_MESSAGE, Message: "message"}
+	msg.SetDeviceIdAndPlatform(session.DeviceId)
+
+	attempts := 0
+	if !deliverPushNotification(testPushSender(func(*model.PushNotification) *model.AppError {
+		attempts++
+		if attempts < 3 {
+			return newPushError("api.push_notification.proxy.send.app_error", http.StatusServiceUnavailable, "")
+		}
+		return nil
+	}), msg, session) || attempts != 3 {
+		t.Fatal("should have retried until the notification was sent", attempts)
+	}
+
+	attempts = 0
+	if deliverPushNotification(testPushSender(func(*model.PushNotification) *model.AppError {
+		attempts++
+		return newPushError("api.push_notification.proxy.send.app_error", http.StatusBadRequest, "")
+	}), msg, session) || attempts != 1 {
+		t.Fatal("shouldn't have retried a permanent failure", attempts)
+	}
+
+	attempts = 0
+	if deliverPushNotification(testPushSender(func(*model.PushNotification) *model.AppError {
+		attempts++
+		return newPushError("api.push_notification.proxy.send.app_error", http.StatusServiceUnavailable, "")
+	}), msg, session) || attempts != 3 {
+		t.Fatal("should have given up after the maximum number of attempts", attempts)
+	}
+
+	if deliverPushNotification(testPushSender(func(*model.PushNotification) *model.AppError {
+		return newPushError("api.push_notification.invalid_device.app_error", http.StatusGone, "")
+	}), msg, session) {
+		t.Fatal("shouldn't have sent to an invalid device")
+	}
+
+	for _, s := range store.Must(Srv.Store.Session().GetSessionsWithActiveDeviceIds(th.BasicUser.Id)).([]*model.Session) {
+		if s.Id == session.Id {
+			t.Fatal("should have removed the invalid device from the session")
+		}
+	}
+}
+
+func TestProxyPushSender(t *testing.T) {
+	var response string
+	status := http.StatusOK
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != model.API_URL_SUFFIX_V1+"/send_push" {
+			t.Fatal("should have sent to the proxy's push route", r.URL.Path)
+		}
+
+		if msg := model.PushNotificationFromJson(r.Body); msg == nil || msg.DeviceId != "device" {
+			t.Fatal("should have sent the notification", msg)
+		}
+
+		w.WriteHeader(status)
+		w.Write([]byte(response))
+	}))
+	defer server.Close()
+
+	sender := NewProxyPushSender(server.URL, server.Client())
+	msg := &model.PushNotification{DeviceId: "device", Platform: model.PUSH_NOTIFY_APPLE}
+
+	response = model.NewOkPushResponse().ToJson()
+	if err := sender.Send(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	response = ""
+	if err := sender.Send(msg); err != nil {
+		t.Fatal("should have accepted an empty reply from an older proxy", err)
+	}
+
+	response = model.NewRemovePushResponse().ToJson()
+	if err := sender.Send(msg); err == nil || err.StatusCode != http.StatusGone {
+		t.Fatal("should have reported the invalid device", err)
+	}
+
+	response = model.NewErrorPushResponse("failed").ToJson()
+	if err := sender.Send(msg); err == nil || err.StatusCode == http.StatusBadRequest || err.StatusCode == http.StatusGone {
+		t.Fatal("should have reported a temporary failure", err)
+	}
+
+	status = http.StatusNotFound
+	if err := sender.Send(msg); err == nil || err.StatusCode != http.StatusBadRequest {
+		t.Fatal("should have reported a permanent failure", err)
+	}
+}
+
+func TestAPNSSender(t *testing.T) {
+	utils.TranslationsPreInit()
+
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	keyFile := writeTestPrivateKey(t, key)
+	defer os.Remove(keyFile)
+
+	status := http.StatusOK
+	reason := ""
+	var tokens []string
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor != 2 {
+			t.Fatal("should have used HTTP/2")
+		}
+
+		if r.URL.Path != "/3/device/device" || r.Header.Get("apns-topic") != "com.example.app" || r.Header.Get("apns-push-type") != "alert" {
+			t.Fatal("should have sent the notification to the device", r.URL.Path, r.Header)
+		}
+
+		token := strings.TrimPrefix(r.Header.Get("authorization"), "bearer ")
+		claims := checkTestJWT(t, token, func(hash []byte, signature []byte) bool {
+			return len(signature) == 64 && ecdsa.Verify(&key.PublicKey, hash, new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:]))
+		})
+		if claims["iss"] != "TEAMID" {
+			t.Fatal("should have issued the token for the team", claims)
+		}
+		tokens = append(tokens, token)
+
+		var payload struct {
+			Aps struct {
+				Alert string `json:"alert"`
+				Badge int    `json:"badge"`
+			} `json:"aps"`
+			ChannelId string `json:"channel_id"`
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+		if payload.Aps.Alert != "message" || payload.Aps.Badge != 2 || payload.ChannelId != "channel" {
+			t.Fatal("should have sent the payload", payload)
+		}
+
+		w.Header().Set("apns-id", model.NewId())
+		w.WriteHeader(status)
+		if len(reason) > 0 {
+			w.Write([]byte(`{"reason":"` + reason + `"}`))
+		}
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	sender, err := NewAPNSSender(server.URL, keyFile, "KEYID", "TEAMID", "com.example.app", server.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := &model.PushNotification{DeviceId: "device", Platform: model.PUSH_NOTIFY_APPLE, Type: model.PUSH_TYPE_MESSAGE, Message: "message", Badge: 2, ChannelId: "channel"}
+
+	if err := sender.Send(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := sender.Send(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tokens) != 2 || tokens[0] != tokens[1] {
+		t.Fatal("should have reused the provider token")
+	}
+
+	status, reason = http.StatusGone, "Unregistered"
+	if err := sender.Send(msg); err == nil || err.StatusCode != http.StatusGone {
+		t.Fatal("should have reported the invalid device", err)
+	}
+
+	status, reason = http.StatusBadRequest, "BadDeviceToken"
+	if err := sender.Send(msg); err == nil || err.StatusCode != http.StatusGone {
+		t.Fatal("should have reported the invalid device", err)
+	}
+
+	status, reason = http.StatusForbidden, "ExpiredProviderToken"
+	if err := sender.Send(msg); err == nil || err.StatusCode == http.StatusBadRequest || err.StatusCode == http.StatusGone {
+		t.Fatal("should have retried with a new token", err)
+	}
+
+	status, reason = http.StatusServiceUnavailable, "ServiceUnavailable"
+	if err := sender.Send(msg); err == nil || err.StatusCode != http.StatusServiceUnavailable {
+		t.Fatal("should have reported a temporary failure", err)
+	}
+
+	if tokens[len(tokens)-1] == tokens[0] {
+		t.Fatal("should have signed a new token after the old one expired")
+	}
+
+	status, reason = http.StatusBadRequest, "PayloadTooLarge"
+	if err := sender.Send(msg); err == nil || err.StatusCode != http.StatusBadRequest {
+		t.Fatal("should have reported a permanent failure", err)
+	}
+}
+
+func TestFCMSender(t *testing.T) {
+	utils.TranslationsPreInit()
+
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+
+	status := http.StatusOK
+	errorCode := ""
+	tokenRequests := 0
+
+	var server *httptest.Server
+	server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			r.ParseForm()
+
+			claims := checkTestJWT(t, r.Form.Get("assertion"), func(hash []byte, signature []byte) bool {
+				return rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, hash, signature) == nil
+			})
+			if claims["iss"] != "push@example.iam.gserviceaccount.com" || claims["aud"] != server.URL+"/token" {
+				t.Fatal("should have asked for a token for the service account", claims)
+			}
+
+			tokenRequests++
+			w.Write([]byte(`{"access_token":"token` + model.NewId() + `","expires_in":3600}`))
+			return
+		}
+
+		if r.URL.Path != "/v1/projects/project/messages:send" || !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer token") {
+			t.Fatal("should have sent the notification to the project", r.URL.Path)
+		}
+
+		var payload struct {
+			Message struct {
+				Token string            `json:"token"`
+				Data  map[string]string `json:"data"`
+			} `json:"message"`
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+		if payload.Message.Token != "device" || payload.Message.Data["message"] != "message" || payload.Message.Data["badge"] != "2" {
+			t.Fatal("should have sent the payload", payload)
+		}
+
+		w.WriteHeader(status)
+		if status == http.StatusOK {
+			w.Write([]byte(`{"name":"projects/project/messages/1"}`))
+		} else {
+			w.Write([]byte(`{"error":{"code":` + strconv.Itoa(status) + `,"status":"ERROR","details":[{"errorCode":"` + errorCode + `"}]}}`))
+		}
+	}))
+	defer server.Close()
+
+	account, _ := json.Marshal(map[string]string{
+		"type":         "service_account",
+		"project_id":   "project",
+		"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"client_email": "push@example.iam.gserviceaccount.com",
+		"token_uri":    server.URL + "/token",
+	})
+
+	accountFile, _ := ioutil.TempFile("", "service_account")
+	accountFile.Write(account)
+	accountFile.Close()
+	defer os.Remove(accountFile.Name())
+
+	sender, err := NewFCMSender(server.URL, accountFile.Name(), server.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := &model.PushNotification{DeviceId: "device", Platform: model.PUSH_NOTIFY_ANDROID, Type: model.PUSH_TYPE_MESSAGE, Message: "message", Badge: 2}
+
+	if err := sender.Send(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := sender.Send(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	if tokenRequests != 1 {
+		t.Fatal("should have reused the access token", tokenRequests)
+	}
+
+	status, errorCode = http.StatusNotFound, "UNREGISTERED"
+	if err := sender.Send(msg); err == nil || err.StatusCode != http.StatusGone {
+		t.Fatal("should have reported the invalid device", err)
+	}
+
+	status, errorCode = http.StatusUnauthorized, "THIRD_PARTY_AUTH_ERROR"
+	if err := sender.Send(msg); err == nil || err.StatusCode == http.StatusBadRequest || err.StatusCode == http.StatusGone {
+		t.Fatal("should have retried with a new token", err)
+	}
+
+	status, errorCode = http.StatusOK, ""
+	if err := sender.Send(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	if tokenRequests != 2 {
+		t.Fatal("should have asked for a new access token", tokenRequests)
+	}
+
+	status, errorCode = http.StatusBadRequest, "INVALID_ARGUMENT"
+	if err := sender.Send(msg); err == nil || err.StatusCode != http.StatusBadRequest {
+		t.Fatal("should have reported a permanent failure", err)
+	}
+}
+
+func TestDirectPushSender(t *testing.T) {
+	sender := &DirectPushSender{}
+
+	if err := sender.Send(&model.PushNotification{Platform: model.PUSH_NOTIFY_ANDROID}); err == nil || err.StatusCode != http.StatusBadRequest {
+		t.Fatal("should have failed for a platform that isn't configured", err)
+	}
+}
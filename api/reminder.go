@@ -0,0 +1,383 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	l4g "github.com/alecthomas/log4go"
+	"github.com/gorilla/mux"
+
+	"github.com/mattermost/platform/model"
+	"github.com/mattermost/platform/utils"
+)
+
+const (
+	REMINDER_TASK_NAME  = "Message Reminders"
+	REMINDER_LEASE_NAME = "MessageRemindersLease"
+
+	REMINDER_CHECK_INTERVAL = time.Minute
+	REMINDER_BATCH_SIZE     = 100
+	REMINDER_RETENTION      = 7 * 24 * time.Hour
+
+	REMINDER_BOT_USERNAME   = "remindbot"
+	REMINDER_BOT_SYSTEM_KEY = "ReminderBotUserId"
+
+	REMINDER_TIME_FORMAT = "Mon Jan 2, 2006 at 3:04 PM MST"
+)
+
+func InitReminder() {
+	l4g.Debug(utils.T("api.reminder.init.debug"))
+
+	BaseRoutes.Reminders.Handle("/list", ApiUserRequired(getReminders)).Methods("GET")
+	BaseRoutes.NeedReminder.Handle("/snooze", ApiUserRequired(snoozeReminder)).Methods("POST")
+	BaseRoutes.NeedReminder.Handle("/delete", ApiUserRequired(deleteReminder)).Methods("POST")
+	BaseRoutes.NeedPost.Handle("/remind", ApiUserRequired(remindOfPost)).Methods("POST")
+}
+
+// StartReminderJob starts the task that sends reminders when they're due. Only one server in a cluster
+// sends reminders at a time.
+func StartReminderJob() {
+	model.CreateRecurringTask(REMINDER_TASK_NAME, SendDueReminders, REMINDER_CHECK_INTERVAL)
+}
+
+func SendDueReminders() {
+	if !acquireLease(REMINDER_LEASE_NAME, 2*REMINDER_CHECK_INTERVAL) {
+		return
+	}
+
+	now := model.GetMillis()
+
+	for {
+		var reminders []*model.Reminder
+		if result := <-Srv.Store.Reminder().GetDue(now, REMINDER_BATCH_SIZE); result.Err != nil {
+			l4g.Error(utils.T("api.reminder.send_due_reminders.get_due.error"), result.Err)
+			break
+		} else {
+			reminders = result.Data.([]*model.Reminder)
+		}
+
+		for _, reminder := range reminders {
+			// marking the reminder first means it's never sent twice, even if sending it fails
+			if result := <-Srv.Store.Reminder().MarkFired(reminder.Id, now); result.Err != nil {
+				l4g.Error(utils.T("api.reminder.send_due_reminders.mark_fired.error"), reminder.Id, result.Err)
+			} else if result.Data.(bool) {
+				if err := sendReminder(reminder); err != nil {
+					l4g.Error(utils.T("api.reminder.send_due_reminders.send.error"), reminder.Id, err)
+				}
+			}
+		}
+
+		if len(reminders) < REMINDER_BATCH_SIZE {
+			break
+		}
+	}
+
+	if result := <-Srv.Store.Reminder().PermanentDeleteFiredBefore(now - int64(REMINDER_RETENTION/time.Millisecond)); result.Err != nil {
+		l4g.Error(utils.T("api.reminder.send_due_reminders.cleanup.error"), result.Err)
+	}
+}
+
+// sendReminder sends the reminder to its user in a direct message from the reminder bot
+func sendReminder(reminder *model.Reminder) *model.AppError {
+	var user *model.User
+	if result := <-Srv.Store.User().Get(reminder.UserId); result.Err != nil {
+		return result.Err
+	} else {
+		user = result.Data.(*model.User)
+	}
+
+	bot, err := getReminderBot()
+	if err != nil {
+		return err
+	}
+
+	channel, err := CreateDirectChannel(bot.Id, user.Id)
+	if err != nil {
+		return err
+	}
+
+	T := utils.TfuncWithFallback(user.Locale)
+
+	var message string
+	if len(reminder.PostId) == 0 {
+		message = T("api.reminder.send.message", map[string]interface{}{"Message": reminder.Message})
+	} else if len(reminder.Message) == 0 {
+		message = T("api.reminder.send.post", map[string]interface{}{"Link": getReminderPostLink(reminder)})
+	} else {
+		message = T("api.reminder.send.post_message", map[string]interface{}{"Link": getReminderPostLink(reminder), "Message": reminder.Message})
+	}
+
+	post := &model.Post{
+		ChannelId: channel.Id,
+		UserId:    bot.Id,
+		Message:   message,
+	}
+	post.AddProp("reminder_id", reminder.Id)
+
+	c := &Context{
+		Session: model.Session{
+			UserId: bot.Id,
+		},
+		RequestId: model.NewId(),
+		T:         T,
+		Locale:    user.Locale,
+		TeamId:    reminder.TeamId,
+	}
+	c.SetSiteURL(*utils.Cfg.ServiceSettings.SiteURL)
+
+	_, err = CreatePost(c, post, false)
+	return err
+}
+
+// getReminderPostLink returns the permalink to the post that the reminder is about
+func getReminderPostLink(reminder *model.Reminder) string {
+	teamName := reminder.TeamId
+	if result := <-Srv.Store.Team().Get(reminder.TeamId); result.Err == nil {
+		teamName = result.Data.(*model.Team).Name
+	}
+
+	return *utils.Cfg.ServiceSettings.SiteURL + "/" + teamName + "/pl/" + reminder.PostId
+}
+
+// getReminderBot returns the user that reminders are sent from, creating it the first time. It's only called
+// by the server holding the reminder lease so there's no race to create it.
+func getReminderBot() (*model.User, *model.AppError) {
+	if result := <-Srv.Store.System().Get(); result.Err != nil {
+		return nil, result.Err
+	} else if userId := result.Data.(model.StringMap)[REMINDER_BOT_SYSTEM_KEY]; len(userId) > 0 {
+		if result := <-Srv.Store.User().Get(userId); result.Err == nil {
+			return result.Data.(*model.User), nil
+		}
+	}
+
+	username := REMINDER_BOT_USERNAME
+	if result := <-Srv.Store.User().GetByUsername(username); result.Err == nil {
+		// someone already took the name
+		username += "-" + model.NewId()[:8]
+	}
+
+	// the bot has no password and an auth service that nothing logs in with so it can't be used as an account
+	bot := &model.User{
+		Username:      username,
+		Email:         username + "@localhost",
+		EmailVerified: true,
+		AuthService:   model.USER_AUTH_SERVICE_BOT,
+		AuthData:      new(string),
+	}
+	*bot.AuthData = username
+	bot.MakeNonNil()
+
+	if result := <-Srv.Store.User().Save(bot); result.Err != nil {
+		return nil, result.Err
+	} else {
+		bot = result.Data.(*model.User)
+	}
+
+	if result := <-Srv.Store.System().SaveOrUpdate(&model.System{Name: REMINDER_BOT_SYSTEM_KEY, Value: bot.Id}); result.Err != nil {
+		return nil, result.Err
+	}
+
+	return bot, nil
+}
+
+// getUserLocation returns the time zone that the user's reminders are in
+func getUserLocation(userId string) (*time.Location, *model.AppError) {
+	if result := <-Srv.Store.User().Get(userId); result.Err != nil {
+		return nil, result.Err
+	} else {
+		return model.LocationFromNotifyProps(result.Data.(*model.User).NotifyProps), nil
+	}
+}
+
+// ParseUserReminderTime parses when a reminder is due from the start of the text in the user's time zone,
+// returning the time in milliseconds and the rest of the text
+func ParseUserReminderTime(userId string, text string) (int64, string, *model.AppError) {
+	location, err := getUserLocation(userId)
+	if err != nil {
+		return 0, "", err
+	}
+
+	remindAt, rest, err := model.ParseReminderTime(text, time.Now().In(location))
+	if err != nil {
+		err.StatusCode = http.StatusBadRequest
+		return 0, "", err
+	}
+
+	return remindAt.UnixNano() / int64(time.Millisecond), rest, nil
+}
+
+func CreateReminder(reminder *model.Reminder) (*model.Reminder, *model.AppError) {
+	if result := <-Srv.Store.Reminder().Save(reminder); result.Err != nil {
+		result.Err.StatusCode = http.StatusBadRequest
+		return nil, result.Err
+	} else {
+		return result.Data.(*model.Reminder), nil
+	}
+}
+
+func GetRemindersForUser(userId string) ([]*model.Reminder, *model.AppError) {
+	if result := <-Srv.Store.Reminder().GetForUser(userId); result.Err != nil {
+		return nil, result.Err
+	} else {
+		return result.Data.([]*model.Reminder), nil
+	}
+}
+
+// GetReminder returns one of the user's reminders. Other users' reminders aren't found.
+func GetReminder(id string, userId string) (*model.Reminder, *model.AppError) {
+	if result := <-Srv.Store.Reminder().Get(id); result.Err != nil {
+		result.Err.StatusCode = http.StatusNotFound
+		return nil, result.Err
+	} else if reminder := result.Data.(*model.Reminder); reminder.UserId != userId {
+		err := model.NewLocAppError("GetReminder", "api.reminder.get.not_found.app_error", nil, "id="+id+", user_id="+userId)
+		err.StatusCode = http.StatusNotFound
+		return nil, err
+	} else {
+		return reminder, nil
+	}
+}
+
+func SnoozeReminder(reminder *model.Reminder, remindAt int64) (*model.Reminder, *model.AppError) {
+	reminder.Snooze(remindAt)
+
+	if result := <-Srv.Store.Reminder().Update(reminder); result.Err != nil {
+		return nil, result.Err
+	} else {
+		return result.Data.(*model.Reminder), nil
+	}
+}
+
+func DeleteReminder(id string) *model.AppError {
+	if result := <-Srv.Store.Reminder().Delete(id); result.Err != nil {
+		return result.Err
+	}
+
+	return nil
+}
+
+// getRequestedReminderTime returns when a reminder is due from the request, given either as remind_at in
+// milliseconds or as when, such as "in 2 hours" or "tomorrow 9am", in the user's time zone
+func getRequestedReminderTime(c *Context, props map[string]string, where string) int64 {
+	if len(props["remind_at"]) > 0 {
+		if remindAt, err := strconv.ParseInt(props["remind_at"], 10, 64); err != nil || remindAt <= model.GetMillis() {
+			c.SetInvalidParam(where, "remind_at")
+			return 0
+		} else {
+			return remindAt
+		}
+	}
+
+	remindAt, rest, err := ParseUserReminderTime(c.Session.UserId, props["when"])
+	if err != nil {
+		c.Err = err
+		return 0
+	} else if len(rest) > 0 {
+		c.SetInvalidParam(where, "when")
+		return 0
+	}
+
+	return remindAt
+}
+
+func getReminders(c *Context, w http.ResponseWriter, r *http.Request) {
+	if reminders, err := GetRemindersForUser(c.Session.UserId); err != nil {
+		c.Err = err
+		return
+	} else {
+		w.Write([]byte(model.RemindersToJson(reminders)))
+	}
+}
+
+func remindOfPost(c *Context, w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+
+	channelId := params["channel_id"]
+	if len(channelId) != 26 {
+		c.SetInvalidParam("remindOfPost", "channelId")
+		return
+	}
+
+	postId := params["post_id"]
+	if len(postId) != 26 {
+		c.SetInvalidParam("remindOfPost", "postId")
+		return
+	}
+
+	props := model.MapFromJson(r.Body)
+
+	pchan := Srv.Store.Post().Get(postId)
+
+	if !HasPermissionToChannelContext(c, channelId, model.PERMISSION_READ_CHANNEL) {
+		return
+	}
+
+	if result := <-pchan; result.Err != nil {
+		c.Err = result.Err
+		return
+	} else if post, ok := result.Data.(*model.PostList).Posts[postId]; !ok || post.ChannelId != channelId {
+		c.Err = model.NewLocAppError("remindOfPost", "api.post.get_post.permissions.app_error", nil, "")
+		c.Err.StatusCode = http.StatusForbidden
+		return
+	}
+
+	remindAt := getRequestedReminderTime(c, props, "remindOfPost")
+	if c.Err != nil {
+		return
+	}
+
+	reminder := &model.Reminder{
+		UserId:   c.Session.UserId,
+		TeamId:   c.TeamId,
+		PostId:   postId,
+		Message:  props["message"],
+		RemindAt: remindAt,
+	}
+
+	if rreminder, err := CreateReminder(reminder); err != nil {
+		c.Err = err
+		return
+	} else {
+		w.Write([]byte(rreminder.ToJson()))
+	}
+}
+
+func snoozeReminder(c *Context, w http.ResponseWriter, r *http.Request) {
+	props := model.MapFromJson(r.Body)
+
+	reminder, err := GetReminder(mux.Vars(r)["reminder_id"], c.Session.UserId)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	remindAt := getRequestedReminderTime(c, props, "snoozeReminder")
+	if c.Err != nil {
+		return
+	}
+
+	if rreminder, err := SnoozeReminder(reminder, remindAt); err != nil {
+		c.Err = err
+		return
+	} else {
+		w.Write([]byte(rreminder.ToJson()))
+	}
+}
+
+func deleteReminder(c *Context, w http.ResponseWriter, r *http.Request) {
+	reminder, err := GetReminder(mux.Vars(r)["reminder_id"], c.Session.UserId)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	if err := DeleteReminder(reminder.Id); err != nil {
+		c.Err = err
+		return
+	}
+
+	ReturnStatusOK(w)
+}
@@ -0,0 +1,129 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/platform/model"
+	"github.com/mattermost/platform/store"
+)
+
+func TestReminders(t *testing.T) {
+	th := Setup().InitBasic()
+	Client := th.BasicClient
+	channel := th.BasicChannel
+
+	post := Client.Must(Client.CreatePost(&model.Post{ChannelId: channel.Id, Message: "don't forget"})).Data.(*model.Post)
+
+	reminder := Client.Must(Client.RemindOfPost(channel.Id, post.Id, "in 2 hours", "follow up")).Data.(*model.Reminder)
+	if reminder.PostId != post.Id || reminder.Message != "follow up" || reminder.UserId != th.BasicUser.Id || reminder.TeamId != th.BasicTeam.Id {
+		t.Fatal("should have created the reminder", reminder)
+	}
+
+	if _, err := Client.RemindOfPost(channel.Id, post.Id, "whenever", ""); err == nil {
+		t.Fatal("should have failed with an unknown time")
+	}
+
+	if _, err := Client.RemindOfPost(channel.Id, post.Id, "1000", ""); err == nil {
+		t.Fatal("should have failed with a time in the past")
+	}
+
+	if reminders := Client.Must(Client.GetReminders()).Data.([]*model.Reminder); len(reminders) != 1 || reminders[0].Id != reminder.Id {
+		t.Fatal("should have returned the reminder", reminders)
+	}
+
+	remindAt := model.GetMillis() + 60*60*1000
+	if snoozed := Client.Must(Client.SnoozeReminder(reminder.Id, strconv.FormatInt(remindAt, 10))).Data.(*model.Reminder); snoozed.RemindAt != remindAt {
+		t.Fatal("should have snoozed the reminder", snoozed)
+	}
+
+	th.LoginBasic2()
+
+	if reminders := Client.Must(Client.GetReminders()).Data.([]*model.Reminder); len(reminders) != 0 {
+		t.Fatal("shouldn't have returned another user's reminders", reminders)
+	}
+
+	if _, err := Client.SnoozeReminder(reminder.Id, "tomorrow"); err == nil {
+		t.Fatal("shouldn't have snoozed another user's reminder")
+	}
+
+	if _, err := Client.DeleteReminder(reminder.Id); err == nil {
+		t.Fatal("shouldn't have deleted another user's reminder")
+	}
+
+	otherChannel := th.CreateChannel(th.BasicClient, th.BasicTeam)
+	if _, err := Client.RemindOfPost(otherChannel.Id, post.Id, "tomorrow", ""); err == nil {
+		t.Fatal("shouldn't have been reminded of a post in another channel")
+	}
+
+	th.LoginBasic()
+
+	Client.Must(Client.DeleteReminder(reminder.Id))
+
+	if reminders := Client.Must(Client.GetReminders()).Data.([]*model.Reminder); len(reminders) != 0 {
+		t.Fatal("should have deleted the reminder", reminders)
+	}
+}
+
+func TestSendDueReminders(t *testing.T) {
+	th := Setup().InitBasic()
+
+	post := th.CreatePost(th.BasicClient, th.BasicChannel)
+
+	reminder := store.Must(Srv.Store.Reminder().Save(&model.Reminder{UserId: th.BasicUser.Id, TeamId: th.BasicTeam.Id, PostId: post.Id, Message: "check this", RemindAt: model.GetMillis() - 1000})).(*model.Reminder)
+
+	SendDueReminders()
+
+	if rreminder := store.Must(Srv.Store.Reminder().Get(reminder.Id)).(*model.Reminder); rreminder.FiredAt == 0 {
+		t.Fatal("should have sent the reminder")
+	}
+
+	bot, err := getReminderBot()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bot.IsBot() || len(bot.Password) != 0 {
+		t.Fatal("should have created a bot that can't log in", bot)
+	}
+
+	if _, err := th.BasicClient.Login(bot.Email, bot.Password); err == nil {
+		t.Fatal("shouldn't have been able to log in as the bot")
+	}
+
+	if profiles := store.Must(Srv.Store.User().GetAllProfiles(0, 1000)).(map[string]*model.User); profiles[bot.Id] != nil {
+		t.Fatal("shouldn't have listed the bot with other users")
+	}
+
+	channel, err := CreateDirectChannel(bot.Id, th.BasicUser.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	posts := store.Must(Srv.Store.Post().GetPosts(channel.Id, 0, 10, false)).(*model.PostList)
+
+	found := false
+	for _, p := range posts.Posts {
+		if p.UserId == bot.Id && p.Props["reminder_id"] == reminder.Id {
+			found = true
+
+			if !strings.Contains(p.Message, "/pl/"+post.Id) || !strings.Contains(p.Message, "check this") {
+				t.Fatal("should have linked the post", p.Message)
+			}
+		}
+	}
+
+	if !found {
+		t.Fatal("should have sent the reminder in a direct message", posts)
+	}
+
+	SendDueReminders()
+
+	if count := len(store.Must(Srv.Store.Post().GetPosts(channel.Id, 0, 10, false)).(*model.PostList).Posts); count != len(posts.Posts) {
+		t.Fatal("shouldn't have sent the reminder twice")
+	}
+}
@@ -0,0 +1,287 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api
+
+import (
+	"net/http"
+
+	l4g "github.com/alecthomas/log4go"
+	"github.com/gorilla/mux"
+	"github.com/mattermost/platform/einterfaces"
+	"github.com/mattermost/platform/model"
+	"github.com/mattermost/platform/store"
+	"github.com/mattermost/platform/utils"
+)
+
+func InitRole() {
+	l4g.Debug(utils.T("api.role.init.debug"))
+
+	BaseRoutes.Roles.Handle("/list", ApiAdminSystemRequired(getAllRoles)).Methods("GET")
+	BaseRoutes.NeedRole.Handle("/get", ApiAdminSystemRequired(getRole)).Methods("GET")
+	BaseRoutes.NeedRole.Handle("/update", ApiAdminSystemRequired(updateRole)).Methods("POST")
+	BaseRoutes.NeedRole.Handle("/reset", ApiAdminSystemRequired(resetRole)).Methods("POST")
+
+	BaseRoutes.NeedTeam.Handle("/scheme/get", ApiAdminSystemRequired(getTeamScheme)).Methods("GET")
+	BaseRoutes.NeedTeam.Handle("/scheme/update", ApiAdminSystemRequired(updateTeamScheme)).Methods("POST")
+	BaseRoutes.NeedTeam.Handle("/scheme/delete", ApiAdminSystemRequired(deleteTeamScheme)).Methods("POST")
+}
+
+func getAllRoles(c *Context, w http.ResponseWriter, r *http.Request) {
+	roles := make([]*model.Role, 0, len(model.BuiltInRoles))
+	for roleId := range model.BuiltInRoles {
+		roles = append(roles, GetRole(roleId))
+	}
+
+	w.Write([]byte(model.RoleListToJson(roles)))
+}
+
+func getRole(c *Context, w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	roleId := params["role_id"]
+
+	if role := GetRole(roleId); role == nil {
+		c.Err = model.NewLocAppError("getRole", "api.role.get_role.not_found.app_error", nil, "role_id="+roleId)
+		c.Err.StatusCode = http.StatusNotFound
+		return
+	} else {
+		w.Write([]byte(role.ToJson()))
+	}
+}
+
+func updateRole(c *Context, w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	roleId := params["role_id"]
+
+	role := model.RoleFromJson(r.Body)
+	if role == nil {
+		c.SetInvalidParam("updateRole", "role")
+		return
+	}
+
+	oldRole := GetRole(roleId)
+	if oldRole == nil {
+		c.Err = model.NewLocAppError("updateRole", "api.role.get_role.not_found.app_error", nil, "role_id="+roleId)
+		c.Err.StatusCode = http.StatusNotFound
+		return
+	}
+
+	// Only the permissions of a role can be changed
+	updatedRole := &model.Role{
+		Id:          oldRole.Id,
+		Name:        oldRole.Name,
+		Description: oldRole.Description,
+		Permissions: role.Permissions,
+		CreateAt:    oldRole.CreateAt,
+	}
+
+	if rrole, err := SaveRole(updatedRole); err != nil {
+		c.Err = err
+		return
+	} else {
+		c.LogAudit("role_id=" + roleId + " permissions=" + model.ArrayToJson(rrole.Permissions))
+		w.Write([]byte(rrole.ToJson()))
+	}
+}
+
+func resetRole(c *Context, w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	roleId := params["role_id"]
+
+	if _, ok := model.BuiltInRoles[roleId]; !ok {
+		c.Err = model.NewLocAppError("resetRole", "api.role.get_role.not_found.app_error", nil, "role_id="+roleId)
+		c.Err.StatusCode = http.StatusNotFound
+		return
+	}
+
+	if result := <-Srv.Store.Role().Delete(roleId); result.Err != nil {
+		c.Err = result.Err
+		return
+	}
+
+	InvalidateCacheForRoles()
+
+	c.LogAudit("role_id=" + roleId)
+
+	w.Write([]byte(GetRole(roleId).ToJson()))
+}
+
+func getTeamScheme(c *Context, w http.ResponseWriter, r *http.Request) {
+	if scheme := GetSchemeForTeam(c.TeamId); scheme == nil {
+		c.Err = model.NewLocAppError("getTeamScheme", "api.role.get_team_scheme.not_found.app_error", nil, "team_id="+c.TeamId)
+		c.Err.StatusCode = http.StatusNotFound
+		return
+	} else {
+		w.Write([]byte(scheme.ToJson()))
+	}
+}
+
+func updateTeamScheme(c *Context, w http.ResponseWriter, r *http.Request) {
+	scheme := model.SchemeFromJson(r.Body)
+	if scheme == nil {
+		c.SetInvalidParam("updateTeamScheme", "scheme")
+		return
+	}
+
+	scheme.TeamId = c.TeamId
+	scheme.CreateAt = 0
+	if oldScheme := GetSchemeForTeam(c.TeamId); oldScheme != nil {
+		scheme.CreateAt = oldScheme.CreateAt
+	}
+
+	if result := <-Srv.Store.Scheme().Save(scheme); result.Err != nil {
+		c.Err = result.Err
+		c.Err.StatusCode = http.StatusBadRequest
+		return
+	} else {
+		InvalidateCacheForRoles()
+
+		c.LogAudit("roles=" + model.MapToJson(scheme.Roles))
+		w.Write([]byte(result.Data.(*model.Scheme).ToJson()))
+	}
+}
+
+func deleteTeamScheme(c *Context, w http.ResponseWriter, r *http.Request) {
+	if result := <-Srv.Store.Scheme().Delete(c.TeamId); result.Err != nil {
+		c.Err = result.Err
+		return
+	}
+
+	InvalidateCacheForRoles()
+
+	c.LogAudit("")
+	ReturnStatusOK(w)
+}
+
+// GetRole returns the role as it has been saved to the database, falling back to the built in
+// role when it has never been saved. Returns nil if the role doesn't exist.
+func GetRole(roleId string) *model.Role {
+	if result := <-Srv.Store.Role().GetAll(true); result.Err != nil {
+		l4g.Error(utils.T("api.role.get_role.error"), result.Err.Error())
+	} else {
+		for _, role := range result.Data.([]*model.Role) {
+			if role.Id == roleId {
+				return role
+			}
+		}
+	}
+
+	return model.BuiltInRoles[roleId]
+}
+
+// GetSchemeForTeam returns the permission scheme of a team or nil if the team uses the system wide roles.
+func GetSchemeForTeam(teamId string) *model.Scheme {
+	if result := <-Srv.Store.Scheme().GetAll(true); result.Err != nil {
+		l4g.Error(utils.T("api.role.get_team_scheme.error"), result.Err.Error())
+	} else if scheme, ok := result.Data.(map[string]*model.Scheme)[teamId]; ok {
+		return scheme
+	}
+
+	return nil
+}
+
+func SaveRole(role *model.Role) (*model.Role, *model.AppError) {
+	if result := <-Srv.Store.Role().Save(role); result.Err != nil {
+		result.Err.StatusCode = http.StatusBadRequest
+		return nil, result.Err
+	} else {
+		InvalidateCacheForRoles()
+		return result.Data.(*model.Role), nil
+	}
+}
+
+// ResetRolesFromConfig recalculates the built in roles from the config and removes any saved
+// roles so the recalculated ones are used.
+func ResetRolesFromConfig() *model.AppError {
+	utils.SetDefaultRolesBasedOnConfig()
+
+	for roleId := range model.BuiltInRoles {
+		if result := <-Srv.Store.Role().Delete(roleId); result.Err != nil {
+			return result.Err
+		}
+	}
+
+	InvalidateCacheForRoles()
+
+	return nil
+}
+
+// getBuiltInRolePermissions returns a copy of the permissions of every built in role
+func getBuiltInRolePermissions() map[string][]string {
+	permissions := make(map[string][]string, len(model.BuiltInRoles))
+	for roleId, role := range model.BuiltInRoles {
+		permissions[roleId] = append([]string{}, role.Permissions...)
+	}
+
+	return permissions
+}
+
+// UpdateSavedRolesForConfig makes the same changes to the saved roles that reloading the config made to the built in
+// roles, so that a change to the Restrict* settings still takes effect without losing any other edits to those roles.
+// The permissions of the built in roles before the config was reloaded are passed in.
+func UpdateSavedRolesForConfig(oldPermissions map[string][]string) *model.AppError {
+	added := make(map[string][]string)
+	removed := make(map[string][]string)
+
+	for roleId, role := range model.BuiltInRoles {
+		if permissions := subtractPermissions(role.Permissions, oldPermissions[roleId]); len(permissions) > 0 {
+			added[roleId] = permissions
+		}
+
+		if permissions := subtractPermissions(oldPermissions[roleId], role.Permissions); len(permissions) > 0 {
+			removed[roleId] = permissions
+		}
+	}
+
+	if len(added) == 0 && len(removed) == 0 {
+		return nil
+	}
+
+	result := <-Srv.Store.Role().GetAll(false)
+	if result.Err != nil {
+		return result.Err
+	}
+
+	for _, role := range result.Data.([]*model.Role) {
+		if len(added[role.Id]) == 0 && len(removed[role.Id]) == 0 {
+			continue
+		}
+
+		role.Permissions = append(subtractPermissions(role.Permissions, removed[role.Id]), subtractPermissions(added[role.Id], role.Permissions)...)
+
+		if result := <-Srv.Store.Role().Save(role); result.Err != nil {
+			return result.Err
+		}
+	}
+
+	InvalidateCacheForRoles()
+
+	return nil
+}
+
+// subtractPermissions returns the permissions that are in the first list but not the second
+func subtractPermissions(permissions []string, toSubtract []string) []string {
+	subtracted := &model.Role{Permissions: toSubtract}
+
+	result := []string{}
+	for _, permission := range permissions {
+		if !subtracted.HasPermission(permission) {
+			result = append(result, permission)
+		}
+	}
+
+	return result
+}
+
+func InvalidateCacheForRoles() {
+	InvalidateCacheForRolesSkipClusterSend()
+
+	if cluster := einterfaces.GetClusterInterface(); cluster != nil {
+		cluster.InvalidateCacheForRoles()
+	}
+}
+
+func InvalidateCacheForRolesSkipClusterSend() {
+	store.ClearRoleCaches()
+	store.ClearSchemeCaches()
+}
@@ -0,0 +1,159 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mattermost/platform/model"
+	"github.com/mattermost/platform/utils"
+)
+
+func TestGetAllRoles(t *testing.T) {
+	th := Setup().InitSystemAdmin().InitBasic()
+
+	if _, err := th.BasicClient.GetAllRoles(); err == nil {
+		t.Fatal("should have errored, not a system admin")
+	}
+
+	if roles, err := th.SystemAdminClient.GetAllRoles(); err != nil {
+		t.Fatal(err)
+	} else if len(roles) != len(model.BuiltInRoles) {
+		t.Fatal("should have returned every role")
+	}
+
+	if role, err := th.SystemAdminClient.GetRole(model.ROLE_TEAM_USER.Id); err != nil {
+		t.Fatal(err)
+	} else if role.Id != model.ROLE_TEAM_USER.Id {
+		t.Fatal("should have returned the team user role")
+	}
+
+	if _, err := th.SystemAdminClient.GetRole("junk"); err == nil {
+		t.Fatal("should have errored, role doesn't exist")
+	}
+}
+
+func TestUpdateRole(t *testing.T) {
+	th := Setup().InitSystemAdmin().InitBasic()
+	defer ResetRolesFromConfig()
+
+	role := &model.Role{Id: model.ROLE_CHANNEL_USER.Id, Permissions: []string{model.PERMISSION_READ_CHANNEL.Id}}
+
+	if _, err := th.BasicClient.UpdateRole(role); err == nil {
+		t.Fatal("should have errored, not a system admin")
+	}
+
+	if _, err := th.SystemAdminClient.UpdateRole(&model.Role{Id: model.ROLE_CHANNEL_USER.Id, Permissions: []string{"junk"}}); err == nil {
+		t.Fatal("should have errored, unknown permission")
+	}
+
+	if rrole, err := th.SystemAdminClient.UpdateRole(role); err != nil {
+		t.Fatal(err)
+	} else if len(rrole.Permissions) != 1 || rrole.Name != model.ROLE_CHANNEL_USER.Name {
+		t.Fatal("only the permissions should have been updated")
+	}
+
+	if CheckIfRolesGrantPermission([]string{model.ROLE_CHANNEL_USER.Id}, model.PERMISSION_CREATE_POST.Id) {
+		t.Fatal("saved role should no longer grant the permission")
+	}
+
+	post := &model.Post{ChannelId: th.BasicChannel.Id, Message: "message"}
+	if _, err := th.BasicClient.CreatePost(post); err == nil {
+		t.Fatal("should have errored, channel users can no longer post")
+	}
+
+	if rrole, err := th.SystemAdminClient.ResetRole(model.ROLE_CHANNEL_USER.Id); err != nil {
+		t.Fatal(err)
+	} else if !rrole.HasPermission(model.PERMISSION_CREATE_POST.Id) {
+		t.Fatal("role should have its default permissions again")
+	}
+
+	if _, err := th.BasicClient.CreatePost(post); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRoleSettingsChangedInConfig(t *testing.T) {
+	th := Setup().InitSystemAdmin().InitBasic()
+	defer ResetRolesFromConfig()
+
+	cfg := model.ConfigFromJson(strings.NewReader(utils.Cfg.ToJson()))
+	restrictPublicChannel := *cfg.TeamSettings.RestrictPublicChannelCreation
+	defer func() {
+		*cfg.TeamSettings.RestrictPublicChannelCreation = restrictPublicChannel
+		th.SystemAdminClient.SaveConfig(cfg)
+	}()
+
+	role := &model.Role{Id: model.ROLE_TEAM_USER.Id, Permissions: []string{model.PERMISSION_LIST_TEAM_CHANNELS.Id}}
+	if _, err := th.SystemAdminClient.UpdateRole(role); err != nil {
+		t.Fatal(err)
+	}
+
+	if restrictPublicChannel == model.PERMISSIONS_ALL {
+		*cfg.TeamSettings.RestrictPublicChannelCreation = model.PERMISSIONS_TEAM_ADMIN
+	} else {
+		*cfg.TeamSettings.RestrictPublicChannelCreation = model.PERMISSIONS_ALL
+	}
+
+	if _, err := th.SystemAdminClient.SaveConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	if rrole := GetRole(model.ROLE_TEAM_USER.Id); !rrole.HasPermission(model.PERMISSION_LIST_TEAM_CHANNELS.Id) || rrole.HasPermission(model.PERMISSION_JOIN_PUBLIC_CHANNELS.Id) {
+		t.Fatal("should have kept the other edits to the role")
+	} else if rrole.HasPermission(model.PERMISSION_CREATE_PUBLIC_CHANNEL.Id) != (*cfg.TeamSettings.RestrictPublicChannelCreation == model.PERMISSIONS_ALL) {
+		t.Fatal("role should match the new config")
+	}
+}
+
+func TestTeamScheme(t *testing.T) {
+	th := Setup().InitSystemAdmin().InitBasic()
+	th.SystemAdminClient.SetTeamId(th.BasicTeam.Id)
+	defer th.SystemAdminClient.DeleteTeamScheme()
+
+	scheme := &model.Scheme{
+		Name:  "Read only",
+		Roles: map[string]string{model.ROLE_CHANNEL_USER.Id: model.PERMISSION_READ_CHANNEL.Id},
+	}
+
+	if _, err := th.BasicClient.UpdateTeamScheme(scheme); err == nil {
+		t.Fatal("should have errored, not a system admin")
+	}
+
+	if _, err := th.SystemAdminClient.GetTeamScheme(); err == nil {
+		t.Fatal("should have errored, team has no scheme")
+	}
+
+	if _, err := th.SystemAdminClient.UpdateTeamScheme(&model.Scheme{Roles: map[string]string{model.ROLE_SYSTEM_USER.Id: ""}}); err == nil {
+		t.Fatal("should have errored, system roles can't be overridden")
+	}
+
+	if rscheme, err := th.SystemAdminClient.UpdateTeamScheme(scheme); err != nil {
+		t.Fatal(err)
+	} else if rscheme.TeamId != th.BasicTeam.Id {
+		t.Fatal("scheme should belong to the team")
+	}
+
+	if !CheckIfRolesGrantPermission([]string{model.ROLE_CHANNEL_USER.Id}, model.PERMISSION_CREATE_POST.Id) {
+		t.Fatal("scheme shouldn't affect other teams")
+	}
+
+	if CheckIfRolesGrantPermissionInTeam(th.BasicTeam.Id, []string{model.ROLE_CHANNEL_USER.Id}, model.PERMISSION_CREATE_POST.Id) {
+		t.Fatal("scheme should have removed the permission")
+	}
+
+	post := &model.Post{ChannelId: th.BasicChannel.Id, Message: "message"}
+	if _, err := th.BasicClient.CreatePost(post); err == nil {
+		t.Fatal("should have errored, channel users can't post in this team")
+	}
+
+	if err := th.SystemAdminClient.DeleteTeamScheme(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := th.BasicClient.CreatePost(post); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,969 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	l4g "github.com/alecthomas/log4go"
+	"github.com/gorilla/mux"
+
+	"github.com/mattermost/platform/model"
+	"github.com/mattermost/platform/store"
+	"github.com/mattermost/platform/utils"
+)
+
+var scimErrorTypes = map[string]string{
+	"api.scim.invalid_filter.app_error":              model.SCIM_ERROR_TYPE_INVALID_FILTER,
+	"api.scim.unsupported_filter.app_error":          model.SCIM_ERROR_TYPE_INVALID_FILTER,
+	"api.scim.invalid_body.app_error":                model.SCIM_ERROR_TYPE_INVALID_VALUE,
+	"api.scim.invalid_patch.app_error":               model.SCIM_ERROR_TYPE_INVALID_VALUE,
+	"api.scim.user_invalid.app_error":                model.SCIM_ERROR_TYPE_INVALID_VALUE,
+	"api.scim.group_invalid.app_error":               model.SCIM_ERROR_TYPE_INVALID_VALUE,
+	"api.scim.group_team_not_found.app_error":        model.SCIM_ERROR_TYPE_INVALID_VALUE,
+	"api.scim.member_not_found.app_error":            model.SCIM_ERROR_TYPE_INVALID_VALUE,
+	"api.scim.group_exists.app_error":                model.SCIM_ERROR_TYPE_UNIQUENESS,
+	"store.sql_user.save.email_exists.app_error":     model.SCIM_ERROR_TYPE_UNIQUENESS,
+	"store.sql_user.save.username_exists.app_error":  model.SCIM_ERROR_TYPE_UNIQUENESS,
+	"store.sql_user.update.email_taken.app_error":    model.SCIM_ERROR_TYPE_UNIQUENESS,
+	"store.sql_user.update.username_taken.app_error": model.SCIM_ERROR_TYPE_UNIQUENESS,
+	"api.scim.delete_group.app_error":                model.SCIM_ERROR_TYPE_MUTABILITY,
+}
+
+type scimHandler struct {
+	handleFunc func(*Context, http.ResponseWriter, *http.Request)
+}
+
+// ScimHandler serves a SCIM request authenticated with the bearer token from the SCIM settings and
+// reports errors in the SCIM format instead of as an AppError
+func ScimHandler(h func(*Context, http.ResponseWriter, *http.Request)) http.Handler {
+	return &scimHandler{h}
+}
+
+func (h scimHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	l4g.Debug("%v", r.URL.Path)
+
+	c := &Context{}
+	c.T, c.Locale = utils.GetTranslationsAndLocale(w, r)
+	c.RequestId = model.NewId()
+	c.IpAddress = GetIpAddress(r)
+	c.Path = r.URL.Path
+
+	if *utils.Cfg.ServiceSettings.SiteURL != "" {
+		c.SetSiteURL(*utils.Cfg.ServiceSettings.SiteURL)
+	} else {
+		c.SetSiteURL(GetProtocol(r) + "://" + r.Host)
+	}
+
+	w.Header().Set(model.HEADER_REQUEST_ID, c.RequestId)
+
+	if !*utils.Cfg.ScimSettings.Enable {
+		c.Err = model.NewLocAppError("ScimHandler", "api.scim.disabled.app_error", nil, "")
+		c.Err.StatusCode = http.StatusNotImplemented
+	} else if !isValidScimToken(r) {
+		c.Err = model.NewLocAppError("ScimHandler", "api.scim.invalid_token.app_error", nil, "")
+		c.Err.StatusCode = http.StatusUnauthorized
+	}
+
+	if c.Err == nil {
+		h.handleFunc(c, w, r)
+	}
+
+	if c.Err != nil {
+		c.Err.Translate(c.T)
+		c.Err.RequestId = c.RequestId
+		c.LogError(c.Err)
+
+		if c.Err.StatusCode == http.StatusUnauthorized {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+		}
+
+		writeScimResponse(w, c.Err.StatusCode, model.NewScimError(c.Err.StatusCode, scimErrorTypes[c.Err.Id], c.Err.Message))
+	}
+}
+
+func isValidScimToken(r *http.Request) bool {
+	token := *utils.Cfg.ScimSettings.Token
+
+	authHeader := r.Header.Get(model.HEADER_AUTH)
+	if len(token) == 0 || len(authHeader) <= 7 || strings.ToUpper(authHeader[0:6]) != model.HEADER_BEARER {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(authHeader[7:]), []byte(token)) == 1
+}
+
+func writeScimResponse(w http.ResponseWriter, status int, resource interface{}) {
+	w.Header().Set("Content-Type", model.SCIM_CONTENT_TYPE)
+	w.WriteHeader(status)
+
+	if b, err := json.Marshal(resource); err == nil {
+		w.Write(b)
+	}
+}
+
+func InitScim() {
+	l4g.Debug(utils.T("api.scim.init.debug"))
+
+	BaseRoutes.Scim.Handle("/ServiceProviderConfig", ScimHandler(getScimServiceProviderConfig)).Methods("GET")
+
+	BaseRoutes.Scim.Handle("/Users", ScimHandler(getScimUsers)).Methods("GET")
+	BaseRoutes.Scim.Handle("/Users", ScimHandler(createScimUser)).Methods("POST")
+	BaseRoutes.Scim.Handle("/Users/{user_id:[A-Za-z0-9]+}", ScimHandler(getScimUser)).Methods("GET")
+	BaseRoutes.Scim.Handle("/Users/{user_id:[A-Za-z0-9]+}", ScimHandler(replaceScimUser)).Methods("PUT")
+	BaseRoutes.Scim.Handle("/Users/{user_id:[A-Za-z0-9]+}", ScimHandler(patchScimUser)).Methods("PATCH")
+	BaseRoutes.Scim.Handle("/Users/{user_id:[A-Za-z0-9]+}", ScimHandler(deleteScimUser)).Methods("DELETE")
+
+	BaseRoutes.Scim.Handle("/Groups", ScimHandler(getScimGroups)).Methods("GET")
+	BaseRoutes.Scim.Handle("/Groups", ScimHandler(createScimGroup)).Methods("POST")
+	BaseRoutes.Scim.Handle("/Groups/{group_id:[A-Za-z0-9]+}", ScimHandler(getScimGroup)).Methods("GET")
+	BaseRoutes.Scim.Handle("/Groups/{group_id:[A-Za-z0-9]+}", ScimHandler(replaceScimGroup)).Methods("PUT")
+	BaseRoutes.Scim.Handle("/Groups/{group_id:[A-Za-z0-9]+}", ScimHandler(patchScimGroup)).Methods("PATCH")
+	BaseRoutes.Scim.Handle("/Groups/{group_id:[A-Za-z0-9]+}", ScimHandler(deleteScimGroup)).Methods("DELETE")
+
+	// keep unknown SCIM routes from falling through to the web app
+	BaseRoutes.Scim.Handle("/{anything:.*}", ScimHandler(scimNotFound))
+}
+
+func scimNotFound(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.Err = model.NewLocAppError("scimNotFound", "api.scim.not_found.app_error", nil, "path="+r.URL.Path)
+	c.Err.StatusCode = http.StatusNotFound
+}
+
+func getScimServiceProviderConfig(c *Context, w http.ResponseWriter, r *http.Request) {
+	writeScimResponse(w, http.StatusOK, map[string]interface{}{
+		"schemas":        []string{model.SCIM_SCHEMA_SERVICE_PROVIDER_CONFIG},
+		"patch":          map[string]interface{}{"supported": true},
+		"bulk":           map[string]interface{}{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         map[string]interface{}{"supported": true, "maxResults": model.SCIM_MAX_COUNT},
+		"changePassword": map[string]interface{}{"supported": true},
+		"sort":           map[string]interface{}{"supported": false},
+		"etag":           map[string]interface{}{"supported": false},
+		"authenticationSchemes": []map[string]interface{}{{
+			"type":        "oauthbearertoken",
+			"name":        "OAuth Bearer Token",
+			"description": "Authentication with the token from the SCIM settings",
+			"primary":     true,
+		}},
+	})
+}
+
+// getScimListParams returns the 1-based start index, the page size and the filters of a list request
+func getScimListParams(c *Context, r *http.Request) (int, int, []*model.ScimFilter) {
+	query := r.URL.Query()
+
+	startIndex, err := strconv.Atoi(query.Get("startIndex"))
+	if err != nil || startIndex < 1 {
+		startIndex = 1
+	}
+
+	count, err := strconv.Atoi(query.Get("count"))
+	if err != nil {
+		count = model.SCIM_DEFAULT_COUNT
+	} else if count < 0 {
+		count = 0
+	} else if count > model.SCIM_MAX_COUNT {
+		count = model.SCIM_MAX_COUNT
+	}
+
+	var filters []*model.ScimFilter
+	if filter := query.Get("filter"); len(filter) > 0 {
+		if filters, err = model.ParseScimFilter(filter); err != nil {
+			c.Err = model.NewLocAppError("getScimListParams", "api.scim.invalid_filter.app_error", nil, err.Error())
+			c.Err.StatusCode = http.StatusBadRequest
+			return 0, 0, nil
+		}
+	}
+
+	return startIndex, count, filters
+}
+
+func scimPage(total int, startIndex int, count int) (int, int) {
+	start := startIndex - 1
+	if start > total {
+		start = total
+	}
+
+	end := start + count
+	if end > total {
+		end = total
+	}
+
+	return start, end
+}
+
+func scimUserAttribute(user *model.User, attribute string) string {
+	switch strings.TrimPrefix(attribute, strings.ToLower(model.SCIM_USER_SCHEMA_PREFIX)) {
+	case "id":
+		return user.Id
+	case "username":
+		return user.Username
+	case "externalid":
+		return user.Props[model.SCIM_PROP_EXTERNAL_ID]
+	case "emails", "emails.value":
+		return user.Email
+	case "displayname", "name.formatted":
+		return user.GetFullName()
+	case "name.givenname":
+		return user.FirstName
+	case "name.familyname":
+		return user.LastName
+	case "nickname":
+		return user.Nickname
+	case "title":
+		return user.Position
+	case "active":
+		return strconv.FormatBool(user.DeleteAt == 0)
+	}
+
+	return ""
+}
+
+func scimUserMatches(user *model.User, filters []*model.ScimFilter) bool {
+	for _, filter := range filters {
+		value := scimUserAttribute(user, filter.Attribute)
+
+		// identity providers often use the email address as the userName
+		if filter.Attribute == "username" && strings.Contains(filter.Value, "@") {
+			value = user.Email
+		}
+
+		if !filter.Matches(value) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// findScimUsers looks up the users matching the filters by one of the attributes that can be queried directly,
+// since scanning every user to evaluate an arbitrary filter doesn't scale
+func findScimUsers(filters []*model.ScimFilter) ([]*model.User, *model.AppError) {
+	if len(filters) == 0 {
+		if result := <-Srv.Store.User().GetAll(); result.Err != nil {
+			return nil, result.Err
+		} else {
+			return result.Data.([]*model.User), nil
+		}
+	}
+
+	var schan store.StoreChannel
+	single := true
+	for _, filter := range filters {
+		if filter.Operator != model.SCIM_FILTER_OPERATOR_EQUAL {
+			continue
+		}
+
+		value := filter.Value
+
+		switch strings.TrimPrefix(filter.Attribute, strings.ToLower(model.SCIM_USER_SCHEMA_PREFIX)) {
+		case "id":
+			schan = Srv.Store.User().Get(value)
+		case "username":
+			if strings.Contains(value, "@") {
+				schan = Srv.Store.User().GetByEmail(strings.ToLower(value))
+			} else {
+				schan = Srv.Store.User().GetByUsername(strings.ToLower(value))
+			}
+		case "emails", "emails.value":
+			schan = Srv.Store.User().GetByEmail(strings.ToLower(value))
+		case "externalid":
+			schan = Srv.Store.User().GetByProp(model.SCIM_PROP_EXTERNAL_ID, value)
+			single = false
+		}
+
+		if schan != nil {
+			break
+		}
+	}
+
+	if schan == nil {
+		err := model.NewLocAppError("findScimUsers", "api.scim.unsupported_filter.app_error", nil, "")
+		err.StatusCode = http.StatusBadRequest
+		return nil, err
+	}
+
+	var candidates []*model.User
+	if result := <-schan; result.Err != nil {
+		// the single user lookups fail when there's no such user
+		if !single {
+			return nil, result.Err
+		}
+	} else if single {
+		candidates = []*model.User{result.Data.(*model.User)}
+	} else {
+		candidates = result.Data.([]*model.User)
+	}
+
+	users := []*model.User{}
+	for _, user := range candidates {
+		if scimUserMatches(user, filters) {
+			users = append(users, user)
+		}
+	}
+
+	return users, nil
+}
+
+func getScimUsers(c *Context, w http.ResponseWriter, r *http.Request) {
+	startIndex, count, filters := getScimListParams(c, r)
+	if c.Err != nil {
+		return
+	}
+
+	users, err := findScimUsers(filters)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	sort.Sort(usersByUsername(users))
+
+	start, end := scimPage(len(users), startIndex, count)
+
+	resources := []interface{}{}
+	for _, user := range users[start:end] {
+		resources = append(resources, model.NewScimUser(user, c.GetSiteURL()))
+	}
+
+	writeScimResponse(w, http.StatusOK, model.NewScimListResponse(len(users), startIndex, resources))
+}
+
+type usersByUsername []*model.User
+
+func (s usersByUsername) Len() int           { return len(s) }
+func (s usersByUsername) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s usersByUsername) Less(i, j int) bool { return s[i].Username < s[j].Username }
+
+func getScimUserById(c *Context, userId string) *model.User {
+	if result := <-Srv.Store.User().Get(userId); result.Err != nil {
+		c.Err = model.NewLocAppError("getScimUserById", "api.scim.user_not_found.app_error", nil, "user_id="+userId)
+		c.Err.StatusCode = http.StatusNotFound
+		return nil
+	} else {
+		return result.Data.(*model.User)
+	}
+}
+
+func getScimUser(c *Context, w http.ResponseWriter, r *http.Request) {
+	user := getScimUserById(c, mux.Vars(r)["user_id"])
+	if c.Err != nil {
+		return
+	}
+
+	writeScimResponse(w, http.StatusOK, model.NewScimUser(user, c.GetSiteURL()))
+}
+
+func isValidScimUser(c *Context, su *model.ScimUser) bool {
+	if su == nil || len(su.UserName) == 0 || len(su.PrimaryEmail()) == 0 {
+		c.Err = model.NewLocAppError("isValidScimUser", "api.scim.user_invalid.app_error", nil, "")
+		c.Err.StatusCode = http.StatusBadRequest
+		return false
+	}
+
+	return true
+}
+
+func setScimStatusCode(err *model.AppError) *model.AppError {
+	if scimErrorTypes[err.Id] == model.SCIM_ERROR_TYPE_UNIQUENESS {
+		err.StatusCode = http.StatusConflict
+	} else if err.StatusCode == http.StatusInternalServerError && strings.HasPrefix(err.Id, "model.") {
+		err.StatusCode = http.StatusBadRequest
+	}
+
+	return err
+}
+
+func createScimUser(c *Context, w http.ResponseWriter, r *http.Request) {
+	su := model.ScimUserFromJson(r.Body)
+	if !isValidScimUser(c, su) {
+		return
+	}
+
+	user := &model.User{EmailVerified: true, Password: su.Password}
+	su.ApplyTo(user)
+
+	// users are expected to sign in through the identity provider, or reset their password, when none is provisioned
+	if len(user.Password) == 0 {
+		user.Password = (model.NewId() + model.NewId() + model.NewId())[:model.PASSWORD_MAXIMUM_LENGTH-4] + "Aa1!"
+	}
+
+	ruser, err := CreateUser(user)
+	if err != nil {
+		c.Err = setScimStatusCode(err)
+		return
+	}
+
+	if !su.IsActive() {
+		if _, err := UpdateActive(ruser, false); err != nil {
+			c.Err = err
+			return
+		}
+	}
+
+	c.LogAuditWithUserId(ruser.Id, "created")
+
+	if ruser = getScimUserById(c, ruser.Id); c.Err != nil {
+		return
+	}
+
+	writeScimResponse(w, http.StatusCreated, model.NewScimUser(ruser, c.GetSiteURL()))
+}
+
+// saveScimUser replaces the user's provisioned attributes with those of the SCIM user
+func saveScimUser(c *Context, user *model.User, su *model.ScimUser) *model.User {
+	if !isValidScimUser(c, su) {
+		return nil
+	}
+
+	su.ApplyTo(user)
+
+	if len(su.Password) > 0 {
+		if err := utils.IsPasswordValid(su.Password); err != nil {
+			c.Err = err
+			return nil
+		}
+
+		if err := updateUserPassword(user, su.Password); err != nil {
+			c.Err = err
+			return nil
+		}
+	}
+
+	ruser, err := UpdateUser(c, user)
+	if err != nil {
+		c.Err = setScimStatusCode(err)
+		return nil
+	}
+
+	if su.IsActive() != (ruser.DeleteAt == 0) {
+		if _, err := UpdateActive(ruser, su.IsActive()); err != nil {
+			c.Err = err
+			return nil
+		}
+	}
+
+	c.LogAuditWithUserId(user.Id, "updated")
+
+	return getScimUserById(c, user.Id)
+}
+
+func replaceScimUser(c *Context, w http.ResponseWriter, r *http.Request) {
+	user := getScimUserById(c, mux.Vars(r)["user_id"])
+	if c.Err != nil {
+		return
+	}
+
+	if ruser := saveScimUser(c, user, model.ScimUserFromJson(r.Body)); c.Err == nil {
+		writeScimResponse(w, http.StatusOK, model.NewScimUser(ruser, c.GetSiteURL()))
+	}
+}
+
+func patchScimUser(c *Context, w http.ResponseWriter, r *http.Request) {
+	user := getScimUserById(c, mux.Vars(r)["user_id"])
+	if c.Err != nil {
+		return
+	}
+
+	patch := model.ScimPatchRequestFromJson(r.Body)
+	if patch == nil {
+		c.Err = model.NewLocAppError("patchScimUser", "api.scim.invalid_body.app_error", nil, "")
+		c.Err.StatusCode = http.StatusBadRequest
+		return
+	}
+
+	su := model.NewScimUser(user, c.GetSiteURL())
+	if err := su.ApplyPatch(patch.Operations); err != nil {
+		c.Err = model.NewLocAppError("patchScimUser", "api.scim.invalid_patch.app_error", nil, err.Error())
+		c.Err.StatusCode = http.StatusBadRequest
+		return
+	}
+
+	if ruser := saveScimUser(c, user, su); c.Err == nil {
+		writeScimResponse(w, http.StatusOK, model.NewScimUser(ruser, c.GetSiteURL()))
+	}
+}
+
+// deleteScimUser deactivates the user rather than deleting them so that their posts are kept
+func deleteScimUser(c *Context, w http.ResponseWriter, r *http.Request) {
+	user := getScimUserById(c, mux.Vars(r)["user_id"])
+	if c.Err != nil {
+		return
+	}
+
+	if user.DeleteAt == 0 {
+		if _, err := UpdateActive(user, false); err != nil {
+			c.Err = err
+			return
+		}
+	}
+
+	c.LogAuditWithUserId(user.Id, "deactivated")
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// scimGroup is a team or, when the channel is set, one of the team's channels
+type scimGroup struct {
+	team    *model.Team
+	channel *model.Channel
+}
+
+func (g *scimGroup) id() string {
+	if g.channel != nil {
+		return g.channel.Id
+	}
+
+	return g.team.Id
+}
+
+func (g *scimGroup) displayName() string {
+	if g.channel != nil {
+		return g.team.DisplayName + model.SCIM_GROUP_CHANNEL_SEPARATOR + g.channel.DisplayName
+	}
+
+	return g.team.DisplayName
+}
+
+func (g *scimGroup) memberIds() ([]string, *model.AppError) {
+	var ids []string
+
+	if g.channel != nil {
+		if result := <-Srv.Store.Channel().GetMembers(g.channel.Id); result.Err != nil {
+			return nil, result.Err
+		} else {
+			for _, member := range result.Data.([]model.ChannelMember) {
+				ids = append(ids, member.UserId)
+			}
+		}
+
+		return ids, nil
+	}
+
+	return GetAllTeamMemberIds(g.team.Id)
+}
+
+func (g *scimGroup) toScim(siteURL string, includeMembers bool) (*model.ScimGroup, *model.AppError) {
+	createAt, updateAt := g.team.CreateAt, g.team.UpdateAt
+	if g.channel != nil {
+		createAt, updateAt = g.channel.CreateAt, g.channel.UpdateAt
+	}
+
+	sg := model.NewScimGroup(g.id(), g.displayName(), createAt, updateAt, siteURL)
+
+	if includeMembers {
+		ids, err := g.memberIds()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, id := range ids {
+			sg.Members = append(sg.Members, model.ScimMultiValue{Value: id, Ref: siteURL + model.SCIM_URL_SUFFIX + "/Users/" + id})
+		}
+	}
+
+	return sg, nil
+}
+
+func (g *scimGroup) addMember(userId string) *model.AppError {
+	var user *model.User
+	if result := <-Srv.Store.User().Get(userId); result.Err != nil {
+		err := model.NewLocAppError("addMember", "api.scim.member_not_found.app_error", map[string]interface{}{"UserId": userId}, "")
+		err.StatusCode = http.StatusBadRequest
+		return err
+	} else {
+		user = result.Data.(*model.User)
+	}
+
+	// channel members have to belong to the team first
+	if err := JoinUserToTeam(g.team, user); err != nil {
+		return err
+	}
+
+	if g.channel != nil {
+		if _, err := AddUserToChannel(user, g.channel); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (g *scimGroup) removeMember(userId string) *model.AppError {
+	if g.channel != nil {
+		return RemoveUserFromChannel(userId, "", g.channel)
+	}
+
+	if result := <-Srv.Store.User().Get(userId); result.Err != nil {
+		return nil
+	} else if tmr := <-Srv.Store.Team().GetMember(g.team.Id, userId); tmr.Err != nil || tmr.Data.(model.TeamMember).DeleteAt > 0 {
+		return nil
+	} else {
+		return LeaveTeam(g.team, result.Data.(*model.User))
+	}
+}
+
+// setMembers adds and removes members so that the group contains exactly the given users
+func (g *scimGroup) setMembers(userIds []string) *model.AppError {
+	currentIds, err := g.memberIds()
+	if err != nil {
+		return err
+	}
+
+	current := make(map[string]bool)
+	for _, id := range currentIds {
+		current[id] = true
+	}
+
+	wanted := make(map[string]bool)
+	for _, id := range userIds {
+		wanted[id] = true
+
+		if !current[id] {
+			if err := g.addMember(id); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, id := range currentIds {
+		if !wanted[id] {
+			if err := g.removeMember(id); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func getAllScimGroups() ([]*scimGroup, *model.AppError) {
+	var teams []*model.Team
+	if result := <-Srv.Store.Team().GetAll(); result.Err != nil {
+		return nil, result.Err
+	} else {
+		teams = result.Data.([]*model.Team)
+	}
+
+	groups := []*scimGroup{}
+	for _, team := range teams {
+		if team.DeleteAt > 0 {
+			continue
+		}
+
+		groups = append(groups, &scimGroup{team: team})
+
+		if result := <-Srv.Store.Channel().GetAll(team.Id); result.Err != nil {
+			return nil, result.Err
+		} else {
+			for _, channel := range result.Data.([]*model.Channel) {
+				if channel.DeleteAt == 0 && (channel.Type == model.CHANNEL_OPEN || channel.Type == model.CHANNEL_PRIVATE) {
+					groups = append(groups, &scimGroup{team: team, channel: channel})
+				}
+			}
+		}
+	}
+
+	return groups, nil
+}
+
+func getScimGroupById(c *Context, groupId string) *scimGroup {
+	if result := <-Srv.Store.Team().Get(groupId); result.Err == nil {
+		if team := result.Data.(*model.Team); team.DeleteAt == 0 {
+			return &scimGroup{team: team}
+		}
+	} else if result := <-Srv.Store.Channel().Get(groupId); result.Err == nil {
+		channel := result.Data.(*model.Channel)
+
+		if channel.DeleteAt == 0 && (channel.Type == model.CHANNEL_OPEN || channel.Type == model.CHANNEL_PRIVATE) {
+			if tresult := <-Srv.Store.Team().Get(channel.TeamId); tresult.Err == nil {
+				return &scimGroup{team: tresult.Data.(*model.Team), channel: channel}
+			}
+		}
+	}
+
+	c.Err = model.NewLocAppError("getScimGroupById", "api.scim.group_not_found.app_error", nil, "group_id="+groupId)
+	c.Err.StatusCode = http.StatusNotFound
+	return nil
+}
+
+func scimGroupMatches(group *scimGroup, filters []*model.ScimFilter) bool {
+	for _, filter := range filters {
+		value := ""
+		switch strings.TrimPrefix(filter.Attribute, strings.ToLower(model.SCIM_SCHEMA_GROUP+":")) {
+		case "id":
+			value = group.id()
+		case "displayname":
+			value = group.displayName()
+		}
+
+		if !filter.Matches(value) {
+			return false
+		}
+	}
+
+	return true
+}
+
+type scimGroupsByDisplayName []*scimGroup
+
+func (s scimGroupsByDisplayName) Len() int      { return len(s) }
+func (s scimGroupsByDisplayName) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s scimGroupsByDisplayName) Less(i, j int) bool {
+	return strings.ToLower(s[i].displayName()) < strings.ToLower(s[j].displayName())
+}
+
+func getScimGroups(c *Context, w http.ResponseWriter, r *http.Request) {
+	startIndex, count, filters := getScimListParams(c, r)
+	if c.Err != nil {
+		return
+	}
+
+	allGroups, err := getAllScimGroups()
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	groups := []*scimGroup{}
+	for _, group := range allGroups {
+		if scimGroupMatches(group, filters) {
+			groups = append(groups, group)
+		}
+	}
+
+	sort.Sort(scimGroupsByDisplayName(groups))
+
+	// identity providers exclude the members when they only need to find a group
+	includeMembers := !strings.Contains(strings.ToLower(r.URL.Query().Get("excludedAttributes")), "members")
+
+	start, end := scimPage(len(groups), startIndex, count)
+
+	resources := []interface{}{}
+	for _, group := range groups[start:end] {
+		if sg, err := group.toScim(c.GetSiteURL(), includeMembers); err != nil {
+			c.Err = err
+			return
+		} else {
+			resources = append(resources, sg)
+		}
+	}
+
+	writeScimResponse(w, http.StatusOK, model.NewScimListResponse(len(groups), startIndex, resources))
+}
+
+func writeScimGroup(c *Context, w http.ResponseWriter, status int, group *scimGroup) {
+	if sg, err := group.toScim(c.GetSiteURL(), true); err != nil {
+		c.Err = err
+	} else {
+		writeScimResponse(w, status, sg)
+	}
+}
+
+func getScimGroup(c *Context, w http.ResponseWriter, r *http.Request) {
+	group := getScimGroupById(c, mux.Vars(r)["group_id"])
+	if c.Err != nil {
+		return
+	}
+
+	writeScimGroup(c, w, http.StatusOK, group)
+}
+
+var invalidScimChannelNameCharacters = regexp.MustCompile(`[^a-z0-9_-]+`)
+
+// createScimGroup creates an invite only team or, for a display name made of a team's display name, a slash and
+// a channel name, a private channel in that team
+func createScimGroup(c *Context, w http.ResponseWriter, r *http.Request) {
+	sg := model.ScimGroupFromJson(r.Body)
+	if sg == nil || len(strings.TrimSpace(sg.DisplayName)) == 0 {
+		c.Err = model.NewLocAppError("createScimGroup", "api.scim.group_invalid.app_error", nil, "")
+		c.Err.StatusCode = http.StatusBadRequest
+		return
+	}
+
+	groups, err := getAllScimGroups()
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	var team *model.Team
+	var displayName string
+
+	for _, group := range groups {
+		if strings.EqualFold(group.displayName(), sg.DisplayName) {
+			c.Err = model.NewLocAppError("createScimGroup", "api.scim.group_exists.app_error", nil, "display_name="+sg.DisplayName)
+			c.Err.StatusCode = http.StatusConflict
+			return
+		}
+
+		// use the team with the longest matching display name since team display names may contain the separator
+		prefix := group.team.DisplayName + model.SCIM_GROUP_CHANNEL_SEPARATOR
+		if group.channel == nil && len(sg.DisplayName) > len(prefix) && strings.EqualFold(sg.DisplayName[:len(prefix)], prefix) {
+			if team == nil || len(group.team.DisplayName) > len(team.DisplayName) {
+				team = group.team
+				displayName = sg.DisplayName[len(prefix):]
+			}
+		}
+	}
+
+	group := &scimGroup{team: team}
+
+	if team == nil {
+		if strings.Contains(sg.DisplayName, model.SCIM_GROUP_CHANNEL_SEPARATOR) {
+			c.Err = model.NewLocAppError("createScimGroup", "api.scim.group_team_not_found.app_error", nil, "display_name="+sg.DisplayName)
+			c.Err.StatusCode = http.StatusBadRequest
+			return
+		}
+
+		name := model.CleanTeamName(sg.DisplayName)
+		if result := <-Srv.Store.Team().GetByName(name); result.Err == nil {
+			name = model.NewId()
+		}
+
+		if group.team = CreateTeam(c, &model.Team{DisplayName: sg.DisplayName, Name: name, Type: model.TEAM_INVITE}); c.Err != nil {
+			c.Err = setScimStatusCode(c.Err)
+			return
+		}
+	} else {
+		name := strings.Trim(invalidScimChannelNameCharacters.ReplaceAllString(strings.ToLower(displayName), "-"), "-")
+		if !model.IsValidChannelIdentifier(name) {
+			name = model.NewId()
+		} else if result := <-Srv.Store.Channel().GetByName(team.Id, name); result.Err == nil {
+			name = model.NewId()
+		}
+
+		channel := &model.Channel{DisplayName: displayName, Name: name, Type: model.CHANNEL_PRIVATE, TeamId: team.Id}
+		if group.channel, err = CreateChannel(c, channel, false); err != nil {
+			c.Err = setScimStatusCode(err)
+			return
+		}
+	}
+
+	var userIds []string
+	for _, member := range sg.Members {
+		userIds = append(userIds, member.Value)
+	}
+
+	if err := group.setMembers(userIds); err != nil {
+		c.Err = err
+		return
+	}
+
+	c.LogAudit("created group_id=" + group.id())
+
+	writeScimGroup(c, w, http.StatusCreated, group)
+}
+
+func replaceScimGroup(c *Context, w http.ResponseWriter, r *http.Request) {
+	group := getScimGroupById(c, mux.Vars(r)["group_id"])
+	if c.Err != nil {
+		return
+	}
+
+	sg := model.ScimGroupFromJson(r.Body)
+	if sg == nil {
+		c.Err = model.NewLocAppError("replaceScimGroup", "api.scim.invalid_body.app_error", nil, "")
+		c.Err.StatusCode = http.StatusBadRequest
+		return
+	}
+
+	var userIds []string
+	for _, member := range sg.Members {
+		userIds = append(userIds, member.Value)
+	}
+
+	if err := group.setMembers(userIds); err != nil {
+		c.Err = err
+		return
+	}
+
+	c.LogAudit("updated group_id=" + group.id())
+
+	writeScimGroup(c, w, http.StatusOK, group)
+}
+
+// patchScimGroup applies changes to the members of a group, other attributes can't be changed through SCIM
+func patchScimGroup(c *Context, w http.ResponseWriter, r *http.Request) {
+	group := getScimGroupById(c, mux.Vars(r)["group_id"])
+	if c.Err != nil {
+		return
+	}
+
+	patch := model.ScimPatchRequestFromJson(r.Body)
+	if patch == nil {
+		c.Err = model.NewLocAppError("patchScimGroup", "api.scim.invalid_body.app_error", nil, "")
+		c.Err.StatusCode = http.StatusBadRequest
+		return
+	}
+
+	for _, operation := range patch.Operations {
+		op := strings.ToLower(operation.Op)
+		path := strings.ToLower(operation.Path)
+		value := operation.Value
+
+		if len(path) == 0 {
+			// a patch without a path carries the attributes to change in its value
+			if values, ok := value.(map[string]interface{}); ok {
+				if members, ok := values["members"]; ok {
+					path = "members"
+					value = members
+				}
+			}
+		}
+
+		var err *model.AppError
+
+		switch {
+		case op == model.SCIM_PATCH_OP_ADD && path == "members":
+			for _, id := range model.ScimMemberIds(value) {
+				if err = group.addMember(id); err != nil {
+					break
+				}
+			}
+		case op == model.SCIM_PATCH_OP_REPLACE && path == "members":
+			err = group.setMembers(model.ScimMemberIds(value))
+		case op == model.SCIM_PATCH_OP_REMOVE && path == "members":
+			ids := model.ScimMemberIds(value)
+			if value == nil {
+				err = group.setMembers(nil)
+			}
+
+			for _, id := range ids {
+				if err = group.removeMember(id); err != nil {
+					break
+				}
+			}
+		case op == model.SCIM_PATCH_OP_REMOVE && len(model.ScimMemberFilterId(operation.Path)) > 0:
+			err = group.removeMember(model.ScimMemberFilterId(operation.Path))
+		case op != model.SCIM_PATCH_OP_ADD && op != model.SCIM_PATCH_OP_REPLACE && op != model.SCIM_PATCH_OP_REMOVE:
+			err = model.NewLocAppError("patchScimGroup", "api.scim.invalid_patch.app_error", nil, "op="+operation.Op)
+			err.StatusCode = http.StatusBadRequest
+		}
+
+		if err != nil {
+			c.Err = err
+			return
+		}
+	}
+
+	c.LogAudit("updated group_id=" + group.id())
+
+	writeScimGroup(c, w, http.StatusOK, group)
+}
+
+func deleteScimGroup(c *Context, w http.ResponseWriter, r *http.Request) {
+	if getScimGroupById(c, mux.Vars(r)["group_id"]); c.Err != nil {
+		return
+	}
+
+	c.Err = model.NewLocAppError("deleteScimGroup", "api.scim.delete_group.app_error", nil, "")
+	c.Err.StatusCode = http.StatusNotImplemented
+}
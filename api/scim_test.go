@@ -0,0 +1,277 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/platform/model"
+	"github.com/mattermost/platform/utils"
+)
+
+const testScimToken = "scimtestscimtestscimtestscimtest1"
+
+func doScimRequest(t *testing.T, method string, path string, body string, token string) *http.Response {
+	r, err := http.NewRequest(method, "http://localhost"+utils.Cfg.ServiceSettings.ListenAddress+model.SCIM_URL_SUFFIX+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r.Header.Set("Content-Type", model.SCIM_CONTENT_TYPE)
+	if len(token) > 0 {
+		r.Header.Set(model.HEADER_AUTH, "Bearer "+token)
+	}
+
+	resp, err := http.DefaultClient.Do(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return resp
+}
+
+func enableScim() func() {
+	enable := *utils.Cfg.ScimSettings.Enable
+	token := *utils.Cfg.ScimSettings.Token
+
+	*utils.Cfg.ScimSettings.Enable = true
+	*utils.Cfg.ScimSettings.Token = testScimToken
+
+	return func() {
+		*utils.Cfg.ScimSettings.Enable = enable
+		*utils.Cfg.ScimSettings.Token = token
+	}
+}
+
+func TestScimAuthentication(t *testing.T) {
+	Setup()
+
+	resp := doScimRequest(t, "GET", "/Users", "", testScimToken)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotImplemented {
+		t.Fatal("should have failed while SCIM is disabled", resp.StatusCode)
+	}
+
+	defer enableScim()()
+
+	resp = doScimRequest(t, "GET", "/Users", "", "")
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatal("should have failed without a token", resp.StatusCode)
+	}
+
+	if scimErr := model.ScimErrorFromJson(resp.Body); scimErr == nil || scimErr.Status != "401" {
+		t.Fatal("should have returned a SCIM error")
+	}
+	resp.Body.Close()
+
+	resp = doScimRequest(t, "GET", "/Users", "", testScimToken+"x")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatal("should have failed with the wrong token", resp.StatusCode)
+	}
+
+	resp = doScimRequest(t, "GET", "/ServiceProviderConfig", "", testScimToken)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatal("should have succeeded", resp.StatusCode)
+	}
+
+	resp = doScimRequest(t, "GET", "/Schemas/unknown", "", testScimToken)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatal("should have returned not found", resp.StatusCode)
+	}
+}
+
+func TestScimUsers(t *testing.T) {
+	Setup()
+	defer enableScim()()
+
+	email := strings.ToLower("success+"+model.NewId()) + "@simulator.amazonses.com"
+	su := &model.ScimUser{
+		Schemas:    []string{model.SCIM_SCHEMA_USER},
+		UserName:   email,
+		ExternalId: model.NewId(),
+		Name:       &model.ScimName{GivenName: "Jane", FamilyName: "Doe"},
+		Emails:     []model.ScimMultiValue{{Value: email, Primary: true}},
+	}
+
+	resp := doScimRequest(t, "POST", "/Users", su.ToJson(), testScimToken)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatal("should have created the user", resp.StatusCode)
+	}
+
+	created := model.ScimUserFromJson(resp.Body)
+	resp.Body.Close()
+	if created == nil || created.UserName != model.ScimUsername(email) || created.PrimaryEmail() != email || !created.IsActive() {
+		t.Fatal("wrong user created", created)
+	}
+
+	if result := <-Srv.Store.User().Get(created.Id); result.Err != nil {
+		t.Fatal(result.Err)
+	} else if user := result.Data.(*model.User); !user.EmailVerified || user.FirstName != "Jane" || user.Props[model.SCIM_PROP_EXTERNAL_ID] != su.ExternalId {
+		t.Fatal("didn't store the user's attributes")
+	}
+
+	resp = doScimRequest(t, "POST", "/Users", su.ToJson(), testScimToken)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusConflict {
+		t.Fatal("should have failed on a duplicate user", resp.StatusCode)
+	}
+
+	resp = doScimRequest(t, "GET", "/Users?filter="+url.QueryEscape(`userName eq "`+email+`"`), "", testScimToken)
+	list := model.ScimListResponseFromJson(resp.Body)
+	resp.Body.Close()
+	if list == nil || list.TotalResults != 1 {
+		t.Fatal("should have found the user")
+	}
+
+	resp = doScimRequest(t, "GET", "/Users?filter="+url.QueryEscape(`userName xx "a"`), "", testScimToken)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatal("should have failed on an invalid filter", resp.StatusCode)
+	}
+
+	resp = doScimRequest(t, "GET", "/Users?filter="+url.QueryEscape(`externalId eq "`+su.ExternalId+`"`), "", testScimToken)
+	list = model.ScimListResponseFromJson(resp.Body)
+	resp.Body.Close()
+	if list == nil || list.TotalResults != 1 {
+		t.Fatal("should have found the user by external id")
+	}
+
+	resp = doScimRequest(t, "GET", "/Users?filter="+url.QueryEscape(`title co "a"`), "", testScimToken)
+	scimErr := model.ScimErrorFromJson(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest || scimErr == nil || scimErr.ScimType != model.SCIM_ERROR_TYPE_INVALID_FILTER {
+		t.Fatal("should have rejected a filter that can't be looked up directly", resp.StatusCode)
+	}
+
+	patch := &model.ScimPatchRequest{
+		Schemas: []string{model.SCIM_SCHEMA_PATCH_OP},
+		Operations: []*model.ScimPatchOperation{
+			{Op: model.SCIM_PATCH_OP_REPLACE, Path: "title", Value: "Engineer"},
+			{Op: model.SCIM_PATCH_OP_REPLACE, Path: "active", Value: false},
+		},
+	}
+
+	resp = doScimRequest(t, "PATCH", "/Users/"+created.Id, patch.ToJson(), testScimToken)
+	patched := model.ScimUserFromJson(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || patched.Title != "Engineer" || patched.IsActive() {
+		t.Fatal("should have patched the user", resp.StatusCode)
+	}
+
+	su.NickName = "jd"
+	resp = doScimRequest(t, "PUT", "/Users/"+created.Id, su.ToJson(), testScimToken)
+	replaced := model.ScimUserFromJson(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || replaced.NickName != "jd" || replaced.Title != "" || !replaced.IsActive() {
+		t.Fatal("should have replaced and reactivated the user", resp.StatusCode)
+	}
+
+	resp = doScimRequest(t, "DELETE", "/Users/"+created.Id, "", testScimToken)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatal("should have deactivated the user", resp.StatusCode)
+	}
+
+	if result := <-Srv.Store.User().Get(created.Id); result.Err != nil {
+		t.Fatal(result.Err)
+	} else if result.Data.(*model.User).DeleteAt == 0 {
+		t.Fatal("user should be inactive")
+	}
+
+	resp = doScimRequest(t, "GET", "/Users/"+model.NewId(), "", testScimToken)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatal("should have returned not found", resp.StatusCode)
+	}
+}
+
+func TestScimGroups(t *testing.T) {
+	th := Setup().InitBasic()
+	defer enableScim()()
+
+	user := th.BasicUser2
+
+	resp := doScimRequest(t, "GET", "/Groups/"+th.BasicTeam.Id, "", testScimToken)
+	group := model.ScimGroupFromJson(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || group.DisplayName != th.BasicTeam.DisplayName {
+		t.Fatal("should have returned the team", resp.StatusCode)
+	}
+
+	displayName := th.BasicTeam.DisplayName + model.SCIM_GROUP_CHANNEL_SEPARATOR + "SCIM " + model.NewId()
+	sg := &model.ScimGroup{
+		Schemas:     []string{model.SCIM_SCHEMA_GROUP},
+		DisplayName: displayName,
+		Members:     []model.ScimMultiValue{{Value: user.Id}},
+	}
+
+	resp = doScimRequest(t, "POST", "/Groups", sg.ToJson(), testScimToken)
+	created := model.ScimGroupFromJson(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated || created.DisplayName != displayName || len(created.Members) != 1 {
+		t.Fatal("should have created the channel group", resp.StatusCode)
+	}
+
+	if result := <-Srv.Store.Channel().Get(created.Id); result.Err != nil {
+		t.Fatal(result.Err)
+	} else if channel := result.Data.(*model.Channel); channel.Type != model.CHANNEL_PRIVATE || channel.TeamId != th.BasicTeam.Id {
+		t.Fatal("should have created a private channel in the team")
+	}
+
+	resp = doScimRequest(t, "POST", "/Groups", sg.ToJson(), testScimToken)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusConflict {
+		t.Fatal("should have failed on a duplicate group", resp.StatusCode)
+	}
+
+	sg.DisplayName = "Unknown team " + model.NewId() + model.SCIM_GROUP_CHANNEL_SEPARATOR + "channel"
+	resp = doScimRequest(t, "POST", "/Groups", sg.ToJson(), testScimToken)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatal("should have failed without a team", resp.StatusCode)
+	}
+
+	resp = doScimRequest(t, "GET", "/Groups?excludedAttributes=members&filter="+url.QueryEscape(`displayName eq "`+displayName+`"`), "", testScimToken)
+	list := model.ScimListResponseFromJson(resp.Body)
+	resp.Body.Close()
+	if list == nil || list.TotalResults != 1 {
+		t.Fatal("should have found the group")
+	}
+
+	patch := &model.ScimPatchRequest{
+		Schemas: []string{model.SCIM_SCHEMA_PATCH_OP},
+		Operations: []*model.ScimPatchOperation{
+			{Op: model.SCIM_PATCH_OP_REMOVE, Path: `members[value eq "` + user.Id + `"]`},
+		},
+	}
+
+	resp = doScimRequest(t, "PATCH", "/Groups/"+created.Id, patch.ToJson(), testScimToken)
+	patched := model.ScimGroupFromJson(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || len(patched.Members) != 0 {
+		t.Fatal("should have removed the member", resp.StatusCode)
+	}
+
+	patch.Operations = []*model.ScimPatchOperation{
+		{Op: model.SCIM_PATCH_OP_ADD, Path: "members", Value: []interface{}{map[string]interface{}{"value": model.NewId()}}},
+	}
+
+	resp = doScimRequest(t, "PATCH", "/Groups/"+created.Id, patch.ToJson(), testScimToken)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatal("should have failed on an unknown member", resp.StatusCode)
+	}
+
+	resp = doScimRequest(t, "DELETE", "/Groups/"+created.Id, "", testScimToken)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotImplemented {
+		t.Fatal("shouldn't have deleted the group", resp.StatusCode)
+	}
+}
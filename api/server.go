@@ -180,6 +180,7 @@ func StopServer() {
 	l4g.Info(utils.T("api.server.stop_server.stopping.info"))
 
 	Srv.GracefulServer.Stop(TIME_TO_WAIT_FOR_CONNECTIONS_TO_CLOSE_ON_SERVER_SHUTDOWN)
+	StopEmailReplyServer()
 	Srv.Store.Close()
 	HubStop()
 
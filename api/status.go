@@ -5,6 +5,7 @@ package api
 
 import (
 	"net/http"
+	"time"
 
 	l4g "github.com/alecthomas/log4go"
 
@@ -14,6 +15,11 @@ import (
 	"github.com/mattermost/platform/utils"
 )
 
+const (
+	DND_EXPIRY_TASK_NAME           = "Do Not Disturb Expiry"
+	CUSTOM_STATUS_EXPIRY_TASK_NAME = "Custom Status Expiry"
+)
+
 var statusCache *utils.Cache = utils.NewLru(model.STATUS_CACHE_SIZE)
 
 func ClearStatusCache() {
@@ -37,6 +43,8 @@ func InitStatus() {
 
 	BaseRoutes.Users.Handle("/status", ApiUserRequired(getStatusesHttp)).Methods("GET")
 	BaseRoutes.Users.Handle("/status/ids", ApiUserRequired(getStatusesByIdsHttp)).Methods("POST")
+	BaseRoutes.Users.Handle("/status/custom/set", ApiUserRequired(setCustomStatus)).Methods("POST")
+	BaseRoutes.Users.Handle("/status/custom/clear", ApiUserRequired(clearCustomStatus)).Methods("POST")
 	BaseRoutes.WebSocket.Handle("get_statuses", ApiWebSocketHandler(getStatusesWebSocket))
 	BaseRoutes.WebSocket.Handle("get_statuses_by_ids", ApiWebSocketHandler(getStatusesByIdsWebSocket))
 }
@@ -84,13 +92,18 @@ func getStatusesByIdsHttp(c *Context, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	statusMap, err := GetStatusesByIds(userIds)
+	statusMap, customStatusMap, err := GetStatusesAndCustomStatusesByIds(userIds)
 	if err != nil {
 		c.Err = err
 		return
 	}
 
-	w.Write([]byte(model.StringInterfaceToJson(statusMap)))
+	// the custom statuses are only returned when asked for so that older clients still get a map of statuses
+	if r.URL.Query().Get("include_custom") == "true" {
+		w.Write([]byte(model.StringInterfaceToJson(map[string]interface{}{"statuses": statusMap, "custom_statuses": customStatusMap})))
+	} else {
+		w.Write([]byte(model.StringInterfaceToJson(statusMap)))
+	}
 }
 
 func getStatusesByIdsWebSocket(req *model.WebSocketRequest) (map[string]interface{}, *model.AppError) {
@@ -100,22 +113,61 @@ func getStatusesByIdsWebSocket(req *model.WebSocketRequest) (map[string]interfac
 		return nil, NewInvalidWebSocketParamError(req.Action, "user_ids")
 	}
 
-	statusMap, err := GetStatusesByIds(userIds)
+	statusMap, customStatusMap, err := GetStatusesAndCustomStatusesByIds(userIds)
 	if err != nil {
 		return nil, err
 	}
 
+	if includeCustom, ok := req.Data["include_custom"].(bool); ok && includeCustom {
+		return map[string]interface{}{"statuses": statusMap, "custom_statuses": customStatusMap}, nil
+	}
+
 	return statusMap, nil
 }
 
 func GetStatusesByIds(userIds []string) (map[string]interface{}, *model.AppError) {
+	statusMap, _, err := GetStatusesAndCustomStatusesByIds(userIds)
+	return statusMap, err
+}
+
+// GetStatusesAndCustomStatusesByIds returns the statuses of the users and the custom statuses of the users that
+// have one, both using user id as the key
+func GetStatusesAndCustomStatusesByIds(userIds []string) (map[string]interface{}, map[string]interface{}, *model.AppError) {
+	statuses, err := getStatusObjectsByIds(userIds)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := model.GetMillis()
+
 	statusMap := map[string]interface{}{}
+	customStatusMap := map[string]interface{}{}
+	for _, userId := range userIds {
+		if status, ok := statuses[userId]; ok {
+			statusMap[userId] = status.Status
+
+			if customStatus := status.GetCustomStatus(now); customStatus != nil {
+				customStatusMap[userId] = customStatus
+			}
+		} else {
+			// For the case where the user does not have a row in the Status table and cache
+			statusMap[userId] = model.STATUS_OFFLINE
+		}
+	}
+
+	return statusMap, customStatusMap, nil
+}
+
+// getStatusObjectsByIds returns the statuses of the users that have one using user id as the key. The statuses
+// are shared with the cache and must not be modified.
+func getStatusObjectsByIds(userIds []string) (map[string]*model.Status, *model.AppError) {
+	statuses := map[string]*model.Status{}
 	metrics := einterfaces.GetMetricsInterface()
 
 	missingUserIds := []string{}
 	for _, userId := range userIds {
 		if result, ok := statusCache.Get(userId); ok {
-			statusMap[userId] = result.(*model.Status).Status
+			statuses[userId] = result.(*model.Status)
 			if metrics != nil {
 				metrics.IncrementMemCacheHitCounter("Status")
 			}
@@ -131,23 +183,39 @@ func GetStatusesByIds(userIds []string) (map[string]interface{}, *model.AppError
 		if result := <-Srv.Store.Status().GetByIds(missingUserIds); result.Err != nil {
 			return nil, result.Err
 		} else {
-			statuses := result.Data.([]*model.Status)
-
-			for _, s := range statuses {
+			for _, s := range result.Data.([]*model.Status) {
 				AddStatusCache(s)
-				statusMap[s.UserId] = s.Status
+				statuses[s.UserId] = s
 			}
 		}
 	}
 
-	// For the case where the user does not have a row in the Status table and cache
-	for _, userId := range missingUserIds {
-		if _, ok := statusMap[userId]; !ok {
-			statusMap[userId] = model.STATUS_OFFLINE
-		}
+	return statuses, nil
+}
+
+func setCustomStatus(c *Context, w http.ResponseWriter, r *http.Request) {
+	customStatus := model.CustomStatusFromJson(r.Body)
+	if customStatus == nil {
+		c.SetInvalidParam("setCustomStatus", "custom_status")
+		return
 	}
 
-	return statusMap, nil
+	customStatus.PreSave()
+	if err := customStatus.IsValid(model.GetMillis()); err != nil {
+		err.StatusCode = http.StatusBadRequest
+		c.Err = err
+		return
+	}
+
+	SetCustomStatus(c.Session.UserId, customStatus)
+
+	w.Write([]byte(customStatus.ToJson()))
+}
+
+func clearCustomStatus(c *Context, w http.ResponseWriter, r *http.Request) {
+	SetCustomStatus(c.Session.UserId, nil)
+
+	ReturnStatusOK(w)
 }
 
 func SetStatusOnline(userId string, sessionId string, manual bool) {
@@ -160,7 +228,7 @@ func SetStatusOnline(userId string, sessionId string, manual bool) {
 	var err *model.AppError
 
 	if status, err = GetStatus(userId); err != nil {
-		status = &model.Status{userId, model.STATUS_ONLINE, false, model.GetMillis(), ""}
+		status = &model.Status{UserId: userId, Status: model.STATUS_ONLINE, LastActivityAt: model.GetMillis()}
 		broadcast = true
 	} else {
 		if status.Manual && !manual {
@@ -178,6 +246,8 @@ func SetStatusOnline(userId string, sessionId string, manual bool) {
 		status.Status = model.STATUS_ONLINE
 		status.Manual = false // for "online" there's no manual setting
 		status.LastActivityAt = model.GetMillis()
+		status.DNDEndTime = 0
+		status.PrevStatus = ""
 	}
 
 	AddStatusCache(status)
@@ -217,7 +287,14 @@ func SetStatusOffline(userId string, manual bool) {
 		return // manually set status always overrides non-manual one
 	}
 
-	status = &model.Status{userId, model.STATUS_OFFLINE, manual, model.GetMillis(), ""}
+	newStatus := &model.Status{UserId: userId, Status: model.STATUS_OFFLINE, Manual: manual, LastActivityAt: model.GetMillis()}
+	if err == nil {
+		// going offline doesn't clear the custom status
+		newStatus.CustomEmoji = status.CustomEmoji
+		newStatus.CustomText = status.CustomText
+		newStatus.CustomExpiresAt = status.CustomExpiresAt
+	}
+	status = newStatus
 
 	AddStatusCache(status)
 
@@ -235,7 +312,7 @@ func SetStatusAwayIfNeeded(userId string, manual bool) {
 	status, err := GetStatus(userId)
 
 	if err != nil {
-		status = &model.Status{userId, model.STATUS_OFFLINE, manual, 0, ""}
+		status = &model.Status{UserId: userId, Status: model.STATUS_OFFLINE, Manual: manual}
 	}
 
 	if !manual && status.Manual {
@@ -255,6 +332,8 @@ func SetStatusAwayIfNeeded(userId string, manual bool) {
 	status.Status = model.STATUS_AWAY
 	status.Manual = manual
 	status.ActiveChannel = ""
+	status.DNDEndTime = 0
+	status.PrevStatus = ""
 
 	AddStatusCache(status)
 
@@ -268,6 +347,114 @@ func SetStatusAwayIfNeeded(userId string, manual bool) {
 	go Publish(event)
 }
 
+// SetStatusDoNotDisturb sets the user's status to do not disturb until endTime, or until they change
+// it themselves if endTime is 0. The previous status is kept so it can be restored when the time is up.
+func SetStatusDoNotDisturb(userId string, endTime int64) {
+	status, err := GetStatus(userId)
+
+	if err != nil {
+		status = &model.Status{UserId: userId, Status: model.STATUS_OFFLINE}
+	}
+
+	if status.Status != model.STATUS_DND {
+		status.PrevStatus = status.Status
+	}
+
+	status.Status = model.STATUS_DND
+	status.Manual = true
+	status.DNDEndTime = endTime
+	status.ActiveChannel = ""
+
+	AddStatusCache(status)
+
+	if result := <-Srv.Store.Status().SaveOrUpdate(status); result.Err != nil {
+		l4g.Error(utils.T("api.status.save_status.error"), userId, result.Err)
+	}
+
+	event := model.NewWebSocketEvent(model.WEBSOCKET_EVENT_STATUS_CHANGE, "", "", status.UserId, nil)
+	event.Add("status", model.STATUS_DND)
+	event.Add("user_id", status.UserId)
+	go Publish(event)
+}
+
+func StartDNDExpiryJob() {
+	RevertExpiredDNDStatuses()
+	model.CreateRecurringTask(DND_EXPIRY_TASK_NAME, RevertExpiredDNDStatuses, time.Minute)
+}
+
+// RevertExpiredDNDStatuses restores the previous status of every user whose do not disturb time is up
+func RevertExpiredDNDStatuses() {
+	if result := <-Srv.Store.Status().GetExpiredDND(model.GetMillis()); result.Err != nil {
+		l4g.Error(utils.T("api.status.revert_expired_dnd.error"), result.Err.Error())
+	} else {
+		for _, status := range result.Data.([]*model.Status) {
+			revertDNDStatus(status)
+		}
+	}
+}
+
+func revertDNDStatus(status *model.Status) {
+	status.Status = status.PrevStatus
+	if status.Status == "" || status.Status == model.STATUS_DND {
+		status.Status = model.STATUS_OFFLINE
+	}
+
+	// The previous status is treated as automatic so that activity updates it as usual from now on
+	status.Manual = false
+	status.DNDEndTime = 0
+	status.PrevStatus = ""
+
+	AddStatusCache(status)
+
+	if result := <-Srv.Store.Status().SaveOrUpdate(status); result.Err != nil {
+		l4g.Error(utils.T("api.status.save_status.error"), status.UserId, result.Err)
+	}
+
+	event := model.NewWebSocketEvent(model.WEBSOCKET_EVENT_STATUS_CHANGE, "", "", status.UserId, nil)
+	event.Add("status", status.Status)
+	event.Add("user_id", status.UserId)
+	go Publish(event)
+}
+
+// SetCustomStatus replaces the user's custom status, clearing it if customStatus is nil, and lets everyone know
+func SetCustomStatus(userId string, customStatus *model.CustomStatus) {
+	status, err := GetStatus(userId)
+
+	if err != nil {
+		status = &model.Status{UserId: userId, Status: model.STATUS_OFFLINE}
+	}
+
+	status.SetCustomStatus(customStatus)
+
+	AddStatusCache(status)
+
+	if result := <-Srv.Store.Status().SaveOrUpdate(status); result.Err != nil {
+		l4g.Error(utils.T("api.status.save_status.error"), userId, result.Err)
+	}
+
+	event := model.NewWebSocketEvent(model.WEBSOCKET_EVENT_STATUS_CHANGE, "", "", status.UserId, nil)
+	event.Add("status", status.Status)
+	event.Add("user_id", status.UserId)
+	event.Add("custom_status", status.GetCustomStatus(model.GetMillis()))
+	go Publish(event)
+}
+
+func StartCustomStatusExpiryJob() {
+	ClearExpiredCustomStatuses()
+	model.CreateRecurringTask(CUSTOM_STATUS_EXPIRY_TASK_NAME, ClearExpiredCustomStatuses, time.Minute)
+}
+
+// ClearExpiredCustomStatuses clears the custom status of every user whose custom status has expired
+func ClearExpiredCustomStatuses() {
+	if result := <-Srv.Store.Status().GetExpiredCustom(model.GetMillis()); result.Err != nil {
+		l4g.Error(utils.T("api.status.clear_expired_custom.error"), result.Err.Error())
+	} else {
+		for _, status := range result.Data.([]*model.Status) {
+			SetCustomStatus(status.UserId, nil)
+		}
+	}
+}
+
 func GetStatus(userId string) (*model.Status, *model.AppError) {
 	if result, ok := statusCache.Get(userId); ok {
 		status := result.(*model.Status)
@@ -287,13 +474,21 @@ func IsUserAway(lastActivityAt int64) bool {
 	return model.GetMillis()-lastActivityAt >= *utils.Cfg.TeamSettings.UserStatusAwayTimeout*1000
 }
 
-func DoesStatusAllowPushNotification(user *model.User, status *model.Status, channelId string) bool {
+func DoesStatusAllowPushNotification(user *model.User, channelNotifyProps model.StringMap, status *model.Status, channelId string) bool {
 	props := user.NotifyProps
 
 	if props["push"] == "none" {
 		return false
 	}
 
+	if model.IsChannelMutedByNotifyProps(channelNotifyProps) || user.IsInDndSchedule(time.Now()) {
+		return false
+	}
+
+	if status.IsDND(model.GetMillis()) {
+		return false
+	}
+
 	if pushStatus, ok := props["push_status"]; (pushStatus == model.STATUS_ONLINE || !ok) && (status.ActiveChannel != channelId || model.GetMillis()-status.LastActivityAt > model.STATUS_CHANNEL_TIMEOUT) {
 		return true
 	} else if pushStatus == model.STATUS_AWAY && (status.Status == model.STATUS_AWAY || status.Status == model.STATUS_OFFLINE) {
@@ -229,3 +229,45 @@ func TestGetStatusesByIds(t *testing.T) {
 		t.Fatal("should have errored")
 	}
 }
+
+func TestCustomStatus(t *testing.T) {
+	th := Setup().InitBasic()
+	Client := th.BasicClient
+
+	if _, err := Client.SetCustomStatus(&model.CustomStatus{}); err == nil {
+		t.Fatal("should have failed with an empty custom status")
+	}
+
+	if _, err := Client.SetCustomStatus(&model.CustomStatus{Text: "In a meeting", ExpiresAt: model.GetMillis() - 1000}); err == nil {
+		t.Fatal("should have failed with an expiry in the past")
+	}
+
+	Client.Must(Client.SetCustomStatus(&model.CustomStatus{Emoji: ":calendar:", Text: "In a meeting"}))
+
+	statuses := Client.Must(Client.GetStatusesAndCustomStatusesByIds([]string{th.BasicUser.Id, th.BasicUser2.Id})).Data.(*model.StatusesWithCustomStatuses)
+	if len(statuses.Statuses) != 2 {
+		t.Fatal("should have returned the statuses", statuses.Statuses)
+	}
+
+	if customStatuses := statuses.CustomStatuses; len(customStatuses) != 1 || customStatuses[th.BasicUser.Id].Emoji != "calendar" || customStatuses[th.BasicUser.Id].Text != "In a meeting" {
+		t.Fatal("should have returned the custom status", customStatuses)
+	}
+
+	SetStatusOffline(th.BasicUser.Id, false)
+	if status, _ := GetStatus(th.BasicUser.Id); status.CustomText != "In a meeting" {
+		t.Fatal("going offline shouldn't have cleared the custom status")
+	}
+
+	Client.Must(Client.ClearCustomStatus())
+
+	if statuses := Client.Must(Client.GetStatusesAndCustomStatusesByIds([]string{th.BasicUser.Id})).Data.(*model.StatusesWithCustomStatuses); len(statuses.CustomStatuses) != 0 {
+		t.Fatal("should have cleared the custom status")
+	}
+
+	SetCustomStatus(th.BasicUser.Id, &model.CustomStatus{Text: "Back soon", ExpiresAt: model.GetMillis() - 1000})
+	ClearExpiredCustomStatuses()
+
+	if status, _ := GetStatus(th.BasicUser.Id); status.CustomText != "" || status.CustomExpiresAt != 0 {
+		t.Fatal("should have cleared the expired custom status")
+	}
+}
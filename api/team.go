@@ -21,6 +21,10 @@ import (
 	"github.com/mattermost/platform/utils"
 )
 
+const (
+	TEAM_MEMBERS_PAGE_SIZE = 1000
+)
+
 func InitTeam() {
 	l4g.Debug(utils.T("api.team.init.debug"))
 
@@ -272,6 +276,10 @@ func JoinUserToTeam(team *model.Team, user *model.User) *model.AppError {
 		channelRole = model.ROLE_CHANNEL_USER.Id + " " + model.ROLE_CHANNEL_ADMIN.Id
 	}
 
+	if user.IsGuest() {
+		tm.Roles = model.ROLE_TEAM_GUEST.Id
+	}
+
 	if etmr := <-Srv.Store.Team().GetMember(team.Id, user.Id); etmr.Err == nil {
 		// Membership alredy exists.  Check if deleted and and update, otherwise do nothing
 		rtm := etmr.Data.(model.TeamMember)
@@ -295,9 +303,11 @@ func JoinUserToTeam(team *model.Team, user *model.User) *model.AppError {
 		return uua.Err
 	}
 
-	// Soft error if there is an issue joining the default channels
-	if err := JoinDefaultChannels(team.Id, user, channelRole); err != nil {
-		l4g.Error(utils.T("api.user.create_user.joining.error"), user.Id, team.Id, err)
+	// Soft error if there is an issue joining the default channels, guests only join the channels they're added to
+	if !user.IsGuest() {
+		if err := JoinDefaultChannels(team.Id, user, channelRole); err != nil {
+			l4g.Error(utils.T("api.user.create_user.joining.error"), user.Id, team.Id, err)
+		}
 	}
 
 	RemoveAllSessionsForUserId(user.Id)
@@ -306,6 +316,26 @@ func JoinUserToTeam(team *model.Team, user *model.User) *model.AppError {
 	return nil
 }
 
+// GetAllTeamMemberIds returns the ids of every active member of the team, reading them a page at a time
+func GetAllTeamMemberIds(teamId string) ([]string, *model.AppError) {
+	var ids []string
+
+	for offset := 0; ; offset += TEAM_MEMBERS_PAGE_SIZE {
+		if result := <-Srv.Store.Team().GetMembers(teamId, offset, TEAM_MEMBERS_PAGE_SIZE); result.Err != nil {
+			return nil, result.Err
+		} else {
+			members := result.Data.([]*model.TeamMember)
+			for _, member := range members {
+				ids = append(ids, member.UserId)
+			}
+
+			if len(members) < TEAM_MEMBERS_PAGE_SIZE {
+				return ids, nil
+			}
+		}
+	}
+}
+
 func LeaveTeam(team *model.Team, user *model.User) *model.AppError {
 
 	var teamMember model.TeamMember
@@ -598,6 +628,11 @@ func removeUserFromTeam(c *Context, w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if err := CheckLdapGroupMemberRemoval(user.Id, team.Id, ""); err != nil {
+		c.Err = err
+		return
+	}
+
 	err := LeaveTeam(team, user)
 	if err != nil {
 		c.Err = err
@@ -935,6 +970,10 @@ func PermanentDeleteTeam(team *model.Team) *model.AppError {
 		return result.Err
 	}
 
+	if result := <-Srv.Store.Scheme().Delete(team.Id); result.Err != nil {
+		return result.Err
+	}
+
 	if result := <-Srv.Store.Team().PermanentDelete(team.Id); result.Err != nil {
 		return result.Err
 	}
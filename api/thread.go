@@ -0,0 +1,217 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	l4g "github.com/alecthomas/log4go"
+	"github.com/gorilla/mux"
+
+	"github.com/mattermost/platform/model"
+	"github.com/mattermost/platform/utils"
+)
+
+func InitThread() {
+	l4g.Debug(utils.T("api.thread.init.debug"))
+
+	BaseRoutes.NeedTeam.Handle("/posts/threads/{offset:[0-9]+}/{limit:[0-9]+}", ApiUserRequired(getFollowedThreads)).Methods("GET")
+	BaseRoutes.NeedPost.Handle("/follow", ApiUserRequired(followThread)).Methods("POST")
+	BaseRoutes.NeedPost.Handle("/unfollow", ApiUserRequired(unfollowThread)).Methods("POST")
+	BaseRoutes.NeedPost.Handle("/mute", ApiUserRequired(muteThread)).Methods("POST")
+	BaseRoutes.NeedPost.Handle("/unmute", ApiUserRequired(unmuteThread)).Methods("POST")
+	BaseRoutes.NeedPost.Handle("/view_thread", ApiUserRequired(viewThread)).Methods("POST")
+}
+
+// FollowThread makes the user follow the thread of a root post. Users who unfollowed the thread only follow it
+// again if refollow is set, such as when they reply to it or are mentioned in it.
+func FollowThread(postId string, channelId string, userId string, refollow bool) *model.AppError {
+	if result := <-Srv.Store.ThreadMembership().Get(postId, userId); result.Err == nil {
+		membership := result.Data.(*model.ThreadMembership)
+		if membership.Following || !refollow {
+			return nil
+		}
+
+		membership.Following = true
+		if result := <-Srv.Store.ThreadMembership().Update(membership); result.Err != nil {
+			return result.Err
+		}
+
+		publishThreadUpdated(membership)
+		return nil
+	}
+
+	membership := &model.ThreadMembership{PostId: postId, UserId: userId, ChannelId: channelId, Following: true}
+	if result := <-Srv.Store.ThreadMembership().Save(membership); result.Err != nil {
+		// someone else created the membership first
+		if result.Err.Id == "store.sql_thread_membership.save.exists.app_error" {
+			return nil
+		}
+
+		return result.Err
+	}
+
+	publishThreadUpdated(membership)
+	return nil
+}
+
+// UpdateThreadMembership changes the user's membership in the thread of a root post, creating it if they
+// haven't participated in the thread yet
+func UpdateThreadMembership(postId string, channelId string, userId string, update func(membership *model.ThreadMembership)) (*model.ThreadMembership, *model.AppError) {
+	var membership *model.ThreadMembership
+	if result := <-Srv.Store.ThreadMembership().Get(postId, userId); result.Err == nil {
+		membership = result.Data.(*model.ThreadMembership)
+		update(membership)
+
+		if result := <-Srv.Store.ThreadMembership().Update(membership); result.Err != nil {
+			return nil, result.Err
+		}
+	} else {
+		membership = &model.ThreadMembership{PostId: postId, UserId: userId, ChannelId: channelId}
+		update(membership)
+
+		if result := <-Srv.Store.ThreadMembership().Save(membership); result.Err != nil {
+			return nil, result.Err
+		}
+	}
+
+	publishThreadUpdated(membership)
+	return membership, nil
+}
+
+func ViewThread(postId string, userId string) *model.AppError {
+	if result := <-Srv.Store.ThreadMembership().ViewThread(postId, userId, model.GetMillis()); result.Err != nil {
+		return result.Err
+	}
+
+	if result := <-Srv.Store.ThreadMembership().Get(postId, userId); result.Err == nil {
+		publishThreadUpdated(result.Data.(*model.ThreadMembership))
+	}
+
+	return nil
+}
+
+// publishThreadUpdated lets the user's clients know about the change to their unread replies or following
+func publishThreadUpdated(membership *model.ThreadMembership) {
+	message := model.NewWebSocketEvent(model.WEBSOCKET_EVENT_THREAD_UPDATED, "", "", membership.UserId, nil)
+	message.Add("thread", membership.ToJson())
+
+	go Publish(message)
+}
+
+func getFollowedThreads(c *Context, w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+
+	offset, err := strconv.Atoi(params["offset"])
+	if err != nil {
+		c.SetInvalidParam("getFollowedThreads", "offset")
+		return
+	}
+
+	limit, err := strconv.Atoi(params["limit"])
+	if err != nil || limit > 200 {
+		c.SetInvalidParam("getFollowedThreads", "limit")
+		return
+	}
+
+	if result := <-Srv.Store.ThreadMembership().GetFollowedByUser(c.Session.UserId, c.TeamId, offset, limit); result.Err != nil {
+		c.Err = result.Err
+		return
+	} else {
+		w.Write([]byte(model.ThreadMembershipsToJson(result.Data.([]*model.ThreadMembership))))
+	}
+}
+
+// getThreadRootPost checks that the post is a root post in the channel that the user can read
+func getThreadRootPost(c *Context, r *http.Request, where string) *model.Post {
+	params := mux.Vars(r)
+
+	channelId := params["channel_id"]
+	if len(channelId) != 26 {
+		c.SetInvalidParam(where, "channelId")
+		return nil
+	}
+
+	postId := params["post_id"]
+	if len(postId) != 26 {
+		c.SetInvalidParam(where, "postId")
+		return nil
+	}
+
+	pchan := Srv.Store.Post().Get(postId)
+
+	if !HasPermissionToChannelContext(c, channelId, model.PERMISSION_READ_CHANNEL) {
+		return nil
+	}
+
+	if result := <-pchan; result.Err != nil {
+		c.Err = result.Err
+		return nil
+	} else if post, ok := result.Data.(*model.PostList).Posts[postId]; !ok || post.ChannelId != channelId {
+		c.Err = model.NewLocAppError(where, "api.post.get_post.permissions.app_error", nil, "")
+		c.Err.StatusCode = http.StatusForbidden
+		return nil
+	} else if len(post.RootId) > 0 {
+		c.Err = model.NewLocAppError(where, "api.thread.root_post.app_error", nil, "post_id="+postId)
+		c.Err.StatusCode = http.StatusBadRequest
+		return nil
+	} else {
+		return post
+	}
+}
+
+func followThread(c *Context, w http.ResponseWriter, r *http.Request) {
+	updateThread(c, w, r, "followThread", func(membership *model.ThreadMembership) {
+		membership.Following = true
+	})
+}
+
+func unfollowThread(c *Context, w http.ResponseWriter, r *http.Request) {
+	updateThread(c, w, r, "unfollowThread", func(membership *model.ThreadMembership) {
+		membership.Following = false
+		membership.UnreadReplies = 0
+	})
+}
+
+func muteThread(c *Context, w http.ResponseWriter, r *http.Request) {
+	updateThread(c, w, r, "muteThread", func(membership *model.ThreadMembership) {
+		membership.Muted = true
+		membership.UnreadReplies = 0
+	})
+}
+
+func unmuteThread(c *Context, w http.ResponseWriter, r *http.Request) {
+	updateThread(c, w, r, "unmuteThread", func(membership *model.ThreadMembership) {
+		membership.Muted = false
+	})
+}
+
+func updateThread(c *Context, w http.ResponseWriter, r *http.Request, where string, update func(membership *model.ThreadMembership)) {
+	post := getThreadRootPost(c, r, where)
+	if post == nil {
+		return
+	}
+
+	if membership, err := UpdateThreadMembership(post.Id, post.ChannelId, c.Session.UserId, update); err != nil {
+		c.Err = err
+		return
+	} else {
+		w.Write([]byte(membership.ToJson()))
+	}
+}
+
+func viewThread(c *Context, w http.ResponseWriter, r *http.Request) {
+	post := getThreadRootPost(c, r, "viewThread")
+	if post == nil {
+		return
+	}
+
+	if err := ViewThread(post.Id, c.Session.UserId); err != nil {
+		c.Err = err
+		return
+	}
+
+	ReturnStatusOK(w)
+}
@@ -0,0 +1,78 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api
+
+import (
+	"testing"
+
+	"github.com/mattermost/platform/model"
+)
+
+func TestThreads(t *testing.T) {
+	th := Setup().InitBasic()
+	Client := th.BasicClient
+	channel := th.BasicChannel
+
+	AddUserToChannel(th.BasicUser2, channel)
+
+	root := Client.Must(Client.CreatePost(&model.Post{ChannelId: channel.Id, Message: "question"})).Data.(*model.Post)
+
+	th.LoginBasic2()
+	reply := Client.Must(Client.CreatePost(&model.Post{ChannelId: channel.Id, RootId: root.Id, ParentId: root.Id, Message: "answer"})).Data.(*model.Post)
+
+	if threads := Client.Must(Client.GetFollowedThreads(0, 10)).Data.([]*model.ThreadMembership); len(threads) != 1 || threads[0].PostId != root.Id || threads[0].UnreadReplies != 0 {
+		t.Fatal("should have followed the thread after replying", threads)
+	}
+
+	if _, err := Client.FollowThread(channel.Id, reply.Id); err == nil {
+		t.Fatal("should have failed to follow a reply")
+	}
+
+	th.LoginBasic()
+
+	if threads := Client.Must(Client.GetFollowedThreads(0, 10)).Data.([]*model.ThreadMembership); len(threads) != 1 || threads[0].UnreadReplies != 1 {
+		t.Fatal("should have followed the thread as its author with an unread reply", threads)
+	}
+
+	Client.Must(Client.ViewThread(channel.Id, root.Id))
+
+	if threads := Client.Must(Client.GetFollowedThreads(0, 10)).Data.([]*model.ThreadMembership); len(threads) != 1 || threads[0].UnreadReplies != 0 {
+		t.Fatal("should have read the replies", threads)
+	}
+
+	if membership := Client.Must(Client.MuteThread(channel.Id, root.Id)).Data.(*model.ThreadMembership); !membership.Muted || !membership.Following {
+		t.Fatal("should have muted the thread", membership)
+	}
+
+	th.LoginBasic2()
+	Client.Must(Client.CreatePost(&model.Post{ChannelId: channel.Id, RootId: root.Id, ParentId: root.Id, Message: "any luck?"}))
+	th.LoginBasic()
+
+	if threads := Client.Must(Client.GetFollowedThreads(0, 10)).Data.([]*model.ThreadMembership); len(threads) != 1 || threads[0].UnreadReplies != 0 {
+		t.Fatal("shouldn't have counted replies to the muted thread", threads)
+	}
+
+	if membership := Client.Must(Client.UnmuteThread(channel.Id, root.Id)).Data.(*model.ThreadMembership); membership.Muted {
+		t.Fatal("should have unmuted the thread", membership)
+	}
+
+	if membership := Client.Must(Client.UnfollowThread(channel.Id, root.Id)).Data.(*model.ThreadMembership); membership.Following {
+		t.Fatal("should have unfollowed the thread", membership)
+	}
+
+	if threads := Client.Must(Client.GetFollowedThreads(0, 10)).Data.([]*model.ThreadMembership); len(threads) != 0 {
+		t.Fatal("shouldn't have returned the unfollowed thread", threads)
+	}
+
+	if membership := Client.Must(Client.FollowThread(channel.Id, root.Id)).Data.(*model.ThreadMembership); !membership.Following {
+		t.Fatal("should have followed the thread", membership)
+	}
+
+	otherChannel := th.CreateChannel(Client, th.BasicTeam)
+	if _, err := Client.FollowThread(otherChannel.Id, root.Id); err == nil {
+		t.Fatal("should have failed for a post in another channel")
+	}
+
+	Client.Must(Client.DeletePost(channel.Id, root.Id))
+}
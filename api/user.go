@@ -19,6 +19,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -41,12 +42,13 @@ func InitUser() {
 	BaseRoutes.Users.Handle("/update", ApiUserRequired(updateUser)).Methods("POST")
 	BaseRoutes.Users.Handle("/update_active", ApiUserRequired(updateActive)).Methods("POST")
 	BaseRoutes.Users.Handle("/update_notify", ApiUserRequired(updateUserNotify)).Methods("POST")
-	BaseRoutes.Users.Handle("/newpassword", ApiUserRequired(updatePassword)).Methods("POST")
+	BaseRoutes.Users.Handle("/newpassword", ApiUserRequiredExpiredPassword(updatePassword)).Methods("POST")
 	BaseRoutes.Users.Handle("/send_password_reset", ApiAppHandler(sendPasswordReset)).Methods("POST")
 	BaseRoutes.Users.Handle("/reset_password", ApiAppHandler(resetPassword)).Methods("POST")
 	BaseRoutes.Users.Handle("/login", ApiAppHandler(login)).Methods("POST")
 	BaseRoutes.Users.Handle("/logout", ApiAppHandler(logout)).Methods("POST")
 	BaseRoutes.Users.Handle("/revoke_session", ApiUserRequired(revokeSession)).Methods("POST")
+	BaseRoutes.Users.Handle("/revoke_all_other_sessions", ApiUserRequired(revokeAllOtherSessions)).Methods("POST")
 	BaseRoutes.Users.Handle("/attach_device", ApiUserRequired(attachDeviceId)).Methods("POST")
 	BaseRoutes.Users.Handle("/verify_email", ApiAppHandler(verifyEmail)).Methods("POST")
 	BaseRoutes.Users.Handle("/resend_verification", ApiAppHandler(resendVerification)).Methods("POST")
@@ -101,15 +103,28 @@ func createUser(c *Context, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if attributes, err := GetProfileAttributes(); err != nil {
+		c.Err = err
+		return
+	} else if values, err := model.CleanProfileAttributes(attributes, user.ProfileAttributes); err != nil {
+		c.Err = err
+		c.Err.StatusCode = http.StatusBadRequest
+		return
+	} else {
+		user.ProfileAttributes = values
+	}
+
 	hash := r.URL.Query().Get("h")
 	teamId := ""
 	var team *model.Team
+	var inviteProps map[string]string
 	shouldSendWelcomeEmail := true
 	user.EmailVerified = false
 
 	if len(hash) > 0 {
 		data := r.URL.Query().Get("d")
 		props := model.MapFromJson(strings.NewReader(data))
+		inviteProps = props
 
 		if !model.ComparePassword(hash, fmt.Sprintf("%v:%v", data, utils.Cfg.EmailSettings.InviteSalt)) {
 			c.Err = model.NewLocAppError("createUser", "api.user.create_user.signup_link_invalid.app_error", nil, "")
@@ -166,7 +181,8 @@ func createUser(c *Context, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !CheckUserDomain(user, utils.Cfg.TeamSettings.RestrictCreationToDomains) {
+	// guests are usually from outside the organisation so their invite is trusted instead of their email domain
+	if inviteProps["guest"] != "true" && !CheckUserDomain(user, utils.Cfg.TeamSettings.RestrictCreationToDomains) {
 		c.Err = model.NewLocAppError("createUser", "api.user.create_user.accepted_domain.app_error", nil, "")
 		return
 	}
@@ -177,7 +193,12 @@ func createUser(c *Context, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if len(teamId) > 0 {
+	if inviteProps["guest"] == "true" {
+		if ruser, err = joinGuestFromInvite(ruser, team, inviteProps); err != nil {
+			c.Err = err
+			return
+		}
+	} else if len(teamId) > 0 {
 		err := JoinUserToTeam(team, ruser)
 		if err != nil {
 			c.Err = err
@@ -383,7 +404,7 @@ func sendWelcomeEmail(c *Context, userId string, email string, siteURL string, v
 		bodyPage.Props["VerifyUrl"] = link
 	}
 
-	if err := utils.SendMail(email, subject, bodyPage.Render()); err != nil {
+	if err := QueueMail(email, subject, bodyPage.Render()); err != nil {
 		l4g.Error(utils.T("api.user.send_welcome_email_and_forget.failed.error"), err)
 	}
 }
@@ -440,7 +461,7 @@ func SendVerifyEmail(c *Context, userId, userEmail, siteURL string) {
 	bodyPage.Props["VerifyUrl"] = link
 	bodyPage.Props["Button"] = c.T("api.templates.verify_body.button")
 
-	if err := utils.SendMail(userEmail, subject, bodyPage.Render()); err != nil {
+	if err := QueueMail(userEmail, subject, bodyPage.Render()); err != nil {
 		l4g.Error(utils.T("api.user.send_verify_email_and_forget.failed.error"), err)
 	}
 }
@@ -461,6 +482,16 @@ func login(c *Context, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	throttleKey := loginId
+	if len(id) != 0 {
+		throttleKey = id
+	}
+
+	if !checkLoginRateLimit(c, w, throttleKey) {
+		c.LogAudit("throttled")
+		return
+	}
+
 	var user *model.User
 	var err *model.AppError
 
@@ -643,6 +674,25 @@ func doLogin(c *Context, w http.ResponseWriter, r *http.Request, user *model.Use
 	session.AddProp(model.SESSION_PROP_PLATFORM, plat)
 	session.AddProp(model.SESSION_PROP_OS, os)
 	session.AddProp(model.SESSION_PROP_BROWSER, fmt.Sprintf("%v/%v", bname, bversion))
+	session.AddProp(model.SESSION_PROP_IS_MOBILE, strconv.FormatBool(ua.Mobile()))
+	session.AddProp(model.SESSION_PROP_IP_ADDRESS, c.IpAddress)
+
+	userAgent := r.UserAgent()
+	if len(userAgent) > model.SESSION_USER_AGENT_MAX_LENGTH {
+		userAgent = userAgent[:model.SESSION_USER_AGENT_MAX_LENGTH]
+	}
+	session.AddProp(model.SESSION_PROP_USER_AGENT, userAgent)
+
+	// the session can only be used to change the password until it has been changed
+	if user.IsPasswordExpired(*utils.Cfg.PasswordSettings.MaximumAgeInDays) {
+		c.LogAuditWithUserId(user.Id, "password expired")
+		session.AddProp(model.SESSION_PROP_PASSWORD_EXPIRED, "true")
+	}
+
+	if err := enforceSessionLimit(c, user.Id); err != nil {
+		c.Err = err
+		return
+	}
 
 	if result := <-Srv.Store.Session().Save(session); result.Err != nil {
 		c.Err = result.Err
@@ -697,6 +747,13 @@ func attachDeviceId(c *Context, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// mobile apps can register a public key so that push notifications can be encrypted for them
+	deviceKey := props["device_key"]
+	if len(deviceKey) > 0 && !model.IsValidDevicePublicKey(deviceKey) {
+		c.SetInvalidParam("attachDevice", "deviceKey")
+		return
+	}
+
 	// A special case where we logout of all other sessions with the same Id
 	if result := <-Srv.Store.Session().GetSessions(c.Session.UserId); result.Err != nil {
 		c.Err = result.Err
@@ -739,7 +796,7 @@ func attachDeviceId(c *Context, w http.ResponseWriter, r *http.Request) {
 
 	http.SetCookie(w, sessionCookie)
 
-	if result := <-Srv.Store.Session().UpdateDeviceId(c.Session.Id, deviceId, c.Session.ExpiresAt); result.Err != nil {
+	if result := <-Srv.Store.Session().UpdateDeviceId(c.Session.Id, deviceId, deviceKey, c.Session.ExpiresAt); result.Err != nil {
 		c.Err = result.Err
 		return
 	}
@@ -767,8 +824,53 @@ func RevokeSessionById(c *Context, sessionId string) {
 	}
 }
 
-// IF YOU UPDATE THIS PLEASE UPDATE BELOW
+// enforceSessionLimit revokes the user's oldest sessions so that a new one can be created without going over
+// the maximum number of sessions per user
+func enforceSessionLimit(c *Context, userId string) *model.AppError {
+	max := *utils.Cfg.ServiceSettings.MaximumSessionsPerUser
+	if max <= 0 {
+		return nil
+	}
+
+	var sessions []*model.Session
+	if result := <-Srv.Store.Session().GetSessions(userId); result.Err != nil {
+		return result.Err
+	} else {
+		for _, session := range result.Data.([]*model.Session) {
+			// OAuth sessions belong to apps that the user authorized so they don't count
+			if !session.IsOAuth {
+				sessions = append(sessions, session)
+			}
+		}
+	}
+
+	sort.Sort(sessionsByCreateAt(sessions))
+
+	for i := 0; i <= len(sessions)-max; i++ {
+		l4g.Debug(utils.T("api.user.login.revoking_oldest.debug"), sessions[i].Id, userId)
+		RevokeSessionById(c, sessions[i].Id)
+		if c.Err != nil {
+			err := c.Err
+			c.Err = nil
+			return err
+		}
+	}
+
+	return nil
+}
+
+type sessionsByCreateAt []*model.Session
+
+func (s sessionsByCreateAt) Len() int           { return len(s) }
+func (s sessionsByCreateAt) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s sessionsByCreateAt) Less(i, j int) bool { return s[i].CreateAt < s[j].CreateAt }
+
 func RevokeAllSession(c *Context, userId string) {
+	RevokeAllSessionsExcept(c, userId, "")
+}
+
+// IF YOU UPDATE THIS PLEASE UPDATE BELOW
+func RevokeAllSessionsExcept(c *Context, userId string, keepSessionId string) {
 	if result := <-Srv.Store.Session().GetSessions(userId); result.Err != nil {
 		c.Err = result.Err
 		return
@@ -776,6 +878,10 @@ func RevokeAllSession(c *Context, userId string) {
 		sessions := result.Data.([]*model.Session)
 
 		for _, session := range sessions {
+			if session.Id == keepSessionId {
+				continue
+			}
+
 			c.LogAuditWithUserId(userId, "session_id="+session.Id)
 			if session.IsOAuth {
 				RevokeAccessToken(session.Token)
@@ -819,6 +925,17 @@ func RevokeAllSessionsNoContext(userId string) *model.AppError {
 	return nil
 }
 
+func revokeAllOtherSessions(c *Context, w http.ResponseWriter, r *http.Request) {
+	RevokeAllSessionsExcept(c, c.Session.UserId, c.Session.Id)
+	if c.Err != nil {
+		return
+	}
+
+	c.LogAudit("revoked all other sessions")
+
+	ReturnStatusOK(w)
+}
+
 func getSessions(c *Context, w http.ResponseWriter, r *http.Request) {
 
 	params := mux.Vars(r)
@@ -1403,6 +1520,7 @@ func uploadProfileImage(c *Context, w http.ResponseWriter, r *http.Request) {
 	} else {
 		user := result.Data.(*model.User)
 		user = sanitizeProfile(c, user)
+		sanitizeProfileAttributes(user, false)
 		omitUsers := make(map[string]bool, 1)
 		omitUsers[user.Id] = true
 		message := model.NewWebSocketEvent(model.WEBSOCKET_EVENT_USER_UPDATED, "", "", "", omitUsers)
@@ -1434,12 +1552,28 @@ func updateUser(c *Context, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if result := <-Srv.Store.User().Update(user, false); result.Err != nil {
-		c.Err = result.Err
+	if err := cleanProfileAttributes(user); err != nil {
+		c.Err = err
+		return
+	}
+
+	if ruser, err := UpdateUser(c, user); err != nil {
+		c.Err = err
 		return
 	} else {
 		c.LogAudit("")
 
+		ruser.Sanitize(map[string]bool{})
+		w.Write([]byte(ruser.ToJson()))
+	}
+}
+
+// UpdateUser saves the user's profile, notifying the user of email and username changes and everyone else
+// of the update
+func UpdateUser(c *Context, user *model.User) (*model.User, *model.AppError) {
+	if result := <-Srv.Store.User().Update(user, false); result.Err != nil {
+		return nil, result.Err
+	} else {
 		rusers := result.Data.([2]*model.User)
 
 		if rusers[0].Email != rusers[1].Email {
@@ -1456,19 +1590,17 @@ func updateUser(c *Context, w http.ResponseWriter, r *http.Request) {
 
 		InvalidateCacheForUser(user.Id)
 
-		updatedUser := rusers[0]
-		updatedUser = sanitizeProfile(c, updatedUser)
+		updatedUser := *rusers[0]
+		sanitizeProfile(c, &updatedUser)
+		sanitizeProfileAttributes(&updatedUser, false)
 
 		omitUsers := make(map[string]bool, 1)
 		omitUsers[user.Id] = true
 		message := model.NewWebSocketEvent(model.WEBSOCKET_EVENT_USER_UPDATED, "", "", "", omitUsers)
-		message.Add("user", updatedUser)
+		message.Add("user", &updatedUser)
 		go Publish(message)
 
-		rusers[0].Password = ""
-		rusers[0].AuthData = new(string)
-		*rusers[0].AuthData = ""
-		w.Write([]byte(rusers[0].ToJson()))
+		return rusers[0], nil
 	}
 }
 
@@ -1533,18 +1665,51 @@ func updatePassword(c *Context, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if uresult := <-Srv.Store.User().UpdatePassword(c.Session.UserId, model.HashPassword(newPassword)); uresult.Err != nil {
-		c.Err = model.NewLocAppError("updatePassword", "api.user.update_password.failed.app_error", nil, uresult.Err.Error())
+	if err := checkPasswordHistory(user, newPassword); err != nil {
+		c.LogAudit("failed - tried to reuse a previous password")
+		c.Err = err
 		return
-	} else {
-		c.LogAudit("completed")
+	}
 
-		go sendPasswordChangeEmail(c, user.Email, c.GetSiteURL(), c.T("api.user.update_password.menu"))
+	if err := updateUserPassword(user, newPassword); err != nil {
+		c.Err = model.NewLocAppError("updatePassword", "api.user.update_password.failed.app_error", nil, err.Error())
+		return
+	}
 
-		data := make(map[string]string)
-		data["user_id"] = uresult.Data.(string)
-		w.Write([]byte(model.MapToJson(data)))
+	c.LogAudit("completed")
+
+	go sendPasswordChangeEmail(c, user.Email, c.GetSiteURL(), c.T("api.user.update_password.menu"))
+
+	data := make(map[string]string)
+	data["user_id"] = user.Id
+	w.Write([]byte(model.MapToJson(data)))
+}
+
+func checkPasswordHistory(user *model.User, newPassword string) *model.AppError {
+	historyCount := *utils.Cfg.PasswordSettings.HistoryCount
+
+	if user.IsPasswordReused(newPassword, historyCount) {
+		err := model.NewLocAppError("checkPasswordHistory", "api.user.check_password_history.reused.app_error", map[string]interface{}{"Count": historyCount}, "user_id="+user.Id)
+		err.StatusCode = http.StatusBadRequest
+		return err
 	}
+
+	return nil
+}
+
+// updateUserPassword replaces the user's password and remembers the old one so that it can't be reused
+func updateUserPassword(user *model.User, newPassword string) *model.AppError {
+	if result := <-Srv.Store.User().UpdatePassword(user.Id, model.HashPassword(newPassword)); result.Err != nil {
+		return result.Err
+	}
+
+	if result := <-Srv.Store.User().UpdatePasswordHistory(user.Id, user.NewPasswordHistory(*utils.Cfg.PasswordSettings.HistoryCount)); result.Err != nil {
+		return result.Err
+	}
+
+	InvalidateCacheForUser(user.Id)
+
+	return nil
 }
 
 func updateRoles(c *Context, w http.ResponseWriter, r *http.Request) {
@@ -1739,6 +1904,22 @@ func PermanentDeleteUser(user *model.User) *model.AppError {
 		return result.Err
 	}
 
+	if result := <-Srv.Store.WebAuthn().PermanentDeleteByUser(user.Id); result.Err != nil {
+		return result.Err
+	}
+
+	if result := <-Srv.Store.UserGroup().PermanentDeleteMembersByUser(user.Id); result.Err != nil {
+		return result.Err
+	}
+
+	if result := <-Srv.Store.ThreadMembership().PermanentDeleteByUser(user.Id); result.Err != nil {
+		return result.Err
+	}
+
+	if result := <-Srv.Store.Reminder().PermanentDeleteByUser(user.Id); result.Err != nil {
+		return result.Err
+	}
+
 	l4g.Warn(utils.T("api.user.permanent_delete_user.deleted.warn"), user.Email, user.Id)
 
 	return nil
@@ -1798,7 +1979,7 @@ func sendPasswordReset(c *Context, w http.ResponseWriter, r *http.Request) {
 	bodyPage.Props["ResetUrl"] = link
 	bodyPage.Props["Button"] = c.T("api.templates.reset_body.button")
 
-	if err := utils.SendMail(email, subject, bodyPage.Render()); err != nil {
+	if err := QueueMail(email, subject, bodyPage.Render()); err != nil {
 		c.Err = model.NewLocAppError("sendPasswordReset", "api.user.send_password_reset.send.app_error", nil, "err="+err.Message)
 		return
 	}
@@ -1869,13 +2050,21 @@ func ResetPassword(c *Context, userId, newPassword string) *model.AppError {
 		user = result.Data.(*model.User)
 	}
 
+	if user.IsBot() {
+		return model.NewLocAppError("ResetPassword", "api.user.reset_password.bot.app_error", nil, "userId="+user.Id)
+	}
+
 	if user.AuthData != nil && len(*user.AuthData) != 0 && !HasPermissionToContext(c, model.PERMISSION_MANAGE_SYSTEM) {
 		return model.NewLocAppError("ResetPassword", "api.user.reset_password.sso.app_error", nil, "userId="+user.Id)
 
 	}
 
-	if result := <-Srv.Store.User().UpdatePassword(userId, model.HashPassword(newPassword)); result.Err != nil {
-		return result.Err
+	if err := checkPasswordHistory(user, newPassword); err != nil {
+		return err
+	}
+
+	if err := updateUserPassword(user, newPassword); err != nil {
+		return err
 	}
 
 	go sendPasswordChangeEmail(c, user.Email, c.GetSiteURL(), c.T("api.user.reset_password.method"))
@@ -2013,6 +2202,24 @@ func updateUserNotify(c *Context, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if _, err := model.DndScheduleFromNotifyProps(props); err != nil {
+		c.Err = err
+		c.Err.StatusCode = http.StatusBadRequest
+		return
+	}
+
+	if _, err := model.AutoResponderFromNotifyProps(props); err != nil {
+		c.Err = err
+		c.Err.StatusCode = http.StatusBadRequest
+		return
+	}
+
+	if _, err := model.DigestScheduleFromNotifyProps(props); err != nil {
+		c.Err = err
+		c.Err.StatusCode = http.StatusBadRequest
+		return
+	}
+
 	var user *model.User
 	if result := <-uchan; result.Err != nil {
 		c.Err = result.Err
@@ -2102,7 +2309,7 @@ func emailToOAuth(c *Context, w http.ResponseWriter, r *http.Request) {
 	if service == model.USER_AUTH_SERVICE_SAML {
 		m["follow_link"] = c.GetSiteURL() + "/login/sso/saml?action=" + model.OAUTH_ACTION_EMAIL_TO_SSO + "&email=" + email
 	} else {
-		if authUrl, err := GetAuthorizationCode(c, service, stateProps, ""); err != nil {
+		if authUrl, err := GetAuthorizationCode(c, w, service, stateProps, ""); err != nil {
 			c.LogAuditWithUserId(user.Id, "fail - oauth issue")
 			c.Err = err
 			return
@@ -2511,6 +2718,15 @@ func DeactivateMfa(userId string) *model.AppError {
 	return nil
 }
 
+// UnlockUser clears the failed login attempts that locked the user out
+func UnlockUser(userId string) *model.AppError {
+	if result := <-Srv.Store.User().UpdateFailedPasswordAttempts(userId, 0); result.Err != nil {
+		return result.Err
+	}
+
+	return nil
+}
+
 func checkMfa(c *Context, w http.ResponseWriter, r *http.Request) {
 	if !utils.IsLicensed || !*utils.License.Features.MFA || !*utils.Cfg.ServiceSettings.EnableMultifactorAuthentication {
 		rdata := map[string]string{}
@@ -2527,6 +2743,10 @@ func checkMfa(c *Context, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !checkLoginRateLimit(c, w, loginId) {
+		return
+	}
+
 	// we don't need to worry about contacting the ldap server to get this user because
 	// only users already in the system could have MFA enabled
 	uchan := Srv.Store.User().GetForLogin(
@@ -2540,7 +2760,15 @@ func checkMfa(c *Context, w http.ResponseWriter, r *http.Request) {
 	if result := <-uchan; result.Err != nil {
 		rdata["mfa_required"] = "false"
 	} else {
-		rdata["mfa_required"] = strconv.FormatBool(result.Data.(*model.User).MfaActive)
+		user := result.Data.(*model.User)
+
+		credentials, err := GetWebAuthnCredentials(user.Id)
+		if err != nil {
+			c.Err = err
+			return
+		}
+
+		rdata["mfa_required"] = strconv.FormatBool(user.MfaActive || len(credentials) > 0)
 	}
 	w.Write([]byte(model.MapToJson(rdata)))
 }
@@ -2677,7 +2905,8 @@ func userTyping(req *model.WebSocketRequest) (map[string]interface{}, *model.App
 func sanitizeProfile(c *Context, user *model.User) *model.User {
 	options := utils.Cfg.GetSanitizeOptions()
 
-	if HasPermissionToContext(c, model.PERMISSION_MANAGE_SYSTEM) {
+	isSystemAdmin := HasPermissionToContext(c, model.PERMISSION_MANAGE_SYSTEM)
+	if isSystemAdmin {
 		options["email"] = true
 		options["fullname"] = true
 		options["authservice"] = true
@@ -2685,6 +2914,7 @@ func sanitizeProfile(c *Context, user *model.User) *model.User {
 	c.Err = nil
 
 	user.SanitizeProfile(options)
+	sanitizeProfileAttributes(user, isSystemAdmin || user.Id == c.Session.UserId)
 
 	return user
 }
@@ -2701,6 +2931,13 @@ func searchUsers(c *Context, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// guests can only find the users in the channels they belong to
+	if props.InChannelId == "" && c.Session.IsGuest() {
+		c.Err = model.NewLocAppError("searchUsers", "api.user.search_users.guest.app_error", nil, "userId="+c.Session.UserId)
+		c.Err.StatusCode = http.StatusForbidden
+		return
+	}
+
 	if props.InChannelId != "" && !HasPermissionToChannelContext(c, props.InChannelId, model.PERMISSION_READ_CHANNEL) {
 		return
 	}
@@ -2712,7 +2949,8 @@ func searchUsers(c *Context, w http.ResponseWriter, r *http.Request) {
 	searchOptions := map[string]bool{}
 	searchOptions[store.USER_SEARCH_OPTION_ALLOW_INACTIVE] = props.AllowInactive
 
-	if !HasPermissionToContext(c, model.PERMISSION_MANAGE_SYSTEM) {
+	isSystemAdmin := HasPermissionToContext(c, model.PERMISSION_MANAGE_SYSTEM)
+	if !isSystemAdmin {
 		hideFullName := !utils.Cfg.PrivacySettings.ShowFullName
 		hideEmail := !utils.Cfg.PrivacySettings.ShowEmailAddress
 
@@ -2724,9 +2962,13 @@ func searchUsers(c *Context, w http.ResponseWriter, r *http.Request) {
 			searchOptions[store.USER_SEARCH_OPTION_NAMES_ONLY] = true
 		}
 
+		searchOptions[store.USER_SEARCH_OPTION_HIDE_GUESTS] = true
+
 		c.Err = nil
 	}
 
+	addProfileAttributeSearchOptions(searchOptions, isSystemAdmin)
+
 	var uchan store.StoreChannel
 	if props.InChannelId != "" {
 		uchan = Srv.Store.User().SearchInChannel(props.InChannelId, props.Term, searchOptions)
@@ -2800,7 +3042,22 @@ func autocompleteUsersInChannel(c *Context, w http.ResponseWriter, r *http.Reque
 	}
 
 	uchan := Srv.Store.User().SearchInChannel(channelId, term, searchOptions)
-	nuchan := Srv.Store.User().SearchNotInChannel(teamId, channelId, term, searchOptions)
+
+	// guests can't find users outside of their channels, and nobody but a system admin can find guests outside of a channel
+	var nuchan store.StoreChannel
+	if !c.Session.IsGuest() {
+		outOfChannelOptions := map[string]bool{}
+		for option, value := range searchOptions {
+			outOfChannelOptions[option] = value
+		}
+
+		if !HasPermissionToContext(c, model.PERMISSION_MANAGE_SYSTEM) {
+			outOfChannelOptions[store.USER_SEARCH_OPTION_HIDE_GUESTS] = true
+			c.Err = nil
+		}
+
+		nuchan = Srv.Store.User().SearchNotInChannel(teamId, channelId, term, outOfChannelOptions)
+	}
 
 	autocomplete := &model.UserAutocompleteInChannel{}
 
@@ -2817,7 +3074,9 @@ func autocompleteUsersInChannel(c *Context, w http.ResponseWriter, r *http.Reque
 		autocomplete.InChannel = profiles
 	}
 
-	if result := <-nuchan; result.Err != nil {
+	if nuchan == nil {
+		autocomplete.OutOfChannel = []*model.User{}
+	} else if result := <-nuchan; result.Err != nil {
 		c.Err = result.Err
 		return
 	} else {
@@ -2845,6 +3104,12 @@ func autocompleteUsersInTeam(c *Context, w http.ResponseWriter, r *http.Request)
 		}
 	}
 
+	if c.Session.IsGuest() {
+		c.Err = model.NewLocAppError("autocompleteUsersInTeam", "api.user.search_users.guest.app_error", nil, "userId="+c.Session.UserId)
+		c.Err.StatusCode = http.StatusForbidden
+		return
+	}
+
 	searchOptions := map[string]bool{}
 
 	hideFullName := !utils.Cfg.PrivacySettings.ShowFullName
@@ -2855,6 +3120,11 @@ func autocompleteUsersInTeam(c *Context, w http.ResponseWriter, r *http.Request)
 		searchOptions[store.USER_SEARCH_OPTION_NAMES_ONLY] = true
 	}
 
+	if !HasPermissionToContext(c, model.PERMISSION_MANAGE_SYSTEM) {
+		searchOptions[store.USER_SEARCH_OPTION_HIDE_GUESTS] = true
+		c.Err = nil
+	}
+
 	uchan := Srv.Store.User().Search(teamId, term, searchOptions)
 
 	autocomplete := &model.UserAutocompleteInTeam{}
@@ -2878,6 +3148,12 @@ func autocompleteUsersInTeam(c *Context, w http.ResponseWriter, r *http.Request)
 func autocompleteUsers(c *Context, w http.ResponseWriter, r *http.Request) {
 	term := r.URL.Query().Get("term")
 
+	if c.Session.IsGuest() {
+		c.Err = model.NewLocAppError("autocompleteUsers", "api.user.search_users.guest.app_error", nil, "userId="+c.Session.UserId)
+		c.Err.StatusCode = http.StatusForbidden
+		return
+	}
+
 	searchOptions := map[string]bool{}
 
 	hideFullName := !utils.Cfg.PrivacySettings.ShowFullName
@@ -2888,6 +3164,11 @@ func autocompleteUsers(c *Context, w http.ResponseWriter, r *http.Request) {
 		searchOptions[store.USER_SEARCH_OPTION_NAMES_ONLY] = true
 	}
 
+	if !HasPermissionToContext(c, model.PERMISSION_MANAGE_SYSTEM) {
+		searchOptions[store.USER_SEARCH_OPTION_HIDE_GUESTS] = true
+		c.Err = nil
+	}
+
 	uchan := Srv.Store.User().Search("", term, searchOptions)
 
 	var profiles []*model.User
@@ -0,0 +1,384 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api
+
+import (
+	"net/http"
+
+	l4g "github.com/alecthomas/log4go"
+	"github.com/gorilla/mux"
+
+	"github.com/mattermost/platform/einterfaces"
+	"github.com/mattermost/platform/model"
+	"github.com/mattermost/platform/utils"
+)
+
+func InitUserGroup() {
+	l4g.Debug(utils.T("api.user_group.init.debug"))
+
+	BaseRoutes.UserGroups.Handle("/list", ApiUserRequired(getUserGroups)).Methods("GET")
+	BaseRoutes.UserGroups.Handle("/create", ApiAdminSystemRequired(createUserGroup)).Methods("POST")
+	BaseRoutes.NeedUserGroup.Handle("/get", ApiUserRequired(getUserGroup)).Methods("GET")
+	BaseRoutes.NeedUserGroup.Handle("/update", ApiAdminSystemRequired(updateUserGroup)).Methods("POST")
+	BaseRoutes.NeedUserGroup.Handle("/delete", ApiAdminSystemRequired(deleteUserGroup)).Methods("POST")
+	BaseRoutes.NeedUserGroup.Handle("/members", ApiUserRequired(getUserGroupMembers)).Methods("GET")
+	BaseRoutes.NeedUserGroup.Handle("/add_member", ApiUserRequired(addUserGroupMember)).Methods("POST")
+	BaseRoutes.NeedUserGroup.Handle("/remove_member", ApiUserRequired(removeUserGroupMember)).Methods("POST")
+}
+
+func GetUserGroup(id string) (*model.UserGroup, *model.AppError) {
+	if result := <-Srv.Store.UserGroup().Get(id); result.Err != nil {
+		result.Err.StatusCode = http.StatusNotFound
+		return nil, result.Err
+	} else {
+		return result.Data.(*model.UserGroup), nil
+	}
+}
+
+func GetUserGroupByName(name string) (*model.UserGroup, *model.AppError) {
+	if result := <-Srv.Store.UserGroup().GetByName(name); result.Err != nil {
+		result.Err.StatusCode = http.StatusNotFound
+		return nil, result.Err
+	} else {
+		return result.Data.(*model.UserGroup), nil
+	}
+}
+
+func GetUserGroupMemberIds(groupId string) ([]string, *model.AppError) {
+	if result := <-Srv.Store.UserGroup().GetMemberIds(groupId); result.Err != nil {
+		return nil, result.Err
+	} else {
+		return result.Data.([]string), nil
+	}
+}
+
+// checkUserGroup makes sure that the group can be mentioned without also mentioning a user and that the
+// directory group that it's synchronized from is available
+func checkUserGroup(group *model.UserGroup) *model.AppError {
+	if result := <-Srv.Store.User().GetByUsername(group.Name); result.Err == nil {
+		err := model.NewLocAppError("checkUserGroup", "api.user_group.name_taken.app_error", nil, "name="+group.Name)
+		err.StatusCode = http.StatusBadRequest
+		return err
+	}
+
+	if group.IsLdapSynced() && !isLdapGroupSyncAvailable() {
+		return ldapGroupSyncDisabledError("checkUserGroup")
+	}
+
+	return nil
+}
+
+// CreateUserGroup saves a new group. Groups synchronized from a directory group get their members right away.
+func CreateUserGroup(group *model.UserGroup) (*model.UserGroup, *model.AppError) {
+	if err := checkUserGroup(group); err != nil {
+		return nil, err
+	}
+
+	var rgroup *model.UserGroup
+	if result := <-Srv.Store.UserGroup().Save(group); result.Err != nil {
+		result.Err.StatusCode = http.StatusBadRequest
+		return nil, result.Err
+	} else {
+		rgroup = result.Data.(*model.UserGroup)
+	}
+
+	if rgroup.IsLdapSynced() {
+		if err := syncLdapUserGroup(rgroup, nil); err != nil {
+			l4g.Error(utils.T("api.user_group.sync_ldap.error"), err.Error())
+		}
+	}
+
+	return rgroup, nil
+}
+
+func UpdateUserGroup(group *model.UserGroup) (*model.UserGroup, *model.AppError) {
+	oldGroup, err := GetUserGroup(group.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	group.CreatorId = oldGroup.CreatorId
+	group.CreateAt = oldGroup.CreateAt
+
+	if group.Name != oldGroup.Name || group.LdapGroupId != oldGroup.LdapGroupId {
+		if err := checkUserGroup(group); err != nil {
+			return nil, err
+		}
+	}
+
+	var rgroup *model.UserGroup
+	if result := <-Srv.Store.UserGroup().Update(group); result.Err != nil {
+		result.Err.StatusCode = http.StatusBadRequest
+		return nil, result.Err
+	} else {
+		rgroup = result.Data.(*model.UserGroup)
+	}
+
+	if rgroup.IsLdapSynced() && rgroup.LdapGroupId != oldGroup.LdapGroupId {
+		if err := syncLdapUserGroup(rgroup, nil); err != nil {
+			l4g.Error(utils.T("api.user_group.sync_ldap.error"), err.Error())
+		}
+	}
+
+	return rgroup, nil
+}
+
+func DeleteUserGroup(groupId string) *model.AppError {
+	if result := <-Srv.Store.UserGroup().Delete(groupId); result.Err != nil {
+		result.Err.StatusCode = http.StatusBadRequest
+		return result.Err
+	}
+
+	return nil
+}
+
+// AddUserGroupMember adds a user to a group. Members of groups synchronized from LDAP can't be changed by hand.
+func AddUserGroupMember(group *model.UserGroup, userId string) *model.AppError {
+	if group.IsLdapSynced() {
+		err := model.NewLocAppError("AddUserGroupMember", "api.user_group.ldap_synced.app_error", nil, "group_id="+group.Id)
+		err.StatusCode = http.StatusBadRequest
+		return err
+	}
+
+	if result := <-Srv.Store.User().Get(userId); result.Err != nil {
+		result.Err.StatusCode = http.StatusBadRequest
+		return result.Err
+	}
+
+	if result := <-Srv.Store.UserGroup().SaveMember(&model.UserGroupMember{GroupId: group.Id, UserId: userId}); result.Err != nil {
+		result.Err.StatusCode = http.StatusBadRequest
+		return result.Err
+	}
+
+	return nil
+}
+
+func RemoveUserGroupMember(group *model.UserGroup, userId string) *model.AppError {
+	if group.IsLdapSynced() {
+		err := model.NewLocAppError("RemoveUserGroupMember", "api.user_group.ldap_synced.app_error", nil, "group_id="+group.Id)
+		err.StatusCode = http.StatusBadRequest
+		return err
+	}
+
+	if result := <-Srv.Store.UserGroup().RemoveMember(group.Id, userId); result.Err != nil {
+		return result.Err
+	}
+
+	return nil
+}
+
+// SyncLdapUserGroups makes the members of every group synchronized from LDAP match the users in its directory group
+func SyncLdapUserGroups() *model.AppError {
+	if !isLdapGroupSyncAvailable() {
+		return ldapGroupSyncDisabledError("SyncLdapUserGroups")
+	}
+
+	var groups []*model.UserGroup
+	if result := <-Srv.Store.UserGroup().GetAll(); result.Err != nil {
+		return result.Err
+	} else {
+		groups = result.Data.([]*model.UserGroup)
+	}
+
+	usersByLdapId, err := getUsersByLdapId()
+	if err != nil {
+		return err
+	}
+
+	for _, group := range groups {
+		if group.IsLdapSynced() {
+			if err := syncLdapUserGroup(group, usersByLdapId); err != nil {
+				l4g.Error(utils.T("api.user_group.sync_ldap.error"), err.Error())
+			}
+		}
+	}
+
+	return nil
+}
+
+// syncLdapUserGroup replaces the members of the group with the users in its directory group. The LDAP users are
+// loaded when usersByLdapId is nil.
+func syncLdapUserGroup(group *model.UserGroup, usersByLdapId map[string]*model.User) *model.AppError {
+	if !isLdapGroupSyncAvailable() {
+		return ldapGroupSyncDisabledError("syncLdapUserGroup")
+	}
+
+	if usersByLdapId == nil {
+		var err *model.AppError
+		if usersByLdapId, err = getUsersByLdapId(); err != nil {
+			return err
+		}
+	}
+
+	ldapIds, err := einterfaces.GetLdapInterface().GetGroupMemberIds(group.LdapGroupId)
+	if err != nil {
+		return err
+	}
+
+	wanted := make(map[string]bool)
+	for _, ldapId := range ldapIds {
+		if user := usersByLdapId[ldapId]; user != nil && user.DeleteAt == 0 {
+			wanted[user.Id] = true
+		}
+	}
+
+	current, err := GetUserGroupMemberIds(group.Id)
+	if err != nil {
+		return err
+	}
+
+	for _, userId := range current {
+		if wanted[userId] {
+			delete(wanted, userId)
+		} else if result := <-Srv.Store.UserGroup().RemoveMember(group.Id, userId); result.Err != nil {
+			return result.Err
+		}
+	}
+
+	for userId := range wanted {
+		if result := <-Srv.Store.UserGroup().SaveMember(&model.UserGroupMember{GroupId: group.Id, UserId: userId}); result.Err != nil {
+			return result.Err
+		}
+	}
+
+	return nil
+}
+
+// canManageUserGroupMembers returns whether the user of the session can add and remove members of the group
+func canManageUserGroupMembers(c *Context, group *model.UserGroup) bool {
+	if CheckIfRolesGrantPermission(c.Session.GetUserRoles(), model.PERMISSION_MANAGE_SYSTEM.Id) {
+		return true
+	}
+
+	if !group.MemberManaged {
+		return false
+	}
+
+	if ids, err := GetUserGroupMemberIds(group.Id); err == nil {
+		for _, id := range ids {
+			if id == c.Session.UserId {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func getUserGroups(c *Context, w http.ResponseWriter, r *http.Request) {
+	if result := <-Srv.Store.UserGroup().GetAll(); result.Err != nil {
+		c.Err = result.Err
+	} else {
+		w.Write([]byte(model.UserGroupsToJson(result.Data.([]*model.UserGroup))))
+	}
+}
+
+func createUserGroup(c *Context, w http.ResponseWriter, r *http.Request) {
+	group := model.UserGroupFromJson(r.Body)
+	if group == nil {
+		c.SetInvalidParam("createUserGroup", "group")
+		return
+	}
+
+	group.Id = ""
+	group.CreatorId = c.Session.UserId
+
+	if rgroup, err := CreateUserGroup(group); err != nil {
+		c.Err = err
+	} else {
+		c.LogAudit("name=" + rgroup.Name)
+		w.Write([]byte(rgroup.ToJson()))
+	}
+}
+
+func getUserGroup(c *Context, w http.ResponseWriter, r *http.Request) {
+	if group, err := GetUserGroup(mux.Vars(r)["group_id"]); err != nil {
+		c.Err = err
+	} else {
+		w.Write([]byte(group.ToJson()))
+	}
+}
+
+func updateUserGroup(c *Context, w http.ResponseWriter, r *http.Request) {
+	group := model.UserGroupFromJson(r.Body)
+	if group == nil {
+		c.SetInvalidParam("updateUserGroup", "group")
+		return
+	}
+
+	group.Id = mux.Vars(r)["group_id"]
+
+	if rgroup, err := UpdateUserGroup(group); err != nil {
+		c.Err = err
+	} else {
+		c.LogAudit("name=" + rgroup.Name)
+		w.Write([]byte(rgroup.ToJson()))
+	}
+}
+
+func deleteUserGroup(c *Context, w http.ResponseWriter, r *http.Request) {
+	groupId := mux.Vars(r)["group_id"]
+
+	if err := DeleteUserGroup(groupId); err != nil {
+		c.Err = err
+		return
+	}
+
+	c.LogAudit("group_id=" + groupId)
+
+	ReturnStatusOK(w)
+}
+
+func getUserGroupMembers(c *Context, w http.ResponseWriter, r *http.Request) {
+	if ids, err := GetUserGroupMemberIds(mux.Vars(r)["group_id"]); err != nil {
+		c.Err = err
+	} else {
+		w.Write([]byte(model.ArrayToJson(ids)))
+	}
+}
+
+func addUserGroupMember(c *Context, w http.ResponseWriter, r *http.Request) {
+	changeUserGroupMember(c, w, r, true)
+}
+
+func removeUserGroupMember(c *Context, w http.ResponseWriter, r *http.Request) {
+	changeUserGroupMember(c, w, r, false)
+}
+
+func changeUserGroupMember(c *Context, w http.ResponseWriter, r *http.Request, add bool) {
+	props := model.MapFromJson(r.Body)
+
+	userId := props["user_id"]
+	if len(userId) != 26 {
+		c.SetInvalidParam("changeUserGroupMember", "user_id")
+		return
+	}
+
+	group, err := GetUserGroup(mux.Vars(r)["group_id"])
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	if !canManageUserGroupMembers(c, group) {
+		c.Err = model.NewLocAppError("changeUserGroupMember", "api.user_group.manage_members.permissions.app_error", nil, "group_id="+group.Id)
+		c.Err.StatusCode = http.StatusForbidden
+		return
+	}
+
+	if add {
+		err = AddUserGroupMember(group, userId)
+	} else {
+		err = RemoveUserGroupMember(group, userId)
+	}
+
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	c.LogAudit("group_id=" + group.Id + " user_id=" + userId)
+
+	ReturnStatusOK(w)
+}
@@ -0,0 +1,141 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api
+
+import (
+	"testing"
+
+	"github.com/mattermost/platform/model"
+	"github.com/mattermost/platform/utils"
+)
+
+func TestUserGroups(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	Client := th.BasicClient
+	AdminClient := th.SystemAdminClient
+
+	name := "sre" + model.NewId()[:8]
+
+	if _, err := Client.CreateUserGroup(&model.UserGroup{Name: name, DisplayName: "SRE"}); err == nil {
+		t.Fatal("should have failed without permissions")
+	}
+
+	if _, err := AdminClient.CreateUserGroup(&model.UserGroup{Name: th.BasicUser2.Username, DisplayName: "Taken"}); err == nil {
+		t.Fatal("should have failed with the name of a user")
+	}
+
+	group := AdminClient.Must(AdminClient.CreateUserGroup(&model.UserGroup{Name: name, DisplayName: "SRE"})).Data.(*model.UserGroup)
+	defer AdminClient.DeleteUserGroup(group.Id)
+
+	if group.CreatorId != th.SystemAdminUser.Id {
+		t.Fatal("should have set the creator")
+	}
+
+	found := false
+	for _, g := range Client.Must(Client.GetUserGroups()).Data.([]*model.UserGroup) {
+		if g.Id == group.Id {
+			found = true
+		}
+	}
+
+	if !found {
+		t.Fatal("should have listed the group")
+	}
+
+	if rgroup := Client.Must(Client.GetUserGroup(group.Id)).Data.(*model.UserGroup); rgroup.Name != name {
+		t.Fatal("should have returned the group")
+	}
+
+	if _, err := Client.AddUserGroupMember(group.Id, th.BasicUser.Id); err == nil {
+		t.Fatal("should have failed to add a member to an admin managed group")
+	}
+
+	AdminClient.Must(AdminClient.AddUserGroupMember(group.Id, th.BasicUser.Id))
+
+	if _, err := AdminClient.AddUserGroupMember(group.Id, th.BasicUser.Id); err == nil {
+		t.Fatal("should have failed to add the same member twice")
+	}
+
+	if _, err := Client.AddUserGroupMember(group.Id, th.BasicUser2.Id); err == nil {
+		t.Fatal("should have failed for a member of an admin managed group")
+	}
+
+	group.MemberManaged = true
+	group.Description = "Site reliability engineers"
+	if rgroup := AdminClient.Must(AdminClient.UpdateUserGroup(group)).Data.(*model.UserGroup); !rgroup.MemberManaged || rgroup.Description != group.Description {
+		t.Fatal("should have updated the group")
+	}
+
+	Client.Must(Client.AddUserGroupMember(group.Id, th.BasicUser2.Id))
+
+	if ids := Client.Must(Client.GetUserGroupMembers(group.Id)).Data.([]string); len(ids) != 2 {
+		t.Fatal("should have returned both members", ids)
+	}
+
+	Client.Must(Client.RemoveUserGroupMember(group.Id, th.BasicUser2.Id))
+
+	if ids := Client.Must(Client.GetUserGroupMembers(group.Id)).Data.([]string); len(ids) != 1 || ids[0] != th.BasicUser.Id {
+		t.Fatal("should have removed the member", ids)
+	}
+
+	if _, err := Client.DeleteUserGroup(group.Id); err == nil {
+		t.Fatal("should have failed to delete without permissions")
+	}
+
+	AdminClient.Must(AdminClient.DeleteUserGroup(group.Id))
+
+	if _, err := Client.GetUserGroup(group.Id); err == nil {
+		t.Fatal("should have deleted the group")
+	}
+}
+
+func TestSendNotificationsToUserGroup(t *testing.T) {
+	th := Setup().InitBasic()
+	Client := th.BasicClient
+
+	// BasicUser2 is on the team but not in the channel
+	group, err := CreateUserGroup(&model.UserGroup{Name: "eng" + model.NewId()[:8], DisplayName: "Engineering"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer DeleteUserGroup(group.Id)
+
+	user3 := th.CreateUser(Client)
+	LinkUserToTeam(user3, th.BasicTeam)
+	AddUserToChannel(user3, th.BasicChannel)
+
+	for _, user := range []*model.User{th.BasicUser, th.BasicUser2, user3} {
+		if err := AddUserGroupMember(group, user.Id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	service := newNotificationService(sqlNotificationStore{Srv.Store}, getNotificationChannels())
+
+	if groups := service.getMentionedUserGroups("hey @" + group.Name + ", take a look"); len(groups[group.Name]) != 3 {
+		t.Fatal("should have found the group and its members", groups)
+	}
+
+	if usernames := service.getUserGroupMentionsNotInChannel(map[string][]string{group.Name: {th.BasicUser.Id, th.BasicUser2.Id}}, map[string]*model.User{th.BasicUser.Id: th.BasicUser}); len(usernames) != 1 || usernames[0] != th.BasicUser2.Username {
+		t.Fatal("should have returned the member outside of the channel", usernames)
+	}
+
+	post := Client.Must(Client.CreatePost(&model.Post{
+		ChannelId: th.BasicChannel.Id,
+		Message:   "hey @" + group.Name,
+	})).Data.(*model.Post)
+
+	context := &Context{
+		Session:   model.Session{UserId: th.BasicUser.Id},
+		RequestId: model.NewId(),
+		siteURL:   *utils.Cfg.ServiceSettings.SiteURL,
+		TeamId:    th.BasicTeam.Id,
+		T:         utils.TfuncWithFallback(model.DEFAULT_LOCALE),
+	}
+
+	mentions := sendNotifications(context, post, th.BasicTeam, th.BasicChannel)
+	if len(mentions) != 1 || mentions[0] != user3.Id {
+		t.Fatal("should have only mentioned the other group member in the channel", mentions)
+	}
+}
@@ -5,6 +5,11 @@ package api
 
 import (
 	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
 	"fmt"
 	"image"
 	"image/color"
@@ -329,6 +334,148 @@ func TestPasswordGuessLockout(t *testing.T) {
 	}
 }
 
+func TestPasswordGuessLockoutExpires(t *testing.T) {
+	th := Setup().InitBasic()
+	Client := th.BasicClient
+	user := th.BasicUser
+	Client.Must(Client.Logout())
+
+	passwordAttempts := utils.Cfg.ServiceSettings.MaximumLoginAttempts
+	lockoutDuration := *utils.Cfg.ServiceSettings.LoginLockoutDurationInMinutes
+	defer func() {
+		utils.Cfg.ServiceSettings.MaximumLoginAttempts = passwordAttempts
+		*utils.Cfg.ServiceSettings.LoginLockoutDurationInMinutes = lockoutDuration
+	}()
+	utils.Cfg.ServiceSettings.MaximumLoginAttempts = 2
+	*utils.Cfg.ServiceSettings.LoginLockoutDurationInMinutes = 5
+
+	Client.Login(user.Email, "notthepassword")
+	Client.Login(user.Email, "notthepassword")
+
+	if _, err := Client.Login(user.Email, user.Password); err == nil {
+		t.Fatal("Shouldn't be able to login with password when account is locked out.")
+	}
+
+	if result := <-Srv.Store.Audit().Get(user.Id, 50); result.Err != nil {
+		t.Fatal(result.Err)
+	} else {
+		found := false
+		for _, audit := range result.Data.(model.Audits) {
+			if audit.Action == "lockout" {
+				found = true
+			}
+		}
+
+		if !found {
+			t.Fatal("should've audited the lockout")
+		}
+	}
+
+	// pretend the lockout started before the duration
+	Srv.Store.(*store.SqlStore).GetMaster().Exec("UPDATE Users SET LastFailedAttempt = :LastFailedAttempt WHERE Id = :UserId",
+		map[string]interface{}{"LastFailedAttempt": model.GetMillis() - 6*60*1000, "UserId": user.Id})
+
+	if _, err := Client.Login(user.Email, user.Password); err != nil {
+		t.Fatal("should be able to login once the lockout has expired", err)
+	}
+}
+
+func TestLoginRateLimit(t *testing.T) {
+	th := Setup().InitBasic()
+	Client := th.BasicClient
+	user := th.BasicUser
+	Client.Must(Client.Logout())
+
+	perLoginId := *utils.Cfg.ServiceSettings.LoginAttemptsPerMinutePerLoginId
+	defer func() {
+		*utils.Cfg.ServiceSettings.LoginAttemptsPerMinutePerLoginId = perLoginId
+	}()
+	*utils.Cfg.ServiceSettings.LoginAttemptsPerMinutePerLoginId = 2
+
+	Client.Login(user.Email, "notthepassword")
+	Client.Login(user.Email, "notthepassword")
+
+	if _, err := Client.Login(strings.ToUpper(user.Email), user.Password); err == nil {
+		t.Fatal("should've been throttled")
+	} else if err.StatusCode != http.StatusTooManyRequests {
+		t.Fatal("wrong status code", err.StatusCode)
+	}
+
+	if _, err := Client.Login(th.BasicUser2.Email, th.BasicUser2.Password); err != nil {
+		t.Fatal("other login ids shouldn't be throttled", err)
+	}
+}
+
+func TestPasswordHistory(t *testing.T) {
+	th := Setup().InitBasic()
+	Client := th.BasicClient
+	user := th.BasicUser
+
+	historyCount := *utils.Cfg.PasswordSettings.HistoryCount
+	defer func() {
+		*utils.Cfg.PasswordSettings.HistoryCount = historyCount
+	}()
+	*utils.Cfg.PasswordSettings.HistoryCount = 2
+
+	if _, err := Client.UpdateUserPassword(user.Id, user.Password, user.Password); err == nil {
+		t.Fatal("shouldn't be able to reuse the current password")
+	}
+
+	if _, err := Client.UpdateUserPassword(user.Id, user.Password, "newpwd1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Client.UpdateUserPassword(user.Id, "newpwd1", user.Password); err == nil {
+		t.Fatal("shouldn't be able to reuse the previous password")
+	} else if err.Id != "api.user.check_password_history.reused.app_error" {
+		t.Fatal("wrong error", err.Id)
+	}
+
+	if _, err := Client.UpdateUserPassword(user.Id, "newpwd1", "newpwd2"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Client.UpdateUserPassword(user.Id, "newpwd2", user.Password); err != nil {
+		t.Fatal("should be able to reuse a password that is no longer in the history", err)
+	}
+}
+
+func TestPasswordExpiry(t *testing.T) {
+	th := Setup().InitBasic()
+	Client := th.BasicClient
+	user := th.BasicUser
+
+	maxAge := *utils.Cfg.PasswordSettings.MaximumAgeInDays
+	defer func() {
+		*utils.Cfg.PasswordSettings.MaximumAgeInDays = maxAge
+	}()
+	*utils.Cfg.PasswordSettings.MaximumAgeInDays = 30
+
+	Srv.Store.(*store.SqlStore).GetMaster().Exec("UPDATE Users SET LastPasswordUpdate = :LastPasswordUpdate WHERE Id = :UserId",
+		map[string]interface{}{"LastPasswordUpdate": model.GetMillis() - 31*24*60*60*1000, "UserId": user.Id})
+
+	if _, err := Client.GetMe(""); err != nil {
+		t.Fatal("sessions from before the password expired should keep working", err)
+	}
+
+	Client.Logout()
+	Client.Must(Client.Login(user.Email, user.Password))
+
+	if _, err := Client.GetMe(""); err == nil {
+		t.Fatal("should've been forced to change the password")
+	} else if err.Id != "api.context.password_expired.app_error" {
+		t.Fatal("wrong error", err.Id)
+	}
+
+	if _, err := Client.UpdateUserPassword(user.Id, user.Password, "newpwd1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Client.GetMe(""); err != nil {
+		t.Fatal("should work once the password has been changed", err)
+	}
+}
+
 func TestSessions(t *testing.T) {
 	th := Setup().InitBasic()
 	Client := th.BasicClient
@@ -867,12 +1014,21 @@ func TestUserUpdate(t *testing.T) {
 	user.Roles = model.ROLE_SYSTEM_ADMIN.Id
 	user.LastPasswordUpdate = 123
 
+	emailPrivacy := utils.Cfg.PrivacySettings.ShowEmailAddress
+	defer func() {
+		utils.Cfg.PrivacySettings.ShowEmailAddress = emailPrivacy
+	}()
+	utils.Cfg.PrivacySettings.ShowEmailAddress = false
+
 	if result, err := Client.UpdateUser(user); err != nil {
 		t.Fatal(err)
 	} else {
 		if result.Data.(*model.User).Nickname != "Jim Jimmy" {
 			t.Fatal("Nickname did not update properly")
 		}
+		if result.Data.(*model.User).Email != user.Email {
+			t.Fatal("Should have returned the user's own email")
+		}
 		if result.Data.(*model.User).Roles != model.ROLE_SYSTEM_USER.Id {
 			t.Fatal("Roles should not have updated")
 		}
@@ -1127,6 +1283,130 @@ func TestUserUpdateDeviceId(t *testing.T) {
 			t.Fatal("Missing device Id")
 		}
 	}
+
+	if _, err := Client.AttachDeviceIdWithKey(deviceId, "junk"); err == nil {
+		t.Fatal("should have failed with an invalid device key")
+	}
+
+	deviceKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	der, _ := x509.MarshalPKIXPublicKey(&deviceKey.PublicKey)
+	encodedKey := base64.StdEncoding.EncodeToString(der)
+
+	if _, err := Client.AttachDeviceIdWithKey(deviceId, encodedKey); err != nil {
+		t.Fatal(err)
+	}
+
+	if result := <-Srv.Store.Session().GetSessions(user.Id); result.Err != nil {
+		t.Fatal(result.Err)
+	} else if sessions := result.Data.([]*model.Session); sessions[0].DeviceKey != encodedKey {
+		t.Fatal("Missing device key")
+	}
+}
+
+func TestRevokeAllOtherSessions(t *testing.T) {
+	th := Setup().InitBasic()
+	Client := th.BasicClient
+
+	Client2 := th.CreateClient()
+	Client2.Must(Client2.Login(th.BasicUser.Email, th.BasicUser.Password))
+
+	if _, err := Client.RevokeAllOtherSessions(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Client.GetMe(""); err != nil {
+		t.Fatal("should still be logged in")
+	}
+
+	if _, err := Client2.GetMe(""); err == nil {
+		t.Fatal("should've been logged out")
+	}
+
+	if sessions := Client.Must(Client.GetSessions(th.BasicUser.Id)).Data.([]*model.Session); len(sessions) != 1 {
+		t.Fatal("should only have the current session left")
+	}
+}
+
+func TestMaximumSessionsPerUser(t *testing.T) {
+	th := Setup().InitBasic()
+
+	maxSessions := *utils.Cfg.ServiceSettings.MaximumSessionsPerUser
+	defer func() {
+		*utils.Cfg.ServiceSettings.MaximumSessionsPerUser = maxSessions
+	}()
+	*utils.Cfg.ServiceSettings.MaximumSessionsPerUser = 2
+
+	th.BasicClient.Must(th.BasicClient.RevokeAllOtherSessions())
+
+	Client2 := th.CreateClient()
+	Client2.Must(Client2.Login(th.BasicUser.Email, th.BasicUser.Password))
+
+	Client3 := th.CreateClient()
+	Client3.Must(Client3.Login(th.BasicUser.Email, th.BasicUser.Password))
+
+	if _, err := th.BasicClient.GetMe(""); err == nil {
+		t.Fatal("the oldest session should've been revoked")
+	}
+
+	if _, err := Client2.GetMe(""); err != nil {
+		t.Fatal(err)
+	}
+
+	sessions := Client3.Must(Client3.GetSessions(th.BasicUser.Id)).Data.([]*model.Session)
+	if len(sessions) != 2 {
+		t.Fatal("should have two sessions")
+	}
+
+	for _, session := range sessions {
+		if len(session.Props[model.SESSION_PROP_USER_AGENT]) == 0 || len(session.Props[model.SESSION_PROP_IP_ADDRESS]) == 0 {
+			t.Fatal("should've recorded the device", session.Props)
+		}
+	}
+}
+
+func TestSessionIdleTimeout(t *testing.T) {
+	th := Setup().InitBasic()
+	Client := th.BasicClient
+
+	idleTimeout := *utils.Cfg.ServiceSettings.SessionIdleTimeoutInMinutes
+	defer func() {
+		*utils.Cfg.ServiceSettings.SessionIdleTimeoutInMinutes = idleTimeout
+	}()
+	*utils.Cfg.ServiceSettings.SessionIdleTimeoutInMinutes = 10
+
+	if _, err := Client.GetMe(""); err != nil {
+		t.Fatal(err)
+	}
+
+	session := GetSession(Client.AuthToken)
+	lastActivityAt := model.GetMillis() - 5*60*1000
+	store.Must(Srv.Store.Session().UpdateLastActivityAt(session.Id, lastActivityAt))
+	RemoveAllSessionsForUserId(th.BasicUser.Id)
+
+	if _, err := Client.GetMe(""); err != nil {
+		t.Fatal(err)
+	}
+
+	if rsession := store.Must(Srv.Store.Session().Get(session.Id)).(*model.Session); rsession.LastActivityAt != lastActivityAt {
+		t.Fatal("background requests shouldn't have counted as activity")
+	}
+
+	th.CreatePost(Client, th.BasicChannel)
+
+	if rsession := store.Must(Srv.Store.Session().Get(session.Id)).(*model.Session); rsession.LastActivityAt <= lastActivityAt {
+		t.Fatal("creating a post should have counted as activity")
+	}
+
+	store.Must(Srv.Store.Session().UpdateLastActivityAt(session.Id, model.GetMillis()-11*60*1000))
+	RemoveAllSessionsForUserId(th.BasicUser.Id)
+
+	if _, err := Client.GetMe(""); err == nil {
+		t.Fatal("should've timed out")
+	}
+
+	if result := <-Srv.Store.Session().Get(session.Id); result.Err == nil {
+		t.Fatal("idle session should've been revoked")
+	}
 }
 
 func TestUserUpdateActive(t *testing.T) {
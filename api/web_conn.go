@@ -33,6 +33,7 @@ type WebConn struct {
 	Locale                    string
 	AllChannelMembers         map[string]string
 	LastAllChannelMembersTime int64
+	checkAuthentication       chan bool
 }
 
 func NewWebConn(c *Context, ws *websocket.Conn) *WebConn {
@@ -48,6 +49,8 @@ func NewWebConn(c *Context, ws *websocket.Conn) *WebConn {
 		SessionExpiresAt: c.Session.ExpiresAt,
 		T:                c.T,
 		Locale:           c.Locale,
+
+		checkAuthentication: make(chan bool, 1),
 	}
 }
 
@@ -133,6 +136,13 @@ func (c *WebConn) writePump() {
 				return
 			}
 
+		case <-c.checkAuthentication:
+			// disconnect clients whose session was revoked so they have to log in again
+			if !c.isAuthenticated() {
+				l4g.Debug(fmt.Sprintf("websocket.checkAuthentication: session is no longer valid, closing websocket for userId=%v", c.UserId))
+				return
+			}
+
 		case <-authTicker.C:
 			if c.SessionToken == "" {
 				l4g.Debug(fmt.Sprintf("websocket.authTicker: did not authenticate ip=%v", c.WebSocket.RemoteAddr()))
@@ -149,6 +159,16 @@ func (webCon *WebConn) InvalidateCache() {
 	webCon.SessionExpiresAt = 0
 }
 
+// CheckAuthentication has the connection recheck its session from its own goroutine so that the hub doesn't
+// have to wait on the database
+func (webCon *WebConn) CheckAuthentication() {
+	select {
+	case webCon.checkAuthentication <- true:
+	default:
+		// a check is already pending
+	}
+}
+
 func (webCon *WebConn) isAuthenticated() bool {
 	// Check the expiry to see if we need to check for a new session
 	if webCon.SessionExpiresAt < model.GetMillis() {
@@ -157,7 +177,7 @@ func (webCon *WebConn) isAuthenticated() bool {
 		}
 
 		session := GetSession(webCon.SessionToken)
-		if session == nil || session.IsExpired() {
+		if session == nil || session.IsExpired() || IsSessionIdle(session) {
 			webCon.SessionToken = ""
 			webCon.SessionExpiresAt = 0
 			return false
@@ -165,6 +185,13 @@ func (webCon *WebConn) isAuthenticated() bool {
 
 		webCon.SessionToken = session.Token
 		webCon.SessionExpiresAt = session.ExpiresAt
+
+		// check again once the session could have gone idle
+		if timeout := *utils.Cfg.ServiceSettings.SessionIdleTimeoutInMinutes; timeout > 0 && !session.IsMobileApp() && !session.IsOAuth {
+			if idleAt := session.LastActivityAt + int64(timeout)*60*1000; webCon.SessionExpiresAt <= 0 || idleAt < webCon.SessionExpiresAt {
+				webCon.SessionExpiresAt = idleAt
+			}
+		}
 	}
 
 	return true
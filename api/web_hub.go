@@ -211,6 +211,7 @@ func (h *Hub) Start() {
 				for webCon := range h.connections {
 					if webCon.UserId == userId {
 						webCon.InvalidateCache()
+						webCon.CheckAuthentication()
 					}
 				}
 
@@ -0,0 +1,359 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+
+	l4g "github.com/alecthomas/log4go"
+	"github.com/gorilla/mux"
+
+	"github.com/mattermost/platform/einterfaces"
+	"github.com/mattermost/platform/model"
+	"github.com/mattermost/platform/utils"
+)
+
+func InitWebAuthn() {
+	l4g.Debug(utils.T("api.webauthn.init.debug"))
+
+	BaseRoutes.Users.Handle("/webauthn/login/begin", ApiAppHandler(beginWebAuthnLogin)).Methods("POST")
+
+	// These only require a user and not MFA so that users can set up a second factor when it's being enforced
+	BaseRoutes.Users.Handle("/webauthn/register/begin", ApiUserRequiredMfa(beginWebAuthnRegistration)).Methods("POST")
+	BaseRoutes.Users.Handle("/webauthn/register/finish", ApiUserRequiredMfa(finishWebAuthnRegistration)).Methods("POST")
+	BaseRoutes.Users.Handle("/webauthn/credentials", ApiUserRequiredMfa(getWebAuthnCredentials)).Methods("GET")
+	BaseRoutes.Users.Handle("/webauthn/credentials/{credential_id:[A-Za-z0-9]+}/delete", ApiUserRequiredMfa(deleteWebAuthnCredential)).Methods("POST")
+	BaseRoutes.Users.Handle("/mfa/recovery_codes", ApiUserRequiredMfa(generateMfaRecoveryCodes)).Methods("POST")
+}
+
+func isMfaAvailable() bool {
+	return utils.IsLicensed && *utils.License.Features.MFA && *utils.Cfg.ServiceSettings.EnableMultifactorAuthentication
+}
+
+// isSecurityKeyRequired returns true if TOTP alone isn't enough of a second factor for the user
+func isSecurityKeyRequired(user *model.User) bool {
+	return *utils.Cfg.ServiceSettings.EnforceSecurityKeysForAdmins && user.IsInRole(model.ROLE_SYSTEM_ADMIN.Id)
+}
+
+func getWebAuthnChallengeKey() string {
+	return *utils.Cfg.ServiceSettings.WebAuthnChallengeSalt
+}
+
+// issueWebAuthnChallenge saves a new challenge so that it can be consumed once it's answered and returns it encoded
+func issueWebAuthnChallenge(purpose, userId, siteURL string) (string, *model.AppError) {
+	challenge := model.NewWebAuthnChallenge(purpose, userId, siteURL)
+
+	if result := <-Srv.Store.WebAuthn().SaveChallenge(challenge); result.Err != nil {
+		return "", result.Err
+	}
+
+	return challenge.Encode(getWebAuthnChallengeKey()), nil
+}
+
+// consumeWebAuthnChallenge makes sure that the challenge answered by the client data was issued by us and hasn't
+// been answered before so that a signed response can't be replayed
+func consumeWebAuthnChallenge(clientDataJSON string) error {
+	challenge, err := model.DecodeWebAuthnClientDataChallenge(clientDataJSON, getWebAuthnChallengeKey())
+	if err != nil {
+		return err
+	}
+
+	if result := <-Srv.Store.WebAuthn().ConsumeChallenge(challenge.Nonce, model.GetMillis()); result.Err != nil {
+		return result.Err
+	} else if !result.Data.(bool) {
+		return errors.New("webauthn: challenge already used")
+	}
+
+	return nil
+}
+
+func GetWebAuthnCredentials(userId string) ([]*model.WebAuthnCredential, *model.AppError) {
+	if result := <-Srv.Store.WebAuthn().GetForUser(userId); result.Err != nil {
+		return nil, result.Err
+	} else {
+		return result.Data.([]*model.WebAuthnCredential), nil
+	}
+}
+
+// NewWebAuthnLoginOptions returns the options a browser needs to sign a login challenge with one of the user's security keys
+func NewWebAuthnLoginOptions(user *model.User, credentials []*model.WebAuthnCredential, siteURL string) (*model.WebAuthnOptions, *model.AppError) {
+	challenge, err := issueWebAuthnChallenge(model.WEBAUTHN_CHALLENGE_LOGIN, user.Id, siteURL)
+	if err != nil {
+		return nil, err
+	}
+
+	options := &model.WebAuthnOptions{
+		Challenge:   challenge,
+		RpId:        model.WebAuthnRpIdFromOrigin(siteURL),
+		Credentials: make([]string, 0, len(credentials)),
+		Timeout:     model.WEBAUTHN_CHALLENGE_TIMEOUT,
+	}
+
+	for _, credential := range credentials {
+		options.Credentials = append(options.Credentials, credential.CredentialId)
+	}
+
+	return options, nil
+}
+
+func checkWebAuthnAssertion(user *model.User, credentials []*model.WebAuthnCredential, assertion *model.WebAuthnAssertion) *model.AppError {
+	for _, credential := range credentials {
+		if credential.CredentialId != assertion.CredentialId {
+			continue
+		}
+
+		signCount, err := assertion.Verify(getWebAuthnChallengeKey(), user.Id, credential)
+		if err == nil {
+			err = consumeWebAuthnChallenge(assertion.ClientDataJSON)
+		}
+
+		if err != nil {
+			return model.NewLocAppError("checkWebAuthnAssertion", "api.user.check_user_mfa.bad_assertion.app_error", nil, "user_id="+user.Id+", "+err.Error())
+		}
+
+		if result := <-Srv.Store.WebAuthn().UpdateSignCount(credential.Id, signCount, model.GetMillis()); result.Err != nil {
+			return result.Err
+		}
+
+		return nil
+	}
+
+	return model.NewLocAppError("checkWebAuthnAssertion", "api.user.check_user_mfa.bad_assertion.app_error", nil, "user_id="+user.Id+", unknown credential")
+}
+
+// beginWebAuthnLogin returns a login challenge for the user's security keys. Since the options list the ids of the
+// user's keys, the password has to be checked first.
+func beginWebAuthnLogin(c *Context, w http.ResponseWriter, r *http.Request) {
+	if !isMfaAvailable() {
+		c.Err = model.NewLocAppError("beginWebAuthnLogin", "api.webauthn.not_available.app_error", nil, "")
+		c.Err.StatusCode = http.StatusNotImplemented
+		return
+	}
+
+	props := model.MapFromJson(r.Body)
+
+	loginId := props["login_id"]
+	if len(loginId) == 0 {
+		c.SetInvalidParam("beginWebAuthnLogin", "login_id")
+		return
+	}
+
+	password := props["password"]
+	if len(password) == 0 {
+		c.SetInvalidParam("beginWebAuthnLogin", "password")
+		return
+	}
+
+	if !checkLoginRateLimit(c, w, loginId) {
+		return
+	}
+
+	user, err := getUserForLogin(loginId, false)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	if err := checkUserLoginAttempts(user); err != nil {
+		c.Err = err
+		c.Err.StatusCode = http.StatusUnauthorized
+		return
+	}
+
+	if user.AuthService == model.USER_AUTH_SERVICE_LDAP {
+		if ldapInterface := einterfaces.GetLdapInterface(); ldapInterface == nil || user.AuthData == nil {
+			c.Err = model.NewLocAppError("beginWebAuthnLogin", "api.user.login_ldap.not_available.app_error", nil, "")
+			c.Err.StatusCode = http.StatusNotImplemented
+			return
+		} else if err := ldapInterface.CheckPassword(*user.AuthData, password); err != nil {
+			c.Err = err
+			c.Err.StatusCode = http.StatusUnauthorized
+			return
+		}
+	} else if err := checkUserPassword(user, password); err != nil {
+		c.Err = err
+		c.Err.StatusCode = http.StatusUnauthorized
+		return
+	}
+
+	credentials, err := GetWebAuthnCredentials(user.Id)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	if len(credentials) == 0 {
+		c.Err = model.NewLocAppError("beginWebAuthnLogin", "api.webauthn.login.no_credentials.app_error", nil, "user_id="+user.Id)
+		c.Err.StatusCode = http.StatusBadRequest
+		return
+	}
+
+	if options, err := NewWebAuthnLoginOptions(user, credentials, c.GetSiteURL()); err != nil {
+		c.Err = err
+		return
+	} else {
+		w.Write([]byte(options.ToJson()))
+	}
+}
+
+func beginWebAuthnRegistration(c *Context, w http.ResponseWriter, r *http.Request) {
+	if !isMfaAvailable() {
+		c.Err = model.NewLocAppError("beginWebAuthnRegistration", "api.webauthn.not_available.app_error", nil, "")
+		c.Err.StatusCode = http.StatusNotImplemented
+		return
+	}
+
+	var user *model.User
+	if result := <-Srv.Store.User().Get(c.Session.UserId); result.Err != nil {
+		c.Err = result.Err
+		return
+	} else {
+		user = result.Data.(*model.User)
+	}
+
+	if len(user.AuthService) > 0 && user.AuthService != model.USER_AUTH_SERVICE_LDAP {
+		c.Err = model.NewLocAppError("beginWebAuthnRegistration", "api.user.activate_mfa.email_and_ldap_only.app_error", nil, "")
+		c.Err.StatusCode = http.StatusBadRequest
+		return
+	}
+
+	credentials, err := GetWebAuthnCredentials(user.Id)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	challenge, err := issueWebAuthnChallenge(model.WEBAUTHN_CHALLENGE_REGISTER, user.Id, c.GetSiteURL())
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	options := &model.WebAuthnOptions{
+		Challenge:   challenge,
+		RpId:        model.WebAuthnRpIdFromOrigin(c.GetSiteURL()),
+		RpName:      utils.Cfg.TeamSettings.SiteName,
+		UserHandle:  base64.RawURLEncoding.EncodeToString([]byte(user.Id)),
+		UserName:    user.Username,
+		Algorithms:  []int{model.COSE_ALGORITHM_ES256, model.COSE_ALGORITHM_RS256},
+		Credentials: make([]string, 0, len(credentials)),
+		Timeout:     model.WEBAUTHN_CHALLENGE_TIMEOUT,
+	}
+
+	for _, credential := range credentials {
+		options.Credentials = append(options.Credentials, credential.CredentialId)
+	}
+
+	w.Write([]byte(options.ToJson()))
+}
+
+func finishWebAuthnRegistration(c *Context, w http.ResponseWriter, r *http.Request) {
+	if !isMfaAvailable() {
+		c.Err = model.NewLocAppError("finishWebAuthnRegistration", "api.webauthn.not_available.app_error", nil, "")
+		c.Err.StatusCode = http.StatusNotImplemented
+		return
+	}
+
+	registration := model.WebAuthnRegistrationFromJson(r.Body)
+	if registration == nil {
+		c.SetInvalidParam("finishWebAuthnRegistration", "registration")
+		return
+	}
+
+	credential, err := registration.Verify(getWebAuthnChallengeKey(), c.Session.UserId)
+	if err == nil {
+		err = consumeWebAuthnChallenge(registration.ClientDataJSON)
+	}
+
+	if err != nil {
+		c.LogAudit("fail - invalid security key registration")
+		c.Err = model.NewLocAppError("finishWebAuthnRegistration", "api.webauthn.register.invalid.app_error", nil, err.Error())
+		c.Err.StatusCode = http.StatusBadRequest
+		return
+	}
+
+	credentials, appErr := GetWebAuthnCredentials(c.Session.UserId)
+	if appErr != nil {
+		c.Err = appErr
+		return
+	}
+
+	for _, existing := range credentials {
+		if existing.CredentialId == credential.CredentialId {
+			c.Err = model.NewLocAppError("finishWebAuthnRegistration", "api.webauthn.register.exists.app_error", nil, "")
+			c.Err.StatusCode = http.StatusBadRequest
+			return
+		}
+	}
+
+	if result := <-Srv.Store.WebAuthn().Save(credential); result.Err != nil {
+		c.Err = result.Err
+		return
+	}
+
+	c.LogAudit("registered security key id=" + credential.Id)
+
+	w.Write([]byte(credential.ToJson()))
+}
+
+func getWebAuthnCredentials(c *Context, w http.ResponseWriter, r *http.Request) {
+	if credentials, err := GetWebAuthnCredentials(c.Session.UserId); err != nil {
+		c.Err = err
+		return
+	} else {
+		w.Write([]byte(model.WebAuthnCredentialsToJson(credentials)))
+	}
+}
+
+func deleteWebAuthnCredential(c *Context, w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["credential_id"]
+	if len(id) != 26 {
+		c.SetInvalidParam("deleteWebAuthnCredential", "credential_id")
+		return
+	}
+
+	if result := <-Srv.Store.WebAuthn().Delete(c.Session.UserId, id); result.Err != nil {
+		c.Err = result.Err
+		c.Err.StatusCode = http.StatusBadRequest
+		return
+	}
+
+	c.LogAudit("deleted security key id=" + id)
+
+	ReturnStatusOK(w)
+}
+
+func generateMfaRecoveryCodes(c *Context, w http.ResponseWriter, r *http.Request) {
+	if !isMfaAvailable() {
+		c.Err = model.NewLocAppError("generateMfaRecoveryCodes", "api.webauthn.not_available.app_error", nil, "")
+		c.Err.StatusCode = http.StatusNotImplemented
+		return
+	}
+
+	codes, hashes := model.NewMfaRecoveryCodes()
+
+	if result := <-Srv.Store.User().UpdateMfaRecoveryCodes(c.Session.UserId, hashes); result.Err != nil {
+		c.Err = result.Err
+		return
+	}
+
+	c.LogAudit("generated recovery codes")
+
+	w.Write([]byte(model.ArrayToJson(codes)))
+}
+
+// ResetSecondFactors removes the user's security keys and recovery codes
+func ResetSecondFactors(userId string) *model.AppError {
+	if result := <-Srv.Store.WebAuthn().PermanentDeleteByUser(userId); result.Err != nil {
+		return result.Err
+	}
+
+	if result := <-Srv.Store.User().UpdateMfaRecoveryCodes(userId, ""); result.Err != nil {
+		return result.Err
+	}
+
+	return nil
+}
@@ -0,0 +1,161 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api
+
+import (
+	"testing"
+
+	"github.com/mattermost/platform/model"
+	"github.com/mattermost/platform/store"
+	"github.com/mattermost/platform/utils"
+)
+
+func enableMfaForTest() func() {
+	if utils.License.Features.MFA == nil {
+		utils.License.Features.MFA = new(bool)
+	}
+
+	isLicensed := utils.IsLicensed
+	enableMfa := *utils.Cfg.ServiceSettings.EnableMultifactorAuthentication
+	enforceKeys := *utils.Cfg.ServiceSettings.EnforceSecurityKeysForAdmins
+
+	utils.IsLicensed = true
+	*utils.License.Features.MFA = true
+	*utils.Cfg.ServiceSettings.EnableMultifactorAuthentication = true
+
+	return func() {
+		utils.IsLicensed = isLicensed
+		*utils.License.Features.MFA = false
+		*utils.Cfg.ServiceSettings.EnableMultifactorAuthentication = enableMfa
+		*utils.Cfg.ServiceSettings.EnforceSecurityKeysForAdmins = enforceKeys
+	}
+}
+
+func TestWebAuthnRegistration(t *testing.T) {
+	th := Setup().InitBasic()
+	Client := th.BasicClient
+
+	if _, err := Client.BeginWebAuthnRegistration(); err == nil {
+		t.Fatal("should have failed - not licensed")
+	}
+
+	defer enableMfaForTest()()
+
+	if result, err := Client.BeginWebAuthnRegistration(); err != nil {
+		t.Fatal(err)
+	} else if options := result.Data.(*model.WebAuthnOptions); len(options.Challenge) == 0 || len(options.RpId) == 0 || len(options.Credentials) != 0 {
+		t.Fatal("should have returned registration options", options)
+	}
+
+	if _, err := Client.FinishWebAuthnRegistration(&model.WebAuthnRegistration{Name: "key", ClientDataJSON: "junk", AttestationObject: "junk"}); err == nil {
+		t.Fatal("should have failed - invalid registration")
+	}
+
+	credential := store.Must(Srv.Store.WebAuthn().Save(&model.WebAuthnCredential{UserId: th.BasicUser.Id, Name: "key", CredentialId: model.NewId(), PublicKey: model.NewId()})).(*model.WebAuthnCredential)
+
+	if result, err := Client.GetWebAuthnCredentials(); err != nil {
+		t.Fatal(err)
+	} else if credentials := result.Data.([]*model.WebAuthnCredential); len(credentials) != 1 || credentials[0].Id != credential.Id {
+		t.Fatal("should have returned the credential")
+	} else if credentials[0].PublicKey != "" {
+		t.Fatal("should not have returned the public key")
+	}
+
+	Client2 := th.CreateClient()
+	Client2.Must(Client2.Login(th.BasicUser2.Email, th.BasicUser2.Password))
+
+	if _, err := Client2.DeleteWebAuthnCredential(credential.Id); err == nil {
+		t.Fatal("should have failed - another user's credential")
+	}
+
+	Client.Must(Client.DeleteWebAuthnCredential(credential.Id))
+
+	if result, err := Client.GetWebAuthnCredentials(); err != nil {
+		t.Fatal(err)
+	} else if len(result.Data.([]*model.WebAuthnCredential)) != 0 {
+		t.Fatal("should have deleted the credential")
+	}
+}
+
+func TestWebAuthnLogin(t *testing.T) {
+	th := Setup().InitBasic()
+	Client := th.BasicClient
+	user := th.BasicUser
+
+	defer enableMfaForTest()()
+
+	store.Must(Srv.Store.WebAuthn().Save(&model.WebAuthnCredential{UserId: user.Id, Name: "key", CredentialId: model.NewId(), PublicKey: model.NewId()}))
+
+	codes := Client.Must(Client.GenerateMfaRecoveryCodes()).Data.([]string)
+	if len(codes) != model.MFA_RECOVERY_CODE_COUNT {
+		t.Fatal("should have returned the recovery codes")
+	}
+
+	if result, err := Client.CheckMfa(user.Email); err != nil {
+		t.Fatal(err)
+	} else if resp := result.Data.(map[string]string); resp["mfa_required"] != "true" || len(resp["webauthn_options"]) != 0 {
+		t.Fatal("should require mfa without returning the user's security keys", resp)
+	}
+
+	if _, err := Client.BeginWebAuthnLogin(user.Email, "wrongpassword"); err == nil {
+		t.Fatal("should have failed - wrong password")
+	}
+
+	if result, err := Client.BeginWebAuthnLogin(user.Email, user.Password); err != nil {
+		t.Fatal(err)
+	} else if options := result.Data.(*model.WebAuthnOptions); len(options.Challenge) == 0 || len(options.Credentials) != 1 {
+		t.Fatal("should have returned a challenge for the security key", options)
+	}
+
+	Client.Logout()
+
+	if _, err := Client.Login(user.Email, user.Password); err == nil {
+		t.Fatal("should have failed - missing second factor")
+	}
+
+	if _, err := Client.LoginWithMfa(user.Email, user.Password, "123456"); err == nil {
+		t.Fatal("should have failed - totp isn't set up")
+	}
+
+	if _, err := Client.LoginWithWebAuthn(user.Email, user.Password, &model.WebAuthnAssertion{CredentialId: model.NewId()}); err == nil {
+		t.Fatal("should have failed - invalid assertion")
+	}
+
+	if _, err := Client.LoginWithMfa(user.Email, "wrongpassword", codes[0]); err == nil {
+		t.Fatal("should have failed - wrong password")
+	}
+
+	// the recovery code shouldn't have been used up by the login with the wrong password
+	Client.Must(Client.LoginWithMfa(user.Email, user.Password, codes[0]))
+
+	if _, err := Client.LoginWithMfa(user.Email, user.Password, codes[0]); err == nil {
+		t.Fatal("should have failed - recovery code already used")
+	}
+}
+
+func TestEnforceSecurityKeysForAdmins(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	Client := th.SystemAdminClient
+
+	defer enableMfaForTest()()
+	*utils.Cfg.ServiceSettings.EnforceSecurityKeysForAdmins = true
+
+	if _, err := Client.GetMe(""); err == nil {
+		t.Fatal("should have failed - admin without a security key")
+	}
+
+	if _, err := th.BasicClient.GetMe(""); err != nil {
+		t.Fatal("should only apply to admins", err)
+	}
+
+	if _, err := Client.BeginWebAuthnRegistration(); err != nil {
+		t.Fatal("should be able to register a key", err)
+	}
+
+	store.Must(Srv.Store.WebAuthn().Save(&model.WebAuthnCredential{UserId: th.SystemAdminUser.Id, Name: "key", CredentialId: model.NewId(), PublicKey: model.NewId()}))
+
+	if _, err := Client.GetMe(""); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -69,6 +69,16 @@ Channels can be specified by [team]:[channel]. ie. myteam:mychannel or by channe
 	RunE:    restoreChannelsCmdF,
 }
 
+var channelRolesCmd = &cobra.Command{
+	Use:   "roles [channel] [user] [roles]",
+	Short: "Set the channel roles of a user",
+	Long: `Set the roles a member has in a channel.
+Roles are given as a space separated list. ie. "channel_user channel_admin" to promote or "channel_user" to demote.
+Channels can be specified by [team]:[channel]. ie. myteam:mychannel or by channel ID.`,
+	Example: `  channel roles myteam:mychannel user@example.com "channel_user channel_admin"`,
+	RunE:    channelRolesCmdF,
+}
+
 func init() {
 	channelCreateCmd.Flags().String("name", "", "Channel Name")
 	channelCreateCmd.Flags().String("display_name", "", "Channel Display Name")
@@ -84,6 +94,7 @@ func init() {
 		deleteChannelsCmd,
 		listChannelsCmd,
 		restoreChannelsCmd,
+		channelRolesCmd,
 	)
 }
 
@@ -282,3 +293,31 @@ func restoreChannelsCmdF(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+func channelRolesCmdF(cmd *cobra.Command, args []string) error {
+	initDBCommandContextCobra(cmd)
+
+	if len(args) != 3 {
+		return errors.New("Enter a channel, a user and the new roles.")
+	}
+
+	channel := getChannelFromChannelArg(args[0])
+	if channel == nil {
+		return errors.New("Unable to find channel '" + args[0] + "'")
+	}
+
+	user := getUserFromUserArg(args[1])
+	if user == nil {
+		return errors.New("Unable to find user '" + args[1] + "'")
+	}
+
+	if !model.IsValidChannelRoles(args[2]) {
+		return errors.New("Invalid roles '" + args[2] + "'")
+	}
+
+	if _, err := api.UpdateChannelMemberRoles(channel.Id, user.Id, args[2]); err != nil {
+		return err
+	}
+
+	return nil
+}
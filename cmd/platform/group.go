@@ -0,0 +1,207 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+package main
+
+import (
+	"errors"
+
+	"github.com/mattermost/platform/api"
+	"github.com/mattermost/platform/model"
+	"github.com/spf13/cobra"
+)
+
+var groupCmd = &cobra.Command{
+	Use:   "group",
+	Short: "Management of user groups",
+}
+
+var groupCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Create a user group",
+	Long:  `Create a group of users that are all mentioned by @ followed by the group's name.`,
+	Example: `  group create --name sre --display_name "Site Reliability"
+  group create --name frontend --display_name "Frontend" --member_managed
+  group create --name engineering --display_name "Engineering" --ldap_group "cn=engineering,ou=groups,dc=example,dc=com"`,
+	RunE: createGroupCmdF,
+}
+
+var deleteGroupsCmd = &cobra.Command{
+	Use:     "delete [groups]",
+	Short:   "Delete user groups",
+	Long:    "Delete some user groups along with their members.",
+	Example: "  group delete sre",
+	RunE:    deleteGroupsCmdF,
+}
+
+var listGroupsCmd = &cobra.Command{
+	Use:     "list",
+	Short:   "List all user groups",
+	Long:    "List all user groups. Groups synchronized from LDAP are appended with ' (ldap)'.",
+	Example: "  group list",
+	RunE:    listGroupsCmdF,
+}
+
+var addGroupUsersCmd = &cobra.Command{
+	Use:     "add [group] [users]",
+	Short:   "Add users to a group",
+	Long:    "Add some users to a user group.",
+	Example: "  group add sre user@example.com username",
+	RunE:    addGroupUsersCmdF,
+}
+
+var removeGroupUsersCmd = &cobra.Command{
+	Use:     "remove [group] [users]",
+	Short:   "Remove users from a group",
+	Long:    "Remove some users from a user group.",
+	Example: "  group remove sre user@example.com username",
+	RunE:    removeGroupUsersCmdF,
+}
+
+func init() {
+	groupCreateCmd.Flags().String("name", "", "Group Name")
+	groupCreateCmd.Flags().String("display_name", "", "Group Display Name")
+	groupCreateCmd.Flags().String("description", "", "Group description")
+	groupCreateCmd.Flags().Bool("member_managed", false, "Allow members of the group to add and remove members.")
+	groupCreateCmd.Flags().String("ldap_group", "", "Id of the LDAP group to synchronize the members from")
+
+	groupCmd.AddCommand(
+		groupCreateCmd,
+		deleteGroupsCmd,
+		listGroupsCmd,
+		addGroupUsersCmd,
+		removeGroupUsersCmd,
+	)
+}
+
+func getGroupFromGroupArg(groupArg string) *model.UserGroup {
+	if group, err := api.GetUserGroupByName(groupArg); err == nil {
+		return group
+	}
+
+	if group, err := api.GetUserGroup(groupArg); err == nil {
+		return group
+	}
+
+	return nil
+}
+
+func createGroupCmdF(cmd *cobra.Command, args []string) error {
+	initDBCommandContextCobra(cmd)
+
+	name, errn := cmd.Flags().GetString("name")
+	if errn != nil || name == "" {
+		return errors.New("Name is required")
+	}
+	displayname, errdn := cmd.Flags().GetString("display_name")
+	if errdn != nil || displayname == "" {
+		return errors.New("Display Name is required")
+	}
+	description, _ := cmd.Flags().GetString("description")
+	memberManaged, _ := cmd.Flags().GetBool("member_managed")
+	ldapGroup, _ := cmd.Flags().GetString("ldap_group")
+
+	group := &model.UserGroup{
+		Name:          name,
+		DisplayName:   displayname,
+		Description:   description,
+		MemberManaged: memberManaged,
+		LdapGroupId:   ldapGroup,
+	}
+
+	if _, err := api.CreateUserGroup(group); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func deleteGroupsCmdF(cmd *cobra.Command, args []string) error {
+	initDBCommandContextCobra(cmd)
+
+	if len(args) < 1 {
+		return errors.New("Enter at least one group to delete.")
+	}
+
+	for _, groupArg := range args {
+		group := getGroupFromGroupArg(groupArg)
+		if group == nil {
+			CommandPrintErrorln("Unable to find group '" + groupArg + "'")
+			continue
+		}
+		if err := api.DeleteUserGroup(group.Id); err != nil {
+			CommandPrintErrorln("Unable to delete group '" + group.Name + "' error: " + err.Error())
+		}
+	}
+
+	return nil
+}
+
+func listGroupsCmdF(cmd *cobra.Command, args []string) error {
+	initDBCommandContextCobra(cmd)
+
+	if result := <-api.Srv.Store.UserGroup().GetAll(); result.Err != nil {
+		return result.Err
+	} else {
+		for _, group := range result.Data.([]*model.UserGroup) {
+			if group.IsLdapSynced() {
+				CommandPrettyPrintln(group.Name + " (ldap)")
+			} else {
+				CommandPrettyPrintln(group.Name)
+			}
+		}
+	}
+
+	return nil
+}
+
+func addGroupUsersCmdF(cmd *cobra.Command, args []string) error {
+	initDBCommandContextCobra(cmd)
+
+	if len(args) < 2 {
+		return errors.New("Not enough arguments.")
+	}
+
+	group := getGroupFromGroupArg(args[0])
+	if group == nil {
+		return errors.New("Unable to find group '" + args[0] + "'")
+	}
+
+	users := getUsersFromUserArgs(args[1:])
+	for i, user := range users {
+		if user == nil {
+			CommandPrintErrorln("Can't find user '" + args[i+1] + "'")
+			continue
+		}
+		if err := api.AddUserGroupMember(group, user.Id); err != nil {
+			CommandPrintErrorln("Unable to add '" + args[i+1] + "' to " + group.Name + ". Error: " + err.Error())
+		}
+	}
+
+	return nil
+}
+
+func removeGroupUsersCmdF(cmd *cobra.Command, args []string) error {
+	initDBCommandContextCobra(cmd)
+
+	if len(args) < 2 {
+		return errors.New("Not enough arguments.")
+	}
+
+	group := getGroupFromGroupArg(args[0])
+	if group == nil {
+		return errors.New("Unable to find group '" + args[0] + "'")
+	}
+
+	users := getUsersFromUserArgs(args[1:])
+	for i, user := range users {
+		if user == nil {
+			CommandPrintErrorln("Can't find user '" + args[i+1] + "'")
+			continue
+		}
+		if err := api.RemoveUserGroupMember(group, user.Id); err != nil {
+			CommandPrintErrorln("Unable to remove '" + args[i+1] + "' from " + group.Name + ". Error: " + err.Error())
+		}
+	}
+
+	return nil
+}
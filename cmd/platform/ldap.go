@@ -3,7 +3,12 @@
 package main
 
 import (
+	"errors"
+	"strings"
+
+	"github.com/mattermost/platform/api"
 	"github.com/mattermost/platform/einterfaces"
+	"github.com/mattermost/platform/model"
 	"github.com/spf13/cobra"
 )
 
@@ -15,25 +20,195 @@ var ldapCmd = &cobra.Command{
 var ldapSyncCmd = &cobra.Command{
 	Use:     "sync",
 	Short:   "Synchronize now",
-	Long:    "Synchronize all LDAP users now.",
+	Long:    "Synchronize all LDAP users now, then the members of the teams, channels and user groups linked to LDAP groups.",
 	Example: "  ldap sync",
 	RunE:    ldapSyncCmdF,
 }
 
+var ldapGroupsCmd = &cobra.Command{
+	Use:     "groups",
+	Short:   "List linked groups",
+	Long:    "List the LDAP groups linked to teams and channels.",
+	Example: "  ldap groups",
+	RunE:    ldapGroupsCmdF,
+}
+
+var ldapLinkCmd = &cobra.Command{
+	Use:   "link [group] [team or channel]",
+	Short: "Link a group",
+	Long:  "Link an LDAP group to a team or to a private channel. Members of the group are added on the next synchronization.",
+	Example: `  ldap link "cn=engineering,ou=groups,dc=example,dc=com" myteam
+  ldap link "cn=engineering,ou=groups,dc=example,dc=com" myteam:private-channel`,
+	RunE: ldapLinkCmdF,
+}
+
+var ldapUnlinkCmd = &cobra.Command{
+	Use:     "unlink [links]",
+	Short:   "Unlink groups",
+	Long:    "Remove links between LDAP groups and teams or channels. Members are kept.",
+	Example: "  ldap unlink 8j8gm7r5ojbsdm6fqkm1tyfjkw",
+	RunE:    ldapUnlinkCmdF,
+}
+
+var ldapPreviewCmd = &cobra.Command{
+	Use:     "preview",
+	Short:   "Preview group synchronization",
+	Long:    "List the users that the next synchronization would add to and remove from the teams and channels linked to LDAP groups without changing anything.",
+	Example: "  ldap preview",
+	RunE:    ldapPreviewCmdF,
+}
+
 func init() {
 	ldapCmd.AddCommand(
 		ldapSyncCmd,
+		ldapGroupsCmd,
+		ldapLinkCmd,
+		ldapUnlinkCmd,
+		ldapPreviewCmd,
 	)
 }
 
 func ldapSyncCmdF(cmd *cobra.Command, args []string) error {
+	initDBCommandContextCobra(cmd)
+
 	if ldapI := einterfaces.GetLdapInterface(); ldapI != nil {
 		if err := ldapI.Syncronize(); err != nil {
 			CommandPrintErrorln("ERROR: AD/LDAP Synchronization Failed")
+			return nil
+		}
+
+		if report, err := api.SyncLdapGroupLinks(false); err != nil {
+			CommandPrintErrorln("ERROR: AD/LDAP Group Synchronization Failed: " + err.Error())
 		} else {
+			printLdapGroupSyncReport(report)
 			CommandPrettyPrintln("SUCCESS: AD/LDAP Synchronization Complete")
 		}
+
+		if err := api.SyncLdapUserGroups(); err != nil {
+			CommandPrintErrorln("ERROR: AD/LDAP User Group Synchronization Failed: " + err.Error())
+		}
+	}
+
+	return nil
+}
+
+func ldapGroupsCmdF(cmd *cobra.Command, args []string) error {
+	initDBCommandContextCobra(cmd)
+
+	if result := <-api.Srv.Store.LdapGroupLink().GetAll(); result.Err != nil {
+		return result.Err
+	} else {
+		for _, link := range result.Data.([]*model.LdapGroupLink) {
+			CommandPrettyPrintln(link.Id + ": " + link.GroupName + " (" + link.GroupId + ") -> " + ldapGroupTargetName(link.TeamId, link.ChannelId))
+		}
+	}
+
+	return nil
+}
+
+func ldapLinkCmdF(cmd *cobra.Command, args []string) error {
+	initDBCommandContextCobra(cmd)
+
+	if len(args) != 2 {
+		return errors.New("Expected two arguments. See help text for details.")
+	}
+
+	link := &model.LdapGroupLink{GroupId: args[0]}
+
+	if strings.Contains(args[1], CHANNEL_ARG_SEPARATOR) {
+		channel := getChannelFromChannelArg(args[1])
+		if channel == nil {
+			return errors.New("Unable to find channel '" + args[1] + "'")
+		}
+
+		link.TeamId = channel.TeamId
+		link.ChannelId = channel.Id
+	} else {
+		team := getTeamFromTeamArg(args[1])
+		if team == nil {
+			return errors.New("Unable to find team '" + args[1] + "'")
+		}
+
+		link.TeamId = team.Id
+	}
+
+	if rlink, err := api.LinkLdapGroup(link); err != nil {
+		return err
+	} else {
+		CommandPrettyPrintln("Linked " + rlink.GroupName + " with id " + rlink.Id)
+	}
+
+	return nil
+}
+
+func ldapUnlinkCmdF(cmd *cobra.Command, args []string) error {
+	initDBCommandContextCobra(cmd)
+
+	if len(args) < 1 {
+		return errors.New("Enter link(s) to remove.")
+	}
+
+	for _, id := range args {
+		if result := <-api.Srv.Store.LdapGroupLink().Delete(id); result.Err != nil {
+			CommandPrintErrorln("Unable to remove link '" + id + "'. Error: " + result.Err.Error())
+		}
 	}
 
 	return nil
 }
+
+func ldapPreviewCmdF(cmd *cobra.Command, args []string) error {
+	initDBCommandContextCobra(cmd)
+
+	if report, err := api.SyncLdapGroupLinks(true); err != nil {
+		return err
+	} else {
+		printLdapGroupSyncReport(report)
+	}
+
+	return nil
+}
+
+func printLdapGroupSyncReport(report *model.LdapGroupSyncReport) {
+	for _, result := range report.Results {
+		name := ldapGroupTargetName(result.TeamId, result.ChannelId)
+
+		if len(result.Error) > 0 {
+			CommandPrintErrorln(name + ": " + result.Error)
+			continue
+		}
+
+		for _, userId := range result.Added {
+			CommandPrettyPrintln(name + ": add " + ldapGroupUsername(userId))
+		}
+
+		for _, userId := range result.Removed {
+			CommandPrettyPrintln(name + ": remove " + ldapGroupUsername(userId))
+		}
+	}
+}
+
+func ldapGroupTargetName(teamId string, channelId string) string {
+	name := teamId
+	if result := <-api.Srv.Store.Team().Get(teamId); result.Err == nil {
+		name = result.Data.(*model.Team).Name
+	}
+
+	if len(channelId) > 0 {
+		if result := <-api.Srv.Store.Channel().Get(channelId); result.Err == nil {
+			return name + CHANNEL_ARG_SEPARATOR + result.Data.(*model.Channel).Name
+		}
+
+		return name + CHANNEL_ARG_SEPARATOR + channelId
+	}
+
+	return name
+}
+
+func ldapGroupUsername(userId string) string {
+	if result := <-api.Srv.Store.User().Get(userId); result.Err == nil {
+		return result.Data.(*model.User).Username
+	}
+
+	return userId
+}
@@ -14,6 +14,7 @@ import (
 
 	// Plugins
 	_ "github.com/mattermost/platform/model/gitlab"
+	_ "github.com/mattermost/platform/model/openid"
 
 	// Enterprise Deps
 	_ "github.com/dgryski/dgoogauth"
@@ -34,7 +35,7 @@ func main() {
 
 	resetCmd.Flags().Bool("confirm", false, "Confirm you really want to delete everything and a DB backup has been performed.")
 
-	rootCmd.AddCommand(serverCmd, versionCmd, userCmd, teamCmd, licenseCmd, importCmd, resetCmd, channelCmd, rolesCmd, testCmd, ldapCmd)
+	rootCmd.AddCommand(serverCmd, versionCmd, userCmd, teamCmd, licenseCmd, importCmd, resetCmd, channelCmd, rolesCmd, testCmd, ldapCmd, groupCmd)
 
 	flag.Usage = func() {
 		rootCmd.Usage()
@@ -87,6 +87,14 @@ func runServer(configFileLocation string) {
 
 	setDiagnosticId()
 	go runSecurityAndDiagnosticsJob()
+	go api.StartGuestExpiryJob()
+	go api.StartLdapGroupSyncJob()
+	go api.StartDNDExpiryJob()
+	go api.StartCustomStatusExpiryJob()
+	go api.StartMailQueue()
+	go api.StartDigestJob()
+	go api.StartReminderJob()
+	api.StartEmailReplyServer()
 
 	if complianceI := einterfaces.GetComplianceInterface(); complianceI != nil {
 		complianceI.StartComplianceDailyJob()
@@ -5,6 +5,7 @@ package main
 import (
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/mattermost/platform/api"
 	"github.com/mattermost/platform/einterfaces"
@@ -119,6 +120,32 @@ var verifyUserCmd = &cobra.Command{
 	RunE:    verifyUserCmdF,
 }
 
+var userGuestCmd = &cobra.Command{
+	Use:   "guest [users]",
+	Short: "Convert users to guests",
+	Long: `Convert users to guests. Guests can only access the channels they are a member of.
+Use --expires to set a date after which the guest accounts are deactivated.`,
+	Example: `  user guest user1
+  user guest user1 user2 --expires 2017-12-31`,
+	RunE: userGuestCmdF,
+}
+
+var userUnguestCmd = &cobra.Command{
+	Use:     "unguest [users]",
+	Short:   "Convert guests to regular users",
+	Long:    "Convert guests back to regular users.",
+	Example: "  user unguest user1",
+	RunE:    userUnguestCmdF,
+}
+
+var userUnlockCmd = &cobra.Command{
+	Use:     "unlock [users]",
+	Short:   "Unlock users",
+	Long:    "Unlock users that have been locked out after too many failed login attempts.",
+	Example: "  user unlock user@example.com",
+	RunE:    userUnlockCmdF,
+}
+
 func init() {
 	userCreateCmd.Flags().String("username", "", "Username")
 	userCreateCmd.Flags().String("email", "", "Email")
@@ -133,6 +160,8 @@ func init() {
 
 	deleteAllUsersCmd.Flags().Bool("confirm", false, "Confirm you really want to delete the user and a DB backup has been performed.")
 
+	userGuestCmd.Flags().String("expires", "", "Date the guest accounts expire (ex: 2017-12-31)")
+
 	userCmd.AddCommand(
 		userActivateCmd,
 		userDeactivateCmd,
@@ -144,6 +173,9 @@ func init() {
 		deleteAllUsersCmd,
 		migrateAuthCmd,
 		verifyUserCmd,
+		userGuestCmd,
+		userUnguestCmd,
+		userUnlockCmd,
 	)
 }
 
@@ -430,3 +462,75 @@ func verifyUserCmdF(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+func userGuestCmdF(cmd *cobra.Command, args []string) error {
+	initDBCommandContextCobra(cmd)
+	if len(args) < 1 {
+		return errors.New("Enter at least one user.")
+	}
+
+	var expiresAt int64
+	if expires, _ := cmd.Flags().GetString("expires"); len(expires) > 0 {
+		date, err := time.Parse("2006-01-02", expires)
+		if err != nil {
+			return errors.New("Invalid expiry date '" + expires + "', expected the format YYYY-MM-DD")
+		}
+		expiresAt = date.UnixNano() / int64(time.Millisecond)
+	}
+
+	users := getUsersFromUserArgs(args)
+
+	for i, user := range users {
+		if user == nil {
+			CommandPrintErrorln("Unable to find user '" + args[i] + "'")
+			continue
+		}
+		if _, err := api.ConvertUserToGuest(user, expiresAt); err != nil {
+			CommandPrintErrorln("Unable to convert '" + args[i] + "' to a guest. Error: " + err.Error())
+		}
+	}
+
+	return nil
+}
+
+func userUnguestCmdF(cmd *cobra.Command, args []string) error {
+	initDBCommandContextCobra(cmd)
+	if len(args) < 1 {
+		return errors.New("Enter at least one user.")
+	}
+
+	users := getUsersFromUserArgs(args)
+
+	for i, user := range users {
+		if user == nil {
+			CommandPrintErrorln("Unable to find user '" + args[i] + "'")
+			continue
+		}
+		if _, err := api.ConvertGuestToUser(user); err != nil {
+			CommandPrintErrorln("Unable to convert '" + args[i] + "' to a regular user. Error: " + err.Error())
+		}
+	}
+
+	return nil
+}
+
+func userUnlockCmdF(cmd *cobra.Command, args []string) error {
+	initDBCommandContextCobra(cmd)
+	if len(args) < 1 {
+		return errors.New("Enter at least one user.")
+	}
+
+	users := getUsersFromUserArgs(args)
+
+	for i, user := range users {
+		if user == nil {
+			return errors.New("Unable to find user '" + args[i] + "'")
+		}
+
+		if err := api.UnlockUser(user.Id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
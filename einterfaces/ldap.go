@@ -18,6 +18,8 @@ type LdapInterface interface {
 	SyncNow()
 	RunTest() *model.AppError
 	GetAllLdapUsers() ([]*model.User, *model.AppError)
+	GetAllGroups() ([]*model.LdapGroup, *model.AppError)
+	GetGroupMemberIds(groupId string) ([]string, *model.AppError)
 }
 
 var theLdapInterface LdapInterface
@@ -13,6 +13,13 @@ type MetricsInterface interface {
 	IncrementPostBroadcast()
 	IncrementPostFileAttachment(count int)
 
+	IncrementPushNotificationRetry()
+	IncrementPushNotificationFailure()
+	IncrementPushNotificationDeviceRemoved()
+
+	IncrementNotificationSent(channel string)
+	IncrementNotificationFailure(channel string)
+
 	IncrementHttpRequest()
 	IncrementHttpError()
 	ObserveHttpRequestDuration(elapsed float64)
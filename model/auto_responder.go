@@ -0,0 +1,84 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	AUTO_RESPONDER_ACTIVE_NOTIFY_PROP  = "auto_responder_active"
+	AUTO_RESPONDER_MESSAGE_NOTIFY_PROP = "auto_responder_message"
+	AUTO_RESPONDER_START_NOTIFY_PROP   = "auto_responder_start"
+	AUTO_RESPONDER_END_NOTIFY_PROP     = "auto_responder_end"
+
+	AUTO_RESPONDER_MESSAGE_MAX_RUNES = 500
+	AUTO_RESPONDER_INTERVAL          = 24 * 60 * 60 * 1000 // 1 day
+)
+
+// An AutoResponder is an out of office message sent in reply to direct messages. It's stored in the user's
+// notify props as a flag, the message and an optional start and end time in milliseconds. Without a start
+// or end time it's active from when it's turned on or until it's turned off.
+type AutoResponder struct {
+	Message string `json:"message"`
+	StartAt int64  `json:"start_at,omitempty"`
+	EndAt   int64  `json:"end_at,omitempty"`
+}
+
+// AutoResponderFromNotifyProps parses the auto responder out of a user's notify props. It returns nil
+// without an error when the user hasn't turned it on.
+func AutoResponderFromNotifyProps(props StringMap) (*AutoResponder, *AppError) {
+	if props[AUTO_RESPONDER_ACTIVE_NOTIFY_PROP] != "true" {
+		return nil, nil
+	}
+
+	autoResponder := &AutoResponder{Message: strings.TrimSpace(props[AUTO_RESPONDER_MESSAGE_NOTIFY_PROP])}
+
+	if len(autoResponder.Message) == 0 || utf8.RuneCountInString(autoResponder.Message) > AUTO_RESPONDER_MESSAGE_MAX_RUNES {
+		return nil, NewLocAppError("AutoResponderFromNotifyProps", "model.auto_responder.message.app_error", nil, "")
+	}
+
+	var ok bool
+	if autoResponder.StartAt, ok = parseAutoResponderTime(props[AUTO_RESPONDER_START_NOTIFY_PROP]); !ok {
+		return nil, NewLocAppError("AutoResponderFromNotifyProps", "model.auto_responder.start.app_error", nil, AUTO_RESPONDER_START_NOTIFY_PROP+"="+props[AUTO_RESPONDER_START_NOTIFY_PROP])
+	}
+
+	if autoResponder.EndAt, ok = parseAutoResponderTime(props[AUTO_RESPONDER_END_NOTIFY_PROP]); !ok {
+		return nil, NewLocAppError("AutoResponderFromNotifyProps", "model.auto_responder.end.app_error", nil, AUTO_RESPONDER_END_NOTIFY_PROP+"="+props[AUTO_RESPONDER_END_NOTIFY_PROP])
+	}
+
+	if autoResponder.StartAt != 0 && autoResponder.EndAt != 0 && autoResponder.EndAt <= autoResponder.StartAt {
+		return nil, NewLocAppError("AutoResponderFromNotifyProps", "model.auto_responder.end.app_error", nil, AUTO_RESPONDER_END_NOTIFY_PROP+"="+props[AUTO_RESPONDER_END_NOTIFY_PROP])
+	}
+
+	return autoResponder, nil
+}
+
+// IsActive returns true if the time falls within the auto responder's dates.
+func (a *AutoResponder) IsActive(now int64) bool {
+	return (a.StartAt == 0 || a.StartAt <= now) && (a.EndAt == 0 || now < a.EndAt)
+}
+
+// GetActiveAutoResponder returns the user's auto responder if it's on and active at the given time.
+func (u *User) GetActiveAutoResponder(now int64) *AutoResponder {
+	if autoResponder, err := AutoResponderFromNotifyProps(u.NotifyProps); err != nil || autoResponder == nil || !autoResponder.IsActive(now) {
+		return nil
+	} else {
+		return autoResponder
+	}
+}
+
+func parseAutoResponderTime(value string) (int64, bool) {
+	if len(value) == 0 {
+		return 0, true
+	}
+
+	if t, err := strconv.ParseInt(value, 10, 64); err != nil || t < 0 {
+		return 0, false
+	} else {
+		return t, true
+	}
+}
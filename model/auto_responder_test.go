@@ -0,0 +1,76 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestAutoResponderFromNotifyProps(t *testing.T) {
+	props := StringMap{AUTO_RESPONDER_MESSAGE_NOTIFY_PROP: "I'm out of the office"}
+
+	if autoResponder, err := AutoResponderFromNotifyProps(props); err != nil || autoResponder != nil {
+		t.Fatal("should be off unless turned on")
+	}
+
+	props[AUTO_RESPONDER_ACTIVE_NOTIFY_PROP] = "true"
+	if autoResponder, err := AutoResponderFromNotifyProps(props); err != nil {
+		t.Fatal(err)
+	} else if autoResponder.Message != "I'm out of the office" || !autoResponder.IsActive(GetMillis()) {
+		t.Fatal("should be active without dates")
+	}
+
+	props[AUTO_RESPONDER_START_NOTIFY_PROP] = "2000"
+	props[AUTO_RESPONDER_END_NOTIFY_PROP] = "1000"
+	if _, err := AutoResponderFromNotifyProps(props); err == nil {
+		t.Fatal("should fail with an end before the start")
+	}
+
+	props[AUTO_RESPONDER_END_NOTIFY_PROP] = "junk"
+	if _, err := AutoResponderFromNotifyProps(props); err == nil {
+		t.Fatal("should fail with a bad end time")
+	}
+
+	props[AUTO_RESPONDER_END_NOTIFY_PROP] = "3000"
+	if autoResponder, err := AutoResponderFromNotifyProps(props); err != nil {
+		t.Fatal(err)
+	} else if autoResponder.IsActive(1999) || !autoResponder.IsActive(2000) || autoResponder.IsActive(3000) {
+		t.Fatal("should only be active between the dates")
+	}
+
+	props[AUTO_RESPONDER_MESSAGE_NOTIFY_PROP] = strings.Repeat("a", AUTO_RESPONDER_MESSAGE_MAX_RUNES+1)
+	if _, err := AutoResponderFromNotifyProps(props); err == nil {
+		t.Fatal("should fail with a long message")
+	}
+
+	props[AUTO_RESPONDER_MESSAGE_NOTIFY_PROP] = " "
+	if _, err := AutoResponderFromNotifyProps(props); err == nil {
+		t.Fatal("should fail without a message")
+	}
+}
+
+func TestUserGetActiveAutoResponder(t *testing.T) {
+	now := GetMillis()
+
+	user := User{NotifyProps: StringMap{
+		AUTO_RESPONDER_ACTIVE_NOTIFY_PROP:  "true",
+		AUTO_RESPONDER_MESSAGE_NOTIFY_PROP: "Back on Monday",
+		AUTO_RESPONDER_END_NOTIFY_PROP:     strconv.FormatInt(now+1000, 10),
+	}}
+
+	if user.GetActiveAutoResponder(now) == nil {
+		t.Fatal("should be active")
+	}
+
+	if user.GetActiveAutoResponder(now+1000) != nil {
+		t.Fatal("should have ended")
+	}
+
+	user.SanitizeProfile(map[string]bool{})
+	if user.OutOfOffice == nil || user.OutOfOffice.Message != "Back on Monday" {
+		t.Fatal("should have kept the out of office message in the profile")
+	}
+}
@@ -0,0 +1,169 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+const (
+	CBOR_MAX_DEPTH = 16
+)
+
+// DecodeCBOR decodes the first CBOR data item in data and returns it along with any bytes left over.
+// Only the definite length encodings that authenticators produce are supported. Unsigned and negative
+// integers decode to int64, byte strings to []byte, text strings to string, arrays to []interface{}
+// and maps to map[interface{}]interface{}. Tags are skipped and their content returned as is.
+func DecodeCBOR(data []byte) (interface{}, []byte, error) {
+	return decodeCBORItem(data, 0)
+}
+
+func decodeCBORItem(data []byte, depth int) (interface{}, []byte, error) {
+	if depth > CBOR_MAX_DEPTH {
+		return nil, nil, errors.New("cbor: nested too deeply")
+	}
+
+	if len(data) == 0 {
+		return nil, nil, errors.New("cbor: unexpected end of data")
+	}
+
+	major := data[0] >> 5
+	info := data[0] & 0x1f
+
+	if major == 7 {
+		return decodeCBORSimple(data, info)
+	}
+
+	arg, rest, err := decodeCBORArgument(data, info)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch major {
+	case 0:
+		if arg > math.MaxInt64 {
+			return nil, nil, errors.New("cbor: integer overflow")
+		}
+		return int64(arg), rest, nil
+	case 1:
+		if arg > math.MaxInt64 {
+			return nil, nil, errors.New("cbor: integer overflow")
+		}
+		return -1 - int64(arg), rest, nil
+	case 2, 3:
+		if arg > uint64(len(rest)) {
+			return nil, nil, errors.New("cbor: unexpected end of data")
+		}
+
+		if major == 2 {
+			b := make([]byte, arg)
+			copy(b, rest[:arg])
+			return b, rest[arg:], nil
+		}
+
+		return string(rest[:arg]), rest[arg:], nil
+	case 4:
+		// every item takes at least one byte so this also bounds the allocation below
+		if arg > uint64(len(rest)) {
+			return nil, nil, errors.New("cbor: unexpected end of data")
+		}
+
+		items := make([]interface{}, 0, arg)
+		for i := uint64(0); i < arg; i++ {
+			var item interface{}
+			if item, rest, err = decodeCBORItem(rest, depth+1); err != nil {
+				return nil, nil, err
+			}
+			items = append(items, item)
+		}
+
+		return items, rest, nil
+	case 5:
+		if arg > uint64(len(rest)) {
+			return nil, nil, errors.New("cbor: unexpected end of data")
+		}
+
+		items := make(map[interface{}]interface{}, arg)
+		for i := uint64(0); i < arg; i++ {
+			var key, value interface{}
+			if key, rest, err = decodeCBORItem(rest, depth+1); err != nil {
+				return nil, nil, err
+			}
+
+			switch key.(type) {
+			case int64, string:
+			default:
+				return nil, nil, errors.New("cbor: unsupported map key type")
+			}
+
+			if value, rest, err = decodeCBORItem(rest, depth+1); err != nil {
+				return nil, nil, err
+			}
+
+			items[key] = value
+		}
+
+		return items, rest, nil
+	case 6:
+		return decodeCBORItem(rest, depth+1)
+	}
+
+	return nil, nil, errors.New("cbor: unsupported major type")
+}
+
+func decodeCBORArgument(data []byte, info byte) (uint64, []byte, error) {
+	data = data[1:]
+
+	switch {
+	case info < 24:
+		return uint64(info), data, nil
+	case info == 24:
+		if len(data) < 1 {
+			return 0, nil, errors.New("cbor: unexpected end of data")
+		}
+		return uint64(data[0]), data[1:], nil
+	case info == 25:
+		if len(data) < 2 {
+			return 0, nil, errors.New("cbor: unexpected end of data")
+		}
+		return uint64(binary.BigEndian.Uint16(data)), data[2:], nil
+	case info == 26:
+		if len(data) < 4 {
+			return 0, nil, errors.New("cbor: unexpected end of data")
+		}
+		return uint64(binary.BigEndian.Uint32(data)), data[4:], nil
+	case info == 27:
+		if len(data) < 8 {
+			return 0, nil, errors.New("cbor: unexpected end of data")
+		}
+		return binary.BigEndian.Uint64(data), data[8:], nil
+	}
+
+	return 0, nil, errors.New("cbor: indefinite lengths are not supported")
+}
+
+func decodeCBORSimple(data []byte, info byte) (interface{}, []byte, error) {
+	switch info {
+	case 20:
+		return false, data[1:], nil
+	case 21:
+		return true, data[1:], nil
+	case 22, 23:
+		return nil, data[1:], nil
+	case 26:
+		if len(data) < 5 {
+			return nil, nil, errors.New("cbor: unexpected end of data")
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(data[1:]))), data[5:], nil
+	case 27:
+		if len(data) < 9 {
+			return nil, nil, errors.New("cbor: unexpected end of data")
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(data[1:])), data[9:], nil
+	}
+
+	return nil, nil, errors.New("cbor: unsupported simple value")
+}
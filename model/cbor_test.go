@@ -0,0 +1,60 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDecodeCBOR(t *testing.T) {
+	if v, rest, err := DecodeCBOR([]byte{0x18, 0x64, 0xff}); err != nil || v != int64(100) || !bytes.Equal(rest, []byte{0xff}) {
+		t.Fatal("should have decoded an unsigned integer and left the rest", v, rest, err)
+	}
+
+	if v, _, err := DecodeCBOR([]byte{0x38, 0x63}); err != nil || v != int64(-100) {
+		t.Fatal("should have decoded a negative integer", v, err)
+	}
+
+	if v, _, err := DecodeCBOR([]byte{0x43, 0x01, 0x02, 0x03}); err != nil || !bytes.Equal(v.([]byte), []byte{1, 2, 3}) {
+		t.Fatal("should have decoded a byte string", v, err)
+	}
+
+	if v, _, err := DecodeCBOR([]byte{0x63, 'f', 'm', 't'}); err != nil || v != "fmt" {
+		t.Fatal("should have decoded a text string", v, err)
+	}
+
+	// {1: 2, "a": [true, null]}
+	if v, _, err := DecodeCBOR([]byte{0xa2, 0x01, 0x02, 0x61, 'a', 0x82, 0xf5, 0xf6}); err != nil {
+		t.Fatal(err)
+	} else if m := v.(map[interface{}]interface{}); m[int64(1)] != int64(2) {
+		t.Fatal("should have decoded the integer key")
+	} else if a := m["a"].([]interface{}); len(a) != 2 || a[0] != true || a[1] != nil {
+		t.Fatal("should have decoded the nested array")
+	}
+
+	if v, _, err := DecodeCBOR([]byte{0xc2, 0x41, 0x01}); err != nil || !bytes.Equal(v.([]byte), []byte{1}) {
+		t.Fatal("should have skipped the tag", v, err)
+	}
+
+	for _, data := range [][]byte{
+		{},
+		{0x18},
+		{0x44, 0x01},
+		{0x82, 0x01},
+		{0x5f, 0x41, 0x01, 0xff},
+		{0xa1, 0x41, 0x01, 0x01},
+		{0x1b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		{0x9b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+	} {
+		if _, _, err := DecodeCBOR(data); err == nil {
+			t.Fatal("should have failed to decode", data)
+		}
+	}
+
+	nested := bytes.Repeat([]byte{0x81}, CBOR_MAX_DEPTH+2)
+	if _, _, err := DecodeCBOR(append(nested, 0x01)); err == nil {
+		t.Fatal("should have failed to decode deeply nested arrays")
+	}
+}
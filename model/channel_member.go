@@ -6,6 +6,7 @@ package model
 import (
 	"encoding/json"
 	"io"
+	"strconv"
 	"strings"
 )
 
@@ -16,6 +17,8 @@ const (
 	CHANNEL_NOTIFY_NONE         = "none"
 	CHANNEL_MARK_UNREAD_ALL     = "all"
 	CHANNEL_MARK_UNREAD_MENTION = "mention"
+
+	CHANNEL_MENTION_KEYS_MAX_LENGTH = 1000
 )
 
 type ChannelUnread struct {
@@ -100,6 +103,17 @@ func (o *ChannelMember) IsValid() *AppError {
 			nil, "mark_unread_level="+markUnreadLevel)
 	}
 
+	if mutedUntil, ok := o.NotifyProps["muted_until"]; ok && len(mutedUntil) > 0 {
+		if _, err := strconv.ParseInt(mutedUntil, 10, 64); err != nil {
+			return NewLocAppError("ChannelMember.IsValid", "model.channel_member.is_valid.muted_until.app_error",
+				nil, "muted_until="+mutedUntil)
+		}
+	}
+
+	if len(o.NotifyProps["mention_keys"]) > CHANNEL_MENTION_KEYS_MAX_LENGTH {
+		return NewLocAppError("ChannelMember.IsValid", "model.channel_member.is_valid.mention_keys.app_error", nil, "")
+	}
+
 	return nil
 }
 
@@ -115,6 +129,48 @@ func (o *ChannelMember) GetRoles() []string {
 	return strings.Fields(o.Roles)
 }
 
+// IsValidChannelRoles returns true if the roles can be given to a channel member. Only the channel scoped
+// roles are allowed so that managing a channel can't grant permissions outside of it.
+func IsValidChannelRoles(channelRoles string) bool {
+	roles := strings.Fields(channelRoles)
+	if len(roles) == 0 {
+		return false
+	}
+
+	for _, role := range roles {
+		if role != ROLE_CHANNEL_USER.Id && role != ROLE_CHANNEL_ADMIN.Id {
+			return false
+		}
+	}
+
+	return true
+}
+
+// GetMentionKeys returns the extra keywords that notify the user when used in this channel.
+func (o *ChannelMember) GetMentionKeys() []string {
+	keys := []string{}
+	for _, key := range strings.Split(o.NotifyProps["mention_keys"], ",") {
+		if key = strings.TrimSpace(key); len(key) > 0 {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+// IsMuted returns true if the user has muted the channel and the mute hasn't expired yet.
+func (o *ChannelMember) IsMuted() bool {
+	return IsChannelMutedByNotifyProps(o.NotifyProps)
+}
+
+func IsChannelMutedByNotifyProps(notifyProps StringMap) bool {
+	if mutedUntil, err := strconv.ParseInt(notifyProps["muted_until"], 10, 64); err == nil {
+		return mutedUntil > GetMillis()
+	}
+
+	return false
+}
+
 func IsChannelNotifyLevelValid(notifyLevel string) bool {
 	return notifyLevel == CHANNEL_NOTIFY_DEFAULT ||
 		notifyLevel == CHANNEL_NOTIFY_ALL ||
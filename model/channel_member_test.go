@@ -4,6 +4,7 @@
 package model
 
 import (
+	"strconv"
 	"strings"
 	"testing"
 )
@@ -64,8 +65,62 @@ func TestChannelMemberIsValid(t *testing.T) {
 		t.Fatal(err)
 	}
 
+	o.NotifyProps["muted_until"] = "junk"
+	if err := o.IsValid(); err == nil {
+		t.Fatal("should be invalid")
+	}
+
+	o.NotifyProps["muted_until"] = "1"
+	if err := o.IsValid(); err != nil {
+		t.Fatal(err)
+	}
+
+	o.NotifyProps["mention_keys"] = strings.Repeat("a", CHANNEL_MENTION_KEYS_MAX_LENGTH+1)
+	if err := o.IsValid(); err == nil {
+		t.Fatal("should be invalid")
+	}
+
+	o.NotifyProps["mention_keys"] = ""
 	o.Roles = ""
 	if err := o.IsValid(); err != nil {
 		t.Fatal(err)
 	}
 }
+
+func TestIsValidChannelRoles(t *testing.T) {
+	for _, roles := range []string{"channel_user", "channel_user channel_admin", " channel_admin "} {
+		if !IsValidChannelRoles(roles) {
+			t.Fatal("should be valid", roles)
+		}
+	}
+
+	for _, roles := range []string{"", "junk", "system_admin", "channel_user system_admin", "team_admin", "system_user", "channel_guest"} {
+		if IsValidChannelRoles(roles) {
+			t.Fatal("should be invalid", roles)
+		}
+	}
+}
+
+func TestChannelMemberMute(t *testing.T) {
+	o := ChannelMember{NotifyProps: GetDefaultChannelNotifyProps()}
+	if o.IsMuted() {
+		t.Fatal("should not be muted")
+	}
+
+	o.NotifyProps["muted_until"] = strconv.FormatInt(GetMillis()+60000, 10)
+	if !o.IsMuted() {
+		t.Fatal("should be muted")
+	}
+
+	o.NotifyProps["muted_until"] = strconv.FormatInt(GetMillis()-60000, 10)
+	if o.IsMuted() {
+		t.Fatal("mute should have expired")
+	}
+}
+
+func TestChannelMemberGetMentionKeys(t *testing.T) {
+	o := ChannelMember{NotifyProps: StringMap{"mention_keys": "deploy, outage,,"}}
+	if keys := o.GetMentionKeys(); len(keys) != 2 || keys[0] != "deploy" || keys[1] != "outage" {
+		t.Fatal("should have returned the trimmed keys")
+	}
+}
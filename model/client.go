@@ -127,6 +127,14 @@ func (c *Client) GetEmojiRoute() string {
 	return "/emoji"
 }
 
+func (c *Client) GetRoleRoute(roleId string) string {
+	return fmt.Sprintf("/roles/%v", roleId)
+}
+
+func (c *Client) GetUserGroupRoute(groupId string) string {
+	return fmt.Sprintf("/user_groups/%v", groupId)
+}
+
 func (c *Client) GetGeneralRoute() string {
 	return "/general"
 }
@@ -445,6 +453,18 @@ func (c *Client) InviteMembers(invites *Invites) (*Result, *AppError) {
 	}
 }
 
+// InviteGuests sends guest invitations for the current team. The invited users will
+// only have access to the channels listed in the invite. Must be a system admin.
+func (c *Client) InviteGuests(invite *GuestInvite) (*Result, *AppError) {
+	if r, err := c.DoApiPost(c.GetTeamRoute()+"/invite_guests", invite.ToJson()); err != nil {
+		return nil, err
+	} else {
+		defer closeBody(r)
+		return &Result{r.Header.Get(HEADER_REQUEST_ID),
+			r.Header.Get(HEADER_ETAG_SERVER), GuestInviteFromJson(r.Body)}, nil
+	}
+}
+
 // UpdateTeam updates a team based on the changes in the provided team struct. On success
 // it returns a sanitized version of the updated team. Must be authenticated as a team admin
 // for that team or a system admin.
@@ -673,6 +693,22 @@ func (c *Client) Login(loginId string, password string) (*Result, *AppError) {
 	return c.login(m)
 }
 
+// LoginWithMfa authenticates a user by login id, password and a second factor, which can be a TOTP
+// code or one of the user's recovery codes.
+func (c *Client) LoginWithMfa(loginId string, password string, mfaToken string) (*Result, *AppError) {
+	m := make(map[string]string)
+	m["login_id"] = loginId
+	m["password"] = password
+	m["token"] = mfaToken
+	return c.login(m)
+}
+
+// LoginWithWebAuthn authenticates a user by login id, password and an assertion signed by one of
+// their security keys over the challenge returned by BeginWebAuthnLogin.
+func (c *Client) LoginWithWebAuthn(loginId string, password string, assertion *WebAuthnAssertion) (*Result, *AppError) {
+	return c.LoginWithMfa(loginId, password, assertion.ToJson())
+}
+
 // LoginByLdap authenticates a user by LDAP id and password.
 func (c *Client) LoginByLdap(loginId string, password string) (*Result, *AppError) {
 	m := make(map[string]string)
@@ -772,6 +808,80 @@ func (c *Client) UpdateMfa(activate bool, token string) (*Result, *AppError) {
 	}
 }
 
+// BeginWebAuthnLogin returns the options to pass to the browser to sign a login challenge with one of
+// the user's security keys. The user's password is checked first since the options list their keys.
+func (c *Client) BeginWebAuthnLogin(loginId string, password string) (*Result, *AppError) {
+	m := make(map[string]string)
+	m["login_id"] = loginId
+	m["password"] = password
+
+	if r, err := c.DoApiPost("/users/webauthn/login/begin", MapToJson(m)); err != nil {
+		return nil, err
+	} else {
+		defer closeBody(r)
+		return &Result{r.Header.Get(HEADER_REQUEST_ID),
+			r.Header.Get(HEADER_ETAG_SERVER), WebAuthnOptionsFromJson(r.Body)}, nil
+	}
+}
+
+// BeginWebAuthnRegistration returns the options to pass to the browser to register a new security key
+// for the current user.
+func (c *Client) BeginWebAuthnRegistration() (*Result, *AppError) {
+	if r, err := c.DoApiPost("/users/webauthn/register/begin", ""); err != nil {
+		return nil, err
+	} else {
+		defer closeBody(r)
+		return &Result{r.Header.Get(HEADER_REQUEST_ID),
+			r.Header.Get(HEADER_ETAG_SERVER), WebAuthnOptionsFromJson(r.Body)}, nil
+	}
+}
+
+// FinishWebAuthnRegistration saves the security key that the browser created in response to
+// BeginWebAuthnRegistration.
+func (c *Client) FinishWebAuthnRegistration(registration *WebAuthnRegistration) (*Result, *AppError) {
+	if r, err := c.DoApiPost("/users/webauthn/register/finish", registration.ToJson()); err != nil {
+		return nil, err
+	} else {
+		defer closeBody(r)
+		return &Result{r.Header.Get(HEADER_REQUEST_ID),
+			r.Header.Get(HEADER_ETAG_SERVER), WebAuthnCredentialFromJson(r.Body)}, nil
+	}
+}
+
+// GetWebAuthnCredentials returns the security keys registered by the current user.
+func (c *Client) GetWebAuthnCredentials() (*Result, *AppError) {
+	if r, err := c.DoApiGet("/users/webauthn/credentials", "", ""); err != nil {
+		return nil, err
+	} else {
+		defer closeBody(r)
+		return &Result{r.Header.Get(HEADER_REQUEST_ID),
+			r.Header.Get(HEADER_ETAG_SERVER), WebAuthnCredentialsFromJson(r.Body)}, nil
+	}
+}
+
+// DeleteWebAuthnCredential removes one of the current user's security keys.
+func (c *Client) DeleteWebAuthnCredential(id string) (*Result, *AppError) {
+	if r, err := c.DoApiPost("/users/webauthn/credentials/"+id+"/delete", ""); err != nil {
+		return nil, err
+	} else {
+		defer closeBody(r)
+		return &Result{r.Header.Get(HEADER_REQUEST_ID),
+			r.Header.Get(HEADER_ETAG_SERVER), MapFromJson(r.Body)}, nil
+	}
+}
+
+// GenerateMfaRecoveryCodes replaces the current user's recovery codes with a new set and returns them.
+// Only hashes of the codes are kept so this is the only time they can be seen.
+func (c *Client) GenerateMfaRecoveryCodes() (*Result, *AppError) {
+	if r, err := c.DoApiPost("/users/mfa/recovery_codes", ""); err != nil {
+		return nil, err
+	} else {
+		defer closeBody(r)
+		return &Result{r.Header.Get(HEADER_REQUEST_ID),
+			r.Header.Get(HEADER_ETAG_SERVER), ArrayFromJson(r.Body)}, nil
+	}
+}
+
 func (c *Client) AdminResetMfa(userId string) (*Result, *AppError) {
 	m := make(map[string]string)
 	m["user_id"] = userId
@@ -798,6 +908,42 @@ func (c *Client) RevokeSession(sessionAltId string) (*Result, *AppError) {
 	}
 }
 
+// RevokeAllOtherSessions logs the current user out of every device except this one
+func (c *Client) RevokeAllOtherSessions() (*Result, *AppError) {
+	if r, err := c.DoApiPost("/users/revoke_all_other_sessions", ""); err != nil {
+		return nil, err
+	} else {
+		defer closeBody(r)
+		return &Result{r.Header.Get(HEADER_REQUEST_ID),
+			r.Header.Get(HEADER_ETAG_SERVER), MapFromJson(r.Body)}, nil
+	}
+}
+
+// AdminRevokeAllSessions logs each of the given users out of every device. Must be authenticated as a system admin.
+func (c *Client) AdminRevokeAllSessions(userIds []string) (*Result, *AppError) {
+	if r, err := c.DoApiPost("/admin/revoke_all_sessions", ArrayToJson(userIds)); err != nil {
+		return nil, err
+	} else {
+		defer closeBody(r)
+		return &Result{r.Header.Get(HEADER_REQUEST_ID),
+			r.Header.Get(HEADER_ETAG_SERVER), MapFromJson(r.Body)}, nil
+	}
+}
+
+// AdminUnlockUser clears the failed login attempts of a user who has been locked out. Must be a system
+// administrator.
+func (c *Client) AdminUnlockUser(userId string) (*Result, *AppError) {
+	m := make(map[string]string)
+	m["user_id"] = userId
+	if r, err := c.DoApiPost("/admin/unlock_user", MapToJson(m)); err != nil {
+		return nil, err
+	} else {
+		defer closeBody(r)
+		return &Result{r.Header.Get(HEADER_REQUEST_ID),
+			r.Header.Get(HEADER_ETAG_SERVER), MapFromJson(r.Body)}, nil
+	}
+}
+
 func (c *Client) GetSessions(id string) (*Result, *AppError) {
 	if r, err := c.DoApiGet("/users/"+id+"/sessions", "", ""); err != nil {
 		return nil, err
@@ -1124,6 +1270,161 @@ func (c *Client) LdapSyncNow() (*Result, *AppError) {
 	}
 }
 
+// GetLdapGroups returns the groups in the directory that can be linked to teams and channels.
+// You must be the system administrator to use this function.
+func (c *Client) GetLdapGroups() (*Result, *AppError) {
+	if r, err := c.DoApiGet("/admin/ldap_groups", "", ""); err != nil {
+		return nil, err
+	} else {
+		defer closeBody(r)
+		return &Result{r.Header.Get(HEADER_REQUEST_ID),
+			r.Header.Get(HEADER_ETAG_SERVER), LdapGroupsFromJson(r.Body)}, nil
+	}
+}
+
+// GetLdapGroupLinks returns the links between directory groups and teams or channels.
+// You must be the system administrator to use this function.
+func (c *Client) GetLdapGroupLinks() (*Result, *AppError) {
+	if r, err := c.DoApiGet("/admin/ldap_group_links", "", ""); err != nil {
+		return nil, err
+	} else {
+		defer closeBody(r)
+		return &Result{r.Header.Get(HEADER_REQUEST_ID),
+			r.Header.Get(HEADER_ETAG_SERVER), LdapGroupLinksFromJson(r.Body)}, nil
+	}
+}
+
+// LinkLdapGroup links a directory group to a team or, when the link has a channel id, to a private
+// channel so that synchronization keeps their members in step with the group.
+// You must be the system administrator to use this function.
+func (c *Client) LinkLdapGroup(link *LdapGroupLink) (*Result, *AppError) {
+	if r, err := c.DoApiPost("/admin/link_ldap_group", link.ToJson()); err != nil {
+		return nil, err
+	} else {
+		defer closeBody(r)
+		return &Result{r.Header.Get(HEADER_REQUEST_ID),
+			r.Header.Get(HEADER_ETAG_SERVER), LdapGroupLinkFromJson(r.Body)}, nil
+	}
+}
+
+// UnlinkLdapGroup removes a link between a directory group and a team or channel. Members
+// stay where they are.
+// You must be the system administrator to use this function.
+func (c *Client) UnlinkLdapGroup(id string) (*Result, *AppError) {
+	m := make(map[string]string)
+	m["id"] = id
+	if r, err := c.DoApiPost("/admin/unlink_ldap_group", MapToJson(m)); err != nil {
+		return nil, err
+	} else {
+		defer closeBody(r)
+		return &Result{r.Header.Get(HEADER_REQUEST_ID),
+			r.Header.Get(HEADER_ETAG_SERVER), MapFromJson(r.Body)}, nil
+	}
+}
+
+// SyncLdapGroups synchronizes the members of every linked team and channel and returns a report
+// of the changes. With dryRun set the changes are reported without being made.
+// You must be the system administrator to use this function.
+func (c *Client) SyncLdapGroups(dryRun bool) (*Result, *AppError) {
+	m := make(map[string]string)
+	m["dry_run"] = strconv.FormatBool(dryRun)
+	if r, err := c.DoApiPost("/admin/ldap_group_sync", MapToJson(m)); err != nil {
+		return nil, err
+	} else {
+		defer closeBody(r)
+		return &Result{r.Header.Get(HEADER_REQUEST_ID),
+			r.Header.Get(HEADER_ETAG_SERVER), LdapGroupSyncReportFromJson(r.Body)}, nil
+	}
+}
+
+// GetProfileAttributes returns the custom attributes that admins have added to user profiles.
+func (c *Client) GetProfileAttributes() (*Result, *AppError) {
+	if r, err := c.DoApiGet("/users/profile_attributes", "", ""); err != nil {
+		return nil, err
+	} else {
+		defer closeBody(r)
+		return &Result{r.Header.Get(HEADER_REQUEST_ID),
+			r.Header.Get(HEADER_ETAG_SERVER), ProfileAttributesFromJson(r.Body)}, nil
+	}
+}
+
+// AddProfileAttribute adds a custom attribute to user profiles.
+// You must be the system administrator to use this function.
+func (c *Client) AddProfileAttribute(attribute *ProfileAttribute) (*Result, *AppError) {
+	if r, err := c.DoApiPost("/admin/add_profile_attribute", attribute.ToJson()); err != nil {
+		return nil, err
+	} else {
+		defer closeBody(r)
+		return &Result{r.Header.Get(HEADER_REQUEST_ID),
+			r.Header.Get(HEADER_ETAG_SERVER), ProfileAttributeFromJson(r.Body)}, nil
+	}
+}
+
+// UpdateProfileAttribute updates a custom profile attribute. The name of an attribute can't be changed.
+// You must be the system administrator to use this function.
+func (c *Client) UpdateProfileAttribute(attribute *ProfileAttribute) (*Result, *AppError) {
+	if r, err := c.DoApiPost("/admin/update_profile_attribute", attribute.ToJson()); err != nil {
+		return nil, err
+	} else {
+		defer closeBody(r)
+		return &Result{r.Header.Get(HEADER_REQUEST_ID),
+			r.Header.Get(HEADER_ETAG_SERVER), ProfileAttributeFromJson(r.Body)}, nil
+	}
+}
+
+// DeleteProfileAttribute removes a custom profile attribute and hides the values users saved for it.
+// You must be the system administrator to use this function.
+func (c *Client) DeleteProfileAttribute(id string) (*Result, *AppError) {
+	m := make(map[string]string)
+	m["id"] = id
+	if r, err := c.DoApiPost("/admin/delete_profile_attribute", MapToJson(m)); err != nil {
+		return nil, err
+	} else {
+		defer closeBody(r)
+		return &Result{r.Header.Get(HEADER_REQUEST_ID),
+			r.Header.Get(HEADER_ETAG_SERVER), MapFromJson(r.Body)}, nil
+	}
+}
+
+// GetMailQueueStats returns the number of emails waiting in the outbound mail queue and the most recent
+// failures. You must be the system administrator to use this function.
+func (c *Client) GetMailQueueStats() (*Result, *AppError) {
+	if r, err := c.DoApiGet("/admin/mail_queue", "", ""); err != nil {
+		return nil, err
+	} else {
+		defer closeBody(r)
+		return &Result{r.Header.Get(HEADER_REQUEST_ID),
+			r.Header.Get(HEADER_ETAG_SERVER), MailQueueStatsFromJson(r.Body)}, nil
+	}
+}
+
+// MarkEmailBouncing stops notification emails from being sent to an address that mail can't be delivered
+// to. You must be the system administrator to use this function.
+func (c *Client) MarkEmailBouncing(email string, reason string) (*Result, *AppError) {
+	bounce := &EmailBounce{Email: email, Reason: reason}
+	if r, err := c.DoApiPost("/admin/mark_email_bouncing", bounce.ToJson()); err != nil {
+		return nil, err
+	} else {
+		defer closeBody(r)
+		return &Result{r.Header.Get(HEADER_REQUEST_ID),
+			r.Header.Get(HEADER_ETAG_SERVER), MapFromJson(r.Body)}, nil
+	}
+}
+
+// UnmarkEmailBouncing lets notification emails be sent to an address again. You must be the system
+// administrator to use this function.
+func (c *Client) UnmarkEmailBouncing(email string) (*Result, *AppError) {
+	m := make(map[string]string)
+	m["email"] = email
+	if r, err := c.DoApiPost("/admin/unmark_email_bouncing", MapToJson(m)); err != nil {
+		return nil, err
+	} else {
+		defer closeBody(r)
+		return &Result{r.Header.Get(HEADER_REQUEST_ID),
+			r.Header.Get(HEADER_ETAG_SERVER), MapFromJson(r.Body)}, nil
+	}
+}
+
 func (c *Client) CreateChannel(channel *Channel) (*Result, *AppError) {
 	if r, err := c.DoApiPost(c.GetTeamRoute()+"/channels/create", channel.ToJson()); err != nil {
 		return nil, err
@@ -1656,9 +1957,30 @@ func (c *Client) UpdateTeamRoles(userId string, roles string) (*Result, *AppErro
 	}
 }
 
+func (c *Client) UpdateChannelRoles(channelId string, userId string, roles string) (*Result, *AppError) {
+	data := make(map[string]string)
+	data["new_roles"] = roles
+	data["user_id"] = userId
+
+	if r, err := c.DoApiPost(c.GetChannelRoute(channelId)+"/update_member_roles", MapToJson(data)); err != nil {
+		return nil, err
+	} else {
+		defer closeBody(r)
+		return &Result{r.Header.Get(HEADER_REQUEST_ID),
+			r.Header.Get(HEADER_ETAG_SERVER), MapFromJson(r.Body)}, nil
+	}
+}
+
 func (c *Client) AttachDeviceId(deviceId string) (*Result, *AppError) {
+	return c.AttachDeviceIdWithKey(deviceId, "")
+}
+
+// AttachDeviceIdWithKey attaches a mobile device to the session along with the base64 encoded P-256 public
+// key that push notifications should be encrypted with
+func (c *Client) AttachDeviceIdWithKey(deviceId string, deviceKey string) (*Result, *AppError) {
 	data := make(map[string]string)
 	data["device_id"] = deviceId
+	data["device_key"] = deviceKey
 	if r, err := c.DoApiPost("/users/attach_device", MapToJson(data)); err != nil {
 		return nil, err
 	} else {
@@ -1681,6 +2003,31 @@ func (c *Client) UpdateActive(userId string, active bool) (*Result, *AppError) {
 	}
 }
 
+// ConvertUserToGuest turns a regular user into a guest. An expiresAt of 0 means the
+// guest account never expires. Must be a system admin.
+func (c *Client) ConvertUserToGuest(userId string, expiresAt int64) (*Result, *AppError) {
+	data := make(map[string]string)
+	data["expires_at"] = strconv.FormatInt(expiresAt, 10)
+	if r, err := c.DoApiPost(c.GetUserRequiredRoute(userId)+"/convert_to_guest", MapToJson(data)); err != nil {
+		return nil, err
+	} else {
+		defer closeBody(r)
+		return &Result{r.Header.Get(HEADER_REQUEST_ID),
+			r.Header.Get(HEADER_ETAG_SERVER), UserFromJson(r.Body)}, nil
+	}
+}
+
+// ConvertGuestToUser turns a guest back into a regular user. Must be a system admin.
+func (c *Client) ConvertGuestToUser(userId string) (*Result, *AppError) {
+	if r, err := c.DoApiPost(c.GetUserRequiredRoute(userId)+"/convert_to_user", ""); err != nil {
+		return nil, err
+	} else {
+		defer closeBody(r)
+		return &Result{r.Header.Get(HEADER_REQUEST_ID),
+			r.Header.Get(HEADER_ETAG_SERVER), UserFromJson(r.Body)}, nil
+	}
+}
+
 func (c *Client) UpdateUserNotify(data map[string]string) (*Result, *AppError) {
 	if r, err := c.DoApiPost("/users/update_notify", MapToJson(data)); err != nil {
 		return nil, err
@@ -1767,6 +2114,40 @@ func (c *Client) GetStatusesByIds(userIds []string) (*Result, *AppError) {
 	}
 }
 
+// GetStatusesAndCustomStatusesByIds returns the statuses of the provided user ids along with the custom
+// statuses of the ones that have a custom status
+func (c *Client) GetStatusesAndCustomStatusesByIds(userIds []string) (*Result, *AppError) {
+	if r, err := c.DoApiPost("/users/status/ids?include_custom=true", ArrayToJson(userIds)); err != nil {
+		return nil, err
+	} else {
+		defer closeBody(r)
+		return &Result{r.Header.Get(HEADER_REQUEST_ID),
+			r.Header.Get(HEADER_ETAG_SERVER), StatusesWithCustomStatusesFromJson(r.Body)}, nil
+	}
+}
+
+// SetCustomStatus sets the custom status of the logged in user.
+func (c *Client) SetCustomStatus(customStatus *CustomStatus) (*Result, *AppError) {
+	if r, err := c.DoApiPost("/users/status/custom/set", customStatus.ToJson()); err != nil {
+		return nil, err
+	} else {
+		defer closeBody(r)
+		return &Result{r.Header.Get(HEADER_REQUEST_ID),
+			r.Header.Get(HEADER_ETAG_SERVER), CustomStatusFromJson(r.Body)}, nil
+	}
+}
+
+// ClearCustomStatus clears the custom status of the logged in user.
+func (c *Client) ClearCustomStatus() (*Result, *AppError) {
+	if r, err := c.DoApiPost("/users/status/custom/clear", ""); err != nil {
+		return nil, err
+	} else {
+		defer closeBody(r)
+		return &Result{r.Header.Get(HEADER_REQUEST_ID),
+			r.Header.Get(HEADER_ETAG_SERVER), MapFromJson(r.Body)}, nil
+	}
+}
+
 // SetActiveChannel sets the the channel id the user is currently viewing.
 // The channelId key is required but the value can be blank. Returns standard
 // response.
@@ -2331,3 +2712,286 @@ func (c *Client) ListReactions(channelId string, postId string) ([]*Reaction, *A
 		return ReactionsFromJson(r.Body), nil
 	}
 }
+
+// GetAllRoles returns every role along with the permissions it currently grants. Must be a system admin.
+func (c *Client) GetAllRoles() ([]*Role, *AppError) {
+	if r, err := c.DoApiGet("/roles/list", "", ""); err != nil {
+		return nil, err
+	} else {
+		defer closeBody(r)
+		c.fillInExtraProperties(r)
+		return RoleListFromJson(r.Body), nil
+	}
+}
+
+// GetRole returns a single role. Must be a system admin.
+func (c *Client) GetRole(roleId string) (*Role, *AppError) {
+	if r, err := c.DoApiGet(c.GetRoleRoute(roleId)+"/get", "", ""); err != nil {
+		return nil, err
+	} else {
+		defer closeBody(r)
+		c.fillInExtraProperties(r)
+		return RoleFromJson(r.Body), nil
+	}
+}
+
+// UpdateRole replaces the permissions granted by a role. Only the Id and Permissions fields
+// of the provided role are used. Must be a system admin.
+func (c *Client) UpdateRole(role *Role) (*Role, *AppError) {
+	if r, err := c.DoApiPost(c.GetRoleRoute(role.Id)+"/update", role.ToJson()); err != nil {
+		return nil, err
+	} else {
+		defer closeBody(r)
+		c.fillInExtraProperties(r)
+		return RoleFromJson(r.Body), nil
+	}
+}
+
+// ResetRole discards any changes made to a role and returns the role with its default
+// permissions. Must be a system admin.
+func (c *Client) ResetRole(roleId string) (*Role, *AppError) {
+	if r, err := c.DoApiPost(c.GetRoleRoute(roleId)+"/reset", ""); err != nil {
+		return nil, err
+	} else {
+		defer closeBody(r)
+		c.fillInExtraProperties(r)
+		return RoleFromJson(r.Body), nil
+	}
+}
+
+// GetTeamScheme returns the permission scheme of the current team. Must be a system admin.
+func (c *Client) GetTeamScheme() (*Scheme, *AppError) {
+	if r, err := c.DoApiGet(c.GetTeamRoute()+"/scheme/get", "", ""); err != nil {
+		return nil, err
+	} else {
+		defer closeBody(r)
+		c.fillInExtraProperties(r)
+		return SchemeFromJson(r.Body), nil
+	}
+}
+
+// UpdateTeamScheme sets the permission scheme of the current team, overriding the permissions
+// of the team and channel roles within that team. Must be a system admin.
+func (c *Client) UpdateTeamScheme(scheme *Scheme) (*Scheme, *AppError) {
+	if r, err := c.DoApiPost(c.GetTeamRoute()+"/scheme/update", scheme.ToJson()); err != nil {
+		return nil, err
+	} else {
+		defer closeBody(r)
+		c.fillInExtraProperties(r)
+		return SchemeFromJson(r.Body), nil
+	}
+}
+
+// DeleteTeamScheme removes the permission scheme of the current team so it uses the system
+// wide roles again. Must be a system admin.
+func (c *Client) DeleteTeamScheme() *AppError {
+	if r, err := c.DoApiPost(c.GetTeamRoute()+"/scheme/delete", ""); err != nil {
+		return err
+	} else {
+		defer closeBody(r)
+		c.fillInExtraProperties(r)
+		return nil
+	}
+}
+
+// GetUserGroups returns all of the user groups that can be mentioned.
+func (c *Client) GetUserGroups() (*Result, *AppError) {
+	if r, err := c.DoApiGet("/user_groups/list", "", ""); err != nil {
+		return nil, err
+	} else {
+		defer closeBody(r)
+		return &Result{r.Header.Get(HEADER_REQUEST_ID),
+			r.Header.Get(HEADER_ETAG_SERVER), UserGroupsFromJson(r.Body)}, nil
+	}
+}
+
+// CreateUserGroup creates a group of users that are all mentioned by @ followed by the group's name.
+// Must be a system admin.
+func (c *Client) CreateUserGroup(group *UserGroup) (*Result, *AppError) {
+	if r, err := c.DoApiPost("/user_groups/create", group.ToJson()); err != nil {
+		return nil, err
+	} else {
+		defer closeBody(r)
+		return &Result{r.Header.Get(HEADER_REQUEST_ID),
+			r.Header.Get(HEADER_ETAG_SERVER), UserGroupFromJson(r.Body)}, nil
+	}
+}
+
+func (c *Client) GetUserGroup(groupId string) (*Result, *AppError) {
+	if r, err := c.DoApiGet(c.GetUserGroupRoute(groupId)+"/get", "", ""); err != nil {
+		return nil, err
+	} else {
+		defer closeBody(r)
+		return &Result{r.Header.Get(HEADER_REQUEST_ID),
+			r.Header.Get(HEADER_ETAG_SERVER), UserGroupFromJson(r.Body)}, nil
+	}
+}
+
+// UpdateUserGroup updates the name, description and settings of a user group. Must be a system admin.
+func (c *Client) UpdateUserGroup(group *UserGroup) (*Result, *AppError) {
+	if r, err := c.DoApiPost(c.GetUserGroupRoute(group.Id)+"/update", group.ToJson()); err != nil {
+		return nil, err
+	} else {
+		defer closeBody(r)
+		return &Result{r.Header.Get(HEADER_REQUEST_ID),
+			r.Header.Get(HEADER_ETAG_SERVER), UserGroupFromJson(r.Body)}, nil
+	}
+}
+
+// DeleteUserGroup deletes a user group along with its members. Must be a system admin.
+func (c *Client) DeleteUserGroup(groupId string) (*Result, *AppError) {
+	if r, err := c.DoApiPost(c.GetUserGroupRoute(groupId)+"/delete", ""); err != nil {
+		return nil, err
+	} else {
+		defer closeBody(r)
+		return &Result{r.Header.Get(HEADER_REQUEST_ID),
+			r.Header.Get(HEADER_ETAG_SERVER), MapFromJson(r.Body)}, nil
+	}
+}
+
+// GetUserGroupMembers returns the ids of the users in a group.
+func (c *Client) GetUserGroupMembers(groupId string) (*Result, *AppError) {
+	if r, err := c.DoApiGet(c.GetUserGroupRoute(groupId)+"/members", "", ""); err != nil {
+		return nil, err
+	} else {
+		defer closeBody(r)
+		return &Result{r.Header.Get(HEADER_REQUEST_ID),
+			r.Header.Get(HEADER_ETAG_SERVER), ArrayFromJson(r.Body)}, nil
+	}
+}
+
+// AddUserGroupMember adds a user to a group. Must be a system admin or, if the group is managed by its
+// members, a member of the group.
+func (c *Client) AddUserGroupMember(groupId string, userId string) (*Result, *AppError) {
+	data := make(map[string]string)
+	data["user_id"] = userId
+	if r, err := c.DoApiPost(c.GetUserGroupRoute(groupId)+"/add_member", MapToJson(data)); err != nil {
+		return nil, err
+	} else {
+		defer closeBody(r)
+		return &Result{r.Header.Get(HEADER_REQUEST_ID),
+			r.Header.Get(HEADER_ETAG_SERVER), MapFromJson(r.Body)}, nil
+	}
+}
+
+// RemoveUserGroupMember removes a user from a group. Must be a system admin or, if the group is managed by
+// its members, a member of the group.
+func (c *Client) RemoveUserGroupMember(groupId string, userId string) (*Result, *AppError) {
+	data := make(map[string]string)
+	data["user_id"] = userId
+	if r, err := c.DoApiPost(c.GetUserGroupRoute(groupId)+"/remove_member", MapToJson(data)); err != nil {
+		return nil, err
+	} else {
+		defer closeBody(r)
+		return &Result{r.Header.Get(HEADER_REQUEST_ID),
+			r.Header.Get(HEADER_ETAG_SERVER), MapFromJson(r.Body)}, nil
+	}
+}
+
+// GetFollowedThreads returns a page of the threads that the user follows on the current team, starting with
+// the ones with the most recent replies.
+func (c *Client) GetFollowedThreads(offset int, limit int) (*Result, *AppError) {
+	if r, err := c.DoApiGet(c.GetTeamRoute()+fmt.Sprintf("/posts/threads/%v/%v", offset, limit), "", ""); err != nil {
+		return nil, err
+	} else {
+		defer closeBody(r)
+		return &Result{r.Header.Get(HEADER_REQUEST_ID),
+			r.Header.Get(HEADER_ETAG_SERVER), ThreadMembershipsFromJson(r.Body)}, nil
+	}
+}
+
+// FollowThread notifies the user of replies to the thread of a root post
+func (c *Client) FollowThread(channelId string, postId string) (*Result, *AppError) {
+	return c.updateThread(channelId, postId, "follow")
+}
+
+// UnfollowThread stops notifying the user of replies to the thread of a root post
+func (c *Client) UnfollowThread(channelId string, postId string) (*Result, *AppError) {
+	return c.updateThread(channelId, postId, "unfollow")
+}
+
+// MuteThread stops notifying the user of replies to the thread of a root post, even if they keep
+// participating in it
+func (c *Client) MuteThread(channelId string, postId string) (*Result, *AppError) {
+	return c.updateThread(channelId, postId, "mute")
+}
+
+func (c *Client) UnmuteThread(channelId string, postId string) (*Result, *AppError) {
+	return c.updateThread(channelId, postId, "unmute")
+}
+
+func (c *Client) updateThread(channelId string, postId string, action string) (*Result, *AppError) {
+	if r, err := c.DoApiPost(c.GetChannelRoute(channelId)+fmt.Sprintf("/posts/%v/%v", postId, action), ""); err != nil {
+		return nil, err
+	} else {
+		defer closeBody(r)
+		return &Result{r.Header.Get(HEADER_REQUEST_ID),
+			r.Header.Get(HEADER_ETAG_SERVER), ThreadMembershipFromJson(r.Body)}, nil
+	}
+}
+
+// ViewThread marks the replies to the thread of a root post as read
+func (c *Client) ViewThread(channelId string, postId string) (*Result, *AppError) {
+	if r, err := c.DoApiPost(c.GetChannelRoute(channelId)+fmt.Sprintf("/posts/%v/view_thread", postId), ""); err != nil {
+		return nil, err
+	} else {
+		defer closeBody(r)
+		return &Result{r.Header.Get(HEADER_REQUEST_ID),
+			r.Header.Get(HEADER_ETAG_SERVER), MapFromJson(r.Body)}, nil
+	}
+}
+
+// GetReminders returns the user's reminders, including the ones that were sent recently, in the order that
+// they're due.
+func (c *Client) GetReminders() (*Result, *AppError) {
+	if r, err := c.DoApiGet("/reminders/list", "", ""); err != nil {
+		return nil, err
+	} else {
+		defer closeBody(r)
+		return &Result{r.Header.Get(HEADER_REQUEST_ID),
+			r.Header.Get(HEADER_ETAG_SERVER), RemindersFromJson(r.Body)}, nil
+	}
+}
+
+// RemindOfPost reminds the user of a post with an optional message. When is either a time such as "in 2 hours"
+// or "tomorrow 9am" in the user's time zone or a number of milliseconds since the epoch.
+func (c *Client) RemindOfPost(channelId string, postId string, when string, message string) (*Result, *AppError) {
+	if r, err := c.DoApiPost(c.GetChannelRoute(channelId)+fmt.Sprintf("/posts/%v/remind", postId), MapToJson(reminderTimeProps(when, map[string]string{"message": message}))); err != nil {
+		return nil, err
+	} else {
+		defer closeBody(r)
+		return &Result{r.Header.Get(HEADER_REQUEST_ID),
+			r.Header.Get(HEADER_ETAG_SERVER), ReminderFromJson(r.Body)}, nil
+	}
+}
+
+// SnoozeReminder moves a reminder to a later time, sending it again if it was already sent.
+func (c *Client) SnoozeReminder(reminderId string, when string) (*Result, *AppError) {
+	if r, err := c.DoApiPost("/reminders/"+reminderId+"/snooze", MapToJson(reminderTimeProps(when, map[string]string{}))); err != nil {
+		return nil, err
+	} else {
+		defer closeBody(r)
+		return &Result{r.Header.Get(HEADER_REQUEST_ID),
+			r.Header.Get(HEADER_ETAG_SERVER), ReminderFromJson(r.Body)}, nil
+	}
+}
+
+func (c *Client) DeleteReminder(reminderId string) (*Result, *AppError) {
+	if r, err := c.DoApiPost("/reminders/"+reminderId+"/delete", ""); err != nil {
+		return nil, err
+	} else {
+		defer closeBody(r)
+		return &Result{r.Header.Get(HEADER_REQUEST_ID),
+			r.Header.Get(HEADER_ETAG_SERVER), MapFromJson(r.Body)}, nil
+	}
+}
+
+func reminderTimeProps(when string, props map[string]string) map[string]string {
+	if _, err := strconv.ParseInt(when, 10, 64); err == nil {
+		props["remind_at"] = when
+	} else {
+		props["when"] = when
+	}
+
+	return props
+}
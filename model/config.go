@@ -7,6 +7,7 @@ import (
 	"encoding/json"
 	"io"
 	"net/url"
+	"strings"
 )
 
 const (
@@ -21,19 +22,23 @@ const (
 	DATABASE_DRIVER_MYSQL    = "mysql"
 	DATABASE_DRIVER_POSTGRES = "postgres"
 
-	PASSWORD_MAXIMUM_LENGTH = 64
-	PASSWORD_MINIMUM_LENGTH = 5
+	PASSWORD_MAXIMUM_LENGTH  = 64
+	PASSWORD_MINIMUM_LENGTH  = 5
+	PASSWORD_HISTORY_MAXIMUM = 24
 
 	SERVICE_GITLAB    = "gitlab"
 	SERVICE_GOOGLE    = "google"
 	SERVICE_OFFICE365 = "office365"
+	SERVICE_OPENID    = "openid"
 
 	WEBSERVER_MODE_REGULAR  = "regular"
 	WEBSERVER_MODE_GZIP     = "gzip"
 	WEBSERVER_MODE_DISABLED = "disabled"
 
-	GENERIC_NOTIFICATION = "generic"
-	FULL_NOTIFICATION    = "full"
+	GENERIC_NOTIFICATION   = "generic"
+	FULL_NOTIFICATION      = "full"
+	ID_LOADED_NOTIFICATION = "id_loaded"
+	ENCRYPTED_NOTIFICATION = "encrypted"
 
 	DIRECT_MESSAGE_ANY  = "any"
 	DIRECT_MESSAGE_TEAM = "team"
@@ -52,6 +57,10 @@ const (
 	EMAIL_BATCHING_BUFFER_SIZE = 256
 	EMAIL_BATCHING_INTERVAL    = 30
 
+	MAIL_QUEUE_WORKERS       = 2
+	MAIL_QUEUE_MAX_ATTEMPTS  = 5
+	MAX_EMAILS_PER_RECIPIENT = 60
+
 	SITENAME_MAX_LENGTH = 30
 )
 
@@ -82,11 +91,18 @@ type ServiceSettings struct {
 	EnableInsecureOutgoingConnections *bool
 	EnableMultifactorAuthentication   *bool
 	EnforceMultifactorAuthentication  *bool
+	EnforceSecurityKeysForAdmins      *bool
+	WebAuthnChallengeSalt             *string
 	AllowCorsFrom                     *string
 	SessionLengthWebInDays            *int
 	SessionLengthMobileInDays         *int
 	SessionLengthSSOInDays            *int
 	SessionCacheInMinutes             *int
+	SessionIdleTimeoutInMinutes       *int
+	MaximumSessionsPerUser            *int
+	LoginLockoutDurationInMinutes     *int
+	LoginAttemptsPerMinutePerIp       *int
+	LoginAttemptsPerMinutePerLoginId  *int
 	WebsocketSecurePort               *int
 	WebsocketPort                     *int
 	WebserverMode                     *string
@@ -142,11 +158,14 @@ type LogSettings struct {
 }
 
 type PasswordSettings struct {
-	MinimumLength *int
-	Lowercase     *bool
-	Number        *bool
-	Uppercase     *bool
-	Symbol        *bool
+	MinimumLength            *int
+	Lowercase                *bool
+	Number                   *bool
+	Uppercase                *bool
+	Symbol                   *bool
+	HistoryCount             *int
+	MaximumAgeInDays         *int
+	BreachedPasswordHashFile *string
 }
 
 type FileSettings struct {
@@ -192,6 +211,23 @@ type EmailSettings struct {
 	EnableEmailBatching      *bool
 	EmailBatchingBufferSize  *int
 	EmailBatchingInterval    *int
+	MailQueueWorkers         *int
+	MailQueueMaxAttempts     *int
+	MaxEmailsPerRecipient    *int
+	EnableEmailReplies       *bool
+	ReplyToAddress           *string
+	ReplyToSalt              *string
+	InboundSMTPListenAddress *string
+	PushNotificationSender   *string
+	PushMaxAttempts          *int
+	APNSServer               *string
+	APNSKeyFile              *string
+	APNSKeyId                *string
+	APNSTeamId               *string
+	APNSTopic                *string
+	FCMServer                *string
+	FCMServiceAccountFile    *string
+	HttpNotificationURL      *string
 }
 
 type RateLimitSettings struct {
@@ -329,6 +365,24 @@ type WebrtcSettings struct {
 	TurnSharedKey       *string
 }
 
+type OpenIdSettings struct {
+	Enable            *bool
+	ButtonText        *string
+	Id                *string
+	Secret            *string
+	Scope             *string
+	DiscoveryEndpoint *string
+	UsernameClaim     *string
+	EmailClaim        *string
+	FirstNameClaim    *string
+	LastNameClaim     *string
+}
+
+type ScimSettings struct {
+	Enable *bool
+	Token  *string
+}
+
 type Config struct {
 	ServiceSettings      ServiceSettings
 	TeamSettings         TeamSettings
@@ -343,6 +397,8 @@ type Config struct {
 	GitLabSettings       SSOSettings
 	GoogleSettings       SSOSettings
 	Office365Settings    SSOSettings
+	OpenIdSettings       OpenIdSettings
+	ScimSettings         ScimSettings
 	LdapSettings         LdapSettings
 	ComplianceSettings   ComplianceSettings
 	LocalizationSettings LocalizationSettings
@@ -461,6 +517,16 @@ func (o *Config) SetDefaults() {
 		*o.ServiceSettings.EnforceMultifactorAuthentication = false
 	}
 
+	if o.ServiceSettings.EnforceSecurityKeysForAdmins == nil {
+		o.ServiceSettings.EnforceSecurityKeysForAdmins = new(bool)
+		*o.ServiceSettings.EnforceSecurityKeysForAdmins = false
+	}
+
+	if o.ServiceSettings.WebAuthnChallengeSalt == nil || len(*o.ServiceSettings.WebAuthnChallengeSalt) == 0 {
+		o.ServiceSettings.WebAuthnChallengeSalt = new(string)
+		*o.ServiceSettings.WebAuthnChallengeSalt = NewRandomString(32)
+	}
+
 	if o.PasswordSettings.MinimumLength == nil {
 		o.PasswordSettings.MinimumLength = new(int)
 		*o.PasswordSettings.MinimumLength = PASSWORD_MINIMUM_LENGTH
@@ -486,6 +552,21 @@ func (o *Config) SetDefaults() {
 		*o.PasswordSettings.Symbol = false
 	}
 
+	if o.PasswordSettings.HistoryCount == nil {
+		o.PasswordSettings.HistoryCount = new(int)
+		*o.PasswordSettings.HistoryCount = 0
+	}
+
+	if o.PasswordSettings.MaximumAgeInDays == nil {
+		o.PasswordSettings.MaximumAgeInDays = new(int)
+		*o.PasswordSettings.MaximumAgeInDays = 0
+	}
+
+	if o.PasswordSettings.BreachedPasswordHashFile == nil {
+		o.PasswordSettings.BreachedPasswordHashFile = new(string)
+		*o.PasswordSettings.BreachedPasswordHashFile = ""
+	}
+
 	if o.TeamSettings.EnableCustomBrand == nil {
 		o.TeamSettings.EnableCustomBrand = new(bool)
 		*o.TeamSettings.EnableCustomBrand = false
@@ -595,6 +676,56 @@ func (o *Config) SetDefaults() {
 		*o.EmailSettings.PushNotificationContents = GENERIC_NOTIFICATION
 	}
 
+	if o.EmailSettings.PushNotificationSender == nil {
+		o.EmailSettings.PushNotificationSender = new(string)
+		*o.EmailSettings.PushNotificationSender = PUSH_SENDER_PROXY
+	}
+
+	if o.EmailSettings.PushMaxAttempts == nil {
+		o.EmailSettings.PushMaxAttempts = new(int)
+		*o.EmailSettings.PushMaxAttempts = 3
+	}
+
+	if o.EmailSettings.APNSServer == nil {
+		o.EmailSettings.APNSServer = new(string)
+		*o.EmailSettings.APNSServer = APNS_SERVER
+	}
+
+	if o.EmailSettings.APNSKeyFile == nil {
+		o.EmailSettings.APNSKeyFile = new(string)
+		*o.EmailSettings.APNSKeyFile = ""
+	}
+
+	if o.EmailSettings.APNSKeyId == nil {
+		o.EmailSettings.APNSKeyId = new(string)
+		*o.EmailSettings.APNSKeyId = ""
+	}
+
+	if o.EmailSettings.APNSTeamId == nil {
+		o.EmailSettings.APNSTeamId = new(string)
+		*o.EmailSettings.APNSTeamId = ""
+	}
+
+	if o.EmailSettings.APNSTopic == nil {
+		o.EmailSettings.APNSTopic = new(string)
+		*o.EmailSettings.APNSTopic = ""
+	}
+
+	if o.EmailSettings.FCMServer == nil {
+		o.EmailSettings.FCMServer = new(string)
+		*o.EmailSettings.FCMServer = FCM_SERVER
+	}
+
+	if o.EmailSettings.FCMServiceAccountFile == nil {
+		o.EmailSettings.FCMServiceAccountFile = new(string)
+		*o.EmailSettings.FCMServiceAccountFile = ""
+	}
+
+	if o.EmailSettings.HttpNotificationURL == nil {
+		o.EmailSettings.HttpNotificationURL = new(string)
+		*o.EmailSettings.HttpNotificationURL = ""
+	}
+
 	if o.EmailSettings.FeedbackOrganization == nil {
 		o.EmailSettings.FeedbackOrganization = new(string)
 		*o.EmailSettings.FeedbackOrganization = ""
@@ -615,6 +746,41 @@ func (o *Config) SetDefaults() {
 		*o.EmailSettings.EmailBatchingInterval = EMAIL_BATCHING_INTERVAL
 	}
 
+	if o.EmailSettings.MailQueueWorkers == nil {
+		o.EmailSettings.MailQueueWorkers = new(int)
+		*o.EmailSettings.MailQueueWorkers = MAIL_QUEUE_WORKERS
+	}
+
+	if o.EmailSettings.MailQueueMaxAttempts == nil {
+		o.EmailSettings.MailQueueMaxAttempts = new(int)
+		*o.EmailSettings.MailQueueMaxAttempts = MAIL_QUEUE_MAX_ATTEMPTS
+	}
+
+	if o.EmailSettings.MaxEmailsPerRecipient == nil {
+		o.EmailSettings.MaxEmailsPerRecipient = new(int)
+		*o.EmailSettings.MaxEmailsPerRecipient = MAX_EMAILS_PER_RECIPIENT
+	}
+
+	if o.EmailSettings.EnableEmailReplies == nil {
+		o.EmailSettings.EnableEmailReplies = new(bool)
+		*o.EmailSettings.EnableEmailReplies = false
+	}
+
+	if o.EmailSettings.ReplyToAddress == nil {
+		o.EmailSettings.ReplyToAddress = new(string)
+		*o.EmailSettings.ReplyToAddress = ""
+	}
+
+	if o.EmailSettings.ReplyToSalt == nil || len(*o.EmailSettings.ReplyToSalt) == 0 {
+		o.EmailSettings.ReplyToSalt = new(string)
+		*o.EmailSettings.ReplyToSalt = NewRandomString(32)
+	}
+
+	if o.EmailSettings.InboundSMTPListenAddress == nil {
+		o.EmailSettings.InboundSMTPListenAddress = new(string)
+		*o.EmailSettings.InboundSMTPListenAddress = ":2525"
+	}
+
 	if !IsSafeLink(o.SupportSettings.TermsOfServiceLink) {
 		o.SupportSettings.TermsOfServiceLink = nil
 	}
@@ -785,6 +951,31 @@ func (o *Config) SetDefaults() {
 		*o.ServiceSettings.SessionCacheInMinutes = 10
 	}
 
+	if o.ServiceSettings.SessionIdleTimeoutInMinutes == nil {
+		o.ServiceSettings.SessionIdleTimeoutInMinutes = new(int)
+		*o.ServiceSettings.SessionIdleTimeoutInMinutes = 0
+	}
+
+	if o.ServiceSettings.MaximumSessionsPerUser == nil {
+		o.ServiceSettings.MaximumSessionsPerUser = new(int)
+		*o.ServiceSettings.MaximumSessionsPerUser = 0
+	}
+
+	if o.ServiceSettings.LoginLockoutDurationInMinutes == nil {
+		o.ServiceSettings.LoginLockoutDurationInMinutes = new(int)
+		*o.ServiceSettings.LoginLockoutDurationInMinutes = 30
+	}
+
+	if o.ServiceSettings.LoginAttemptsPerMinutePerIp == nil {
+		o.ServiceSettings.LoginAttemptsPerMinutePerIp = new(int)
+		*o.ServiceSettings.LoginAttemptsPerMinutePerIp = 0
+	}
+
+	if o.ServiceSettings.LoginAttemptsPerMinutePerLoginId == nil {
+		o.ServiceSettings.LoginAttemptsPerMinutePerLoginId = new(int)
+		*o.ServiceSettings.LoginAttemptsPerMinutePerLoginId = 0
+	}
+
 	if o.ServiceSettings.EnableCommands == nil {
 		o.ServiceSettings.EnableCommands = new(bool)
 		*o.ServiceSettings.EnableCommands = false
@@ -1047,6 +1238,8 @@ func (o *Config) SetDefaults() {
 	}
 
 	o.defaultWebrtcSettings()
+	o.defaultOpenIdSettings()
+	o.defaultScimSettings()
 }
 
 func (o *Config) IsValid() *AppError {
@@ -1065,10 +1258,6 @@ func (o *Config) IsValid() *AppError {
 		return NewLocAppError("Config.IsValid", "model.config.is_valid.listen_address.app_error", nil, "")
 	}
 
-	if *o.ClusterSettings.Enable && *o.EmailSettings.EnableEmailBatching {
-		return NewLocAppError("Config.IsValid", "model.config.is_valid.cluster_email_batching.app_error", nil, "")
-	}
-
 	if len(*o.ServiceSettings.SiteURL) == 0 && *o.EmailSettings.EnableEmailBatching {
 		return NewLocAppError("Config.IsValid", "model.config.is_valid.site_url_email_batching.app_error", nil, "")
 	}
@@ -1165,6 +1354,42 @@ func (o *Config) IsValid() *AppError {
 		return NewLocAppError("Config.IsValid", "model.config.is_valid.email_batching_interval.app_error", nil, "")
 	}
 
+	if *o.EmailSettings.MailQueueWorkers <= 0 {
+		return NewLocAppError("Config.IsValid", "model.config.is_valid.mail_queue_workers.app_error", nil, "")
+	}
+
+	if *o.EmailSettings.MailQueueMaxAttempts <= 0 {
+		return NewLocAppError("Config.IsValid", "model.config.is_valid.mail_queue_max_attempts.app_error", nil, "")
+	}
+
+	if *o.EmailSettings.MaxEmailsPerRecipient < 0 {
+		return NewLocAppError("Config.IsValid", "model.config.is_valid.max_emails_per_recipient.app_error", nil, "")
+	}
+
+	if *o.EmailSettings.EnableEmailReplies && !IsValidEmail(*o.EmailSettings.ReplyToAddress) {
+		return NewLocAppError("Config.IsValid", "model.config.is_valid.reply_to_address.app_error", nil, "")
+	}
+
+	if len(*o.EmailSettings.ReplyToSalt) < 32 {
+		return NewLocAppError("Config.IsValid", "model.config.is_valid.reply_to_salt.app_error", nil, "")
+	}
+
+	if contents := *o.EmailSettings.PushNotificationContents; contents != GENERIC_NOTIFICATION && contents != FULL_NOTIFICATION && contents != ID_LOADED_NOTIFICATION && contents != ENCRYPTED_NOTIFICATION {
+		return NewLocAppError("Config.IsValid", "model.config.is_valid.push_notification_contents.app_error", nil, "")
+	}
+
+	if *o.EmailSettings.PushNotificationSender != PUSH_SENDER_PROXY && *o.EmailSettings.PushNotificationSender != PUSH_SENDER_DIRECT {
+		return NewLocAppError("Config.IsValid", "model.config.is_valid.push_notification_sender.app_error", nil, "")
+	}
+
+	if *o.EmailSettings.PushMaxAttempts <= 0 {
+		return NewLocAppError("Config.IsValid", "model.config.is_valid.push_max_attempts.app_error", nil, "")
+	}
+
+	if len(*o.EmailSettings.HttpNotificationURL) > 0 && !IsValidHttpUrl(*o.EmailSettings.HttpNotificationURL) {
+		return NewLocAppError("Config.IsValid", "model.config.is_valid.http_notification_url.app_error", nil, "")
+	}
+
 	if o.RateLimitSettings.MemoryStoreSize <= 0 {
 		return NewLocAppError("Config.IsValid", "model.config.is_valid.rate_mem.app_error", nil, "")
 	}
@@ -1253,6 +1478,14 @@ func (o *Config) IsValid() *AppError {
 		return NewLocAppError("Config.IsValid", "model.config.is_valid.password_length.app_error", map[string]interface{}{"MinLength": PASSWORD_MINIMUM_LENGTH, "MaxLength": PASSWORD_MAXIMUM_LENGTH}, "")
 	}
 
+	if *o.PasswordSettings.HistoryCount < 0 || *o.PasswordSettings.HistoryCount > PASSWORD_HISTORY_MAXIMUM {
+		return NewLocAppError("Config.IsValid", "model.config.is_valid.password_history.app_error", map[string]interface{}{"Max": PASSWORD_HISTORY_MAXIMUM}, "")
+	}
+
+	if *o.PasswordSettings.MaximumAgeInDays < 0 {
+		return NewLocAppError("Config.IsValid", "model.config.is_valid.password_max_age.app_error", nil, "")
+	}
+
 	if len(o.TeamSettings.SiteName) > SITENAME_MAX_LENGTH {
 		return NewLocAppError("Config.IsValid", "model.config.is_valid.sitename_length.app_error", map[string]interface{}{"MaxLength": SITENAME_MAX_LENGTH}, "")
 	}
@@ -1265,10 +1498,38 @@ func (o *Config) IsValid() *AppError {
 		return err
 	}
 
+	if err := o.isValidOpenIdSettings(); err != nil {
+		return err
+	}
+
+	if err := o.isValidScimSettings(); err != nil {
+		return err
+	}
+
 	if !(*o.ServiceSettings.ConnectionSecurity == CONN_SECURITY_NONE || *o.ServiceSettings.ConnectionSecurity == CONN_SECURITY_TLS) {
 		return NewLocAppError("Config.IsValid", "model.config.is_valid.webserver_security.app_error", nil, "")
 	}
 
+	if len(*o.ServiceSettings.WebAuthnChallengeSalt) < 32 {
+		return NewLocAppError("Config.IsValid", "model.config.is_valid.webauthn_challenge_salt.app_error", nil, "")
+	}
+
+	if *o.ServiceSettings.SessionIdleTimeoutInMinutes < 0 {
+		return NewLocAppError("Config.IsValid", "model.config.is_valid.session_idle_timeout.app_error", nil, "")
+	}
+
+	if *o.ServiceSettings.MaximumSessionsPerUser < 0 {
+		return NewLocAppError("Config.IsValid", "model.config.is_valid.max_sessions_per_user.app_error", nil, "")
+	}
+
+	if *o.ServiceSettings.LoginLockoutDurationInMinutes < 0 {
+		return NewLocAppError("Config.IsValid", "model.config.is_valid.login_lockout_duration.app_error", nil, "")
+	}
+
+	if *o.ServiceSettings.LoginAttemptsPerMinutePerIp < 0 || *o.ServiceSettings.LoginAttemptsPerMinutePerLoginId < 0 {
+		return NewLocAppError("Config.IsValid", "model.config.is_valid.login_rate_limit.app_error", nil, "")
+	}
+
 	if *o.ServiceSettings.ReadTimeout <= 0 {
 		return NewLocAppError("Config.IsValid", "model.config.is_valid.read_timeout.app_error", nil, "")
 	}
@@ -1294,12 +1555,14 @@ func (o *Config) Sanitize() {
 	}
 
 	*o.FileSettings.PublicLinkSalt = FAKE_SETTING
+	*o.ServiceSettings.WebAuthnChallengeSalt = FAKE_SETTING
 	if len(o.FileSettings.AmazonS3SecretAccessKey) > 0 {
 		o.FileSettings.AmazonS3SecretAccessKey = FAKE_SETTING
 	}
 
 	o.EmailSettings.InviteSalt = FAKE_SETTING
 	o.EmailSettings.PasswordResetSalt = FAKE_SETTING
+	*o.EmailSettings.ReplyToSalt = FAKE_SETTING
 	if len(o.EmailSettings.SMTPPassword) > 0 {
 		o.EmailSettings.SMTPPassword = FAKE_SETTING
 	}
@@ -1308,6 +1571,14 @@ func (o *Config) Sanitize() {
 		o.GitLabSettings.Secret = FAKE_SETTING
 	}
 
+	if o.OpenIdSettings.Secret != nil && len(*o.OpenIdSettings.Secret) > 0 {
+		*o.OpenIdSettings.Secret = FAKE_SETTING
+	}
+
+	if o.ScimSettings.Token != nil && len(*o.ScimSettings.Token) > 0 {
+		*o.ScimSettings.Token = FAKE_SETTING
+	}
+
 	o.SqlSettings.DataSource = FAKE_SETTING
 	o.SqlSettings.AtRestEncryptKey = FAKE_SETTING
 
@@ -1383,3 +1654,91 @@ func (o *Config) isValidWebrtcSettings() *AppError {
 
 	return nil
 }
+
+func (o *Config) defaultOpenIdSettings() {
+	if o.OpenIdSettings.Enable == nil {
+		o.OpenIdSettings.Enable = new(bool)
+		*o.OpenIdSettings.Enable = false
+	}
+
+	if o.OpenIdSettings.ButtonText == nil {
+		o.OpenIdSettings.ButtonText = new(string)
+		*o.OpenIdSettings.ButtonText = ""
+	}
+
+	if o.OpenIdSettings.Id == nil {
+		o.OpenIdSettings.Id = new(string)
+		*o.OpenIdSettings.Id = ""
+	}
+
+	if o.OpenIdSettings.Secret == nil {
+		o.OpenIdSettings.Secret = new(string)
+		*o.OpenIdSettings.Secret = ""
+	}
+
+	if o.OpenIdSettings.Scope == nil {
+		o.OpenIdSettings.Scope = new(string)
+		*o.OpenIdSettings.Scope = "openid profile email"
+	}
+
+	if o.OpenIdSettings.DiscoveryEndpoint == nil {
+		o.OpenIdSettings.DiscoveryEndpoint = new(string)
+		*o.OpenIdSettings.DiscoveryEndpoint = ""
+	}
+
+	if o.OpenIdSettings.UsernameClaim == nil {
+		o.OpenIdSettings.UsernameClaim = new(string)
+		*o.OpenIdSettings.UsernameClaim = "preferred_username"
+	}
+
+	if o.OpenIdSettings.EmailClaim == nil {
+		o.OpenIdSettings.EmailClaim = new(string)
+		*o.OpenIdSettings.EmailClaim = "email"
+	}
+
+	if o.OpenIdSettings.FirstNameClaim == nil {
+		o.OpenIdSettings.FirstNameClaim = new(string)
+		*o.OpenIdSettings.FirstNameClaim = "given_name"
+	}
+
+	if o.OpenIdSettings.LastNameClaim == nil {
+		o.OpenIdSettings.LastNameClaim = new(string)
+		*o.OpenIdSettings.LastNameClaim = "family_name"
+	}
+}
+
+func (o *Config) isValidOpenIdSettings() *AppError {
+	if *o.OpenIdSettings.Enable {
+		if len(*o.OpenIdSettings.Id) == 0 {
+			return NewLocAppError("Config.IsValid", "model.config.is_valid.openid_id.app_error", nil, "")
+		} else if !IsValidHttpUrl(*o.OpenIdSettings.DiscoveryEndpoint) {
+			return NewLocAppError("Config.IsValid", "model.config.is_valid.openid_discovery_endpoint.app_error", nil, "")
+		} else if !strings.Contains(" "+*o.OpenIdSettings.Scope+" ", " openid ") {
+			return NewLocAppError("Config.IsValid", "model.config.is_valid.openid_scope.app_error", nil, "")
+		} else if len(*o.OpenIdSettings.UsernameClaim) == 0 || len(*o.OpenIdSettings.EmailClaim) == 0 {
+			return NewLocAppError("Config.IsValid", "model.config.is_valid.openid_claims.app_error", nil, "")
+		}
+	}
+
+	return nil
+}
+
+func (o *Config) defaultScimSettings() {
+	if o.ScimSettings.Enable == nil {
+		o.ScimSettings.Enable = new(bool)
+		*o.ScimSettings.Enable = false
+	}
+
+	if o.ScimSettings.Token == nil {
+		o.ScimSettings.Token = new(string)
+		*o.ScimSettings.Token = ""
+	}
+}
+
+func (o *Config) isValidScimSettings() *AppError {
+	if *o.ScimSettings.Enable && len(*o.ScimSettings.Token) < SCIM_TOKEN_MINIMUM_LENGTH {
+		return NewLocAppError("Config.IsValid", "model.config.is_valid.scim_token.app_error", map[string]interface{}{"Min": SCIM_TOKEN_MINIMUM_LENGTH}, "")
+	}
+
+	return nil
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DIGEST_FREQUENCY_NOTIFY_PROP = "digest_frequency"
+	DIGEST_DAY_NOTIFY_PROP       = "digest_day"
+	DIGEST_TIME_NOTIFY_PROP      = "digest_time"
+	DIGEST_TIMEZONE_NOTIFY_PROP  = "digest_timezone"
+
+	DIGEST_FREQUENCY_NEVER  = "never"
+	DIGEST_FREQUENCY_DAILY  = "daily"
+	DIGEST_FREQUENCY_WEEKLY = "weekly"
+
+	DIGEST_DEFAULT_DAY  = time.Monday
+	DIGEST_DEFAULT_TIME = "08:00"
+)
+
+// A DigestSchedule is when a user gets emailed a digest of their unread activity. It's stored in the user's
+// notify props as a daily or weekly frequency, the weekday for weekly digests (0 is Sunday), the time of
+// day in the HH:MM format and an IANA time zone, all but the frequency being optional.
+type DigestSchedule struct {
+	Frequency string
+	Day       time.Weekday
+	Time      int
+	Location  *time.Location
+}
+
+// DigestScheduleFromNotifyProps parses the digest schedule out of a user's notify props. It returns nil
+// without an error when the user hasn't opted in to digests.
+func DigestScheduleFromNotifyProps(props StringMap) (*DigestSchedule, *AppError) {
+	frequency := props[DIGEST_FREQUENCY_NOTIFY_PROP]
+	if len(frequency) == 0 || frequency == DIGEST_FREQUENCY_NEVER {
+		return nil, nil
+	} else if frequency != DIGEST_FREQUENCY_DAILY && frequency != DIGEST_FREQUENCY_WEEKLY {
+		return nil, NewLocAppError("DigestScheduleFromNotifyProps", "model.digest_schedule.frequency.app_error", nil, DIGEST_FREQUENCY_NOTIFY_PROP+"="+frequency)
+	}
+
+	schedule := &DigestSchedule{Frequency: frequency, Day: DIGEST_DEFAULT_DAY}
+
+	if day := strings.TrimSpace(props[DIGEST_DAY_NOTIFY_PROP]); len(day) > 0 {
+		if d, err := strconv.Atoi(day); err != nil || d < 0 || d > 6 {
+			return nil, NewLocAppError("DigestScheduleFromNotifyProps", "model.digest_schedule.day.app_error", nil, DIGEST_DAY_NOTIFY_PROP+"="+day)
+		} else {
+			schedule.Day = time.Weekday(d)
+		}
+	}
+
+	timeOfDay := props[DIGEST_TIME_NOTIFY_PROP]
+	if len(timeOfDay) == 0 {
+		timeOfDay = DIGEST_DEFAULT_TIME
+	}
+
+	var ok bool
+	if schedule.Time, ok = parseTimeOfDay(timeOfDay); !ok {
+		return nil, NewLocAppError("DigestScheduleFromNotifyProps", "model.digest_schedule.time.app_error", nil, DIGEST_TIME_NOTIFY_PROP+"="+timeOfDay)
+	}
+
+	if location, err := time.LoadLocation(props[DIGEST_TIMEZONE_NOTIFY_PROP]); err != nil {
+		return nil, NewLocAppError("DigestScheduleFromNotifyProps", "model.digest_schedule.timezone.app_error", nil, DIGEST_TIMEZONE_NOTIFY_PROP+"="+props[DIGEST_TIMEZONE_NOTIFY_PROP])
+	} else {
+		schedule.Location = location
+	}
+
+	return schedule, nil
+}
+
+// LastScheduledAt returns the most recent time at or before t that a digest was scheduled for.
+func (s *DigestSchedule) LastScheduledAt(t time.Time) time.Time {
+	t = t.In(s.Location)
+
+	scheduled := time.Date(t.Year(), t.Month(), t.Day(), s.Time/60, s.Time%60, 0, 0, s.Location)
+	if scheduled.After(t) {
+		scheduled = scheduled.AddDate(0, 0, -1)
+	}
+
+	if s.Frequency == DIGEST_FREQUENCY_WEEKLY {
+		for scheduled.Weekday() != s.Day {
+			scheduled = scheduled.AddDate(0, 0, -1)
+		}
+	}
+
+	return scheduled
+}
+
+// IsDue returns true if a digest has been scheduled since the last one was sent at lastSent milliseconds.
+func (s *DigestSchedule) IsDue(lastSent int64, now time.Time) bool {
+	return s.LastScheduledAt(now).UnixNano()/int64(time.Millisecond) > lastSent
+}
@@ -0,0 +1,79 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDigestScheduleFromNotifyProps(t *testing.T) {
+	if schedule, err := DigestScheduleFromNotifyProps(StringMap{}); err != nil || schedule != nil {
+		t.Fatal("shouldn't have a schedule without a frequency")
+	}
+
+	if schedule, err := DigestScheduleFromNotifyProps(StringMap{DIGEST_FREQUENCY_NOTIFY_PROP: DIGEST_FREQUENCY_NEVER}); err != nil || schedule != nil {
+		t.Fatal("shouldn't have a schedule when turned off")
+	}
+
+	if schedule, err := DigestScheduleFromNotifyProps(StringMap{DIGEST_FREQUENCY_NOTIFY_PROP: DIGEST_FREQUENCY_WEEKLY}); err != nil {
+		t.Fatal(err)
+	} else if schedule.Day != time.Monday || schedule.Time != 8*60 || schedule.Location != time.UTC {
+		t.Fatal("should have used the defaults", schedule)
+	}
+
+	invalid := []StringMap{
+		{DIGEST_FREQUENCY_NOTIFY_PROP: "monthly"},
+		{DIGEST_FREQUENCY_NOTIFY_PROP: DIGEST_FREQUENCY_WEEKLY, DIGEST_DAY_NOTIFY_PROP: "7"},
+		{DIGEST_FREQUENCY_NOTIFY_PROP: DIGEST_FREQUENCY_DAILY, DIGEST_TIME_NOTIFY_PROP: "25:00"},
+		{DIGEST_FREQUENCY_NOTIFY_PROP: DIGEST_FREQUENCY_DAILY, DIGEST_TIMEZONE_NOTIFY_PROP: "Mars/Olympus_Mons"},
+	}
+
+	for _, props := range invalid {
+		if _, err := DigestScheduleFromNotifyProps(props); err == nil {
+			t.Fatal("should have failed", props)
+		}
+	}
+}
+
+func TestDigestScheduleIsDue(t *testing.T) {
+	location, _ := time.LoadLocation("America/Toronto")
+
+	daily := &DigestSchedule{Frequency: DIGEST_FREQUENCY_DAILY, Time: 8 * 60, Location: location}
+
+	// Wednesday at 7:30 and 8:30 in Toronto
+	before := time.Date(2016, time.November, 2, 7, 30, 0, 0, location)
+	after := time.Date(2016, time.November, 2, 8, 30, 0, 0, location)
+
+	if scheduled := daily.LastScheduledAt(before); !scheduled.Equal(time.Date(2016, time.November, 1, 8, 0, 0, 0, location)) {
+		t.Fatal("should have been scheduled the previous morning", scheduled)
+	}
+
+	if scheduled := daily.LastScheduledAt(after); !scheduled.Equal(time.Date(2016, time.November, 2, 8, 0, 0, 0, location)) {
+		t.Fatal("should have been scheduled that morning", scheduled)
+	}
+
+	lastSent := before.UnixNano() / int64(time.Millisecond)
+	if daily.IsDue(lastSent, before) {
+		t.Fatal("shouldn't be due before the time of day")
+	}
+
+	if !daily.IsDue(lastSent, after) {
+		t.Fatal("should be due after the time of day")
+	}
+
+	weekly := &DigestSchedule{Frequency: DIGEST_FREQUENCY_WEEKLY, Day: time.Monday, Time: 8 * 60, Location: location}
+
+	if scheduled := weekly.LastScheduledAt(after); !scheduled.Equal(time.Date(2016, time.October, 31, 8, 0, 0, 0, location)) {
+		t.Fatal("should have been scheduled on Monday", scheduled)
+	}
+
+	if weekly.IsDue(lastSent, after) {
+		t.Fatal("shouldn't be due until the next Monday")
+	}
+
+	if !weekly.IsDue(lastSent, time.Date(2016, time.November, 7, 8, 0, 0, 0, location)) {
+		t.Fatal("should be due on Monday")
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A DndSchedule is a recurring window during which a user doesn't want to be notified. It's
+// stored in the user's notify props as a list of weekdays (0 is Sunday), a start and end time
+// of day in the HH:MM format and an IANA time zone. A window that ends before it starts runs
+// past midnight into the next day.
+type DndSchedule struct {
+	Days     map[time.Weekday]bool
+	Start    int
+	End      int
+	Location *time.Location
+}
+
+// DndScheduleFromNotifyProps parses the schedule out of a user's notify props. It returns nil
+// without an error when the user doesn't have a schedule.
+func DndScheduleFromNotifyProps(props StringMap) (*DndSchedule, *AppError) {
+	if len(props["dnd_days"]) == 0 {
+		return nil, nil
+	}
+
+	schedule := &DndSchedule{Days: make(map[time.Weekday]bool)}
+
+	for _, day := range strings.Split(props["dnd_days"], ",") {
+		if d, err := strconv.Atoi(strings.TrimSpace(day)); err != nil || d < 0 || d > 6 {
+			return nil, NewLocAppError("DndScheduleFromNotifyProps", "model.dnd_schedule.days.app_error", nil, "dnd_days="+props["dnd_days"])
+		} else {
+			schedule.Days[time.Weekday(d)] = true
+		}
+	}
+
+	var ok bool
+	if schedule.Start, ok = parseTimeOfDay(props["dnd_start"]); !ok {
+		return nil, NewLocAppError("DndScheduleFromNotifyProps", "model.dnd_schedule.start.app_error", nil, "dnd_start="+props["dnd_start"])
+	}
+
+	if schedule.End, ok = parseTimeOfDay(props["dnd_end"]); !ok {
+		return nil, NewLocAppError("DndScheduleFromNotifyProps", "model.dnd_schedule.end.app_error", nil, "dnd_end="+props["dnd_end"])
+	}
+
+	if location, err := time.LoadLocation(props["dnd_timezone"]); err != nil {
+		return nil, NewLocAppError("DndScheduleFromNotifyProps", "model.dnd_schedule.timezone.app_error", nil, "dnd_timezone="+props["dnd_timezone"])
+	} else {
+		schedule.Location = location
+	}
+
+	return schedule, nil
+}
+
+// IsActive returns true if the time falls within the schedule.
+func (s *DndSchedule) IsActive(t time.Time) bool {
+	t = t.In(s.Location)
+	minutes := t.Hour()*60 + t.Minute()
+
+	if s.Start == s.End {
+		return s.Days[t.Weekday()]
+	} else if s.Start < s.End {
+		return s.Days[t.Weekday()] && minutes >= s.Start && minutes < s.End
+	} else {
+		// The window started on the previous day and runs past midnight
+		return (s.Days[t.Weekday()] && minutes >= s.Start) || (s.Days[t.AddDate(0, 0, -1).Weekday()] && minutes < s.End)
+	}
+}
+
+func parseTimeOfDay(value string) (int, bool) {
+	if t, err := time.Parse("15:04", value); err != nil {
+		return 0, false
+	} else {
+		return t.Hour()*60 + t.Minute(), true
+	}
+}
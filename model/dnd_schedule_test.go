@@ -0,0 +1,98 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDndScheduleFromNotifyProps(t *testing.T) {
+	if schedule, err := DndScheduleFromNotifyProps(StringMap{}); err != nil || schedule != nil {
+		t.Fatal("should not have a schedule")
+	}
+
+	props := StringMap{"dnd_days": "1,2,3,4,5", "dnd_start": "18:00", "dnd_end": "09:00", "dnd_timezone": "UTC"}
+	if schedule, err := DndScheduleFromNotifyProps(props); err != nil {
+		t.Fatal(err)
+	} else if schedule.Start != 18*60 || schedule.End != 9*60 || !schedule.Days[time.Monday] || schedule.Days[time.Sunday] {
+		t.Fatal("schedule was parsed incorrectly")
+	}
+
+	props["dnd_days"] = "1,7"
+	if _, err := DndScheduleFromNotifyProps(props); err == nil {
+		t.Fatal("should have failed, invalid day")
+	}
+
+	props["dnd_days"] = "1"
+	props["dnd_start"] = "25:00"
+	if _, err := DndScheduleFromNotifyProps(props); err == nil {
+		t.Fatal("should have failed, invalid start")
+	}
+
+	props["dnd_start"] = "18:00"
+	props["dnd_end"] = ""
+	if _, err := DndScheduleFromNotifyProps(props); err == nil {
+		t.Fatal("should have failed, missing end")
+	}
+
+	props["dnd_end"] = "09:00"
+	props["dnd_timezone"] = "Not/AZone"
+	if _, err := DndScheduleFromNotifyProps(props); err == nil {
+		t.Fatal("should have failed, invalid time zone")
+	}
+}
+
+func TestDndScheduleIsActive(t *testing.T) {
+	// Monday, January 2nd 2017
+	monday := time.Date(2017, time.January, 2, 0, 0, 0, 0, time.UTC)
+
+	daytime := &DndSchedule{Days: map[time.Weekday]bool{time.Monday: true}, Start: 9 * 60, End: 17 * 60, Location: time.UTC}
+	if !daytime.IsActive(monday.Add(10 * time.Hour)) {
+		t.Fatal("should be active during the day")
+	}
+	if daytime.IsActive(monday.Add(17 * time.Hour)) {
+		t.Fatal("should not be active once the window ends")
+	}
+	if daytime.IsActive(monday.AddDate(0, 0, 1).Add(10 * time.Hour)) {
+		t.Fatal("should not be active on tuesday")
+	}
+
+	overnight := &DndSchedule{Days: map[time.Weekday]bool{time.Monday: true}, Start: 18 * 60, End: 9 * 60, Location: time.UTC}
+	if !overnight.IsActive(monday.Add(20 * time.Hour)) {
+		t.Fatal("should be active monday evening")
+	}
+	if !overnight.IsActive(monday.AddDate(0, 0, 1).Add(8 * time.Hour)) {
+		t.Fatal("should be active tuesday morning")
+	}
+	if overnight.IsActive(monday.Add(8 * time.Hour)) {
+		t.Fatal("should not be active monday morning since sunday isn't scheduled")
+	}
+
+	allDay := &DndSchedule{Days: map[time.Weekday]bool{time.Sunday: true}, Location: time.UTC}
+	if !allDay.IsActive(monday.Add(-time.Hour)) {
+		t.Fatal("should be active all of sunday")
+	}
+
+	zone := time.FixedZone("UTC-5", -5*60*60)
+	shifted := &DndSchedule{Days: map[time.Weekday]bool{time.Monday: true}, Start: 9 * 60, End: 17 * 60, Location: zone}
+	if shifted.IsActive(monday.Add(10 * time.Hour)) {
+		t.Fatal("should not be active, it's 5am in the user's time zone")
+	}
+	if !shifted.IsActive(monday.Add(15 * time.Hour)) {
+		t.Fatal("should be active, it's 10am in the user's time zone")
+	}
+}
+
+func TestUserIsInDndSchedule(t *testing.T) {
+	user := &User{NotifyProps: StringMap{"dnd_days": "0,1,2,3,4,5,6", "dnd_start": "00:00", "dnd_end": "00:00"}}
+	if !user.IsInDndSchedule(time.Now()) {
+		t.Fatal("user should always be in their schedule")
+	}
+
+	user.NotifyProps = StringMap{}
+	if user.IsInDndSchedule(time.Now()) {
+		t.Fatal("user doesn't have a schedule")
+	}
+}
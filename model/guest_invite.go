@@ -0,0 +1,63 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"encoding/json"
+	"io"
+)
+
+type GuestInvite struct {
+	Emails     []string `json:"emails"`
+	ChannelIds []string `json:"channel_ids"`
+	ExpiresAt  int64    `json:"expires_at"`
+}
+
+func (o *GuestInvite) ToJson() string {
+	b, err := json.Marshal(o)
+	if err != nil {
+		return ""
+	} else {
+		return string(b)
+	}
+}
+
+func GuestInviteFromJson(data io.Reader) *GuestInvite {
+	decoder := json.NewDecoder(data)
+	var o GuestInvite
+	err := decoder.Decode(&o)
+	if err == nil {
+		return &o
+	} else {
+		return nil
+	}
+}
+
+func (o *GuestInvite) IsValid() *AppError {
+	if len(o.Emails) == 0 {
+		return NewLocAppError("GuestInvite.IsValid", "model.guest_invite.is_valid.emails.app_error", nil, "")
+	}
+
+	for _, email := range o.Emails {
+		if !IsValidEmail(email) {
+			return NewLocAppError("GuestInvite.IsValid", "model.guest_invite.is_valid.emails.app_error", nil, "email="+email)
+		}
+	}
+
+	if len(o.ChannelIds) == 0 {
+		return NewLocAppError("GuestInvite.IsValid", "model.guest_invite.is_valid.channel_ids.app_error", nil, "")
+	}
+
+	for _, channelId := range o.ChannelIds {
+		if len(channelId) != 26 {
+			return NewLocAppError("GuestInvite.IsValid", "model.guest_invite.is_valid.channel_ids.app_error", nil, "channel_id="+channelId)
+		}
+	}
+
+	if o.ExpiresAt < 0 || (o.ExpiresAt > 0 && o.ExpiresAt <= GetMillis()) {
+		return NewLocAppError("GuestInvite.IsValid", "model.guest_invite.is_valid.expires_at.app_error", nil, "")
+	}
+
+	return nil
+}
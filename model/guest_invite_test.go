@@ -0,0 +1,57 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGuestInviteJson(t *testing.T) {
+	o := GuestInvite{Emails: []string{"guest@example.com"}, ChannelIds: []string{NewId()}, ExpiresAt: GetMillis()}
+	json := o.ToJson()
+	ro := GuestInviteFromJson(strings.NewReader(json))
+
+	if o.ChannelIds[0] != ro.ChannelIds[0] || o.ExpiresAt != ro.ExpiresAt {
+		t.Fatal("invites do not match")
+	}
+}
+
+func TestGuestInviteIsValid(t *testing.T) {
+	o := GuestInvite{}
+
+	if err := o.IsValid(); err == nil {
+		t.Fatal("should be invalid")
+	}
+
+	o.Emails = []string{"junk"}
+	if err := o.IsValid(); err == nil {
+		t.Fatal("should be invalid")
+	}
+
+	o.Emails = []string{"guest@example.com"}
+	if err := o.IsValid(); err == nil {
+		t.Fatal("should be invalid")
+	}
+
+	o.ChannelIds = []string{"junk"}
+	if err := o.IsValid(); err == nil {
+		t.Fatal("should be invalid")
+	}
+
+	o.ChannelIds = []string{NewId()}
+	if err := o.IsValid(); err != nil {
+		t.Fatal(err)
+	}
+
+	o.ExpiresAt = GetMillis() - 1000
+	if err := o.IsValid(); err == nil {
+		t.Fatal("should be invalid")
+	}
+
+	o.ExpiresAt = GetMillis() + 1000*60*60
+	if err := o.IsValid(); err != nil {
+		t.Fatal(err)
+	}
+}
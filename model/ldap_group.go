@@ -0,0 +1,158 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"encoding/json"
+	"io"
+)
+
+const (
+	LDAP_GROUP_ID_MAX_LENGTH   = 512
+	LDAP_GROUP_NAME_MAX_LENGTH = 256
+)
+
+// LdapGroup is a group in the directory, identified by the attribute configured as its id such as its DN
+type LdapGroup struct {
+	Id          string `json:"id"`
+	DisplayName string `json:"display_name"`
+}
+
+// LdapGroupLink links a directory group to a team or, when ChannelId is set, to a private channel in that
+// team. Synchronization keeps the LDAP users among the members of the team or channel equal to the members
+// of the linked groups.
+type LdapGroupLink struct {
+	Id        string `json:"id"`
+	GroupId   string `json:"group_id"`
+	GroupName string `json:"group_name"`
+	TeamId    string `json:"team_id"`
+	ChannelId string `json:"channel_id"`
+	CreatorId string `json:"creator_id"`
+	CreateAt  int64  `json:"create_at"`
+}
+
+// LdapGroupSyncResult lists the users added to and removed from a team or channel by a synchronization
+type LdapGroupSyncResult struct {
+	TeamId    string   `json:"team_id"`
+	ChannelId string   `json:"channel_id"`
+	GroupIds  []string `json:"group_ids"`
+	Added     []string `json:"added"`
+	Removed   []string `json:"removed"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// LdapGroupSyncReport is the outcome of synchronizing every group link. A dry run reports the changes
+// without making them.
+type LdapGroupSyncReport struct {
+	DryRun  bool                   `json:"dry_run"`
+	Results []*LdapGroupSyncResult `json:"results"`
+}
+
+func (o *LdapGroupLink) PreSave() {
+	if o.Id == "" {
+		o.Id = NewId()
+	}
+
+	o.CreateAt = GetMillis()
+}
+
+func (o *LdapGroupLink) IsValid() *AppError {
+	if len(o.Id) != 26 {
+		return NewLocAppError("LdapGroupLink.IsValid", "model.ldap_group_link.is_valid.id.app_error", nil, "")
+	}
+
+	if len(o.GroupId) == 0 || len(o.GroupId) > LDAP_GROUP_ID_MAX_LENGTH {
+		return NewLocAppError("LdapGroupLink.IsValid", "model.ldap_group_link.is_valid.group_id.app_error", nil, "id="+o.Id)
+	}
+
+	if len(o.GroupName) > LDAP_GROUP_NAME_MAX_LENGTH {
+		return NewLocAppError("LdapGroupLink.IsValid", "model.ldap_group_link.is_valid.group_name.app_error", nil, "id="+o.Id)
+	}
+
+	if len(o.TeamId) != 26 {
+		return NewLocAppError("LdapGroupLink.IsValid", "model.ldap_group_link.is_valid.team_id.app_error", nil, "id="+o.Id)
+	}
+
+	if len(o.ChannelId) != 0 && len(o.ChannelId) != 26 {
+		return NewLocAppError("LdapGroupLink.IsValid", "model.ldap_group_link.is_valid.channel_id.app_error", nil, "id="+o.Id)
+	}
+
+	if o.CreateAt == 0 {
+		return NewLocAppError("LdapGroupLink.IsValid", "model.ldap_group_link.is_valid.create_at.app_error", nil, "id="+o.Id)
+	}
+
+	return nil
+}
+
+func (o *LdapGroupLink) ToJson() string {
+	if b, err := json.Marshal(o); err != nil {
+		return ""
+	} else {
+		return string(b)
+	}
+}
+
+func LdapGroupLinkFromJson(data io.Reader) *LdapGroupLink {
+	var o LdapGroupLink
+
+	if err := json.NewDecoder(data).Decode(&o); err != nil {
+		return nil
+	} else {
+		return &o
+	}
+}
+
+func LdapGroupLinksToJson(o []*LdapGroupLink) string {
+	if b, err := json.Marshal(o); err != nil {
+		return ""
+	} else {
+		return string(b)
+	}
+}
+
+func LdapGroupLinksFromJson(data io.Reader) []*LdapGroupLink {
+	var o []*LdapGroupLink
+
+	if err := json.NewDecoder(data).Decode(&o); err != nil {
+		return nil
+	} else {
+		return o
+	}
+}
+
+func LdapGroupsToJson(o []*LdapGroup) string {
+	if b, err := json.Marshal(o); err != nil {
+		return ""
+	} else {
+		return string(b)
+	}
+}
+
+func LdapGroupsFromJson(data io.Reader) []*LdapGroup {
+	var o []*LdapGroup
+
+	if err := json.NewDecoder(data).Decode(&o); err != nil {
+		return nil
+	} else {
+		return o
+	}
+}
+
+func (o *LdapGroupSyncReport) ToJson() string {
+	if b, err := json.Marshal(o); err != nil {
+		return ""
+	} else {
+		return string(b)
+	}
+}
+
+func LdapGroupSyncReportFromJson(data io.Reader) *LdapGroupSyncReport {
+	var o LdapGroupSyncReport
+
+	if err := json.NewDecoder(data).Decode(&o); err != nil {
+		return nil
+	} else {
+		return &o
+	}
+}
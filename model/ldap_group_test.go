@@ -0,0 +1,52 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLdapGroupLinkJson(t *testing.T) {
+	link := LdapGroupLink{Id: NewId(), GroupId: "cn=engineering", TeamId: NewId()}
+	json := link.ToJson()
+	rlink := LdapGroupLinkFromJson(strings.NewReader(json))
+
+	if link.Id != rlink.Id || link.GroupId != rlink.GroupId {
+		t.Fatal("ids do not match")
+	}
+}
+
+func TestLdapGroupLinkIsValid(t *testing.T) {
+	link := LdapGroupLink{GroupId: "cn=engineering", TeamId: NewId()}
+
+	if err := link.IsValid(); err == nil {
+		t.Fatal("should be invalid without an id")
+	}
+
+	link.PreSave()
+	if err := link.IsValid(); err != nil {
+		t.Fatal(err)
+	}
+
+	link.ChannelId = "junk"
+	if err := link.IsValid(); err == nil {
+		t.Fatal("should be invalid with a bad channel id")
+	}
+
+	link.ChannelId = NewId()
+	if err := link.IsValid(); err != nil {
+		t.Fatal(err)
+	}
+
+	link.GroupId = strings.Repeat("a", LDAP_GROUP_ID_MAX_LENGTH+1)
+	if err := link.IsValid(); err == nil {
+		t.Fatal("should be invalid with a long group id")
+	}
+
+	link.GroupId = ""
+	if err := link.IsValid(); err == nil {
+		t.Fatal("should be invalid without a group id")
+	}
+}
@@ -0,0 +1,363 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
+	"strings"
+)
+
+const (
+	USER_AUTH_SERVICE_OPENID = "openid"
+	OPENID_VERIFIER_COOKIE   = "MMOIDCVERIFIER"
+	OPENID_CLOCK_SKEW        = 60 // seconds
+)
+
+// OpenIdDiscovery holds the parts of an identity provider's /.well-known/openid-configuration document that we use
+type OpenIdDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserInfoEndpoint      string `json:"userinfo_endpoint"`
+	JwksUri               string `json:"jwks_uri"`
+}
+
+type OpenIdTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	IdToken     string `json:"id_token"`
+}
+
+type JSONWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type JSONWebKeySet struct {
+	Keys []*JSONWebKey `json:"keys"`
+}
+
+// OpenIdClaims are the claims of a verified ID token, optionally merged with the ones from the user info endpoint
+type OpenIdClaims map[string]interface{}
+
+func OpenIdDiscoveryFromJson(data io.Reader) *OpenIdDiscovery {
+	var o OpenIdDiscovery
+
+	if err := json.NewDecoder(data).Decode(&o); err != nil {
+		return nil
+	} else {
+		return &o
+	}
+}
+
+func (o *OpenIdDiscovery) IsValid() bool {
+	return len(o.Issuer) > 0 && IsValidHttpUrl(o.AuthorizationEndpoint) && IsValidHttpUrl(o.TokenEndpoint) && IsValidHttpUrl(o.JwksUri)
+}
+
+func OpenIdTokenResponseFromJson(data io.Reader) *OpenIdTokenResponse {
+	var o OpenIdTokenResponse
+
+	if err := json.NewDecoder(data).Decode(&o); err != nil {
+		return nil
+	} else {
+		return &o
+	}
+}
+
+func JSONWebKeySetFromJson(data io.Reader) *JSONWebKeySet {
+	var o JSONWebKeySet
+
+	if err := json.NewDecoder(data).Decode(&o); err != nil {
+		return nil
+	} else {
+		return &o
+	}
+}
+
+func OpenIdClaimsFromJson(data io.Reader) OpenIdClaims {
+	var o OpenIdClaims
+
+	decoder := json.NewDecoder(data)
+	decoder.UseNumber()
+	if err := decoder.Decode(&o); err != nil {
+		return nil
+	} else {
+		return o
+	}
+}
+
+func (o OpenIdClaims) ToJson() string {
+	if b, err := json.Marshal(o); err != nil {
+		return ""
+	} else {
+		return string(b)
+	}
+}
+
+// Get returns the key with the given id. Tokens without a key id can only be verified when the set has a single key.
+func (o *JSONWebKeySet) Get(kid string) *JSONWebKey {
+	if len(kid) == 0 && len(o.Keys) == 1 {
+		return o.Keys[0]
+	}
+
+	for _, key := range o.Keys {
+		if key.Kid == kid && len(kid) > 0 {
+			return key
+		}
+	}
+
+	return nil
+}
+
+func (o *JSONWebKey) PublicKey() (crypto.PublicKey, error) {
+	switch o.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(o.N, "="))
+		if err != nil || len(n) < 256 {
+			return nil, errors.New("jwk: invalid RSA modulus")
+		}
+
+		e, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(o.E, "="))
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return nil, errors.New("jwk: invalid RSA exponent")
+		}
+
+		exponent := 0
+		for _, b := range e {
+			exponent = exponent<<8 | int(b)
+		}
+
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: exponent}, nil
+	case "EC":
+		if o.Crv != "P-256" {
+			return nil, errors.New("jwk: unsupported curve")
+		}
+
+		x, xErr := base64.RawURLEncoding.DecodeString(strings.TrimRight(o.X, "="))
+		y, yErr := base64.RawURLEncoding.DecodeString(strings.TrimRight(o.Y, "="))
+		if xErr != nil || yErr != nil {
+			return nil, errors.New("jwk: invalid EC point")
+		}
+
+		key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !key.Curve.IsOnCurve(key.X, key.Y) {
+			return nil, errors.New("jwk: point is not on the curve")
+		}
+
+		return key, nil
+	}
+
+	return nil, errors.New("jwk: unsupported key type")
+}
+
+// IdTokenKeyId returns the id of the key that the token claims to be signed with so that the caller can
+// refresh its keys if it doesn't know about it
+func IdTokenKeyId(token string) string {
+	if header, err := parseIdTokenHeader(token); err != nil {
+		return ""
+	} else {
+		return header.Kid
+	}
+}
+
+type idTokenHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+func parseIdTokenHeader(token string) (*idTokenHeader, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("id token: malformed token")
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, errors.New("id token: malformed header")
+	}
+
+	var header idTokenHeader
+	if err := json.Unmarshal(b, &header); err != nil {
+		return nil, errors.New("id token: malformed header")
+	}
+
+	return &header, nil
+}
+
+// VerifyIdToken checks the signature of a compact serialized ID token against keys and returns its claims.
+// Only RS256 and ES256 signatures are accepted. The claims themselves still need to be checked with Validate.
+func VerifyIdToken(token string, keys *JSONWebKeySet) (OpenIdClaims, error) {
+	header, err := parseIdTokenHeader(token)
+	if err != nil {
+		return nil, err
+	}
+
+	key := keys.Get(header.Kid)
+	if key == nil {
+		return nil, errors.New("id token: unknown signing key")
+	}
+
+	if len(key.Alg) > 0 && key.Alg != header.Alg {
+		return nil, errors.New("id token: algorithm doesn't match the key")
+	}
+
+	publicKey, err := key.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+
+	parts := strings.Split(token, ".")
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("id token: malformed signature")
+	}
+
+	hash := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+
+	switch header.Alg {
+	case "RS256":
+		rsaKey, ok := publicKey.(*rsa.PublicKey)
+		if !ok || rsa.VerifyPKCS1v15(rsaKey, crypto.SHA256, hash[:], signature) != nil {
+			return nil, errors.New("id token: invalid signature")
+		}
+	case "ES256":
+		ecKey, ok := publicKey.(*ecdsa.PublicKey)
+		if !ok || len(signature) != 64 {
+			return nil, errors.New("id token: invalid signature")
+		}
+
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(ecKey, hash[:], r, s) {
+			return nil, errors.New("id token: invalid signature")
+		}
+	default:
+		return nil, errors.New("id token: unsupported algorithm " + header.Alg)
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, errors.New("id token: malformed payload")
+	}
+
+	if claims := OpenIdClaimsFromJson(bytes.NewReader(payload)); claims == nil {
+		return nil, errors.New("id token: malformed payload")
+	} else {
+		return claims, nil
+	}
+}
+
+// Validate checks that the token was issued by issuer for clientId in response to the request with nonce and that it
+// hasn't expired. now is in seconds.
+func (o OpenIdClaims) Validate(issuer, clientId, nonce string, now int64) error {
+	if o.GetString("iss") != issuer {
+		return errors.New("id token: wrong issuer")
+	}
+
+	audienceOk := false
+	switch aud := o["aud"].(type) {
+	case string:
+		audienceOk = aud == clientId
+	case []interface{}:
+		for _, a := range aud {
+			if a == clientId {
+				audienceOk = true
+			}
+		}
+
+		if len(aud) > 1 && o.GetString("azp") != clientId {
+			audienceOk = false
+		}
+	}
+
+	if !audienceOk {
+		return errors.New("id token: wrong audience")
+	}
+
+	if exp, ok := o.getInt("exp"); !ok || exp+OPENID_CLOCK_SKEW < now {
+		return errors.New("id token: expired")
+	}
+
+	if iat, ok := o.getInt("iat"); ok && iat-OPENID_CLOCK_SKEW > now {
+		return errors.New("id token: issued in the future")
+	}
+
+	if len(nonce) == 0 || o.GetString("nonce") != nonce {
+		return errors.New("id token: wrong nonce")
+	}
+
+	if len(o.GetString("sub")) == 0 {
+		return errors.New("id token: missing subject")
+	}
+
+	return nil
+}
+
+// GetString returns the claim at path as a string. Nested claims can be reached with dots, such as "address.country".
+func (o OpenIdClaims) GetString(path string) string {
+	var value interface{} = map[string]interface{}(o)
+
+	for _, part := range strings.Split(path, ".") {
+		if m, ok := value.(map[string]interface{}); !ok {
+			return ""
+		} else {
+			value = m[part]
+		}
+	}
+
+	switch v := value.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	}
+
+	return ""
+}
+
+func (o OpenIdClaims) getInt(name string) (int64, bool) {
+	switch v := o[name].(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i, true
+		} else if f, err := v.Float64(); err == nil {
+			return int64(f), true
+		}
+	case float64:
+		return int64(v), true
+	}
+
+	return 0, false
+}
+
+// NewPKCEVerifier returns a random code verifier for a PKCE protected authorization request
+func NewPKCEVerifier() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// PKCEChallenge returns the S256 code challenge for verifier
+func PKCEChallenge(verifier string) string {
+	hash := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package oauthopenid
+
+import (
+	"io"
+	"strings"
+
+	"github.com/mattermost/platform/einterfaces"
+	"github.com/mattermost/platform/model"
+	"github.com/mattermost/platform/utils"
+)
+
+// OpenIdProvider maps the verified claims of an OpenID Connect identity provider onto users using the claim
+// names from OpenIdSettings
+type OpenIdProvider struct {
+}
+
+func init() {
+	provider := &OpenIdProvider{}
+	einterfaces.RegisterOauthProvider(model.USER_AUTH_SERVICE_OPENID, provider)
+}
+
+func userFromClaims(claims model.OpenIdClaims, settings *model.OpenIdSettings) *model.User {
+	user := &model.User{}
+
+	username := claims.GetString(*settings.UsernameClaim)
+	email := strings.TrimSpace(claims.GetString(*settings.EmailClaim))
+	if username == "" {
+		username = strings.Split(email, "@")[0]
+	}
+	user.Username = model.CleanUsername(username)
+	user.Email = email
+
+	if len(*settings.FirstNameClaim) > 0 {
+		user.FirstName = claims.GetString(*settings.FirstNameClaim)
+	}
+	if len(*settings.LastNameClaim) > 0 {
+		user.LastName = claims.GetString(*settings.LastNameClaim)
+	}
+
+	// fall back to splitting the display name like the GitLab provider does
+	if user.FirstName == "" && user.LastName == "" {
+		splitName := strings.SplitN(claims.GetString("name"), " ", 2)
+		user.FirstName = splitName[0]
+		if len(splitName) == 2 {
+			user.LastName = splitName[1]
+		}
+	}
+
+	authData := claims.GetString("sub")
+	user.AuthData = &authData
+	user.AuthService = model.USER_AUTH_SERVICE_OPENID
+
+	return user
+}
+
+func isValidClaims(claims model.OpenIdClaims, settings *model.OpenIdSettings) bool {
+	if claims == nil {
+		return false
+	}
+
+	if len(claims.GetString("sub")) == 0 {
+		return false
+	}
+
+	if len(claims.GetString(*settings.EmailClaim)) == 0 {
+		return false
+	}
+
+	return true
+}
+
+func (m *OpenIdProvider) GetIdentifier() string {
+	return model.USER_AUTH_SERVICE_OPENID
+}
+
+func (m *OpenIdProvider) GetUserFromJson(data io.Reader) *model.User {
+	claims := model.OpenIdClaimsFromJson(data)
+	if isValidClaims(claims, &utils.Cfg.OpenIdSettings) {
+		return userFromClaims(claims, &utils.Cfg.OpenIdSettings)
+	}
+
+	return &model.User{}
+}
+
+func (m *OpenIdProvider) GetAuthDataFromJson(data io.Reader) string {
+	claims := model.OpenIdClaimsFromJson(data)
+	if isValidClaims(claims, &utils.Cfg.OpenIdSettings) {
+		return claims.GetString("sub")
+	}
+
+	return ""
+}
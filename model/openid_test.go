@@ -0,0 +1,247 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func signTestIdToken(t *testing.T, alg, kid string, key crypto.Signer, claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	hash := sha256.Sum256([]byte(signed))
+
+	var signature []byte
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		var err error
+		if signature, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, hash[:]); err != nil {
+			t.Fatal(err)
+		}
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, hash[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		signature = make([]byte, 64)
+		rb, sb := r.Bytes(), s.Bytes()
+		copy(signature[32-len(rb):], rb)
+		copy(signature[64-len(sb):], sb)
+	}
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func testRSAWebKey(kid string, key *rsa.PrivateKey) *JSONWebKey {
+	return &JSONWebKey{
+		Kid: kid,
+		Kty: "RSA",
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func testIdTokenClaims() map[string]interface{} {
+	return map[string]interface{}{
+		"iss":   "https://idp.example.com",
+		"aud":   "client",
+		"sub":   "user1",
+		"nonce": "nonce1",
+		"exp":   GetMillis()/1000 + 300,
+		"iat":   GetMillis() / 1000,
+		"email": "user1@example.com",
+		"address": map[string]interface{}{
+			"country": "CA",
+		},
+	}
+}
+
+func TestVerifyIdTokenRS256(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys := &JSONWebKeySet{Keys: []*JSONWebKey{testRSAWebKey("key1", key), testRSAWebKey("key2", otherKey)}}
+
+	token := signTestIdToken(t, "RS256", "key1", key, testIdTokenClaims())
+	if IdTokenKeyId(token) != "key1" {
+		t.Fatal("should've returned the key id")
+	}
+
+	if claims, err := VerifyIdToken(token, keys); err != nil {
+		t.Fatal(err)
+	} else {
+		if claims.GetString("sub") != "user1" {
+			t.Fatal("should've returned the claims")
+		}
+
+		if claims.GetString("address.country") != "CA" {
+			t.Fatal("should've returned the nested claim")
+		}
+
+		if claims.GetString("exp") == "" {
+			t.Fatal("numeric claims should be returned as strings")
+		}
+	}
+
+	if _, err := VerifyIdToken(signTestIdToken(t, "RS256", "key1", otherKey, testIdTokenClaims()), keys); err == nil {
+		t.Fatal("should've failed with the wrong key")
+	}
+
+	if _, err := VerifyIdToken(signTestIdToken(t, "RS256", "key3", key, testIdTokenClaims()), keys); err == nil {
+		t.Fatal("should've failed with an unknown key")
+	}
+
+	parts := strings.Split(token, ".")
+	tampered := testIdTokenClaims()
+	tampered["sub"] = "user2"
+	payload, _ := json.Marshal(tampered)
+	if _, err := VerifyIdToken(parts[0]+"."+base64.RawURLEncoding.EncodeToString(payload)+"."+parts[2], keys); err == nil {
+		t.Fatal("should've failed with a modified payload")
+	}
+
+	header, _ := json.Marshal(map[string]string{"alg": "none", "kid": "key1"})
+	if _, err := VerifyIdToken(base64.RawURLEncoding.EncodeToString(header)+"."+parts[1]+".", keys); err == nil {
+		t.Fatal("should've rejected an unsigned token")
+	}
+
+	if _, err := VerifyIdToken("garbage", keys); err == nil {
+		t.Fatal("should've failed to parse")
+	}
+}
+
+func TestVerifyIdTokenES256(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys := &JSONWebKeySet{Keys: []*JSONWebKey{{
+		Kty: "EC",
+		Crv: "P-256",
+		X:   base64.RawURLEncoding.EncodeToString(key.X.Bytes()),
+		Y:   base64.RawURLEncoding.EncodeToString(key.Y.Bytes()),
+	}}}
+
+	// a token without a key id can be verified with the only key in the set
+	if claims, err := VerifyIdToken(signTestIdToken(t, "ES256", "", key, testIdTokenClaims()), keys); err != nil {
+		t.Fatal(err)
+	} else if claims.GetString("email") != "user1@example.com" {
+		t.Fatal("should've returned the claims")
+	}
+
+	keys.Keys[0].Alg = "RS256"
+	if _, err := VerifyIdToken(signTestIdToken(t, "ES256", "", key, testIdTokenClaims()), keys); err == nil {
+		t.Fatal("should've failed when the algorithm doesn't match the key")
+	}
+}
+
+func TestOpenIdClaimsValidate(t *testing.T) {
+	now := GetMillis() / 1000
+
+	claims := OpenIdClaimsFromJson(strings.NewReader(OpenIdClaims(testIdTokenClaims()).ToJson()))
+	if err := claims.Validate("https://idp.example.com", "client", "nonce1", now); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := claims.Validate("https://other.example.com", "client", "nonce1", now); err == nil {
+		t.Fatal("should've failed with the wrong issuer")
+	}
+
+	if err := claims.Validate("https://idp.example.com", "other", "nonce1", now); err == nil {
+		t.Fatal("should've failed with the wrong audience")
+	}
+
+	if err := claims.Validate("https://idp.example.com", "client", "nonce2", now); err == nil {
+		t.Fatal("should've failed with the wrong nonce")
+	}
+
+	if err := claims.Validate("https://idp.example.com", "client", "", now); err == nil {
+		t.Fatal("should've failed without a nonce")
+	}
+
+	if err := claims.Validate("https://idp.example.com", "client", "nonce1", now+300+OPENID_CLOCK_SKEW+1); err == nil {
+		t.Fatal("should've failed once expired")
+	}
+
+	if err := claims.Validate("https://idp.example.com", "client", "nonce1", now-OPENID_CLOCK_SKEW-1); err == nil {
+		t.Fatal("should've failed when issued in the future")
+	}
+
+	claims["aud"] = []interface{}{"other", "client"}
+	if err := claims.Validate("https://idp.example.com", "client", "nonce1", now); err == nil {
+		t.Fatal("should've required azp with multiple audiences")
+	}
+
+	claims["azp"] = "client"
+	if err := claims.Validate("https://idp.example.com", "client", "nonce1", now); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestJSONWebKeySetFromJson(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, _ := json.Marshal(&JSONWebKeySet{Keys: []*JSONWebKey{testRSAWebKey("key1", key)}})
+
+	keys := JSONWebKeySetFromJson(strings.NewReader(string(b)))
+	if keys == nil || keys.Get("key1") == nil {
+		t.Fatal("should've decoded the keys")
+	}
+
+	if keys.Get("key2") != nil {
+		t.Fatal("shouldn't have returned an unknown key")
+	}
+
+	if publicKey, err := keys.Get("key1").PublicKey(); err != nil {
+		t.Fatal(err)
+	} else if publicKey.(*rsa.PublicKey).N.Cmp(key.N) != 0 || publicKey.(*rsa.PublicKey).E != key.E {
+		t.Fatal("should've decoded the public key")
+	}
+
+	if _, err := (&JSONWebKey{Kty: "EC", Crv: "P-256", X: "AQ", Y: "AQ"}).PublicKey(); err == nil {
+		t.Fatal("should've rejected a point that isn't on the curve")
+	}
+
+	if _, err := (&JSONWebKey{Kty: "oct"}).PublicKey(); err == nil {
+		t.Fatal("should've rejected a symmetric key")
+	}
+}
+
+func TestPKCE(t *testing.T) {
+	verifier := NewPKCEVerifier()
+	if len(verifier) < 43 {
+		t.Fatal("verifier is too short")
+	}
+
+	if verifier == NewPKCEVerifier() {
+		t.Fatal("verifiers should be random")
+	}
+
+	hash := sha256.Sum256([]byte(verifier))
+	if challenge := PKCEChallenge(verifier); challenge != base64.RawURLEncoding.EncodeToString(hash[:]) || strings.Contains(challenge, "=") {
+		t.Fatal("wrong challenge")
+	}
+}
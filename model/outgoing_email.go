@@ -0,0 +1,172 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+const (
+	OUTGOING_EMAIL_STATUS_PENDING = "pending"
+	OUTGOING_EMAIL_STATUS_SENDING = "sending"
+	OUTGOING_EMAIL_STATUS_SENT    = "sent"
+	OUTGOING_EMAIL_STATUS_FAILED  = "failed"
+
+	OUTGOING_EMAIL_RECIPIENT_MAX_LENGTH = 128
+	OUTGOING_EMAIL_SUBJECT_MAX_LENGTH   = 1024
+	OUTGOING_EMAIL_ERROR_MAX_LENGTH     = 1024
+
+	EMAIL_BOUNCE_REASON_MAX_LENGTH = 1024
+)
+
+// OutgoingEmail is an email waiting in the outbound mail queue or the record of one that has been sent or
+// has failed. Pending emails are sent once NextAttemptAt has passed.
+type OutgoingEmail struct {
+	Id            string `json:"id"`
+	Recipient     string `json:"recipient"`
+	ReplyTo       string `json:"reply_to"`
+	Subject       string `json:"subject"`
+	Body          string `json:"-"`
+	Status        string `json:"status"`
+	Attempts      int    `json:"attempts"`
+	NextAttemptAt int64  `json:"next_attempt_at"`
+	LastError     string `json:"last_error"`
+	CreateAt      int64  `json:"create_at"`
+	UpdateAt      int64  `json:"update_at"`
+}
+
+// EmailBounce marks an address that mail can't be delivered to so that notifications are no longer sent to it
+type EmailBounce struct {
+	Email    string `json:"email"`
+	Reason   string `json:"reason"`
+	CreateAt int64  `json:"create_at"`
+}
+
+// MailQueueStats describes the state of the outbound mail queue for admins
+type MailQueueStats struct {
+	Pending  int64            `json:"pending"`
+	Sending  int64            `json:"sending"`
+	Failed   int64            `json:"failed"`
+	Failures []*OutgoingEmail `json:"failures"`
+}
+
+func (o *OutgoingEmail) PreSave() {
+	if o.Id == "" {
+		o.Id = NewId()
+	}
+
+	o.Recipient = strings.ToLower(strings.TrimSpace(o.Recipient))
+
+	if o.Status == "" {
+		o.Status = OUTGOING_EMAIL_STATUS_PENDING
+	}
+
+	o.CreateAt = GetMillis()
+	o.UpdateAt = o.CreateAt
+
+	if o.NextAttemptAt == 0 {
+		o.NextAttemptAt = o.CreateAt
+	}
+}
+
+func (o *OutgoingEmail) PreUpdate() {
+	o.UpdateAt = GetMillis()
+
+	if len(o.LastError) > OUTGOING_EMAIL_ERROR_MAX_LENGTH {
+		o.LastError = o.LastError[:OUTGOING_EMAIL_ERROR_MAX_LENGTH]
+	}
+}
+
+func (o *OutgoingEmail) IsValid() *AppError {
+	if len(o.Id) != 26 {
+		return NewLocAppError("OutgoingEmail.IsValid", "model.outgoing_email.is_valid.id.app_error", nil, "")
+	}
+
+	if len(o.Recipient) == 0 || len(o.Recipient) > OUTGOING_EMAIL_RECIPIENT_MAX_LENGTH || !IsValidEmail(o.Recipient) {
+		return NewLocAppError("OutgoingEmail.IsValid", "model.outgoing_email.is_valid.recipient.app_error", nil, "id="+o.Id)
+	}
+
+	if len(o.ReplyTo) > OUTGOING_EMAIL_RECIPIENT_MAX_LENGTH || (len(o.ReplyTo) > 0 && !IsValidEmail(o.ReplyTo)) {
+		return NewLocAppError("OutgoingEmail.IsValid", "model.outgoing_email.is_valid.reply_to.app_error", nil, "id="+o.Id)
+	}
+
+	if len(o.Subject) > OUTGOING_EMAIL_SUBJECT_MAX_LENGTH {
+		return NewLocAppError("OutgoingEmail.IsValid", "model.outgoing_email.is_valid.subject.app_error", nil, "id="+o.Id)
+	}
+
+	if o.Status != OUTGOING_EMAIL_STATUS_PENDING && o.Status != OUTGOING_EMAIL_STATUS_SENDING &&
+		o.Status != OUTGOING_EMAIL_STATUS_SENT && o.Status != OUTGOING_EMAIL_STATUS_FAILED {
+		return NewLocAppError("OutgoingEmail.IsValid", "model.outgoing_email.is_valid.status.app_error", nil, "id="+o.Id)
+	}
+
+	if o.CreateAt == 0 {
+		return NewLocAppError("OutgoingEmail.IsValid", "model.outgoing_email.is_valid.create_at.app_error", nil, "id="+o.Id)
+	}
+
+	if o.UpdateAt == 0 {
+		return NewLocAppError("OutgoingEmail.IsValid", "model.outgoing_email.is_valid.update_at.app_error", nil, "id="+o.Id)
+	}
+
+	return nil
+}
+
+func (o *EmailBounce) PreSave() {
+	o.Email = strings.ToLower(strings.TrimSpace(o.Email))
+
+	if len(o.Reason) > EMAIL_BOUNCE_REASON_MAX_LENGTH {
+		o.Reason = o.Reason[:EMAIL_BOUNCE_REASON_MAX_LENGTH]
+	}
+
+	o.CreateAt = GetMillis()
+}
+
+func (o *EmailBounce) IsValid() *AppError {
+	if len(o.Email) == 0 || len(o.Email) > OUTGOING_EMAIL_RECIPIENT_MAX_LENGTH || !IsValidEmail(o.Email) {
+		return NewLocAppError("EmailBounce.IsValid", "model.email_bounce.is_valid.email.app_error", nil, "")
+	}
+
+	if o.CreateAt == 0 {
+		return NewLocAppError("EmailBounce.IsValid", "model.email_bounce.is_valid.create_at.app_error", nil, "email="+o.Email)
+	}
+
+	return nil
+}
+
+func (o *EmailBounce) ToJson() string {
+	if b, err := json.Marshal(o); err != nil {
+		return ""
+	} else {
+		return string(b)
+	}
+}
+
+func EmailBounceFromJson(data io.Reader) *EmailBounce {
+	var o EmailBounce
+
+	if err := json.NewDecoder(data).Decode(&o); err != nil {
+		return nil
+	} else {
+		return &o
+	}
+}
+
+func (o *MailQueueStats) ToJson() string {
+	if b, err := json.Marshal(o); err != nil {
+		return ""
+	} else {
+		return string(b)
+	}
+}
+
+func MailQueueStatsFromJson(data io.Reader) *MailQueueStats {
+	var o MailQueueStats
+
+	if err := json.NewDecoder(data).Decode(&o); err != nil {
+		return nil
+	} else {
+		return &o
+	}
+}
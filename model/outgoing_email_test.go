@@ -0,0 +1,53 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOutgoingEmailIsValid(t *testing.T) {
+	email := OutgoingEmail{Recipient: " User@Example.com ", Subject: "subject", Body: "body"}
+
+	if err := email.IsValid(); err == nil {
+		t.Fatal("should be invalid before saving")
+	}
+
+	email.PreSave()
+	if err := email.IsValid(); err != nil {
+		t.Fatal(err)
+	}
+
+	if email.Recipient != "user@example.com" || email.Status != OUTGOING_EMAIL_STATUS_PENDING || email.NextAttemptAt != email.CreateAt {
+		t.Fatal("should have set the defaults", email)
+	}
+
+	email.Recipient = "user"
+	if err := email.IsValid(); err == nil {
+		t.Fatal("should be invalid without a valid address")
+	}
+
+	email.Recipient = "user@example.com"
+	email.Status = "queued"
+	if err := email.IsValid(); err == nil {
+		t.Fatal("should be invalid with an unknown status")
+	}
+
+	email.Status = OUTGOING_EMAIL_STATUS_FAILED
+	email.LastError = strings.Repeat("a", OUTGOING_EMAIL_ERROR_MAX_LENGTH+1)
+	email.PreUpdate()
+	if len(email.LastError) != OUTGOING_EMAIL_ERROR_MAX_LENGTH {
+		t.Fatal("should have truncated the error")
+	}
+}
+
+func TestEmailBounceJson(t *testing.T) {
+	bounce := EmailBounce{Email: "user@example.com", Reason: "550 No such user"}
+	rbounce := EmailBounceFromJson(strings.NewReader(bounce.ToJson()))
+
+	if rbounce.Email != bounce.Email || rbounce.Reason != bounce.Reason {
+		t.Fatal("bounces do not match")
+	}
+}
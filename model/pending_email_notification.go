@@ -0,0 +1,47 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+// PendingEmailNotification is a notification email waiting to be sent to a user as part of their next batch
+type PendingEmailNotification struct {
+	Id       string `json:"id"`
+	UserId   string `json:"user_id"`
+	PostId   string `json:"post_id"`
+	TeamName string `json:"team_name"`
+	CreateAt int64  `json:"create_at"`
+}
+
+func (o *PendingEmailNotification) PreSave() {
+	if o.Id == "" {
+		o.Id = NewId()
+	}
+
+	if o.CreateAt == 0 {
+		o.CreateAt = GetMillis()
+	}
+}
+
+func (o *PendingEmailNotification) IsValid() *AppError {
+	if len(o.Id) != 26 {
+		return NewLocAppError("PendingEmailNotification.IsValid", "model.pending_email_notification.is_valid.id.app_error", nil, "")
+	}
+
+	if len(o.UserId) != 26 {
+		return NewLocAppError("PendingEmailNotification.IsValid", "model.pending_email_notification.is_valid.user_id.app_error", nil, "id="+o.Id)
+	}
+
+	if len(o.PostId) != 26 {
+		return NewLocAppError("PendingEmailNotification.IsValid", "model.pending_email_notification.is_valid.post_id.app_error", nil, "id="+o.Id)
+	}
+
+	if len(o.TeamName) == 0 || len(o.TeamName) > 64 {
+		return NewLocAppError("PendingEmailNotification.IsValid", "model.pending_email_notification.is_valid.team_name.app_error", nil, "id="+o.Id)
+	}
+
+	if o.CreateAt == 0 {
+		return NewLocAppError("PendingEmailNotification.IsValid", "model.pending_email_notification.is_valid.create_at.app_error", nil, "id="+o.Id)
+	}
+
+	return nil
+}
@@ -20,6 +20,7 @@ const (
 	POST_DISPLAYNAME_CHANGE    = "system_displayname_change"
 	POST_CHANNEL_DELETED       = "system_channel_deleted"
 	POST_EPHEMERAL             = "system_ephemeral"
+	POST_AUTO_RESPONDER        = "system_auto_responder"
 	POST_FILEIDS_MAX_RUNES     = 150
 	POST_FILENAMES_MAX_RUNES   = 4000
 	POST_HASHTAGS_MAX_RUNES    = 1000
@@ -120,7 +121,8 @@ func (o *Post) IsValid() *AppError {
 	// should be removed once more message types are supported
 	if !(o.Type == POST_DEFAULT || o.Type == POST_JOIN_LEAVE || o.Type == POST_ADD_REMOVE ||
 		o.Type == POST_SLACK_ATTACHMENT || o.Type == POST_HEADER_CHANGE ||
-		o.Type == POST_DISPLAYNAME_CHANGE || o.Type == POST_CHANNEL_DELETED) {
+		o.Type == POST_DISPLAYNAME_CHANGE || o.Type == POST_CHANNEL_DELETED ||
+		o.Type == POST_AUTO_RESPONDER) {
 		return NewLocAppError("Post.IsValid", "model.post.is_valid.type.app_error", nil, "id="+o.Type)
 	}
 
@@ -38,6 +38,10 @@ const (
 	PREFERENCE_CATEGORY_NOTIFICATIONS = "notifications"
 	PREFERENCE_NAME_EMAIL_INTERVAL    = "email_interval"
 	PREFERENCE_DEFAULT_EMAIL_INTERVAL = "30" // default to match the interval of the "immediate" setting (ie 30 seconds)
+	PREFERENCE_NAME_DIGEST_LAST_SENT  = "digest_last_sent"
+
+	PREFERENCE_CATEGORY_NOTIFICATION_CHANNELS = "notification_channels"
+	// the name for notification_channels is the name of the delivery channel and value is "false" to turn it off
 )
 
 type Preference struct {
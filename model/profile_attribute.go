@@ -0,0 +1,256 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	PROFILE_ATTRIBUTE_TYPE_TEXT   = "text"
+	PROFILE_ATTRIBUTE_TYPE_SELECT = "select"
+	PROFILE_ATTRIBUTE_TYPE_URL    = "url"
+	PROFILE_ATTRIBUTE_TYPE_PHONE  = "phone"
+
+	PROFILE_ATTRIBUTE_VISIBILITY_ALWAYS = "always" // shown to everyone who can see the user
+	PROFILE_ATTRIBUTE_VISIBILITY_HIDDEN = "hidden" // shown to the user and system admins only
+
+	PROFILE_ATTRIBUTE_NAME_MAX_LENGTH         = 32
+	PROFILE_ATTRIBUTE_DISPLAY_NAME_MAX_LENGTH = 64
+	PROFILE_ATTRIBUTE_OPTIONS_MAX_LENGTH      = 1024
+	PROFILE_ATTRIBUTE_VALUE_MAX_LENGTH        = 128
+	PROFILE_ATTRIBUTES_MAX_LENGTH             = 4000
+)
+
+var validProfileAttributeName = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+var validProfileAttributePhone = regexp.MustCompile(`^\+?[0-9][0-9 ()./-]{2,31}$`)
+
+// ProfileAttribute is a custom field that admins add to user profiles. Values are kept in the user's
+// ProfileAttributes keyed by Name. LdapAttribute and SamlAttribute name the directory attribute or
+// assertion that the value is synchronized from for users signing in with that service.
+type ProfileAttribute struct {
+	Id            string      `json:"id"`
+	Name          string      `json:"name"`
+	DisplayName   string      `json:"display_name"`
+	Type          string      `json:"type"`
+	Options       StringArray `json:"options"`
+	Visibility    string      `json:"visibility"`
+	LdapAttribute string      `json:"ldap_attribute"`
+	SamlAttribute string      `json:"saml_attribute"`
+	SortOrder     int         `json:"sort_order"`
+	CreateAt      int64       `json:"create_at"`
+	UpdateAt      int64       `json:"update_at"`
+}
+
+func (o *ProfileAttribute) ToJson() string {
+	if b, err := json.Marshal(o); err != nil {
+		return ""
+	} else {
+		return string(b)
+	}
+}
+
+func ProfileAttributeFromJson(data io.Reader) *ProfileAttribute {
+	var o ProfileAttribute
+
+	if err := json.NewDecoder(data).Decode(&o); err != nil {
+		return nil
+	} else {
+		return &o
+	}
+}
+
+func ProfileAttributesToJson(o []*ProfileAttribute) string {
+	if b, err := json.Marshal(o); err != nil {
+		return ""
+	} else {
+		return string(b)
+	}
+}
+
+func ProfileAttributesFromJson(data io.Reader) []*ProfileAttribute {
+	var o []*ProfileAttribute
+
+	if err := json.NewDecoder(data).Decode(&o); err != nil {
+		return nil
+	} else {
+		return o
+	}
+}
+
+func (o *ProfileAttribute) PreSave() {
+	if o.Id == "" {
+		o.Id = NewId()
+	}
+
+	if o.Options == nil {
+		o.Options = StringArray{}
+	}
+
+	if o.Visibility == "" {
+		o.Visibility = PROFILE_ATTRIBUTE_VISIBILITY_ALWAYS
+	}
+
+	o.CreateAt = GetMillis()
+	o.UpdateAt = o.CreateAt
+}
+
+func (o *ProfileAttribute) PreUpdate() {
+	if o.Options == nil {
+		o.Options = StringArray{}
+	}
+
+	o.UpdateAt = GetMillis()
+}
+
+func (o *ProfileAttribute) IsValid() *AppError {
+	if len(o.Id) != 26 {
+		return NewLocAppError("ProfileAttribute.IsValid", "model.profile_attribute.is_valid.id.app_error", nil, "")
+	}
+
+	if len(o.Name) > PROFILE_ATTRIBUTE_NAME_MAX_LENGTH || !validProfileAttributeName.MatchString(o.Name) {
+		return NewLocAppError("ProfileAttribute.IsValid", "model.profile_attribute.is_valid.name.app_error", nil, "id="+o.Id)
+	}
+
+	if len(o.DisplayName) == 0 || utf8.RuneCountInString(o.DisplayName) > PROFILE_ATTRIBUTE_DISPLAY_NAME_MAX_LENGTH {
+		return NewLocAppError("ProfileAttribute.IsValid", "model.profile_attribute.is_valid.display_name.app_error", nil, "id="+o.Id)
+	}
+
+	switch o.Type {
+	case PROFILE_ATTRIBUTE_TYPE_TEXT, PROFILE_ATTRIBUTE_TYPE_URL, PROFILE_ATTRIBUTE_TYPE_PHONE:
+	case PROFILE_ATTRIBUTE_TYPE_SELECT:
+		if len(o.Options) == 0 {
+			return NewLocAppError("ProfileAttribute.IsValid", "model.profile_attribute.is_valid.options.app_error", nil, "id="+o.Id)
+		}
+	default:
+		return NewLocAppError("ProfileAttribute.IsValid", "model.profile_attribute.is_valid.type.app_error", nil, "id="+o.Id)
+	}
+
+	if len(ArrayToJson(o.Options)) > PROFILE_ATTRIBUTE_OPTIONS_MAX_LENGTH {
+		return NewLocAppError("ProfileAttribute.IsValid", "model.profile_attribute.is_valid.options.app_error", nil, "id="+o.Id)
+	}
+
+	for _, option := range o.Options {
+		if len(option) == 0 || utf8.RuneCountInString(option) > PROFILE_ATTRIBUTE_VALUE_MAX_LENGTH {
+			return NewLocAppError("ProfileAttribute.IsValid", "model.profile_attribute.is_valid.options.app_error", nil, "id="+o.Id)
+		}
+	}
+
+	if o.Visibility != PROFILE_ATTRIBUTE_VISIBILITY_ALWAYS && o.Visibility != PROFILE_ATTRIBUTE_VISIBILITY_HIDDEN {
+		return NewLocAppError("ProfileAttribute.IsValid", "model.profile_attribute.is_valid.visibility.app_error", nil, "id="+o.Id)
+	}
+
+	if len(o.LdapAttribute) > 128 || len(o.SamlAttribute) > 128 {
+		return NewLocAppError("ProfileAttribute.IsValid", "model.profile_attribute.is_valid.mapping.app_error", nil, "id="+o.Id)
+	}
+
+	if o.CreateAt == 0 {
+		return NewLocAppError("ProfileAttribute.IsValid", "model.profile_attribute.is_valid.create_at.app_error", nil, "id="+o.Id)
+	}
+
+	if o.UpdateAt == 0 {
+		return NewLocAppError("ProfileAttribute.IsValid", "model.profile_attribute.is_valid.update_at.app_error", nil, "id="+o.Id)
+	}
+
+	return nil
+}
+
+// IsValidValue returns whether the value can be stored for the attribute. Empty values clear the attribute.
+func (o *ProfileAttribute) IsValidValue(value string) bool {
+	if utf8.RuneCountInString(value) > PROFILE_ATTRIBUTE_VALUE_MAX_LENGTH {
+		return false
+	}
+
+	switch o.Type {
+	case PROFILE_ATTRIBUTE_TYPE_SELECT:
+		for _, option := range o.Options {
+			if option == value {
+				return true
+			}
+		}
+
+		return false
+	case PROFILE_ATTRIBUTE_TYPE_URL:
+		u, err := url.Parse(value)
+		return err == nil && (u.Scheme == "http" || u.Scheme == "https") && len(u.Host) > 0
+	case PROFILE_ATTRIBUTE_TYPE_PHONE:
+		return validProfileAttributePhone.MatchString(value)
+	}
+
+	return true
+}
+
+// MappedAttribute returns the attribute synchronized from the given sign in service, if any
+func (o *ProfileAttribute) MappedAttribute(authService string) string {
+	switch authService {
+	case USER_AUTH_SERVICE_LDAP:
+		return o.LdapAttribute
+	case USER_AUTH_SERVICE_SAML:
+		return o.SamlAttribute
+	}
+
+	return ""
+}
+
+// CleanProfileAttributes checks the values against the attribute definitions, dropping values of attributes
+// that no longer exist and empty values
+func CleanProfileAttributes(attributes []*ProfileAttribute, values StringMap) (StringMap, *AppError) {
+	cleaned := StringMap{}
+
+	for _, attribute := range attributes {
+		value := strings.TrimSpace(values[attribute.Name])
+		if len(value) == 0 {
+			continue
+		}
+
+		if !attribute.IsValidValue(value) {
+			return nil, NewLocAppError("CleanProfileAttributes", "model.profile_attribute.is_valid_value.app_error", map[string]interface{}{"Attribute": attribute.DisplayName}, "name="+attribute.Name)
+		}
+
+		cleaned[attribute.Name] = value
+	}
+
+	if len(MapToJson(cleaned)) > PROFILE_ATTRIBUTES_MAX_LENGTH {
+		return nil, NewLocAppError("CleanProfileAttributes", "model.profile_attribute.too_long.app_error", nil, "")
+	}
+
+	return cleaned, nil
+}
+
+// SetProfileAttributesFromDirectory copies the values of the directory attributes or SAML assertions mapped to
+// profile attributes onto the user, for use by the LDAP and SAML implementations
+func (u *User) SetProfileAttributesFromDirectory(attributes []*ProfileAttribute, values map[string]string) {
+	u.MakeNonNil()
+
+	for _, attribute := range attributes {
+		if mapped := attribute.MappedAttribute(u.AuthService); len(mapped) > 0 {
+			if value := strings.TrimSpace(values[mapped]); len(value) > 0 && attribute.IsValidValue(value) {
+				u.ProfileAttributes[attribute.Name] = value
+			} else {
+				delete(u.ProfileAttributes, attribute.Name)
+			}
+		}
+	}
+}
+
+// SanitizeProfileAttributes removes the values of hidden attributes and of attributes that no longer exist
+func (u *User) SanitizeProfileAttributes(attributes []*ProfileAttribute, showHidden bool) {
+	if len(u.ProfileAttributes) == 0 {
+		return
+	}
+
+	visible := StringMap{}
+	for _, attribute := range attributes {
+		if value, ok := u.ProfileAttributes[attribute.Name]; ok && (showHidden || attribute.Visibility != PROFILE_ATTRIBUTE_VISIBILITY_HIDDEN) {
+			visible[attribute.Name] = value
+		}
+	}
+
+	u.ProfileAttributes = visible
+}
@@ -0,0 +1,135 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestProfileAttributeJson(t *testing.T) {
+	attribute := ProfileAttribute{Id: NewId(), Name: "department", Options: StringArray{"Sales"}}
+	json := attribute.ToJson()
+	rattribute := ProfileAttributeFromJson(strings.NewReader(json))
+
+	if attribute.Id != rattribute.Id || attribute.Name != rattribute.Name || rattribute.Options[0] != "Sales" {
+		t.Fatal("attributes do not match")
+	}
+}
+
+func TestProfileAttributeIsValid(t *testing.T) {
+	attribute := ProfileAttribute{Name: "department", DisplayName: "Department", Type: PROFILE_ATTRIBUTE_TYPE_TEXT}
+
+	if err := attribute.IsValid(); err == nil {
+		t.Fatal("should be invalid without an id")
+	}
+
+	attribute.PreSave()
+	if err := attribute.IsValid(); err != nil {
+		t.Fatal(err)
+	}
+
+	if attribute.Visibility != PROFILE_ATTRIBUTE_VISIBILITY_ALWAYS {
+		t.Fatal("should have defaulted to always visible")
+	}
+
+	attribute.Name = "Department"
+	if err := attribute.IsValid(); err == nil {
+		t.Fatal("should be invalid with an upper case name")
+	}
+
+	attribute.Name = strings.Repeat("a", PROFILE_ATTRIBUTE_NAME_MAX_LENGTH+1)
+	if err := attribute.IsValid(); err == nil {
+		t.Fatal("should be invalid with a long name")
+	}
+
+	attribute.Name = "department"
+	attribute.Type = PROFILE_ATTRIBUTE_TYPE_SELECT
+	if err := attribute.IsValid(); err == nil {
+		t.Fatal("should be invalid as a select without options")
+	}
+
+	attribute.Options = StringArray{"Sales", "Engineering"}
+	if err := attribute.IsValid(); err != nil {
+		t.Fatal(err)
+	}
+
+	attribute.Type = "date"
+	if err := attribute.IsValid(); err == nil {
+		t.Fatal("should be invalid with an unknown type")
+	}
+
+	attribute.Type = PROFILE_ATTRIBUTE_TYPE_TEXT
+	attribute.Visibility = "admins"
+	if err := attribute.IsValid(); err == nil {
+		t.Fatal("should be invalid with an unknown visibility")
+	}
+}
+
+func TestProfileAttributeIsValidValue(t *testing.T) {
+	selectAttribute := &ProfileAttribute{Type: PROFILE_ATTRIBUTE_TYPE_SELECT, Options: StringArray{"she/her", "he/him", "they/them"}}
+	if !selectAttribute.IsValidValue("they/them") || selectAttribute.IsValidValue("other") {
+		t.Fatal("select values should match an option")
+	}
+
+	urlAttribute := &ProfileAttribute{Type: PROFILE_ATTRIBUTE_TYPE_URL}
+	if !urlAttribute.IsValidValue("https://example.com/me") || urlAttribute.IsValidValue("javascript:alert(1)") || urlAttribute.IsValidValue("example.com") {
+		t.Fatal("url values should be http or https links")
+	}
+
+	phoneAttribute := &ProfileAttribute{Type: PROFILE_ATTRIBUTE_TYPE_PHONE}
+	if !phoneAttribute.IsValidValue("+1 (555) 123-4567") || phoneAttribute.IsValidValue("call me") {
+		t.Fatal("phone values should be phone numbers")
+	}
+
+	textAttribute := &ProfileAttribute{Type: PROFILE_ATTRIBUTE_TYPE_TEXT}
+	if !textAttribute.IsValidValue("Toronto") || textAttribute.IsValidValue(strings.Repeat("a", PROFILE_ATTRIBUTE_VALUE_MAX_LENGTH+1)) {
+		t.Fatal("text values should be limited in length")
+	}
+}
+
+func TestCleanProfileAttributes(t *testing.T) {
+	attributes := []*ProfileAttribute{
+		{Name: "location", Type: PROFILE_ATTRIBUTE_TYPE_TEXT},
+		{Name: "pronouns", Type: PROFILE_ATTRIBUTE_TYPE_SELECT, Options: StringArray{"they/them"}},
+	}
+
+	values, err := CleanProfileAttributes(attributes, StringMap{"location": " Toronto ", "pronouns": "", "deleted": "value"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(values) != 1 || values["location"] != "Toronto" {
+		t.Fatal("should have kept only the trimmed location", values)
+	}
+
+	if _, err := CleanProfileAttributes(attributes, StringMap{"pronouns": "other"}); err == nil {
+		t.Fatal("should have failed with an invalid value")
+	}
+}
+
+func TestUserProfileAttributes(t *testing.T) {
+	attributes := []*ProfileAttribute{
+		{Name: "department", Type: PROFILE_ATTRIBUTE_TYPE_TEXT, Visibility: PROFILE_ATTRIBUTE_VISIBILITY_ALWAYS, LdapAttribute: "ou"},
+		{Name: "phone", Type: PROFILE_ATTRIBUTE_TYPE_PHONE, Visibility: PROFILE_ATTRIBUTE_VISIBILITY_HIDDEN, SamlAttribute: "Phone"},
+	}
+
+	user := User{AuthService: USER_AUTH_SERVICE_LDAP, ProfileAttributes: StringMap{"phone": "555-1234"}}
+	user.SetProfileAttributesFromDirectory(attributes, map[string]string{"ou": "Sales", "Phone": "555-0000"})
+
+	if user.ProfileAttributes["department"] != "Sales" || user.ProfileAttributes["phone"] != "555-1234" {
+		t.Fatal("should have only set the attributes mapped from LDAP", user.ProfileAttributes)
+	}
+
+	visible := user
+	visible.SanitizeProfileAttributes(attributes, true)
+	if len(visible.ProfileAttributes) != 2 {
+		t.Fatal("should have kept hidden attributes")
+	}
+
+	user.SanitizeProfileAttributes(attributes, false)
+	if len(user.ProfileAttributes) != 1 || user.ProfileAttributes["department"] != "Sales" {
+		t.Fatal("should have removed hidden attributes", user.ProfileAttributes)
+	}
+}
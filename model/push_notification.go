@@ -4,6 +4,14 @@
 package model
 
 import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
 	"encoding/json"
 	"io"
 	"strings"
@@ -19,6 +27,12 @@ const (
 	CATEGORY_DM = "DIRECT_MESSAGE"
 
 	MHPNS = "https://push.mattermost.com"
+
+	PUSH_SENDER_PROXY  = "proxy"
+	PUSH_SENDER_DIRECT = "direct"
+
+	APNS_SERVER = "https://api.push.apple.com"
+	FCM_SERVER  = "https://fcm.googleapis.com"
 )
 
 type PushNotification struct {
@@ -34,6 +48,16 @@ type PushNotification struct {
 	ChannelId        string `json:"channel_id"`
 	ChannelName      string `json:"channel_name"`
 	Type             string `json:"type"`
+	PostId           string `json:"post_id"`
+	IsIdLoaded       bool   `json:"is_id_loaded"`
+	EncryptedData    string `json:"encrypted_data"`
+}
+
+// PushNotificationContents holds the parts of a notification that are encrypted for the device when the
+// server is configured to send encrypted notifications
+type PushNotificationContents struct {
+	Message     string `json:"message"`
+	ChannelName string `json:"channel_name"`
 }
 
 func (me *PushNotification) ToJson() string {
@@ -65,3 +89,70 @@ func PushNotificationFromJson(data io.Reader) *PushNotification {
 		return nil
 	}
 }
+
+// IsValidDevicePublicKey checks that a key registered by a mobile app is a base64 encoded DER
+// SubjectPublicKeyInfo for a P-256 key that notifications can be encrypted with
+func IsValidDevicePublicKey(publicKey string) bool {
+	_, err := parseDevicePublicKey(publicKey)
+	return err == nil
+}
+
+func parseDevicePublicKey(publicKey string) (*ecdh.PublicKey, error) {
+	der, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, err
+	}
+
+	ecdsaKey, ok := key.(*ecdsa.PublicKey)
+	if !ok || ecdsaKey.Curve.Params().Name != "P-256" {
+		return nil, x509.ErrUnsupportedAlgorithm
+	}
+
+	return ecdsaKey.ECDH()
+}
+
+// EncryptContents encrypts the contents for the device that registered the public key and stores them in
+// EncryptedData. The result is the base64 encoding of an ephemeral uncompressed P-256 public key, a 12 byte
+// nonce and the AES-256-GCM sealed JSON of the contents, keyed with the SHA-256 hash of the ECDH shared
+// secret followed by the ephemeral public key.
+func (me *PushNotification) EncryptContents(publicKey string, contents *PushNotificationContents) *AppError {
+	deviceKey, err := parseDevicePublicKey(publicKey)
+	if err != nil {
+		return NewLocAppError("PushNotification.EncryptContents", "model.push_notification.encrypt_contents.public_key.app_error", nil, err.Error())
+	}
+
+	ephemeralKey, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return NewLocAppError("PushNotification.EncryptContents", "model.push_notification.encrypt_contents.app_error", nil, err.Error())
+	}
+
+	secret, err := ephemeralKey.ECDH(deviceKey)
+	if err != nil {
+		return NewLocAppError("PushNotification.EncryptContents", "model.push_notification.encrypt_contents.app_error", nil, err.Error())
+	}
+
+	ephemeralPublicKey := ephemeralKey.PublicKey().Bytes()
+	key := sha256.Sum256(append(secret, ephemeralPublicKey...))
+
+	block, _ := aes.NewCipher(key[:])
+	gcm, _ := cipher.NewGCM(block)
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return NewLocAppError("PushNotification.EncryptContents", "model.push_notification.encrypt_contents.app_error", nil, err.Error())
+	}
+
+	plaintext, _ := json.Marshal(contents)
+
+	data := append(ephemeralPublicKey, nonce...)
+	data = gcm.Seal(data, nonce, plaintext, nil)
+
+	me.EncryptedData = base64.StdEncoding.EncodeToString(data)
+
+	return nil
+}
@@ -4,6 +4,16 @@
 package model
 
 import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
 	"strings"
 	"testing"
 )
@@ -17,3 +27,66 @@ func TestPushNotification(t *testing.T) {
 		t.Fatal("Ids do not match")
 	}
 }
+
+func TestPushNotificationEncryptContents(t *testing.T) {
+	ecdsaKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	der, _ := x509.MarshalPKIXPublicKey(&ecdsaKey.PublicKey)
+	publicKey := base64.StdEncoding.EncodeToString(der)
+
+	if !IsValidDevicePublicKey(publicKey) {
+		t.Fatal("should have accepted a P-256 public key")
+	}
+
+	if IsValidDevicePublicKey("junk") {
+		t.Fatal("shouldn't have accepted an invalid key")
+	}
+
+	otherKey, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	otherDer, _ := x509.MarshalPKIXPublicKey(&otherKey.PublicKey)
+	if IsValidDevicePublicKey(base64.StdEncoding.EncodeToString(otherDer)) {
+		t.Fatal("shouldn't have accepted a key for a different curve")
+	}
+
+	msg := PushNotification{}
+	if err := msg.EncryptContents("junk", &PushNotificationContents{Message: "secret"}); err == nil {
+		t.Fatal("should have failed to encrypt with an invalid key")
+	}
+
+	contents := &PushNotificationContents{Message: "@sender: secret", ChannelName: "town-square"}
+	if err := msg.EncryptContents(publicKey, contents); err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(msg.ToJson(), "secret") {
+		t.Fatal("shouldn't have sent the contents in plain text")
+	}
+
+	data, err := base64.StdEncoding.DecodeString(msg.EncryptedData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ephemeralKey, err := ecdh.P256().NewPublicKey(data[:65])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	privateKey, _ := ecdsaKey.ECDH()
+	secret, _ := privateKey.ECDH(ephemeralKey)
+	key := sha256.Sum256(append(secret, data[:65]...))
+
+	block, _ := aes.NewCipher(key[:])
+	gcm, _ := cipher.NewGCM(block)
+
+	plaintext, err := gcm.Open(nil, data[65:65+gcm.NonceSize()], data[65+gcm.NonceSize():], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decrypted PushNotificationContents
+	if err := json.Unmarshal(plaintext, &decrypted); err != nil {
+		t.Fatal(err)
+	} else if decrypted != *contents {
+		t.Fatal("should have decrypted the contents", decrypted)
+	}
+}
@@ -0,0 +1,29 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPushResponse(t *testing.T) {
+	m := NewRemovePushResponse()
+	result := PushResponseFromJson(strings.NewReader(m.ToJson()))
+
+	if result[PUSH_STATUS] != PUSH_STATUS_REMOVE {
+		t.Fatal("status should be REMOVE")
+	}
+
+	m = NewErrorPushResponse("an error")
+	result = PushResponseFromJson(strings.NewReader(m.ToJson()))
+
+	if result[PUSH_STATUS] != PUSH_STATUS_FAIL || result[PUSH_STATUS_ERROR_MSG] != "an error" {
+		t.Fatal("should have kept the error")
+	}
+
+	if PushResponseFromJson(strings.NewReader("junk")) != nil {
+		t.Fatal("should have failed to parse")
+	}
+}
@@ -0,0 +1,286 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	TIMEZONE_NOTIFY_PROP = "timezone"
+
+	REMINDER_MESSAGE_MAX_RUNES = 4000
+	REMINDER_DEFAULT_TIME      = 9 * 60
+)
+
+// A Reminder is a message that a user asked to be sent back to them at a later time, optionally about a
+// post. Reminders are kept for a while after they've fired so that they can still be snoozed.
+type Reminder struct {
+	Id       string `json:"id"`
+	UserId   string `json:"user_id"`
+	TeamId   string `json:"team_id"`
+	PostId   string `json:"post_id"`
+	Message  string `json:"message"`
+	RemindAt int64  `json:"remind_at"`
+	CreateAt int64  `json:"create_at"`
+	FiredAt  int64  `json:"fired_at"`
+}
+
+func (o *Reminder) ToJson() string {
+	if b, err := json.Marshal(o); err != nil {
+		return ""
+	} else {
+		return string(b)
+	}
+}
+
+func ReminderFromJson(data io.Reader) *Reminder {
+	var o Reminder
+
+	if err := json.NewDecoder(data).Decode(&o); err != nil {
+		return nil
+	} else {
+		return &o
+	}
+}
+
+func RemindersToJson(o []*Reminder) string {
+	if b, err := json.Marshal(o); err != nil {
+		return ""
+	} else {
+		return string(b)
+	}
+}
+
+func RemindersFromJson(data io.Reader) []*Reminder {
+	var o []*Reminder
+
+	if err := json.NewDecoder(data).Decode(&o); err != nil {
+		return nil
+	} else {
+		return o
+	}
+}
+
+func (o *Reminder) PreSave() {
+	if o.Id == "" {
+		o.Id = NewId()
+	}
+
+	o.CreateAt = GetMillis()
+	o.FiredAt = 0
+}
+
+// Snooze moves the reminder to a later time, sending it again if it has already fired
+func (o *Reminder) Snooze(remindAt int64) {
+	o.RemindAt = remindAt
+	o.FiredAt = 0
+}
+
+func (o *Reminder) IsValid() *AppError {
+	if len(o.Id) != 26 {
+		return NewLocAppError("Reminder.IsValid", "model.reminder.is_valid.id.app_error", nil, "")
+	}
+
+	if len(o.UserId) != 26 {
+		return NewLocAppError("Reminder.IsValid", "model.reminder.is_valid.user_id.app_error", nil, "id="+o.Id)
+	}
+
+	if len(o.TeamId) != 26 {
+		return NewLocAppError("Reminder.IsValid", "model.reminder.is_valid.team_id.app_error", nil, "id="+o.Id)
+	}
+
+	if len(o.PostId) != 0 && len(o.PostId) != 26 {
+		return NewLocAppError("Reminder.IsValid", "model.reminder.is_valid.post_id.app_error", nil, "id="+o.Id)
+	}
+
+	if utf8.RuneCountInString(o.Message) > REMINDER_MESSAGE_MAX_RUNES || (len(o.Message) == 0 && len(o.PostId) == 0) {
+		return NewLocAppError("Reminder.IsValid", "model.reminder.is_valid.message.app_error", nil, "id="+o.Id)
+	}
+
+	if o.RemindAt == 0 {
+		return NewLocAppError("Reminder.IsValid", "model.reminder.is_valid.remind_at.app_error", nil, "id="+o.Id)
+	}
+
+	if o.CreateAt == 0 {
+		return NewLocAppError("Reminder.IsValid", "model.reminder.is_valid.create_at.app_error", nil, "id="+o.Id)
+	}
+
+	return nil
+}
+
+// LocationFromNotifyProps returns the time zone that the user set in their notify props, falling back to
+// the ones they picked for digests or do not disturb and then to the server's.
+func LocationFromNotifyProps(props StringMap) *time.Location {
+	for _, prop := range []string{TIMEZONE_NOTIFY_PROP, DIGEST_TIMEZONE_NOTIFY_PROP, "dnd_timezone"} {
+		if len(props[prop]) == 0 {
+			continue
+		}
+
+		if location, err := time.LoadLocation(props[prop]); err == nil {
+			return location
+		}
+	}
+
+	return time.Local
+}
+
+// ParseReminderTime parses when a reminder is due from the start of the text, returning the time and the
+// rest of the text. Times are relative to now and in its time zone. The supported forms are:
+//
+//	in 2 hours, in 30 minutes, in a day, in 3 weeks
+//	tomorrow, tomorrow 9am, tomorrow at 14:30
+//	today 5pm, at 3:30pm, 9am, noon
+//	monday, on friday at 4pm
+//	2016-12-01, on 2016-12-01 15:00
+//
+// Days without a time of day default to 9am and times of day that have passed today mean tomorrow.
+func ParseReminderTime(text string, now time.Time) (time.Time, string, *AppError) {
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return time.Time{}, "", NewLocAppError("ParseReminderTime", "model.reminder.parse_time.app_error", nil, "text="+text)
+	}
+
+	var remindAt time.Time
+	n := 0
+
+	first := strings.ToLower(words[0])
+	if first == "on" && len(words) > 1 {
+		first = strings.ToLower(words[1])
+		n = 1
+	}
+
+	if first == "in" && n == 0 {
+		if len(words) < 3 {
+			return time.Time{}, "", NewLocAppError("ParseReminderTime", "model.reminder.parse_time.app_error", nil, "text="+text)
+		}
+
+		count := 0
+		if amount := strings.ToLower(words[1]); amount == "a" || amount == "an" {
+			count = 1
+		} else if c, err := strconv.Atoi(amount); err != nil || c <= 0 {
+			return time.Time{}, "", NewLocAppError("ParseReminderTime", "model.reminder.parse_time.app_error", nil, "text="+text)
+		} else {
+			count = c
+		}
+
+		switch strings.TrimSuffix(strings.ToLower(words[2]), "s") {
+		case "minute", "min":
+			remindAt = now.Add(time.Duration(count) * time.Minute)
+		case "hour", "hr":
+			remindAt = now.Add(time.Duration(count) * time.Hour)
+		case "day":
+			remindAt = now.AddDate(0, 0, count)
+		case "week":
+			remindAt = now.AddDate(0, 0, 7*count)
+		default:
+			return time.Time{}, "", NewLocAppError("ParseReminderTime", "model.reminder.parse_time.app_error", nil, "text="+text)
+		}
+
+		n = 3
+	} else if day, ok := parseReminderDay(first, now); ok {
+		n++
+
+		timeOfDay, consumed, ok := parseReminderTimeOfDay(words[n:])
+		if !ok {
+			if first == "today" {
+				return time.Time{}, "", NewLocAppError("ParseReminderTime", "model.reminder.parse_time.app_error", nil, "text="+text)
+			}
+
+			timeOfDay = REMINDER_DEFAULT_TIME
+		}
+
+		remindAt = time.Date(day.Year(), day.Month(), day.Day(), timeOfDay/60, timeOfDay%60, 0, 0, now.Location())
+		n += consumed
+	} else if n > 0 {
+		return time.Time{}, "", NewLocAppError("ParseReminderTime", "model.reminder.parse_time.app_error", nil, "text="+text)
+	} else if timeOfDay, consumed, ok := parseReminderTimeOfDay(words); ok {
+		remindAt = time.Date(now.Year(), now.Month(), now.Day(), timeOfDay/60, timeOfDay%60, 0, 0, now.Location())
+		if !remindAt.After(now) {
+			remindAt = remindAt.AddDate(0, 0, 1)
+		}
+
+		n = consumed
+	} else {
+		return time.Time{}, "", NewLocAppError("ParseReminderTime", "model.reminder.parse_time.app_error", nil, "text="+text)
+	}
+
+	if !remindAt.After(now) {
+		return time.Time{}, "", NewLocAppError("ParseReminderTime", "model.reminder.parse_time.past.app_error", nil, "text="+text)
+	}
+
+	return remindAt, strings.Join(words[n:], " "), nil
+}
+
+// parseReminderDay returns the day named by a word, being today, tomorrow, the next occurrence of a weekday
+// or a date in the YYYY-MM-DD format
+func parseReminderDay(word string, now time.Time) (time.Time, bool) {
+	switch word {
+	case "today":
+		return now, true
+	case "tomorrow":
+		return now.AddDate(0, 0, 1), true
+	}
+
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if weekday := strings.ToLower(d.String()); word == weekday || word == weekday[:3] {
+			days := (int(d) - int(now.Weekday()) + 7) % 7
+			if days == 0 {
+				days = 7
+			}
+
+			return now.AddDate(0, 0, days), true
+		}
+	}
+
+	if t, err := time.ParseInLocation("2006-01-02", word, now.Location()); err == nil {
+		return t, true
+	}
+
+	return time.Time{}, false
+}
+
+// parseReminderTimeOfDay parses a time of day, optionally preceded by "at", from the start of the words. It
+// returns the minutes since midnight and how many words it used.
+func parseReminderTimeOfDay(words []string) (int, int, bool) {
+	n := 0
+	if len(words) > 0 && strings.ToLower(words[0]) == "at" {
+		n = 1
+	}
+
+	if len(words) <= n {
+		return 0, 0, false
+	}
+
+	value := strings.ToLower(words[n])
+	n++
+
+	// allow a space before am or pm
+	if len(words) > n {
+		if suffix := strings.ToLower(words[n]); suffix == "am" || suffix == "pm" {
+			value += suffix
+			n++
+		}
+	}
+
+	switch value {
+	case "noon":
+		return 12 * 60, n, true
+	case "midnight":
+		return 0, n, true
+	}
+
+	for _, layout := range []string{"15:04", "3pm", "3:04pm"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.Hour()*60 + t.Minute(), n, true
+		}
+	}
+
+	return 0, 0, false
+}
@@ -0,0 +1,144 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReminderJson(t *testing.T) {
+	reminder := Reminder{Id: NewId(), UserId: NewId(), TeamId: NewId(), Message: "call back", RemindAt: 1234}
+	rreminder := ReminderFromJson(strings.NewReader(reminder.ToJson()))
+
+	if reminder.Id != rreminder.Id || reminder.Message != rreminder.Message || reminder.RemindAt != rreminder.RemindAt {
+		t.Fatal("reminders do not match")
+	}
+
+	reminders := RemindersFromJson(strings.NewReader(RemindersToJson([]*Reminder{&reminder})))
+	if len(reminders) != 1 || reminders[0].Id != reminder.Id {
+		t.Fatal("reminders do not match")
+	}
+}
+
+func TestReminderIsValid(t *testing.T) {
+	reminder := Reminder{UserId: NewId(), TeamId: NewId(), Message: "call back", RemindAt: 1234}
+	if err := reminder.IsValid(); err == nil {
+		t.Fatal("should be invalid without an id")
+	}
+
+	reminder.PreSave()
+	if err := reminder.IsValid(); err != nil {
+		t.Fatal(err)
+	}
+
+	reminder.Message = ""
+	if err := reminder.IsValid(); err == nil {
+		t.Fatal("should be invalid without a message or a post")
+	}
+
+	reminder.PostId = NewId()
+	if err := reminder.IsValid(); err != nil {
+		t.Fatal("should be valid with just a post", err)
+	}
+
+	reminder.PostId = "junk"
+	if err := reminder.IsValid(); err == nil {
+		t.Fatal("should be invalid with a bad post id")
+	}
+
+	reminder.PostId = ""
+	reminder.Message = strings.Repeat("a", REMINDER_MESSAGE_MAX_RUNES+1)
+	if err := reminder.IsValid(); err == nil {
+		t.Fatal("should be invalid with a long message")
+	}
+
+	reminder.Message = "call back"
+	reminder.RemindAt = 0
+	if err := reminder.IsValid(); err == nil {
+		t.Fatal("should be invalid without a time")
+	}
+
+	reminder.FiredAt = 5678
+	reminder.Snooze(9012)
+	if reminder.RemindAt != 9012 || reminder.FiredAt != 0 {
+		t.Fatal("should have snoozed the reminder", reminder)
+	}
+}
+
+func TestLocationFromNotifyProps(t *testing.T) {
+	if location := LocationFromNotifyProps(StringMap{}); location != time.Local {
+		t.Fatal("should have used the server's time zone", location)
+	}
+
+	if location := LocationFromNotifyProps(StringMap{TIMEZONE_NOTIFY_PROP: "Asia/Tokyo", DIGEST_TIMEZONE_NOTIFY_PROP: "Europe/Paris"}); location.String() != "Asia/Tokyo" {
+		t.Fatal("should have used the user's time zone", location)
+	}
+
+	if location := LocationFromNotifyProps(StringMap{TIMEZONE_NOTIFY_PROP: "Mars/Olympus_Mons", DIGEST_TIMEZONE_NOTIFY_PROP: "Europe/Paris"}); location.String() != "Europe/Paris" {
+		t.Fatal("should have fallen back to the digest time zone", location)
+	}
+
+	if location := LocationFromNotifyProps(StringMap{"dnd_timezone": "America/Toronto"}); location.String() != "America/Toronto" {
+		t.Fatal("should have fallen back to the do not disturb time zone", location)
+	}
+}
+
+func TestParseReminderTime(t *testing.T) {
+	location, err := time.LoadLocation("America/Toronto")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a Wednesday afternoon
+	now := time.Date(2016, 11, 30, 14, 15, 0, 0, location)
+
+	for _, test := range []struct {
+		Text     string
+		RemindAt time.Time
+		Message  string
+	}{
+		{"in 2 hours call back", now.Add(2 * time.Hour), "call back"},
+		{"in 1 minute", now.Add(time.Minute), ""},
+		{"in 90 mins stretch", now.Add(90 * time.Minute), "stretch"},
+		{"in a day", now.AddDate(0, 0, 1), ""},
+		{"In 2 Weeks renew", now.AddDate(0, 0, 14), "renew"},
+		{"tomorrow", time.Date(2016, 12, 1, 9, 0, 0, 0, location), ""},
+		{"tomorrow 10am standup", time.Date(2016, 12, 1, 10, 0, 0, 0, location), "standup"},
+		{"tomorrow at 14:30 review", time.Date(2016, 12, 1, 14, 30, 0, 0, location), "review"},
+		{"today 5 pm leave", time.Date(2016, 11, 30, 17, 0, 0, 0, location), "leave"},
+		{"at 3:30pm coffee", time.Date(2016, 11, 30, 15, 30, 0, 0, location), "coffee"},
+		{"9am", time.Date(2016, 12, 1, 9, 0, 0, 0, location), ""},
+		{"noon lunch", time.Date(2016, 12, 1, 12, 0, 0, 0, location), "lunch"},
+		{"friday demo", time.Date(2016, 12, 2, 9, 0, 0, 0, location), "demo"},
+		{"on wed at 4pm", time.Date(2016, 12, 7, 16, 0, 0, 0, location), ""},
+		{"on 2016-12-24 18:00 party", time.Date(2016, 12, 24, 18, 0, 0, 0, location), "party"},
+		{"2017-01-01", time.Date(2017, 1, 1, 9, 0, 0, 0, location), ""},
+	} {
+		if remindAt, message, err := ParseReminderTime(test.Text, now); err != nil {
+			t.Fatal(test.Text, err)
+		} else if !remindAt.Equal(test.RemindAt) || message != test.Message {
+			t.Fatal(test.Text, remindAt, message)
+		}
+	}
+
+	for _, text := range []string{
+		"",
+		"call back",
+		"in 2",
+		"in two hours",
+		"in -1 hours",
+		"in 2 fortnights",
+		"today",
+		"today 9am",
+		"on call back",
+		"2016-11-01 party",
+		"at",
+	} {
+		if _, _, err := ParseReminderTime(text, now); err == nil {
+			t.Fatal("should have failed to parse", text)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"encoding/json"
+	"io"
+)
+
+type Role struct {
+	Id          string      `json:"id"`
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Permissions StringArray `json:"permissions"`
+	CreateAt    int64       `json:"create_at"`
+	UpdateAt    int64       `json:"update_at"`
+}
+
+func (o *Role) ToJson() string {
+	if b, err := json.Marshal(o); err != nil {
+		return ""
+	} else {
+		return string(b)
+	}
+}
+
+func RoleFromJson(data io.Reader) *Role {
+	var o Role
+
+	if err := json.NewDecoder(data).Decode(&o); err != nil {
+		return nil
+	} else {
+		return &o
+	}
+}
+
+func RoleListToJson(o []*Role) string {
+	if b, err := json.Marshal(o); err != nil {
+		return ""
+	} else {
+		return string(b)
+	}
+}
+
+func RoleListFromJson(data io.Reader) []*Role {
+	var o []*Role
+
+	if err := json.NewDecoder(data).Decode(&o); err != nil {
+		return nil
+	} else {
+		return o
+	}
+}
+
+func (o *Role) PreSave() {
+	if o.CreateAt == 0 {
+		o.CreateAt = GetMillis()
+	}
+
+	o.UpdateAt = GetMillis()
+}
+
+func (o *Role) IsValid() *AppError {
+	if _, ok := BuiltInRoles[o.Id]; !ok {
+		return NewLocAppError("Role.IsValid", "model.role.is_valid.id.app_error", nil, "id="+o.Id)
+	}
+
+	if !IsValidPermissions(o.Permissions) {
+		return NewLocAppError("Role.IsValid", "model.role.is_valid.permissions.app_error", nil, "id="+o.Id)
+	}
+
+	if o.CreateAt == 0 {
+		return NewLocAppError("Role.IsValid", "model.role.is_valid.create_at.app_error", nil, "id="+o.Id)
+	}
+
+	if o.UpdateAt == 0 {
+		return NewLocAppError("Role.IsValid", "model.role.is_valid.update_at.app_error", nil, "id="+o.Id)
+	}
+
+	return nil
+}
+
+// HasPermission returns true if the role grants the permission.
+func (o *Role) HasPermission(permissionId string) bool {
+	for _, permission := range o.Permissions {
+		if permission == permissionId {
+			return true
+		}
+	}
+
+	return false
+}
+
+func IsValidPermissions(permissions []string) bool {
+	for _, permission := range permissions {
+		if _, ok := AllPermissions[permission]; !ok {
+			return false
+		}
+	}
+
+	return true
+}
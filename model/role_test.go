@@ -0,0 +1,52 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRoleJson(t *testing.T) {
+	role := &Role{Id: ROLE_TEAM_USER.Id, Permissions: []string{PERMISSION_CREATE_POST.Id}}
+	json := role.ToJson()
+	rrole := RoleFromJson(strings.NewReader(json))
+
+	if rrole.Id != role.Id || len(rrole.Permissions) != 1 || rrole.Permissions[0] != PERMISSION_CREATE_POST.Id {
+		t.Fatal("ids do not match")
+	}
+
+	roles := RoleListFromJson(strings.NewReader(RoleListToJson([]*Role{role})))
+	if len(roles) != 1 || roles[0].Id != role.Id {
+		t.Fatal("role list should have round tripped")
+	}
+}
+
+func TestRoleIsValid(t *testing.T) {
+	role := &Role{Id: "junk"}
+	role.PreSave()
+
+	if err := role.IsValid(); err == nil {
+		t.Fatal("should be invalid, not a built in role")
+	}
+
+	role.Id = ROLE_TEAM_USER.Id
+	role.Permissions = []string{"junk"}
+	if err := role.IsValid(); err == nil {
+		t.Fatal("should be invalid, unknown permission")
+	}
+
+	role.Permissions = []string{PERMISSION_CREATE_POST.Id, PERMISSION_READ_CHANNEL.Id}
+	if err := role.IsValid(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !role.HasPermission(PERMISSION_READ_CHANNEL.Id) {
+		t.Fatal("role should have the permission")
+	}
+
+	if role.HasPermission(PERMISSION_MANAGE_SYSTEM.Id) {
+		t.Fatal("role should not have the permission")
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+// A Scheme overrides the permissions of the team and channel roles for a single team. Roles is
+// keyed by role id and holds the space separated permissions the role has within the team.
+type Scheme struct {
+	TeamId      string    `json:"team_id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Roles       StringMap `json:"roles"`
+	CreateAt    int64     `json:"create_at"`
+	UpdateAt    int64     `json:"update_at"`
+}
+
+func (o *Scheme) ToJson() string {
+	if b, err := json.Marshal(o); err != nil {
+		return ""
+	} else {
+		return string(b)
+	}
+}
+
+func SchemeFromJson(data io.Reader) *Scheme {
+	var o Scheme
+
+	if err := json.NewDecoder(data).Decode(&o); err != nil {
+		return nil
+	} else {
+		return &o
+	}
+}
+
+func (o *Scheme) PreSave() {
+	if o.CreateAt == 0 {
+		o.CreateAt = GetMillis()
+	}
+
+	if o.Roles == nil {
+		o.Roles = make(map[string]string)
+	}
+
+	o.UpdateAt = GetMillis()
+}
+
+func (o *Scheme) IsValid() *AppError {
+	if len(o.TeamId) != 26 {
+		return NewLocAppError("Scheme.IsValid", "model.scheme.is_valid.team_id.app_error", nil, "")
+	}
+
+	if len(o.Name) > 64 {
+		return NewLocAppError("Scheme.IsValid", "model.scheme.is_valid.name.app_error", nil, "team_id="+o.TeamId)
+	}
+
+	if len(o.Description) > 1024 {
+		return NewLocAppError("Scheme.IsValid", "model.scheme.is_valid.description.app_error", nil, "team_id="+o.TeamId)
+	}
+
+	for roleId, permissions := range o.Roles {
+		if !IsSchemeManagedRole(roleId) {
+			return NewLocAppError("Scheme.IsValid", "model.scheme.is_valid.role.app_error", nil, "team_id="+o.TeamId+" role="+roleId)
+		}
+
+		if !IsValidPermissions(strings.Fields(permissions)) {
+			return NewLocAppError("Scheme.IsValid", "model.scheme.is_valid.permissions.app_error", nil, "team_id="+o.TeamId+" role="+roleId)
+		}
+	}
+
+	if o.CreateAt == 0 {
+		return NewLocAppError("Scheme.IsValid", "model.scheme.is_valid.create_at.app_error", nil, "team_id="+o.TeamId)
+	}
+
+	if o.UpdateAt == 0 {
+		return NewLocAppError("Scheme.IsValid", "model.scheme.is_valid.update_at.app_error", nil, "team_id="+o.TeamId)
+	}
+
+	return nil
+}
+
+// GetRolePermissions returns the permissions the scheme gives to a role and whether the
+// scheme overrides that role at all.
+func (o *Scheme) GetRolePermissions(roleId string) ([]string, bool) {
+	if permissions, ok := o.Roles[roleId]; ok {
+		return strings.Fields(permissions), true
+	}
+
+	return nil, false
+}
+
+// IsSchemeManagedRole returns true for the roles that a team scheme is allowed to override.
+func IsSchemeManagedRole(roleId string) bool {
+	switch roleId {
+	case ROLE_TEAM_USER.Id, ROLE_TEAM_ADMIN.Id, ROLE_CHANNEL_USER.Id, ROLE_CHANNEL_ADMIN.Id, ROLE_TEAM_GUEST.Id, ROLE_CHANNEL_GUEST.Id:
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,59 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSchemeJson(t *testing.T) {
+	scheme := &Scheme{TeamId: NewId(), Name: "name", Roles: map[string]string{ROLE_TEAM_USER.Id: PERMISSION_CREATE_POST.Id}}
+	json := scheme.ToJson()
+	rscheme := SchemeFromJson(strings.NewReader(json))
+
+	if rscheme.TeamId != scheme.TeamId || rscheme.Roles[ROLE_TEAM_USER.Id] != PERMISSION_CREATE_POST.Id {
+		t.Fatal("schemes do not match")
+	}
+}
+
+func TestSchemeIsValid(t *testing.T) {
+	scheme := &Scheme{}
+	scheme.PreSave()
+
+	if err := scheme.IsValid(); err == nil {
+		t.Fatal("should be invalid, missing team id")
+	}
+
+	scheme.TeamId = NewId()
+	scheme.Name = strings.Repeat("a", 65)
+	if err := scheme.IsValid(); err == nil {
+		t.Fatal("should be invalid, name too long")
+	}
+
+	scheme.Name = "name"
+	scheme.Roles[ROLE_SYSTEM_ADMIN.Id] = PERMISSION_CREATE_POST.Id
+	if err := scheme.IsValid(); err == nil {
+		t.Fatal("should be invalid, system roles can't be overridden")
+	}
+
+	delete(scheme.Roles, ROLE_SYSTEM_ADMIN.Id)
+	scheme.Roles[ROLE_TEAM_USER.Id] = "junk"
+	if err := scheme.IsValid(); err == nil {
+		t.Fatal("should be invalid, unknown permission")
+	}
+
+	scheme.Roles[ROLE_TEAM_USER.Id] = PERMISSION_CREATE_POST.Id + " " + PERMISSION_READ_CHANNEL.Id
+	if err := scheme.IsValid(); err != nil {
+		t.Fatal(err)
+	}
+
+	if permissions, ok := scheme.GetRolePermissions(ROLE_TEAM_USER.Id); !ok || len(permissions) != 2 {
+		t.Fatal("should have returned the role permissions")
+	}
+
+	if _, ok := scheme.GetRolePermissions(ROLE_TEAM_ADMIN.Id); ok {
+		t.Fatal("team admin role is not overridden")
+	}
+}
@@ -0,0 +1,595 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	SCIM_SCHEMA_USER                     = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SCIM_SCHEMA_GROUP                    = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	SCIM_SCHEMA_LIST_RESPONSE            = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SCIM_SCHEMA_PATCH_OP                 = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SCIM_SCHEMA_ERROR                    = "urn:ietf:params:scim:api:messages:2.0:Error"
+	SCIM_SCHEMA_SERVICE_PROVIDER_CONFIG  = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	SCIM_CONTENT_TYPE                    = "application/scim+json"
+	SCIM_URL_SUFFIX                      = "/scim/v2"
+	SCIM_PROP_EXTERNAL_ID                = "scim_external_id"
+	SCIM_GROUP_CHANNEL_SEPARATOR         = "/"
+	SCIM_DEFAULT_COUNT                   = 100
+	SCIM_MAX_COUNT                       = 200
+	SCIM_TOKEN_MINIMUM_LENGTH            = 32
+	SCIM_PATCH_OP_ADD                    = "add"
+	SCIM_PATCH_OP_REMOVE                 = "remove"
+	SCIM_PATCH_OP_REPLACE                = "replace"
+	SCIM_FILTER_OPERATOR_EQUAL           = "eq"
+	SCIM_FILTER_OPERATOR_NOT_EQUAL       = "ne"
+	SCIM_FILTER_OPERATOR_CONTAINS        = "co"
+	SCIM_FILTER_OPERATOR_STARTS_WITH     = "sw"
+	SCIM_FILTER_OPERATOR_ENDS_WITH       = "ew"
+	SCIM_FILTER_OPERATOR_PRESENT         = "pr"
+	SCIM_RESOURCE_TYPE_USER              = "User"
+	SCIM_RESOURCE_TYPE_GROUP             = "Group"
+	SCIM_ERROR_TYPE_INVALID_FILTER       = "invalidFilter"
+	SCIM_ERROR_TYPE_INVALID_VALUE        = "invalidValue"
+	SCIM_ERROR_TYPE_UNIQUENESS           = "uniqueness"
+	SCIM_ERROR_TYPE_MUTABILITY           = "mutability"
+	SCIM_USER_SCHEMA_PREFIX              = SCIM_SCHEMA_USER + ":"
+	SCIM_MEMBERS_VALUE_FILTER_PREFIX     = "members["
+	SCIM_EMAILS_VALUE_FILTER_PREFIX      = "emails["
+	SCIM_MULTI_VALUE_FILTER_VALUE_SUFFIX = "].value"
+)
+
+type ScimMeta struct {
+	ResourceType string `json:"resourceType"`
+	Created      string `json:"created,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+	Location     string `json:"location,omitempty"`
+}
+
+type ScimName struct {
+	Formatted  string `json:"formatted,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+type ScimMultiValue struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+	Ref     string `json:"$ref,omitempty"`
+}
+
+type ScimUser struct {
+	Schemas     []string         `json:"schemas"`
+	Id          string           `json:"id,omitempty"`
+	ExternalId  string           `json:"externalId,omitempty"`
+	UserName    string           `json:"userName"`
+	Name        *ScimName        `json:"name,omitempty"`
+	DisplayName string           `json:"displayName,omitempty"`
+	NickName    string           `json:"nickName,omitempty"`
+	Title       string           `json:"title,omitempty"`
+	Locale      string           `json:"locale,omitempty"`
+	Emails      []ScimMultiValue `json:"emails,omitempty"`
+	Active      *bool            `json:"active,omitempty"`
+	Password    string           `json:"password,omitempty"`
+	Meta        *ScimMeta        `json:"meta,omitempty"`
+}
+
+type ScimGroup struct {
+	Schemas     []string         `json:"schemas"`
+	Id          string           `json:"id,omitempty"`
+	DisplayName string           `json:"displayName"`
+	Members     []ScimMultiValue `json:"members,omitempty"`
+	Meta        *ScimMeta        `json:"meta,omitempty"`
+}
+
+type ScimListResponse struct {
+	Schemas      []string      `json:"schemas"`
+	TotalResults int           `json:"totalResults"`
+	StartIndex   int           `json:"startIndex"`
+	ItemsPerPage int           `json:"itemsPerPage"`
+	Resources    []interface{} `json:"Resources"`
+}
+
+type ScimError struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+}
+
+type ScimPatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+type ScimPatchRequest struct {
+	Schemas    []string              `json:"schemas"`
+	Operations []*ScimPatchOperation `json:"Operations"`
+}
+
+// ScimFilter is a single attribute comparison of a SCIM filter such as userName eq "jane"
+type ScimFilter struct {
+	Attribute string
+	Operator  string
+	Value     string
+}
+
+func scimTime(millis int64) string {
+	return time.Unix(0, millis*int64(time.Millisecond)).UTC().Format(time.RFC3339)
+}
+
+// NewScimUser converts a user to its SCIM representation
+func NewScimUser(user *User, siteURL string) *ScimUser {
+	active := user.DeleteAt == 0
+
+	su := &ScimUser{
+		Schemas:     []string{SCIM_SCHEMA_USER},
+		Id:          user.Id,
+		ExternalId:  user.Props[SCIM_PROP_EXTERNAL_ID],
+		UserName:    user.Username,
+		DisplayName: user.GetFullName(),
+		NickName:    user.Nickname,
+		Title:       user.Position,
+		Locale:      user.Locale,
+		Active:      &active,
+		Meta: &ScimMeta{
+			ResourceType: SCIM_RESOURCE_TYPE_USER,
+			Created:      scimTime(user.CreateAt),
+			LastModified: scimTime(user.UpdateAt),
+			Location:     siteURL + SCIM_URL_SUFFIX + "/Users/" + user.Id,
+		},
+	}
+
+	if len(user.FirstName) > 0 || len(user.LastName) > 0 {
+		su.Name = &ScimName{Formatted: user.GetFullName(), GivenName: user.FirstName, FamilyName: user.LastName}
+	}
+
+	if len(user.Email) > 0 {
+		su.Emails = []ScimMultiValue{{Value: user.Email, Type: "work", Primary: true}}
+	}
+
+	return su
+}
+
+// NewScimGroup returns a SCIM group without members
+func NewScimGroup(id string, displayName string, createAt int64, updateAt int64, siteURL string) *ScimGroup {
+	return &ScimGroup{
+		Schemas:     []string{SCIM_SCHEMA_GROUP},
+		Id:          id,
+		DisplayName: displayName,
+		Meta: &ScimMeta{
+			ResourceType: SCIM_RESOURCE_TYPE_GROUP,
+			Created:      scimTime(createAt),
+			LastModified: scimTime(updateAt),
+			Location:     siteURL + SCIM_URL_SUFFIX + "/Groups/" + id,
+		},
+	}
+}
+
+// PrimaryEmail returns the email marked as primary or the first one if none are
+func (su *ScimUser) PrimaryEmail() string {
+	for _, email := range su.Emails {
+		if email.Primary {
+			return email.Value
+		}
+	}
+
+	if len(su.Emails) > 0 {
+		return su.Emails[0].Value
+	}
+
+	return ""
+}
+
+// IsActive returns whether the user should be active, users are active unless told otherwise
+func (su *ScimUser) IsActive() bool {
+	return su.Active == nil || *su.Active
+}
+
+// ApplyTo copies the provisioned attributes onto the user
+func (su *ScimUser) ApplyTo(user *User) {
+	user.Username = ScimUsername(su.UserName)
+	user.Email = strings.ToLower(su.PrimaryEmail())
+	user.Nickname = su.NickName
+	user.Position = su.Title
+
+	if su.Name != nil {
+		user.FirstName = su.Name.GivenName
+		user.LastName = su.Name.FamilyName
+	} else {
+		user.FirstName = ""
+		user.LastName = ""
+	}
+
+	if user.Props == nil {
+		user.Props = StringMap{}
+	}
+
+	if len(su.ExternalId) > 0 {
+		user.Props[SCIM_PROP_EXTERNAL_ID] = su.ExternalId
+	} else {
+		delete(user.Props, SCIM_PROP_EXTERNAL_ID)
+	}
+}
+
+// ScimUsername converts a SCIM userName, which identity providers commonly set to an email address,
+// to a valid username
+func ScimUsername(userName string) string {
+	userName = strings.ToLower(userName)
+
+	if IsValidUsername(userName) {
+		return userName
+	}
+
+	if i := strings.Index(userName, "@"); i > 0 {
+		userName = userName[:i]
+	}
+
+	return CleanUsername(userName)
+}
+
+// ApplyPatch applies the PATCH operations to the user. Attributes that aren't stored are ignored so that
+// identity providers sending their full schema don't fail.
+func (su *ScimUser) ApplyPatch(operations []*ScimPatchOperation) error {
+	for _, operation := range operations {
+		op := strings.ToLower(operation.Op)
+		if op != SCIM_PATCH_OP_ADD && op != SCIM_PATCH_OP_REPLACE && op != SCIM_PATCH_OP_REMOVE {
+			return errors.New("unsupported operation " + operation.Op)
+		}
+
+		if len(operation.Path) == 0 {
+			values, ok := operation.Value.(map[string]interface{})
+			if !ok || op == SCIM_PATCH_OP_REMOVE {
+				return errors.New("a path is required")
+			}
+
+			for path, value := range values {
+				if err := su.setAttribute(path, value); err != nil {
+					return err
+				}
+			}
+		} else if op == SCIM_PATCH_OP_REMOVE {
+			if err := su.setAttribute(operation.Path, nil); err != nil {
+				return err
+			}
+		} else if err := su.setAttribute(operation.Path, operation.Value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (su *ScimUser) setAttribute(path string, value interface{}) error {
+	path = strings.ToLower(path)
+	path = strings.TrimPrefix(path, strings.ToLower(SCIM_USER_SCHEMA_PREFIX))
+
+	str, _ := value.(string)
+
+	switch {
+	case path == "username":
+		su.UserName = str
+	case path == "externalid":
+		su.ExternalId = str
+	case path == "nickname":
+		su.NickName = str
+	case path == "title":
+		su.Title = str
+	case path == "password":
+		su.Password = str
+	case path == "active":
+		active, err := scimBool(value)
+		if err != nil {
+			return err
+		}
+		su.Active = &active
+	case path == "name":
+		su.Name = nil
+		if values, ok := value.(map[string]interface{}); ok {
+			for name, v := range values {
+				if err := su.setAttribute("name."+name, v); err != nil {
+					return err
+				}
+			}
+		}
+	case strings.HasPrefix(path, "name."):
+		if su.Name == nil {
+			su.Name = &ScimName{}
+		}
+
+		switch strings.TrimPrefix(path, "name.") {
+		case "givenname":
+			su.Name.GivenName = str
+		case "familyname":
+			su.Name.FamilyName = str
+		case "formatted":
+			su.Name.Formatted = str
+		}
+	case path == "emails":
+		su.Emails = nil
+		if values, ok := value.([]interface{}); ok {
+			for _, v := range values {
+				if email, ok := v.(map[string]interface{}); ok {
+					address, _ := email["value"].(string)
+					primary, _ := scimBool(email["primary"])
+					su.Emails = append(su.Emails, ScimMultiValue{Value: address, Primary: primary})
+				}
+			}
+		}
+	case strings.HasPrefix(path, SCIM_EMAILS_VALUE_FILTER_PREFIX) && strings.HasSuffix(path, SCIM_MULTI_VALUE_FILTER_VALUE_SUFFIX):
+		// we only store a single email so any email filter refers to it
+		su.Emails = []ScimMultiValue{{Value: str, Primary: true}}
+	}
+
+	return nil
+}
+
+func scimBool(value interface{}) (bool, error) {
+	switch v := value.(type) {
+	case nil:
+		return false, nil
+	case bool:
+		return v, nil
+	case string:
+		// some identity providers send booleans as strings such as "False"
+		return strconv.ParseBool(strings.ToLower(v))
+	}
+
+	return false, errors.New("invalid boolean")
+}
+
+// ParseScimFilter parses a SCIM filter made of comparisons joined by "and"
+func ParseScimFilter(filter string) ([]*ScimFilter, error) {
+	var filters []*ScimFilter
+
+	tokens, err := scimFilterTokens(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	for len(tokens) > 0 {
+		if len(tokens) < 2 {
+			return nil, errors.New("incomplete filter")
+		}
+
+		f := &ScimFilter{Attribute: strings.ToLower(tokens[0]), Operator: strings.ToLower(tokens[1])}
+		tokens = tokens[2:]
+
+		switch f.Operator {
+		case SCIM_FILTER_OPERATOR_PRESENT:
+		case SCIM_FILTER_OPERATOR_EQUAL, SCIM_FILTER_OPERATOR_NOT_EQUAL, SCIM_FILTER_OPERATOR_CONTAINS, SCIM_FILTER_OPERATOR_STARTS_WITH, SCIM_FILTER_OPERATOR_ENDS_WITH:
+			if len(tokens) == 0 {
+				return nil, errors.New("missing value for " + f.Attribute)
+			}
+			f.Value = tokens[0]
+			tokens = tokens[1:]
+		default:
+			return nil, errors.New("unsupported operator " + f.Operator)
+		}
+
+		filters = append(filters, f)
+
+		if len(tokens) > 0 {
+			if strings.ToLower(tokens[0]) != "and" || len(tokens) == 1 {
+				return nil, errors.New("only and is supported between comparisons")
+			}
+			tokens = tokens[1:]
+		}
+	}
+
+	if len(filters) == 0 {
+		return nil, errors.New("empty filter")
+	}
+
+	return filters, nil
+}
+
+func scimFilterTokens(filter string) ([]string, error) {
+	var tokens []string
+
+	for i := 0; i < len(filter); {
+		switch {
+		case filter[i] == ' ':
+			i++
+		case filter[i] == '"':
+			var value []byte
+			i++
+			for ; i < len(filter) && filter[i] != '"'; i++ {
+				if filter[i] == '\\' && i+1 < len(filter) {
+					i++
+				}
+				value = append(value, filter[i])
+			}
+
+			if i >= len(filter) {
+				return nil, errors.New("unterminated string")
+			}
+
+			tokens = append(tokens, string(value))
+			i++
+		default:
+			start := i
+			for ; i < len(filter) && filter[i] != ' '; i++ {
+			}
+			tokens = append(tokens, filter[start:i])
+		}
+	}
+
+	return tokens, nil
+}
+
+// Matches compares the value of the filtered attribute, SCIM string comparisons aren't case sensitive
+func (f *ScimFilter) Matches(value string) bool {
+	value = strings.ToLower(value)
+	expected := strings.ToLower(f.Value)
+
+	switch f.Operator {
+	case SCIM_FILTER_OPERATOR_EQUAL:
+		return value == expected
+	case SCIM_FILTER_OPERATOR_NOT_EQUAL:
+		return value != expected
+	case SCIM_FILTER_OPERATOR_CONTAINS:
+		return strings.Contains(value, expected)
+	case SCIM_FILTER_OPERATOR_STARTS_WITH:
+		return strings.HasPrefix(value, expected)
+	case SCIM_FILTER_OPERATOR_ENDS_WITH:
+		return strings.HasSuffix(value, expected)
+	case SCIM_FILTER_OPERATOR_PRESENT:
+		return len(value) > 0
+	}
+
+	return false
+}
+
+// ScimMemberIds returns the user ids of a list of members or the value of a member filter such as
+// members[value eq "id"]
+func ScimMemberIds(value interface{}) []string {
+	var ids []string
+
+	switch v := value.(type) {
+	case []interface{}:
+		for _, member := range v {
+			if m, ok := member.(map[string]interface{}); ok {
+				if id, ok := m["value"].(string); ok {
+					ids = append(ids, id)
+				}
+			}
+		}
+	case map[string]interface{}:
+		if id, ok := v["value"].(string); ok {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids
+}
+
+// ScimMemberFilterId returns the user id of a path such as members[value eq "id"]
+func ScimMemberFilterId(path string) string {
+	if !strings.HasPrefix(strings.ToLower(path), SCIM_MEMBERS_VALUE_FILTER_PREFIX) || !strings.HasSuffix(path, "]") {
+		return ""
+	}
+
+	filters, err := ParseScimFilter(path[len(SCIM_MEMBERS_VALUE_FILTER_PREFIX) : len(path)-1])
+	if err != nil || len(filters) != 1 || filters[0].Attribute != "value" || filters[0].Operator != SCIM_FILTER_OPERATOR_EQUAL {
+		return ""
+	}
+
+	return filters[0].Value
+}
+
+func NewScimError(status int, scimType string, detail string) *ScimError {
+	return &ScimError{
+		Schemas:  []string{SCIM_SCHEMA_ERROR},
+		Status:   strconv.Itoa(status),
+		ScimType: scimType,
+		Detail:   detail,
+	}
+}
+
+func NewScimListResponse(totalResults int, startIndex int, resources []interface{}) *ScimListResponse {
+	if resources == nil {
+		resources = []interface{}{}
+	}
+
+	return &ScimListResponse{
+		Schemas:      []string{SCIM_SCHEMA_LIST_RESPONSE},
+		TotalResults: totalResults,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	}
+}
+
+func (su *ScimUser) ToJson() string {
+	b, err := json.Marshal(su)
+	if err != nil {
+		return ""
+	} else {
+		return string(b)
+	}
+}
+
+func ScimUserFromJson(data io.Reader) *ScimUser {
+	decoder := json.NewDecoder(data)
+	var su ScimUser
+	err := decoder.Decode(&su)
+	if err == nil {
+		return &su
+	} else {
+		return nil
+	}
+}
+
+func (sg *ScimGroup) ToJson() string {
+	b, err := json.Marshal(sg)
+	if err != nil {
+		return ""
+	} else {
+		return string(b)
+	}
+}
+
+func ScimGroupFromJson(data io.Reader) *ScimGroup {
+	decoder := json.NewDecoder(data)
+	var sg ScimGroup
+	err := decoder.Decode(&sg)
+	if err == nil {
+		return &sg
+	} else {
+		return nil
+	}
+}
+
+func (r *ScimPatchRequest) ToJson() string {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return ""
+	} else {
+		return string(b)
+	}
+}
+
+func ScimPatchRequestFromJson(data io.Reader) *ScimPatchRequest {
+	decoder := json.NewDecoder(data)
+	var r ScimPatchRequest
+	err := decoder.Decode(&r)
+	if err == nil {
+		return &r
+	} else {
+		return nil
+	}
+}
+
+func ScimListResponseFromJson(data io.Reader) *ScimListResponse {
+	decoder := json.NewDecoder(data)
+	var r ScimListResponse
+	err := decoder.Decode(&r)
+	if err == nil {
+		return &r
+	} else {
+		return nil
+	}
+}
+
+func ScimErrorFromJson(data io.Reader) *ScimError {
+	decoder := json.NewDecoder(data)
+	var e ScimError
+	err := decoder.Decode(&e)
+	if err == nil {
+		return &e
+	} else {
+		return nil
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseScimFilter(t *testing.T) {
+	if filters, err := ParseScimFilter(`userName eq "Jane.Doe@example.com"`); err != nil {
+		t.Fatal(err)
+	} else if len(filters) != 1 || filters[0].Attribute != "username" || filters[0].Operator != SCIM_FILTER_OPERATOR_EQUAL || filters[0].Value != "Jane.Doe@example.com" {
+		t.Fatal("parsed the wrong filter", filters)
+	}
+
+	if filters, err := ParseScimFilter(`displayName sw "Team \"A\"" and id pr`); err != nil {
+		t.Fatal(err)
+	} else if len(filters) != 2 || filters[0].Value != `Team "A"` || filters[1].Attribute != "id" || filters[1].Operator != SCIM_FILTER_OPERATOR_PRESENT {
+		t.Fatal("parsed the wrong filters", filters)
+	}
+
+	for _, filter := range []string{"", "userName", `userName gt "a"`, `userName eq "a" or id pr`, `userName eq "a`, `userName eq "a" and`} {
+		if _, err := ParseScimFilter(filter); err == nil {
+			t.Fatal("should have failed to parse " + filter)
+		}
+	}
+}
+
+func TestScimFilterMatches(t *testing.T) {
+	tests := []struct {
+		filter   ScimFilter
+		value    string
+		expected bool
+	}{
+		{ScimFilter{Operator: SCIM_FILTER_OPERATOR_EQUAL, Value: "Jane"}, "jane", true},
+		{ScimFilter{Operator: SCIM_FILTER_OPERATOR_NOT_EQUAL, Value: "jane"}, "jane", false},
+		{ScimFilter{Operator: SCIM_FILTER_OPERATOR_CONTAINS, Value: "AN"}, "jane", true},
+		{ScimFilter{Operator: SCIM_FILTER_OPERATOR_STARTS_WITH, Value: "ja"}, "jane", true},
+		{ScimFilter{Operator: SCIM_FILTER_OPERATOR_ENDS_WITH, Value: "ja"}, "jane", false},
+		{ScimFilter{Operator: SCIM_FILTER_OPERATOR_PRESENT}, "", false},
+		{ScimFilter{Operator: SCIM_FILTER_OPERATOR_PRESENT}, "jane", true},
+	}
+
+	for _, test := range tests {
+		if test.filter.Matches(test.value) != test.expected {
+			t.Fatal("wrong match", test.filter, test.value)
+		}
+	}
+}
+
+func TestScimUsername(t *testing.T) {
+	if ScimUsername("Jane.Doe") != "jane.doe" {
+		t.Fatal("should have lowercased the username")
+	}
+
+	if ScimUsername("jane.doe@example.com") != "jane.doe" {
+		t.Fatal("should have used the local part of the email")
+	}
+
+	if !IsValidUsername(ScimUsername("Jane Doe!")) {
+		t.Fatal("should have cleaned the username")
+	}
+}
+
+func TestScimUserApplyTo(t *testing.T) {
+	active := false
+	su := &ScimUser{
+		UserName:   "Jane.Doe@example.com",
+		ExternalId: "00u1",
+		Name:       &ScimName{GivenName: "Jane", FamilyName: "Doe"},
+		Title:      "Engineer",
+		Emails:     []ScimMultiValue{{Value: "other@example.com"}, {Value: "Jane.Doe@Example.com", Primary: true}},
+		Active:     &active,
+	}
+
+	user := &User{}
+	su.ApplyTo(user)
+
+	if user.Username != "jane.doe" || user.Email != "jane.doe@example.com" || user.FirstName != "Jane" || user.LastName != "Doe" || user.Position != "Engineer" {
+		t.Fatal("didn't copy the attributes", user)
+	}
+
+	if user.Props[SCIM_PROP_EXTERNAL_ID] != "00u1" {
+		t.Fatal("didn't store the external id")
+	}
+
+	if su.IsActive() {
+		t.Fatal("should be inactive")
+	}
+
+	rsu := NewScimUser(user, "http://localhost")
+	if rsu.UserName != user.Username || rsu.PrimaryEmail() != user.Email || rsu.ExternalId != "00u1" || !rsu.IsActive() {
+		t.Fatal("didn't convert the user", rsu)
+	}
+}
+
+func TestScimUserApplyPatch(t *testing.T) {
+	su := &ScimUser{UserName: "jane", Emails: []ScimMultiValue{{Value: "jane@example.com", Primary: true}}}
+
+	operations := []*ScimPatchOperation{
+		{Op: SCIM_PATCH_OP_REPLACE, Path: "active", Value: "False"},
+		{Op: SCIM_PATCH_OP_REPLACE, Path: "name.givenName", Value: "Jane"},
+		{Op: SCIM_PATCH_OP_REPLACE, Path: `emails[type eq "work"].value`, Value: "jane.doe@example.com"},
+		{Op: SCIM_PATCH_OP_REPLACE, Value: map[string]interface{}{"title": "Manager"}},
+		{Op: SCIM_PATCH_OP_ADD, Path: "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:department", Value: "Sales"},
+	}
+
+	if err := su.ApplyPatch(operations); err != nil {
+		t.Fatal(err)
+	}
+
+	if su.IsActive() || su.Name == nil || su.Name.GivenName != "Jane" || su.PrimaryEmail() != "jane.doe@example.com" || su.Title != "Manager" {
+		t.Fatal("didn't apply the patch", su.ToJson())
+	}
+
+	if err := su.ApplyPatch([]*ScimPatchOperation{{Op: "move", Path: "title"}}); err == nil {
+		t.Fatal("should have failed on an unknown operation")
+	}
+
+	if err := su.ApplyPatch([]*ScimPatchOperation{{Op: SCIM_PATCH_OP_REPLACE, Path: "active", Value: "maybe"}}); err == nil {
+		t.Fatal("should have failed on an invalid boolean")
+	}
+}
+
+func TestScimMembers(t *testing.T) {
+	ids := ScimMemberIds([]interface{}{map[string]interface{}{"value": "a"}, map[string]interface{}{"value": "b"}})
+	if len(ids) != 2 || ids[0] != "a" || ids[1] != "b" {
+		t.Fatal("wrong member ids", ids)
+	}
+
+	if id := ScimMemberFilterId(`members[value eq "abc"]`); id != "abc" {
+		t.Fatal("wrong member filter id", id)
+	}
+
+	if id := ScimMemberFilterId("members"); id != "" {
+		t.Fatal("shouldn't have found a member id", id)
+	}
+}
+
+func TestScimUserJson(t *testing.T) {
+	su := NewScimUser(&User{Id: NewId(), Username: "jane", Email: "jane@example.com"}, "")
+	json := su.ToJson()
+
+	if strings.Contains(json, "password") {
+		t.Fatal("shouldn't include a password")
+	}
+
+	if rsu := ScimUserFromJson(strings.NewReader(json)); rsu == nil || rsu.Id != su.Id {
+		t.Fatal("didn't decode the user")
+	}
+}
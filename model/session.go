@@ -10,11 +10,17 @@ import (
 )
 
 const (
-	SESSION_COOKIE_TOKEN  = "MMAUTHTOKEN"
-	SESSION_CACHE_SIZE    = 25000
-	SESSION_PROP_PLATFORM = "platform"
-	SESSION_PROP_OS       = "os"
-	SESSION_PROP_BROWSER  = "browser"
+	SESSION_COOKIE_TOKEN          = "MMAUTHTOKEN"
+	SESSION_CACHE_SIZE            = 25000
+	SESSION_PROP_PLATFORM         = "platform"
+	SESSION_PROP_OS               = "os"
+	SESSION_PROP_BROWSER          = "browser"
+	SESSION_PROP_USER_AGENT       = "user_agent"
+	SESSION_PROP_IP_ADDRESS       = "ip_address"
+	SESSION_PROP_IS_MOBILE        = "is_mobile"
+	SESSION_PROP_PASSWORD_EXPIRED = "password_expired"
+	SESSION_USER_AGENT_MAX_LENGTH = 256
+	SESSION_ACTIVITY_UPDATE_TIME  = 60000 // 1 minute
 )
 
 type Session struct {
@@ -25,6 +31,7 @@ type Session struct {
 	LastActivityAt int64         `json:"last_activity_at"`
 	UserId         string        `json:"user_id"`
 	DeviceId       string        `json:"device_id"`
+	DeviceKey      string        `json:"device_key"`
 	Roles          string        `json:"roles"`
 	IsOAuth        bool          `json:"is_oauth"`
 	Props          StringMap     `json:"props"`
@@ -83,6 +90,16 @@ func (me *Session) IsExpired() bool {
 	return false
 }
 
+// IsIdle returns true if the session hasn't been used for longer than the idle timeout. Mobile app and
+// OAuth sessions are never considered idle since they aren't tied to someone sitting at a browser.
+func (me *Session) IsIdle(idleTimeoutInMinutes int) bool {
+	if idleTimeoutInMinutes <= 0 || me.IsOAuth || me.IsMobileApp() {
+		return false
+	}
+
+	return GetMillis()-me.LastActivityAt > int64(idleTimeoutInMinutes)*60*1000
+}
+
 func (me *Session) SetExpireInDays(days int) {
 	if me.CreateAt == 0 {
 		me.ExpiresAt = GetMillis() + (1000 * 60 * 60 * 24 * int64(days))
@@ -119,6 +136,10 @@ func (me *Session) GetUserRoles() []string {
 	return strings.Fields(me.Roles)
 }
 
+func (me *Session) IsGuest() bool {
+	return IsInRole(me.Roles, ROLE_SYSTEM_GUEST.Id)
+}
+
 func SessionsToJson(o []*Session) string {
 	if b, err := json.Marshal(o); err != nil {
 		return "[]"
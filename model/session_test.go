@@ -33,3 +33,32 @@ func TestSessionJson(t *testing.T) {
 
 	session.SetExpireInDays(10)
 }
+
+func TestSessionIsIdle(t *testing.T) {
+	session := Session{}
+	session.PreSave()
+
+	if session.IsIdle(0) {
+		t.Fatal("shouldn't be idle without a timeout")
+	}
+
+	if session.IsIdle(10) {
+		t.Fatal("shouldn't be idle right after being created")
+	}
+
+	session.LastActivityAt = GetMillis() - 11*60*1000
+	if !session.IsIdle(10) {
+		t.Fatal("should be idle")
+	}
+
+	session.DeviceId = PUSH_NOTIFY_APPLE + ":1234567890"
+	if session.IsIdle(10) {
+		t.Fatal("mobile app sessions shouldn't go idle")
+	}
+
+	session.DeviceId = ""
+	session.IsOAuth = true
+	if session.IsIdle(10) {
+		t.Fatal("oauth sessions shouldn't go idle")
+	}
+}
@@ -6,23 +6,130 @@ package model
 import (
 	"encoding/json"
 	"io"
+	"regexp"
+	"strings"
+	"unicode/utf8"
 )
 
 const (
 	STATUS_OFFLINE         = "offline"
 	STATUS_AWAY            = "away"
 	STATUS_ONLINE          = "online"
+	STATUS_DND             = "dnd"
 	STATUS_CACHE_SIZE      = 25000
 	STATUS_CHANNEL_TIMEOUT = 20000  // 20 seconds
 	STATUS_MIN_UPDATE_TIME = 120000 // 2 minutes
+
+	CUSTOM_STATUS_TEXT_MAX_RUNES   = 100
+	CUSTOM_STATUS_EMOJI_MAX_LENGTH = 64
 )
 
+var validCustomStatusEmoji = regexp.MustCompile(`^[a-zA-Z0-9_+-]+$`)
+
 type Status struct {
 	UserId         string `json:"user_id"`
 	Status         string `json:"status"`
 	Manual         bool   `json:"manual"`
 	LastActivityAt int64  `json:"last_activity_at"`
 	ActiveChannel  string `json:"active_channel" db:"-"`
+	DNDEndTime     int64  `json:"dnd_end_time"`
+	PrevStatus     string `json:"prev_status"`
+
+	CustomEmoji     string `json:"custom_emoji,omitempty"`
+	CustomText      string `json:"custom_text,omitempty"`
+	CustomExpiresAt int64  `json:"custom_expires_at,omitempty"`
+}
+
+// CustomStatus is a short message and emoji that users show next to their availability, such as
+// "In a meeting", until they clear it or until ExpiresAt if it is set
+type CustomStatus struct {
+	Emoji     string `json:"emoji"`
+	Text      string `json:"text"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+func (o *CustomStatus) ToJson() string {
+	if b, err := json.Marshal(o); err != nil {
+		return ""
+	} else {
+		return string(b)
+	}
+}
+
+func CustomStatusFromJson(data io.Reader) *CustomStatus {
+	var o CustomStatus
+
+	if err := json.NewDecoder(data).Decode(&o); err != nil {
+		return nil
+	} else {
+		return &o
+	}
+}
+
+// StatusesWithCustomStatuses holds the statuses of some users along with the custom statuses of the ones that have
+// one, both keyed by user id
+type StatusesWithCustomStatuses struct {
+	Statuses       map[string]string        `json:"statuses"`
+	CustomStatuses map[string]*CustomStatus `json:"custom_statuses"`
+}
+
+func StatusesWithCustomStatusesFromJson(data io.Reader) *StatusesWithCustomStatuses {
+	var o StatusesWithCustomStatuses
+
+	if err := json.NewDecoder(data).Decode(&o); err != nil {
+		return nil
+	} else {
+		return &o
+	}
+}
+
+func (o *CustomStatus) PreSave() {
+	o.Emoji = strings.Trim(strings.TrimSpace(o.Emoji), ":")
+	o.Text = strings.TrimSpace(o.Text)
+}
+
+func (o *CustomStatus) IsValid(now int64) *AppError {
+	if len(o.Emoji) == 0 && len(o.Text) == 0 {
+		return NewLocAppError("CustomStatus.IsValid", "model.custom_status.is_valid.empty.app_error", nil, "")
+	}
+
+	if len(o.Emoji) > CUSTOM_STATUS_EMOJI_MAX_LENGTH || (len(o.Emoji) > 0 && !validCustomStatusEmoji.MatchString(o.Emoji)) {
+		return NewLocAppError("CustomStatus.IsValid", "model.custom_status.is_valid.emoji.app_error", nil, "")
+	}
+
+	if utf8.RuneCountInString(o.Text) > CUSTOM_STATUS_TEXT_MAX_RUNES {
+		return NewLocAppError("CustomStatus.IsValid", "model.custom_status.is_valid.text.app_error", nil, "")
+	}
+
+	if o.ExpiresAt != 0 && o.ExpiresAt <= now {
+		return NewLocAppError("CustomStatus.IsValid", "model.custom_status.is_valid.expires_at.app_error", nil, "")
+	}
+
+	return nil
+}
+
+// GetCustomStatus returns the user's custom status or nil if there isn't one or it has expired
+func (o *Status) GetCustomStatus(now int64) *CustomStatus {
+	if len(o.CustomEmoji) == 0 && len(o.CustomText) == 0 {
+		return nil
+	}
+
+	if o.CustomExpiresAt != 0 && o.CustomExpiresAt <= now {
+		return nil
+	}
+
+	return &CustomStatus{Emoji: o.CustomEmoji, Text: o.CustomText, ExpiresAt: o.CustomExpiresAt}
+}
+
+// SetCustomStatus replaces the user's custom status, clearing it if customStatus is nil
+func (o *Status) SetCustomStatus(customStatus *CustomStatus) {
+	if customStatus == nil {
+		customStatus = &CustomStatus{}
+	}
+
+	o.CustomEmoji = customStatus.Emoji
+	o.CustomText = customStatus.Text
+	o.CustomExpiresAt = customStatus.ExpiresAt
 }
 
 func (o *Status) ToJson() string {
@@ -34,6 +141,11 @@ func (o *Status) ToJson() string {
 	}
 }
 
+// IsDND returns true if the status is do not disturb and its end time, if any, hasn't passed yet
+func (o *Status) IsDND(now int64) bool {
+	return o.Status == STATUS_DND && (o.DNDEndTime == 0 || o.DNDEndTime > now)
+}
+
 func StatusFromJson(data io.Reader) *Status {
 	decoder := json.NewDecoder(data)
 	var o Status
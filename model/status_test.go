@@ -9,7 +9,7 @@ import (
 )
 
 func TestStatus(t *testing.T) {
-	status := Status{NewId(), STATUS_ONLINE, true, 0, ""}
+	status := Status{UserId: NewId(), Status: STATUS_ONLINE, Manual: true}
 	json := status.ToJson()
 	status2 := StatusFromJson(strings.NewReader(json))
 
@@ -29,3 +29,87 @@ func TestStatus(t *testing.T) {
 		t.Fatal("Manual should have matched")
 	}
 }
+
+func TestStatusIsDND(t *testing.T) {
+	now := GetMillis()
+
+	status := Status{UserId: NewId(), Status: STATUS_ONLINE}
+	if status.IsDND(now) {
+		t.Fatal("online status should not be dnd")
+	}
+
+	status.Status = STATUS_DND
+	if !status.IsDND(now) {
+		t.Fatal("dnd status without an end time should be dnd")
+	}
+
+	status.DNDEndTime = now + 1000
+	if !status.IsDND(now) {
+		t.Fatal("dnd status before its end time should be dnd")
+	}
+
+	status.DNDEndTime = now - 1000
+	if status.IsDND(now) {
+		t.Fatal("dnd status after its end time should not be dnd")
+	}
+}
+
+func TestCustomStatusIsValid(t *testing.T) {
+	now := GetMillis()
+
+	customStatus := CustomStatus{Emoji: " :calendar: ", Text: " In a meeting "}
+	customStatus.PreSave()
+
+	if customStatus.Emoji != "calendar" || customStatus.Text != "In a meeting" {
+		t.Fatal("should have trimmed the emoji and text")
+	}
+
+	if err := customStatus.IsValid(now); err != nil {
+		t.Fatal(err)
+	}
+
+	customStatus.ExpiresAt = now - 1000
+	if err := customStatus.IsValid(now); err == nil {
+		t.Fatal("should be invalid with an expiry in the past")
+	}
+
+	customStatus.ExpiresAt = 0
+	customStatus.Emoji = "not an emoji"
+	if err := customStatus.IsValid(now); err == nil {
+		t.Fatal("should be invalid with a bad emoji name")
+	}
+
+	customStatus.Emoji = ""
+	customStatus.Text = strings.Repeat("a", CUSTOM_STATUS_TEXT_MAX_RUNES+1)
+	if err := customStatus.IsValid(now); err == nil {
+		t.Fatal("should be invalid with long text")
+	}
+
+	customStatus.Text = ""
+	if err := customStatus.IsValid(now); err == nil {
+		t.Fatal("should be invalid when empty")
+	}
+}
+
+func TestStatusCustomStatus(t *testing.T) {
+	now := GetMillis()
+
+	status := Status{UserId: NewId(), Status: STATUS_ONLINE}
+	if status.GetCustomStatus(now) != nil {
+		t.Fatal("should have no custom status")
+	}
+
+	status.SetCustomStatus(&CustomStatus{Emoji: "palm_tree", Text: "On vacation", ExpiresAt: now + 1000})
+	if customStatus := status.GetCustomStatus(now); customStatus == nil || customStatus.Text != "On vacation" {
+		t.Fatal("should have the custom status")
+	}
+
+	if status.GetCustomStatus(now+1000) != nil {
+		t.Fatal("should have expired")
+	}
+
+	status.SetCustomStatus(nil)
+	if status.CustomEmoji != "" || status.CustomText != "" || status.CustomExpiresAt != 0 {
+		t.Fatal("should have cleared the custom status")
+	}
+}
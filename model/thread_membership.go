@@ -0,0 +1,96 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// ThreadMembership tracks a user's participation in the thread of a root post. Users follow the threads they
+// post in or are mentioned in and are notified of new replies to them until they unfollow or mute the thread.
+type ThreadMembership struct {
+	PostId        string `json:"post_id"`
+	UserId        string `json:"user_id"`
+	ChannelId     string `json:"channel_id"`
+	Following     bool   `json:"following"`
+	Muted         bool   `json:"muted"`
+	UnreadReplies int64  `json:"unread_replies"`
+	LastReplyAt   int64  `json:"last_reply_at"`
+	LastViewedAt  int64  `json:"last_viewed_at"`
+	CreateAt      int64  `json:"create_at"`
+}
+
+func (o *ThreadMembership) ToJson() string {
+	if b, err := json.Marshal(o); err != nil {
+		return ""
+	} else {
+		return string(b)
+	}
+}
+
+func ThreadMembershipFromJson(data io.Reader) *ThreadMembership {
+	var o ThreadMembership
+
+	if err := json.NewDecoder(data).Decode(&o); err != nil {
+		return nil
+	} else {
+		return &o
+	}
+}
+
+func ThreadMembershipsToJson(o []*ThreadMembership) string {
+	if b, err := json.Marshal(o); err != nil {
+		return ""
+	} else {
+		return string(b)
+	}
+}
+
+func ThreadMembershipsFromJson(data io.Reader) []*ThreadMembership {
+	var o []*ThreadMembership
+
+	if err := json.NewDecoder(data).Decode(&o); err != nil {
+		return nil
+	} else {
+		return o
+	}
+}
+
+func (o *ThreadMembership) PreSave() {
+	o.CreateAt = GetMillis()
+
+	if o.LastViewedAt == 0 {
+		o.LastViewedAt = o.CreateAt
+	}
+}
+
+// IsNotified returns true if the user should be notified of new replies to the thread
+func (o *ThreadMembership) IsNotified() bool {
+	return o.Following && !o.Muted
+}
+
+func (o *ThreadMembership) IsValid() *AppError {
+	if len(o.PostId) != 26 {
+		return NewLocAppError("ThreadMembership.IsValid", "model.thread_membership.is_valid.post_id.app_error", nil, "")
+	}
+
+	if len(o.UserId) != 26 {
+		return NewLocAppError("ThreadMembership.IsValid", "model.thread_membership.is_valid.user_id.app_error", nil, "")
+	}
+
+	if len(o.ChannelId) != 26 {
+		return NewLocAppError("ThreadMembership.IsValid", "model.thread_membership.is_valid.channel_id.app_error", nil, "")
+	}
+
+	if o.CreateAt == 0 {
+		return NewLocAppError("ThreadMembership.IsValid", "model.thread_membership.is_valid.create_at.app_error", nil, "")
+	}
+
+	if o.UnreadReplies < 0 {
+		return NewLocAppError("ThreadMembership.IsValid", "model.thread_membership.is_valid.unread_replies.app_error", nil, "")
+	}
+
+	return nil
+}
@@ -0,0 +1,67 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestThreadMembershipJson(t *testing.T) {
+	membership := ThreadMembership{PostId: NewId(), UserId: NewId(), ChannelId: NewId(), Following: true, UnreadReplies: 3}
+	rmembership := ThreadMembershipFromJson(strings.NewReader(membership.ToJson()))
+
+	if membership.PostId != rmembership.PostId || membership.UnreadReplies != rmembership.UnreadReplies {
+		t.Fatal("memberships do not match")
+	}
+
+	memberships := ThreadMembershipsFromJson(strings.NewReader(ThreadMembershipsToJson([]*ThreadMembership{&membership})))
+	if len(memberships) != 1 || memberships[0].UserId != membership.UserId {
+		t.Fatal("memberships do not match")
+	}
+}
+
+func TestThreadMembershipIsValid(t *testing.T) {
+	membership := ThreadMembership{PostId: NewId(), UserId: NewId(), ChannelId: NewId()}
+	if err := membership.IsValid(); err == nil {
+		t.Fatal("should be invalid without a create at")
+	}
+
+	membership.PreSave()
+	if err := membership.IsValid(); err != nil {
+		t.Fatal(err)
+	}
+
+	if membership.LastViewedAt != membership.CreateAt {
+		t.Fatal("should have been viewed when created")
+	}
+
+	membership.PostId = "junk"
+	if err := membership.IsValid(); err == nil {
+		t.Fatal("should be invalid with a bad post id")
+	}
+
+	membership.PostId = NewId()
+	membership.UnreadReplies = -1
+	if err := membership.IsValid(); err == nil {
+		t.Fatal("should be invalid with negative unread replies")
+	}
+}
+
+func TestThreadMembershipIsNotified(t *testing.T) {
+	membership := ThreadMembership{Following: true}
+	if !membership.IsNotified() {
+		t.Fatal("should notify followers")
+	}
+
+	membership.Muted = true
+	if membership.IsNotified() {
+		t.Fatal("shouldn't notify a muted thread")
+	}
+
+	membership = ThreadMembership{}
+	if membership.IsNotified() {
+		t.Fatal("shouldn't notify users who aren't following")
+	}
+}
@@ -4,11 +4,15 @@
 package model
 
 import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"regexp"
 	"strings"
+	"time"
 	"unicode/utf8"
 
 	"golang.org/x/crypto/bcrypt"
@@ -21,6 +25,9 @@ const (
 	DEFAULT_LOCALE             = "en"
 	USER_AUTH_SERVICE_EMAIL    = "email"
 	USER_AUTH_SERVICE_USERNAME = "username"
+	USER_AUTH_SERVICE_BOT      = "bot"
+	MFA_RECOVERY_CODE_COUNT    = 10
+	MFA_RECOVERY_CODE_LENGTH   = 16
 )
 
 type User struct {
@@ -42,13 +49,21 @@ type User struct {
 	AllowMarketing     bool      `json:"allow_marketing,omitempty"`
 	Props              StringMap `json:"props,omitempty"`
 	NotifyProps        StringMap `json:"notify_props,omitempty"`
+	ProfileAttributes  StringMap `json:"profile_attributes,omitempty"`
 	LastPasswordUpdate int64     `json:"last_password_update,omitempty"`
 	LastPictureUpdate  int64     `json:"last_picture_update,omitempty"`
 	FailedAttempts     int       `json:"failed_attempts,omitempty"`
+	LastFailedAttempt  int64     `json:"last_failed_attempt,omitempty"`
 	Locale             string    `json:"locale"`
 	MfaActive          bool      `json:"mfa_active,omitempty"`
 	MfaSecret          string    `json:"mfa_secret,omitempty"`
+	MfaRecoveryCodes   string    `json:"mfa_recovery_codes,omitempty"`
+	PasswordHistory    string    `json:"password_history,omitempty"`
+	GuestExpiresAt     int64     `json:"guest_expires_at,omitempty"`
 	LastActivityAt     int64     `db:"-" json:"last_activity_at,omitempty"`
+
+	// OutOfOffice is copied from the notify props when the profile is sanitized for other users
+	OutOfOffice *AutoResponder `db:"-" json:"out_of_office,omitempty"`
 }
 
 // IsValid validates the user and returns an error if it isn't configured
@@ -140,6 +155,10 @@ func (u *User) PreSave() {
 		u.Props = make(map[string]string)
 	}
 
+	if u.ProfileAttributes == nil {
+		u.ProfileAttributes = make(map[string]string)
+	}
+
 	if u.NotifyProps == nil || len(u.NotifyProps) == 0 {
 		u.SetDefaultNotifications()
 	}
@@ -226,6 +245,8 @@ func (u *User) Sanitize(options map[string]bool) {
 	u.AuthData = new(string)
 	*u.AuthData = ""
 	u.MfaSecret = ""
+	u.MfaRecoveryCodes = ""
+	u.PasswordHistory = ""
 
 	if len(options) != 0 && !options["email"] {
 		u.Email = ""
@@ -247,6 +268,8 @@ func (u *User) ClearNonProfileFields() {
 	u.AuthData = new(string)
 	*u.AuthData = ""
 	u.MfaSecret = ""
+	u.MfaRecoveryCodes = ""
+	u.PasswordHistory = ""
 	u.EmailVerified = false
 	u.AllowMarketing = false
 	u.Props = StringMap{}
@@ -254,9 +277,12 @@ func (u *User) ClearNonProfileFields() {
 	u.LastPasswordUpdate = 0
 	u.LastPictureUpdate = 0
 	u.FailedAttempts = 0
+	u.LastFailedAttempt = 0
 }
 
 func (u *User) SanitizeProfile(options map[string]bool) {
+	u.OutOfOffice = u.GetActiveAutoResponder(GetMillis())
+
 	u.ClearNonProfileFields()
 
 	u.Sanitize(options)
@@ -270,6 +296,10 @@ func (u *User) MakeNonNil() {
 	if u.NotifyProps == nil {
 		u.NotifyProps = make(map[string]string)
 	}
+
+	if u.ProfileAttributes == nil {
+		u.ProfileAttributes = make(map[string]string)
+	}
 }
 
 func (u *User) AddProp(key string, value string) {
@@ -355,6 +385,126 @@ func isValidRole(roleId string) bool {
 	return ok
 }
 
+// IsGuest returns true if the user is a guest account that can only access
+// the channels it has explicitly been added to.
+func (u *User) IsGuest() bool {
+	return IsInRole(u.Roles, ROLE_SYSTEM_GUEST.Id)
+}
+
+// IsGuestExpired returns true if the user is a guest whose access has passed
+// its expiry date.
+func (u *User) IsGuestExpired() bool {
+	return u.IsGuest() && u.GuestExpiresAt > 0 && u.GuestExpiresAt <= GetMillis()
+}
+
+// IsLockedOut returns true if the user has reached the maximum number of failed login attempts and the
+// lockout hasn't expired yet. A lockout duration of zero never expires.
+func (u *User) IsLockedOut(maxAttempts int, lockoutDurationInMinutes int) bool {
+	if u.FailedAttempts < maxAttempts {
+		return false
+	}
+
+	return lockoutDurationInMinutes <= 0 || GetMillis()-u.LastFailedAttempt < int64(lockoutDurationInMinutes)*60*1000
+}
+
+// IsPasswordExpired returns true if the password of an email account is older than the maximum age. A
+// maximum age of zero never expires.
+func (u *User) IsPasswordExpired(maxAgeInDays int) bool {
+	if maxAgeInDays <= 0 || (u.AuthService != "" && u.AuthService != USER_AUTH_SERVICE_EMAIL) {
+		return false
+	}
+
+	return GetMillis()-u.LastPasswordUpdate > int64(maxAgeInDays)*24*60*60*1000
+}
+
+// IsPasswordReused returns true if the password matches the current password or one of the previous
+// passwords that are remembered for the history count.
+func (u *User) IsPasswordReused(password string, historyCount int) bool {
+	if historyCount <= 0 {
+		return false
+	}
+
+	if len(u.Password) > 0 && ComparePassword(u.Password, password) {
+		return true
+	}
+
+	for i, hash := range strings.Fields(u.PasswordHistory) {
+		if i >= historyCount-1 {
+			break
+		}
+
+		if ComparePassword(hash, password) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NewPasswordHistory returns the password history to store once the current password has been replaced.
+// The current password is only added while the history count covers more than the new password.
+func (u *User) NewPasswordHistory(historyCount int) string {
+	hashes := strings.Fields(u.PasswordHistory)
+	if len(u.Password) > 0 {
+		hashes = append([]string{u.Password}, hashes...)
+	}
+
+	if historyCount <= 1 {
+		return ""
+	} else if len(hashes) > historyCount-1 {
+		hashes = hashes[:historyCount-1]
+	}
+
+	return strings.Join(hashes, " ")
+}
+
+// NewMfaRecoveryCodes generates a set of single use recovery codes and returns them along with the
+// hashes to store in their place.
+func NewMfaRecoveryCodes() ([]string, string) {
+	codes := make([]string, MFA_RECOVERY_CODE_COUNT)
+	hashes := make([]string, MFA_RECOVERY_CODE_COUNT)
+
+	for i := range codes {
+		codes[i] = NewRandomString(MFA_RECOVERY_CODE_LENGTH)
+		hashes[i] = hashMfaRecoveryCode(codes[i])
+	}
+
+	return codes, strings.Join(hashes, " ")
+}
+
+// UseMfaRecoveryCode returns true and the hashes of the codes left over if code is one of the user's
+// recovery codes.
+func (u *User) UseMfaRecoveryCode(code string) (string, bool) {
+	code = strings.ToLower(strings.Replace(strings.TrimSpace(code), "-", "", -1))
+	if len(code) != MFA_RECOVERY_CODE_LENGTH {
+		return u.MfaRecoveryCodes, false
+	}
+
+	hash := hashMfaRecoveryCode(code)
+	hashes := strings.Fields(u.MfaRecoveryCodes)
+	for i, h := range hashes {
+		if subtle.ConstantTimeCompare([]byte(h), []byte(hash)) == 1 {
+			return strings.Join(append(hashes[:i], hashes[i+1:]...), " "), true
+		}
+	}
+
+	return u.MfaRecoveryCodes, false
+}
+
+func hashMfaRecoveryCode(code string) string {
+	hash := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(hash[:])
+}
+
+// IsInDndSchedule returns true if the user's do not disturb schedule covers the given time.
+func (u *User) IsInDndSchedule(t time.Time) bool {
+	if schedule, err := DndScheduleFromNotifyProps(u.NotifyProps); err != nil || schedule == nil {
+		return false
+	} else {
+		return schedule.IsActive(t)
+	}
+}
+
 // Make sure you acually want to use this function. In context.go there are functions to check permissions
 // This function should not be used to check permissions.
 func (u *User) IsInRole(inRole string) bool {
@@ -377,7 +527,7 @@ func IsInRole(userRoles string, inRole string) bool {
 }
 
 func (u *User) IsOAuthUser() bool {
-	if u.AuthService == USER_AUTH_SERVICE_GITLAB {
+	if u.AuthService == USER_AUTH_SERVICE_GITLAB || u.AuthService == USER_AUTH_SERVICE_OPENID {
 		return true
 	}
 	return false
@@ -390,6 +540,11 @@ func (u *User) IsLDAPUser() bool {
 	return false
 }
 
+// IsBot returns true for the accounts the server posts as, which can't log in and aren't listed with other users
+func (u *User) IsBot() bool {
+	return u.AuthService == USER_AUTH_SERVICE_BOT
+}
+
 // UserFromJson will decode the input and return a User
 func UserFromJson(data io.Reader) *User {
 	decoder := json.NewDecoder(data)
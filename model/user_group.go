@@ -0,0 +1,144 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"encoding/json"
+	"io"
+	"unicode/utf8"
+)
+
+const (
+	USER_GROUP_NAME_MAX_LENGTH         = 64
+	USER_GROUP_DISPLAY_NAME_MAX_LENGTH = 64
+	USER_GROUP_DESCRIPTION_MAX_LENGTH  = 1024
+)
+
+// UserGroup is a set of users that are all mentioned by @Name. Groups are managed by system admins and, when
+// MemberManaged is set, their members can also add and remove members. Groups with an LdapGroupId have their
+// members synchronized from that directory group instead.
+type UserGroup struct {
+	Id            string `json:"id"`
+	Name          string `json:"name"`
+	DisplayName   string `json:"display_name"`
+	Description   string `json:"description"`
+	MemberManaged bool   `json:"member_managed"`
+	LdapGroupId   string `json:"ldap_group_id"`
+	CreatorId     string `json:"creator_id"`
+	CreateAt      int64  `json:"create_at"`
+	UpdateAt      int64  `json:"update_at"`
+}
+
+type UserGroupMember struct {
+	GroupId  string `json:"group_id"`
+	UserId   string `json:"user_id"`
+	CreateAt int64  `json:"create_at"`
+}
+
+func (o *UserGroup) ToJson() string {
+	if b, err := json.Marshal(o); err != nil {
+		return ""
+	} else {
+		return string(b)
+	}
+}
+
+func UserGroupFromJson(data io.Reader) *UserGroup {
+	var o UserGroup
+
+	if err := json.NewDecoder(data).Decode(&o); err != nil {
+		return nil
+	} else {
+		return &o
+	}
+}
+
+func UserGroupsToJson(o []*UserGroup) string {
+	if b, err := json.Marshal(o); err != nil {
+		return ""
+	} else {
+		return string(b)
+	}
+}
+
+func UserGroupsFromJson(data io.Reader) []*UserGroup {
+	var o []*UserGroup
+
+	if err := json.NewDecoder(data).Decode(&o); err != nil {
+		return nil
+	} else {
+		return o
+	}
+}
+
+func (o *UserGroup) PreSave() {
+	if o.Id == "" {
+		o.Id = NewId()
+	}
+
+	o.CreateAt = GetMillis()
+	o.UpdateAt = o.CreateAt
+}
+
+func (o *UserGroup) PreUpdate() {
+	o.UpdateAt = GetMillis()
+}
+
+// IsLdapSynced returns whether the members of the group come from a directory group
+func (o *UserGroup) IsLdapSynced() bool {
+	return len(o.LdapGroupId) > 0
+}
+
+func (o *UserGroup) IsValid() *AppError {
+	if len(o.Id) != 26 {
+		return NewLocAppError("UserGroup.IsValid", "model.user_group.is_valid.id.app_error", nil, "")
+	}
+
+	// groups are mentioned the same way as users so their names follow the same rules
+	if len(o.Name) > USER_GROUP_NAME_MAX_LENGTH || !IsValidUsername(o.Name) || o.Name == "here" {
+		return NewLocAppError("UserGroup.IsValid", "model.user_group.is_valid.name.app_error", nil, "id="+o.Id)
+	}
+
+	if len(o.DisplayName) == 0 || utf8.RuneCountInString(o.DisplayName) > USER_GROUP_DISPLAY_NAME_MAX_LENGTH {
+		return NewLocAppError("UserGroup.IsValid", "model.user_group.is_valid.display_name.app_error", nil, "id="+o.Id)
+	}
+
+	if utf8.RuneCountInString(o.Description) > USER_GROUP_DESCRIPTION_MAX_LENGTH {
+		return NewLocAppError("UserGroup.IsValid", "model.user_group.is_valid.description.app_error", nil, "id="+o.Id)
+	}
+
+	if len(o.LdapGroupId) > LDAP_GROUP_ID_MAX_LENGTH {
+		return NewLocAppError("UserGroup.IsValid", "model.user_group.is_valid.ldap_group_id.app_error", nil, "id="+o.Id)
+	}
+
+	if len(o.CreatorId) != 0 && len(o.CreatorId) != 26 {
+		return NewLocAppError("UserGroup.IsValid", "model.user_group.is_valid.creator_id.app_error", nil, "id="+o.Id)
+	}
+
+	if o.CreateAt == 0 {
+		return NewLocAppError("UserGroup.IsValid", "model.user_group.is_valid.create_at.app_error", nil, "id="+o.Id)
+	}
+
+	if o.UpdateAt == 0 {
+		return NewLocAppError("UserGroup.IsValid", "model.user_group.is_valid.update_at.app_error", nil, "id="+o.Id)
+	}
+
+	return nil
+}
+
+func (o *UserGroupMember) PreSave() {
+	o.CreateAt = GetMillis()
+}
+
+func (o *UserGroupMember) IsValid() *AppError {
+	if len(o.GroupId) != 26 {
+		return NewLocAppError("UserGroupMember.IsValid", "model.user_group_member.is_valid.group_id.app_error", nil, "")
+	}
+
+	if len(o.UserId) != 26 {
+		return NewLocAppError("UserGroupMember.IsValid", "model.user_group_member.is_valid.user_id.app_error", nil, "")
+	}
+
+	return nil
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUserGroupJson(t *testing.T) {
+	group := UserGroup{Id: NewId(), Name: "sre", DisplayName: "SRE"}
+	json := group.ToJson()
+	rgroup := UserGroupFromJson(strings.NewReader(json))
+
+	if group.Id != rgroup.Id || group.Name != rgroup.Name {
+		t.Fatal("ids do not match")
+	}
+
+	groups := UserGroupsFromJson(strings.NewReader(UserGroupsToJson([]*UserGroup{&group})))
+	if len(groups) != 1 || groups[0].Id != group.Id {
+		t.Fatal("groups do not match")
+	}
+}
+
+func TestUserGroupIsValid(t *testing.T) {
+	group := UserGroup{Name: "sre", DisplayName: "SRE"}
+	if err := group.IsValid(); err == nil {
+		t.Fatal("should be invalid without an id")
+	}
+
+	group.PreSave()
+	if err := group.IsValid(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"", "SRE", "s r e", "all", "channel", "here", strings.Repeat("a", USER_GROUP_NAME_MAX_LENGTH+1)} {
+		group.Name = name
+		if err := group.IsValid(); err == nil {
+			t.Fatalf("should be invalid with name %q", name)
+		}
+	}
+
+	group.Name = "front-end"
+	group.DisplayName = ""
+	if err := group.IsValid(); err == nil {
+		t.Fatal("should be invalid without a display name")
+	}
+
+	group.DisplayName = "Front End"
+	group.Description = strings.Repeat("a", USER_GROUP_DESCRIPTION_MAX_LENGTH+1)
+	if err := group.IsValid(); err == nil {
+		t.Fatal("should be invalid with a long description")
+	}
+
+	group.Description = ""
+	group.CreatorId = "junk"
+	if err := group.IsValid(); err == nil {
+		t.Fatal("should be invalid with a bad creator id")
+	}
+
+	group.CreatorId = NewId()
+	group.LdapGroupId = "cn=frontend,ou=groups,dc=example,dc=com"
+	if err := group.IsValid(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !group.IsLdapSynced() {
+		t.Fatal("should be synced from ldap")
+	}
+}
+
+func TestUserGroupMemberIsValid(t *testing.T) {
+	member := UserGroupMember{GroupId: NewId()}
+	if err := member.IsValid(); err == nil {
+		t.Fatal("should be invalid without a user")
+	}
+
+	member.UserId = NewId()
+	if err := member.IsValid(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -252,3 +252,148 @@ func TestRoles(t *testing.T) {
 		t.Fatal()
 	}
 }
+
+func TestUserIsLockedOut(t *testing.T) {
+	user := User{FailedAttempts: 2, LastFailedAttempt: GetMillis()}
+	if user.IsLockedOut(3, 30) {
+		t.Fatal("should not be locked out before reaching the maximum")
+	}
+
+	user.FailedAttempts = 3
+	if !user.IsLockedOut(3, 30) {
+		t.Fatal("should be locked out")
+	}
+
+	user.LastFailedAttempt = GetMillis() - 31*60*1000
+	if user.IsLockedOut(3, 30) {
+		t.Fatal("lockout should have expired")
+	}
+
+	if !user.IsLockedOut(3, 0) {
+		t.Fatal("lockout without a duration should never expire")
+	}
+}
+
+func TestUserPasswordHistory(t *testing.T) {
+	user := User{Password: HashPassword("passwd1")}
+
+	if user.IsPasswordReused("passwd1", 0) {
+		t.Fatal("nothing should be reused without a history")
+	}
+
+	if !user.IsPasswordReused("passwd1", 1) {
+		t.Fatal("the current password should be reused")
+	}
+
+	user.PasswordHistory = user.NewPasswordHistory(3)
+	user.Password = HashPassword("passwd2")
+	user.PasswordHistory = user.NewPasswordHistory(3)
+	user.Password = HashPassword("passwd3")
+
+	if len(strings.Fields(user.PasswordHistory)) != 2 {
+		t.Fatal("should've kept the two previous passwords")
+	}
+
+	if !user.IsPasswordReused("passwd1", 3) || !user.IsPasswordReused("passwd2", 3) {
+		t.Fatal("previous passwords should be reused")
+	}
+
+	if user.IsPasswordReused("passwd1", 2) {
+		t.Fatal("should only check as far back as the history count")
+	}
+
+	if user.IsPasswordReused("passwd4", 3) {
+		t.Fatal("a new password shouldn't be reused")
+	}
+
+	user.PasswordHistory = user.NewPasswordHistory(2)
+	if len(strings.Fields(user.PasswordHistory)) != 1 {
+		t.Fatal("should've trimmed the history")
+	}
+
+	if user.NewPasswordHistory(1) != "" {
+		t.Fatal("should've cleared the history")
+	}
+}
+
+func TestUserIsPasswordExpired(t *testing.T) {
+	user := User{LastPasswordUpdate: GetMillis() - 10*24*60*60*1000}
+
+	if user.IsPasswordExpired(0) {
+		t.Fatal("shouldn't expire without a maximum age")
+	}
+
+	if user.IsPasswordExpired(11) {
+		t.Fatal("shouldn't have expired yet")
+	}
+
+	if !user.IsPasswordExpired(9) {
+		t.Fatal("should have expired")
+	}
+
+	user.AuthService = USER_AUTH_SERVICE_LDAP
+	if user.IsPasswordExpired(9) {
+		t.Fatal("should only expire email passwords")
+	}
+}
+
+func TestUserIsGuest(t *testing.T) {
+	user := User{Roles: ROLE_SYSTEM_USER.Id}
+	if user.IsGuest() || user.IsGuestExpired() {
+		t.Fatal("should not be a guest")
+	}
+
+	user.Roles = ROLE_SYSTEM_GUEST.Id
+	if !user.IsGuest() {
+		t.Fatal("should be a guest")
+	}
+
+	if user.IsGuestExpired() {
+		t.Fatal("guest without an expiry date should not expire")
+	}
+
+	user.GuestExpiresAt = GetMillis() + 1000*60
+	if user.IsGuestExpired() {
+		t.Fatal("should not have expired yet")
+	}
+
+	user.GuestExpiresAt = GetMillis() - 1000
+	if !user.IsGuestExpired() {
+		t.Fatal("should have expired")
+	}
+}
+
+func TestMfaRecoveryCodes(t *testing.T) {
+	codes, hashes := NewMfaRecoveryCodes()
+	if len(codes) != MFA_RECOVERY_CODE_COUNT || len(strings.Fields(hashes)) != MFA_RECOVERY_CODE_COUNT {
+		t.Fatal("should have generated the codes")
+	}
+
+	if strings.Contains(hashes, codes[0]) {
+		t.Fatal("should not have stored the codes themselves")
+	}
+
+	user := User{MfaRecoveryCodes: hashes}
+
+	if _, ok := user.UseMfaRecoveryCode("123456"); ok {
+		t.Fatal("should not have accepted a code that isn't a recovery code")
+	}
+
+	remaining, ok := user.UseMfaRecoveryCode(" " + strings.ToUpper(codes[3]) + " ")
+	if !ok {
+		t.Fatal("should have accepted the recovery code")
+	}
+
+	if len(strings.Fields(remaining)) != MFA_RECOVERY_CODE_COUNT-1 {
+		t.Fatal("should have used up the recovery code")
+	}
+
+	user.MfaRecoveryCodes = remaining
+	if _, ok := user.UseMfaRecoveryCode(codes[3]); ok {
+		t.Fatal("should not have accepted the recovery code twice")
+	}
+
+	if _, ok := user.UseMfaRecoveryCode(codes[4]); !ok {
+		t.Fatal("should have accepted another recovery code")
+	}
+}
@@ -0,0 +1,489 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
+	"net/url"
+	"strings"
+)
+
+const (
+	WEBAUTHN_CHALLENGE_REGISTER = "register"
+	WEBAUTHN_CHALLENGE_LOGIN    = "login"
+	WEBAUTHN_CHALLENGE_TIMEOUT  = 300000 // 5 minutes
+
+	WEBAUTHN_CLIENT_DATA_CREATE = "webauthn.create"
+	WEBAUTHN_CLIENT_DATA_GET    = "webauthn.get"
+
+	WEBAUTHN_FLAG_USER_PRESENT  = 0x01
+	WEBAUTHN_FLAG_ATTESTED_DATA = 0x40
+
+	COSE_ALGORITHM_ES256 = -7
+	COSE_ALGORITHM_RS256 = -257
+)
+
+// WebAuthnChallenge is the state the server needs to verify a response from a security key. It is signed
+// and handed to the browser as the challenge itself, which comes back inside the client data. Issued
+// challenges are also saved by nonce so that each one can only be answered once.
+type WebAuthnChallenge struct {
+	Purpose   string `json:"purpose"`
+	UserId    string `json:"user_id"`
+	Origin    string `json:"origin"`
+	ExpiresAt int64  `json:"expires_at"`
+	Nonce     string `json:"nonce"`
+}
+
+// WebAuthnOptions holds what the browser needs to call navigator.credentials.create or get. Credentials
+// lists the ids to exclude when registering and the ids allowed when logging in.
+type WebAuthnOptions struct {
+	Challenge   string   `json:"challenge"`
+	RpId        string   `json:"rp_id"`
+	RpName      string   `json:"rp_name,omitempty"`
+	UserHandle  string   `json:"user_handle,omitempty"`
+	UserName    string   `json:"user_name,omitempty"`
+	Algorithms  []int    `json:"algorithms,omitempty"`
+	Credentials []string `json:"credentials"`
+	Timeout     int64    `json:"timeout"`
+}
+
+// WebAuthnRegistration is the response to navigator.credentials.create with every binary field base64url encoded
+type WebAuthnRegistration struct {
+	Name              string `json:"name"`
+	ClientDataJSON    string `json:"client_data_json"`
+	AttestationObject string `json:"attestation_object"`
+}
+
+// WebAuthnAssertion is the response to navigator.credentials.get with every binary field base64url encoded
+type WebAuthnAssertion struct {
+	CredentialId      string `json:"credential_id"`
+	ClientDataJSON    string `json:"client_data_json"`
+	AuthenticatorData string `json:"authenticator_data"`
+	Signature         string `json:"signature"`
+}
+
+type webAuthnClientData struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Origin    string `json:"origin"`
+}
+
+type webAuthnAuthenticatorData struct {
+	RpIdHash     []byte
+	Flags        byte
+	SignCount    uint32
+	CredentialId []byte
+	PublicKey    []byte
+}
+
+func NewWebAuthnChallenge(purpose, userId, origin string) *WebAuthnChallenge {
+	return &WebAuthnChallenge{
+		Purpose:   purpose,
+		UserId:    userId,
+		Origin:    strings.TrimRight(origin, "/"),
+		ExpiresAt: GetMillis() + WEBAUTHN_CHALLENGE_TIMEOUT,
+		Nonce:     NewRandomString(32),
+	}
+}
+
+// Encode signs the challenge with key and returns it base64url encoded the way browsers echo it back
+func (o *WebAuthnChallenge) Encode(key string) string {
+	b, _ := json.Marshal(o)
+	payload := base64.RawURLEncoding.EncodeToString(b)
+	signed := payload + "." + base64.RawURLEncoding.EncodeToString(signWebAuthnPayload(payload, key))
+
+	return base64.RawURLEncoding.EncodeToString([]byte(signed))
+}
+
+// DecodeWebAuthnChallenge checks the signature and expiry of a challenge created by Encode
+func DecodeWebAuthnChallenge(encoded, key string) (*WebAuthnChallenge, error) {
+	signed, err := decodeWebAuthnBase64(encoded)
+	if err != nil {
+		return nil, errors.New("webauthn: malformed challenge")
+	}
+
+	parts := strings.Split(string(signed), ".")
+	if len(parts) != 2 {
+		return nil, errors.New("webauthn: malformed challenge")
+	}
+
+	if signature, err := decodeWebAuthnBase64(parts[1]); err != nil || !hmac.Equal(signature, signWebAuthnPayload(parts[0], key)) {
+		return nil, errors.New("webauthn: invalid challenge signature")
+	}
+
+	payload, err := decodeWebAuthnBase64(parts[0])
+	if err != nil {
+		return nil, errors.New("webauthn: malformed challenge")
+	}
+
+	var challenge WebAuthnChallenge
+	if err := json.Unmarshal(payload, &challenge); err != nil {
+		return nil, errors.New("webauthn: malformed challenge")
+	}
+
+	if challenge.ExpiresAt < GetMillis() {
+		return nil, errors.New("webauthn: challenge expired")
+	}
+
+	return &challenge, nil
+}
+
+// RpId returns the relying party id for the origin the challenge was issued for, which is its host name
+func (o *WebAuthnChallenge) RpId() string {
+	return WebAuthnRpIdFromOrigin(o.Origin)
+}
+
+func WebAuthnRpIdFromOrigin(origin string) string {
+	if u, err := url.Parse(origin); err != nil {
+		return ""
+	} else {
+		return u.Hostname()
+	}
+}
+
+func signWebAuthnPayload(payload, key string) []byte {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+func decodeWebAuthnBase64(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+// Verify checks a registration against the challenge it answers and returns the credential to save.
+// Attestation statements aren't verified since the challenge asks for "none" attestation, so any
+// authenticator that the user holds can be registered.
+func (o *WebAuthnRegistration) Verify(key, userId string) (*WebAuthnCredential, error) {
+	challenge, err := verifyWebAuthnClientData(o.ClientDataJSON, WEBAUTHN_CLIENT_DATA_CREATE, WEBAUTHN_CHALLENGE_REGISTER, userId, key)
+	if err != nil {
+		return nil, err
+	}
+
+	attestation, err := decodeWebAuthnBase64(o.AttestationObject)
+	if err != nil {
+		return nil, errors.New("webauthn: malformed attestation object")
+	}
+
+	decoded, _, err := DecodeCBOR(attestation)
+	if err != nil {
+		return nil, err
+	}
+
+	attestationMap, ok := decoded.(map[interface{}]interface{})
+	if !ok {
+		return nil, errors.New("webauthn: malformed attestation object")
+	}
+
+	rawAuthData, ok := attestationMap["authData"].([]byte)
+	if !ok {
+		return nil, errors.New("webauthn: missing authenticator data")
+	}
+
+	authData, err := parseWebAuthnAuthenticatorData(rawAuthData, challenge.RpId())
+	if err != nil {
+		return nil, err
+	}
+
+	if authData.Flags&WEBAUTHN_FLAG_ATTESTED_DATA == 0 {
+		return nil, errors.New("webauthn: missing attested credential data")
+	}
+
+	if _, err := parseCOSEPublicKey(authData.PublicKey); err != nil {
+		return nil, err
+	}
+
+	return &WebAuthnCredential{
+		UserId:       userId,
+		Name:         o.Name,
+		CredentialId: base64.RawURLEncoding.EncodeToString(authData.CredentialId),
+		PublicKey:    base64.RawURLEncoding.EncodeToString(authData.PublicKey),
+		SignCount:    int64(authData.SignCount),
+	}, nil
+}
+
+// Verify checks an assertion made with credential against the challenge it answers and returns the new
+// signature counter to store for the credential
+func (o *WebAuthnAssertion) Verify(key, userId string, credential *WebAuthnCredential) (int64, error) {
+	if o.CredentialId != credential.CredentialId {
+		return 0, errors.New("webauthn: credential mismatch")
+	}
+
+	challenge, err := verifyWebAuthnClientData(o.ClientDataJSON, WEBAUTHN_CLIENT_DATA_GET, WEBAUTHN_CHALLENGE_LOGIN, userId, key)
+	if err != nil {
+		return 0, err
+	}
+
+	rawAuthData, err := decodeWebAuthnBase64(o.AuthenticatorData)
+	if err != nil {
+		return 0, errors.New("webauthn: malformed authenticator data")
+	}
+
+	authData, err := parseWebAuthnAuthenticatorData(rawAuthData, challenge.RpId())
+	if err != nil {
+		return 0, err
+	}
+
+	clientData, _ := decodeWebAuthnBase64(o.ClientDataJSON)
+	signature, err := decodeWebAuthnBase64(o.Signature)
+	if err != nil {
+		return 0, errors.New("webauthn: malformed signature")
+	}
+
+	publicKey, err := decodeWebAuthnBase64(credential.PublicKey)
+	if err != nil {
+		return 0, errors.New("webauthn: malformed public key")
+	}
+
+	clientDataHash := sha256.Sum256(clientData)
+	if err := verifyCOSESignature(publicKey, append(rawAuthData, clientDataHash[:]...), signature); err != nil {
+		return 0, err
+	}
+
+	// A counter that doesn't go up means the authenticator may have been cloned, unless it doesn't keep one
+	signCount := int64(authData.SignCount)
+	if (signCount != 0 || credential.SignCount != 0) && signCount <= credential.SignCount {
+		return 0, errors.New("webauthn: signature counter did not increase")
+	}
+
+	return signCount, nil
+}
+
+// DecodeWebAuthnClientDataChallenge returns the signed challenge that a browser echoed back in its client data
+func DecodeWebAuthnClientDataChallenge(encoded, key string) (*WebAuthnChallenge, error) {
+	clientData, err := decodeWebAuthnClientData(encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	return DecodeWebAuthnChallenge(clientData.Challenge, key)
+}
+
+func decodeWebAuthnClientData(encoded string) (*webAuthnClientData, error) {
+	raw, err := decodeWebAuthnBase64(encoded)
+	if err != nil {
+		return nil, errors.New("webauthn: malformed client data")
+	}
+
+	var clientData webAuthnClientData
+	if err := json.Unmarshal(raw, &clientData); err != nil {
+		return nil, errors.New("webauthn: malformed client data")
+	}
+
+	return &clientData, nil
+}
+
+func verifyWebAuthnClientData(encoded, clientDataType, purpose, userId, key string) (*WebAuthnChallenge, error) {
+	clientData, err := decodeWebAuthnClientData(encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	if clientData.Type != clientDataType {
+		return nil, errors.New("webauthn: wrong client data type")
+	}
+
+	challenge, err := DecodeWebAuthnChallenge(clientData.Challenge, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if challenge.Purpose != purpose || challenge.UserId != userId {
+		return nil, errors.New("webauthn: challenge was issued for something else")
+	}
+
+	if strings.TrimRight(clientData.Origin, "/") != challenge.Origin {
+		return nil, errors.New("webauthn: origin mismatch")
+	}
+
+	return challenge, nil
+}
+
+func parseWebAuthnAuthenticatorData(data []byte, rpId string) (*webAuthnAuthenticatorData, error) {
+	if len(data) < 37 {
+		return nil, errors.New("webauthn: authenticator data too short")
+	}
+
+	authData := &webAuthnAuthenticatorData{
+		RpIdHash:  data[:32],
+		Flags:     data[32],
+		SignCount: binary.BigEndian.Uint32(data[33:37]),
+	}
+
+	rpIdHash := sha256.Sum256([]byte(rpId))
+	if !bytes.Equal(authData.RpIdHash, rpIdHash[:]) {
+		return nil, errors.New("webauthn: relying party mismatch")
+	}
+
+	if authData.Flags&WEBAUTHN_FLAG_USER_PRESENT == 0 {
+		return nil, errors.New("webauthn: user not present")
+	}
+
+	if authData.Flags&WEBAUTHN_FLAG_ATTESTED_DATA != 0 {
+		// 16 byte AAGUID followed by the length of the credential id
+		rest := data[37:]
+		if len(rest) < 18 {
+			return nil, errors.New("webauthn: attested credential data too short")
+		}
+
+		idLength := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if len(rest) < idLength {
+			return nil, errors.New("webauthn: attested credential data too short")
+		}
+
+		authData.CredentialId = rest[:idLength]
+		rest = rest[idLength:]
+
+		// The public key is followed by extensions, if any, so decode it to find out where it ends
+		_, remaining, err := DecodeCBOR(rest)
+		if err != nil {
+			return nil, err
+		}
+
+		authData.PublicKey = rest[:len(rest)-len(remaining)]
+	}
+
+	return authData, nil
+}
+
+func parseCOSEPublicKey(data []byte) (crypto.PublicKey, error) {
+	decoded, _, err := DecodeCBOR(data)
+	if err != nil {
+		return nil, err
+	}
+
+	key, ok := decoded.(map[interface{}]interface{})
+	if !ok {
+		return nil, errors.New("cose: malformed key")
+	}
+
+	switch key[int64(3)] {
+	case int64(COSE_ALGORITHM_ES256):
+		x, xOk := key[int64(-2)].([]byte)
+		y, yOk := key[int64(-3)].([]byte)
+		if key[int64(1)] != int64(2) || key[int64(-1)] != int64(1) || !xOk || !yOk || len(x) != 32 || len(y) != 32 {
+			return nil, errors.New("cose: malformed EC2 key")
+		}
+
+		publicKey := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !publicKey.Curve.IsOnCurve(publicKey.X, publicKey.Y) {
+			return nil, errors.New("cose: point is not on the curve")
+		}
+
+		return publicKey, nil
+	case int64(COSE_ALGORITHM_RS256):
+		n, nOk := key[int64(-1)].([]byte)
+		e, eOk := key[int64(-2)].([]byte)
+		if key[int64(1)] != int64(3) || !nOk || !eOk || len(n) < 256 || len(e) == 0 || len(e) > 4 {
+			return nil, errors.New("cose: malformed RSA key")
+		}
+
+		exponent := 0
+		for _, b := range e {
+			exponent = exponent<<8 | int(b)
+		}
+
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: exponent}, nil
+	}
+
+	return nil, errors.New("cose: unsupported algorithm")
+}
+
+func verifyCOSESignature(key []byte, data []byte, signature []byte) error {
+	publicKey, err := parseCOSEPublicKey(key)
+	if err != nil {
+		return err
+	}
+
+	hash := sha256.Sum256(data)
+
+	switch publicKey := publicKey.(type) {
+	case *ecdsa.PublicKey:
+		var sig struct {
+			R, S *big.Int
+		}
+
+		if rest, err := asn1.Unmarshal(signature, &sig); err != nil || len(rest) != 0 {
+			return errors.New("webauthn: malformed signature")
+		}
+
+		if !ecdsa.Verify(publicKey, hash[:], sig.R, sig.S) {
+			return errors.New("webauthn: invalid signature")
+		}
+	case *rsa.PublicKey:
+		if err := rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, hash[:], signature); err != nil {
+			return errors.New("webauthn: invalid signature")
+		}
+	}
+
+	return nil
+}
+
+func (o *WebAuthnOptions) ToJson() string {
+	if b, err := json.Marshal(o); err != nil {
+		return ""
+	} else {
+		return string(b)
+	}
+}
+
+func WebAuthnOptionsFromJson(data io.Reader) *WebAuthnOptions {
+	var o WebAuthnOptions
+
+	if err := json.NewDecoder(data).Decode(&o); err != nil {
+		return nil
+	} else {
+		return &o
+	}
+}
+
+func (o *WebAuthnRegistration) ToJson() string {
+	if b, err := json.Marshal(o); err != nil {
+		return ""
+	} else {
+		return string(b)
+	}
+}
+
+func WebAuthnRegistrationFromJson(data io.Reader) *WebAuthnRegistration {
+	var o WebAuthnRegistration
+
+	if err := json.NewDecoder(data).Decode(&o); err != nil {
+		return nil
+	} else {
+		return &o
+	}
+}
+
+func (o *WebAuthnAssertion) ToJson() string {
+	if b, err := json.Marshal(o); err != nil {
+		return ""
+	} else {
+		return string(b)
+	}
+}
+
+func WebAuthnAssertionFromJson(data io.Reader) *WebAuthnAssertion {
+	var o WebAuthnAssertion
+
+	if err := json.NewDecoder(data).Decode(&o); err != nil {
+		return nil
+	} else {
+		return &o
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"encoding/json"
+	"io"
+)
+
+const (
+	WEBAUTHN_CREDENTIAL_NAME_MAX_LENGTH       = 64
+	WEBAUTHN_CREDENTIAL_ID_MAX_LENGTH         = 512
+	WEBAUTHN_CREDENTIAL_PUBLIC_KEY_MAX_LENGTH = 1024
+)
+
+// WebAuthnCredential is a security key registered by a user as a second factor. The credential id
+// and the COSE encoded public key are stored base64url encoded.
+type WebAuthnCredential struct {
+	Id           string `json:"id"`
+	UserId       string `json:"user_id"`
+	Name         string `json:"name"`
+	CredentialId string `json:"credential_id"`
+	PublicKey    string `json:"-"`
+	SignCount    int64  `json:"sign_count"`
+	CreateAt     int64  `json:"create_at"`
+	LastUsedAt   int64  `json:"last_used_at"`
+}
+
+func (o *WebAuthnCredential) ToJson() string {
+	if b, err := json.Marshal(o); err != nil {
+		return ""
+	} else {
+		return string(b)
+	}
+}
+
+func WebAuthnCredentialFromJson(data io.Reader) *WebAuthnCredential {
+	var o WebAuthnCredential
+
+	if err := json.NewDecoder(data).Decode(&o); err != nil {
+		return nil
+	} else {
+		return &o
+	}
+}
+
+func WebAuthnCredentialsToJson(o []*WebAuthnCredential) string {
+	if b, err := json.Marshal(o); err != nil {
+		return ""
+	} else {
+		return string(b)
+	}
+}
+
+func WebAuthnCredentialsFromJson(data io.Reader) []*WebAuthnCredential {
+	var o []*WebAuthnCredential
+
+	if err := json.NewDecoder(data).Decode(&o); err != nil {
+		return nil
+	} else {
+		return o
+	}
+}
+
+func (o *WebAuthnCredential) IsValid() *AppError {
+	if len(o.Id) != 26 {
+		return NewLocAppError("WebAuthnCredential.IsValid", "model.webauthn_credential.is_valid.id.app_error", nil, "")
+	}
+
+	if len(o.UserId) != 26 {
+		return NewLocAppError("WebAuthnCredential.IsValid", "model.webauthn_credential.is_valid.user_id.app_error", nil, "id="+o.Id)
+	}
+
+	if len(o.Name) == 0 || len(o.Name) > WEBAUTHN_CREDENTIAL_NAME_MAX_LENGTH {
+		return NewLocAppError("WebAuthnCredential.IsValid", "model.webauthn_credential.is_valid.name.app_error", nil, "id="+o.Id)
+	}
+
+	if len(o.CredentialId) == 0 || len(o.CredentialId) > WEBAUTHN_CREDENTIAL_ID_MAX_LENGTH {
+		return NewLocAppError("WebAuthnCredential.IsValid", "model.webauthn_credential.is_valid.credential_id.app_error", nil, "id="+o.Id)
+	}
+
+	if len(o.PublicKey) == 0 || len(o.PublicKey) > WEBAUTHN_CREDENTIAL_PUBLIC_KEY_MAX_LENGTH {
+		return NewLocAppError("WebAuthnCredential.IsValid", "model.webauthn_credential.is_valid.public_key.app_error", nil, "id="+o.Id)
+	}
+
+	if o.CreateAt == 0 {
+		return NewLocAppError("WebAuthnCredential.IsValid", "model.webauthn_credential.is_valid.create_at.app_error", nil, "id="+o.Id)
+	}
+
+	return nil
+}
+
+func (o *WebAuthnCredential) PreSave() {
+	if o.Id == "" {
+		o.Id = NewId()
+	}
+
+	if o.CreateAt == 0 {
+		o.CreateAt = GetMillis()
+	}
+}
@@ -0,0 +1,286 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+const (
+	testWebAuthnKey    = "abcdefghijklmnopqrstuvwxyz123456"
+	testWebAuthnOrigin = "https://chat.example.com"
+)
+
+func cborHead(major byte, n int) []byte {
+	if n < 24 {
+		return []byte{major<<5 | byte(n)}
+	} else if n < 256 {
+		return []byte{major<<5 | 24, byte(n)}
+	}
+
+	return []byte{major<<5 | 25, byte(n >> 8), byte(n)}
+}
+
+func cborInt(n int) []byte {
+	if n < 0 {
+		return cborHead(1, -1-n)
+	}
+
+	return cborHead(0, n)
+}
+
+func cborBytes(b []byte) []byte {
+	return append(cborHead(2, len(b)), b...)
+}
+
+func cborText(s string) []byte {
+	return append(cborHead(3, len(s)), s...)
+}
+
+func cborMap(items ...[]byte) []byte {
+	encoded := cborHead(5, len(items)/2)
+	for _, item := range items {
+		encoded = append(encoded, item...)
+	}
+	return encoded
+}
+
+type testAuthenticator struct {
+	key          *ecdsa.PrivateKey
+	credentialId []byte
+	signCount    uint32
+	rpId         string
+}
+
+func newTestAuthenticator(t *testing.T) *testAuthenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &testAuthenticator{key: key, credentialId: []byte(NewId()), rpId: WebAuthnRpIdFromOrigin(testWebAuthnOrigin)}
+}
+
+func (a *testAuthenticator) publicKey() []byte {
+	x := make([]byte, 32)
+	y := make([]byte, 32)
+	xb := a.key.X.Bytes()
+	yb := a.key.Y.Bytes()
+	copy(x[32-len(xb):], xb)
+	copy(y[32-len(yb):], yb)
+
+	return cborMap(
+		cborInt(1), cborInt(2),
+		cborInt(3), cborInt(COSE_ALGORITHM_ES256),
+		cborInt(-1), cborInt(1),
+		cborInt(-2), cborBytes(x),
+		cborInt(-3), cborBytes(y),
+	)
+}
+
+func (a *testAuthenticator) authData(attested bool) []byte {
+	rpIdHash := sha256.Sum256([]byte(a.rpId))
+	data := append([]byte{}, rpIdHash[:]...)
+
+	flags := byte(WEBAUTHN_FLAG_USER_PRESENT)
+	if attested {
+		flags |= WEBAUTHN_FLAG_ATTESTED_DATA
+	}
+	data = append(data, flags)
+
+	counter := make([]byte, 4)
+	binary.BigEndian.PutUint32(counter, a.signCount)
+	data = append(data, counter...)
+
+	if attested {
+		data = append(data, make([]byte, 16)...)
+		data = append(data, byte(len(a.credentialId)>>8), byte(len(a.credentialId)))
+		data = append(data, a.credentialId...)
+		data = append(data, a.publicKey()...)
+	}
+
+	return data
+}
+
+func testClientData(clientDataType, challenge, origin string) []byte {
+	b, _ := json.Marshal(map[string]string{"type": clientDataType, "challenge": challenge, "origin": origin})
+	return b
+}
+
+func (a *testAuthenticator) register(challenge, origin string) *WebAuthnRegistration {
+	attestation := cborMap(
+		cborText("fmt"), cborText("none"),
+		cborText("attStmt"), cborMap(),
+		cborText("authData"), cborBytes(a.authData(true)),
+	)
+
+	return &WebAuthnRegistration{
+		Name:              "key",
+		ClientDataJSON:    base64.RawURLEncoding.EncodeToString(testClientData(WEBAUTHN_CLIENT_DATA_CREATE, challenge, origin)),
+		AttestationObject: base64.RawURLEncoding.EncodeToString(attestation),
+	}
+}
+
+func (a *testAuthenticator) assert(t *testing.T, challenge, origin string) *WebAuthnAssertion {
+	a.signCount++
+
+	clientData := testClientData(WEBAUTHN_CLIENT_DATA_GET, challenge, origin)
+	authData := a.authData(false)
+
+	clientDataHash := sha256.Sum256(clientData)
+	hash := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+
+	r, s, err := ecdsa.Sign(rand.Reader, a.key, hash[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signature, err := asn1.Marshal(struct{ R, S *big.Int }{r, s})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &WebAuthnAssertion{
+		CredentialId:      base64.RawURLEncoding.EncodeToString(a.credentialId),
+		ClientDataJSON:    base64.RawURLEncoding.EncodeToString(clientData),
+		AuthenticatorData: base64.RawURLEncoding.EncodeToString(authData),
+		Signature:         base64.RawURLEncoding.EncodeToString(signature),
+	}
+}
+
+func TestWebAuthnChallenge(t *testing.T) {
+	userId := NewId()
+	challenge := NewWebAuthnChallenge(WEBAUTHN_CHALLENGE_LOGIN, userId, testWebAuthnOrigin+"/")
+	encoded := challenge.Encode(testWebAuthnKey)
+
+	if decoded, err := DecodeWebAuthnChallenge(encoded, testWebAuthnKey); err != nil {
+		t.Fatal(err)
+	} else if decoded.UserId != userId || decoded.Purpose != WEBAUTHN_CHALLENGE_LOGIN || decoded.Origin != testWebAuthnOrigin {
+		t.Fatal("should have decoded the challenge", decoded)
+	} else if decoded.RpId() != "chat.example.com" {
+		t.Fatal("should have used the host as the relying party id", decoded.RpId())
+	}
+
+	if _, err := DecodeWebAuthnChallenge(encoded, "some other key"); err == nil {
+		t.Fatal("should have failed with another key")
+	}
+
+	if _, err := DecodeWebAuthnChallenge(encoded[:len(encoded)-4], testWebAuthnKey); err == nil {
+		t.Fatal("should have failed with a truncated challenge")
+	}
+
+	challenge.ExpiresAt = GetMillis() - 1000
+	if _, err := DecodeWebAuthnChallenge(challenge.Encode(testWebAuthnKey), testWebAuthnKey); err == nil {
+		t.Fatal("should have failed with an expired challenge")
+	}
+
+	if WebAuthnRpIdFromOrigin("http://localhost:8065") != "localhost" {
+		t.Fatal("should have removed the port")
+	}
+}
+
+func TestWebAuthnRegistrationAndAssertion(t *testing.T) {
+	userId := NewId()
+	authenticator := newTestAuthenticator(t)
+
+	registerChallenge := NewWebAuthnChallenge(WEBAUTHN_CHALLENGE_REGISTER, userId, testWebAuthnOrigin).Encode(testWebAuthnKey)
+
+	if _, err := authenticator.register(registerChallenge, "https://evil.example.com").Verify(testWebAuthnKey, userId); err == nil {
+		t.Fatal("should have failed with the wrong origin")
+	}
+
+	if _, err := authenticator.register(registerChallenge, testWebAuthnOrigin).Verify(testWebAuthnKey, NewId()); err == nil {
+		t.Fatal("should have failed for another user")
+	}
+
+	credential, err := authenticator.register(registerChallenge, testWebAuthnOrigin).Verify(testWebAuthnKey, userId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	credential.PreSave()
+	if appErr := credential.IsValid(); appErr != nil {
+		t.Fatal(appErr)
+	}
+
+	if credential.CredentialId != base64.RawURLEncoding.EncodeToString(authenticator.credentialId) {
+		t.Fatal("should have saved the credential id")
+	}
+
+	loginChallenge := NewWebAuthnChallenge(WEBAUTHN_CHALLENGE_LOGIN, userId, testWebAuthnOrigin).Encode(testWebAuthnKey)
+
+	if _, err := authenticator.assert(t, registerChallenge, testWebAuthnOrigin).Verify(testWebAuthnKey, userId, credential); err == nil {
+		t.Fatal("should have failed to log in with a registration challenge")
+	}
+
+	assertion := authenticator.assert(t, loginChallenge, testWebAuthnOrigin)
+	if signCount, err := assertion.Verify(testWebAuthnKey, userId, credential); err != nil {
+		t.Fatal(err)
+	} else if signCount != int64(authenticator.signCount) {
+		t.Fatal("should have returned the new signature counter")
+	} else {
+		credential.SignCount = signCount
+	}
+
+	if _, err := assertion.Verify(testWebAuthnKey, userId, credential); err == nil {
+		t.Fatal("should have failed to replay the assertion")
+	}
+
+	tampered := authenticator.assert(t, loginChallenge, testWebAuthnOrigin)
+	tampered.AuthenticatorData = authenticator.assert(t, loginChallenge, testWebAuthnOrigin).AuthenticatorData
+	if _, err := tampered.Verify(testWebAuthnKey, userId, credential); err == nil || !strings.Contains(err.Error(), "signature") {
+		t.Fatal("should have failed with a signature that doesn't match", err)
+	}
+
+	other := newTestAuthenticator(t)
+	other.credentialId = authenticator.credentialId
+	if _, err := other.assert(t, loginChallenge, testWebAuthnOrigin).Verify(testWebAuthnKey, userId, credential); err == nil {
+		t.Fatal("should have failed with another key")
+	}
+
+	authenticator.rpId = "evil.example.com"
+	if _, err := authenticator.assert(t, loginChallenge, testWebAuthnOrigin).Verify(testWebAuthnKey, userId, credential); err == nil {
+		t.Fatal("should have failed for another relying party")
+	}
+}
+
+func TestWebAuthnCredentialIsValid(t *testing.T) {
+	credential := WebAuthnCredential{}
+
+	if err := credential.IsValid(); err == nil {
+		t.Fatal("should be invalid")
+	}
+
+	credential.PreSave()
+	credential.UserId = NewId()
+	credential.CredentialId = NewId()
+	credential.PublicKey = NewId()
+	if err := credential.IsValid(); err == nil {
+		t.Fatal("should be invalid without a name")
+	}
+
+	credential.Name = strings.Repeat("a", WEBAUTHN_CREDENTIAL_NAME_MAX_LENGTH+1)
+	if err := credential.IsValid(); err == nil {
+		t.Fatal("should be invalid with a long name")
+	}
+
+	credential.Name = "key"
+	if err := credential.IsValid(); err != nil {
+		t.Fatal(err)
+	}
+
+	if json := credential.ToJson(); strings.Contains(json, credential.PublicKey) {
+		t.Fatal("should not have serialized the public key")
+	}
+}
@@ -132,3 +132,13 @@ func (wsc *WebSocketClient) GetStatusesByIds(userIds []string) {
 	}
 	wsc.SendMessage("get_statuses_by_ids", data)
 }
+
+// GetStatusesAndCustomStatusesByIds will fetch certain user statuses based on ids and return a map
+// of string statuses under "statuses" and a map of custom statuses under "custom_statuses"
+func (wsc *WebSocketClient) GetStatusesAndCustomStatusesByIds(userIds []string) {
+	data := map[string]interface{}{
+		"user_ids":       userIds,
+		"include_custom": true,
+	}
+	wsc.SendMessage("get_statuses_by_ids", data)
+}
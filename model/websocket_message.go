@@ -9,27 +9,29 @@ import (
 )
 
 const (
-	WEBSOCKET_EVENT_TYPING             = "typing"
-	WEBSOCKET_EVENT_POSTED             = "posted"
-	WEBSOCKET_EVENT_POST_EDITED        = "post_edited"
-	WEBSOCKET_EVENT_POST_DELETED       = "post_deleted"
-	WEBSOCKET_EVENT_CHANNEL_DELETED    = "channel_deleted"
-	WEBSOCKET_EVENT_CHANNEL_VIEWED     = "channel_viewed"
-	WEBSOCKET_EVENT_DIRECT_ADDED       = "direct_added"
-	WEBSOCKET_EVENT_NEW_USER           = "new_user"
-	WEBSOCKET_EVENT_LEAVE_TEAM         = "leave_team"
-	WEBSOCKET_EVENT_UPDATE_TEAM        = "update_team"
-	WEBSOCKET_EVENT_USER_ADDED         = "user_added"
-	WEBSOCKET_EVENT_USER_UPDATED       = "user_updated"
-	WEBSOCKET_EVENT_USER_REMOVED       = "user_removed"
-	WEBSOCKET_EVENT_PREFERENCE_CHANGED = "preference_changed"
-	WEBSOCKET_EVENT_EPHEMERAL_MESSAGE  = "ephemeral_message"
-	WEBSOCKET_EVENT_STATUS_CHANGE      = "status_change"
-	WEBSOCKET_EVENT_HELLO              = "hello"
-	WEBSOCKET_EVENT_WEBRTC             = "webrtc"
-	WEBSOCKET_AUTHENTICATION_CHALLENGE = "authentication_challenge"
-	WEBSOCKET_EVENT_REACTION_ADDED     = "reaction_added"
-	WEBSOCKET_EVENT_REACTION_REMOVED   = "reaction_removed"
+	WEBSOCKET_EVENT_TYPING                 = "typing"
+	WEBSOCKET_EVENT_POSTED                 = "posted"
+	WEBSOCKET_EVENT_POST_EDITED            = "post_edited"
+	WEBSOCKET_EVENT_POST_DELETED           = "post_deleted"
+	WEBSOCKET_EVENT_CHANNEL_DELETED        = "channel_deleted"
+	WEBSOCKET_EVENT_CHANNEL_VIEWED         = "channel_viewed"
+	WEBSOCKET_EVENT_CHANNEL_MEMBER_UPDATED = "channel_member_updated"
+	WEBSOCKET_EVENT_DIRECT_ADDED           = "direct_added"
+	WEBSOCKET_EVENT_NEW_USER               = "new_user"
+	WEBSOCKET_EVENT_LEAVE_TEAM             = "leave_team"
+	WEBSOCKET_EVENT_UPDATE_TEAM            = "update_team"
+	WEBSOCKET_EVENT_USER_ADDED             = "user_added"
+	WEBSOCKET_EVENT_USER_UPDATED           = "user_updated"
+	WEBSOCKET_EVENT_USER_REMOVED           = "user_removed"
+	WEBSOCKET_EVENT_PREFERENCE_CHANGED     = "preference_changed"
+	WEBSOCKET_EVENT_EPHEMERAL_MESSAGE      = "ephemeral_message"
+	WEBSOCKET_EVENT_STATUS_CHANGE          = "status_change"
+	WEBSOCKET_EVENT_HELLO                  = "hello"
+	WEBSOCKET_EVENT_WEBRTC                 = "webrtc"
+	WEBSOCKET_AUTHENTICATION_CHALLENGE     = "authentication_challenge"
+	WEBSOCKET_EVENT_REACTION_ADDED         = "reaction_added"
+	WEBSOCKET_EVENT_REACTION_REMOVED       = "reaction_removed"
+	WEBSOCKET_EVENT_THREAD_UPDATED         = "thread_updated"
 )
 
 type WebSocketMessage interface {
@@ -26,6 +26,9 @@ const (
 
 	CHANNEL_MEMBERS_COUNTS_CACHE_SIZE = 20000
 	CHANNEL_MEMBERS_COUNTS_CACHE_SEC  = 900 // 15 mins
+
+	CHANNEL_TEAM_ID_CACHE_SIZE = 20000
+	CHANNEL_TEAM_ID_CACHE_SEC  = 900 // 15 mins
 )
 
 type SqlChannelStore struct {
@@ -34,10 +37,12 @@ type SqlChannelStore struct {
 
 var channelMemberCountsCache = utils.NewLru(CHANNEL_MEMBERS_COUNTS_CACHE_SIZE)
 var allChannelMembersForUserCache = utils.NewLru(ALL_CHANNEL_MEMBERS_FOR_USER_CACHE_SIZE)
+var channelTeamIdCache = utils.NewLru(CHANNEL_TEAM_ID_CACHE_SIZE)
 
 func ClearChannelCaches() {
 	channelMemberCountsCache.Purge()
 	allChannelMembersForUserCache.Purge()
+	channelTeamIdCache.Purge()
 }
 
 func NewSqlChannelStore(sqlStore *SqlStore) ChannelStore {
@@ -804,6 +809,52 @@ func (s SqlChannelStore) GetAllChannelMembersForUser(userId string, allowFromCac
 	return storeChannel
 }
 
+// GetTeamIdForChannel returns the id of the team that a channel belongs to. A channel never moves between teams
+// so the cached value doesn't need to be invalidated.
+func (s SqlChannelStore) GetTeamIdForChannel(channelId string, allowFromCache bool) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+		metrics := einterfaces.GetMetricsInterface()
+
+		if allowFromCache {
+			if cacheItem, ok := channelTeamIdCache.Get(channelId); ok {
+				if metrics != nil {
+					metrics.IncrementMemCacheHitCounter("Channel Team Id")
+				}
+				result.Data = cacheItem.(string)
+				storeChannel <- result
+				close(storeChannel)
+				return
+			} else {
+				if metrics != nil {
+					metrics.IncrementMemCacheMissCounter("Channel Team Id")
+				}
+			}
+		} else {
+			if metrics != nil {
+				metrics.IncrementMemCacheMissCounter("Channel Team Id")
+			}
+		}
+
+		if teamId, err := s.GetReplica().SelectStr("SELECT TeamId FROM Channels WHERE Id = :Id", map[string]interface{}{"Id": channelId}); err != nil {
+			result.Err = model.NewLocAppError("SqlChannelStore.GetTeamIdForChannel", "store.sql_channel.get.find.app_error", nil, "id="+channelId+", "+err.Error())
+		} else {
+			result.Data = teamId
+
+			if allowFromCache {
+				channelTeamIdCache.AddWithExpiresInSecs(channelId, teamId, CHANNEL_TEAM_ID_CACHE_SEC)
+			}
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
 func (us SqlChannelStore) InvalidateMemberCount(channelId string) {
 	channelMemberCountsCache.Remove(channelId)
 }
@@ -0,0 +1,164 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package store
+
+import (
+	"github.com/mattermost/platform/model"
+)
+
+type SqlLdapGroupLinkStore struct {
+	*SqlStore
+}
+
+func NewSqlLdapGroupLinkStore(sqlStore *SqlStore) LdapGroupLinkStore {
+	s := &SqlLdapGroupLinkStore{sqlStore}
+
+	for _, db := range sqlStore.GetAllConns() {
+		table := db.AddTableWithName(model.LdapGroupLink{}, "LdapGroupLinks").SetKeys(false, "Id")
+		table.ColMap("Id").SetMaxSize(26)
+		table.ColMap("GroupId").SetMaxSize(model.LDAP_GROUP_ID_MAX_LENGTH)
+		table.ColMap("GroupName").SetMaxSize(model.LDAP_GROUP_NAME_MAX_LENGTH)
+		table.ColMap("TeamId").SetMaxSize(26)
+		table.ColMap("ChannelId").SetMaxSize(26)
+		table.ColMap("CreatorId").SetMaxSize(26)
+	}
+
+	return s
+}
+
+func (s SqlLdapGroupLinkStore) CreateIndexesIfNotExists() {
+	s.CreateIndexIfNotExists("idx_ldapgrouplinks_team_id", "LdapGroupLinks", "TeamId")
+	s.CreateIndexIfNotExists("idx_ldapgrouplinks_channel_id", "LdapGroupLinks", "ChannelId")
+}
+
+func (s SqlLdapGroupLinkStore) Save(link *model.LdapGroupLink) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		link.PreSave()
+		if result.Err = link.IsValid(); result.Err != nil {
+			storeChannel <- result
+			close(storeChannel)
+			return
+		}
+
+		if count, err := s.GetReplica().SelectInt("SELECT COUNT(*) FROM LdapGroupLinks WHERE GroupId = :GroupId AND TeamId = :TeamId AND ChannelId = :ChannelId",
+			map[string]interface{}{"GroupId": link.GroupId, "TeamId": link.TeamId, "ChannelId": link.ChannelId}); err != nil {
+			result.Err = model.NewLocAppError("SqlLdapGroupLinkStore.Save", "store.sql_ldap_group_link.save.app_error", nil, "group_id="+link.GroupId+", "+err.Error())
+		} else if count > 0 {
+			result.Err = model.NewLocAppError("SqlLdapGroupLinkStore.Save", "store.sql_ldap_group_link.save.exists.app_error", nil, "group_id="+link.GroupId)
+		} else if err := s.GetMaster().Insert(link); err != nil {
+			result.Err = model.NewLocAppError("SqlLdapGroupLinkStore.Save", "store.sql_ldap_group_link.save.app_error", nil, "group_id="+link.GroupId+", "+err.Error())
+		} else {
+			result.Data = link
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
+func (s SqlLdapGroupLinkStore) Get(id string) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		var link model.LdapGroupLink
+		if err := s.GetReplica().SelectOne(&link, "SELECT * FROM LdapGroupLinks WHERE Id = :Id", map[string]interface{}{"Id": id}); err != nil {
+			result.Err = model.NewLocAppError("SqlLdapGroupLinkStore.Get", "store.sql_ldap_group_link.get.app_error", nil, "id="+id+", "+err.Error())
+		} else {
+			result.Data = &link
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
+func (s SqlLdapGroupLinkStore) GetAll() StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		var links []*model.LdapGroupLink
+		if _, err := s.GetReplica().Select(&links, "SELECT * FROM LdapGroupLinks ORDER BY TeamId, ChannelId, CreateAt"); err != nil {
+			result.Err = model.NewLocAppError("SqlLdapGroupLinkStore.GetAll", "store.sql_ldap_group_link.get_all.app_error", nil, err.Error())
+		} else {
+			result.Data = links
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
+// GetForTeam returns the links to the team itself, not the ones to its channels
+func (s SqlLdapGroupLinkStore) GetForTeam(teamId string) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		var links []*model.LdapGroupLink
+		if _, err := s.GetReplica().Select(&links, "SELECT * FROM LdapGroupLinks WHERE TeamId = :TeamId AND ChannelId = '' ORDER BY CreateAt", map[string]interface{}{"TeamId": teamId}); err != nil {
+			result.Err = model.NewLocAppError("SqlLdapGroupLinkStore.GetForTeam", "store.sql_ldap_group_link.get_for_team.app_error", nil, "team_id="+teamId+", "+err.Error())
+		} else {
+			result.Data = links
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
+func (s SqlLdapGroupLinkStore) GetForChannel(channelId string) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		var links []*model.LdapGroupLink
+		if _, err := s.GetReplica().Select(&links, "SELECT * FROM LdapGroupLinks WHERE ChannelId = :ChannelId ORDER BY CreateAt", map[string]interface{}{"ChannelId": channelId}); err != nil {
+			result.Err = model.NewLocAppError("SqlLdapGroupLinkStore.GetForChannel", "store.sql_ldap_group_link.get_for_channel.app_error", nil, "channel_id="+channelId+", "+err.Error())
+		} else {
+			result.Data = links
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
+func (s SqlLdapGroupLinkStore) Delete(id string) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		if sqlResult, err := s.GetMaster().Exec("DELETE FROM LdapGroupLinks WHERE Id = :Id", map[string]interface{}{"Id": id}); err != nil {
+			result.Err = model.NewLocAppError("SqlLdapGroupLinkStore.Delete", "store.sql_ldap_group_link.delete.app_error", nil, "id="+id+", "+err.Error())
+		} else if rows, _ := sqlResult.RowsAffected(); rows == 0 {
+			result.Err = model.NewLocAppError("SqlLdapGroupLinkStore.Delete", "store.sql_ldap_group_link.delete.missing.app_error", nil, "id="+id)
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
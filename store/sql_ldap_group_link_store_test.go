@@ -0,0 +1,61 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package store
+
+import (
+	"testing"
+
+	"github.com/mattermost/platform/model"
+)
+
+func TestLdapGroupLinkStore(t *testing.T) {
+	Setup()
+
+	teamId := model.NewId()
+	channelId := model.NewId()
+	groupId := "cn=engineering,ou=groups,dc=example,dc=com"
+
+	teamLink := &model.LdapGroupLink{GroupId: groupId, GroupName: "Engineering", TeamId: teamId}
+	if result := <-store.LdapGroupLink().Save(teamLink); result.Err != nil {
+		t.Fatal(result.Err)
+	}
+
+	channelLink := &model.LdapGroupLink{GroupId: groupId, GroupName: "Engineering", TeamId: teamId, ChannelId: channelId}
+	Must(store.LdapGroupLink().Save(channelLink))
+
+	if result := <-store.LdapGroupLink().Save(&model.LdapGroupLink{GroupId: groupId, TeamId: teamId}); result.Err == nil {
+		t.Fatal("should have failed to link the group to the team twice")
+	}
+
+	if result := <-store.LdapGroupLink().Save(&model.LdapGroupLink{TeamId: teamId}); result.Err == nil {
+		t.Fatal("should have failed to save a link without a group")
+	}
+
+	if link := Must(store.LdapGroupLink().Get(teamLink.Id)).(*model.LdapGroupLink); link.GroupId != groupId {
+		t.Fatal("should have returned the link")
+	}
+
+	if links := Must(store.LdapGroupLink().GetForTeam(teamId)).([]*model.LdapGroupLink); len(links) != 1 || links[0].Id != teamLink.Id {
+		t.Fatal("should have only returned the team link")
+	}
+
+	if links := Must(store.LdapGroupLink().GetForChannel(channelId)).([]*model.LdapGroupLink); len(links) != 1 || links[0].Id != channelLink.Id {
+		t.Fatal("should have only returned the channel link")
+	}
+
+	if links := Must(store.LdapGroupLink().GetAll()).([]*model.LdapGroupLink); len(links) < 2 {
+		t.Fatal("should have returned both links")
+	}
+
+	Must(store.LdapGroupLink().Delete(teamLink.Id))
+	Must(store.LdapGroupLink().Delete(channelLink.Id))
+
+	if result := <-store.LdapGroupLink().Delete(teamLink.Id); result.Err == nil {
+		t.Fatal("should have failed to delete a missing link")
+	}
+
+	if links := Must(store.LdapGroupLink().GetForTeam(teamId)).([]*model.LdapGroupLink); len(links) != 0 {
+		t.Fatal("should have deleted the link")
+	}
+}
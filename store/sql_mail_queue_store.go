@@ -0,0 +1,316 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package store
+
+import (
+	"strings"
+
+	"github.com/mattermost/platform/model"
+)
+
+const (
+	MAIL_QUEUE_CLAIM_CANDIDATES = 10
+)
+
+type SqlMailQueueStore struct {
+	*SqlStore
+}
+
+func NewSqlMailQueueStore(sqlStore *SqlStore) MailQueueStore {
+	s := &SqlMailQueueStore{sqlStore}
+
+	for _, db := range sqlStore.GetAllConns() {
+		table := db.AddTableWithName(model.OutgoingEmail{}, "OutgoingEmails").SetKeys(false, "Id")
+		table.ColMap("Id").SetMaxSize(26)
+		table.ColMap("Recipient").SetMaxSize(model.OUTGOING_EMAIL_RECIPIENT_MAX_LENGTH)
+		table.ColMap("ReplyTo").SetMaxSize(model.OUTGOING_EMAIL_RECIPIENT_MAX_LENGTH)
+		table.ColMap("Subject").SetMaxSize(model.OUTGOING_EMAIL_SUBJECT_MAX_LENGTH)
+		table.ColMap("Body").SetMaxSize(65535)
+		table.ColMap("Status").SetMaxSize(16)
+		table.ColMap("LastError").SetMaxSize(model.OUTGOING_EMAIL_ERROR_MAX_LENGTH)
+
+		tableb := db.AddTableWithName(model.EmailBounce{}, "EmailBounces").SetKeys(false, "Email")
+		tableb.ColMap("Email").SetMaxSize(model.OUTGOING_EMAIL_RECIPIENT_MAX_LENGTH)
+		tableb.ColMap("Reason").SetMaxSize(model.EMAIL_BOUNCE_REASON_MAX_LENGTH)
+	}
+
+	return s
+}
+
+func (s SqlMailQueueStore) CreateIndexesIfNotExists() {
+	s.CreateIndexIfNotExists("idx_outgoingemails_status_next_attempt_at", "OutgoingEmails", "Status, NextAttemptAt")
+	s.CreateIndexIfNotExists("idx_outgoingemails_recipient", "OutgoingEmails", "Recipient")
+	s.CreateIndexIfNotExists("idx_outgoingemails_update_at", "OutgoingEmails", "UpdateAt")
+}
+
+func (s SqlMailQueueStore) Save(email *model.OutgoingEmail) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		email.PreSave()
+		if result.Err = email.IsValid(); result.Err != nil {
+			storeChannel <- result
+			close(storeChannel)
+			return
+		}
+
+		if err := s.GetMaster().Insert(email); err != nil {
+			result.Err = model.NewLocAppError("SqlMailQueueStore.Save", "store.sql_mail_queue.save.app_error", nil, "id="+email.Id+", "+err.Error())
+		} else {
+			result.Data = email
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
+func (s SqlMailQueueStore) Update(email *model.OutgoingEmail) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		email.PreUpdate()
+		if result.Err = email.IsValid(); result.Err != nil {
+			storeChannel <- result
+			close(storeChannel)
+			return
+		}
+
+		if _, err := s.GetMaster().Update(email); err != nil {
+			result.Err = model.NewLocAppError("SqlMailQueueStore.Update", "store.sql_mail_queue.update.app_error", nil, "id="+email.Id+", "+err.Error())
+		} else {
+			result.Data = email
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
+// ClaimNext marks the next pending email that's due as being sent and returns it, or nil if there isn't
+// one. An email is only claimed by one worker even if several servers share the queue.
+func (s SqlMailQueueStore) ClaimNext(now int64) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		var candidates []*model.OutgoingEmail
+		if _, err := s.GetMaster().Select(&candidates,
+			`SELECT * FROM OutgoingEmails WHERE Status = :Status AND NextAttemptAt <= :Now ORDER BY NextAttemptAt LIMIT :Limit`,
+			map[string]interface{}{"Status": model.OUTGOING_EMAIL_STATUS_PENDING, "Now": now, "Limit": MAIL_QUEUE_CLAIM_CANDIDATES}); err != nil {
+			result.Err = model.NewLocAppError("SqlMailQueueStore.ClaimNext", "store.sql_mail_queue.claim_next.app_error", nil, err.Error())
+			storeChannel <- result
+			close(storeChannel)
+			return
+		}
+
+		for _, email := range candidates {
+			updateAt := model.GetMillis()
+
+			if sqlResult, err := s.GetMaster().Exec("UPDATE OutgoingEmails SET Status = :Sending, UpdateAt = :UpdateAt WHERE Id = :Id AND Status = :Pending",
+				map[string]interface{}{"Sending": model.OUTGOING_EMAIL_STATUS_SENDING, "UpdateAt": updateAt, "Id": email.Id, "Pending": model.OUTGOING_EMAIL_STATUS_PENDING}); err != nil {
+				result.Err = model.NewLocAppError("SqlMailQueueStore.ClaimNext", "store.sql_mail_queue.claim_next.app_error", nil, "id="+email.Id+", "+err.Error())
+				break
+			} else if rows, _ := sqlResult.RowsAffected(); rows == 1 {
+				email.Status = model.OUTGOING_EMAIL_STATUS_SENDING
+				email.UpdateAt = updateAt
+				result.Data = email
+				break
+			}
+
+			// another worker claimed it first
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
+// ResetStuck returns emails to the queue that were claimed before the given time and never finished, such
+// as when the server sending them stopped
+func (s SqlMailQueueStore) ResetStuck(before int64) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		if sqlResult, err := s.GetMaster().Exec("UPDATE OutgoingEmails SET Status = :Pending WHERE Status = :Sending AND UpdateAt < :Before",
+			map[string]interface{}{"Pending": model.OUTGOING_EMAIL_STATUS_PENDING, "Sending": model.OUTGOING_EMAIL_STATUS_SENDING, "Before": before}); err != nil {
+			result.Err = model.NewLocAppError("SqlMailQueueStore.ResetStuck", "store.sql_mail_queue.reset_stuck.app_error", nil, err.Error())
+		} else {
+			result.Data, _ = sqlResult.RowsAffected()
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
+// CountSentToSince returns the number of emails sent to an address since the given time
+func (s SqlMailQueueStore) CountSentToSince(recipient string, since int64) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		if count, err := s.GetMaster().SelectInt("SELECT COUNT(*) FROM OutgoingEmails WHERE Recipient = :Recipient AND Status = :Sent AND UpdateAt >= :Since",
+			map[string]interface{}{"Recipient": strings.ToLower(recipient), "Sent": model.OUTGOING_EMAIL_STATUS_SENT, "Since": since}); err != nil {
+			result.Err = model.NewLocAppError("SqlMailQueueStore.CountSentToSince", "store.sql_mail_queue.count_sent_to_since.app_error", nil, err.Error())
+		} else {
+			result.Data = count
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
+// GetStats counts the emails in each state and returns the most recent failures
+func (s SqlMailQueueStore) GetStats(failureLimit int) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		var counts []struct {
+			Status string
+			Count  int64
+		}
+		if _, err := s.GetReplica().Select(&counts, "SELECT Status, COUNT(*) AS Count FROM OutgoingEmails GROUP BY Status"); err != nil {
+			result.Err = model.NewLocAppError("SqlMailQueueStore.GetStats", "store.sql_mail_queue.get_stats.app_error", nil, err.Error())
+			storeChannel <- result
+			close(storeChannel)
+			return
+		}
+
+		stats := &model.MailQueueStats{}
+		for _, count := range counts {
+			switch count.Status {
+			case model.OUTGOING_EMAIL_STATUS_PENDING:
+				stats.Pending = count.Count
+			case model.OUTGOING_EMAIL_STATUS_SENDING:
+				stats.Sending = count.Count
+			case model.OUTGOING_EMAIL_STATUS_FAILED:
+				stats.Failed = count.Count
+			}
+		}
+
+		if _, err := s.GetReplica().Select(&stats.Failures, "SELECT * FROM OutgoingEmails WHERE Status = :Failed ORDER BY UpdateAt DESC LIMIT :Limit",
+			map[string]interface{}{"Failed": model.OUTGOING_EMAIL_STATUS_FAILED, "Limit": failureLimit}); err != nil {
+			result.Err = model.NewLocAppError("SqlMailQueueStore.GetStats", "store.sql_mail_queue.get_stats.app_error", nil, err.Error())
+		} else {
+			result.Data = stats
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
+// PermanentDeleteFinishedBefore removes the records of emails that were sent or failed before the given time
+func (s SqlMailQueueStore) PermanentDeleteFinishedBefore(before int64) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		if _, err := s.GetMaster().Exec("DELETE FROM OutgoingEmails WHERE (Status = :Sent OR Status = :Failed) AND UpdateAt < :Before",
+			map[string]interface{}{"Sent": model.OUTGOING_EMAIL_STATUS_SENT, "Failed": model.OUTGOING_EMAIL_STATUS_FAILED, "Before": before}); err != nil {
+			result.Err = model.NewLocAppError("SqlMailQueueStore.PermanentDeleteFinishedBefore", "store.sql_mail_queue.permanent_delete_finished_before.app_error", nil, err.Error())
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
+func (s SqlMailQueueStore) SaveBounce(bounce *model.EmailBounce) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		bounce.PreSave()
+		if result.Err = bounce.IsValid(); result.Err != nil {
+			storeChannel <- result
+			close(storeChannel)
+			return
+		}
+
+		if count, err := s.GetMaster().SelectInt("SELECT COUNT(*) FROM EmailBounces WHERE Email = :Email", map[string]interface{}{"Email": bounce.Email}); err != nil {
+			result.Err = model.NewLocAppError("SqlMailQueueStore.SaveBounce", "store.sql_mail_queue.save_bounce.app_error", nil, "email="+bounce.Email+", "+err.Error())
+		} else if count > 0 {
+			if _, err := s.GetMaster().Update(bounce); err != nil {
+				result.Err = model.NewLocAppError("SqlMailQueueStore.SaveBounce", "store.sql_mail_queue.save_bounce.app_error", nil, "email="+bounce.Email+", "+err.Error())
+			}
+		} else if err := s.GetMaster().Insert(bounce); err != nil {
+			result.Err = model.NewLocAppError("SqlMailQueueStore.SaveBounce", "store.sql_mail_queue.save_bounce.app_error", nil, "email="+bounce.Email+", "+err.Error())
+		}
+
+		if result.Err == nil {
+			result.Data = bounce
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
+// IsBouncing returns true if the address has been marked as bouncing
+func (s SqlMailQueueStore) IsBouncing(email string) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		if count, err := s.GetReplica().SelectInt("SELECT COUNT(*) FROM EmailBounces WHERE Email = :Email", map[string]interface{}{"Email": strings.ToLower(email)}); err != nil {
+			result.Err = model.NewLocAppError("SqlMailQueueStore.IsBouncing", "store.sql_mail_queue.is_bouncing.app_error", nil, "email="+email+", "+err.Error())
+		} else {
+			result.Data = count > 0
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
+func (s SqlMailQueueStore) DeleteBounce(email string) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		if _, err := s.GetMaster().Exec("DELETE FROM EmailBounces WHERE Email = :Email", map[string]interface{}{"Email": strings.ToLower(email)}); err != nil {
+			result.Err = model.NewLocAppError("SqlMailQueueStore.DeleteBounce", "store.sql_mail_queue.delete_bounce.app_error", nil, "email="+email+", "+err.Error())
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
@@ -0,0 +1,110 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package store
+
+import (
+	"testing"
+
+	"github.com/mattermost/platform/model"
+)
+
+func TestMailQueueStoreClaimNext(t *testing.T) {
+	Setup()
+
+	recipient := "success+" + model.NewId() + "@simulator.amazonses.com"
+
+	email := Must(store.MailQueue().Save(&model.OutgoingEmail{Recipient: recipient, Subject: "subject", Body: "body"})).(*model.OutgoingEmail)
+	later := Must(store.MailQueue().Save(&model.OutgoingEmail{Recipient: recipient, Subject: "later", Body: "body", NextAttemptAt: model.GetMillis() + 60000})).(*model.OutgoingEmail)
+
+	claimed := map[string]bool{}
+	for {
+		result := Must(store.MailQueue().ClaimNext(model.GetMillis()))
+		if result == nil {
+			break
+		}
+
+		rclaimed := result.(*model.OutgoingEmail)
+		if rclaimed.Status != model.OUTGOING_EMAIL_STATUS_SENDING {
+			t.Fatal("should have marked the email as being sent")
+		}
+
+		claimed[rclaimed.Id] = true
+	}
+
+	if !claimed[email.Id] {
+		t.Fatal("should have claimed the email")
+	}
+
+	if claimed[later.Id] {
+		t.Fatal("shouldn't have claimed an email that isn't due")
+	}
+
+	if reset := Must(store.MailQueue().ResetStuck(model.GetMillis() + 1)).(int64); reset == 0 {
+		t.Fatal("should have returned the claimed emails to the queue")
+	}
+
+	if result := Must(store.MailQueue().ClaimNext(model.GetMillis())); result == nil {
+		t.Fatal("should have claimed the email again")
+	}
+
+	email.Status = model.OUTGOING_EMAIL_STATUS_SENT
+	Must(store.MailQueue().Update(email))
+
+	if count := Must(store.MailQueue().CountSentToSince(recipient, 0)).(int64); count != 1 {
+		t.Fatal("should have counted the sent email", count)
+	}
+
+	later.Status = model.OUTGOING_EMAIL_STATUS_FAILED
+	later.LastError = "550 No such user"
+	Must(store.MailQueue().Update(later))
+
+	stats := Must(store.MailQueue().GetStats(100)).(*model.MailQueueStats)
+	if stats.Failed == 0 {
+		t.Fatal("should have counted the failed email")
+	}
+
+	found := false
+	for _, failure := range stats.Failures {
+		if failure.Id == later.Id && failure.LastError == later.LastError {
+			found = true
+		}
+	}
+
+	if !found {
+		t.Fatal("should have returned the failure")
+	}
+
+	Must(store.MailQueue().PermanentDeleteFinishedBefore(model.GetMillis() + 1))
+
+	if count := Must(store.MailQueue().CountSentToSince(recipient, 0)).(int64); count != 0 {
+		t.Fatal("should have deleted the sent email", count)
+	}
+}
+
+func TestMailQueueStoreBounces(t *testing.T) {
+	Setup()
+
+	email := "bounce+" + model.NewId() + "@simulator.amazonses.com"
+
+	if bouncing := Must(store.MailQueue().IsBouncing(email)).(bool); bouncing {
+		t.Fatal("shouldn't be bouncing")
+	}
+
+	if result := <-store.MailQueue().SaveBounce(&model.EmailBounce{Email: "invalid"}); result.Err == nil {
+		t.Fatal("shouldn't have saved an invalid address")
+	}
+
+	Must(store.MailQueue().SaveBounce(&model.EmailBounce{Email: email, Reason: "550 No such user"}))
+	Must(store.MailQueue().SaveBounce(&model.EmailBounce{Email: email, Reason: "550 Mailbox disabled"}))
+
+	if bouncing := Must(store.MailQueue().IsBouncing(email)).(bool); !bouncing {
+		t.Fatal("should be bouncing")
+	}
+
+	Must(store.MailQueue().DeleteBounce(email))
+
+	if bouncing := Must(store.MailQueue().IsBouncing(email)).(bool); bouncing {
+		t.Fatal("shouldn't be bouncing anymore")
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package store
+
+import (
+	"github.com/mattermost/platform/model"
+)
+
+type SqlPendingEmailNotificationStore struct {
+	*SqlStore
+}
+
+func NewSqlPendingEmailNotificationStore(sqlStore *SqlStore) PendingEmailNotificationStore {
+	s := &SqlPendingEmailNotificationStore{sqlStore}
+
+	for _, db := range sqlStore.GetAllConns() {
+		table := db.AddTableWithName(model.PendingEmailNotification{}, "PendingEmailNotifications").SetKeys(false, "Id")
+		table.ColMap("Id").SetMaxSize(26)
+		table.ColMap("UserId").SetMaxSize(26)
+		table.ColMap("PostId").SetMaxSize(26)
+		table.ColMap("TeamName").SetMaxSize(64)
+	}
+
+	return s
+}
+
+func (s SqlPendingEmailNotificationStore) CreateIndexesIfNotExists() {
+	s.CreateIndexIfNotExists("idx_pendingemailnotifications_user_id", "PendingEmailNotifications", "UserId")
+	s.CreateIndexIfNotExists("idx_pendingemailnotifications_create_at", "PendingEmailNotifications", "CreateAt")
+}
+
+func (s SqlPendingEmailNotificationStore) Save(notification *model.PendingEmailNotification) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		notification.PreSave()
+		if result.Err = notification.IsValid(); result.Err != nil {
+			storeChannel <- result
+			close(storeChannel)
+			return
+		}
+
+		if err := s.GetMaster().Insert(notification); err != nil {
+			result.Err = model.NewLocAppError("SqlPendingEmailNotificationStore.Save", "store.sql_pending_email_notification.save.app_error", nil, "user_id="+notification.UserId+", "+err.Error())
+		} else {
+			result.Data = notification
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
+// GetAll returns every pending notification in the order they were queued
+func (s SqlPendingEmailNotificationStore) GetAll() StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		var notifications []*model.PendingEmailNotification
+		if _, err := s.GetMaster().Select(&notifications, "SELECT * FROM PendingEmailNotifications ORDER BY CreateAt, Id"); err != nil {
+			result.Err = model.NewLocAppError("SqlPendingEmailNotificationStore.GetAll", "store.sql_pending_email_notification.get_all.app_error", nil, err.Error())
+		} else {
+			result.Data = notifications
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
+func (s SqlPendingEmailNotificationStore) Count() StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		if count, err := s.GetMaster().SelectInt("SELECT COUNT(*) FROM PendingEmailNotifications"); err != nil {
+			result.Err = model.NewLocAppError("SqlPendingEmailNotificationStore.Count", "store.sql_pending_email_notification.count.app_error", nil, err.Error())
+		} else {
+			result.Data = count
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
+// DeleteForUser removes the user's notifications queued up to and including the given time so that ones
+// queued while a batch was being handled are kept for the next one
+func (s SqlPendingEmailNotificationStore) DeleteForUser(userId string, before int64) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		if _, err := s.GetMaster().Exec("DELETE FROM PendingEmailNotifications WHERE UserId = :UserId AND CreateAt <= :Before",
+			map[string]interface{}{"UserId": userId, "Before": before}); err != nil {
+			result.Err = model.NewLocAppError("SqlPendingEmailNotificationStore.DeleteForUser", "store.sql_pending_email_notification.delete_for_user.app_error", nil, "user_id="+userId+", "+err.Error())
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
@@ -0,0 +1,53 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package store
+
+import (
+	"testing"
+
+	"github.com/mattermost/platform/model"
+)
+
+func TestPendingEmailNotificationStore(t *testing.T) {
+	Setup()
+
+	userId := model.NewId()
+
+	if result := <-store.PendingEmailNotification().Save(&model.PendingEmailNotification{UserId: userId, TeamName: "team"}); result.Err == nil {
+		t.Fatal("shouldn't have saved a notification without a post")
+	}
+
+	n1 := Must(store.PendingEmailNotification().Save(&model.PendingEmailNotification{UserId: userId, PostId: model.NewId(), TeamName: "team", CreateAt: 1000})).(*model.PendingEmailNotification)
+	n2 := Must(store.PendingEmailNotification().Save(&model.PendingEmailNotification{UserId: userId, PostId: model.NewId(), TeamName: "team", CreateAt: 2000})).(*model.PendingEmailNotification)
+	n3 := Must(store.PendingEmailNotification().Save(&model.PendingEmailNotification{UserId: model.NewId(), PostId: model.NewId(), TeamName: "team", CreateAt: 1500})).(*model.PendingEmailNotification)
+
+	if count := Must(store.PendingEmailNotification().Count()).(int64); count < 3 {
+		t.Fatal("should have counted the notifications", count)
+	}
+
+	var ids []string
+	for _, notification := range Must(store.PendingEmailNotification().GetAll()).([]*model.PendingEmailNotification) {
+		if notification.Id == n1.Id || notification.Id == n2.Id || notification.Id == n3.Id {
+			ids = append(ids, notification.Id)
+		}
+	}
+
+	if len(ids) != 3 || ids[0] != n1.Id || ids[1] != n3.Id || ids[2] != n2.Id {
+		t.Fatal("should have returned the notifications in the order they were queued", ids)
+	}
+
+	Must(store.PendingEmailNotification().DeleteForUser(userId, 1000))
+
+	remaining := map[string]bool{}
+	for _, notification := range Must(store.PendingEmailNotification().GetAll()).([]*model.PendingEmailNotification) {
+		remaining[notification.Id] = true
+	}
+
+	if remaining[n1.Id] || !remaining[n2.Id] || !remaining[n3.Id] {
+		t.Fatal("should only have deleted the user's notifications queued before the time")
+	}
+
+	Must(store.PendingEmailNotification().DeleteForUser(userId, model.GetMillis()))
+	Must(store.PendingEmailNotification().DeleteForUser(n3.UserId, model.GetMillis()))
+}
@@ -1034,6 +1034,37 @@ func (s SqlPostStore) AnalyticsPostCountsByDay(teamId string) StoreChannel {
 	return storeChannel
 }
 
+// CountByTypeSince counts the posts of the given type that the user made in the channel since the time. It reads
+// from the master so that posts made a moment ago are counted.
+func (s SqlPostStore) CountByTypeSince(channelId string, userId string, postType string, time int64) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		if v, err := s.GetMaster().SelectInt(
+			`SELECT
+				COUNT(Id)
+			FROM
+				Posts
+			WHERE
+				ChannelId = :ChannelId
+				AND UserId = :UserId
+				AND Type = :Type
+				AND CreateAt >= :Time
+				AND DeleteAt = 0`, map[string]interface{}{"ChannelId": channelId, "UserId": userId, "Type": postType, "Time": time}); err != nil {
+			result.Err = model.NewLocAppError("SqlPostStore.CountByTypeSince", "store.sql_post.count_by_type_since.app_error", nil, "channel_id="+channelId+", "+err.Error())
+		} else {
+			result.Data = v
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
 func (s SqlPostStore) AnalyticsPostCount(teamId string, mustHaveFile bool, mustHaveHashtag bool) StoreChannel {
 	storeChannel := make(StoreChannel, 1)
 
@@ -0,0 +1,208 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package store
+
+import (
+	"github.com/mattermost/platform/einterfaces"
+	"github.com/mattermost/platform/model"
+	"github.com/mattermost/platform/utils"
+)
+
+const (
+	PROFILE_ATTRIBUTES_CACHE_SIZE = 1
+	PROFILE_ATTRIBUTES_CACHE_SEC  = 900 // 15 mins
+	PROFILE_ATTRIBUTES_CACHE_KEY  = "profile_attributes"
+)
+
+type SqlProfileAttributeStore struct {
+	*SqlStore
+}
+
+var profileAttributesCache = utils.NewLru(PROFILE_ATTRIBUTES_CACHE_SIZE)
+
+func ClearProfileAttributeCaches() {
+	profileAttributesCache.Purge()
+}
+
+func NewSqlProfileAttributeStore(sqlStore *SqlStore) ProfileAttributeStore {
+	s := &SqlProfileAttributeStore{sqlStore}
+
+	for _, db := range sqlStore.GetAllConns() {
+		table := db.AddTableWithName(model.ProfileAttribute{}, "ProfileAttributes").SetKeys(false, "Id")
+		table.ColMap("Id").SetMaxSize(26)
+		table.ColMap("Name").SetMaxSize(model.PROFILE_ATTRIBUTE_NAME_MAX_LENGTH).SetUnique(true)
+		table.ColMap("DisplayName").SetMaxSize(model.PROFILE_ATTRIBUTE_DISPLAY_NAME_MAX_LENGTH)
+		table.ColMap("Type").SetMaxSize(32)
+		table.ColMap("Options").SetMaxSize(model.PROFILE_ATTRIBUTE_OPTIONS_MAX_LENGTH)
+		table.ColMap("Visibility").SetMaxSize(32)
+		table.ColMap("LdapAttribute").SetMaxSize(128)
+		table.ColMap("SamlAttribute").SetMaxSize(128)
+	}
+
+	return s
+}
+
+func (s SqlProfileAttributeStore) CreateIndexesIfNotExists() {
+}
+
+func (s SqlProfileAttributeStore) Save(attribute *model.ProfileAttribute) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		if len(attribute.Id) > 0 {
+			result.Err = model.NewLocAppError("SqlProfileAttributeStore.Save", "store.sql_profile_attribute.save.existing.app_error", nil, "id="+attribute.Id)
+			storeChannel <- result
+			close(storeChannel)
+			return
+		}
+
+		attribute.PreSave()
+		if result.Err = attribute.IsValid(); result.Err != nil {
+			storeChannel <- result
+			close(storeChannel)
+			return
+		}
+
+		if err := s.GetMaster().Insert(attribute); err != nil {
+			if IsUniqueConstraintError(err.Error(), []string{"Name", "profileattributes_name_key"}) {
+				result.Err = model.NewLocAppError("SqlProfileAttributeStore.Save", "store.sql_profile_attribute.save.name_exists.app_error", nil, "name="+attribute.Name+", "+err.Error())
+			} else {
+				result.Err = model.NewLocAppError("SqlProfileAttributeStore.Save", "store.sql_profile_attribute.save.app_error", nil, "id="+attribute.Id+", "+err.Error())
+			}
+		} else {
+			result.Data = attribute
+		}
+
+		profileAttributesCache.Purge()
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
+func (s SqlProfileAttributeStore) Update(attribute *model.ProfileAttribute) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		attribute.PreUpdate()
+		if result.Err = attribute.IsValid(); result.Err != nil {
+			storeChannel <- result
+			close(storeChannel)
+			return
+		}
+
+		if count, err := s.GetMaster().Update(attribute); err != nil {
+			if IsUniqueConstraintError(err.Error(), []string{"Name", "profileattributes_name_key"}) {
+				result.Err = model.NewLocAppError("SqlProfileAttributeStore.Update", "store.sql_profile_attribute.save.name_exists.app_error", nil, "name="+attribute.Name+", "+err.Error())
+			} else {
+				result.Err = model.NewLocAppError("SqlProfileAttributeStore.Update", "store.sql_profile_attribute.update.app_error", nil, "id="+attribute.Id+", "+err.Error())
+			}
+		} else if count != 1 {
+			result.Err = model.NewLocAppError("SqlProfileAttributeStore.Update", "store.sql_profile_attribute.get.app_error", nil, "id="+attribute.Id)
+		} else {
+			result.Data = attribute
+		}
+
+		profileAttributesCache.Purge()
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
+func (s SqlProfileAttributeStore) Get(id string) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		var attribute model.ProfileAttribute
+		if err := s.GetReplica().SelectOne(&attribute, "SELECT * FROM ProfileAttributes WHERE Id = :Id", map[string]interface{}{"Id": id}); err != nil {
+			result.Err = model.NewLocAppError("SqlProfileAttributeStore.Get", "store.sql_profile_attribute.get.app_error", nil, "id="+id+", "+err.Error())
+		} else {
+			result.Data = &attribute
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
+// GetAll returns the attributes in the order that they are shown on profiles.
+func (s SqlProfileAttributeStore) GetAll(allowFromCache bool) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+	metrics := einterfaces.GetMetricsInterface()
+
+	go func() {
+		result := StoreResult{}
+
+		if allowFromCache {
+			if cacheItem, ok := profileAttributesCache.Get(PROFILE_ATTRIBUTES_CACHE_KEY); ok {
+				if metrics != nil {
+					metrics.IncrementMemCacheHitCounter("Profile Attributes")
+				}
+				result.Data = cacheItem.([]*model.ProfileAttribute)
+				storeChannel <- result
+				close(storeChannel)
+				return
+			} else {
+				if metrics != nil {
+					metrics.IncrementMemCacheMissCounter("Profile Attributes")
+				}
+			}
+		} else {
+			if metrics != nil {
+				metrics.IncrementMemCacheMissCounter("Profile Attributes")
+			}
+		}
+
+		var attributes []*model.ProfileAttribute
+		if _, err := s.GetReplica().Select(&attributes, "SELECT * FROM ProfileAttributes ORDER BY SortOrder, Name"); err != nil {
+			result.Err = model.NewLocAppError("SqlProfileAttributeStore.GetAll", "store.sql_profile_attribute.get_all.app_error", nil, err.Error())
+		} else {
+			result.Data = attributes
+
+			if allowFromCache {
+				profileAttributesCache.AddWithExpiresInSecs(PROFILE_ATTRIBUTES_CACHE_KEY, attributes, PROFILE_ATTRIBUTES_CACHE_SEC)
+			}
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
+// Delete removes the attribute definition. Values saved on users are dropped the next time they are saved.
+func (s SqlProfileAttributeStore) Delete(id string) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		if res, err := s.GetMaster().Exec("DELETE FROM ProfileAttributes WHERE Id = :Id", map[string]interface{}{"Id": id}); err != nil {
+			result.Err = model.NewLocAppError("SqlProfileAttributeStore.Delete", "store.sql_profile_attribute.delete.app_error", nil, "id="+id+", "+err.Error())
+		} else if count, _ := res.RowsAffected(); count == 0 {
+			result.Err = model.NewLocAppError("SqlProfileAttributeStore.Delete", "store.sql_profile_attribute.get.app_error", nil, "id="+id)
+		}
+
+		profileAttributesCache.Purge()
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
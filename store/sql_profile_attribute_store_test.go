@@ -0,0 +1,71 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package store
+
+import (
+	"testing"
+
+	"github.com/mattermost/platform/model"
+)
+
+func TestProfileAttributeStore(t *testing.T) {
+	Setup()
+
+	attribute := &model.ProfileAttribute{
+		Name:        "a" + model.NewId(),
+		DisplayName: "Pronouns",
+		Type:        model.PROFILE_ATTRIBUTE_TYPE_SELECT,
+		Options:     model.StringArray{"she/her", "he/him", "they/them"},
+	}
+
+	Must(store.ProfileAttribute().Save(attribute))
+
+	if result := <-store.ProfileAttribute().Save(attribute); result.Err == nil {
+		t.Fatal("shouldn't have saved an existing attribute")
+	}
+
+	if result := <-store.ProfileAttribute().Save(&model.ProfileAttribute{Name: attribute.Name, DisplayName: "Other", Type: model.PROFILE_ATTRIBUTE_TYPE_TEXT}); result.Err == nil {
+		t.Fatal("shouldn't have saved a duplicate name")
+	}
+
+	attribute.Visibility = model.PROFILE_ATTRIBUTE_VISIBILITY_HIDDEN
+	Must(store.ProfileAttribute().Update(attribute))
+
+	if result := <-store.ProfileAttribute().Get(attribute.Id); result.Err != nil {
+		t.Fatal(result.Err)
+	} else if rattribute := result.Data.(*model.ProfileAttribute); rattribute.Visibility != model.PROFILE_ATTRIBUTE_VISIBILITY_HIDDEN || len(rattribute.Options) != 3 {
+		t.Fatal("attribute should have been updated")
+	}
+
+	found := false
+	if result := <-store.ProfileAttribute().GetAll(true); result.Err != nil {
+		t.Fatal(result.Err)
+	} else {
+		for _, rattribute := range result.Data.([]*model.ProfileAttribute) {
+			if rattribute.Id == attribute.Id {
+				found = true
+			}
+		}
+	}
+
+	if !found {
+		t.Fatal("attribute should have been returned")
+	}
+
+	Must(store.ProfileAttribute().Delete(attribute.Id))
+
+	if result := <-store.ProfileAttribute().Delete(attribute.Id); result.Err == nil {
+		t.Fatal("shouldn't have deleted a missing attribute")
+	}
+
+	if result := <-store.ProfileAttribute().GetAll(true); result.Err != nil {
+		t.Fatal(result.Err)
+	} else {
+		for _, rattribute := range result.Data.([]*model.ProfileAttribute) {
+			if rattribute.Id == attribute.Id {
+				t.Fatal("cache should have been cleared")
+			}
+		}
+	}
+}
@@ -0,0 +1,233 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package store
+
+import (
+	"github.com/mattermost/platform/model"
+)
+
+type SqlReminderStore struct {
+	*SqlStore
+}
+
+func NewSqlReminderStore(sqlStore *SqlStore) ReminderStore {
+	s := &SqlReminderStore{sqlStore}
+
+	for _, db := range sqlStore.GetAllConns() {
+		table := db.AddTableWithName(model.Reminder{}, "Reminders").SetKeys(false, "Id")
+		table.ColMap("Id").SetMaxSize(26)
+		table.ColMap("UserId").SetMaxSize(26)
+		table.ColMap("TeamId").SetMaxSize(26)
+		table.ColMap("PostId").SetMaxSize(26)
+		table.ColMap("Message").SetMaxSize(model.REMINDER_MESSAGE_MAX_RUNES)
+	}
+
+	return s
+}
+
+func (s SqlReminderStore) CreateIndexesIfNotExists() {
+	s.CreateIndexIfNotExists("idx_reminders_user_id", "Reminders", "UserId")
+	s.CreateIndexIfNotExists("idx_reminders_remind_at", "Reminders", "RemindAt")
+	s.CreateIndexIfNotExists("idx_reminders_fired_at", "Reminders", "FiredAt")
+}
+
+func (s SqlReminderStore) Save(reminder *model.Reminder) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		reminder.PreSave()
+		if result.Err = reminder.IsValid(); result.Err != nil {
+			storeChannel <- result
+			close(storeChannel)
+			return
+		}
+
+		if err := s.GetMaster().Insert(reminder); err != nil {
+			result.Err = model.NewLocAppError("SqlReminderStore.Save", "store.sql_reminder.save.app_error", nil, "id="+reminder.Id+", "+err.Error())
+		} else {
+			result.Data = reminder
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
+func (s SqlReminderStore) Update(reminder *model.Reminder) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		if result.Err = reminder.IsValid(); result.Err != nil {
+			storeChannel <- result
+			close(storeChannel)
+			return
+		}
+
+		if count, err := s.GetMaster().Update(reminder); err != nil {
+			result.Err = model.NewLocAppError("SqlReminderStore.Update", "store.sql_reminder.update.app_error", nil, "id="+reminder.Id+", "+err.Error())
+		} else if count != 1 {
+			result.Err = model.NewLocAppError("SqlReminderStore.Update", "store.sql_reminder.update.app_error", nil, "id="+reminder.Id)
+		} else {
+			result.Data = reminder
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
+func (s SqlReminderStore) Get(id string) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		var reminder model.Reminder
+		if err := s.GetReplica().SelectOne(&reminder, "SELECT * FROM Reminders WHERE Id = :Id", map[string]interface{}{"Id": id}); err != nil {
+			result.Err = model.NewLocAppError("SqlReminderStore.Get", "store.sql_reminder.get.app_error", nil, "id="+id+", "+err.Error())
+		} else {
+			result.Data = &reminder
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
+// GetForUser returns the user's reminders, including the ones that have recently fired, in the order that
+// they're due
+func (s SqlReminderStore) GetForUser(userId string) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		var reminders []*model.Reminder
+		if _, err := s.GetReplica().Select(&reminders, "SELECT * FROM Reminders WHERE UserId = :UserId ORDER BY RemindAt", map[string]interface{}{"UserId": userId}); err != nil {
+			result.Err = model.NewLocAppError("SqlReminderStore.GetForUser", "store.sql_reminder.get_for_user.app_error", nil, "user_id="+userId+", "+err.Error())
+		} else {
+			result.Data = reminders
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
+// GetDue returns the reminders that haven't fired yet and are due at or before the given time, oldest first
+func (s SqlReminderStore) GetDue(before int64, limit int) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		var reminders []*model.Reminder
+		if _, err := s.GetMaster().Select(&reminders,
+			`SELECT
+				*
+			FROM
+				Reminders
+			WHERE
+				FiredAt = 0
+				AND RemindAt <= :Before
+			ORDER BY RemindAt
+			LIMIT :Limit`, map[string]interface{}{"Before": before, "Limit": limit}); err != nil {
+			result.Err = model.NewLocAppError("SqlReminderStore.GetDue", "store.sql_reminder.get_due.app_error", nil, err.Error())
+		} else {
+			result.Data = reminders
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
+// MarkFired records that the reminder was sent. The data is false if it had already fired or was snoozed in
+// the meantime, in which case it shouldn't be sent again.
+func (s SqlReminderStore) MarkFired(id string, firedAt int64) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		if sqlResult, err := s.GetMaster().Exec("UPDATE Reminders SET FiredAt = :FiredAt WHERE Id = :Id AND FiredAt = 0 AND RemindAt <= :FiredAt", map[string]interface{}{"Id": id, "FiredAt": firedAt}); err != nil {
+			result.Err = model.NewLocAppError("SqlReminderStore.MarkFired", "store.sql_reminder.mark_fired.app_error", nil, "id="+id+", "+err.Error())
+		} else if count, err := sqlResult.RowsAffected(); err != nil {
+			result.Err = model.NewLocAppError("SqlReminderStore.MarkFired", "store.sql_reminder.mark_fired.app_error", nil, "id="+id+", "+err.Error())
+		} else {
+			result.Data = count == 1
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
+func (s SqlReminderStore) Delete(id string) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		if _, err := s.GetMaster().Exec("DELETE FROM Reminders WHERE Id = :Id", map[string]interface{}{"Id": id}); err != nil {
+			result.Err = model.NewLocAppError("SqlReminderStore.Delete", "store.sql_reminder.delete.app_error", nil, "id="+id+", "+err.Error())
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
+// PermanentDeleteFiredBefore deletes the reminders that fired before the given time and weren't snoozed
+func (s SqlReminderStore) PermanentDeleteFiredBefore(before int64) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		if _, err := s.GetMaster().Exec("DELETE FROM Reminders WHERE FiredAt != 0 AND FiredAt < :Before", map[string]interface{}{"Before": before}); err != nil {
+			result.Err = model.NewLocAppError("SqlReminderStore.PermanentDeleteFiredBefore", "store.sql_reminder.permanent_delete_fired_before.app_error", nil, err.Error())
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
+func (s SqlReminderStore) PermanentDeleteByUser(userId string) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		if _, err := s.GetMaster().Exec("DELETE FROM Reminders WHERE UserId = :UserId", map[string]interface{}{"UserId": userId}); err != nil {
+			result.Err = model.NewLocAppError("SqlReminderStore.PermanentDeleteByUser", "store.sql_reminder.permanent_delete_by_user.app_error", nil, "user_id="+userId+", "+err.Error())
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
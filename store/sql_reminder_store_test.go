@@ -0,0 +1,85 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package store
+
+import (
+	"testing"
+
+	"github.com/mattermost/platform/model"
+)
+
+func TestReminderStore(t *testing.T) {
+	Setup()
+
+	userId := model.NewId()
+	teamId := model.NewId()
+
+	r1 := Must(store.Reminder().Save(&model.Reminder{UserId: userId, TeamId: teamId, Message: "first", RemindAt: 1000})).(*model.Reminder)
+	r2 := Must(store.Reminder().Save(&model.Reminder{UserId: userId, TeamId: teamId, PostId: model.NewId(), RemindAt: 3000})).(*model.Reminder)
+	r3 := Must(store.Reminder().Save(&model.Reminder{UserId: model.NewId(), TeamId: teamId, Message: "other", RemindAt: 2000})).(*model.Reminder)
+
+	if result := <-store.Reminder().Save(&model.Reminder{UserId: userId, TeamId: teamId, RemindAt: 1000}); result.Err == nil {
+		t.Fatal("should have failed to save a reminder without a message or a post")
+	}
+
+	if reminder := Must(store.Reminder().Get(r1.Id)).(*model.Reminder); reminder.Message != r1.Message {
+		t.Fatal("should have returned the reminder", reminder)
+	}
+
+	if reminders := Must(store.Reminder().GetForUser(userId)).([]*model.Reminder); len(reminders) != 2 || reminders[0].Id != r1.Id || reminders[1].Id != r2.Id {
+		t.Fatal("should have returned the user's reminders in order", reminders)
+	}
+
+	due := Must(store.Reminder().GetDue(2000, 10)).([]*model.Reminder)
+	if len(due) != 2 || due[0].Id != r1.Id || due[1].Id != r3.Id {
+		t.Fatal("should have returned the due reminders", due)
+	}
+
+	if fired := Must(store.Reminder().MarkFired(r1.Id, 2000)).(bool); !fired {
+		t.Fatal("should have marked the reminder as fired")
+	}
+
+	if fired := Must(store.Reminder().MarkFired(r1.Id, 2001)).(bool); fired {
+		t.Fatal("shouldn't have fired the reminder twice")
+	}
+
+	if fired := Must(store.Reminder().MarkFired(r2.Id, 2000)).(bool); fired {
+		t.Fatal("shouldn't have fired a reminder that isn't due")
+	}
+
+	if due := Must(store.Reminder().GetDue(2000, 10)).([]*model.Reminder); len(due) != 1 || due[0].Id != r3.Id {
+		t.Fatal("shouldn't have returned the fired reminder", due)
+	}
+
+	r1 = Must(store.Reminder().Get(r1.Id)).(*model.Reminder)
+	r1.Snooze(4000)
+	Must(store.Reminder().Update(r1))
+
+	if reminder := Must(store.Reminder().Get(r1.Id)).(*model.Reminder); reminder.RemindAt != 4000 || reminder.FiredAt != 0 {
+		t.Fatal("should have snoozed the reminder", reminder)
+	}
+
+	Must(store.Reminder().MarkFired(r3.Id, 2000))
+	Must(store.Reminder().PermanentDeleteFiredBefore(2001))
+
+	if result := <-store.Reminder().Get(r3.Id); result.Err == nil {
+		t.Fatal("should have deleted the fired reminder")
+	}
+
+	if result := <-store.Reminder().Get(r1.Id); result.Err != nil {
+		t.Fatal("shouldn't have deleted the snoozed reminder")
+	}
+
+	Must(store.Reminder().Delete(r2.Id))
+
+	if reminders := Must(store.Reminder().GetForUser(userId)).([]*model.Reminder); len(reminders) != 1 || reminders[0].Id != r1.Id {
+		t.Fatal("should have deleted the reminder", reminders)
+	}
+
+	Must(store.Reminder().PermanentDeleteByUser(userId))
+
+	if reminders := Must(store.Reminder().GetForUser(userId)).([]*model.Reminder); len(reminders) != 0 {
+		t.Fatal("should have deleted the user's reminders", reminders)
+	}
+}
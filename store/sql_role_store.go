@@ -0,0 +1,167 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package store
+
+import (
+	"github.com/mattermost/platform/einterfaces"
+	"github.com/mattermost/platform/model"
+	"github.com/mattermost/platform/utils"
+)
+
+const (
+	ROLES_CACHE_SIZE = 1
+	ROLES_CACHE_SEC  = 900 // 15 mins
+	ROLES_CACHE_KEY  = "roles"
+)
+
+type SqlRoleStore struct {
+	*SqlStore
+}
+
+var rolesCache = utils.NewLru(ROLES_CACHE_SIZE)
+
+func ClearRoleCaches() {
+	rolesCache.Purge()
+}
+
+func NewSqlRoleStore(sqlStore *SqlStore) RoleStore {
+	s := &SqlRoleStore{sqlStore}
+
+	for _, db := range sqlStore.GetAllConns() {
+		table := db.AddTableWithName(model.Role{}, "Roles").SetKeys(false, "Id")
+		table.ColMap("Id").SetMaxSize(64)
+		table.ColMap("Name").SetMaxSize(128)
+		table.ColMap("Description").SetMaxSize(1024)
+		table.ColMap("Permissions").SetMaxSize(4096)
+	}
+
+	return s
+}
+
+func (s SqlRoleStore) CreateIndexesIfNotExists() {
+}
+
+func (s SqlRoleStore) Save(role *model.Role) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		role.PreSave()
+		if result.Err = role.IsValid(); result.Err != nil {
+			storeChannel <- result
+			close(storeChannel)
+			return
+		}
+
+		if count, err := s.GetMaster().SelectInt("SELECT COUNT(*) FROM Roles WHERE Id = :Id", map[string]interface{}{"Id": role.Id}); err != nil {
+			result.Err = model.NewLocAppError("SqlRoleStore.Save", "store.sql_role.save.app_error", nil, "id="+role.Id+", "+err.Error())
+		} else if count > 0 {
+			if _, err := s.GetMaster().Update(role); err != nil {
+				result.Err = model.NewLocAppError("SqlRoleStore.Save", "store.sql_role.save.app_error", nil, "id="+role.Id+", "+err.Error())
+			} else {
+				result.Data = role
+			}
+		} else {
+			if err := s.GetMaster().Insert(role); err != nil {
+				result.Err = model.NewLocAppError("SqlRoleStore.Save", "store.sql_role.save.app_error", nil, "id="+role.Id+", "+err.Error())
+			} else {
+				result.Data = role
+			}
+		}
+
+		rolesCache.Purge()
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
+func (s SqlRoleStore) Get(roleId string) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		var role model.Role
+		if err := s.GetReplica().SelectOne(&role, "SELECT * FROM Roles WHERE Id = :Id", map[string]interface{}{"Id": roleId}); err != nil {
+			result.Err = model.NewLocAppError("SqlRoleStore.Get", "store.sql_role.get.app_error", nil, "id="+roleId+", "+err.Error())
+		} else {
+			result.Data = &role
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
+// GetAll returns every role that has been saved to the database. Roles that have never been
+// saved are not returned and should fall back to their built in defaults.
+func (s SqlRoleStore) GetAll(allowFromCache bool) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+	metrics := einterfaces.GetMetricsInterface()
+
+	go func() {
+		result := StoreResult{}
+
+		if allowFromCache {
+			if cacheItem, ok := rolesCache.Get(ROLES_CACHE_KEY); ok {
+				if metrics != nil {
+					metrics.IncrementMemCacheHitCounter("Roles")
+				}
+				result.Data = cacheItem.([]*model.Role)
+				storeChannel <- result
+				close(storeChannel)
+				return
+			} else {
+				if metrics != nil {
+					metrics.IncrementMemCacheMissCounter("Roles")
+				}
+			}
+		} else {
+			if metrics != nil {
+				metrics.IncrementMemCacheMissCounter("Roles")
+			}
+		}
+
+		var roles []*model.Role
+		if _, err := s.GetReplica().Select(&roles, "SELECT * FROM Roles"); err != nil {
+			result.Err = model.NewLocAppError("SqlRoleStore.GetAll", "store.sql_role.get_all.app_error", nil, err.Error())
+		} else {
+			result.Data = roles
+
+			if allowFromCache {
+				rolesCache.AddWithExpiresInSecs(ROLES_CACHE_KEY, roles, ROLES_CACHE_SEC)
+			}
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
+func (s SqlRoleStore) Delete(roleId string) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		if _, err := s.GetMaster().Exec("DELETE FROM Roles WHERE Id = :Id", map[string]interface{}{"Id": roleId}); err != nil {
+			result.Err = model.NewLocAppError("SqlRoleStore.Delete", "store.sql_role.delete.app_error", nil, "id="+roleId+", "+err.Error())
+		}
+
+		rolesCache.Purge()
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
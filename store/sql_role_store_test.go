@@ -0,0 +1,66 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package store
+
+import (
+	"testing"
+
+	"github.com/mattermost/platform/model"
+)
+
+func TestRoleStoreSaveGetDelete(t *testing.T) {
+	Setup()
+
+	role := &model.Role{
+		Id:          model.ROLE_CHANNEL_ADMIN.Id,
+		Name:        model.ROLE_CHANNEL_ADMIN.Name,
+		Permissions: []string{model.PERMISSION_MANAGE_CHANNEL_ROLES.Id},
+	}
+
+	Must(store.Role().Save(role))
+
+	role.Permissions = append(role.Permissions, model.PERMISSION_DELETE_PUBLIC_CHANNEL.Id)
+	Must(store.Role().Save(role))
+
+	if result := <-store.Role().Get(role.Id); result.Err != nil {
+		t.Fatal(result.Err)
+	} else if rrole := result.Data.(*model.Role); len(rrole.Permissions) != 2 {
+		t.Fatal("role should have been updated")
+	}
+
+	if result := <-store.Role().GetAll(true); result.Err != nil {
+		t.Fatal(result.Err)
+	} else {
+		found := false
+		for _, rrole := range result.Data.([]*model.Role) {
+			if rrole.Id == role.Id {
+				found = true
+			}
+		}
+
+		if !found {
+			t.Fatal("role should have been returned")
+		}
+	}
+
+	if result := <-store.Role().Save(&model.Role{Id: "junk"}); result.Err == nil {
+		t.Fatal("should have failed, not a built in role")
+	}
+
+	Must(store.Role().Delete(role.Id))
+
+	if result := <-store.Role().Get(role.Id); result.Err == nil {
+		t.Fatal("role should have been deleted")
+	}
+
+	if result := <-store.Role().GetAll(true); result.Err != nil {
+		t.Fatal(result.Err)
+	} else {
+		for _, rrole := range result.Data.([]*model.Role) {
+			if rrole.Id == role.Id {
+				t.Fatal("cache should have been cleared")
+			}
+		}
+	}
+}
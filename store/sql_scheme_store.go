@@ -0,0 +1,171 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package store
+
+import (
+	"github.com/mattermost/platform/einterfaces"
+	"github.com/mattermost/platform/model"
+	"github.com/mattermost/platform/utils"
+)
+
+const (
+	SCHEMES_CACHE_SIZE = 1
+	SCHEMES_CACHE_SEC  = 900 // 15 mins
+	SCHEMES_CACHE_KEY  = "schemes"
+)
+
+type SqlSchemeStore struct {
+	*SqlStore
+}
+
+var schemesCache = utils.NewLru(SCHEMES_CACHE_SIZE)
+
+func ClearSchemeCaches() {
+	schemesCache.Purge()
+}
+
+func NewSqlSchemeStore(sqlStore *SqlStore) SchemeStore {
+	s := &SqlSchemeStore{sqlStore}
+
+	for _, db := range sqlStore.GetAllConns() {
+		table := db.AddTableWithName(model.Scheme{}, "Schemes").SetKeys(false, "TeamId")
+		table.ColMap("TeamId").SetMaxSize(26)
+		table.ColMap("Name").SetMaxSize(64)
+		table.ColMap("Description").SetMaxSize(1024)
+		table.ColMap("Roles").SetMaxSize(8192)
+	}
+
+	return s
+}
+
+func (s SqlSchemeStore) CreateIndexesIfNotExists() {
+}
+
+func (s SqlSchemeStore) Save(scheme *model.Scheme) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		scheme.PreSave()
+		if result.Err = scheme.IsValid(); result.Err != nil {
+			storeChannel <- result
+			close(storeChannel)
+			return
+		}
+
+		if count, err := s.GetMaster().SelectInt("SELECT COUNT(*) FROM Schemes WHERE TeamId = :TeamId", map[string]interface{}{"TeamId": scheme.TeamId}); err != nil {
+			result.Err = model.NewLocAppError("SqlSchemeStore.Save", "store.sql_scheme.save.app_error", nil, "team_id="+scheme.TeamId+", "+err.Error())
+		} else if count > 0 {
+			if _, err := s.GetMaster().Update(scheme); err != nil {
+				result.Err = model.NewLocAppError("SqlSchemeStore.Save", "store.sql_scheme.save.app_error", nil, "team_id="+scheme.TeamId+", "+err.Error())
+			} else {
+				result.Data = scheme
+			}
+		} else {
+			if err := s.GetMaster().Insert(scheme); err != nil {
+				result.Err = model.NewLocAppError("SqlSchemeStore.Save", "store.sql_scheme.save.app_error", nil, "team_id="+scheme.TeamId+", "+err.Error())
+			} else {
+				result.Data = scheme
+			}
+		}
+
+		schemesCache.Purge()
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
+func (s SqlSchemeStore) Get(teamId string) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		var scheme model.Scheme
+		if err := s.GetReplica().SelectOne(&scheme, "SELECT * FROM Schemes WHERE TeamId = :TeamId", map[string]interface{}{"TeamId": teamId}); err != nil {
+			result.Err = model.NewLocAppError("SqlSchemeStore.Get", "store.sql_scheme.get.app_error", nil, "team_id="+teamId+", "+err.Error())
+		} else {
+			result.Data = &scheme
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
+// GetAll returns the schemes of all teams keyed by team id.
+func (s SqlSchemeStore) GetAll(allowFromCache bool) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+	metrics := einterfaces.GetMetricsInterface()
+
+	go func() {
+		result := StoreResult{}
+
+		if allowFromCache {
+			if cacheItem, ok := schemesCache.Get(SCHEMES_CACHE_KEY); ok {
+				if metrics != nil {
+					metrics.IncrementMemCacheHitCounter("Schemes")
+				}
+				result.Data = cacheItem.(map[string]*model.Scheme)
+				storeChannel <- result
+				close(storeChannel)
+				return
+			} else {
+				if metrics != nil {
+					metrics.IncrementMemCacheMissCounter("Schemes")
+				}
+			}
+		} else {
+			if metrics != nil {
+				metrics.IncrementMemCacheMissCounter("Schemes")
+			}
+		}
+
+		var schemes []*model.Scheme
+		if _, err := s.GetReplica().Select(&schemes, "SELECT * FROM Schemes"); err != nil {
+			result.Err = model.NewLocAppError("SqlSchemeStore.GetAll", "store.sql_scheme.get_all.app_error", nil, err.Error())
+		} else {
+			schemesByTeam := make(map[string]*model.Scheme)
+			for _, scheme := range schemes {
+				schemesByTeam[scheme.TeamId] = scheme
+			}
+
+			result.Data = schemesByTeam
+
+			if allowFromCache {
+				schemesCache.AddWithExpiresInSecs(SCHEMES_CACHE_KEY, schemesByTeam, SCHEMES_CACHE_SEC)
+			}
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
+func (s SqlSchemeStore) Delete(teamId string) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		if _, err := s.GetMaster().Exec("DELETE FROM Schemes WHERE TeamId = :TeamId", map[string]interface{}{"TeamId": teamId}); err != nil {
+			result.Err = model.NewLocAppError("SqlSchemeStore.Delete", "store.sql_scheme.delete.app_error", nil, "team_id="+teamId+", "+err.Error())
+		}
+
+		schemesCache.Purge()
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
@@ -0,0 +1,49 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package store
+
+import (
+	"testing"
+
+	"github.com/mattermost/platform/model"
+)
+
+func TestSchemeStoreSaveGetDelete(t *testing.T) {
+	Setup()
+
+	scheme := &model.Scheme{
+		TeamId: model.NewId(),
+		Name:   "name",
+		Roles:  map[string]string{model.ROLE_TEAM_USER.Id: model.PERMISSION_CREATE_POST.Id},
+	}
+
+	Must(store.Scheme().Save(scheme))
+
+	scheme.Name = "name2"
+	Must(store.Scheme().Save(scheme))
+
+	if result := <-store.Scheme().Get(scheme.TeamId); result.Err != nil {
+		t.Fatal(result.Err)
+	} else if rscheme := result.Data.(*model.Scheme); rscheme.Name != "name2" || rscheme.Roles[model.ROLE_TEAM_USER.Id] != model.PERMISSION_CREATE_POST.Id {
+		t.Fatal("scheme should have been updated")
+	}
+
+	if result := <-store.Scheme().GetAll(true); result.Err != nil {
+		t.Fatal(result.Err)
+	} else if _, ok := result.Data.(map[string]*model.Scheme)[scheme.TeamId]; !ok {
+		t.Fatal("scheme should have been returned")
+	}
+
+	Must(store.Scheme().Delete(scheme.TeamId))
+
+	if result := <-store.Scheme().Get(scheme.TeamId); result.Err == nil {
+		t.Fatal("scheme should have been deleted")
+	}
+
+	if result := <-store.Scheme().GetAll(true); result.Err != nil {
+		t.Fatal(result.Err)
+	} else if _, ok := result.Data.(map[string]*model.Scheme)[scheme.TeamId]; ok {
+		t.Fatal("cache should have been cleared")
+	}
+}
@@ -22,6 +22,7 @@ func NewSqlSessionStore(sqlStore *SqlStore) SessionStore {
 		table.ColMap("Token").SetMaxSize(26)
 		table.ColMap("UserId").SetMaxSize(26)
 		table.ColMap("DeviceId").SetMaxSize(512)
+		table.ColMap("DeviceKey").SetMaxSize(512)
 		table.ColMap("Roles").SetMaxSize(64)
 		table.ColMap("Props").SetMaxSize(1000)
 	}
@@ -300,12 +301,12 @@ func (me SqlSessionStore) UpdateRoles(userId, roles string) StoreChannel {
 	return storeChannel
 }
 
-func (me SqlSessionStore) UpdateDeviceId(id string, deviceId string, expiresAt int64) StoreChannel {
+func (me SqlSessionStore) UpdateDeviceId(id string, deviceId string, deviceKey string, expiresAt int64) StoreChannel {
 	storeChannel := make(StoreChannel, 1)
 
 	go func() {
 		result := StoreResult{}
-		if _, err := me.GetMaster().Exec("UPDATE Sessions SET DeviceId = :DeviceId, ExpiresAt = :ExpiresAt WHERE Id = :Id", map[string]interface{}{"DeviceId": deviceId, "Id": id, "ExpiresAt": expiresAt}); err != nil {
+		if _, err := me.GetMaster().Exec("UPDATE Sessions SET DeviceId = :DeviceId, DeviceKey = :DeviceKey, ExpiresAt = :ExpiresAt WHERE Id = :Id", map[string]interface{}{"DeviceId": deviceId, "DeviceKey": deviceKey, "Id": id, "ExpiresAt": expiresAt}); err != nil {
 			result.Err = model.NewLocAppError("SqlSessionStore.UpdateDeviceId", "store.sql_session.update_device_id.app_error", nil, err.Error())
 		} else {
 			result.Data = deviceId
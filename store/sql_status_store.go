@@ -26,6 +26,9 @@ func NewSqlStatusStore(sqlStore *SqlStore) StatusStore {
 		table.ColMap("UserId").SetMaxSize(26)
 		table.ColMap("Status").SetMaxSize(32)
 		table.ColMap("ActiveChannel").SetMaxSize(26)
+		table.ColMap("PrevStatus").SetMaxSize(32)
+		table.ColMap("CustomEmoji").SetMaxSize(model.CUSTOM_STATUS_EMOJI_MAX_LENGTH)
+		table.ColMap("CustomText").SetMaxSize(model.CUSTOM_STATUS_TEXT_MAX_RUNES)
 	}
 
 	return s
@@ -129,7 +132,7 @@ func (s SqlStatusStore) GetOnlineAway() StoreChannel {
 		result := StoreResult{}
 
 		var statuses []*model.Status
-		if _, err := s.GetReplica().Select(&statuses, "SELECT * FROM Status WHERE Status = :Online OR Status = :Away LIMIT 300", map[string]interface{}{"Online": model.STATUS_ONLINE, "Away": model.STATUS_AWAY}); err != nil {
+		if _, err := s.GetReplica().Select(&statuses, "SELECT * FROM Status WHERE Status = :Online OR Status = :Away OR Status = :DND LIMIT 300", map[string]interface{}{"Online": model.STATUS_ONLINE, "Away": model.STATUS_AWAY, "DND": model.STATUS_DND}); err != nil {
 			result.Err = model.NewLocAppError("SqlStatusStore.GetOnlineAway", "store.sql_status.get_online_away.app_error", nil, err.Error())
 		} else {
 			result.Data = statuses
@@ -184,6 +187,46 @@ func (s SqlStatusStore) GetAllFromTeam(teamId string) StoreChannel {
 	return storeChannel
 }
 
+func (s SqlStatusStore) GetExpiredDND(time int64) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		var statuses []*model.Status
+		if _, err := s.GetReplica().Select(&statuses, "SELECT * FROM Status WHERE Status = :DND AND DNDEndTime > 0 AND DNDEndTime <= :Time", map[string]interface{}{"DND": model.STATUS_DND, "Time": time}); err != nil {
+			result.Err = model.NewLocAppError("SqlStatusStore.GetExpiredDND", "store.sql_status.get_expired_dnd.app_error", nil, err.Error())
+		} else {
+			result.Data = statuses
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
+func (s SqlStatusStore) GetExpiredCustom(time int64) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		var statuses []*model.Status
+		if _, err := s.GetReplica().Select(&statuses, "SELECT * FROM Status WHERE CustomExpiresAt > 0 AND CustomExpiresAt <= :Time", map[string]interface{}{"Time": time}); err != nil {
+			result.Err = model.NewLocAppError("SqlStatusStore.GetExpiredCustom", "store.sql_status.get_expired_custom.app_error", nil, err.Error())
+		} else {
+			result.Data = statuses
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
 func (s SqlStatusStore) ResetAll() StoreChannel {
 	storeChannel := make(StoreChannel, 1)
 
@@ -12,7 +12,7 @@ import (
 func TestSqlStatusStore(t *testing.T) {
 	Setup()
 
-	status := &model.Status{model.NewId(), model.STATUS_ONLINE, false, 0, ""}
+	status := &model.Status{UserId: model.NewId(), Status: model.STATUS_ONLINE}
 
 	if err := (<-store.Status().SaveOrUpdate(status)).Err; err != nil {
 		t.Fatal(err)
@@ -28,16 +28,71 @@ func TestSqlStatusStore(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	status2 := &model.Status{model.NewId(), model.STATUS_AWAY, false, 0, ""}
+	status2 := &model.Status{UserId: model.NewId(), Status: model.STATUS_AWAY}
 	if err := (<-store.Status().SaveOrUpdate(status2)).Err; err != nil {
 		t.Fatal(err)
 	}
 
-	status3 := &model.Status{model.NewId(), model.STATUS_OFFLINE, false, 0, ""}
+	status3 := &model.Status{UserId: model.NewId(), Status: model.STATUS_OFFLINE}
 	if err := (<-store.Status().SaveOrUpdate(status3)).Err; err != nil {
 		t.Fatal(err)
 	}
 
+	status4 := &model.Status{UserId: model.NewId(), Status: model.STATUS_DND, Manual: true, DNDEndTime: model.GetMillis() - 1000, PrevStatus: model.STATUS_ONLINE}
+	if err := (<-store.Status().SaveOrUpdate(status4)).Err; err != nil {
+		t.Fatal(err)
+	}
+
+	status5 := &model.Status{UserId: model.NewId(), Status: model.STATUS_DND, Manual: true, PrevStatus: model.STATUS_AWAY}
+	if err := (<-store.Status().SaveOrUpdate(status5)).Err; err != nil {
+		t.Fatal(err)
+	}
+
+	if result := <-store.Status().GetExpiredDND(model.GetMillis()); result.Err != nil {
+		t.Fatal(result.Err)
+	} else {
+		found := false
+		for _, status := range result.Data.([]*model.Status) {
+			if status.UserId == status4.UserId {
+				found = true
+				if status.PrevStatus != model.STATUS_ONLINE {
+					t.Fatal("should have saved the previous status")
+				}
+			} else if status.UserId == status5.UserId {
+				t.Fatal("should not have returned a status without an end time")
+			}
+		}
+
+		if !found {
+			t.Fatal("should have returned the expired status")
+		}
+	}
+
+	status6 := &model.Status{UserId: model.NewId(), Status: model.STATUS_ONLINE, CustomEmoji: "calendar", CustomText: "In a meeting", CustomExpiresAt: model.GetMillis() - 1000}
+	if err := (<-store.Status().SaveOrUpdate(status6)).Err; err != nil {
+		t.Fatal(err)
+	}
+
+	if result := <-store.Status().GetExpiredCustom(model.GetMillis()); result.Err != nil {
+		t.Fatal(result.Err)
+	} else {
+		found := false
+		for _, status := range result.Data.([]*model.Status) {
+			if status.UserId == status6.UserId {
+				found = true
+				if status.CustomText != "In a meeting" {
+					t.Fatal("should have saved the custom status")
+				}
+			} else if status.CustomExpiresAt == 0 {
+				t.Fatal("should not have returned a custom status without an expiry")
+			}
+		}
+
+		if !found {
+			t.Fatal("should have returned the expired custom status")
+		}
+	}
+
 	if result := <-store.Status().GetOnlineAway(); result.Err != nil {
 		t.Fatal(result.Err)
 	} else {
@@ -90,7 +145,7 @@ func TestSqlStatusStore(t *testing.T) {
 func TestActiveUserCount(t *testing.T) {
 	Setup()
 
-	status := &model.Status{model.NewId(), model.STATUS_ONLINE, false, model.GetMillis(), ""}
+	status := &model.Status{UserId: model.NewId(), Status: model.STATUS_ONLINE, LastActivityAt: model.GetMillis()}
 	Must(store.Status().SaveOrUpdate(status))
 
 	if result := <-store.Status().GetTotalActiveUsersCount(); result.Err != nil {
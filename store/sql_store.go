@@ -65,28 +65,38 @@ const (
 )
 
 type SqlStore struct {
-	master        *gorp.DbMap
-	replicas      []*gorp.DbMap
-	team          TeamStore
-	channel       ChannelStore
-	post          PostStore
-	user          UserStore
-	audit         AuditStore
-	compliance    ComplianceStore
-	session       SessionStore
-	oauth         OAuthStore
-	system        SystemStore
-	webhook       WebhookStore
-	command       CommandStore
-	preference    PreferenceStore
-	license       LicenseStore
-	recovery      PasswordRecoveryStore
-	emoji         EmojiStore
-	status        StatusStore
-	fileInfo      FileInfoStore
-	reaction      ReactionStore
-	SchemaVersion string
-	rrCounter     int64
+	master           *gorp.DbMap
+	replicas         []*gorp.DbMap
+	team             TeamStore
+	channel          ChannelStore
+	post             PostStore
+	user             UserStore
+	audit            AuditStore
+	compliance       ComplianceStore
+	session          SessionStore
+	oauth            OAuthStore
+	system           SystemStore
+	webhook          WebhookStore
+	command          CommandStore
+	preference       PreferenceStore
+	license          LicenseStore
+	recovery         PasswordRecoveryStore
+	emoji            EmojiStore
+	status           StatusStore
+	fileInfo         FileInfoStore
+	reaction         ReactionStore
+	role             RoleStore
+	scheme           SchemeStore
+	webAuthn         WebAuthnCredentialStore
+	ldapGroupLink    LdapGroupLinkStore
+	profileAttribute ProfileAttributeStore
+	pendingEmail     PendingEmailNotificationStore
+	mailQueue        MailQueueStore
+	userGroup        UserGroupStore
+	threadMembership ThreadMembershipStore
+	reminder         ReminderStore
+	SchemaVersion    string
+	rrCounter        int64
 }
 
 func initConnection() *SqlStore {
@@ -136,6 +146,16 @@ func NewSqlStore() Store {
 	sqlStore.status = NewSqlStatusStore(sqlStore)
 	sqlStore.fileInfo = NewSqlFileInfoStore(sqlStore)
 	sqlStore.reaction = NewSqlReactionStore(sqlStore)
+	sqlStore.role = NewSqlRoleStore(sqlStore)
+	sqlStore.scheme = NewSqlSchemeStore(sqlStore)
+	sqlStore.webAuthn = NewSqlWebAuthnCredentialStore(sqlStore)
+	sqlStore.ldapGroupLink = NewSqlLdapGroupLinkStore(sqlStore)
+	sqlStore.profileAttribute = NewSqlProfileAttributeStore(sqlStore)
+	sqlStore.pendingEmail = NewSqlPendingEmailNotificationStore(sqlStore)
+	sqlStore.mailQueue = NewSqlMailQueueStore(sqlStore)
+	sqlStore.userGroup = NewSqlUserGroupStore(sqlStore)
+	sqlStore.threadMembership = NewSqlThreadMembershipStore(sqlStore)
+	sqlStore.reminder = NewSqlReminderStore(sqlStore)
 
 	err := sqlStore.master.CreateTablesIfNotExists()
 	if err != nil {
@@ -164,6 +184,16 @@ func NewSqlStore() Store {
 	sqlStore.status.(*SqlStatusStore).CreateIndexesIfNotExists()
 	sqlStore.fileInfo.(*SqlFileInfoStore).CreateIndexesIfNotExists()
 	sqlStore.reaction.(*SqlReactionStore).CreateIndexesIfNotExists()
+	sqlStore.role.(*SqlRoleStore).CreateIndexesIfNotExists()
+	sqlStore.scheme.(*SqlSchemeStore).CreateIndexesIfNotExists()
+	sqlStore.webAuthn.(*SqlWebAuthnCredentialStore).CreateIndexesIfNotExists()
+	sqlStore.ldapGroupLink.(*SqlLdapGroupLinkStore).CreateIndexesIfNotExists()
+	sqlStore.profileAttribute.(*SqlProfileAttributeStore).CreateIndexesIfNotExists()
+	sqlStore.pendingEmail.(*SqlPendingEmailNotificationStore).CreateIndexesIfNotExists()
+	sqlStore.mailQueue.(*SqlMailQueueStore).CreateIndexesIfNotExists()
+	sqlStore.userGroup.(*SqlUserGroupStore).CreateIndexesIfNotExists()
+	sqlStore.threadMembership.(*SqlThreadMembershipStore).CreateIndexesIfNotExists()
+	sqlStore.reminder.(*SqlReminderStore).CreateIndexesIfNotExists()
 
 	sqlStore.preference.(*SqlPreferenceStore).DeleteUnusedFeatures()
 
@@ -683,6 +713,46 @@ func (ss *SqlStore) Reaction() ReactionStore {
 	return ss.reaction
 }
 
+func (ss *SqlStore) Role() RoleStore {
+	return ss.role
+}
+
+func (ss *SqlStore) Scheme() SchemeStore {
+	return ss.scheme
+}
+
+func (ss *SqlStore) WebAuthn() WebAuthnCredentialStore {
+	return ss.webAuthn
+}
+
+func (ss *SqlStore) LdapGroupLink() LdapGroupLinkStore {
+	return ss.ldapGroupLink
+}
+
+func (ss *SqlStore) ProfileAttribute() ProfileAttributeStore {
+	return ss.profileAttribute
+}
+
+func (ss *SqlStore) PendingEmailNotification() PendingEmailNotificationStore {
+	return ss.pendingEmail
+}
+
+func (ss *SqlStore) MailQueue() MailQueueStore {
+	return ss.mailQueue
+}
+
+func (ss *SqlStore) UserGroup() UserGroupStore {
+	return ss.userGroup
+}
+
+func (ss *SqlStore) ThreadMembership() ThreadMembershipStore {
+	return ss.threadMembership
+}
+
+func (ss *SqlStore) Reminder() ReminderStore {
+	return ss.reminder
+}
+
 func (ss *SqlStore) DropAllTables() {
 	ss.master.TruncateTables()
 }
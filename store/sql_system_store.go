@@ -4,6 +4,9 @@
 package store
 
 import (
+	"strconv"
+	"strings"
+
 	"github.com/mattermost/platform/model"
 )
 
@@ -132,3 +135,48 @@ func (s SqlSystemStore) GetByName(name string) StoreChannel {
 
 	return storeChannel
 }
+
+// AcquireLease takes or renews a lease with the given name for duration milliseconds so that only one
+// server in a cluster holds it at a time. The lease is stored as the holder id and its expiry time and
+// is only taken if it's free, expired or already held by the holder. The result is true if it was acquired.
+func (s SqlSystemStore) AcquireLease(name string, holderId string, duration int64) StoreChannel {
+
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		now := model.GetMillis()
+		value := holderId + ":" + strconv.FormatInt(now+duration, 10)
+
+		var system model.System
+		if err := s.GetMaster().SelectOne(&system, "SELECT * FROM Systems WHERE Name = :Name", map[string]interface{}{"Name": name}); err != nil {
+			// nobody has held the lease yet so whoever inserts it first gets it
+			result.Data = s.GetMaster().Insert(&model.System{Name: name, Value: value}) == nil
+		} else {
+			parts := strings.SplitN(system.Value, ":", 2)
+
+			expireAt := int64(0)
+			if len(parts) == 2 {
+				expireAt, _ = strconv.ParseInt(parts[1], 10, 64)
+			}
+
+			if parts[0] != holderId && expireAt > now {
+				result.Data = false
+			} else if sqlResult, err := s.GetMaster().Exec("UPDATE Systems SET Value = :Value WHERE Name = :Name AND Value = :OldValue",
+				map[string]interface{}{"Name": name, "Value": value, "OldValue": system.Value}); err != nil {
+				result.Err = model.NewLocAppError("SqlSystemStore.AcquireLease", "store.sql_system.acquire_lease.app_error", nil, "name="+name+", "+err.Error())
+			} else if rows, err := sqlResult.RowsAffected(); err != nil {
+				result.Err = model.NewLocAppError("SqlSystemStore.AcquireLease", "store.sql_system.acquire_lease.app_error", nil, "name="+name+", "+err.Error())
+			} else {
+				// another server changed the lease first if nothing was updated
+				result.Data = rows == 1
+			}
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
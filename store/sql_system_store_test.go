@@ -54,3 +54,27 @@ func TestSqlSystemStoreSaveOrUpdate(t *testing.T) {
 		t.Fatal(r.Err)
 	}
 }
+
+func TestSqlSystemStoreAcquireLease(t *testing.T) {
+	Setup()
+
+	name := model.NewId()
+	holder1 := model.NewId()
+	holder2 := model.NewId()
+
+	if acquired := Must(store.System().AcquireLease(name, holder1, 60000)).(bool); !acquired {
+		t.Fatal("should have acquired a new lease")
+	}
+
+	if acquired := Must(store.System().AcquireLease(name, holder2, 60000)).(bool); acquired {
+		t.Fatal("shouldn't have acquired a lease held by someone else")
+	}
+
+	if acquired := Must(store.System().AcquireLease(name, holder1, -1)).(bool); !acquired {
+		t.Fatal("should have renewed the lease")
+	}
+
+	if acquired := Must(store.System().AcquireLease(name, holder2, 60000)).(bool); !acquired {
+		t.Fatal("should have acquired an expired lease")
+	}
+}
@@ -0,0 +1,236 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package store
+
+import (
+	"github.com/mattermost/platform/model"
+)
+
+type SqlThreadMembershipStore struct {
+	*SqlStore
+}
+
+func NewSqlThreadMembershipStore(sqlStore *SqlStore) ThreadMembershipStore {
+	s := &SqlThreadMembershipStore{sqlStore}
+
+	for _, db := range sqlStore.GetAllConns() {
+		table := db.AddTableWithName(model.ThreadMembership{}, "ThreadMemberships").SetKeys(false, "PostId", "UserId")
+		table.ColMap("PostId").SetMaxSize(26)
+		table.ColMap("UserId").SetMaxSize(26)
+		table.ColMap("ChannelId").SetMaxSize(26)
+	}
+
+	return s
+}
+
+func (s SqlThreadMembershipStore) CreateIndexesIfNotExists() {
+	s.CreateIndexIfNotExists("idx_threadmemberships_user_id", "ThreadMemberships", "UserId")
+	s.CreateIndexIfNotExists("idx_threadmemberships_last_reply_at", "ThreadMemberships", "LastReplyAt")
+}
+
+func (s SqlThreadMembershipStore) Save(membership *model.ThreadMembership) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		membership.PreSave()
+		if result.Err = membership.IsValid(); result.Err != nil {
+			storeChannel <- result
+			close(storeChannel)
+			return
+		}
+
+		if err := s.GetMaster().Insert(membership); err != nil {
+			if IsUniqueConstraintError(err.Error(), []string{"PostId", "threadmemberships_pkey", "PRIMARY"}) {
+				result.Err = model.NewLocAppError("SqlThreadMembershipStore.Save", "store.sql_thread_membership.save.exists.app_error", nil, "post_id="+membership.PostId+", user_id="+membership.UserId+", "+err.Error())
+			} else {
+				result.Err = model.NewLocAppError("SqlThreadMembershipStore.Save", "store.sql_thread_membership.save.app_error", nil, "post_id="+membership.PostId+", user_id="+membership.UserId+", "+err.Error())
+			}
+		} else {
+			result.Data = membership
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
+func (s SqlThreadMembershipStore) Update(membership *model.ThreadMembership) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		if result.Err = membership.IsValid(); result.Err != nil {
+			storeChannel <- result
+			close(storeChannel)
+			return
+		}
+
+		if _, err := s.GetMaster().Update(membership); err != nil {
+			result.Err = model.NewLocAppError("SqlThreadMembershipStore.Update", "store.sql_thread_membership.update.app_error", nil, "post_id="+membership.PostId+", user_id="+membership.UserId+", "+err.Error())
+		} else {
+			result.Data = membership
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
+func (s SqlThreadMembershipStore) Get(postId string, userId string) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		var membership model.ThreadMembership
+		if err := s.GetReplica().SelectOne(&membership, "SELECT * FROM ThreadMemberships WHERE PostId = :PostId AND UserId = :UserId", map[string]interface{}{"PostId": postId, "UserId": userId}); err != nil {
+			result.Err = model.NewLocAppError("SqlThreadMembershipStore.Get", "store.sql_thread_membership.get.app_error", nil, "post_id="+postId+", user_id="+userId+", "+err.Error())
+		} else {
+			result.Data = &membership
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
+func (s SqlThreadMembershipStore) GetForPost(postId string) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		var memberships []*model.ThreadMembership
+		if _, err := s.GetReplica().Select(&memberships, "SELECT * FROM ThreadMemberships WHERE PostId = :PostId", map[string]interface{}{"PostId": postId}); err != nil {
+			result.Err = model.NewLocAppError("SqlThreadMembershipStore.GetForPost", "store.sql_thread_membership.get_for_post.app_error", nil, "post_id="+postId+", "+err.Error())
+		} else {
+			result.Data = memberships
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
+// GetFollowedByUser returns the threads that the user follows in the channels of the team and their direct
+// channels, starting with the most recent replies
+func (s SqlThreadMembershipStore) GetFollowedByUser(userId string, teamId string, offset int, limit int) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		var memberships []*model.ThreadMembership
+		if _, err := s.GetReplica().Select(&memberships,
+			`SELECT
+				ThreadMemberships.*
+			FROM
+				ThreadMemberships, Channels
+			WHERE
+				ThreadMemberships.UserId = :UserId
+				AND ThreadMemberships.Following = :Following
+				AND Channels.Id = ThreadMemberships.ChannelId
+				AND Channels.DeleteAt = 0
+				AND (Channels.TeamId = :TeamId OR Channels.TeamId = '')
+			ORDER BY ThreadMemberships.LastReplyAt DESC
+			LIMIT :Limit OFFSET :Offset`, map[string]interface{}{"UserId": userId, "Following": true, "TeamId": teamId, "Limit": limit, "Offset": offset}); err != nil {
+			result.Err = model.NewLocAppError("SqlThreadMembershipStore.GetFollowedByUser", "store.sql_thread_membership.get_followed_by_user.app_error", nil, "user_id="+userId+", "+err.Error())
+		} else {
+			result.Data = memberships
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
+// IncrementUnreadReplies records a new reply to the thread, counting it as unread for everyone notified of
+// replies other than the user who made it
+func (s SqlThreadMembershipStore) IncrementUnreadReplies(postId string, userId string, replyAt int64) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		if _, err := s.GetMaster().Exec(
+			`UPDATE
+				ThreadMemberships
+			SET
+				LastReplyAt = :ReplyAt,
+				UnreadReplies = CASE WHEN Following = :Following AND Muted = :Muted AND UserId != :UserId THEN UnreadReplies + 1 ELSE UnreadReplies END
+			WHERE
+				PostId = :PostId`, map[string]interface{}{"PostId": postId, "UserId": userId, "ReplyAt": replyAt, "Following": true, "Muted": false}); err != nil {
+			result.Err = model.NewLocAppError("SqlThreadMembershipStore.IncrementUnreadReplies", "store.sql_thread_membership.increment_unread_replies.app_error", nil, "post_id="+postId+", "+err.Error())
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
+func (s SqlThreadMembershipStore) ViewThread(postId string, userId string, viewedAt int64) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		if _, err := s.GetMaster().Exec("UPDATE ThreadMemberships SET UnreadReplies = 0, LastViewedAt = :ViewedAt WHERE PostId = :PostId AND UserId = :UserId", map[string]interface{}{"PostId": postId, "UserId": userId, "ViewedAt": viewedAt}); err != nil {
+			result.Err = model.NewLocAppError("SqlThreadMembershipStore.ViewThread", "store.sql_thread_membership.view_thread.app_error", nil, "post_id="+postId+", user_id="+userId+", "+err.Error())
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
+func (s SqlThreadMembershipStore) PermanentDeleteByPost(postId string) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		if _, err := s.GetMaster().Exec("DELETE FROM ThreadMemberships WHERE PostId = :PostId", map[string]interface{}{"PostId": postId}); err != nil {
+			result.Err = model.NewLocAppError("SqlThreadMembershipStore.PermanentDeleteByPost", "store.sql_thread_membership.permanent_delete_by_post.app_error", nil, "post_id="+postId+", "+err.Error())
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
+func (s SqlThreadMembershipStore) PermanentDeleteByUser(userId string) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		if _, err := s.GetMaster().Exec("DELETE FROM ThreadMemberships WHERE UserId = :UserId", map[string]interface{}{"UserId": userId}); err != nil {
+			result.Err = model.NewLocAppError("SqlThreadMembershipStore.PermanentDeleteByUser", "store.sql_thread_membership.permanent_delete_by_user.app_error", nil, "user_id="+userId+", "+err.Error())
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
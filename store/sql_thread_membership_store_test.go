@@ -0,0 +1,85 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package store
+
+import (
+	"testing"
+
+	"github.com/mattermost/platform/model"
+)
+
+func TestThreadMembershipStore(t *testing.T) {
+	Setup()
+
+	teamId := model.NewId()
+	channel := &model.Channel{TeamId: teamId, DisplayName: "Name", Name: "a" + model.NewId() + "b", Type: model.CHANNEL_OPEN}
+	Must(store.Channel().Save(channel))
+
+	postId := model.NewId()
+	userId1 := model.NewId()
+	userId2 := model.NewId()
+	userId3 := model.NewId()
+
+	m1 := &model.ThreadMembership{PostId: postId, UserId: userId1, ChannelId: channel.Id, Following: true}
+	Must(store.ThreadMembership().Save(m1))
+
+	if result := <-store.ThreadMembership().Save(&model.ThreadMembership{PostId: postId, UserId: userId1, ChannelId: channel.Id}); result.Err == nil {
+		t.Fatal("should have failed to save the membership twice")
+	}
+
+	Must(store.ThreadMembership().Save(&model.ThreadMembership{PostId: postId, UserId: userId2, ChannelId: channel.Id, Following: true, Muted: true}))
+	Must(store.ThreadMembership().Save(&model.ThreadMembership{PostId: postId, UserId: userId3, ChannelId: channel.Id, Following: true}))
+
+	if memberships := Must(store.ThreadMembership().GetForPost(postId)).([]*model.ThreadMembership); len(memberships) != 3 {
+		t.Fatal("should have returned all the memberships")
+	}
+
+	Must(store.ThreadMembership().IncrementUnreadReplies(postId, userId3, 1234))
+
+	if m := Must(store.ThreadMembership().Get(postId, userId1)).(*model.ThreadMembership); m.UnreadReplies != 1 || m.LastReplyAt != 1234 {
+		t.Fatal("should have counted the unread reply", m)
+	}
+
+	if m := Must(store.ThreadMembership().Get(postId, userId2)).(*model.ThreadMembership); m.UnreadReplies != 0 || m.LastReplyAt != 1234 {
+		t.Fatal("shouldn't have counted the reply for a muted thread", m)
+	}
+
+	if m := Must(store.ThreadMembership().Get(postId, userId3)).(*model.ThreadMembership); m.UnreadReplies != 0 {
+		t.Fatal("shouldn't have counted the reply for the user who made it", m)
+	}
+
+	Must(store.ThreadMembership().ViewThread(postId, userId1, 5678))
+
+	m1 = Must(store.ThreadMembership().Get(postId, userId1)).(*model.ThreadMembership)
+	if m1.UnreadReplies != 0 || m1.LastViewedAt != 5678 {
+		t.Fatal("should have viewed the thread", m1)
+	}
+
+	if memberships := Must(store.ThreadMembership().GetFollowedByUser(userId1, teamId, 0, 10)).([]*model.ThreadMembership); len(memberships) != 1 || memberships[0].PostId != postId {
+		t.Fatal("should have returned the followed thread")
+	}
+
+	if memberships := Must(store.ThreadMembership().GetFollowedByUser(userId1, model.NewId(), 0, 10)).([]*model.ThreadMembership); len(memberships) != 0 {
+		t.Fatal("shouldn't have returned threads on other teams")
+	}
+
+	m1.Following = false
+	Must(store.ThreadMembership().Update(m1))
+
+	if memberships := Must(store.ThreadMembership().GetFollowedByUser(userId1, teamId, 0, 10)).([]*model.ThreadMembership); len(memberships) != 0 {
+		t.Fatal("shouldn't have returned the unfollowed thread")
+	}
+
+	Must(store.ThreadMembership().PermanentDeleteByUser(userId1))
+
+	if result := <-store.ThreadMembership().Get(postId, userId1); result.Err == nil {
+		t.Fatal("should have deleted the membership of the user")
+	}
+
+	Must(store.ThreadMembership().PermanentDeleteByPost(postId))
+
+	if memberships := Must(store.ThreadMembership().GetForPost(postId)).([]*model.ThreadMembership); len(memberships) != 0 {
+		t.Fatal("should have deleted the memberships of the thread")
+	}
+}
@@ -29,6 +29,7 @@ const (
 	EXIT_TOO_OLD              = 1002
 	EXIT_VERSION_SAVE         = 1003
 	EXIT_THEME_MIGRATION      = 1004
+	EXIT_ROLES_MIGRATION      = 1005
 )
 
 func UpgradeDatabase(sqlStore *SqlStore) {
@@ -228,6 +229,66 @@ func UpgradeDatabaseToVersion36(sqlStore *SqlStore) {
 	// Remove ActiveChannel column from Status
 	sqlStore.RemoveColumnIfExists("Status", "ActiveChannel")
 
+	// Add an expiry date for guest accounts
+	sqlStore.CreateColumnIfNotExists("Users", "GuestExpiresAt", "bigint(20)", "bigint", "0")
+
+	// Add the end time and previous status for do not disturb
+	sqlStore.CreateColumnIfNotExists("Status", "DNDEndTime", "bigint(20)", "bigint", "0")
+	sqlStore.CreateColumnIfNotExists("Status", "PrevStatus", "varchar(32)", "varchar(32)", "")
+
+	// Add recovery codes for multi-factor authentication
+	sqlStore.CreateColumnIfNotExists("Users", "MfaRecoveryCodes", "varchar(1024)", "varchar(1024)", "")
+
+	// Add the time of the last failed login so that lockouts can expire
+	sqlStore.CreateColumnIfNotExists("Users", "LastFailedAttempt", "bigint(20)", "bigint", "0")
+
+	// Add the previous password hashes that can't be reused
+	sqlStore.CreateColumnIfNotExists("Users", "PasswordHistory", "varchar(2048)", "varchar(2048)", "")
+
+	// Add the values of the custom profile attributes defined by admins
+	sqlStore.CreateColumnIfNotExists("Users", "ProfileAttributes", "varchar(4000)", "varchar(4000)", "{}")
+
+	// Add custom status messages
+	sqlStore.CreateColumnIfNotExists("Status", "CustomEmoji", "varchar(64)", "varchar(64)", "")
+	sqlStore.CreateColumnIfNotExists("Status", "CustomText", "varchar(100)", "varchar(100)", "")
+	sqlStore.CreateColumnIfNotExists("Status", "CustomExpiresAt", "bigint(20)", "bigint", "0")
+
+	// Add the public keys that mobile apps register to receive encrypted push notifications
+	sqlStore.CreateColumnIfNotExists("Sessions", "DeviceKey", "varchar(512)", "varchar(512)", "")
+
+	// Save the roles that the Restrict* settings generate so they can be edited from now on
+	migrateRolesFromConfig(sqlStore)
+
 	//saveSchemaVersion(sqlStore, VERSION_3_6_0)
 	//}
 }
+
+func migrateRolesFromConfig(sqlStore *SqlStore) {
+	if result := <-sqlStore.system.Get(); result.Err == nil {
+		if _, ok := result.Data.(model.StringMap)[model.SYSTEM_ROLES_MIGRATED]; ok {
+			return
+		}
+	}
+
+	defaults := &model.Config{}
+	defaults.SetDefaults()
+
+	// The built in roles have already been adjusted for the Restrict* settings when the config was loaded. They only
+	// need to be saved when those settings were changed, otherwise the built in roles already match them.
+	if utils.HaveRoleSettingsChanged(defaults, utils.Cfg) {
+		for _, roleId := range []string{model.ROLE_SYSTEM_USER.Id, model.ROLE_TEAM_USER.Id, model.ROLE_TEAM_ADMIN.Id, model.ROLE_CHANNEL_ADMIN.Id} {
+			migrated := *model.BuiltInRoles[roleId]
+			if result := <-sqlStore.role.Save(&migrated); result.Err != nil {
+				l4g.Critical(result.Err.Error())
+				time.Sleep(time.Second)
+				os.Exit(EXIT_ROLES_MIGRATION)
+			}
+		}
+	}
+
+	if result := <-sqlStore.system.Save(&model.System{Name: model.SYSTEM_ROLES_MIGRATED, Value: "true"}); result.Err != nil {
+		l4g.Critical(result.Err.Error())
+		time.Sleep(time.Second)
+		os.Exit(EXIT_ROLES_MIGRATION)
+	}
+}
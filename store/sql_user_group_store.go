@@ -0,0 +1,322 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package store
+
+import (
+	"strconv"
+
+	"github.com/mattermost/platform/model"
+)
+
+type SqlUserGroupStore struct {
+	*SqlStore
+}
+
+func NewSqlUserGroupStore(sqlStore *SqlStore) UserGroupStore {
+	s := &SqlUserGroupStore{sqlStore}
+
+	for _, db := range sqlStore.GetAllConns() {
+		table := db.AddTableWithName(model.UserGroup{}, "UserGroups").SetKeys(false, "Id")
+		table.ColMap("Id").SetMaxSize(26)
+		table.ColMap("Name").SetMaxSize(model.USER_GROUP_NAME_MAX_LENGTH).SetUnique(true)
+		table.ColMap("DisplayName").SetMaxSize(model.USER_GROUP_DISPLAY_NAME_MAX_LENGTH)
+		table.ColMap("Description").SetMaxSize(model.USER_GROUP_DESCRIPTION_MAX_LENGTH)
+		table.ColMap("LdapGroupId").SetMaxSize(model.LDAP_GROUP_ID_MAX_LENGTH)
+		table.ColMap("CreatorId").SetMaxSize(26)
+
+		tablem := db.AddTableWithName(model.UserGroupMember{}, "UserGroupMembers").SetKeys(false, "GroupId", "UserId")
+		tablem.ColMap("GroupId").SetMaxSize(26)
+		tablem.ColMap("UserId").SetMaxSize(26)
+	}
+
+	return s
+}
+
+func (s SqlUserGroupStore) CreateIndexesIfNotExists() {
+	s.CreateIndexIfNotExists("idx_usergroupmembers_user_id", "UserGroupMembers", "UserId")
+}
+
+func (s SqlUserGroupStore) Save(group *model.UserGroup) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		if len(group.Id) > 0 {
+			result.Err = model.NewLocAppError("SqlUserGroupStore.Save", "store.sql_user_group.save.existing.app_error", nil, "id="+group.Id)
+			storeChannel <- result
+			close(storeChannel)
+			return
+		}
+
+		group.PreSave()
+		if result.Err = group.IsValid(); result.Err != nil {
+			storeChannel <- result
+			close(storeChannel)
+			return
+		}
+
+		if err := s.GetMaster().Insert(group); err != nil {
+			if IsUniqueConstraintError(err.Error(), []string{"Name", "usergroups_name_key"}) {
+				result.Err = model.NewLocAppError("SqlUserGroupStore.Save", "store.sql_user_group.save.name_exists.app_error", nil, "name="+group.Name+", "+err.Error())
+			} else {
+				result.Err = model.NewLocAppError("SqlUserGroupStore.Save", "store.sql_user_group.save.app_error", nil, "id="+group.Id+", "+err.Error())
+			}
+		} else {
+			result.Data = group
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
+func (s SqlUserGroupStore) Update(group *model.UserGroup) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		group.PreUpdate()
+		if result.Err = group.IsValid(); result.Err != nil {
+			storeChannel <- result
+			close(storeChannel)
+			return
+		}
+
+		if count, err := s.GetMaster().Update(group); err != nil {
+			if IsUniqueConstraintError(err.Error(), []string{"Name", "usergroups_name_key"}) {
+				result.Err = model.NewLocAppError("SqlUserGroupStore.Update", "store.sql_user_group.save.name_exists.app_error", nil, "name="+group.Name+", "+err.Error())
+			} else {
+				result.Err = model.NewLocAppError("SqlUserGroupStore.Update", "store.sql_user_group.update.app_error", nil, "id="+group.Id+", "+err.Error())
+			}
+		} else if count != 1 {
+			result.Err = model.NewLocAppError("SqlUserGroupStore.Update", "store.sql_user_group.get.app_error", nil, "id="+group.Id)
+		} else {
+			result.Data = group
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
+func (s SqlUserGroupStore) Get(id string) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		var group model.UserGroup
+		if err := s.GetReplica().SelectOne(&group, "SELECT * FROM UserGroups WHERE Id = :Id", map[string]interface{}{"Id": id}); err != nil {
+			result.Err = model.NewLocAppError("SqlUserGroupStore.Get", "store.sql_user_group.get.app_error", nil, "id="+id+", "+err.Error())
+		} else {
+			result.Data = &group
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
+func (s SqlUserGroupStore) GetByName(name string) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		var group model.UserGroup
+		if err := s.GetReplica().SelectOne(&group, "SELECT * FROM UserGroups WHERE Name = :Name", map[string]interface{}{"Name": name}); err != nil {
+			result.Err = model.NewLocAppError("SqlUserGroupStore.GetByName", "store.sql_user_group.get.app_error", nil, "name="+name+", "+err.Error())
+		} else {
+			result.Data = &group
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
+// GetByNames returns the groups with any of the names. Names without a group are ignored.
+func (s SqlUserGroupStore) GetByNames(names []string) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		groups := []*model.UserGroup{}
+		if len(names) == 0 {
+			result.Data = groups
+			storeChannel <- result
+			close(storeChannel)
+			return
+		}
+
+		props := make(map[string]interface{})
+		nameQuery := ""
+
+		for index, name := range names {
+			if len(nameQuery) > 0 {
+				nameQuery += ", "
+			}
+
+			props["name"+strconv.Itoa(index)] = name
+			nameQuery += ":name" + strconv.Itoa(index)
+		}
+
+		if _, err := s.GetReplica().Select(&groups, "SELECT * FROM UserGroups WHERE Name IN ("+nameQuery+")", props); err != nil {
+			result.Err = model.NewLocAppError("SqlUserGroupStore.GetByNames", "store.sql_user_group.get_all.app_error", nil, err.Error())
+		} else {
+			result.Data = groups
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
+func (s SqlUserGroupStore) GetAll() StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		var groups []*model.UserGroup
+		if _, err := s.GetReplica().Select(&groups, "SELECT * FROM UserGroups ORDER BY Name"); err != nil {
+			result.Err = model.NewLocAppError("SqlUserGroupStore.GetAll", "store.sql_user_group.get_all.app_error", nil, err.Error())
+		} else {
+			result.Data = groups
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
+// Delete removes the group along with its members
+func (s SqlUserGroupStore) Delete(id string) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		if _, err := s.GetMaster().Exec("DELETE FROM UserGroupMembers WHERE GroupId = :GroupId", map[string]interface{}{"GroupId": id}); err != nil {
+			result.Err = model.NewLocAppError("SqlUserGroupStore.Delete", "store.sql_user_group.delete.app_error", nil, "id="+id+", "+err.Error())
+		} else if res, err := s.GetMaster().Exec("DELETE FROM UserGroups WHERE Id = :Id", map[string]interface{}{"Id": id}); err != nil {
+			result.Err = model.NewLocAppError("SqlUserGroupStore.Delete", "store.sql_user_group.delete.app_error", nil, "id="+id+", "+err.Error())
+		} else if count, _ := res.RowsAffected(); count == 0 {
+			result.Err = model.NewLocAppError("SqlUserGroupStore.Delete", "store.sql_user_group.get.app_error", nil, "id="+id)
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
+func (s SqlUserGroupStore) SaveMember(member *model.UserGroupMember) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		member.PreSave()
+		if result.Err = member.IsValid(); result.Err != nil {
+			storeChannel <- result
+			close(storeChannel)
+			return
+		}
+
+		if err := s.GetMaster().Insert(member); err != nil {
+			if IsUniqueConstraintError(err.Error(), []string{"GroupId", "usergroupmembers_pkey", "PRIMARY"}) {
+				result.Err = model.NewLocAppError("SqlUserGroupStore.SaveMember", "store.sql_user_group.save_member.exists.app_error", nil, "group_id="+member.GroupId+", user_id="+member.UserId+", "+err.Error())
+			} else {
+				result.Err = model.NewLocAppError("SqlUserGroupStore.SaveMember", "store.sql_user_group.save_member.save.app_error", nil, "group_id="+member.GroupId+", user_id="+member.UserId+", "+err.Error())
+			}
+		} else {
+			result.Data = member
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
+func (s SqlUserGroupStore) RemoveMember(groupId string, userId string) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		if _, err := s.GetMaster().Exec("DELETE FROM UserGroupMembers WHERE GroupId = :GroupId AND UserId = :UserId", map[string]interface{}{"GroupId": groupId, "UserId": userId}); err != nil {
+			result.Err = model.NewLocAppError("SqlUserGroupStore.RemoveMember", "store.sql_user_group.remove_member.app_error", nil, "group_id="+groupId+", user_id="+userId+", "+err.Error())
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
+// GetMemberIds returns the ids of the active users in the group
+func (s SqlUserGroupStore) GetMemberIds(groupId string) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		var ids []string
+		if _, err := s.GetReplica().Select(&ids,
+			`SELECT
+				UserGroupMembers.UserId
+			FROM
+				UserGroupMembers, Users
+			WHERE
+				UserGroupMembers.GroupId = :GroupId
+				AND Users.Id = UserGroupMembers.UserId
+				AND Users.DeleteAt = 0`, map[string]interface{}{"GroupId": groupId}); err != nil {
+			result.Err = model.NewLocAppError("SqlUserGroupStore.GetMemberIds", "store.sql_user_group.get_members.app_error", nil, "group_id="+groupId+", "+err.Error())
+		} else {
+			result.Data = ids
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
+func (s SqlUserGroupStore) PermanentDeleteMembersByUser(userId string) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		if _, err := s.GetMaster().Exec("DELETE FROM UserGroupMembers WHERE UserId = :UserId", map[string]interface{}{"UserId": userId}); err != nil {
+			result.Err = model.NewLocAppError("SqlUserGroupStore.PermanentDeleteMembersByUser", "store.sql_user_group.permanent_delete_members_by_user.app_error", nil, "user_id="+userId+", "+err.Error())
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
@@ -0,0 +1,94 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package store
+
+import (
+	"testing"
+
+	"github.com/mattermost/platform/model"
+)
+
+func TestUserGroupStore(t *testing.T) {
+	Setup()
+
+	group := &model.UserGroup{Name: "sre-" + model.NewId()[:8], DisplayName: "SRE"}
+	if result := <-store.UserGroup().Save(group); result.Err != nil {
+		t.Fatal(result.Err)
+	}
+
+	if result := <-store.UserGroup().Save(&model.UserGroup{Name: group.Name, DisplayName: "Other"}); result.Err == nil {
+		t.Fatal("should have failed to save a group with the same name")
+	}
+
+	if rgroup := Must(store.UserGroup().Get(group.Id)).(*model.UserGroup); rgroup.Name != group.Name {
+		t.Fatal("should have returned the group")
+	}
+
+	if rgroup := Must(store.UserGroup().GetByName(group.Name)).(*model.UserGroup); rgroup.Id != group.Id {
+		t.Fatal("should have returned the group by name")
+	}
+
+	if groups := Must(store.UserGroup().GetByNames([]string{group.Name, "missing"})).([]*model.UserGroup); len(groups) != 1 || groups[0].Id != group.Id {
+		t.Fatal("should have only returned the existing group")
+	}
+
+	if groups := Must(store.UserGroup().GetByNames([]string{})).([]*model.UserGroup); len(groups) != 0 {
+		t.Fatal("should have returned no groups")
+	}
+
+	group.DisplayName = "Site Reliability"
+	if rgroup := Must(store.UserGroup().Update(group)).(*model.UserGroup); rgroup.DisplayName != "Site Reliability" {
+		t.Fatal("should have updated the group")
+	}
+
+	if groups := Must(store.UserGroup().GetAll()).([]*model.UserGroup); len(groups) < 1 {
+		t.Fatal("should have returned the group")
+	}
+
+	u1 := &model.User{Email: model.NewId(), Username: "n" + model.NewId()}
+	Must(store.User().Save(u1))
+
+	u2 := &model.User{Email: model.NewId(), Username: "n" + model.NewId()}
+	Must(store.User().Save(u2))
+
+	Must(store.UserGroup().SaveMember(&model.UserGroupMember{GroupId: group.Id, UserId: u1.Id}))
+	Must(store.UserGroup().SaveMember(&model.UserGroupMember{GroupId: group.Id, UserId: u2.Id}))
+
+	if result := <-store.UserGroup().SaveMember(&model.UserGroupMember{GroupId: group.Id, UserId: u1.Id}); result.Err == nil {
+		t.Fatal("should have failed to add the member twice")
+	}
+
+	if ids := Must(store.UserGroup().GetMemberIds(group.Id)).([]string); len(ids) != 2 {
+		t.Fatal("should have returned both members")
+	}
+
+	u2.DeleteAt = model.GetMillis()
+	Must(store.User().Update(u2, true))
+
+	if ids := Must(store.UserGroup().GetMemberIds(group.Id)).([]string); len(ids) != 1 || ids[0] != u1.Id {
+		t.Fatal("shouldn't have returned the deactivated member")
+	}
+
+	Must(store.UserGroup().RemoveMember(group.Id, u1.Id))
+	Must(store.UserGroup().PermanentDeleteMembersByUser(u2.Id))
+
+	if ids := Must(store.UserGroup().GetMemberIds(group.Id)).([]string); len(ids) != 0 {
+		t.Fatal("should have removed the members")
+	}
+
+	Must(store.UserGroup().SaveMember(&model.UserGroupMember{GroupId: group.Id, UserId: u1.Id}))
+	Must(store.UserGroup().Delete(group.Id))
+
+	if result := <-store.UserGroup().Get(group.Id); result.Err == nil {
+		t.Fatal("should have deleted the group")
+	}
+
+	if ids := Must(store.UserGroup().GetMemberIds(group.Id)).([]string); len(ids) != 0 {
+		t.Fatal("should have deleted the members with the group")
+	}
+
+	if result := <-store.UserGroup().Delete(group.Id); result.Err == nil {
+		t.Fatal("should have failed to delete a missing group")
+	}
+}
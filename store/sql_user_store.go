@@ -6,6 +6,7 @@ package store
 import (
 	"crypto/md5"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"strconv"
 	"strings"
@@ -26,12 +27,17 @@ const (
 	USER_SEARCH_OPTION_NAMES_ONLY_NO_FULL_NAME = "names_only_no_full_name"
 	USER_SEARCH_OPTION_ALL_NO_FULL_NAME        = "all_no_full_name"
 	USER_SEARCH_OPTION_ALLOW_INACTIVE          = "allow_inactive"
+	USER_SEARCH_OPTION_HIDE_GUESTS             = "hide_guests"
 	USER_SEARCH_TYPE_NAMES_NO_FULL_NAME        = "Username, Nickname"
 	USER_SEARCH_TYPE_NAMES                     = "Username, FirstName, LastName, Nickname"
 	USER_SEARCH_TYPE_ALL_NO_FULL_NAME          = "Username, Nickname, Email"
 	USER_SEARCH_TYPE_ALL                       = "Username, FirstName, LastName, Nickname, Email"
 )
 
+// USER_SEARCH_OPTION_PROFILE_ATTRIBUTE_PREFIX followed by an attribute's name also matches users whose value
+// for the attribute starts with the term
+const USER_SEARCH_OPTION_PROFILE_ATTRIBUTE_PREFIX = "profile_attribute_"
+
 type SqlUserStore struct {
 	*SqlStore
 }
@@ -65,8 +71,11 @@ func NewSqlUserStore(sqlStore *SqlStore) UserStore {
 		table.ColMap("Roles").SetMaxSize(64)
 		table.ColMap("Props").SetMaxSize(4000)
 		table.ColMap("NotifyProps").SetMaxSize(2000)
+		table.ColMap("ProfileAttributes").SetMaxSize(4000)
 		table.ColMap("Locale").SetMaxSize(5)
 		table.ColMap("MfaSecret").SetMaxSize(128)
+		table.ColMap("MfaRecoveryCodes").SetMaxSize(1024)
+		table.ColMap("PasswordHistory").SetMaxSize(2048)
 		table.ColMap("Position").SetMaxSize(64)
 	}
 
@@ -154,12 +163,16 @@ func (us SqlUserStore) Update(user *model.User, trustedUpdateData bool) StoreCha
 			user.LastPictureUpdate = oldUser.LastPictureUpdate
 			user.EmailVerified = oldUser.EmailVerified
 			user.FailedAttempts = oldUser.FailedAttempts
+			user.LastFailedAttempt = oldUser.LastFailedAttempt
 			user.MfaSecret = oldUser.MfaSecret
 			user.MfaActive = oldUser.MfaActive
+			user.MfaRecoveryCodes = oldUser.MfaRecoveryCodes
+			user.PasswordHistory = oldUser.PasswordHistory
 
 			if !trustedUpdateData {
 				user.Roles = oldUser.Roles
 				user.DeleteAt = oldUser.DeleteAt
+				user.GuestExpiresAt = oldUser.GuestExpiresAt
 			}
 
 			if user.IsOAuthUser() {
@@ -253,7 +266,7 @@ func (us SqlUserStore) UpdatePassword(userId, hashedPassword string) StoreChanne
 
 		updateAt := model.GetMillis()
 
-		if _, err := us.GetMaster().Exec("UPDATE Users SET Password = :Password, LastPasswordUpdate = :LastPasswordUpdate, UpdateAt = :UpdateAt, AuthData = NULL, AuthService = '', EmailVerified = true, FailedAttempts = 0 WHERE Id = :UserId", map[string]interface{}{"Password": hashedPassword, "LastPasswordUpdate": updateAt, "UpdateAt": updateAt, "UserId": userId}); err != nil {
+		if _, err := us.GetMaster().Exec("UPDATE Users SET Password = :Password, LastPasswordUpdate = :LastPasswordUpdate, UpdateAt = :UpdateAt, AuthData = NULL, AuthService = '', EmailVerified = true, FailedAttempts = 0, LastFailedAttempt = 0 WHERE Id = :UserId", map[string]interface{}{"Password": hashedPassword, "LastPasswordUpdate": updateAt, "UpdateAt": updateAt, "UserId": userId}); err != nil {
 			result.Err = model.NewLocAppError("SqlUserStore.UpdatePassword", "store.sql_user.update_password.app_error", nil, "id="+userId+", "+err.Error())
 		} else {
 			result.Data = userId
@@ -272,7 +285,12 @@ func (us SqlUserStore) UpdateFailedPasswordAttempts(userId string, attempts int)
 	go func() {
 		result := StoreResult{}
 
-		if _, err := us.GetMaster().Exec("UPDATE Users SET FailedAttempts = :FailedAttempts WHERE Id = :UserId", map[string]interface{}{"FailedAttempts": attempts, "UserId": userId}); err != nil {
+		lastFailedAttempt := int64(0)
+		if attempts > 0 {
+			lastFailedAttempt = model.GetMillis()
+		}
+
+		if _, err := us.GetMaster().Exec("UPDATE Users SET FailedAttempts = :FailedAttempts, LastFailedAttempt = :LastFailedAttempt WHERE Id = :UserId", map[string]interface{}{"FailedAttempts": attempts, "LastFailedAttempt": lastFailedAttempt, "UserId": userId}); err != nil {
 			result.Err = model.NewLocAppError("SqlUserStore.UpdateFailedPasswordAttempts", "store.sql_user.update_failed_pwd_attempts.app_error", nil, "user_id="+userId)
 		} else {
 			result.Data = userId
@@ -304,6 +322,7 @@ func (us SqlUserStore) UpdateAuthData(userId string, service string, authData *s
 			     LastPasswordUpdate = :LastPasswordUpdate,
 			     UpdateAt = :UpdateAt,
 			     FailedAttempts = 0,
+			     LastFailedAttempt = 0,
 			     AuthService = :AuthService,
 			     AuthData = :AuthData`
 
@@ -312,7 +331,7 @@ func (us SqlUserStore) UpdateAuthData(userId string, service string, authData *s
 		}
 
 		if resetMfa {
-			query += ", MfaActive = false, MfaSecret = ''"
+			query += ", MfaActive = false, MfaSecret = '', MfaRecoveryCodes = ''"
 		}
 
 		query += " WHERE Id = :UserId"
@@ -356,6 +375,48 @@ func (us SqlUserStore) UpdateMfaSecret(userId, secret string) StoreChannel {
 	return storeChannel
 }
 
+func (us SqlUserStore) UpdateMfaRecoveryCodes(userId, codes string) StoreChannel {
+
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		updateAt := model.GetMillis()
+
+		if _, err := us.GetMaster().Exec("UPDATE Users SET MfaRecoveryCodes = :Codes, UpdateAt = :UpdateAt WHERE Id = :UserId", map[string]interface{}{"Codes": codes, "UpdateAt": updateAt, "UserId": userId}); err != nil {
+			result.Err = model.NewLocAppError("SqlUserStore.UpdateMfaRecoveryCodes", "store.sql_user.update_mfa_recovery_codes.app_error", nil, "id="+userId+", "+err.Error())
+		} else {
+			result.Data = userId
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
+func (us SqlUserStore) UpdatePasswordHistory(userId, history string) StoreChannel {
+
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		if _, err := us.GetMaster().Exec("UPDATE Users SET PasswordHistory = :History WHERE Id = :UserId", map[string]interface{}{"History": history, "UserId": userId}); err != nil {
+			result.Err = model.NewLocAppError("SqlUserStore.UpdatePasswordHistory", "store.sql_user.update_password_history.app_error", nil, "id="+userId+", "+err.Error())
+		} else {
+			result.Data = userId
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
 func (us SqlUserStore) UpdateMfaActive(userId string, active bool) StoreChannel {
 
 	storeChannel := make(StoreChannel, 1)
@@ -504,7 +565,7 @@ func (us SqlUserStore) GetAllProfiles(offset int, limit int) StoreChannel {
 
 		var users []*model.User
 
-		if _, err := us.GetReplica().Select(&users, "SELECT * FROM Users ORDER BY Username ASC LIMIT :Limit OFFSET :Offset", map[string]interface{}{"Offset": offset, "Limit": limit}); err != nil {
+		if _, err := us.GetReplica().Select(&users, "SELECT * FROM Users WHERE AuthService != :BotAuthService ORDER BY Username ASC LIMIT :Limit OFFSET :Offset", map[string]interface{}{"BotAuthService": model.USER_AUTH_SERVICE_BOT, "Offset": offset, "Limit": limit}); err != nil {
 			result.Err = model.NewLocAppError("SqlUserStore.GetAllProfiles", "store.sql_user.get_profiles.app_error", nil, err.Error())
 		} else {
 
@@ -895,6 +956,34 @@ func (us SqlUserStore) GetSystemAdminProfiles() StoreChannel {
 	return storeChannel
 }
 
+func (us SqlUserStore) GetExpiredGuests(expiredBefore int64) StoreChannel {
+
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		var users []*model.User
+
+		if _, err := us.GetReplica().Select(&users, "SELECT * FROM Users WHERE Roles = :Roles AND GuestExpiresAt > 0 AND GuestExpiresAt <= :ExpiredBefore AND DeleteAt = 0", map[string]interface{}{"Roles": model.ROLE_SYSTEM_GUEST.Id, "ExpiredBefore": expiredBefore}); err != nil {
+			result.Err = model.NewLocAppError("SqlUserStore.GetExpiredGuests", "store.sql_user.get_expired_guests.app_error", nil, err.Error())
+		} else {
+			for _, u := range users {
+				u.Password = ""
+				u.AuthData = new(string)
+				*u.AuthData = ""
+			}
+
+			result.Data = users
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
 func (us SqlUserStore) GetByEmail(email string) StoreChannel {
 
 	storeChannel := make(StoreChannel, 1)
@@ -1062,7 +1151,7 @@ func (us SqlUserStore) GetTotalUsersCount() StoreChannel {
 	go func() {
 		result := StoreResult{}
 
-		if count, err := us.GetReplica().SelectInt("SELECT COUNT(Id) FROM Users"); err != nil {
+		if count, err := us.GetReplica().SelectInt("SELECT COUNT(Id) FROM Users WHERE AuthService != :BotAuthService", map[string]interface{}{"BotAuthService": model.USER_AUTH_SERVICE_BOT}); err != nil {
 			result.Err = model.NewLocAppError("SqlUserStore.GetTotalUsersCount", "store.sql_user.get_total_users_count.app_error", nil, err.Error())
 		} else {
 			result.Data = count
@@ -1104,10 +1193,10 @@ func (us SqlUserStore) AnalyticsUniqueUserCount(teamId string) StoreChannel {
 		if len(teamId) > 0 {
 			query = "SELECT COUNT(DISTINCT Users.Email) From Users, TeamMembers WHERE TeamMembers.TeamId = :TeamId AND Users.Id = TeamMembers.UserId AND TeamMembers.DeleteAt = 0 AND Users.DeleteAt = 0"
 		} else {
-			query = "SELECT COUNT(DISTINCT Email) FROM Users WHERE DeleteAt = 0"
+			query = "SELECT COUNT(DISTINCT Email) FROM Users WHERE DeleteAt = 0 AND AuthService != :BotAuthService"
 		}
 
-		v, err := us.GetReplica().SelectInt(query, map[string]interface{}{"TeamId": teamId})
+		v, err := us.GetReplica().SelectInt(query, map[string]interface{}{"TeamId": teamId, "BotAuthService": model.USER_AUTH_SERVICE_BOT})
 		if err != nil {
 			result.Err = model.NewLocAppError("SqlUserStore.AnalyticsUniqueUserCount", "store.sql_user.analytics_unique_user_count.app_error", nil, err.Error())
 		} else {
@@ -1177,6 +1266,8 @@ func (us SqlUserStore) Search(teamId string, term string, options map[string]boo
 				Id != ''
 				SEARCH_CLAUSE
 				INACTIVE_CLAUSE
+				GUEST_CLAUSE
+				AND Users.AuthService != :BotAuthService
 				ORDER BY Username ASC
 			LIMIT 100`
 		} else {
@@ -1191,6 +1282,8 @@ func (us SqlUserStore) Search(teamId string, term string, options map[string]boo
 				AND TeamMembers.DeleteAt = 0
 				SEARCH_CLAUSE
 				INACTIVE_CLAUSE
+				GUEST_CLAUSE
+				AND Users.AuthService != :BotAuthService
 				ORDER BY Users.Username ASC
 			LIMIT 100`
 		}
@@ -1220,6 +1313,8 @@ func (us SqlUserStore) SearchNotInChannel(teamId string, channelId string, term
 				cm.UserId IS NULL
 				SEARCH_CLAUSE
 				INACTIVE_CLAUSE
+				GUEST_CLAUSE
+				AND Users.AuthService != :BotAuthService
 			ORDER BY Users.Username ASC
 			LIMIT 100`
 		} else {
@@ -1238,6 +1333,8 @@ func (us SqlUserStore) SearchNotInChannel(teamId string, channelId string, term
 				cm.UserId IS NULL
 				SEARCH_CLAUSE
 				INACTIVE_CLAUSE
+				GUEST_CLAUSE
+				AND Users.AuthService != :BotAuthService
 			ORDER BY Users.Username ASC
 			LIMIT 100`
 		}
@@ -1264,6 +1361,7 @@ func (us SqlUserStore) SearchInChannel(channelId string, term string, options ma
             AND ChannelMembers.UserId = Users.Id
             SEARCH_CLAUSE
             INACTIVE_CLAUSE
+            AND Users.AuthService != :BotAuthService
             ORDER BY Users.Username ASC
         LIMIT 100`
 
@@ -1289,9 +1387,105 @@ var specialUserSearchChar = []string{
 	"\"",
 }
 
+// GetByNotifyProp returns the active users with the given value for a notify prop
+func (us SqlUserStore) GetByNotifyProp(key string, value string) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		var users []*model.User
+		if _, err := us.GetReplica().Select(&users, "SELECT * FROM Users WHERE NotifyProps LIKE :Pattern AND DeleteAt = 0",
+			map[string]interface{}{"Pattern": propPattern(key, value)}); err != nil {
+			result.Err = model.NewLocAppError("SqlUserStore.GetByNotifyProp", "store.sql_user.get_by_notify_prop.app_error", nil, "key="+key+", "+err.Error())
+		} else {
+			for _, u := range users {
+				u.Password = ""
+				u.AuthData = new(string)
+				*u.AuthData = ""
+			}
+
+			result.Data = users
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
+// GetByProp returns the users, including deactivated ones, with the given value for a prop
+func (us SqlUserStore) GetByProp(key string, value string) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		var users []*model.User
+		if _, err := us.GetReplica().Select(&users, "SELECT * FROM Users WHERE Props LIKE :Pattern",
+			map[string]interface{}{"Pattern": propPattern(key, value)}); err != nil {
+			result.Err = model.NewLocAppError("SqlUserStore.GetByProp", "store.sql_user.get_by_prop.app_error", nil, "key="+key+", "+err.Error())
+		} else {
+			for _, u := range users {
+				u.Password = ""
+				u.AuthData = new(string)
+				*u.AuthData = ""
+			}
+
+			result.Data = users
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
+// propPattern matches a key and value in the JSON of the Props or NotifyProps column
+func propPattern(key string, value string) string {
+	k, _ := json.Marshal(key)
+	v, _ := json.Marshal(value)
+	pattern := string(k) + ":" + string(v)
+
+	pattern = strings.Replace(pattern, "\\", "\\\\", -1)
+	pattern = strings.Replace(pattern, "%", "\\%", -1)
+	pattern = strings.Replace(pattern, "_", "\\_", -1)
+
+	return "%" + pattern + "%"
+}
+
+// profileAttributeSearchPattern matches the start of an attribute's value in the lower cased JSON of the
+// ProfileAttributes column
+func profileAttributeSearchPattern(name string, term string) string {
+	b, _ := json.Marshal(strings.ToLower(strings.TrimSpace(term)))
+	pattern := "\"" + name + "\":" + strings.TrimSuffix(string(b), "\"")
+
+	pattern = strings.Replace(pattern, "\\", "\\\\", -1)
+	pattern = strings.Replace(pattern, "%", "\\%", -1)
+	pattern = strings.Replace(pattern, "_", "\\_", -1)
+
+	return "%" + pattern + "%"
+}
+
 func (us SqlUserStore) performSearch(searchQuery string, term string, options map[string]bool, parameters map[string]interface{}) StoreResult {
 	result := StoreResult{}
 
+	// the accounts that the server posts as are never returned in searches
+	parameters["BotAuthService"] = model.USER_AUTH_SERVICE_BOT
+
+	attributeClause := ""
+	if term != "" {
+		for option, ok := range options {
+			if ok && strings.HasPrefix(option, USER_SEARCH_OPTION_PROFILE_ATTRIBUTE_PREFIX) {
+				param := fmt.Sprintf("ProfileAttribute%v", len(parameters))
+				attributeClause += " OR LOWER(Users.ProfileAttributes) LIKE :" + param
+				parameters[param] = profileAttributeSearchPattern(strings.TrimPrefix(option, USER_SEARCH_OPTION_PROFILE_ATTRIBUTE_PREFIX), term)
+			}
+		}
+	}
+
 	// these chars have special meaning and can be treated as spaces
 	for _, c := range specialUserSearchChar {
 		term = strings.Replace(term, c, " ", -1)
@@ -1312,6 +1506,13 @@ func (us SqlUserStore) performSearch(searchQuery string, term string, options ma
 		searchQuery = strings.Replace(searchQuery, "INACTIVE_CLAUSE", "AND Users.DeleteAt = 0", 1)
 	}
 
+	if ok := options[USER_SEARCH_OPTION_HIDE_GUESTS]; ok {
+		searchQuery = strings.Replace(searchQuery, "GUEST_CLAUSE", "AND Users.Roles != :GuestRoles", 1)
+		parameters["GuestRoles"] = model.ROLE_SYSTEM_GUEST.Id
+	} else {
+		searchQuery = strings.Replace(searchQuery, "GUEST_CLAUSE", "", 1)
+	}
+
 	if term == "" {
 		searchQuery = strings.Replace(searchQuery, "SEARCH_CLAUSE", "", 1)
 	} else if utils.Cfg.SqlSettings.DriverName == model.DATABASE_DRIVER_POSTGRES {
@@ -1327,7 +1528,7 @@ func (us SqlUserStore) performSearch(searchQuery string, term string, options ma
 		term = strings.Join(splitTerm, " ")
 
 		searchType = convertMySQLFullTextColumnsToPostgres(searchType)
-		searchClause := fmt.Sprintf("AND (%s) @@  to_tsquery('simple', :Term)", searchType)
+		searchClause := fmt.Sprintf("AND ((%s) @@  to_tsquery('simple', :Term)%s)", searchType, attributeClause)
 		searchQuery = strings.Replace(searchQuery, "SEARCH_CLAUSE", searchClause, 1)
 	} else if utils.Cfg.SqlSettings.DriverName == model.DATABASE_DRIVER_MYSQL {
 		splitTerm := strings.Fields(term)
@@ -1337,7 +1538,7 @@ func (us SqlUserStore) performSearch(searchQuery string, term string, options ma
 
 		term = strings.Join(splitTerm, " ")
 
-		searchClause := fmt.Sprintf("AND MATCH(%s) AGAINST (:Term IN BOOLEAN MODE)", searchType)
+		searchClause := fmt.Sprintf("AND (MATCH(%s) AGAINST (:Term IN BOOLEAN MODE)%s)", searchType, attributeClause)
 		searchQuery = strings.Replace(searchQuery, "SEARCH_CLAUSE", searchClause, 1)
 	}
 
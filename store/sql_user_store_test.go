@@ -147,6 +147,20 @@ func TestUserStoreUpdateFailedPasswordAttempts(t *testing.T) {
 		if r1.Data.(*model.User).FailedAttempts != 3 {
 			t.Fatal("FailedAttempts not updated correctly")
 		}
+
+		if r1.Data.(*model.User).LastFailedAttempt == 0 {
+			t.Fatal("LastFailedAttempt not updated correctly")
+		}
+	}
+
+	if err := (<-store.User().UpdateFailedPasswordAttempts(u1.Id, 0)).Err; err != nil {
+		t.Fatal(err)
+	}
+
+	if r1 := <-store.User().Get(u1.Id); r1.Err != nil {
+		t.Fatal(r1.Err)
+	} else if r1.Data.(*model.User).LastFailedAttempt != 0 {
+		t.Fatal("LastFailedAttempt should have been cleared")
 	}
 
 }
@@ -610,6 +624,44 @@ func TestUserStoreGetSystemAdminProfiles(t *testing.T) {
 	}
 }
 
+func TestUserStoreGetExpiredGuests(t *testing.T) {
+	Setup()
+
+	u1 := &model.User{}
+	u1.Email = model.NewId()
+	u1.Roles = model.ROLE_SYSTEM_GUEST.Id
+	u1.GuestExpiresAt = model.GetMillis() - 1000
+	Must(store.User().Save(u1))
+
+	u2 := &model.User{}
+	u2.Email = model.NewId()
+	u2.Roles = model.ROLE_SYSTEM_GUEST.Id
+	u2.GuestExpiresAt = model.GetMillis() + 1000*60*60
+	Must(store.User().Save(u2))
+
+	u3 := &model.User{}
+	u3.Email = model.NewId()
+	u3.Roles = model.ROLE_SYSTEM_GUEST.Id
+	Must(store.User().Save(u3))
+
+	if r1 := <-store.User().GetExpiredGuests(model.GetMillis()); r1.Err != nil {
+		t.Fatal(r1.Err)
+	} else {
+		found1 := false
+		for _, user := range r1.Data.([]*model.User) {
+			if user.Id == u1.Id {
+				found1 = true
+			} else if user.Id == u2.Id || user.Id == u3.Id {
+				t.Fatal("should not have returned a guest that has not expired")
+			}
+		}
+
+		if !found1 {
+			t.Fatal("should have returned the expired guest")
+		}
+	}
+}
+
 func TestUserStoreGetByEmail(t *testing.T) {
 	Setup()
 
@@ -940,6 +992,24 @@ func TestUserStoreUpdateMfaSecret(t *testing.T) {
 	}
 }
 
+func TestUserStoreUpdatePasswordHistory(t *testing.T) {
+	Setup()
+
+	u1 := model.User{}
+	u1.Email = model.NewId()
+	Must(store.User().Save(&u1))
+
+	if err := (<-store.User().UpdatePasswordHistory(u1.Id, "hash1 hash2")).Err; err != nil {
+		t.Fatal(err)
+	}
+
+	if r1 := <-store.User().Get(u1.Id); r1.Err != nil {
+		t.Fatal(r1.Err)
+	} else if r1.Data.(*model.User).PasswordHistory != "hash1 hash2" {
+		t.Fatal("PasswordHistory not updated correctly")
+	}
+}
+
 func TestUserStoreUpdateMfaActive(t *testing.T) {
 	Setup()
 
@@ -969,7 +1039,7 @@ func TestUserStoreGetRecentlyActiveUsersForTeam(t *testing.T) {
 	u1 := &model.User{}
 	u1.Email = model.NewId()
 	Must(store.User().Save(u1))
-	Must(store.Status().SaveOrUpdate(&model.Status{u1.Id, model.STATUS_ONLINE, false, model.GetMillis(), ""}))
+	Must(store.Status().SaveOrUpdate(&model.Status{UserId: u1.Id, Status: model.STATUS_ONLINE, LastActivityAt: model.GetMillis()}))
 	tid := model.NewId()
 	Must(store.Team().SaveMember(&model.TeamMember{TeamId: tid, UserId: u1.Id}))
 
@@ -1323,3 +1393,155 @@ func TestUserStoreSearch(t *testing.T) {
 		}
 	}
 }
+
+func TestUserStoreSearchHideGuests(t *testing.T) {
+	Setup()
+
+	u1 := &model.User{}
+	u1.Username = "guestsearch" + model.NewId()
+	u1.Email = model.NewId()
+	Must(store.User().Save(u1))
+
+	u2 := &model.User{}
+	u2.Username = "guestsearch" + model.NewId()
+	u2.Email = model.NewId()
+	u2.Roles = model.ROLE_SYSTEM_GUEST.Id
+	Must(store.User().Save(u2))
+
+	tid := model.NewId()
+	Must(store.Team().SaveMember(&model.TeamMember{TeamId: tid, UserId: u1.Id}))
+	Must(store.Team().SaveMember(&model.TeamMember{TeamId: tid, UserId: u2.Id}))
+
+	searchOptions := map[string]bool{}
+	searchOptions[USER_SEARCH_OPTION_NAMES_ONLY] = true
+
+	if r1 := <-store.User().Search(tid, "guestsearch", searchOptions); r1.Err != nil {
+		t.Fatal(r1.Err)
+	} else if profiles := r1.Data.([]*model.User); len(profiles) != 2 {
+		t.Fatal("should have found both users")
+	}
+
+	searchOptions[USER_SEARCH_OPTION_HIDE_GUESTS] = true
+
+	if r1 := <-store.User().Search(tid, "guestsearch", searchOptions); r1.Err != nil {
+		t.Fatal(r1.Err)
+	} else if profiles := r1.Data.([]*model.User); len(profiles) != 1 || profiles[0].Id != u1.Id {
+		t.Fatal("should have hidden the guest")
+	}
+}
+
+func TestUserStoreSearchProfileAttributes(t *testing.T) {
+	Setup()
+
+	u1 := &model.User{}
+	u1.Username = "attributesearch" + model.NewId()
+	u1.Email = model.NewId()
+	u1.ProfileAttributes = model.StringMap{"department": "Customer_Success"}
+	Must(store.User().Save(u1))
+
+	u2 := &model.User{}
+	u2.Username = "attributesearch" + model.NewId()
+	u2.Email = model.NewId()
+	u2.ProfileAttributes = model.StringMap{"location": "Customer Success"}
+	Must(store.User().Save(u2))
+
+	tid := model.NewId()
+	Must(store.Team().SaveMember(&model.TeamMember{TeamId: tid, UserId: u1.Id}))
+	Must(store.Team().SaveMember(&model.TeamMember{TeamId: tid, UserId: u2.Id}))
+
+	searchOptions := map[string]bool{}
+	searchOptions[USER_SEARCH_OPTION_NAMES_ONLY] = true
+
+	if r1 := <-store.User().Search(tid, "customer_s", searchOptions); r1.Err != nil {
+		t.Fatal(r1.Err)
+	} else if profiles := r1.Data.([]*model.User); len(profiles) != 0 {
+		t.Fatal("shouldn't have searched attributes without the option")
+	}
+
+	searchOptions[USER_SEARCH_OPTION_PROFILE_ATTRIBUTE_PREFIX+"department"] = true
+
+	if r1 := <-store.User().Search(tid, "customer_s", searchOptions); r1.Err != nil {
+		t.Fatal(r1.Err)
+	} else if profiles := r1.Data.([]*model.User); len(profiles) != 1 || profiles[0].Id != u1.Id {
+		t.Fatal("should have found the user by department")
+	}
+
+	if r1 := <-store.User().Search(tid, "customer%", searchOptions); r1.Err != nil {
+		t.Fatal(r1.Err)
+	} else if profiles := r1.Data.([]*model.User); len(profiles) != 0 {
+		t.Fatal("should have escaped wildcards")
+	}
+}
+
+func TestUserStoreGetByNotifyProp(t *testing.T) {
+	Setup()
+
+	key := "test_" + model.NewId()
+
+	u1 := &model.User{}
+	u1.Email = model.NewId()
+	u1.NotifyProps = model.StringMap{key: "daily"}
+	Must(store.User().Save(u1))
+
+	u2 := &model.User{}
+	u2.Email = model.NewId()
+	u2.NotifyProps = model.StringMap{key: "weekly"}
+	Must(store.User().Save(u2))
+
+	u3 := &model.User{}
+	u3.Email = model.NewId()
+	u3.NotifyProps = model.StringMap{key: "daily"}
+	u3.DeleteAt = model.GetMillis()
+	Must(store.User().Save(u3))
+
+	if r1 := <-store.User().GetByNotifyProp(key, "daily"); r1.Err != nil {
+		t.Fatal(r1.Err)
+	} else if users := r1.Data.([]*model.User); len(users) != 1 || users[0].Id != u1.Id {
+		t.Fatal("should only have returned the active user with the value", users)
+	} else if users[0].Password != "" {
+		t.Fatal("should have sanitized the password")
+	}
+
+	if r1 := <-store.User().GetByNotifyProp(key, "d%"); r1.Err != nil {
+		t.Fatal(r1.Err)
+	} else if users := r1.Data.([]*model.User); len(users) != 0 {
+		t.Fatal("should have escaped wildcards")
+	}
+}
+
+func TestUserStoreGetByProp(t *testing.T) {
+	Setup()
+
+	key := "test_" + model.NewId()
+	value := model.NewId()
+
+	u1 := &model.User{}
+	u1.Email = model.NewId()
+	u1.Props = model.StringMap{key: value}
+	Must(store.User().Save(u1))
+
+	u2 := &model.User{}
+	u2.Email = model.NewId()
+	u2.Props = model.StringMap{key: model.NewId()}
+	Must(store.User().Save(u2))
+
+	u3 := &model.User{}
+	u3.Email = model.NewId()
+	u3.Props = model.StringMap{key: value}
+	u3.DeleteAt = model.GetMillis()
+	Must(store.User().Save(u3))
+
+	if r1 := <-store.User().GetByProp(key, value); r1.Err != nil {
+		t.Fatal(r1.Err)
+	} else if users := r1.Data.([]*model.User); len(users) != 2 {
+		t.Fatal("should have returned both users with the value", users)
+	} else if users[0].Password != "" {
+		t.Fatal("should have sanitized the password")
+	}
+
+	if r1 := <-store.User().GetByProp(key, value[:5]+"%"); r1.Err != nil {
+		t.Fatal(r1.Err)
+	} else if users := r1.Data.([]*model.User); len(users) != 0 {
+		t.Fatal("should have escaped wildcards")
+	}
+}
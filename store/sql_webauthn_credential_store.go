@@ -0,0 +1,181 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package store
+
+import (
+	"github.com/mattermost/platform/model"
+)
+
+type SqlWebAuthnCredentialStore struct {
+	*SqlStore
+}
+
+func NewSqlWebAuthnCredentialStore(sqlStore *SqlStore) WebAuthnCredentialStore {
+	s := &SqlWebAuthnCredentialStore{sqlStore}
+
+	for _, db := range sqlStore.GetAllConns() {
+		table := db.AddTableWithName(model.WebAuthnCredential{}, "WebAuthnCredentials").SetKeys(false, "Id")
+		table.ColMap("Id").SetMaxSize(26)
+		table.ColMap("UserId").SetMaxSize(26)
+		table.ColMap("Name").SetMaxSize(model.WEBAUTHN_CREDENTIAL_NAME_MAX_LENGTH)
+		table.ColMap("CredentialId").SetMaxSize(model.WEBAUTHN_CREDENTIAL_ID_MAX_LENGTH)
+		table.ColMap("PublicKey").SetMaxSize(model.WEBAUTHN_CREDENTIAL_PUBLIC_KEY_MAX_LENGTH)
+
+		tableChallenges := db.AddTableWithName(model.WebAuthnChallenge{}, "WebAuthnChallenges").SetKeys(false, "Nonce")
+		tableChallenges.ColMap("Nonce").SetMaxSize(32)
+		tableChallenges.ColMap("Purpose").SetMaxSize(32)
+		tableChallenges.ColMap("UserId").SetMaxSize(26)
+		tableChallenges.ColMap("Origin").SetMaxSize(256)
+	}
+
+	return s
+}
+
+func (s SqlWebAuthnCredentialStore) CreateIndexesIfNotExists() {
+	s.CreateIndexIfNotExists("idx_webauthncredentials_user_id", "WebAuthnCredentials", "UserId")
+	s.CreateIndexIfNotExists("idx_webauthnchallenges_expires_at", "WebAuthnChallenges", "ExpiresAt")
+}
+
+func (s SqlWebAuthnCredentialStore) Save(credential *model.WebAuthnCredential) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		credential.PreSave()
+		if result.Err = credential.IsValid(); result.Err != nil {
+			storeChannel <- result
+			close(storeChannel)
+			return
+		}
+
+		if err := s.GetMaster().Insert(credential); err != nil {
+			result.Err = model.NewLocAppError("SqlWebAuthnCredentialStore.Save", "store.sql_webauthn_credential.save.app_error", nil, "user_id="+credential.UserId+", "+err.Error())
+		} else {
+			result.Data = credential
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
+func (s SqlWebAuthnCredentialStore) GetForUser(userId string) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		var credentials []*model.WebAuthnCredential
+		if _, err := s.GetReplica().Select(&credentials, "SELECT * FROM WebAuthnCredentials WHERE UserId = :UserId ORDER BY CreateAt", map[string]interface{}{"UserId": userId}); err != nil {
+			result.Err = model.NewLocAppError("SqlWebAuthnCredentialStore.GetForUser", "store.sql_webauthn_credential.get_for_user.app_error", nil, "user_id="+userId+", "+err.Error())
+		} else {
+			result.Data = credentials
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
+func (s SqlWebAuthnCredentialStore) UpdateSignCount(id string, signCount int64, lastUsedAt int64) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		if _, err := s.GetMaster().Exec("UPDATE WebAuthnCredentials SET SignCount = :SignCount, LastUsedAt = :LastUsedAt WHERE Id = :Id", map[string]interface{}{"Id": id, "SignCount": signCount, "LastUsedAt": lastUsedAt}); err != nil {
+			result.Err = model.NewLocAppError("SqlWebAuthnCredentialStore.UpdateSignCount", "store.sql_webauthn_credential.update_sign_count.app_error", nil, "id="+id+", "+err.Error())
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
+func (s SqlWebAuthnCredentialStore) Delete(userId string, id string) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		if sqlResult, err := s.GetMaster().Exec("DELETE FROM WebAuthnCredentials WHERE Id = :Id AND UserId = :UserId", map[string]interface{}{"Id": id, "UserId": userId}); err != nil {
+			result.Err = model.NewLocAppError("SqlWebAuthnCredentialStore.Delete", "store.sql_webauthn_credential.delete.app_error", nil, "id="+id+", "+err.Error())
+		} else if rows, _ := sqlResult.RowsAffected(); rows == 0 {
+			result.Err = model.NewLocAppError("SqlWebAuthnCredentialStore.Delete", "store.sql_webauthn_credential.delete.missing.app_error", nil, "id="+id)
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
+func (s SqlWebAuthnCredentialStore) PermanentDeleteByUser(userId string) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		if _, err := s.GetMaster().Exec("DELETE FROM WebAuthnCredentials WHERE UserId = :UserId", map[string]interface{}{"UserId": userId}); err != nil {
+			result.Err = model.NewLocAppError("SqlWebAuthnCredentialStore.PermanentDeleteByUser", "store.sql_webauthn_credential.permanent_delete_by_user.app_error", nil, "user_id="+userId+", "+err.Error())
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
+// SaveChallenge remembers an issued challenge until it's consumed and clears out any that expired unanswered
+func (s SqlWebAuthnCredentialStore) SaveChallenge(challenge *model.WebAuthnChallenge) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		if _, err := s.GetMaster().Exec("DELETE FROM WebAuthnChallenges WHERE ExpiresAt < :Now", map[string]interface{}{"Now": model.GetMillis()}); err != nil {
+			result.Err = model.NewLocAppError("SqlWebAuthnCredentialStore.SaveChallenge", "store.sql_webauthn_credential.save_challenge.app_error", nil, "user_id="+challenge.UserId+", "+err.Error())
+		} else if err := s.GetMaster().Insert(challenge); err != nil {
+			result.Err = model.NewLocAppError("SqlWebAuthnCredentialStore.SaveChallenge", "store.sql_webauthn_credential.save_challenge.app_error", nil, "user_id="+challenge.UserId+", "+err.Error())
+		} else {
+			result.Data = challenge
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
+
+// ConsumeChallenge deletes an unexpired challenge and returns true as the result data if it was there to delete.
+// Since only one request can delete the row, a challenge can't be answered twice.
+func (s SqlWebAuthnCredentialStore) ConsumeChallenge(nonce string, now int64) StoreChannel {
+	storeChannel := make(StoreChannel, 1)
+
+	go func() {
+		result := StoreResult{}
+
+		if sqlResult, err := s.GetMaster().Exec("DELETE FROM WebAuthnChallenges WHERE Nonce = :Nonce AND ExpiresAt >= :Now", map[string]interface{}{"Nonce": nonce, "Now": now}); err != nil {
+			result.Err = model.NewLocAppError("SqlWebAuthnCredentialStore.ConsumeChallenge", "store.sql_webauthn_credential.consume_challenge.app_error", nil, err.Error())
+		} else {
+			rows, _ := sqlResult.RowsAffected()
+			result.Data = rows == 1
+		}
+
+		storeChannel <- result
+		close(storeChannel)
+	}()
+
+	return storeChannel
+}
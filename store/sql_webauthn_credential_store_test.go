@@ -0,0 +1,75 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package store
+
+import (
+	"testing"
+
+	"github.com/mattermost/platform/model"
+)
+
+func TestWebAuthnCredentialStore(t *testing.T) {
+	Setup()
+
+	userId := model.NewId()
+
+	credential1 := &model.WebAuthnCredential{UserId: userId, Name: "key 1", CredentialId: model.NewId(), PublicKey: model.NewId()}
+	if result := <-store.WebAuthn().Save(credential1); result.Err != nil {
+		t.Fatal(result.Err)
+	}
+
+	credential2 := &model.WebAuthnCredential{UserId: userId, Name: "key 2", CredentialId: model.NewId(), PublicKey: model.NewId()}
+	Must(store.WebAuthn().Save(credential2))
+
+	if result := <-store.WebAuthn().Save(&model.WebAuthnCredential{UserId: userId, CredentialId: model.NewId(), PublicKey: model.NewId()}); result.Err == nil {
+		t.Fatal("should have failed to save a credential without a name")
+	}
+
+	if credentials := Must(store.WebAuthn().GetForUser(userId)).([]*model.WebAuthnCredential); len(credentials) != 2 {
+		t.Fatal("should have returned both credentials")
+	} else if credentials[0].Id != credential1.Id || credentials[0].PublicKey != credential1.PublicKey {
+		t.Fatal("should have returned the credentials in order")
+	}
+
+	Must(store.WebAuthn().UpdateSignCount(credential1.Id, 5, 1234))
+
+	if credentials := Must(store.WebAuthn().GetForUser(userId)).([]*model.WebAuthnCredential); credentials[0].SignCount != 5 || credentials[0].LastUsedAt != 1234 {
+		t.Fatal("should have updated the sign count")
+	}
+
+	if result := <-store.WebAuthn().Delete(model.NewId(), credential1.Id); result.Err == nil {
+		t.Fatal("should not have deleted another user's credential")
+	}
+
+	Must(store.WebAuthn().Delete(userId, credential1.Id))
+
+	if credentials := Must(store.WebAuthn().GetForUser(userId)).([]*model.WebAuthnCredential); len(credentials) != 1 {
+		t.Fatal("should have deleted the credential")
+	}
+
+	Must(store.WebAuthn().PermanentDeleteByUser(userId))
+
+	if credentials := Must(store.WebAuthn().GetForUser(userId)).([]*model.WebAuthnCredential); len(credentials) != 0 {
+		t.Fatal("should have deleted all of the user's credentials")
+	}
+}
+
+func TestWebAuthnChallengeStore(t *testing.T) {
+	Setup()
+
+	challenge := model.NewWebAuthnChallenge(model.WEBAUTHN_CHALLENGE_LOGIN, model.NewId(), "http://localhost:8065")
+	Must(store.WebAuthn().SaveChallenge(challenge))
+
+	if consumed := Must(store.WebAuthn().ConsumeChallenge(challenge.Nonce, challenge.ExpiresAt+1)).(bool); consumed {
+		t.Fatal("shouldn't have consumed an expired challenge")
+	}
+
+	if consumed := Must(store.WebAuthn().ConsumeChallenge(challenge.Nonce, model.GetMillis())).(bool); !consumed {
+		t.Fatal("should have consumed the challenge")
+	}
+
+	if consumed := Must(store.WebAuthn().ConsumeChallenge(challenge.Nonce, model.GetMillis())).(bool); consumed {
+		t.Fatal("shouldn't have consumed the challenge twice")
+	}
+}
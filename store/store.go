@@ -47,6 +47,16 @@ type Store interface {
 	Status() StatusStore
 	FileInfo() FileInfoStore
 	Reaction() ReactionStore
+	Role() RoleStore
+	Scheme() SchemeStore
+	WebAuthn() WebAuthnCredentialStore
+	LdapGroupLink() LdapGroupLinkStore
+	ProfileAttribute() ProfileAttributeStore
+	PendingEmailNotification() PendingEmailNotificationStore
+	MailQueue() MailQueueStore
+	UserGroup() UserGroupStore
+	ThreadMembership() ThreadMembershipStore
+	Reminder() ReminderStore
 	MarkSystemRanUnitTests()
 	Close()
 	DropAllTables()
@@ -108,6 +118,7 @@ type ChannelStore interface {
 	GetMemberForPost(postId string, userId string) StoreChannel
 	InvalidateMemberCount(channelId string)
 	GetMemberCount(channelId string, allowFromCache bool) StoreChannel
+	GetTeamIdForChannel(channelId string, allowFromCache bool) StoreChannel
 	RemoveMember(channelId string, userId string) StoreChannel
 	PermanentDeleteMembersByUser(userId string) StoreChannel
 	UpdateLastViewedAt(channelIds []string, userId string) StoreChannel
@@ -137,6 +148,7 @@ type PostStore interface {
 	AnalyticsUserCountsWithPostsByDay(teamId string) StoreChannel
 	AnalyticsPostCountsByDay(teamId string) StoreChannel
 	AnalyticsPostCount(teamId string, mustHaveFile bool, mustHaveHashtag bool) StoreChannel
+	CountByTypeSince(channelId string, userId string, postType string, time int64) StoreChannel
 	InvalidateLastPostTimeCache(channelId string)
 }
 
@@ -149,6 +161,8 @@ type UserStore interface {
 	UpdateAuthData(userId string, service string, authData *string, email string, resetMfa bool) StoreChannel
 	UpdateMfaSecret(userId, secret string) StoreChannel
 	UpdateMfaActive(userId string, active bool) StoreChannel
+	UpdateMfaRecoveryCodes(userId, codes string) StoreChannel
+	UpdatePasswordHistory(userId, history string) StoreChannel
 	Get(id string) StoreChannel
 	GetAll() StoreChannel
 	InvalidateProfilesInChannelCacheByUser(userId string)
@@ -171,6 +185,9 @@ type UserStore interface {
 	UpdateFailedPasswordAttempts(userId string, attempts int) StoreChannel
 	GetTotalUsersCount() StoreChannel
 	GetSystemAdminProfiles() StoreChannel
+	GetExpiredGuests(expiredBefore int64) StoreChannel
+	GetByNotifyProp(key string, value string) StoreChannel
+	GetByProp(key string, value string) StoreChannel
 	PermanentDelete(userId string) StoreChannel
 	AnalyticsUniqueUserCount(teamId string) StoreChannel
 	GetUnreadCount(userId string) StoreChannel
@@ -191,7 +208,7 @@ type SessionStore interface {
 	PermanentDeleteSessionsByUser(teamId string) StoreChannel
 	UpdateLastActivityAt(sessionId string, time int64) StoreChannel
 	UpdateRoles(userId string, roles string) StoreChannel
-	UpdateDeviceId(id string, deviceId string, expiresAt int64) StoreChannel
+	UpdateDeviceId(id string, deviceId string, deviceKey string, expiresAt int64) StoreChannel
 	AnalyticsSessionCount() StoreChannel
 }
 
@@ -236,6 +253,7 @@ type SystemStore interface {
 	Update(system *model.System) StoreChannel
 	Get() StoreChannel
 	GetByName(name string) StoreChannel
+	AcquireLease(name string, holderId string, duration int64) StoreChannel
 }
 
 type WebhookStore interface {
@@ -304,6 +322,8 @@ type StatusStore interface {
 	GetOnlineAway() StoreChannel
 	GetOnline() StoreChannel
 	GetAllFromTeam(teamId string) StoreChannel
+	GetExpiredDND(time int64) StoreChannel
+	GetExpiredCustom(time int64) StoreChannel
 	ResetAll() StoreChannel
 	GetTotalActiveUsersCount() StoreChannel
 	UpdateLastActivityAt(userId string, lastActivityAt int64) StoreChannel
@@ -324,3 +344,102 @@ type ReactionStore interface {
 	GetForPost(postId string) StoreChannel
 	DeleteAllWithEmojiName(emojiName string) StoreChannel
 }
+
+type RoleStore interface {
+	Save(role *model.Role) StoreChannel
+	Get(roleId string) StoreChannel
+	GetAll(allowFromCache bool) StoreChannel
+	Delete(roleId string) StoreChannel
+}
+
+type SchemeStore interface {
+	Save(scheme *model.Scheme) StoreChannel
+	Get(teamId string) StoreChannel
+	GetAll(allowFromCache bool) StoreChannel
+	Delete(teamId string) StoreChannel
+}
+
+type WebAuthnCredentialStore interface {
+	Save(credential *model.WebAuthnCredential) StoreChannel
+	GetForUser(userId string) StoreChannel
+	UpdateSignCount(id string, signCount int64, lastUsedAt int64) StoreChannel
+	Delete(userId string, id string) StoreChannel
+	PermanentDeleteByUser(userId string) StoreChannel
+	SaveChallenge(challenge *model.WebAuthnChallenge) StoreChannel
+	ConsumeChallenge(nonce string, now int64) StoreChannel
+}
+
+type LdapGroupLinkStore interface {
+	Save(link *model.LdapGroupLink) StoreChannel
+	Get(id string) StoreChannel
+	GetAll() StoreChannel
+	GetForTeam(teamId string) StoreChannel
+	GetForChannel(channelId string) StoreChannel
+	Delete(id string) StoreChannel
+}
+
+type ProfileAttributeStore interface {
+	Save(attribute *model.ProfileAttribute) StoreChannel
+	Update(attribute *model.ProfileAttribute) StoreChannel
+	Get(id string) StoreChannel
+	GetAll(allowFromCache bool) StoreChannel
+	Delete(id string) StoreChannel
+}
+
+type PendingEmailNotificationStore interface {
+	Save(notification *model.PendingEmailNotification) StoreChannel
+	GetAll() StoreChannel
+	Count() StoreChannel
+	DeleteForUser(userId string, before int64) StoreChannel
+}
+
+type MailQueueStore interface {
+	Save(email *model.OutgoingEmail) StoreChannel
+	Update(email *model.OutgoingEmail) StoreChannel
+	ClaimNext(now int64) StoreChannel
+	ResetStuck(before int64) StoreChannel
+	CountSentToSince(recipient string, since int64) StoreChannel
+	GetStats(failureLimit int) StoreChannel
+	PermanentDeleteFinishedBefore(before int64) StoreChannel
+	SaveBounce(bounce *model.EmailBounce) StoreChannel
+	IsBouncing(email string) StoreChannel
+	DeleteBounce(email string) StoreChannel
+}
+
+type UserGroupStore interface {
+	Save(group *model.UserGroup) StoreChannel
+	Update(group *model.UserGroup) StoreChannel
+	Get(id string) StoreChannel
+	GetByName(name string) StoreChannel
+	GetByNames(names []string) StoreChannel
+	GetAll() StoreChannel
+	Delete(id string) StoreChannel
+	SaveMember(member *model.UserGroupMember) StoreChannel
+	RemoveMember(groupId string, userId string) StoreChannel
+	GetMemberIds(groupId string) StoreChannel
+	PermanentDeleteMembersByUser(userId string) StoreChannel
+}
+
+type ThreadMembershipStore interface {
+	Save(membership *model.ThreadMembership) StoreChannel
+	Update(membership *model.ThreadMembership) StoreChannel
+	Get(postId string, userId string) StoreChannel
+	GetForPost(postId string) StoreChannel
+	GetFollowedByUser(userId string, teamId string, offset int, limit int) StoreChannel
+	IncrementUnreadReplies(postId string, userId string, replyAt int64) StoreChannel
+	ViewThread(postId string, userId string, viewedAt int64) StoreChannel
+	PermanentDeleteByPost(postId string) StoreChannel
+	PermanentDeleteByUser(userId string) StoreChannel
+}
+
+type ReminderStore interface {
+	Save(reminder *model.Reminder) StoreChannel
+	Update(reminder *model.Reminder) StoreChannel
+	Get(id string) StoreChannel
+	GetForUser(userId string) StoreChannel
+	GetDue(before int64, limit int) StoreChannel
+	MarkFired(id string, firedAt int64) StoreChannel
+	Delete(id string) StoreChannel
+	PermanentDeleteFiredBefore(before int64) StoreChannel
+	PermanentDeleteByUser(userId string) StoreChannel
+}
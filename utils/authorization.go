@@ -149,3 +149,15 @@ func SetDefaultRolesBasedOnConfig() {
 		)
 	}
 }
+
+// HaveRoleSettingsChanged returns true if any of the settings used to generate the built in roles are different
+func HaveRoleSettingsChanged(oldCfg *model.Config, newCfg *model.Config) bool {
+	return *oldCfg.TeamSettings.RestrictPublicChannelCreation != *newCfg.TeamSettings.RestrictPublicChannelCreation ||
+		*oldCfg.TeamSettings.RestrictPrivateChannelCreation != *newCfg.TeamSettings.RestrictPrivateChannelCreation ||
+		*oldCfg.TeamSettings.RestrictPublicChannelManagement != *newCfg.TeamSettings.RestrictPublicChannelManagement ||
+		*oldCfg.TeamSettings.RestrictPrivateChannelManagement != *newCfg.TeamSettings.RestrictPrivateChannelManagement ||
+		*oldCfg.TeamSettings.RestrictPublicChannelDeletion != *newCfg.TeamSettings.RestrictPublicChannelDeletion ||
+		*oldCfg.TeamSettings.RestrictPrivateChannelDeletion != *newCfg.TeamSettings.RestrictPrivateChannelDeletion ||
+		*oldCfg.TeamSettings.RestrictTeamInvite != *newCfg.TeamSettings.RestrictTeamInvite ||
+		*oldCfg.ServiceSettings.EnableOnlyAdminIntegrations != *newCfg.ServiceSettings.EnableOnlyAdminIntegrations
+}
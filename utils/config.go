@@ -173,7 +173,9 @@ func LoadConfig(fileName string) {
 	}
 
 	needSave := len(config.SqlSettings.AtRestEncryptKey) == 0 || len(*config.FileSettings.PublicLinkSalt) == 0 ||
-		len(config.EmailSettings.InviteSalt) == 0 || len(config.EmailSettings.PasswordResetSalt) == 0
+		len(config.EmailSettings.InviteSalt) == 0 || len(config.EmailSettings.PasswordResetSalt) == 0 ||
+		config.EmailSettings.ReplyToSalt == nil || len(*config.EmailSettings.ReplyToSalt) == 0 ||
+		config.ServiceSettings.WebAuthnChallengeSalt == nil || len(*config.ServiceSettings.WebAuthnChallengeSalt) == 0
 
 	config.SetDefaults()
 
@@ -192,6 +194,7 @@ func LoadConfig(fileName string) {
 	}
 
 	configureLog(&config.LogSettings)
+	checkBreachedPasswordFile(&config)
 
 	if config.FileSettings.DriverName == model.IMAGE_DRIVER_LOCAL {
 		dir := config.FileSettings.Directory
@@ -267,6 +270,8 @@ func getClientConfig(c *model.Config) map[string]string {
 	props["EnableEmailBatching"] = strconv.FormatBool(*c.EmailSettings.EnableEmailBatching)
 
 	props["EnableSignUpWithGitLab"] = strconv.FormatBool(c.GitLabSettings.Enable)
+	props["EnableSignUpWithOpenId"] = strconv.FormatBool(*c.OpenIdSettings.Enable)
+	props["OpenIdButtonText"] = *c.OpenIdSettings.ButtonText
 
 	props["ShowEmailAddress"] = strconv.FormatBool(c.PrivacySettings.ShowEmailAddress)
 
@@ -316,6 +321,7 @@ func getClientConfig(c *model.Config) map[string]string {
 		if *License.Features.MFA {
 			props["EnableMultifactorAuthentication"] = strconv.FormatBool(*c.ServiceSettings.EnableMultifactorAuthentication)
 			props["EnforceMultifactorAuthentication"] = strconv.FormatBool(*c.ServiceSettings.EnforceMultifactorAuthentication)
+			props["EnforceSecurityKeysForAdmins"] = strconv.FormatBool(*c.ServiceSettings.EnforceSecurityKeysForAdmins)
 		}
 
 		if *License.Features.Compliance {
@@ -386,6 +392,12 @@ func Desanitize(cfg *model.Config) {
 	if cfg.EmailSettings.PasswordResetSalt == model.FAKE_SETTING {
 		cfg.EmailSettings.PasswordResetSalt = Cfg.EmailSettings.PasswordResetSalt
 	}
+	if *cfg.EmailSettings.ReplyToSalt == model.FAKE_SETTING {
+		*cfg.EmailSettings.ReplyToSalt = *Cfg.EmailSettings.ReplyToSalt
+	}
+	if *cfg.ServiceSettings.WebAuthnChallengeSalt == model.FAKE_SETTING {
+		*cfg.ServiceSettings.WebAuthnChallengeSalt = *Cfg.ServiceSettings.WebAuthnChallengeSalt
+	}
 	if cfg.EmailSettings.SMTPPassword == model.FAKE_SETTING {
 		cfg.EmailSettings.SMTPPassword = Cfg.EmailSettings.SMTPPassword
 	}
@@ -394,6 +406,14 @@ func Desanitize(cfg *model.Config) {
 		cfg.GitLabSettings.Secret = Cfg.GitLabSettings.Secret
 	}
 
+	if cfg.OpenIdSettings.Secret != nil && *cfg.OpenIdSettings.Secret == model.FAKE_SETTING {
+		*cfg.OpenIdSettings.Secret = *Cfg.OpenIdSettings.Secret
+	}
+
+	if cfg.ScimSettings.Token != nil && *cfg.ScimSettings.Token == model.FAKE_SETTING {
+		*cfg.ScimSettings.Token = *Cfg.ScimSettings.Token
+	}
+
 	if cfg.SqlSettings.DataSource == model.FAKE_SETTING {
 		cfg.SqlSettings.DataSource = Cfg.SqlSettings.DataSource
 	}
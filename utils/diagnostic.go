@@ -135,6 +135,7 @@ func trackConfig() {
 		"gitlab":    Cfg.GitLabSettings.Enable,
 		"google":    Cfg.GoogleSettings.Enable,
 		"office365": Cfg.Office365Settings.Enable,
+		"openid":    *Cfg.OpenIdSettings.Enable,
 	})
 
 	SendDiagnostic(TRACK_CONFIG_LDAP, map[string]interface{}{
@@ -0,0 +1,339 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package utils
+
+import (
+	"bytes"
+	"encoding/base64"
+	"html"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	l4g "github.com/alecthomas/log4go"
+
+	"github.com/mattermost/platform/model"
+)
+
+const (
+	INBOUND_MAIL_TIMEOUT        = 5 * time.Minute
+	INBOUND_MAIL_MAX_RECIPIENTS = 100
+)
+
+// InboundMailServer is a minimal SMTP server that accepts the emails sent to it and passes them to a handler.
+// It's meant to sit behind the organisation's mail server, which relays replies to notification emails to it.
+type InboundMailServer struct {
+	listener net.Listener
+	hostname string
+	maxSize  int
+	handler  func(from string, to []string, data []byte)
+}
+
+// InboundMail is the part of a received email needed to turn it into a post
+type InboundMail struct {
+	From          string
+	Subject       string
+	Text          string
+	AutoSubmitted bool
+}
+
+func StartInboundMailServer(address string, maxSize int, handler func(from string, to []string, data []byte)) (*InboundMailServer, *model.AppError) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, model.NewLocAppError("StartInboundMailServer", "utils.inbound_mail.listen.app_error", nil, err.Error())
+	}
+
+	hostname, _ := os.Hostname()
+	if len(hostname) == 0 {
+		hostname = "localhost"
+	}
+
+	server := &InboundMailServer{listener: listener, hostname: hostname, maxSize: maxSize, handler: handler}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go server.handleConnection(conn)
+		}
+	}()
+
+	return server, nil
+}
+
+func (s *InboundMailServer) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+func (s *InboundMailServer) Close() {
+	s.listener.Close()
+}
+
+func (s *InboundMailServer) handleConnection(conn net.Conn) {
+	defer conn.Close()
+
+	// a bad email shouldn't take down the whole server
+	defer func() {
+		if r := recover(); r != nil {
+			l4g.Error(T("utils.inbound_mail.panic.error"), conn.RemoteAddr(), r)
+		}
+	}()
+
+	text := textproto.NewConn(conn)
+	conn.SetDeadline(time.Now().Add(INBOUND_MAIL_TIMEOUT))
+	text.PrintfLine("220 %v ESMTP", s.hostname)
+
+	var from string
+	var to []string
+	started := false
+
+	for {
+		conn.SetDeadline(time.Now().Add(INBOUND_MAIL_TIMEOUT))
+
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+
+		command := strings.ToUpper(line)
+		switch {
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			text.PrintfLine("250 %v", s.hostname)
+		case strings.HasPrefix(command, "MAIL FROM:"):
+			// the null path <> is allowed for bounces but the path can't be left out
+			if arg := line[len("MAIL FROM:"):]; len(strings.TrimSpace(arg)) == 0 {
+				text.PrintfLine("501 Syntax error in parameters")
+			} else {
+				from = parseSMTPPath(arg)
+				to = nil
+				started = true
+				text.PrintfLine("250 OK")
+			}
+		case strings.HasPrefix(command, "RCPT TO:"):
+			if !started {
+				text.PrintfLine("503 Need MAIL command")
+			} else if len(to) >= INBOUND_MAIL_MAX_RECIPIENTS {
+				text.PrintfLine("452 Too many recipients")
+			} else if recipient := parseSMTPPath(line[len("RCPT TO:"):]); len(recipient) == 0 {
+				text.PrintfLine("501 Syntax error in parameters")
+			} else {
+				to = append(to, recipient)
+				text.PrintfLine("250 OK")
+			}
+		case command == "DATA":
+			if len(to) == 0 {
+				text.PrintfLine("503 Need RCPT command")
+				continue
+			}
+
+			text.PrintfLine("354 End data with <CR><LF>.<CR><LF>")
+
+			reader := text.DotReader()
+			data, err := ioutil.ReadAll(io.LimitReader(reader, int64(s.maxSize)+1))
+			if err != nil {
+				return
+			}
+
+			if len(data) > s.maxSize {
+				io.Copy(ioutil.Discard, reader)
+				text.PrintfLine("552 Message exceeds maximum size")
+			} else {
+				s.handler(from, to, data)
+				text.PrintfLine("250 OK")
+			}
+
+			from = ""
+			to = nil
+			started = false
+		case command == "RSET":
+			from = ""
+			to = nil
+			started = false
+			text.PrintfLine("250 OK")
+		case command == "NOOP":
+			text.PrintfLine("250 OK")
+		case command == "QUIT":
+			text.PrintfLine("221 Bye")
+			return
+		default:
+			text.PrintfLine("502 Command not implemented")
+		}
+	}
+}
+
+// parseSMTPPath returns the address from the argument of a MAIL or RCPT command, ignoring any parameters
+func parseSMTPPath(arg string) string {
+	arg = strings.TrimSpace(arg)
+
+	if end := strings.Index(arg, ">"); strings.HasPrefix(arg, "<") && end > 0 {
+		return arg[1:end]
+	}
+
+	if fields := strings.Fields(arg); len(fields) > 0 {
+		return fields[0]
+	}
+
+	return ""
+}
+
+// ParseInboundMail reads the sender, subject and plain text body of an email. HTML bodies are converted to
+// text when an email has no plain text part.
+func ParseInboundMail(data []byte) (*InboundMail, *model.AppError) {
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		return nil, model.NewLocAppError("ParseInboundMail", "utils.inbound_mail.parse.app_error", nil, err.Error())
+	}
+
+	inbound := &InboundMail{}
+
+	if address, err := mail.ParseAddress(msg.Header.Get("From")); err != nil {
+		return nil, model.NewLocAppError("ParseInboundMail", "utils.inbound_mail.parse_from.app_error", nil, err.Error())
+	} else {
+		inbound.From = address.Address
+	}
+
+	decoder := new(mime.WordDecoder)
+	if subject, err := decoder.DecodeHeader(msg.Header.Get("Subject")); err == nil {
+		inbound.Subject = subject
+	} else {
+		inbound.Subject = msg.Header.Get("Subject")
+	}
+
+	// replies sent by vacation responders and the like shouldn't be posted
+	if autoSubmitted := strings.ToLower(msg.Header.Get("Auto-Submitted")); len(autoSubmitted) > 0 && autoSubmitted != "no" {
+		inbound.AutoSubmitted = true
+	}
+
+	text, isHtml, err := readMailText(textproto.MIMEHeader(msg.Header), msg.Body)
+	if err != nil {
+		return nil, model.NewLocAppError("ParseInboundMail", "utils.inbound_mail.parse_body.app_error", nil, err.Error())
+	}
+
+	if isHtml {
+		text = htmlToText(text)
+	}
+
+	inbound.Text = text
+
+	return inbound, nil
+}
+
+// readMailText returns the plain text part of an email, or its HTML part if there isn't one
+func readMailText(header textproto.MIMEHeader, body io.Reader) (string, bool, error) {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType = "text/plain"
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		reader := multipart.NewReader(body, params["boundary"])
+
+		var htmlText string
+		foundHtml := false
+
+		for {
+			part, err := reader.NextPart()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return "", false, err
+			}
+
+			if text, isHtml, err := readMailText(part.Header, part); err != nil {
+				return "", false, err
+			} else if !isHtml && len(text) > 0 {
+				return text, false, nil
+			} else if isHtml && !foundHtml {
+				htmlText = text
+				foundHtml = true
+			}
+		}
+
+		return htmlText, foundHtml, nil
+	}
+
+	if mediaType != "text/plain" && mediaType != "text/html" {
+		return "", false, nil
+	}
+
+	switch strings.ToLower(header.Get("Content-Transfer-Encoding")) {
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	}
+
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		return "", false, err
+	}
+
+	return string(b), mediaType == "text/html", nil
+}
+
+var htmlBreakRegexp = regexp.MustCompile(`(?i)<br\s*/?>|</p>|</div>|</li>|</tr>`)
+var htmlTagRegexp = regexp.MustCompile(`(?s)<[^>]*>`)
+var htmlIgnoredRegexp = regexp.MustCompile(`(?is)<(head|style|script)[^>]*>.*?</(head|style|script)>`)
+var htmlQuoteRegexp = regexp.MustCompile(`(?is)<blockquote[^>]*>.*</blockquote>`)
+
+func htmlToText(s string) string {
+	s = htmlIgnoredRegexp.ReplaceAllString(s, "")
+	s = htmlQuoteRegexp.ReplaceAllString(s, "")
+	s = htmlBreakRegexp.ReplaceAllString(s, "\n")
+	s = htmlTagRegexp.ReplaceAllString(s, "")
+
+	return html.UnescapeString(s)
+}
+
+var replyHeaderRegexp = regexp.MustCompile(`^On\s.+wrote:$`)
+var forwardHeaderRegexp = regexp.MustCompile(`^-+\s*(Original Message|Forwarded message)\s*-+$`)
+var outlookHeaderRegexp = regexp.MustCompile(`^(From|De|Von):\s`)
+var mobileSignatureRegexp = regexp.MustCompile(`^Sent from my\s`)
+
+// ExtractReplyText returns what the sender wrote in a reply, dropping the quoted email they replied to and
+// their signature
+func ExtractReplyText(text string) string {
+	text = strings.Replace(text, "\r\n", "\n", -1)
+	lines := strings.Split(text, "\n")
+
+	var reply []string
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+
+		// signatures are separated by "-- " and other clients start the quoted email with a header
+		if line == "-- " || trimmed == "--" || forwardHeaderRegexp.MatchString(trimmed) || mobileSignatureRegexp.MatchString(trimmed) {
+			break
+		}
+
+		// the "On <date>, <name> wrote:" header is often wrapped over two lines
+		if replyHeaderRegexp.MatchString(trimmed) ||
+			(strings.HasPrefix(trimmed, "On ") && i+1 < len(lines) && strings.HasSuffix(strings.TrimSpace(lines[i+1]), "wrote:")) {
+			break
+		}
+
+		if outlookHeaderRegexp.MatchString(trimmed) && i+1 < len(lines) && strings.Contains(lines[i+1], ":") {
+			break
+		}
+
+		if strings.HasPrefix(trimmed, ">") {
+			continue
+		}
+
+		reply = append(reply, strings.TrimRight(line, " \t"))
+	}
+
+	return strings.TrimSpace(strings.Join(reply, "\n"))
+}
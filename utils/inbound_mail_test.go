@@ -0,0 +1,176 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package utils
+
+import (
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExtractReplyText(t *testing.T) {
+	cases := []struct {
+		text     string
+		expected string
+	}{
+		{"Sounds good\r\n", "Sounds good"},
+		{"Sounds good\n\nOn Mon, Jan 2, 2017 at 10:00 AM, Mattermost <feedback@example.com> wrote:\n> original", "Sounds good"},
+		{"Sounds good\n\nOn Mon, Jan 2, 2017 at 10:00 AM, Mattermost\n<feedback@example.com> wrote:\n> original", "Sounds good"},
+		{"First line\n> quoted\nSecond line", "First line\nSecond line"},
+		{"Sounds good\n\n-- \nJohn Smith\nField Engineer", "Sounds good"},
+		{"Sounds good\n\nSent from my iPhone", "Sounds good"},
+		{"Sounds good\n\n-----Original Message-----\nFrom: Mattermost", "Sounds good"},
+		{"Sounds good\n\nFrom: Mattermost <feedback@example.com>\nSent: Monday, January 2, 2017 10:00 AM", "Sounds good"},
+		{"> only quoted text", ""},
+	}
+
+	for _, c := range cases {
+		if actual := ExtractReplyText(c.text); actual != c.expected {
+			t.Fatalf("got %q from %q, expected %q", actual, c.text, c.expected)
+		}
+	}
+}
+
+func TestParseInboundMail(t *testing.T) {
+	multipartMail := strings.Join([]string{
+		"From: John Smith <john@example.com>",
+		"Subject: =?utf-8?q?Re:_caf=C3=A9?=",
+		"MIME-Version: 1.0",
+		"Content-Type: multipart/alternative; boundary=\"b1\"",
+		"",
+		"--b1",
+		"Content-Type: text/html; charset=utf-8",
+		"",
+		"<p>html version</p>",
+		"--b1",
+		"Content-Type: text/plain; charset=utf-8",
+		"Content-Transfer-Encoding: quoted-printable",
+		"",
+		"caf=C3=A9 at noon",
+		"--b1--",
+		"",
+	}, "\r\n")
+
+	if inbound, err := ParseInboundMail([]byte(multipartMail)); err != nil {
+		t.Fatal(err)
+	} else if inbound.From != "john@example.com" || inbound.Subject != "Re: café" || strings.TrimSpace(inbound.Text) != "café at noon" || inbound.AutoSubmitted {
+		t.Fatal("should have parsed the plain text part", inbound)
+	}
+
+	htmlMail := strings.Join([]string{
+		"From: john@example.com",
+		"Subject: Re: notification",
+		"Auto-Submitted: auto-replied",
+		"Content-Type: text/html; charset=utf-8",
+		"",
+		"<html><head><style>p {}</style></head><body><p>Fish &amp; chips</p><blockquote>quoted</blockquote></body></html>",
+	}, "\r\n")
+
+	if inbound, err := ParseInboundMail([]byte(htmlMail)); err != nil {
+		t.Fatal(err)
+	} else if strings.TrimSpace(inbound.Text) != "Fish & chips" {
+		t.Fatalf("should have converted the html to text, got %q", inbound.Text)
+	} else if !inbound.AutoSubmitted {
+		t.Fatal("should have marked the email as automatically sent")
+	}
+
+	if _, err := ParseInboundMail([]byte("Subject: no sender\r\n\r\nbody")); err == nil {
+		t.Fatal("should have failed without a sender")
+	}
+}
+
+func TestInboundMailServer(t *testing.T) {
+	TranslationsPreInit()
+
+	type received struct {
+		from string
+		to   []string
+		data string
+	}
+
+	receivedChan := make(chan received, 1)
+
+	server, err := StartInboundMailServer("127.0.0.1:0", 1024, func(from string, to []string, data []byte) {
+		receivedChan <- received{from, to, string(data)}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	message := "From: john@example.com\r\nSubject: Re: test\r\n\r\nreply\r\n"
+	if err := smtp.SendMail(server.Addr().String(), nil, "john@example.com", []string{"reply+abc@example.com"}, []byte(message)); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case r := <-receivedChan:
+		if r.from != "john@example.com" || len(r.to) != 1 || r.to[0] != "reply+abc@example.com" || r.data != strings.Replace(message, "\r\n", "\n", -1) {
+			t.Fatal("should have passed the email to the handler", r)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the email")
+	}
+
+	if err := smtp.SendMail(server.Addr().String(), nil, "john@example.com", []string{"reply+abc@example.com"}, []byte(strings.Repeat("a", 2048))); err == nil {
+		t.Fatal("should have rejected an email over the maximum size")
+	}
+}
+
+func TestInboundMailServerEmptyPath(t *testing.T) {
+	TranslationsPreInit()
+
+	server, err := StartInboundMailServer("127.0.0.1:0", 1024, func(from string, to []string, data []byte) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	conn, dialErr := textproto.Dial("tcp", server.Addr().String())
+	if dialErr != nil {
+		t.Fatal(dialErr)
+	}
+	defer conn.Close()
+
+	if _, _, err := conn.ReadResponse(220); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		Command string
+		Code    int
+	}{
+		{"HELO localhost", 250},
+		{"MAIL FROM:", 501},
+		{"MAIL FROM: ", 501},
+		{"MAIL FROM:<>", 250},
+		{"RCPT TO:", 501},
+		{"RCPT TO:<>", 501},
+		{"RCPT TO:<reply+abc@example.com>", 250},
+		{"QUIT", 221},
+	} {
+		if _, err := conn.Cmd("%s", test.Command); err != nil {
+			t.Fatal(err)
+		} else if _, _, err := conn.ReadResponse(test.Code); err != nil {
+			t.Fatal(test.Command, err)
+		}
+	}
+}
+
+func TestParseSMTPPath(t *testing.T) {
+	for arg, path := range map[string]string{
+		"":                               "",
+		"   ":                            "",
+		"<>":                             "",
+		"<john@example.com>":             "john@example.com",
+		" <john@example.com> SIZE=1":     "john@example.com",
+		"john@example.com BODY=8BITMIME": "john@example.com",
+	} {
+		if p := parseSMTPPath(arg); p != path {
+			t.Fatal("wrong path", arg, p)
+		}
+	}
+}
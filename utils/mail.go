@@ -12,6 +12,7 @@ import (
 	"net"
 	"net/mail"
 	"net/smtp"
+	"net/textproto"
 	"time"
 )
 
@@ -102,9 +103,64 @@ func SendMailUsingConfig(to, subject, body string, config *model.Config) *model.
 		return nil
 	}
 
+	connection, err := OpenMailConnection(config)
+	if err != nil {
+		return err
+	}
+	defer connection.Close()
+
+	if err := connection.Send(to, subject, body); err != nil {
+		return err.AppError
+	}
+
+	return nil
+}
+
+// MailConnection is an open connection to the SMTP server that can be reused to send several emails
+type MailConnection struct {
+	conn   net.Conn
+	client *smtp.Client
+	config *model.Config
+}
+
+func OpenMailConnection(config *model.Config) (*MailConnection, *model.AppError) {
+	conn, err := connectToSMTPServer(config)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := newSMTPClient(conn, config)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return &MailConnection{conn: conn, client: c, config: config}, nil
+}
+
+func (mc *MailConnection) Close() {
+	mc.client.Quit()
+	mc.client.Close()
+	mc.conn.Close()
+}
+
+// MailError is an error from sending an email that keeps the reply code of the SMTP server, if there was one
+type MailError struct {
+	*model.AppError
+	ReplyCode int
+}
+
+// Send sends an email over the connection. Errors for emails the server rejected permanently, such as for
+// an unknown recipient, can be told apart from temporary ones with IsPermanentMailError.
+func (mc *MailConnection) Send(to, subject, body string) *MailError {
+	return mc.SendWithReplyTo(to, "", subject, body)
+}
+
+// SendWithReplyTo sends an email that's replied to at the given address instead of the sender's
+func (mc *MailConnection) SendWithReplyTo(to, replyTo, subject, body string) *MailError {
 	l4g.Debug(T("utils.mail.send_mail.sending.debug"), to, subject)
 
-	fromMail := mail.Address{config.EmailSettings.FeedbackName, config.EmailSettings.FeedbackEmail}
+	fromMail := mail.Address{mc.config.EmailSettings.FeedbackName, mc.config.EmailSettings.FeedbackEmail}
 	toMail := mail.Address{"", to}
 
 	headers := make(map[string]string)
@@ -116,47 +172,58 @@ func SendMailUsingConfig(to, subject, body string, config *model.Config) *model.
 	headers["Content-Transfer-Encoding"] = "8bit"
 	headers["Date"] = time.Now().Format(time.RFC1123Z)
 
+	if len(replyTo) > 0 {
+		replyToMail := mail.Address{"", replyTo}
+		headers["Reply-To"] = replyToMail.String()
+	}
+
 	message := ""
 	for k, v := range headers {
 		message += fmt.Sprintf("%s: %s\r\n", k, v)
 	}
 	message += "\r\n<html><body>" + body + "</body></html>"
 
-	conn, err1 := connectToSMTPServer(config)
-	if err1 != nil {
-		return err1
-	}
-	defer conn.Close()
-
-	c, err2 := newSMTPClient(conn, config)
-	if err2 != nil {
-		return err2
-	}
-	defer c.Quit()
-	defer c.Close()
-
-	if err := c.Mail(fromMail.Address); err != nil {
-		return model.NewLocAppError("SendMail", "utils.mail.send_mail.from_address.app_error", nil, err.Error())
+	if err := mc.client.Mail(fromMail.Address); err != nil {
+		mc.client.Reset()
+		return newMailError("utils.mail.send_mail.from_address.app_error", err)
 	}
 
-	if err := c.Rcpt(toMail.Address); err != nil {
-		return model.NewLocAppError("SendMail", "utils.mail.send_mail.to_address.app_error", nil, err.Error())
+	if err := mc.client.Rcpt(toMail.Address); err != nil {
+		mc.client.Reset()
+		return newMailError("utils.mail.send_mail.to_address.app_error", err)
 	}
 
-	w, err := c.Data()
+	w, err := mc.client.Data()
 	if err != nil {
-		return model.NewLocAppError("SendMail", "utils.mail.send_mail.msg_data.app_error", nil, err.Error())
+		mc.client.Reset()
+		return newMailError("utils.mail.send_mail.msg_data.app_error", err)
 	}
 
 	_, err = w.Write([]byte(message))
 	if err != nil {
-		return model.NewLocAppError("SendMail", "utils.mail.send_mail.msg.app_error", nil, err.Error())
+		return newMailError("utils.mail.send_mail.msg.app_error", err)
 	}
 
 	err = w.Close()
 	if err != nil {
-		return model.NewLocAppError("SendMail", "utils.mail.send_mail.close.app_error", nil, err.Error())
+		return newMailError("utils.mail.send_mail.close.app_error", err)
 	}
 
 	return nil
 }
+
+func newMailError(id string, err error) *MailError {
+	mailErr := &MailError{AppError: model.NewLocAppError("SendMail", id, nil, err.Error())}
+
+	if protoErr, ok := err.(*textproto.Error); ok {
+		mailErr.ReplyCode = protoErr.Code
+	}
+
+	return mailErr
+}
+
+// IsPermanentMailError returns true if sending an email failed in a way that retrying won't fix
+func IsPermanentMailError(err *MailError) bool {
+	// 5xx replies mean the server won't accept the email no matter how many times it's sent
+	return err.ReplyCode >= 500
+}
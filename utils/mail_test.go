@@ -0,0 +1,199 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package utils
+
+import (
+	"bufio"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mattermost/platform/model"
+)
+
+// testSMTPServer is a local stand-in for an SMTP server that accepts mail for any recipient except those
+// starting with "tempfail" or "bounce", which are rejected with a 4xx and 5xx reply
+type testSMTPServer struct {
+	listener    net.Listener
+	mutex       sync.Mutex
+	connections int
+	received    []string
+}
+
+func startTestSMTPServer(t *testing.T) *testSMTPServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := &testSMTPServer{listener: listener}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			server.mutex.Lock()
+			server.connections++
+			server.mutex.Unlock()
+
+			go server.handle(conn)
+		}
+	}()
+
+	return server
+}
+
+func (server *testSMTPServer) handle(conn net.Conn) {
+	defer conn.Close()
+
+	text := textproto.NewConn(conn)
+	text.PrintfLine("220 localhost ESMTP")
+
+	var to string
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+
+		command := strings.ToUpper(line)
+		switch {
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			text.PrintfLine("250 localhost")
+		case strings.HasPrefix(command, "MAIL FROM:"):
+			text.PrintfLine("250 OK")
+		case strings.HasPrefix(command, "RCPT TO:"):
+			to = strings.Trim(line[len("RCPT TO:"):], " <>")
+			if strings.HasPrefix(to, "tempfail") {
+				text.PrintfLine("451 Try again later")
+			} else if strings.HasPrefix(to, "bounce") {
+				text.PrintfLine("550 No such user")
+			} else {
+				text.PrintfLine("250 OK")
+			}
+		case command == "DATA":
+			text.PrintfLine("354 Go ahead")
+			if _, err := text.ReadDotLines(); err != nil {
+				return
+			}
+
+			server.mutex.Lock()
+			server.received = append(server.received, to)
+			server.mutex.Unlock()
+
+			text.PrintfLine("250 OK")
+		case command == "RSET", command == "NOOP":
+			text.PrintfLine("250 OK")
+		case command == "QUIT":
+			text.PrintfLine("221 Bye")
+			return
+		default:
+			text.PrintfLine("502 Not implemented")
+		}
+	}
+}
+
+func (server *testSMTPServer) config() *model.Config {
+	host, port, _ := net.SplitHostPort(server.listener.Addr().String())
+
+	config := *Cfg
+	config.EmailSettings.SendEmailNotifications = true
+	config.EmailSettings.SMTPServer = host
+	config.EmailSettings.SMTPPort = port
+	config.EmailSettings.FeedbackEmail = "feedback@example.com"
+
+	return &config
+}
+
+func TestMailConnection(t *testing.T) {
+	TranslationsPreInit()
+	LoadConfig("config.json")
+
+	server := startTestSMTPServer(t)
+	defer server.listener.Close()
+
+	connection, err := OpenMailConnection(server.config())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := connection.Send("user1@example.com", "subject", "body"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := connection.Send("tempfail@example.com", "subject", "body"); err == nil {
+		t.Fatal("should have failed with a 4xx reply")
+	} else if IsPermanentMailError(err) {
+		t.Fatal("4xx replies should be temporary")
+	}
+
+	if err := connection.Send("bounce@example.com", "subject", "body"); err == nil {
+		t.Fatal("should have failed with a 5xx reply")
+	} else if !IsPermanentMailError(err) {
+		t.Fatal("5xx replies should be permanent")
+	}
+
+	// the connection can still be used after a rejected recipient
+	if err := connection.Send("user2@example.com", "subject", "body"); err != nil {
+		t.Fatal(err)
+	}
+
+	connection.Close()
+
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	if server.connections != 1 {
+		t.Fatal("should have reused the connection", server.connections)
+	}
+
+	if len(server.received) != 2 || server.received[0] != "user1@example.com" || server.received[1] != "user2@example.com" {
+		t.Fatal("should have delivered the accepted emails", server.received)
+	}
+}
+
+func TestSendMailUsingConfig(t *testing.T) {
+	TranslationsPreInit()
+	LoadConfig("config.json")
+
+	server := startTestSMTPServer(t)
+	defer server.listener.Close()
+
+	config := server.config()
+
+	if err := SendMailUsingConfig("user@example.com", "subject", "body", config); err != nil {
+		t.Fatal(err)
+	}
+
+	config.EmailSettings.SendEmailNotifications = false
+	if err := SendMailUsingConfig("user@example.com", "subject", "body", config); err != nil {
+		t.Fatal(err)
+	}
+
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	if len(server.received) != 1 {
+		t.Fatal("should only have sent while notifications are enabled", server.received)
+	}
+}
+
+func TestIsPermanentMailError(t *testing.T) {
+	if !IsPermanentMailError(newMailError("id", &textproto.Error{Code: 550, Msg: "No such user"})) {
+		t.Fatal("5xx replies should be permanent")
+	}
+
+	if IsPermanentMailError(newMailError("id", &textproto.Error{Code: 421, Msg: "Too busy"})) {
+		t.Fatal("4xx replies should be temporary")
+	}
+
+	if IsPermanentMailError(newMailError("id", bufio.ErrBufferFull)) {
+		t.Fatal("other errors should be temporary")
+	}
+}
@@ -4,8 +4,16 @@
 package utils
 
 import (
-	"github.com/mattermost/platform/model"
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
 	"strings"
+
+	l4g "github.com/alecthomas/log4go"
+	"github.com/mattermost/platform/model"
 )
 
 func IsPasswordValid(password string) *model.AppError {
@@ -60,5 +68,108 @@ func IsPasswordValid(password string) *model.AppError {
 		return model.NewLocAppError("User.IsValid", id+".app_error", map[string]interface{}{"Min": min}, "")
 	}
 
+	if IsPasswordBreached(password) {
+		return model.NewLocAppError("User.IsValid", "model.user.is_valid.pwd_breached.app_error", nil, "")
+	}
+
 	return nil
 }
+
+// IsPasswordBreached returns true if the SHA-1 hash of the password is listed in the configured breached
+// password file. Each line of the file holds an uppercase or lowercase hex hash optionally followed by a
+// colon and the number of times it was seen, as in the Pwned Passwords lists ordered by hash. The lines
+// must be sorted by hash since the file is binary searched instead of being read into memory.
+func IsPasswordBreached(password string) bool {
+	fileName := *Cfg.PasswordSettings.BreachedPasswordHashFile
+	if len(fileName) == 0 {
+		return false
+	}
+
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	found, err := searchBreachedPasswordFile(FindConfigFile(fileName), hash)
+	if err != nil && !os.IsNotExist(err) {
+		// a missing file is logged when the config is loaded instead of on every check
+		l4g.Error(T("utils.password.breached_load.error"), fileName, err.Error())
+	}
+
+	return found
+}
+
+// checkBreachedPasswordFile logs an error if the configured breached password file can't be opened
+func checkBreachedPasswordFile(config *model.Config) {
+	fileName := *config.PasswordSettings.BreachedPasswordHashFile
+	if len(fileName) == 0 {
+		return
+	}
+
+	if _, err := os.Stat(FindConfigFile(fileName)); err != nil {
+		l4g.Error(T("utils.password.breached_load.error"), fileName, err.Error())
+	}
+}
+
+// searchBreachedPasswordFile binary searches the sorted file for the hash by finding the smallest offset
+// where the first line that starts at or after it has a hash that isn't less than the one being looked for
+func searchBreachedPasswordFile(fileName string, hash string) (bool, error) {
+	file, err := os.Open(filepath.Clean(fileName))
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return false, err
+	}
+
+	size := info.Size()
+
+	low, high := int64(0), size
+	for low < high {
+		mid := low + (high-low)/2
+
+		if lineHash, err := readBreachedPasswordHashAfter(file, size, mid); err != nil {
+			return false, err
+		} else if len(lineHash) == 0 || lineHash >= hash {
+			high = mid
+		} else {
+			low = mid + 1
+		}
+	}
+
+	lineHash, err := readBreachedPasswordHashAfter(file, size, low)
+	if err != nil {
+		return false, err
+	}
+
+	return lineHash == hash, nil
+}
+
+// readBreachedPasswordHashAfter returns the hash on the first line that starts at or after the offset, or an
+// empty string if there isn't one
+func readBreachedPasswordHashAfter(file *os.File, size int64, offset int64) (string, error) {
+	if offset >= size {
+		return "", nil
+	}
+
+	var reader *bufio.Reader
+	if offset == 0 {
+		reader = bufio.NewReader(io.NewSectionReader(file, 0, size))
+	} else {
+		// start from the previous byte so that a line starting exactly at the offset isn't skipped
+		reader = bufio.NewReader(io.NewSectionReader(file, offset-1, size-offset+1))
+		if _, err := reader.ReadString('\n'); err == io.EOF {
+			return "", nil
+		} else if err != nil {
+			return "", err
+		}
+	}
+
+	line, err := reader.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+
+	return strings.ToUpper(strings.TrimSpace(strings.SplitN(line, ":", 2)[0])), nil
+}
@@ -0,0 +1,55 @@
+// Copyright (c) 2016 Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package utils
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestIsPasswordBreached(t *testing.T) {
+	LoadConfig("config.json")
+
+	file, err := ioutil.TempFile("", "breached")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+
+	// sorted by hash with the SHA-1 of "letmein" in lowercase without a count and of "password1" in uppercase with one
+	file.WriteString("000000005AD76BD555C1D6D771DE417A4B87E4B4:4\r\n")
+	file.WriteString("b7a875fc1ea228b9061041b7cec4bd3c52ab3ce3\n")
+	file.WriteString("C0F7E4B5A8F1A4A5D9A1C2C7D0E55A6D3B5C1F2E:12\r\n")
+	file.WriteString("E38AD214943DAAD1D64C102FAEC29DE4AFE9DA3D:2413945\r\n")
+	file.WriteString("FFFFFFFEE791CBAC0F6305CAF0CEE06BBE131160:2\r\n")
+	file.Close()
+
+	fileName := *Cfg.PasswordSettings.BreachedPasswordHashFile
+	defer func() {
+		*Cfg.PasswordSettings.BreachedPasswordHashFile = fileName
+	}()
+
+	*Cfg.PasswordSettings.BreachedPasswordHashFile = ""
+	if IsPasswordBreached("password1") {
+		t.Fatal("shouldn't check without a file")
+	}
+
+	*Cfg.PasswordSettings.BreachedPasswordHashFile = file.Name()
+	if !IsPasswordBreached("password1") || !IsPasswordBreached("letmein") {
+		t.Fatal("should've found the breached passwords")
+	}
+
+	if IsPasswordBreached("correct horse battery staple") {
+		t.Fatal("shouldn't have found the password")
+	}
+
+	if err := IsPasswordValid("password1"); err == nil || err.Id != "model.user.is_valid.pwd_breached.app_error" {
+		t.Fatal("should've rejected the breached password")
+	}
+
+	if err := IsPasswordValid("Tr0ub4dor&3"); err != nil {
+		t.Fatal(err)
+	}
+}